@@ -36,6 +36,8 @@ const (
 	optionNameP2PQUICEnable              = "p2p-quic-enable"
 	optionNameDebugAPIEnable             = "debug-api-enable"
 	optionNameDebugAPIAddr               = "debug-api-addr"
+	optionNameDebugAPIConnectTimeout     = "debug-api-max-connect-timeout"
+	optionNameDebugAPIReadinessMinPeers  = "debug-api-readiness-min-peers"
 	optionNameBootnodes                  = "bootnode"
 	optionNameNetworkID                  = "network-id"
 	optionWelcomeMessage                 = "welcome-message"
@@ -71,6 +73,10 @@ const (
 	optionWarmUpTime                     = "warmup-time"
 	optionNameMainNet                    = "mainnet"
 	optionNameRetrievalCaching           = "cache-retrieval"
+	optionNameStateStoreSweepBudget      = "statestore-sweep-budget"
+	optionNameResetIdentity              = "reset-identity"
+	optionNameShutdownGracePeriod        = "shutdown-grace-period"
+	optionNameAdvertiseWarmupTime        = "advertise-warmup-time"
 )
 
 func init() {
@@ -215,6 +221,8 @@ func (c *command) setAllFlags(cmd *cobra.Command) {
 	cmd.Flags().StringSlice(optionNameBootnodes, []string{"/dnsaddr/testnet.ethswarm.org"}, "initial nodes to connect to")
 	cmd.Flags().Bool(optionNameDebugAPIEnable, false, "enable debug HTTP API")
 	cmd.Flags().String(optionNameDebugAPIAddr, ":1635", "debug HTTP API listen address")
+	cmd.Flags().Duration(optionNameDebugAPIConnectTimeout, time.Minute, "maximal timeout of the connect requests of the debug HTTP API")
+	cmd.Flags().Int(optionNameDebugAPIReadinessMinPeers, 0, "minimal number of connected peers for the debug HTTP API readiness, not checked if zero")
 	cmd.Flags().Uint64(optionNameNetworkID, 10, "ID of the Swarm network")
 	cmd.Flags().StringSlice(optionCORSAllowedOrigins, []string{}, "origins with CORS headers enabled")
 	cmd.Flags().Bool(optionNameTracingEnabled, false, "enable tracing")
@@ -249,6 +257,10 @@ func (c *command) setAllFlags(cmd *cobra.Command) {
 	cmd.Flags().Duration(optionWarmUpTime, time.Minute*20, "time to warmup the node before pull/push protocols can be kicked off.")
 	cmd.Flags().Bool(optionNameMainNet, false, "triggers connect to main net bootnodes.")
 	cmd.Flags().Bool(optionNameRetrievalCaching, true, "enable forwarded content caching")
+	cmd.Flags().Duration(optionNameStateStoreSweepBudget, 5*time.Second, "time to wait for the removal of stale state store records on startup")
+	cmd.Flags().Bool(optionNameResetIdentity, false, "archive the persisted libp2p identity and start with a new one")
+	cmd.Flags().Duration(optionNameShutdownGracePeriod, 5*time.Second, "time to wait for the running protocol handlers on shutdown before they are cancelled")
+	cmd.Flags().Duration(optionNameAdvertiseWarmupTime, 5*time.Minute, "time after the start during which the node does not advertise itself and is not ready, unless its shallow bins are populated sooner")
 }
 
 func newLogger(cmd *cobra.Command, verbosity string) (logging.Logger, error) {
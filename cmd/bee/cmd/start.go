@@ -166,6 +166,8 @@ inability to use, or your interaction with other nodes or the software.`)
 				DBDisableSeeksCompaction:   c.config.GetBool(optionNameDBDisableSeeksCompaction),
 				APIAddr:                    c.config.GetString(optionNameAPIAddr),
 				DebugAPIAddr:               debugAPIAddr,
+				DebugAPIMaxConnectTimeout:  c.config.GetDuration(optionNameDebugAPIConnectTimeout),
+				DebugAPIReadinessMinPeers:  c.config.GetInt(optionNameDebugAPIReadinessMinPeers),
 				Addr:                       c.config.GetString(optionNameP2PAddr),
 				NATAddr:                    c.config.GetString(optionNameNATAddr),
 				EnableWS:                   c.config.GetBool(optionNameP2PWSEnable),
@@ -199,6 +201,11 @@ inability to use, or your interaction with other nodes or the software.`)
 				WarmupTime:                 c.config.GetDuration(optionWarmUpTime),
 				ChainID:                    networkConfig.chainID,
 				RetrievalCaching:           c.config.GetBool(optionNameRetrievalCaching),
+				StateStoreSweepBudget:      c.config.GetDuration(optionNameStateStoreSweepBudget),
+				ShutdownGracePeriod:        c.config.GetDuration(optionNameShutdownGracePeriod),
+				IdentityPassword:           signerConfig.password,
+				ResetIdentity:              c.config.GetBool(optionNameResetIdentity),
+				AdvertiseWarmupTime:        c.config.GetDuration(optionNameAdvertiseWarmupTime),
 			})
 			if err != nil {
 				return err
@@ -293,6 +300,7 @@ type signerConfig struct {
 	publicKey        *ecdsa.PublicKey
 	libp2pPrivateKey *ecdsa.PrivateKey
 	pssPrivateKey    *ecdsa.PrivateKey
+	password         string
 }
 
 func waitForClef(logger logging.Logger, maxRetries uint64, endpoint string) (externalSigner *external.ExternalSigner, err error) {
@@ -434,6 +442,7 @@ func (c *command) configureSigner(cmd *cobra.Command, logger logging.Logger) (co
 		publicKey:        publicKey,
 		libp2pPrivateKey: libp2pPrivateKey,
 		pssPrivateKey:    pssPrivateKey,
+		password:         password,
 	}, nil
 }
 
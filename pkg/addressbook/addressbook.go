@@ -8,21 +8,45 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/ethersphere/bee/pkg/bzz"
 	"github.com/ethersphere/bee/pkg/storage"
 	"github.com/ethersphere/bee/pkg/swarm"
 )
 
-const keyPrefix = "addressbook_entry_"
+const (
+	keyPrefix = "addressbook_entry_"
+
+	// maxRecordAge is the age of a signed record after which
+	// the address is not returned by BinPeers.
+	maxRecordAge = 7 * 24 * time.Hour
+)
+
+// timeNow is used to deterministically mock time.Now() in tests.
+var timeNow = time.Now
 
 var _ Interface = (*store)(nil)
 
-var ErrNotFound = errors.New("addressbook: not found")
+var (
+	ErrNotFound = errors.New("addressbook: not found")
+	// ErrStaleRecord is returned when a discovered address record
+	// is older than the record that is already saved.
+	ErrStaleRecord = errors.New("addressbook: stale record")
+)
+
+func init() {
+	storage.RegisterPrefix(keyPrefix, func(_, value []byte) error {
+		var a bzz.Address
+		return a.UnmarshalJSON(value)
+	})
+}
 
 // Interface is the AddressBook interface.
 type Interface interface {
 	GetPutter
+	DiscoveryPutter
 	Remover
 	// Overlays returns a list of all overlay addresses saved in addressbook.
 	Overlays() ([]swarm.Address, error)
@@ -30,6 +54,9 @@ type Interface interface {
 	IterateOverlays(func(swarm.Address) (bool, error)) error
 	// Addresses returns a list of all bzz.Address-es saved in addressbook.
 	Addresses() ([]bzz.Address, error)
+	// BinPeers returns up to limit saved addresses of the peers
+	// in the proximity order bin of the base address.
+	BinPeers(base swarm.Address, bin uint8, limit int) ([]bzz.Address, error)
 }
 
 type GetPutter interface {
@@ -37,6 +64,13 @@ type GetPutter interface {
 	Putter
 }
 
+// DiscoveryGetPutter is the addressbook interface used
+// by the discovery protocol.
+type DiscoveryGetPutter interface {
+	Getter
+	DiscoveryPutter
+}
+
 type Getter interface {
 	// Get returns pointer to saved bzz.Address for requested overlay address.
 	Get(overlay swarm.Address) (addr *bzz.Address, err error)
@@ -47,6 +81,13 @@ type Putter interface {
 	Put(overlay swarm.Address, addr bzz.Address) (err error)
 }
 
+type DiscoveryPutter interface {
+	// PutDiscovered saves the address learned from a peer other than its
+	// owner. The signed record of the address is verified for the network
+	// and records older than the saved one are refused with ErrStaleRecord.
+	PutDiscovered(addr bzz.Address, networkID uint64) (err error)
+}
+
 type Remover interface {
 	// Remove removes overlay address.
 	Remove(overlay swarm.Address) error
@@ -54,6 +95,7 @@ type Remover interface {
 
 type store struct {
 	store storage.StateStorer
+	mu    sync.Mutex // serializes the record timestamp checks of PutDiscovered
 }
 
 // New creates new addressbook for state storer.
@@ -82,6 +124,31 @@ func (s *store) Put(overlay swarm.Address, addr bzz.Address) (err error) {
 	return s.store.Put(key, &addr)
 }
 
+func (s *store) PutDiscovered(addr bzz.Address, networkID uint64) error {
+	if addr.Record == nil {
+		return fmt.Errorf("no record: %w", bzz.ErrInvalidRecord)
+	}
+	if err := addr.Record.Verify(networkID); err != nil {
+		return err
+	}
+	if !addr.Record.Overlay.Equal(addr.Overlay) || !addr.Record.HasUnderlay(addr.Underlay) {
+		return fmt.Errorf("address does not match the record: %w", bzz.ErrInvalidRecord)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved, err := s.Get(addr.Overlay)
+	switch {
+	case errors.Is(err, ErrNotFound):
+	case err != nil:
+		return err
+	case saved.Record != nil && saved.Record.Timestamp > addr.Record.Timestamp:
+		return ErrStaleRecord
+	}
+	return s.Put(addr.Overlay, addr)
+}
+
 func (s *store) Remove(overlay swarm.Address) error {
 	return s.store.Delete(keyPrefix + overlay.String())
 }
@@ -140,3 +207,46 @@ func (s *store) Addresses() (addresses []bzz.Address, err error) {
 
 	return addresses, nil
 }
+
+// BinPeers returns up to limit addresses of the peers in the proximity order
+// bin of the base address. As the keys end with the hex encoded overlay, only
+// the keys that share the leading hex digits with the base address are
+// iterated. Addresses with records signed longer than maxRecordAge ago are
+// skipped. A limit of zero returns all the addresses in the bin.
+func (s *store) BinPeers(base swarm.Address, bin uint8, limit int) (addresses []bzz.Address, err error) {
+	prefix := keyPrefix
+	if hex := base.String(); int(bin)/4 <= len(hex) {
+		prefix += hex[:bin/4]
+	}
+	now := timeNow()
+
+	err = s.store.Iterate(prefix, func(key, value []byte) (stop bool, err error) {
+		k := string(key)
+		if !strings.HasPrefix(k, prefix) {
+			return true, nil
+		}
+		overlay, err := swarm.ParseHexAddress(strings.TrimPrefix(k, keyPrefix))
+		if err != nil {
+			return true, err
+		}
+		if swarm.Proximity(base.Bytes(), overlay.Bytes()) != bin {
+			return false, nil
+		}
+
+		entry := &bzz.Address{}
+		if err := entry.UnmarshalJSON(value); err != nil {
+			return true, err
+		}
+		if entry.Record != nil && now.Sub(time.Unix(0, entry.Record.Timestamp)) > maxRecordAge {
+			return false, nil
+		}
+
+		addresses = append(addresses, *entry)
+		return limit > 0 && len(addresses) >= limit, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return addresses, nil
+}
@@ -5,7 +5,9 @@
 package addressbook_test
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethersphere/bee/pkg/addressbook"
@@ -13,6 +15,7 @@ import (
 	"github.com/ethersphere/bee/pkg/crypto"
 	"github.com/ethersphere/bee/pkg/statestore/mock"
 	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/ethersphere/bee/pkg/swarm/test"
 
 	ma "github.com/multiformats/go-multiaddr"
 )
@@ -88,3 +91,173 @@ func run(t *testing.T, f bookFunc) {
 		t.Fatalf("expected addresses len %v, got %v", 1, len(addresses))
 	}
 }
+
+func TestPutDiscovered(t *testing.T) {
+	book := addressbook.New(mock.NewStateStore())
+	nonce := common.HexToHash("0x2").Bytes()
+	underlay, err := ma.NewMultiaddr("/ip4/1.1.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pk, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := crypto.NewDefaultSigner(pk)
+	overlay, err := crypto.NewOverlayAddress(pk.PublicKey, 1, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newAddress := func(t *testing.T, timestamp int64) bzz.Address {
+		t.Helper()
+		addr, err := bzz.NewAddress(signer, underlay, overlay, 1, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr.Record, err = bzz.NewRecord(signer, overlay, []ma.Multiaddr{underlay}, 1, nonce, timestamp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *addr
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		addr := newAddress(t, 2)
+		if err := book.PutDiscovered(addr, 1); err != nil {
+			t.Fatal(err)
+		}
+		got, err := book.Get(overlay)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(&addr) {
+			t.Fatalf("got address %s, want %s", got, addr)
+		}
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		addr := newAddress(t, 3)
+		addr.Record.Timestamp++
+		if err := book.PutDiscovered(addr, 1); !errors.Is(err, bzz.ErrInvalidRecord) {
+			t.Fatalf("got error %v, want %v", err, bzz.ErrInvalidRecord)
+		}
+
+		addr = newAddress(t, 3)
+		addr.Underlay, err = ma.NewMultiaddr("/ip4/2.2.2.2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := book.PutDiscovered(addr, 1); !errors.Is(err, bzz.ErrInvalidRecord) {
+			t.Fatalf("got error %v, want %v", err, bzz.ErrInvalidRecord)
+		}
+
+		addr = newAddress(t, 3)
+		addr.Record = nil
+		if err := book.PutDiscovered(addr, 1); !errors.Is(err, bzz.ErrInvalidRecord) {
+			t.Fatalf("got error %v, want %v", err, bzz.ErrInvalidRecord)
+		}
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		if err := book.PutDiscovered(newAddress(t, 1), 1); !errors.Is(err, addressbook.ErrStaleRecord) {
+			t.Fatalf("got error %v, want %v", err, addressbook.ErrStaleRecord)
+		}
+		got, err := book.Get(overlay)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Record.Timestamp != 2 {
+			t.Fatalf("got record timestamp %d, want %d", got.Record.Timestamp, 2)
+		}
+	})
+
+	t.Run("wrong network", func(t *testing.T) {
+		if err := book.PutDiscovered(newAddress(t, 4), 2); !errors.Is(err, bzz.ErrRecordNetworkID) {
+			t.Fatalf("got error %v, want %v", err, bzz.ErrRecordNetworkID)
+		}
+	})
+}
+
+func TestBinPeers(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	addressbook.SetTimeNow(func() time.Time { return now })
+	defer addressbook.SetTimeNow(time.Now)
+
+	book := addressbook.New(mock.NewStateStore())
+	base := test.RandomAddress()
+	underlay, err := ma.NewMultiaddr("/ip4/1.1.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := crypto.NewDefaultSigner(pk)
+
+	put := func(t *testing.T, bin int, recordTime time.Time) swarm.Address {
+		t.Helper()
+		overlay := test.RandomAddressAt(base, bin)
+		addr, err := bzz.NewAddress(signer, underlay, overlay, 1, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !recordTime.IsZero() {
+			addr.Record, err = bzz.NewRecord(signer, overlay, []ma.Multiaddr{underlay}, 1, make([]byte, 32), recordTime.UnixNano())
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := book.Put(overlay, *addr); err != nil {
+			t.Fatal(err)
+		}
+		return overlay
+	}
+
+	bins := map[uint8]int{0: 3, 5: 4, 9: 2, 17: 1}
+	for bin, n := range bins {
+		for i := 0; i < n; i++ {
+			put(t, int(bin), time.Time{})
+		}
+	}
+	// a recent record is returned, a stale one is skipped
+	put(t, 5, now.Add(-time.Hour))
+	stale := put(t, 5, now.Add(-addressbook.MaxRecordAge-time.Second))
+	bins[5]++
+
+	for bin, n := range bins {
+		addrs, err := book.BinPeers(base, bin, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(addrs) != n {
+			t.Fatalf("bin %d: got %d addresses, want %d", bin, len(addrs), n)
+		}
+		for _, a := range addrs {
+			if po := swarm.Proximity(base.Bytes(), a.Overlay.Bytes()); po != bin {
+				t.Fatalf("bin %d: got address %s in bin %d", bin, a.Overlay, po)
+			}
+			if a.Overlay.Equal(stale) {
+				t.Fatalf("bin %d: got stale address %s", bin, stale)
+			}
+		}
+	}
+
+	addrs, err := book.BinPeers(base, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 0 {
+		t.Fatalf("got %d addresses in empty bin, want none", len(addrs))
+	}
+
+	addrs, err = book.BinPeers(base, 5, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 2 {
+		t.Fatalf("got %d addresses, want limit %d", len(addrs), 2)
+	}
+}
@@ -27,12 +27,14 @@ var ErrInvalidAddress = errors.New("invalid address")
 // Address represents the bzz address in swarm.
 // It consists of a peers underlay (physical) address, overlay (topology) address and signature.
 // Signature is used to verify the `Overlay/Underlay` pair, as it is based on `underlay|networkID`, signed with the public key of Overlay address
+// Record is the signed record of the address owner that is gossiped to other peers, if the owner provided one.
 type Address struct {
 	Underlay        ma.Multiaddr
 	Overlay         swarm.Address
 	Signature       []byte
 	Transaction     []byte
 	EthereumAddress []byte
+	Record          *Record
 }
 
 type addressJSON struct {
@@ -40,6 +42,7 @@ type addressJSON struct {
 	Underlay    string `json:"underlay"`
 	Signature   string `json:"signature"`
 	Transaction string `json:"transaction"`
+	Record      []byte `json:"record,omitempty"`
 }
 
 func NewAddress(signer crypto.Signer, underlay ma.Multiaddr, overlay swarm.Address, networkID uint64, trx []byte) (*Address, error) {
@@ -103,15 +106,23 @@ func generateSignData(underlay, overlay []byte, networkID uint64) []byte {
 }
 
 func (a *Address) Equal(b *Address) bool {
-	return a.Overlay.Equal(b.Overlay) && a.Underlay.Equal(b.Underlay) && bytes.Equal(a.Signature, b.Signature) && bytes.Equal(a.Transaction, b.Transaction)
+	return a.Overlay.Equal(b.Overlay) && a.Underlay.Equal(b.Underlay) && bytes.Equal(a.Signature, b.Signature) && bytes.Equal(a.Transaction, b.Transaction) && a.Record.Equal(b.Record)
 }
 
 func (a *Address) MarshalJSON() ([]byte, error) {
+	var record []byte
+	if a.Record != nil {
+		var err error
+		if record, err = a.Record.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
 	return json.Marshal(&addressJSON{
 		Overlay:     a.Overlay.String(),
 		Underlay:    a.Underlay.String(),
 		Signature:   base64.StdEncoding.EncodeToString(a.Signature),
 		Transaction: common.Bytes2Hex(a.Transaction),
+		Record:      record,
 	})
 }
 
@@ -136,8 +147,17 @@ func (a *Address) UnmarshalJSON(b []byte) error {
 
 	a.Underlay = m
 	a.Signature, err = base64.StdEncoding.DecodeString(v.Signature)
+	if err != nil {
+		return err
+	}
 	a.Transaction = common.Hex2Bytes(v.Transaction)
-	return err
+
+	a.Record = nil
+	if len(v.Record) > 0 {
+		a.Record = new(Record)
+		return a.Record.UnmarshalBinary(v.Record)
+	}
+	return nil
 }
 
 func (a *Address) String() string {
@@ -0,0 +1,192 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bzz
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ethersphere/bee/pkg/crypto"
+	"github.com/ethersphere/bee/pkg/swarm"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+const (
+	recordNonceSize     = 32
+	recordSignatureSize = 65
+	// recordHeaderSize is the size of the fixed length fields of a serialised
+	// record: overlay, network id, timestamp, nonce and signature.
+	recordHeaderSize = swarm.HashSize + 8 + 8 + recordNonceSize + recordSignatureSize
+)
+
+var (
+	// ErrInvalidRecord is returned when the record is malformed or its
+	// signature does not match the overlay address.
+	ErrInvalidRecord = errors.New("invalid record")
+	// ErrRecordNetworkID is returned when the record is signed for another network.
+	ErrRecordNetworkID = errors.New("record network id mismatch")
+)
+
+// Record is the signed bzz address record that is gossiped by the discovery
+// protocol. It is signed with the key that the overlay address is derived
+// from together with the nonce, so that any peer can verify it without
+// connecting to its owner. The timestamp, in unix nanoseconds, orders the
+// records of the same overlay, the most recent one is authoritative.
+type Record struct {
+	Overlay   swarm.Address
+	Underlays []ma.Multiaddr
+	NetworkID uint64
+	Nonce     []byte
+	Timestamp int64
+	Signature []byte
+}
+
+// NewRecord creates a record signed with the signer.
+func NewRecord(signer crypto.Signer, overlay swarm.Address, underlays []ma.Multiaddr, networkID uint64, nonce []byte, timestamp int64) (*Record, error) {
+	r := &Record{
+		Overlay:   overlay,
+		Underlays: underlays,
+		NetworkID: networkID,
+		Nonce:     nonce,
+		Timestamp: timestamp,
+	}
+	data, err := r.signData()
+	if err != nil {
+		return nil, err
+	}
+	signature, err := signer.Sign(data)
+	if err != nil {
+		return nil, err
+	}
+	r.Signature = signature
+	return r, nil
+}
+
+// Verify checks that the record is signed for the network by the key that
+// the record overlay address is derived from.
+func (r *Record) Verify(networkID uint64) error {
+	if r.NetworkID != networkID {
+		return ErrRecordNetworkID
+	}
+	if len(r.Underlays) == 0 {
+		return fmt.Errorf("no underlays: %w", ErrInvalidRecord)
+	}
+
+	data, err := r.signData()
+	if err != nil {
+		return err
+	}
+	recoveredPK, err := crypto.Recover(r.Signature, data)
+	if err != nil {
+		return ErrInvalidRecord
+	}
+	recoveredOverlay, err := crypto.NewOverlayAddress(*recoveredPK, r.NetworkID, r.Nonce)
+	if err != nil {
+		return ErrInvalidRecord
+	}
+	if !recoveredOverlay.Equal(r.Overlay) {
+		return ErrInvalidRecord
+	}
+	return nil
+}
+
+// HasUnderlay reports whether the underlay is one of the record underlays.
+func (r *Record) HasUnderlay(underlay ma.Multiaddr) bool {
+	for _, u := range r.Underlays {
+		if u.Equal(underlay) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Record) signData() ([]byte, error) {
+	b, err := r.marshal(nil)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte("bee-record-"), b...), nil
+}
+
+// MarshalBinary serialises the record as the fixed length fields followed by
+// the underlays, each prefixed with its length as a big endian uint16.
+func (r *Record) MarshalBinary() ([]byte, error) {
+	if len(r.Signature) != recordSignatureSize {
+		return nil, ErrInvalidRecord
+	}
+	return r.marshal(r.Signature)
+}
+
+func (r *Record) marshal(signature []byte) ([]byte, error) {
+	buf := make([]byte, recordHeaderSize, recordHeaderSize+64*len(r.Underlays))
+	copy(buf, r.Overlay.Bytes())
+	binary.BigEndian.PutUint64(buf[32:40], r.NetworkID)
+	binary.BigEndian.PutUint64(buf[40:48], uint64(r.Timestamp))
+	copy(buf[48:80], r.Nonce)
+	copy(buf[80:], signature)
+	for _, u := range r.Underlays {
+		b := u.Bytes()
+		if len(b) > 1<<16-1 {
+			return nil, ErrInvalidRecord
+		}
+		buf = append(buf, byte(len(b)>>8), byte(len(b)))
+		buf = append(buf, b...)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary parses a serialised record. The signature is not verified.
+func (r *Record) UnmarshalBinary(buf []byte) error {
+	if len(buf) < recordHeaderSize {
+		return ErrInvalidRecord
+	}
+	var underlays []ma.Multiaddr
+	for rest := buf[recordHeaderSize:]; len(rest) > 0; {
+		if len(rest) < 2 {
+			return ErrInvalidRecord
+		}
+		l := int(binary.BigEndian.Uint16(rest))
+		if len(rest) < 2+l {
+			return ErrInvalidRecord
+		}
+		u, err := ma.NewMultiaddrBytes(rest[2 : 2+l])
+		if err != nil {
+			return fmt.Errorf("underlay: %v: %w", err, ErrInvalidRecord)
+		}
+		underlays = append(underlays, u)
+		rest = rest[2+l:]
+	}
+
+	r.Overlay = swarm.NewAddress(append([]byte(nil), buf[:32]...))
+	r.NetworkID = binary.BigEndian.Uint64(buf[32:40])
+	r.Timestamp = int64(binary.BigEndian.Uint64(buf[40:48]))
+	r.Nonce = append([]byte(nil), buf[48:80]...)
+	r.Signature = append([]byte(nil), buf[80:recordHeaderSize]...)
+	r.Underlays = underlays
+	return nil
+}
+
+// Equal reports whether the records are the same.
+func (r *Record) Equal(o *Record) bool {
+	if r == nil || o == nil {
+		return r == o
+	}
+	if len(r.Underlays) != len(o.Underlays) {
+		return false
+	}
+	for i := range r.Underlays {
+		if !r.Underlays[i].Equal(o.Underlays[i]) {
+			return false
+		}
+	}
+	return r.Overlay.Equal(o.Overlay) &&
+		r.NetworkID == o.NetworkID &&
+		r.Timestamp == o.Timestamp &&
+		bytes.Equal(r.Nonce, o.Nonce) &&
+		bytes.Equal(r.Signature, o.Signature)
+}
@@ -0,0 +1,115 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bzz_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethersphere/bee/pkg/bzz"
+	"github.com/ethersphere/bee/pkg/crypto"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+func TestRecord(t *testing.T) {
+	underlay, err := ma.NewMultiaddr("/ip4/127.0.0.1/tcp/1634/p2p/16Uiu2HAkx8ULY8cTXhdVAcMmLcH9AsTKz6uBQ7DPLKRjMLgBVYkA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := common.HexToHash("0x2").Bytes()
+
+	key, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlay, err := crypto.NewOverlayAddress(key.PublicKey, 3, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := crypto.NewDefaultSigner(key)
+
+	newRecord := func(t *testing.T) *bzz.Record {
+		t.Helper()
+		record, err := bzz.NewRecord(signer, overlay, []ma.Multiaddr{underlay}, 3, nonce, time.Now().UnixNano())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return record
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		record := newRecord(t)
+		if err := record.Verify(3); err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := record.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded bzz.Record
+		if err := decoded.UnmarshalBinary(b); err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.Equal(record) {
+			t.Fatalf("got record %+v, want %+v", decoded, record)
+		}
+		if err := decoded.Verify(3); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		record := newRecord(t)
+		other, err := ma.NewMultiaddr("/ip4/10.0.0.1/tcp/1634")
+		if err != nil {
+			t.Fatal(err)
+		}
+		record.Underlays = []ma.Multiaddr{other}
+		if err := record.Verify(3); !errors.Is(err, bzz.ErrInvalidRecord) {
+			t.Fatalf("got error %v, want %v", err, bzz.ErrInvalidRecord)
+		}
+
+		record = newRecord(t)
+		record.Timestamp++
+		if err := record.Verify(3); !errors.Is(err, bzz.ErrInvalidRecord) {
+			t.Fatalf("got error %v, want %v", err, bzz.ErrInvalidRecord)
+		}
+	})
+
+	t.Run("signed by another key", func(t *testing.T) {
+		otherKey, err := crypto.GenerateSecp256k1Key()
+		if err != nil {
+			t.Fatal(err)
+		}
+		record, err := bzz.NewRecord(crypto.NewDefaultSigner(otherKey), overlay, []ma.Multiaddr{underlay}, 3, nonce, time.Now().UnixNano())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := record.Verify(3); !errors.Is(err, bzz.ErrInvalidRecord) {
+			t.Fatalf("got error %v, want %v", err, bzz.ErrInvalidRecord)
+		}
+	})
+
+	t.Run("wrong network", func(t *testing.T) {
+		if err := newRecord(t).Verify(4); !errors.Is(err, bzz.ErrRecordNetworkID) {
+			t.Fatalf("got error %v, want %v", err, bzz.ErrRecordNetworkID)
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		b, err := newRecord(t).MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded bzz.Record
+		if err := decoded.UnmarshalBinary(b[:len(b)-1]); !errors.Is(err, bzz.ErrInvalidRecord) {
+			t.Fatalf("got error %v, want %v", err, bzz.ErrInvalidRecord)
+		}
+	})
+}
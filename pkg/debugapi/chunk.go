@@ -10,9 +10,14 @@ import (
 	"github.com/ethersphere/bee/pkg/jsonhttp"
 	"github.com/ethersphere/bee/pkg/storage"
 	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/ethersphere/bee/pkg/topology"
 	"github.com/gorilla/mux"
 )
 
+// responsibilityPeers is the number of the closest connected
+// peers reported for a chunk by the responsibility endpoint.
+const responsibilityPeers = 4
+
 func (s *Service) hasChunkHandler(w http.ResponseWriter, r *http.Request) {
 	addr, err := swarm.ParseHexAddress(mux.Vars(r)["address"])
 	if err != nil {
@@ -63,3 +68,79 @@ func (s *Service) removeChunk(w http.ResponseWriter, r *http.Request) {
 	}
 	jsonhttp.OK(w, nil)
 }
+
+type chunkResponsibilityResponse struct {
+	Address      swarm.Address   `json:"address"`
+	Proximity    uint8           `json:"proximity"`
+	Depth        uint8           `json:"depth"`
+	WithinRadius bool            `json:"withinRadius"`
+	ClosestPeers []swarm.Address `json:"closestPeers"`
+}
+
+// chunkResponsibilityHandler reports whether the chunk falls within the
+// neighbourhood of the node and the connected peers closest to the chunk,
+// which the chunk would be forwarded to otherwise.
+func (s *Service) chunkResponsibilityHandler(w http.ResponseWriter, r *http.Request) {
+	addr, err := swarm.ParseHexAddress(mux.Vars(r)["address"])
+	if err != nil {
+		s.logger.Debugf("debug api: chunk responsibility: parse chunk address: %v", err)
+		jsonhttp.BadRequest(w, "bad address")
+		return
+	}
+
+	info, err := s.info.Info()
+	if err != nil {
+		s.logger.Debugf("debug api: chunk responsibility: node info: %v", err)
+		jsonhttp.InternalServerError(w, err)
+		return
+	}
+
+	peers, err := closestPeers(s.topologyDriver, addr, responsibilityPeers)
+	if err != nil {
+		s.logger.Debugf("debug api: chunk responsibility: closest peers: %v", err)
+		jsonhttp.InternalServerError(w, err)
+		return
+	}
+
+	po := swarm.Proximity(info.Overlay.Bytes(), addr.Bytes())
+	_, depth := s.topologyDriver.NeighborhoodDepth()
+	jsonhttp.OK(w, chunkResponsibilityResponse{
+		Address:      addr,
+		Proximity:    po,
+		Depth:        depth,
+		WithinRadius: po >= depth,
+		ClosestPeers: peers,
+	})
+}
+
+// closestPeers returns up to n connected peers closest to the address, the
+// closest first. Only the n closest peers are kept while iterating.
+func closestPeers(peers topology.EachPeerer, addr swarm.Address, n int) ([]swarm.Address, error) {
+	closest := make([]swarm.Address, 0, n+1)
+	err := peers.EachPeer(func(peer swarm.Address, _ uint8) (bool, bool, error) {
+		i := len(closest)
+		for ; i > 0; i-- {
+			cmp, err := swarm.DistanceCmp(addr.Bytes(), peer.Bytes(), closest[i-1].Bytes())
+			if err != nil {
+				return true, false, err
+			}
+			if cmp != 1 {
+				break
+			}
+		}
+		if i == n {
+			return false, false, nil
+		}
+		closest = append(closest, swarm.Address{})
+		copy(closest[i+1:], closest[i:])
+		closest[i] = peer
+		if len(closest) > n {
+			closest = closest[:n]
+		}
+		return false, false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return closest, nil
+}
@@ -7,13 +7,18 @@ package debugapi_test
 import (
 	"context"
 	"net/http"
+	"strings"
 	"testing"
 
+	"github.com/ethersphere/bee/pkg/debugapi"
 	"github.com/ethersphere/bee/pkg/jsonhttp"
 	"github.com/ethersphere/bee/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethersphere/bee/pkg/nodeinfo"
+	nodeinfomock "github.com/ethersphere/bee/pkg/nodeinfo/mock"
 	"github.com/ethersphere/bee/pkg/storage"
 	"github.com/ethersphere/bee/pkg/storage/mock"
 	"github.com/ethersphere/bee/pkg/swarm"
+	topologymock "github.com/ethersphere/bee/pkg/topology/mock"
 )
 
 func TestHasChunkHandler(t *testing.T) {
@@ -90,3 +95,54 @@ func TestHasChunkHandler(t *testing.T) {
 		}
 	})
 }
+
+func TestChunkResponsibility(t *testing.T) {
+	addr := func(prefix string) swarm.Address {
+		return swarm.MustParseHexAddress(prefix + strings.Repeat("00", swarm.HashSize-1))
+	}
+	overlay := addr("00")
+	peers := []swarm.Address{addr("80"), addr("40"), addr("03"), addr("02"), addr("10"), addr("ff")}
+
+	testServer := newTestServer(t, testServerOptions{
+		NodeInfo: nodeinfomock.New(nodeinfomock.WithInfo(nodeinfo.Info{Overlay: overlay})),
+		TopologyOpts: []topologymock.Option{
+			topologymock.WithPeers(peers...),
+			topologymock.WithNeighborhoodDepth(2),
+		},
+	})
+
+	t.Run("within radius", func(t *testing.T) {
+		chunk := addr("01")
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/chunks/"+chunk.String()+"/responsibility", http.StatusOK,
+			jsonhttptest.WithExpectedJSONResponse(debugapi.ChunkResponsibilityResponse{
+				Address:      chunk,
+				Proximity:    7,
+				Depth:        2,
+				WithinRadius: true,
+				ClosestPeers: []swarm.Address{addr("03"), addr("02"), addr("10"), addr("40")},
+			}),
+		)
+	})
+
+	t.Run("outside radius", func(t *testing.T) {
+		chunk := addr("c0")
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/chunks/"+chunk.String()+"/responsibility", http.StatusOK,
+			jsonhttptest.WithExpectedJSONResponse(debugapi.ChunkResponsibilityResponse{
+				Address:      chunk,
+				Proximity:    0,
+				Depth:        2,
+				WithinRadius: false,
+				ClosestPeers: []swarm.Address{addr("ff"), addr("80"), addr("40"), addr("02")},
+			}),
+		)
+	})
+
+	t.Run("bad address", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/chunks/not-an-address/responsibility", http.StatusBadRequest,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusBadRequest,
+				Message: "bad address",
+			}),
+		)
+	})
+}
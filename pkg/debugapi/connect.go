@@ -0,0 +1,127 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/ethersphere/bee/pkg/jsonhttp"
+	"github.com/multiformats/go-multiaddr"
+)
+
+const (
+	// bulkConnectWorkers is the number of the underlays
+	// of a bulk connect request which are dialed concurrently.
+	bulkConnectWorkers = 8
+	// bulkConnectMaxUnderlays is the maximal number
+	// of the underlays of a bulk connect request.
+	bulkConnectMaxUnderlays = 1000
+)
+
+// errConnectSkipped is the result of the underlays which are not
+// dialed as another one failed and the request fails fast.
+var errConnectSkipped = errors.New("skipped after a failed connection")
+
+type bulkConnectRequest struct {
+	Underlays []string `json:"underlays"`
+}
+
+type bulkConnectResult struct {
+	Underlay string `json:"underlay"`
+	Address  string `json:"address,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type bulkConnectResponse struct {
+	Results []bulkConnectResult `json:"results"`
+}
+
+// bulkConnectHandler connects to the peers with the underlays of the request
+// concurrently and returns the result of each of them in the order of the
+// request. The remaining underlays are not dialed after the first failure if
+// the failfast query parameter is true.
+func (s *Service) bulkConnectHandler(w http.ResponseWriter, r *http.Request) {
+	var failFast bool
+	if v := r.URL.Query().Get("failfast"); v != "" {
+		var err error
+		if failFast, err = strconv.ParseBool(v); err != nil {
+			s.logger.Debugf("debug api: bulk connect: parse failfast %q: %v", v, err)
+			jsonhttp.BadRequest(w, "invalid failfast")
+			return
+		}
+	}
+
+	var req bulkConnectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.logger.Debugf("debug api: bulk connect: read request: %v", err)
+		jsonhttp.BadRequest(w, "invalid request")
+		return
+	}
+	if len(req.Underlays) == 0 || len(req.Underlays) > bulkConnectMaxUnderlays {
+		jsonhttp.BadRequest(w, "invalid number of underlays")
+		return
+	}
+
+	addrs := make([]multiaddr.Multiaddr, len(req.Underlays))
+	for i, u := range req.Underlays {
+		addr, err := multiaddr.NewMultiaddr(u)
+		if err != nil {
+			s.logger.Debugf("debug api: bulk connect: parse underlay %q: %v", u, err)
+			jsonhttp.BadRequest(w, "invalid underlay "+u)
+			return
+		}
+		addrs[i] = addr
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	results := make([]bulkConnectResult, len(addrs))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < bulkConnectWorkers && i < len(addrs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = s.bulkConnect(ctx, addrs[i])
+				if results[i].Error != "" && failFast {
+					cancel()
+				}
+			}
+		}()
+	}
+	for i := range addrs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	jsonhttp.OK(w, bulkConnectResponse{Results: results})
+}
+
+// bulkConnect connects to the peer with the underlay, unless the bulk
+// connect request failed fast, and returns the result of the connection.
+func (s *Service) bulkConnect(ctx context.Context, addr multiaddr.Multiaddr) bulkConnectResult {
+	result := bulkConnectResult{Underlay: addr.String()}
+	if ctx.Err() != nil {
+		result.Error = errConnectSkipped.Error()
+		return result
+	}
+
+	overlay, err := s.connect(ctx, addr)
+	if err != nil {
+		s.logger.Debugf("debug api: bulk connect %s: %v", addr, err)
+		result.Error = err.Error()
+		return result
+	}
+	result.Address = overlay.String()
+	return result
+}
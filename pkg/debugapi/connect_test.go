@@ -0,0 +1,146 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/bzz"
+	"github.com/ethersphere/bee/pkg/debugapi"
+	"github.com/ethersphere/bee/pkg/jsonhttp"
+	"github.com/ethersphere/bee/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethersphere/bee/pkg/p2p"
+	"github.com/ethersphere/bee/pkg/p2p/mock"
+	"github.com/ethersphere/bee/pkg/swarm"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+func TestBulkConnect(t *testing.T) {
+	overlay := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c")
+	connectedOverlay := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59d")
+	underlay := "/ip4/127.0.0.1/tcp/1634"
+	connectedUnderlay := "/ip4/127.0.0.2/tcp/1634"
+	errorUnderlay := "/ip4/127.0.0.3/tcp/1634"
+	testErr := errors.New("test error")
+
+	testServer := newTestServer(t, testServerOptions{
+		P2P: mock.New(mock.WithConnectFunc(func(ctx context.Context, addr ma.Multiaddr) (*bzz.Address, error) {
+			switch addr.String() {
+			case underlay:
+				return &bzz.Address{Overlay: overlay, Underlay: addr}, nil
+			case connectedUnderlay:
+				return &bzz.Address{Overlay: connectedOverlay, Underlay: addr}, p2p.ErrAlreadyConnected
+			case errorUnderlay:
+				return nil, testErr
+			}
+			// the other peers do not respond
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})),
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/connect", http.StatusOK,
+			jsonhttptest.WithJSONRequestBody(debugapi.BulkConnectRequest{
+				Underlays: []string{underlay, errorUnderlay, connectedUnderlay},
+			}),
+			jsonhttptest.WithExpectedJSONResponse(debugapi.BulkConnectResponse{
+				Results: []debugapi.BulkConnectResult{
+					{Underlay: underlay, Address: overlay.String()},
+					{Underlay: errorUnderlay, Error: testErr.Error()},
+					{Underlay: connectedUnderlay, Address: connectedOverlay.String()},
+				},
+			}),
+		)
+	})
+
+	t.Run("failfast", func(t *testing.T) {
+		// the unresponsive peers occupy the other workers, so that the
+		// last underlays are dialed only after the first one failed
+		underlays := []string{errorUnderlay}
+		for i := 0; i < 10; i++ {
+			underlays = append(underlays, fmt.Sprintf("/ip4/10.0.0.1/tcp/%d", 1634+i))
+		}
+
+		var resp debugapi.BulkConnectResponse
+		jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/connect?failfast=true", http.StatusOK,
+			jsonhttptest.WithJSONRequestBody(debugapi.BulkConnectRequest{
+				Underlays: underlays,
+			}),
+			jsonhttptest.WithUnmarshalJSONResponse(&resp),
+		)
+
+		if len(resp.Results) != len(underlays) {
+			t.Fatalf("got %d results, want %d", len(resp.Results), len(underlays))
+		}
+		if want := (debugapi.BulkConnectResult{Underlay: errorUnderlay, Error: testErr.Error()}); resp.Results[0] != want {
+			t.Fatalf("got result %+v, want %+v", resp.Results[0], want)
+		}
+		var skipped int
+		for i, r := range resp.Results[1:] {
+			if r.Underlay != underlays[i+1] {
+				t.Fatalf("got underlay %s, want %s", r.Underlay, underlays[i+1])
+			}
+			if r.Address != "" || r.Error == "" {
+				t.Fatalf("got result %+v, want an error", r)
+			}
+			if strings.HasPrefix(r.Error, "skipped") {
+				skipped++
+			}
+		}
+		if skipped == 0 {
+			t.Fatal("got no skipped underlays")
+		}
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		for _, tc := range []struct {
+			name    string
+			path    string
+			body    jsonhttptest.Option
+			message string
+		}{
+			{
+				name:    "malformed body",
+				path:    "/connect",
+				body:    jsonhttptest.WithRequestBody(strings.NewReader("{")),
+				message: "invalid request",
+			},
+			{
+				name:    "no underlays",
+				path:    "/connect",
+				body:    jsonhttptest.WithJSONRequestBody(debugapi.BulkConnectRequest{}),
+				message: "invalid number of underlays",
+			},
+			{
+				name:    "invalid underlay",
+				path:    "/connect",
+				body:    jsonhttptest.WithJSONRequestBody(debugapi.BulkConnectRequest{Underlays: []string{underlay, "invalid"}}),
+				message: "invalid underlay invalid",
+			},
+			{
+				name:    "invalid failfast",
+				path:    "/connect?failfast=maybe",
+				body:    jsonhttptest.WithJSONRequestBody(debugapi.BulkConnectRequest{Underlays: []string{underlay}}),
+				message: "invalid failfast",
+			},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				jsonhttptest.Request(t, testServer.Client, http.MethodPost, tc.path, http.StatusBadRequest,
+					tc.body,
+					jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+						Code:    http.StatusBadRequest,
+						Message: tc.message,
+					}),
+				)
+			})
+		}
+	})
+}
@@ -0,0 +1,48 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ethersphere/bee/pkg/storage"
+)
+
+func (s *Service) dbCheckHandler(w http.ResponseWriter, r *http.Request) {
+	s.startJob(w, "db check", func(ctx context.Context) (interface{}, error) {
+		report, err := s.stateStoreMaintainer.CheckIntegrity(ctx)
+		if err != nil {
+			s.logger.Debugf("debug api: db check: %v", err)
+			s.logger.Error("state store integrity check failed")
+			return report, err
+		}
+		return report, nil
+	})
+}
+
+func (s *Service) dbCompactHandler(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	s.startJob(w, "db compact", func(ctx context.Context) (interface{}, error) {
+		if err := s.stateStoreMaintainer.Compact(prefix); err != nil {
+			s.logger.Debugf("debug api: db compact %q: %v", prefix, err)
+			s.logger.Error("state store compaction failed")
+			return nil, err
+		}
+		return nil, nil
+	})
+}
+
+func (s *Service) dbSweepHandler(w http.ResponseWriter, r *http.Request) {
+	s.startJob(w, "db sweep", func(ctx context.Context) (interface{}, error) {
+		report, err := storage.RunSweeps(ctx, s.stateStore)
+		if err != nil {
+			s.logger.Debugf("debug api: db sweep: %v", err)
+			s.logger.Error("state store sweep failed")
+			return report, err
+		}
+		return report, nil
+	})
+}
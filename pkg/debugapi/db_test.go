@@ -0,0 +1,178 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/debugapi"
+	"github.com/ethersphere/bee/pkg/jsonhttp"
+	"github.com/ethersphere/bee/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethersphere/bee/pkg/statestore/mock"
+	"github.com/ethersphere/bee/pkg/storage"
+)
+
+type mockMaintainer struct {
+	report     storage.IntegrityReport
+	compactErr error
+	compacted  chan string
+}
+
+func (m *mockMaintainer) CheckIntegrity(context.Context) (storage.IntegrityReport, error) {
+	return m.report, nil
+}
+
+func (m *mockMaintainer) Compact(prefix string) error {
+	m.compacted <- prefix
+	return m.compactErr
+}
+
+func TestDBEndpoints(t *testing.T) {
+	maintainer := &mockMaintainer{
+		report: storage.IntegrityReport{
+			Keys: 10,
+			Namespaces: map[string]*storage.NamespaceReport{
+				"blocklist-": {Keys: 3, BadKeys: 1},
+			},
+		},
+		compacted: make(chan string, 1),
+	}
+
+	testServer := newTestServer(t, testServerOptions{
+		StateStoreMaintainer: maintainer,
+	})
+
+	t.Run("check", func(t *testing.T) {
+		var started debugapi.JobStartedResponse
+		jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/db/check", http.StatusAccepted,
+			jsonhttptest.WithUnmarshalJSONResponse(&started),
+		)
+
+		job := waitJob(t, testServer.Client, started.ID)
+		if job.Status != "done" {
+			t.Fatalf("got job status %q, want %q", job.Status, "done")
+		}
+		result := job.Result.(map[string]interface{})
+		if keys := result["keys"].(float64); keys != 10 {
+			t.Fatalf("got %v keys, want %v", keys, 10)
+		}
+		ns := result["namespaces"].(map[string]interface{})["blocklist-"].(map[string]interface{})
+		if bad := ns["badKeys"].(float64); bad != 1 {
+			t.Fatalf("got %v bad keys, want %v", bad, 1)
+		}
+	})
+
+	t.Run("compact", func(t *testing.T) {
+		var started debugapi.JobStartedResponse
+		jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/db/compact?prefix=blocklist-", http.StatusAccepted,
+			jsonhttptest.WithUnmarshalJSONResponse(&started),
+		)
+		if prefix := <-maintainer.compacted; prefix != "blocklist-" {
+			t.Fatalf("got compacted prefix %q, want %q", prefix, "blocklist-")
+		}
+
+		job := waitJob(t, testServer.Client, started.ID)
+		if job.Status != "done" {
+			t.Fatalf("got job status %q, want %q", job.Status, "done")
+		}
+	})
+
+	t.Run("compact error", func(t *testing.T) {
+		maintainer := &mockMaintainer{
+			compactErr: errors.New("compaction error"),
+			compacted:  make(chan string, 1),
+		}
+		testServer := newTestServer(t, testServerOptions{
+			StateStoreMaintainer: maintainer,
+		})
+
+		var started debugapi.JobStartedResponse
+		jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/db/compact", http.StatusAccepted,
+			jsonhttptest.WithUnmarshalJSONResponse(&started),
+		)
+		<-maintainer.compacted
+
+		job := waitJob(t, testServer.Client, started.ID)
+		if job.Status != "failed" || job.Error != "compaction error" {
+			t.Fatalf("got job status %q with error %q", job.Status, job.Error)
+		}
+	})
+
+	t.Run("unknown job", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/jobs/1000", http.StatusNotFound,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusNotFound,
+				Message: "job not found",
+			}),
+		)
+	})
+}
+
+func TestDBEndpointsDisabled(t *testing.T) {
+	testServer := newTestServer(t, testServerOptions{})
+
+	jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/db/check", http.StatusNotFound)
+	jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/db/compact", http.StatusNotFound)
+	jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/db/sweep", http.StatusNotFound)
+}
+
+func TestDBSweep(t *testing.T) {
+	const prefix = "debugapi-sweep-test-"
+	storage.RegisterSweep(prefix, func(ctx context.Context, store storage.StateStorer) (int, error) {
+		if err := store.Delete(prefix + "stale"); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	})
+
+	store := mock.NewStateStore()
+	if err := store.Put(prefix+"stale", "value"); err != nil {
+		t.Fatal(err)
+	}
+	testServer := newTestServer(t, testServerOptions{
+		StateStorer: store,
+	})
+
+	var started debugapi.JobStartedResponse
+	jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/db/sweep", http.StatusAccepted,
+		jsonhttptest.WithUnmarshalJSONResponse(&started),
+	)
+
+	job := waitJob(t, testServer.Client, started.ID)
+	if job.Status != "done" {
+		t.Fatalf("got job status %q, want %q", job.Status, "done")
+	}
+	result := job.Result.(map[string]interface{})["sweeps"].(map[string]interface{})[prefix].(map[string]interface{})
+	if removed := result["removed"].(float64); removed != 1 {
+		t.Fatalf("got %v removed, want %v", removed, 1)
+	}
+
+	var v string
+	if err := store.Get(prefix+"stale", &v); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, storage.ErrNotFound)
+	}
+}
+
+func waitJob(t *testing.T, client *http.Client, id uint64) debugapi.JobResponse {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		var job debugapi.JobResponse
+		jsonhttptest.Request(t, client, http.MethodGet, fmt.Sprintf("/jobs/%d", id), http.StatusOK,
+			jsonhttptest.WithUnmarshalJSONResponse(&job),
+		)
+		if job.Status != "running" {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %d did not finish", id)
+	return debugapi.JobResponse{}
+}
@@ -11,10 +11,13 @@ import (
 	"crypto/ecdsa"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethersphere/bee/pkg/accounting"
 	"github.com/ethersphere/bee/pkg/logging"
+	"github.com/ethersphere/bee/pkg/metrics"
+	"github.com/ethersphere/bee/pkg/nodeinfo"
 	"github.com/ethersphere/bee/pkg/p2p"
 	"github.com/ethersphere/bee/pkg/pingpong"
 	"github.com/ethersphere/bee/pkg/postage"
@@ -23,18 +26,16 @@ import (
 	"github.com/ethersphere/bee/pkg/settlement/swap"
 	"github.com/ethersphere/bee/pkg/settlement/swap/chequebook"
 	"github.com/ethersphere/bee/pkg/storage"
-	"github.com/ethersphere/bee/pkg/swarm"
 	"github.com/ethersphere/bee/pkg/tags"
 	"github.com/ethersphere/bee/pkg/topology"
 	"github.com/ethersphere/bee/pkg/topology/lightnode"
 	"github.com/ethersphere/bee/pkg/tracing"
 	"github.com/ethersphere/bee/pkg/transaction"
-	"github.com/prometheus/client_golang/prometheus"
 )
 
 // Service implements http.Handler interface to be used in HTTP server.
 type Service struct {
-	overlay            *swarm.Address
+	info               nodeinfo.Provider
 	publicKey          ecdsa.PublicKey
 	pssPublicKey       ecdsa.PublicKey
 	ethereumAddress    common.Address
@@ -55,8 +56,21 @@ type Service struct {
 	postageContract    postagecontract.Interface
 	logger             logging.Logger
 	corsAllowedOrigins []string
-	metricsRegistry    *prometheus.Registry
+	metricsRegistry    *metrics.Registry
 	lightNodes         *lightnode.Container
+	// stateStoreMaintainer is nil if the state store
+	// does not support maintenance operations
+	stateStoreMaintainer storage.StateStoreMaintainer
+	stateStore           storage.StateStorer
+	selfTest             SelfTestOptions
+	maxConnectTimeout    time.Duration
+	jobs                 *jobs
+	// blocklister is nil if the peers can not
+	// be blocklisted on demand
+	blocklister    Blocklister
+	healthCheckers []HealthChecker
+	// shuttingDown is set to 1 when the node is shutting down
+	shuttingDown int32
 	// handler is changed in the Configure method
 	handler   http.Handler
 	handlerMu sync.RWMutex
@@ -65,8 +79,10 @@ type Service struct {
 // New creates a new Debug API Service with only basic routers enabled in order
 // to expose /addresses, /health endpoints, Go metrics and pprof. It is useful to expose
 // these endpoints before all dependencies are configured and injected to have
-// access to basic debugging tools and /health endpoint.
-func New(publicKey, pssPublicKey ecdsa.PublicKey, ethereumAddress common.Address, logger logging.Logger, tracer *tracing.Tracer, corsAllowedOrigins []string, transaction transaction.Service) *Service {
+// access to basic debugging tools and /health endpoint. The metrics of the
+// components registered in the metrics registry are exposed on /metrics,
+// a new registry is created if it is nil.
+func New(publicKey, pssPublicKey ecdsa.PublicKey, ethereumAddress common.Address, logger logging.Logger, tracer *tracing.Tracer, corsAllowedOrigins []string, transaction transaction.Service, metricsRegistry *metrics.Registry) *Service {
 	s := new(Service)
 	s.publicKey = publicKey
 	s.pssPublicKey = pssPublicKey
@@ -74,8 +90,10 @@ func New(publicKey, pssPublicKey ecdsa.PublicKey, ethereumAddress common.Address
 	s.logger = logger
 	s.tracer = tracer
 	s.corsAllowedOrigins = corsAllowedOrigins
-	s.metricsRegistry = newMetricsRegistry()
+	s.metricsRegistry = newMetricsRegistry(metricsRegistry)
 	s.transaction = transaction
+	s.jobs = newJobs()
+	s.maxConnectTimeout = defaultMaxConnectTimeout
 
 	s.setRouter(s.newBasicRouter())
 
@@ -85,7 +103,7 @@ func New(publicKey, pssPublicKey ecdsa.PublicKey, ethereumAddress common.Address
 // Configure injects required dependencies and configuration parameters and
 // constructs HTTP routes that depend on them. It is intended and safe to call
 // this method only once.
-func (s *Service) Configure(overlay swarm.Address, p2p p2p.DebugService, pingpong pingpong.Interface, topologyDriver topology.Driver, lightNodes *lightnode.Container, storer storage.Storer, tags *tags.Tags, accounting accounting.Interface, pseudosettle settlement.Interface, chequebookEnabled bool, swap swap.Interface, chequebook chequebook.Service, batchStore postage.Storer, post postage.Service, postageContract postagecontract.Interface) {
+func (s *Service) Configure(info nodeinfo.Provider, p2p p2p.DebugService, pingpong pingpong.Interface, topologyDriver topology.Driver, lightNodes *lightnode.Container, storer storage.Storer, tags *tags.Tags, accounting accounting.Interface, pseudosettle settlement.Interface, chequebookEnabled bool, swap swap.Interface, chequebook chequebook.Service, batchStore postage.Storer, post postage.Service, postageContract postagecontract.Interface, stateStoreMaintainer storage.StateStoreMaintainer, stateStore storage.StateStorer) {
 	s.p2p = p2p
 	s.pingpong = pingpong
 	s.topologyDriver = topologyDriver
@@ -98,9 +116,11 @@ func (s *Service) Configure(overlay swarm.Address, p2p p2p.DebugService, pingpon
 	s.lightNodes = lightNodes
 	s.batchStore = batchStore
 	s.pseudosettle = pseudosettle
-	s.overlay = &overlay
+	s.info = info
 	s.post = post
 	s.postageContract = postageContract
+	s.stateStoreMaintainer = stateStoreMaintainer
+	s.stateStore = stateStore
 
 	s.setRouter(s.newRouter())
 }
@@ -13,6 +13,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethersphere/bee"
@@ -22,6 +23,8 @@ import (
 	"github.com/ethersphere/bee/pkg/jsonhttp"
 	"github.com/ethersphere/bee/pkg/jsonhttp/jsonhttptest"
 	"github.com/ethersphere/bee/pkg/logging"
+	"github.com/ethersphere/bee/pkg/nodeinfo"
+	nodeinfomock "github.com/ethersphere/bee/pkg/nodeinfo/mock"
 	p2pmock "github.com/ethersphere/bee/pkg/p2p/mock"
 	"github.com/ethersphere/bee/pkg/pingpong"
 	"github.com/ethersphere/bee/pkg/postage"
@@ -52,30 +55,37 @@ func init() {
 }
 
 type testServerOptions struct {
-	Overlay            swarm.Address
-	PublicKey          ecdsa.PublicKey
-	PSSPublicKey       ecdsa.PublicKey
-	EthereumAddress    common.Address
-	CORSAllowedOrigins []string
-	P2P                *p2pmock.Service
-	Pingpong           pingpong.Interface
-	Storer             storage.Storer
-	Resolver           resolver.Interface
-	TopologyOpts       []topologymock.Option
-	Tags               *tags.Tags
-	AccountingOpts     []accountingmock.Option
-	SettlementOpts     []swapmock.Option
-	ChequebookOpts     []chequebookmock.Option
-	SwapOpts           []swapmock.Option
-	BatchStore         postage.Storer
-	TransactionOpts    []transactionmock.Option
-	PostageContract    postagecontract.Interface
-	Post               postage.Service
+	NodeInfo             *nodeinfomock.Provider
+	PublicKey            ecdsa.PublicKey
+	PSSPublicKey         ecdsa.PublicKey
+	EthereumAddress      common.Address
+	CORSAllowedOrigins   []string
+	P2P                  *p2pmock.Service
+	Pingpong             pingpong.Interface
+	Storer               storage.Storer
+	Resolver             resolver.Interface
+	TopologyOpts         []topologymock.Option
+	Tags                 *tags.Tags
+	AccountingOpts       []accountingmock.Option
+	SettlementOpts       []swapmock.Option
+	ChequebookOpts       []chequebookmock.Option
+	SwapOpts             []swapmock.Option
+	BatchStore           postage.Storer
+	TransactionOpts      []transactionmock.Option
+	PostageContract      postagecontract.Interface
+	Post                 postage.Service
+	StateStoreMaintainer storage.StateStoreMaintainer
+	StateStorer          storage.StateStorer
+	SelfTest             debugapi.SelfTestOptions
+	MaxConnectTimeout    time.Duration
+	Blocklister          debugapi.Blocklister
+	HealthCheckers       []debugapi.HealthChecker
 }
 
 type testServer struct {
 	Client  *http.Client
 	P2PMock *p2pmock.Service
+	Service *debugapi.Service
 }
 
 func newTestServer(t *testing.T, o testServerOptions) *testServer {
@@ -85,9 +95,19 @@ func newTestServer(t *testing.T, o testServerOptions) *testServer {
 	chequebook := chequebookmock.NewChequebook(o.ChequebookOpts...)
 	swapserv := swapmock.New(o.SwapOpts...)
 	transaction := transactionmock.New(o.TransactionOpts...)
-	ln := lightnode.NewContainer(o.Overlay)
-	s := debugapi.New(o.PublicKey, o.PSSPublicKey, o.EthereumAddress, logging.New(ioutil.Discard, 0), nil, o.CORSAllowedOrigins, transaction)
-	s.Configure(o.Overlay, o.P2P, o.Pingpong, topologyDriver, ln, o.Storer, o.Tags, acc, settlement, true, swapserv, chequebook, o.BatchStore, o.Post, o.PostageContract)
+	if o.NodeInfo == nil {
+		o.NodeInfo = nodeinfomock.New()
+	}
+	info, _ := o.NodeInfo.Info()
+	ln := lightnode.NewContainer(info.Overlay)
+	s := debugapi.New(o.PublicKey, o.PSSPublicKey, o.EthereumAddress, logging.New(ioutil.Discard, 0), nil, o.CORSAllowedOrigins, transaction, nil)
+	s.SetSelfTest(o.SelfTest)
+	s.SetMaxConnectTimeout(o.MaxConnectTimeout)
+	s.SetBlocklister(o.Blocklister)
+	for _, c := range o.HealthCheckers {
+		s.AddHealthChecker(c)
+	}
+	s.Configure(o.NodeInfo, o.P2P, o.Pingpong, topologyDriver, ln, o.Storer, o.Tags, acc, settlement, true, swapserv, chequebook, o.BatchStore, o.Post, o.PostageContract, o.StateStoreMaintainer, o.StateStorer)
 	ts := httptest.NewServer(s)
 	t.Cleanup(ts.Close)
 
@@ -104,6 +124,7 @@ func newTestServer(t *testing.T, o testServerOptions) *testServer {
 	return &testServer{
 		Client:  client,
 		P2PMock: o.P2P,
+		Service: s,
 	}
 }
 
@@ -141,20 +162,21 @@ func TestServer_Configure(t *testing.T) {
 	o := testServerOptions{
 		PublicKey:       privateKey.PublicKey,
 		PSSPublicKey:    pssPrivateKey.PublicKey,
-		Overlay:         overlay,
 		EthereumAddress: ethereumAddress,
-		P2P: p2pmock.New(p2pmock.WithAddressesFunc(func() ([]multiaddr.Multiaddr, error) {
-			return addresses, nil
+		NodeInfo: nodeinfomock.New(nodeinfomock.WithInfo(nodeinfo.Info{
+			Overlay:   overlay,
+			Underlays: addresses,
 		})),
+		P2P: p2pmock.New(),
 	}
 	topologyDriver := topologymock.NewTopologyDriver(o.TopologyOpts...)
 	acc := accountingmock.NewAccounting(o.AccountingOpts...)
 	settlement := swapmock.New(o.SettlementOpts...)
 	chequebook := chequebookmock.NewChequebook(o.ChequebookOpts...)
 	swapserv := swapmock.New(o.SwapOpts...)
-	ln := lightnode.NewContainer(o.Overlay)
+	ln := lightnode.NewContainer(overlay)
 	transaction := transactionmock.New(o.TransactionOpts...)
-	s := debugapi.New(o.PublicKey, o.PSSPublicKey, o.EthereumAddress, logging.New(ioutil.Discard, 0), nil, nil, transaction)
+	s := debugapi.New(o.PublicKey, o.PSSPublicKey, o.EthereumAddress, logging.New(ioutil.Discard, 0), nil, nil, transaction, nil)
 	ts := httptest.NewServer(s)
 	t.Cleanup(ts.Close)
 
@@ -185,7 +207,7 @@ func TestServer_Configure(t *testing.T) {
 		}),
 	)
 
-	s.Configure(o.Overlay, o.P2P, o.Pingpong, topologyDriver, ln, o.Storer, o.Tags, acc, settlement, true, swapserv, chequebook, nil, mockpost.New(), nil)
+	s.Configure(o.NodeInfo, o.P2P, o.Pingpong, topologyDriver, ln, o.Storer, o.Tags, acc, settlement, true, swapserv, chequebook, nil, mockpost.New(), nil, nil, nil)
 
 	testBasicRouter(t, client)
 	jsonhttptest.Request(t, client, http.MethodGet, "/readiness", http.StatusOK,
@@ -196,7 +218,7 @@ func TestServer_Configure(t *testing.T) {
 	)
 	jsonhttptest.Request(t, client, http.MethodGet, "/addresses", http.StatusOK,
 		jsonhttptest.WithExpectedJSONResponse(debugapi.AddressesResponse{
-			Overlay:      &o.Overlay,
+			Overlay:      &overlay,
 			Underlay:     addresses,
 			Ethereum:     o.EthereumAddress,
 			PublicKey:    hex.EncodeToString(crypto.EncodeSecp256k1PublicKey(&o.PublicKey)),
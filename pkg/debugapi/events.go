@@ -0,0 +1,77 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/jsonhttp"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+// peerEventsKeepAliveInterval is the interval of the comments which keep
+// the peer events stream alive while there are no events.
+var peerEventsKeepAliveInterval = 15 * time.Second
+
+type peerEventResponse struct {
+	Overlay   swarm.Address `json:"overlay"`
+	Type      string        `json:"type"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// peerEventsHandler streams the peers that connect, disconnect or are
+// blocklisted as server-sent events until the client goes away.
+func (s *Service) peerEventsHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.logger.Debug("debug api: peer events: streaming not supported")
+		s.logger.Error("unable to stream peer events")
+		jsonhttp.InternalServerError(w, "streaming not supported")
+		return
+	}
+
+	events, unsubscribe := s.p2p.SubscribePeerEvents()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(peerEventsKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(peerEventResponse{
+				Overlay:   e.Overlay,
+				Type:      e.Type.String(),
+				Timestamp: e.Timestamp,
+			})
+			if err != nil {
+				s.logger.Debugf("debug api: peer events: marshal event: %v", err)
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
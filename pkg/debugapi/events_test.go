@@ -0,0 +1,132 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/debugapi"
+	"github.com/ethersphere/bee/pkg/p2p"
+	"github.com/ethersphere/bee/pkg/p2p/mock"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+func TestPeerEvents(t *testing.T) {
+	defer func(d time.Duration) {
+		*debugapi.PeerEventsKeepAliveInterval = d
+	}(*debugapi.PeerEventsKeepAliveInterval)
+	*debugapi.PeerEventsKeepAliveInterval = 50 * time.Millisecond
+
+	overlay := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c")
+	timestamp := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	events := make(chan p2p.PeerEvent)
+	unsubscribed := make(chan struct{})
+	var once sync.Once
+	testServer := newTestServer(t, testServerOptions{
+		P2P: mock.New(mock.WithSubscribePeerEventsFunc(func() (<-chan p2p.PeerEvent, func()) {
+			return events, func() { once.Do(func() { close(unsubscribed) }) }
+		})),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/events/peers", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := testServer.Client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("got content type %q, want %q", got, "text/event-stream")
+	}
+
+	body := bufio.NewReader(resp.Body)
+
+	// readMessage returns the lines of the next message, which are not comments
+	readMessage := func(t *testing.T) (lines []string, comments int) {
+		t.Helper()
+
+		for {
+			line, err := body.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "":
+				if len(lines) > 0 {
+					return lines, comments
+				}
+			case strings.HasPrefix(line, ":"):
+				comments++
+			default:
+				lines = append(lines, line)
+			}
+		}
+	}
+
+	t.Run("keep-alive", func(t *testing.T) {
+		// the keep-alive comments are sent while there are no events
+		var comments int
+		for comments < 2 {
+			line, err := body.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if line == ": keep-alive\n" {
+				comments++
+			}
+		}
+	})
+
+	t.Run("events", func(t *testing.T) {
+		for _, e := range []p2p.PeerEvent{
+			{Overlay: overlay, Type: p2p.PeerEventConnected, Timestamp: timestamp},
+			{Overlay: overlay, Type: p2p.PeerEventBlocklisted, Timestamp: timestamp.Add(time.Second)},
+			{Overlay: overlay, Type: p2p.PeerEventDisconnected, Timestamp: timestamp.Add(2 * time.Second)},
+		} {
+			events <- e
+
+			data, err := json.Marshal(debugapi.PeerEventResponse{
+				Overlay:   e.Overlay,
+				Type:      e.Type.String(),
+				Timestamp: e.Timestamp,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []string{"event: " + e.Type.String(), "data: " + string(data)}
+
+			got, _ := readMessage(t)
+			if strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Fatalf("got message %q, want %q", got, want)
+			}
+		}
+	})
+
+	// the subscription ends when the client goes away
+	cancel()
+	select {
+	case <-unsubscribed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription not ended")
+	}
+}
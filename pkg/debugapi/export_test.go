@@ -6,9 +6,24 @@ package debugapi
 
 type (
 	StatusResponse                    = statusResponse
+	NodeResponse                      = nodeResponse
+	WarmupResponse                    = warmupResponse
+	ChunkResponsibilityResponse       = chunkResponsibilityResponse
 	PingpongResponse                  = pingpongResponse
 	PeerConnectResponse               = peerConnectResponse
+	BulkConnectRequest                = bulkConnectRequest
+	BulkConnectResult                 = bulkConnectResult
+	BulkConnectResponse               = bulkConnectResponse
 	PeersResponse                     = peersResponse
+	ConnectedPeer                     = connectedPeer
+	BlockedPeersResponse              = blockedPeersResponse
+	BlockedPeer                       = blockedPeer
+	ClearBlocklistResponse            = clearBlocklistResponse
+	PeerResponse                      = peerResponse
+	BulkDisconnectRequest             = bulkDisconnectRequest
+	BulkDisconnectResult              = bulkDisconnectResult
+	BulkDisconnectResponse            = bulkDisconnectResponse
+	PeerEventResponse                 = peerEventResponse
 	AddressesResponse                 = addressesResponse
 	WelcomeMessageRequest             = welcomeMessageRequest
 	WelcomeMessageResponse            = welcomeMessageResponse
@@ -36,6 +51,15 @@ type (
 	PostageStampsResponse             = postageStampsResponse
 	PostageStampBucketsResponse       = postageStampBucketsResponse
 	BucketData                        = bucketData
+	JobStartedResponse                = jobStartedResponse
+	JobResponse                       = jobResponse
+	SelfTestResponse                  = selfTestResponse
+	SelfTestCheck                     = selfTestCheck
+	ReadinessResponse                 = readinessResponse
+	HealthCheckResponse               = healthCheckResponse
+	TopologySuggestionsResponse       = topologySuggestionsResponse
+	BinSuggestionsResponse            = binSuggestionsResponse
+	ConnectSuggestionResponse         = connectSuggestionResponse
 )
 
 var (
@@ -51,3 +75,8 @@ var (
 	ErrCantResendTransaction = errCantResendTransaction
 	ErrAlreadyImported       = errAlreadyImported
 )
+
+var (
+	PeerEventsKeepAliveInterval = &peerEventsKeepAliveInterval
+	HealthCheckTimeout          = &healthCheckTimeout
+)
@@ -0,0 +1,182 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethersphere/bee"
+	"github.com/ethersphere/bee/pkg/jsonhttp"
+	"github.com/ethersphere/bee/pkg/p2p"
+	"github.com/ethersphere/bee/pkg/storage"
+)
+
+// healthCheckTimeout bounds the duration of all health checks of a readiness request.
+var healthCheckTimeout = 5 * time.Second
+
+// healthCheckKey is the state store key read by the state store health check.
+const healthCheckKey = "healthcheck"
+
+// HealthChecker checks a component of the node for the readiness endpoint.
+type HealthChecker interface {
+	// Name is the name of the check in the readiness response.
+	Name() string
+	// Check returns an error if the component is not ready.
+	Check(ctx context.Context) error
+}
+
+type healthChecker struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+func (c healthChecker) Name() string                    { return c.name }
+func (c healthChecker) Check(ctx context.Context) error { return c.check(ctx) }
+
+// NewHealthChecker returns the HealthChecker with the name that calls check.
+func NewHealthChecker(name string, check func(ctx context.Context) error) HealthChecker {
+	return healthChecker{name: name, check: check}
+}
+
+// NewListenHealthChecker returns the HealthChecker which passes
+// when the p2p service listens on at least one address.
+func NewListenHealthChecker(p p2p.DebugService) HealthChecker {
+	return NewHealthChecker("p2p listening", func(_ context.Context) error {
+		addrs, err := p.Addresses()
+		if err != nil {
+			return err
+		}
+		if len(addrs) == 0 {
+			return errors.New("no listen addresses")
+		}
+		return nil
+	})
+}
+
+// NewStateStoreHealthChecker returns the HealthChecker which
+// passes when the state store can be read from.
+func NewStateStoreHealthChecker(store storage.StateStorer) HealthChecker {
+	return NewHealthChecker("statestore", func(_ context.Context) error {
+		var v interface{}
+		if err := store.Get(healthCheckKey, &v); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+		return nil
+	})
+}
+
+// NewPeersHealthChecker returns the HealthChecker which passes
+// when the node is connected to at least min peers.
+func NewPeersHealthChecker(p p2p.DebugService, min int) HealthChecker {
+	return NewHealthChecker("peers", func(_ context.Context) error {
+		if n := len(p.Peers()); n < min {
+			return fmt.Errorf("connected to %d peers, want at least %d", n, min)
+		}
+		return nil
+	})
+}
+
+// AddHealthChecker adds the check to the checks of the readiness
+// endpoint. It must be called before Configure.
+func (s *Service) AddHealthChecker(c HealthChecker) {
+	s.healthCheckers = append(s.healthCheckers, c)
+}
+
+// SetShuttingDown marks the node as shutting down, after which
+// the liveness and the readiness endpoints report that the
+// service is unavailable.
+func (s *Service) SetShuttingDown() {
+	atomic.StoreInt32(&s.shuttingDown, 1)
+}
+
+func (s *Service) isShuttingDown() bool {
+	return atomic.LoadInt32(&s.shuttingDown) == 1
+}
+
+type healthCheckResponse struct {
+	Name  string `json:"name"`
+	Pass  bool   `json:"pass"`
+	Error string `json:"error,omitempty"`
+}
+
+type readinessResponse struct {
+	Status  string                `json:"status"`
+	Version string                `json:"version"`
+	Checks  []healthCheckResponse `json:"checks,omitempty"`
+}
+
+// healthHandler reports that the process is alive unless it is shutting down.
+func (s *Service) healthHandler(w http.ResponseWriter, r *http.Request) {
+	if s.isShuttingDown() {
+		jsonhttp.ServiceUnavailable(w, "shutting down")
+		return
+	}
+	statusHandler(w, r)
+}
+
+// readinessHandler reports that the node is ready once its
+// information, including the underlay addresses, is available,
+// it is no longer warming up after the start and all of the
+// registered health checks pass.
+func (s *Service) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	if s.isShuttingDown() {
+		jsonhttp.ServiceUnavailable(w, "shutting down")
+		return
+	}
+	info, err := s.info.Info()
+	if err != nil {
+		s.logger.Debugf("debug api: readiness: node info: %v", err)
+		jsonhttp.ServiceUnavailable(w, nil)
+		return
+	}
+	if info.Warmup.Active {
+		jsonhttp.ServiceUnavailable(w, "warming up")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+
+	// the checks run concurrently, so that a slow one
+	// does not delay the others
+	checks := make([]healthCheckResponse, len(s.healthCheckers))
+	var wg sync.WaitGroup
+	for i, c := range s.healthCheckers {
+		wg.Add(1)
+		go func(i int, c HealthChecker) {
+			defer wg.Done()
+
+			checks[i] = healthCheckResponse{Name: c.Name(), Pass: true}
+			if err := c.Check(ctx); err != nil {
+				checks[i].Pass = false
+				checks[i].Error = err.Error()
+			}
+		}(i, c)
+	}
+	wg.Wait()
+
+	resp := readinessResponse{
+		Status:  "ok",
+		Version: bee.Version,
+		Checks:  checks,
+	}
+	for _, c := range checks {
+		if !c.Pass {
+			s.logger.Debugf("debug api: readiness: check %s: %s", c.Name, c.Error)
+			resp.Status = "unavailable"
+		}
+	}
+	if resp.Status != "ok" {
+		jsonhttp.ServiceUnavailable(w, resp)
+		return
+	}
+	jsonhttp.OK(w, resp)
+}
@@ -0,0 +1,198 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee"
+	"github.com/ethersphere/bee/pkg/debugapi"
+	"github.com/ethersphere/bee/pkg/jsonhttp"
+	"github.com/ethersphere/bee/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethersphere/bee/pkg/p2p"
+	p2pmock "github.com/ethersphere/bee/pkg/p2p/mock"
+	"github.com/ethersphere/bee/pkg/statestore/mock"
+	"github.com/ethersphere/bee/pkg/storage"
+	"github.com/ethersphere/bee/pkg/swarm"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// unreachableStateStore fails all reads.
+type unreachableStateStore struct {
+	storage.StateStorer
+}
+
+func (unreachableStateStore) Get(string, interface{}) error {
+	return errors.New("unreachable")
+}
+
+func TestReadinessHealthCheckers(t *testing.T) {
+	var ready int32
+	testServer := newTestServer(t, testServerOptions{
+		HealthCheckers: []debugapi.HealthChecker{
+			debugapi.NewHealthChecker("flipping", func(context.Context) error {
+				if atomic.LoadInt32(&ready) == 0 {
+					return errors.New("not ready")
+				}
+				return nil
+			}),
+			debugapi.NewHealthChecker("passing", func(context.Context) error {
+				return nil
+			}),
+		},
+	})
+
+	notReady := debugapi.ReadinessResponse{
+		Status:  "unavailable",
+		Version: bee.Version,
+		Checks: []debugapi.HealthCheckResponse{
+			{Name: "flipping", Error: "not ready"},
+			{Name: "passing", Pass: true},
+		},
+	}
+
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/readiness", http.StatusServiceUnavailable,
+		jsonhttptest.WithExpectedJSONResponse(notReady),
+	)
+
+	atomic.StoreInt32(&ready, 1)
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/readiness", http.StatusOK,
+		jsonhttptest.WithExpectedJSONResponse(debugapi.ReadinessResponse{
+			Status:  "ok",
+			Version: bee.Version,
+			Checks: []debugapi.HealthCheckResponse{
+				{Name: "flipping", Pass: true},
+				{Name: "passing", Pass: true},
+			},
+		}),
+	)
+
+	atomic.StoreInt32(&ready, 0)
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/readiness", http.StatusServiceUnavailable,
+		jsonhttptest.WithExpectedJSONResponse(notReady),
+	)
+
+	// the liveness does not depend on the checks
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/health", http.StatusOK,
+		jsonhttptest.WithExpectedJSONResponse(debugapi.StatusResponse{
+			Status:  "ok",
+			Version: bee.Version,
+		}),
+	)
+}
+
+func TestReadinessHealthCheckTimeout(t *testing.T) {
+	defer func(d time.Duration) {
+		*debugapi.HealthCheckTimeout = d
+	}(*debugapi.HealthCheckTimeout)
+	*debugapi.HealthCheckTimeout = 10 * time.Millisecond
+
+	testServer := newTestServer(t, testServerOptions{
+		HealthCheckers: []debugapi.HealthChecker{
+			debugapi.NewHealthChecker("blocking", func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}),
+		},
+	})
+
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/readiness", http.StatusServiceUnavailable,
+		jsonhttptest.WithExpectedJSONResponse(debugapi.ReadinessResponse{
+			Status:  "unavailable",
+			Version: bee.Version,
+			Checks: []debugapi.HealthCheckResponse{
+				{Name: "blocking", Error: context.DeadlineExceeded.Error()},
+			},
+		}),
+	)
+}
+
+func TestShuttingDown(t *testing.T) {
+	testServer := newTestServer(t, testServerOptions{})
+
+	for _, path := range []string{"/health", "/readiness"} {
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, path, http.StatusOK)
+	}
+
+	testServer.Service.SetShuttingDown()
+
+	for _, path := range []string{"/health", "/readiness"} {
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, path, http.StatusServiceUnavailable,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusServiceUnavailable,
+				Message: "shutting down",
+			}),
+		)
+	}
+}
+
+func TestHealthCheckers(t *testing.T) {
+	addr, err := ma.NewMultiaddr("/ip4/127.0.0.1/tcp/1634")
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer := p2p.Peer{Address: swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c")}
+
+	for _, tc := range []struct {
+		name    string
+		checker debugapi.HealthChecker
+		wantErr bool
+	}{
+		{
+			name: "listening",
+			checker: debugapi.NewListenHealthChecker(p2pmock.New(p2pmock.WithAddressesFunc(func() ([]ma.Multiaddr, error) {
+				return []ma.Multiaddr{addr}, nil
+			}))),
+		},
+		{
+			name: "not listening",
+			checker: debugapi.NewListenHealthChecker(p2pmock.New(p2pmock.WithAddressesFunc(func() ([]ma.Multiaddr, error) {
+				return nil, nil
+			}))),
+			wantErr: true,
+		},
+		{
+			name: "addresses error",
+			checker: debugapi.NewListenHealthChecker(p2pmock.New(p2pmock.WithAddressesFunc(func() ([]ma.Multiaddr, error) {
+				return nil, errors.New("test error")
+			}))),
+			wantErr: true,
+		},
+		{
+			name:    "statestore reachable",
+			checker: debugapi.NewStateStoreHealthChecker(mock.NewStateStore()),
+		},
+		{
+			name:    "statestore unreachable",
+			checker: debugapi.NewStateStoreHealthChecker(unreachableStateStore{mock.NewStateStore()}),
+			wantErr: true,
+		},
+		{
+			name: "enough peers",
+			checker: debugapi.NewPeersHealthChecker(p2pmock.New(p2pmock.WithPeersFunc(func() []p2p.Peer {
+				return []p2p.Peer{peer}
+			})), 1),
+		},
+		{
+			name: "not enough peers",
+			checker: debugapi.NewPeersHealthChecker(p2pmock.New(p2pmock.WithPeersFunc(func() []p2p.Peer {
+				return []p2p.Peer{peer}
+			})), 2),
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.checker.Check(context.Background())
+			if got := err != nil; got != tc.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/jsonhttp"
+	"github.com/gorilla/mux"
+)
+
+// maxJobs is the number of jobs whose results are kept around.
+const maxJobs = 100
+
+const (
+	jobStatusRunning = "running"
+	jobStatusDone    = "done"
+	jobStatusFailed  = "failed"
+)
+
+// jobFunc is a long running operation executed in the background.
+type jobFunc func(ctx context.Context) (result interface{}, err error)
+
+type jobResponse struct {
+	ID       uint64      `json:"id"`
+	Name     string      `json:"name"`
+	Status   string      `json:"status"`
+	Error    string      `json:"error,omitempty"`
+	Result   interface{} `json:"result,omitempty"`
+	Started  time.Time   `json:"started"`
+	Finished *time.Time  `json:"finished,omitempty"`
+}
+
+// jobs runs operations that take too long to be served within a single
+// request and keeps their results so that they can be polled.
+type jobs struct {
+	mtx  sync.Mutex
+	next uint64
+	jobs map[uint64]*jobResponse
+}
+
+func newJobs() *jobs {
+	return &jobs{
+		jobs: make(map[uint64]*jobResponse),
+	}
+}
+
+// start runs f in a new goroutine and returns the job id.
+func (j *jobs) start(name string, f jobFunc) uint64 {
+	j.mtx.Lock()
+	j.next++
+	id := j.next
+	j.jobs[id] = &jobResponse{
+		ID:      id,
+		Name:    name,
+		Status:  jobStatusRunning,
+		Started: time.Now(),
+	}
+	// forget the oldest jobs
+	for old := id - maxJobs; old > 0; old-- {
+		if _, ok := j.jobs[old]; !ok {
+			break
+		}
+		delete(j.jobs, old)
+	}
+	j.mtx.Unlock()
+
+	go func() {
+		result, err := f(context.Background())
+
+		j.mtx.Lock()
+		defer j.mtx.Unlock()
+
+		r, ok := j.jobs[id]
+		if !ok {
+			return
+		}
+		now := time.Now()
+		r.Finished = &now
+		r.Result = result
+		r.Status = jobStatusDone
+		if err != nil {
+			r.Status = jobStatusFailed
+			r.Error = err.Error()
+		}
+	}()
+
+	return id
+}
+
+// get returns a copy of the job state.
+func (j *jobs) get(id uint64) (jobResponse, bool) {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+
+	r, ok := j.jobs[id]
+	if !ok {
+		return jobResponse{}, false
+	}
+	return *r, true
+}
+
+type jobStartedResponse struct {
+	ID uint64 `json:"id"`
+}
+
+func (s *Service) startJob(w http.ResponseWriter, name string, f jobFunc) {
+	jsonhttp.Accepted(w, jobStartedResponse{
+		ID: s.jobs.start(name, f),
+	})
+}
+
+func (s *Service) jobHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := mux.Vars(r)["id"]
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		s.logger.Debugf("debug api: job: parse id %s: %v", idStr, err)
+		jsonhttp.BadRequest(w, "invalid job id")
+		return
+	}
+
+	job, ok := s.jobs.get(id)
+	if !ok {
+		jsonhttp.NotFound(w, "job not found")
+		return
+	}
+
+	jsonhttp.OK(w, job)
+}
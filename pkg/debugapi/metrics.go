@@ -11,8 +11,10 @@ import (
 	"github.com/prometheus/client_golang/prometheus/collectors"
 )
 
-func newMetricsRegistry() (r *prometheus.Registry) {
-	r = prometheus.NewRegistry()
+func newMetricsRegistry(r *metrics.Registry) *metrics.Registry {
+	if r == nil {
+		r = metrics.NewRegistry()
+	}
 
 	// register standard metrics
 	r.MustRegister(
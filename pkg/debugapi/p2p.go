@@ -24,21 +24,25 @@ type addressesResponse struct {
 }
 
 func (s *Service) addressesHandler(w http.ResponseWriter, r *http.Request) {
-	// initialize variable to json encode as [] instead null if p2p is nil
+	var overlay *swarm.Address
+	// initialize variable to json encode as [] instead null if node info is nil
 	underlay := make([]multiaddr.Multiaddr, 0)
-	// addresses endpoint is exposed before p2p service is configured
+	// addresses endpoint is exposed before node info is configured
 	// to provide information about other addresses.
-	if s.p2p != nil {
-		u, err := s.p2p.Addresses()
+	if s.info != nil {
+		info, err := s.info.Info()
 		if err != nil {
-			s.logger.Debugf("debug api: p2p addresses: %v", err)
+			s.logger.Debugf("debug api: node info: %v", err)
 			jsonhttp.InternalServerError(w, err)
 			return
 		}
-		underlay = u
+		overlay = &info.Overlay
+		if info.Underlays != nil {
+			underlay = info.Underlays
+		}
 	}
 	jsonhttp.OK(w, addressesResponse{
-		Overlay:      s.overlay,
+		Overlay:      overlay,
 		Underlay:     underlay,
 		Ethereum:     s.ethereumAddress,
 		PublicKey:    hex.EncodeToString(crypto.EncodeSecp256k1PublicKey(&s.publicKey)),
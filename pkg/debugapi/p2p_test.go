@@ -15,7 +15,8 @@ import (
 	"github.com/ethersphere/bee/pkg/debugapi"
 	"github.com/ethersphere/bee/pkg/jsonhttp"
 	"github.com/ethersphere/bee/pkg/jsonhttp/jsonhttptest"
-	"github.com/ethersphere/bee/pkg/p2p/mock"
+	"github.com/ethersphere/bee/pkg/nodeinfo"
+	nodeinfomock "github.com/ethersphere/bee/pkg/nodeinfo/mock"
 	"github.com/ethersphere/bee/pkg/swarm"
 	"github.com/multiformats/go-multiaddr"
 )
@@ -41,10 +42,10 @@ func TestAddresses(t *testing.T) {
 	testServer := newTestServer(t, testServerOptions{
 		PublicKey:       privateKey.PublicKey,
 		PSSPublicKey:    pssPrivateKey.PublicKey,
-		Overlay:         overlay,
 		EthereumAddress: ethereumAddress,
-		P2P: mock.New(mock.WithAddressesFunc(func() ([]multiaddr.Multiaddr, error) {
-			return addresses, nil
+		NodeInfo: nodeinfomock.New(nodeinfomock.WithInfo(nodeinfo.Info{
+			Overlay:   overlay,
+			Underlays: addresses,
 		})),
 	})
 
@@ -74,9 +75,7 @@ func TestAddresses_error(t *testing.T) {
 	testErr := errors.New("test error")
 
 	testServer := newTestServer(t, testServerOptions{
-		P2P: mock.New(mock.WithAddressesFunc(func() ([]multiaddr.Multiaddr, error) {
-			return nil, testErr
-		})),
+		NodeInfo: nodeinfomock.New(nodeinfomock.WithError(testErr)),
 	})
 
 	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/addresses", http.StatusInternalServerError,
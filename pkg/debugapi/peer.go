@@ -5,20 +5,42 @@
 package debugapi
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ethersphere/bee/pkg/jsonhttp"
 	"github.com/ethersphere/bee/pkg/p2p"
+	"github.com/ethersphere/bee/pkg/storage"
 	"github.com/ethersphere/bee/pkg/swarm"
 	"github.com/gorilla/mux"
 	"github.com/multiformats/go-multiaddr"
 )
 
+// defaultMaxConnectTimeout caps the timeout of the connect
+// requests if no other maximum is set.
+const defaultMaxConnectTimeout = time.Minute
+
 type peerConnectResponse struct {
 	Address string `json:"address"`
 }
 
+// SetMaxConnectTimeout sets the maximal timeout which can be requested
+// by the timeout query parameter of the connect endpoint, longer timeouts
+// are capped to it. It must be called before Configure.
+func (s *Service) SetMaxConnectTimeout(d time.Duration) {
+	if d <= 0 {
+		d = defaultMaxConnectTimeout
+	}
+	s.maxConnectTimeout = d
+}
+
 func (s *Service) peerConnectHandler(w http.ResponseWriter, r *http.Request) {
 	addr, err := multiaddr.NewMultiaddr("/" + mux.Vars(r)["multi-address"])
 	if err != nil {
@@ -27,27 +49,58 @@ func (s *Service) peerConnectHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	bzzAddr, err := s.p2p.Connect(r.Context(), addr)
-	if err != nil {
-		s.logger.Debugf("debug api: peer connect %s: %v", addr, err)
-		s.logger.Errorf("unable to connect to peer %s", addr)
-		jsonhttp.InternalServerError(w, err)
-		return
+	ctx := r.Context()
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil || timeout <= 0 {
+			s.logger.Debugf("debug api: peer connect: parse timeout %q: %v", v, err)
+			jsonhttp.BadRequest(w, "invalid timeout")
+			return
+		}
+		if timeout > s.maxConnectTimeout {
+			timeout = s.maxConnectTimeout
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
 
-	if err := s.topologyDriver.Connected(r.Context(), p2p.Peer{Address: bzzAddr.Overlay}, true); err != nil {
-		_ = s.p2p.Disconnect(bzzAddr.Overlay)
-		s.logger.Debugf("debug api: peer connect handler %s: %v", addr, err)
+	overlay, err := s.connect(ctx, addr)
+	if err != nil {
+		s.logger.Debugf("debug api: peer connect %s: %v", addr, err)
 		s.logger.Errorf("unable to connect to peer %s", addr)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			jsonhttp.GatewayTimeout(w, nil)
+			return
+		}
 		jsonhttp.InternalServerError(w, err)
 		return
 	}
 
 	jsonhttp.OK(w, peerConnectResponse{
-		Address: bzzAddr.Overlay.String(),
+		Address: overlay.String(),
 	})
 }
 
+// connect connects to the peer with the underlay and adds it to the
+// topology. Connecting to an already connected peer is not an error,
+// so that the connect requests can be retried.
+func (s *Service) connect(ctx context.Context, addr multiaddr.Multiaddr) (swarm.Address, error) {
+	bzzAddr, err := s.p2p.Connect(ctx, addr)
+	if errors.Is(err, p2p.ErrAlreadyConnected) && bzzAddr != nil {
+		return bzzAddr.Overlay, nil
+	}
+	if err != nil {
+		return swarm.ZeroAddress, err
+	}
+
+	if err := s.topologyDriver.Connected(ctx, p2p.Peer{Address: bzzAddr.Overlay}, true); err != nil {
+		_ = s.p2p.Disconnect(bzzAddr.Overlay)
+		return swarm.ZeroAddress, err
+	}
+	return bzzAddr.Overlay, nil
+}
+
 func (s *Service) peerDisconnectHandler(w http.ResponseWriter, r *http.Request) {
 	addr := mux.Vars(r)["address"]
 	swarmAddr, err := swarm.ParseHexAddress(addr)
@@ -71,20 +124,274 @@ func (s *Service) peerDisconnectHandler(w http.ResponseWriter, r *http.Request)
 	jsonhttp.OK(w, nil)
 }
 
+// The outcomes of the disconnections of the bulk disconnect requests.
+const (
+	disconnectOK       = "ok"
+	disconnectNotFound = "not found"
+	disconnectError    = "error"
+)
+
+type bulkDisconnectRequest struct {
+	Addresses []string `json:"addresses"`
+}
+
+type bulkDisconnectResult struct {
+	Address swarm.Address `json:"address"`
+	Status  string        `json:"status"`
+	Error   string        `json:"error,omitempty"`
+}
+
+type bulkDisconnectResponse struct {
+	Results []bulkDisconnectResult `json:"results"`
+}
+
+// bulkDisconnectHandler disconnects the peers with the addresses of the
+// request, or all connected peers if the all query parameter is true, and
+// returns the outcome of each disconnection. The request is rejected before
+// any peer is disconnected if any of the addresses is invalid.
+func (s *Service) bulkDisconnectHandler(w http.ResponseWriter, r *http.Request) {
+	var all bool
+	if v := r.URL.Query().Get("all"); v != "" {
+		var err error
+		if all, err = strconv.ParseBool(v); err != nil {
+			s.logger.Debugf("debug api: bulk disconnect: parse all %q: %v", v, err)
+			jsonhttp.BadRequest(w, "invalid all")
+			return
+		}
+	}
+
+	var overlays []swarm.Address
+	if all {
+		for _, p := range s.p2p.Peers() {
+			overlays = append(overlays, p.Address)
+		}
+	} else {
+		var req bulkDisconnectRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.logger.Debugf("debug api: bulk disconnect: read request: %v", err)
+			jsonhttp.BadRequest(w, "invalid request")
+			return
+		}
+		if len(req.Addresses) == 0 {
+			jsonhttp.BadRequest(w, "no peer addresses")
+			return
+		}
+
+		var invalid []string
+		for _, a := range req.Addresses {
+			overlay, err := swarm.ParseHexAddress(a)
+			if err != nil {
+				invalid = append(invalid, a)
+				continue
+			}
+			overlays = append(overlays, overlay)
+		}
+		if len(invalid) > 0 {
+			s.logger.Debugf("debug api: bulk disconnect: invalid peer addresses %v", invalid)
+			jsonhttp.BadRequest(w, "invalid peer addresses: "+strings.Join(invalid, ", "))
+			return
+		}
+	}
+
+	resp := bulkDisconnectResponse{Results: make([]bulkDisconnectResult, 0, len(overlays))}
+	for _, overlay := range overlays {
+		result := bulkDisconnectResult{Address: overlay, Status: disconnectOK}
+		if err := s.p2p.Disconnect(overlay); err != nil {
+			s.logger.Debugf("debug api: bulk disconnect %s: %v", overlay, err)
+			if errors.Is(err, p2p.ErrPeerNotFound) {
+				result.Status = disconnectNotFound
+			} else {
+				result.Status = disconnectError
+				result.Error = err.Error()
+			}
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	jsonhttp.OK(w, resp)
+}
+
 // Peer holds information about a Peer.
 type Peer struct {
 	Address  swarm.Address `json:"address"`
 	FullNode bool          `json:"fullNode"`
 }
 
+type peerResponse struct {
+	Peer
+	Connected   bool                  `json:"connected"`
+	PO          uint8                 `json:"po"`
+	OpenStreams int                   `json:"openStreams"`
+	Underlays   []multiaddr.Multiaddr `json:"underlays,omitempty"`
+	Direction   string                `json:"direction,omitempty"`
+	ConnectedAt *time.Time            `json:"connectedAt,omitempty"`
+}
+
+func (s *Service) peerHandler(w http.ResponseWriter, r *http.Request) {
+	addr := mux.Vars(r)["address"]
+	swarmAddr, err := swarm.ParseHexAddress(addr)
+	if err != nil {
+		s.logger.Debugf("debug api: parse peer address %s: %v", addr, err)
+		jsonhttp.BadRequest(w, "invalid peer address")
+		return
+	}
+
+	var (
+		peer  p2p.Peer
+		found bool
+	)
+	for _, p := range s.p2p.Peers() {
+		if p.Address.Equal(swarmAddr) {
+			peer, found = p, true
+			break
+		}
+	}
+	if !found {
+		jsonhttp.NotFound(w, "peer not found")
+		return
+	}
+
+	streams, err := s.p2p.OpenStreams(swarmAddr)
+	if err != nil {
+		s.logger.Debugf("debug api: peer %s: open streams: %v", addr, err)
+		if errors.Is(err, p2p.ErrPeerNotFound) {
+			jsonhttp.NotFound(w, "peer not found")
+			return
+		}
+		s.logger.Errorf("unable to get peer %s", addr)
+		jsonhttp.InternalServerError(w, err)
+		return
+	}
+
+	conn, err := s.p2p.ConnectionInfo(swarmAddr)
+	if err != nil {
+		s.logger.Debugf("debug api: peer %s: connection info: %v", addr, err)
+		if errors.Is(err, p2p.ErrPeerNotFound) {
+			jsonhttp.NotFound(w, "peer not found")
+			return
+		}
+		s.logger.Errorf("unable to get peer %s", addr)
+		jsonhttp.InternalServerError(w, err)
+		return
+	}
+
+	info, err := s.info.Info()
+	if err != nil {
+		s.logger.Debugf("debug api: peer %s: node info: %v", addr, err)
+		s.logger.Errorf("unable to get peer %s", addr)
+		jsonhttp.InternalServerError(w, err)
+		return
+	}
+
+	resp := peerResponse{
+		Peer: Peer{
+			Address:  peer.Address,
+			FullNode: peer.FullNode,
+		},
+		Connected:   true,
+		PO:          swarm.Proximity(info.Overlay.Bytes(), peer.Address.Bytes()),
+		OpenStreams: streams,
+		Underlays:   conn.Underlays,
+		Direction:   conn.Direction,
+	}
+	if !conn.Opened.IsZero() {
+		connectedAt := conn.Opened
+		resp.ConnectedAt = &connectedAt
+	}
+	jsonhttp.OK(w, resp)
+}
+
+// connectedPeer is a connected peer with its proximity order
+// to the overlay of the node.
+type connectedPeer struct {
+	Peer
+	PO uint8 `json:"po"`
+}
+
+const (
+	// defaultPeersLimit is the number of the peers
+	// listed if the limit is not given.
+	defaultPeersLimit = 100
+	// maxPeersLimit is the greatest allowed limit
+	// of the number of the listed peers.
+	maxPeersLimit = 1000
+)
+
 type peersResponse struct {
-	Peers []Peer `json:"peers"`
+	// Total is the number of all connected peers.
+	Total int             `json:"total"`
+	Peers []connectedPeer `json:"peers"`
+	// Bins are the numbers of the peers by their proximity
+	// orders, the empty bins are omitted.
+	Bins map[uint8]int `json:"bins"`
 }
 
+// peersHandler lists the page of the connected peers, sorted by their
+// overlays, given by the offset and limit query parameters.
 func (s *Service) peersHandler(w http.ResponseWriter, r *http.Request) {
-	jsonhttp.OK(w, peersResponse{
-		Peers: mapPeers(s.p2p.Peers()),
+	offset, limit := 0, defaultPeersLimit
+	if v := r.URL.Query().Get("offset"); v != "" {
+		var err error
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			s.logger.Debugf("debug api: peers: parse offset %s: %v", v, err)
+			jsonhttp.BadRequest(w, "invalid offset")
+			return
+		}
+	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > maxPeersLimit {
+			s.logger.Debugf("debug api: peers: parse limit %s: %v", v, err)
+			jsonhttp.BadRequest(w, "invalid limit")
+			return
+		}
+	}
+
+	info, err := s.info.Info()
+	if err != nil {
+		s.logger.Debugf("debug api: peers: node info: %v", err)
+		s.logger.Error("unable to get peers")
+		jsonhttp.InternalServerError(w, err)
+		return
+	}
+
+	peers := mapPeers(s.p2p.Peers())
+	// the pages are consistent while the peers do not change
+	sort.Slice(peers, func(i, j int) bool {
+		return bytes.Compare(peers[i].Address.Bytes(), peers[j].Address.Bytes()) < 0
 	})
+
+	resp := peersResponse{
+		Total: len(peers),
+		Peers: make([]connectedPeer, 0),
+		Bins:  make(map[uint8]int),
+	}
+	for i, p := range peers {
+		po := swarm.Proximity(info.Overlay.Bytes(), p.Address.Bytes())
+		resp.Bins[po]++
+		if i >= offset && i < offset+limit {
+			resp.Peers = append(resp.Peers, connectedPeer{Peer: p, PO: po})
+		}
+	}
+	jsonhttp.OK(w, resp)
+}
+
+type blockedPeer struct {
+	Address   swarm.Address `json:"address"`
+	FullNode  bool          `json:"fullNode"`
+	Timestamp time.Time     `json:"timestamp"`
+	Duration  string        `json:"duration"`
+	Remaining string        `json:"remaining"`
+	Permanent bool          `json:"permanent"`
+	// Attempts is the number of rejected connection
+	// attempts of the peer while it was blocklisted.
+	Attempts    uint64                `json:"attempts"`
+	LastAttempt *time.Time            `json:"lastAttempt,omitempty"`
+	Reason      string                `json:"reason,omitempty"`
+	Underlays   []multiaddr.Multiaddr `json:"underlays,omitempty"`
+}
+
+type blockedPeersResponse struct {
+	Peers []blockedPeer `json:"peers"`
 }
 
 func (s *Service) blocklistedPeersHandler(w http.ResponseWriter, r *http.Request) {
@@ -95,9 +402,132 @@ func (s *Service) blocklistedPeersHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	jsonhttp.OK(w, peersResponse{
-		Peers: mapPeers(peers),
-	})
+	var resp blockedPeersResponse
+	for _, p := range peers {
+		resp.Peers = append(resp.Peers, newBlockedPeer(p))
+	}
+	jsonhttp.OK(w, resp)
+}
+
+// Blocklister blocklists the peers on demand of the operator.
+type Blocklister interface {
+	// BlocklistPeer blocklists the peer for the duration, permanently if
+	// it is zero, and returns its blocklist entry. The peer is disconnected
+	// only if disconnect is true.
+	BlocklistPeer(overlay swarm.Address, duration time.Duration, reason string, disconnect bool) (p2p.BlockedPeer, error)
+	// UnblocklistPeer removes the peer from the blocklist. It returns
+	// storage.ErrNotFound if the peer is not blocklisted.
+	UnblocklistPeer(overlay swarm.Address) error
+	// ClearBlocklist removes all peers from the blocklist and
+	// returns the number of the removed entries.
+	ClearBlocklist() (removed int, err error)
+}
+
+type clearBlocklistResponse struct {
+	Removed int `json:"removed"`
+}
+
+// SetBlocklister sets the blocklister of the blocklist endpoint, which is
+// not exposed if it is not set. It must be called before Configure.
+func (s *Service) SetBlocklister(b Blocklister) {
+	s.blocklister = b
+}
+
+func (s *Service) blocklistPeerHandler(w http.ResponseWriter, r *http.Request) {
+	addr := mux.Vars(r)["address"]
+	overlay, err := swarm.ParseHexAddress(addr)
+	if err != nil {
+		s.logger.Debugf("debug api: blocklist peer: parse peer address %s: %v", addr, err)
+		jsonhttp.BadRequest(w, "invalid peer address")
+		return
+	}
+
+	query := r.URL.Query()
+
+	// the peer is blocklisted permanently if the duration is not set
+	var duration time.Duration
+	if v := query.Get("duration"); v != "" {
+		duration, err = time.ParseDuration(v)
+		if err != nil || duration <= 0 {
+			s.logger.Debugf("debug api: blocklist peer: parse duration %q: %v", v, err)
+			jsonhttp.BadRequest(w, "invalid duration")
+			return
+		}
+	}
+
+	var disconnect bool
+	if v := query.Get("disconnect"); v != "" {
+		disconnect, err = strconv.ParseBool(v)
+		if err != nil {
+			s.logger.Debugf("debug api: blocklist peer: parse disconnect %q: %v", v, err)
+			jsonhttp.BadRequest(w, "invalid disconnect")
+			return
+		}
+	}
+
+	p, err := s.blocklister.BlocklistPeer(overlay, duration, query.Get("reason"), disconnect)
+	if err != nil {
+		s.logger.Debugf("debug api: blocklist peer %s: %v", overlay, err)
+		s.logger.Errorf("unable to blocklist peer %s", overlay)
+		jsonhttp.InternalServerError(w, err)
+		return
+	}
+
+	jsonhttp.OK(w, newBlockedPeer(p))
+}
+
+func (s *Service) unblocklistPeerHandler(w http.ResponseWriter, r *http.Request) {
+	addr := mux.Vars(r)["address"]
+	overlay, err := swarm.ParseHexAddress(addr)
+	if err != nil {
+		s.logger.Debugf("debug api: unblocklist peer: parse peer address %s: %v", addr, err)
+		jsonhttp.BadRequest(w, "invalid peer address")
+		return
+	}
+
+	if err := s.blocklister.UnblocklistPeer(overlay); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			jsonhttp.NotFound(w, "peer not blocklisted")
+			return
+		}
+		s.logger.Debugf("debug api: unblocklist peer %s: %v", overlay, err)
+		s.logger.Errorf("unable to unblocklist peer %s", overlay)
+		jsonhttp.InternalServerError(w, err)
+		return
+	}
+
+	jsonhttp.OK(w, nil)
+}
+
+func (s *Service) clearBlocklistHandler(w http.ResponseWriter, r *http.Request) {
+	removed, err := s.blocklister.ClearBlocklist()
+	if err != nil {
+		s.logger.Debugf("debug api: clear blocklist: %v", err)
+		s.logger.Error("unable to clear blocklist")
+		jsonhttp.InternalServerError(w, err)
+		return
+	}
+
+	jsonhttp.OK(w, clearBlocklistResponse{Removed: removed})
+}
+
+func newBlockedPeer(p p2p.BlockedPeer) blockedPeer {
+	bp := blockedPeer{
+		Address:   p.Address,
+		FullNode:  p.FullNode,
+		Timestamp: p.Timestamp,
+		Duration:  p.Duration.String(),
+		Remaining: p.Remaining.String(),
+		Permanent: p.Permanent(),
+		Attempts:  p.Attempts,
+		Reason:    p.Reason,
+		Underlays: p.Underlays,
+	}
+	if !p.LastAttempt.IsZero() {
+		lastAttempt := p.LastAttempt
+		bp.LastAttempt = &lastAttempt
+	}
+	return bp
 }
 
 func mapPeers(peers []p2p.Peer) (out []Peer) {
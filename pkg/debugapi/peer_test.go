@@ -8,7 +8,10 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethersphere/bee/pkg/bzz"
@@ -16,8 +19,11 @@ import (
 	"github.com/ethersphere/bee/pkg/debugapi"
 	"github.com/ethersphere/bee/pkg/jsonhttp"
 	"github.com/ethersphere/bee/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethersphere/bee/pkg/nodeinfo"
+	nodeinfomock "github.com/ethersphere/bee/pkg/nodeinfo/mock"
 	"github.com/ethersphere/bee/pkg/p2p"
 	"github.com/ethersphere/bee/pkg/p2p/mock"
+	"github.com/ethersphere/bee/pkg/storage"
 	"github.com/ethersphere/bee/pkg/swarm"
 	ma "github.com/multiformats/go-multiaddr"
 )
@@ -25,6 +31,7 @@ import (
 func TestConnect(t *testing.T) {
 	underlay := "/ip4/127.0.0.1/tcp/1634/p2p/16Uiu2HAkx8ULY8cTXhdVAcMmLcH9AsTKz6uBQ7DPLKRjMLgBVYkS"
 	errorUnderlay := "/ip4/127.0.0.1/tcp/1634/p2p/16Uiu2HAkw88cjH2orYrB6fDui4eUNdmgkwnDM8W681UbfsPgM9QY"
+	connectedUnderlay := "/ip4/127.0.0.2/tcp/1634/p2p/16Uiu2HAkx8ULY8cTXhdVAcMmLcH9AsTKz6uBQ7DPLKRjMLgBVYkS"
 	testErr := errors.New("test error")
 
 	privateKey, err := crypto.GenerateSecp256k1Key()
@@ -50,8 +57,11 @@ func TestConnect(t *testing.T) {
 
 	testServer := newTestServer(t, testServerOptions{
 		P2P: mock.New(mock.WithConnectFunc(func(ctx context.Context, addr ma.Multiaddr) (*bzz.Address, error) {
-			if addr.String() == errorUnderlay {
+			switch addr.String() {
+			case errorUnderlay:
 				return nil, testErr
+			case connectedUnderlay:
+				return bzzAddress, p2p.ErrAlreadyConnected
 			}
 			return bzzAddress, nil
 		})),
@@ -65,6 +75,14 @@ func TestConnect(t *testing.T) {
 		)
 	})
 
+	t.Run("already connected", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/connect"+connectedUnderlay, http.StatusOK,
+			jsonhttptest.WithExpectedJSONResponse(debugapi.PeerConnectResponse{
+				Address: overlay.String(),
+			}),
+		)
+	})
+
 	t.Run("error", func(t *testing.T) {
 		jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/connect"+errorUnderlay, http.StatusInternalServerError,
 			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
@@ -83,6 +101,42 @@ func TestConnect(t *testing.T) {
 		)
 	})
 
+	t.Run("timeout", func(t *testing.T) {
+		testServer := newTestServer(t, testServerOptions{
+			P2P: mock.New(mock.WithConnectFunc(func(ctx context.Context, addr ma.Multiaddr) (*bzz.Address, error) {
+				if addr.String() == errorUnderlay {
+					<-ctx.Done()
+					return nil, ctx.Err()
+				}
+				return bzzAddress, nil
+			})),
+			MaxConnectTimeout: 50 * time.Millisecond,
+		})
+
+		jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/connect"+underlay+"?timeout=1s", http.StatusOK,
+			jsonhttptest.WithExpectedJSONResponse(debugapi.PeerConnectResponse{
+				Address: overlay.String(),
+			}),
+		)
+
+		// the timeout of an hour is capped to the maximum
+		jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/connect"+errorUnderlay+"?timeout=1h", http.StatusGatewayTimeout,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusGatewayTimeout,
+				Message: http.StatusText(http.StatusGatewayTimeout),
+			}),
+		)
+
+		for _, timeout := range []string{"1", "abc", "0s", "-1s"} {
+			jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/connect"+underlay+"?timeout="+timeout, http.StatusBadRequest,
+				jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+					Code:    http.StatusBadRequest,
+					Message: "invalid timeout",
+				}),
+			)
+		}
+	})
+
 	t.Run("error - add peer", func(t *testing.T) {
 		testServer := newTestServer(t, testServerOptions{
 			P2P: mock.New(mock.WithConnectFunc(func(ctx context.Context, addr ma.Multiaddr) (*bzz.Address, error) {
@@ -159,18 +213,232 @@ func TestDisconnect(t *testing.T) {
 	})
 }
 
+func TestBulkDisconnect(t *testing.T) {
+	address := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c")
+	unknownAddress := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59e")
+	errorAddress := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59a")
+	testErr := errors.New("test error")
+
+	var (
+		mu           sync.Mutex
+		disconnected []swarm.Address
+	)
+	testServer := newTestServer(t, testServerOptions{
+		P2P: mock.New(
+			mock.WithDisconnectFunc(func(addr swarm.Address) error {
+				mu.Lock()
+				defer mu.Unlock()
+				disconnected = append(disconnected, addr)
+
+				if addr.Equal(address) {
+					return nil
+				}
+
+				if addr.Equal(errorAddress) {
+					return testErr
+				}
+
+				return p2p.ErrPeerNotFound
+			}),
+			mock.WithPeersFunc(func() []p2p.Peer {
+				return []p2p.Peer{{Address: address}, {Address: errorAddress}}
+			}),
+		),
+	})
+
+	expectDisconnected := func(t *testing.T, want ...swarm.Address) {
+		t.Helper()
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if len(disconnected) != len(want) {
+			t.Fatalf("got %d disconnected peers, want %d", len(disconnected), len(want))
+		}
+		for i, addr := range want {
+			if !disconnected[i].Equal(addr) {
+				t.Fatalf("got disconnected peer %s, want %s", disconnected[i], addr)
+			}
+		}
+		disconnected = nil
+	}
+
+	t.Run("addresses", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodDelete, "/peers", http.StatusOK,
+			jsonhttptest.WithJSONRequestBody(debugapi.BulkDisconnectRequest{
+				Addresses: []string{address.String(), unknownAddress.String(), errorAddress.String()},
+			}),
+			jsonhttptest.WithExpectedJSONResponse(debugapi.BulkDisconnectResponse{
+				Results: []debugapi.BulkDisconnectResult{
+					{Address: address, Status: "ok"},
+					{Address: unknownAddress, Status: "not found"},
+					{Address: errorAddress, Status: "error", Error: testErr.Error()},
+				},
+			}),
+		)
+		expectDisconnected(t, address, unknownAddress, errorAddress)
+	})
+
+	t.Run("all", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodDelete, "/peers?all=true", http.StatusOK,
+			jsonhttptest.WithExpectedJSONResponse(debugapi.BulkDisconnectResponse{
+				Results: []debugapi.BulkDisconnectResult{
+					{Address: address, Status: "ok"},
+					{Address: errorAddress, Status: "error", Error: testErr.Error()},
+				},
+			}),
+		)
+		expectDisconnected(t, address, errorAddress)
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		for _, tc := range []struct {
+			name    string
+			path    string
+			body    jsonhttptest.Option
+			message string
+		}{
+			{
+				name:    "malformed body",
+				path:    "/peers",
+				body:    jsonhttptest.WithRequestBody(strings.NewReader("{")),
+				message: "invalid request",
+			},
+			{
+				name:    "no addresses",
+				path:    "/peers",
+				body:    jsonhttptest.WithJSONRequestBody(debugapi.BulkDisconnectRequest{}),
+				message: "no peer addresses",
+			},
+			{
+				name: "invalid addresses",
+				path: "/peers",
+				body: jsonhttptest.WithJSONRequestBody(debugapi.BulkDisconnectRequest{
+					Addresses: []string{"invalid", address.String(), "ca1"},
+				}),
+				message: "invalid peer addresses: invalid, ca1",
+			},
+			{
+				name:    "invalid all",
+				path:    "/peers?all=maybe",
+				body:    jsonhttptest.WithJSONRequestBody(debugapi.BulkDisconnectRequest{}),
+				message: "invalid all",
+			},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				jsonhttptest.Request(t, testServer.Client, http.MethodDelete, tc.path, http.StatusBadRequest,
+					tc.body,
+					jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+						Code:    http.StatusBadRequest,
+						Message: tc.message,
+					}),
+				)
+				// no peer is disconnected
+				expectDisconnected(t)
+			})
+		}
+	})
+}
+
 func TestPeer(t *testing.T) {
-	overlay := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c")
+	base := swarm.MustParseHexAddress("0000000000000000000000000000000000000000000000000000000000000000")
+	po0 := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c")
+	po1 := swarm.MustParseHexAddress("4000000000000000000000000000000000000000000000000000000000000000")
+	po9 := swarm.MustParseHexAddress("0040000000000000000000000000000000000000000000000000000000000000")
+	anotherPO0 := swarm.MustParseHexAddress("8000000000000000000000000000000000000000000000000000000000000000")
 	testServer := newTestServer(t, testServerOptions{
+		NodeInfo: nodeinfomock.New(nodeinfomock.WithInfo(nodeinfo.Info{Overlay: base})),
 		P2P: mock.New(mock.WithPeersFunc(func() []p2p.Peer {
-			return []p2p.Peer{{Address: overlay}}
+			return []p2p.Peer{{Address: po0}, {Address: po1, FullNode: true}, {Address: po9}, {Address: anotherPO0}}
 		})),
 	})
 
+	// the peers are sorted by their overlays
+	sorted := []debugapi.ConnectedPeer{
+		{Peer: debugapi.Peer{Address: po9}, PO: 9},
+		{Peer: debugapi.Peer{Address: po1, FullNode: true}, PO: 1},
+		{Peer: debugapi.Peer{Address: anotherPO0}, PO: 0},
+		{Peer: debugapi.Peer{Address: po0}, PO: 0},
+	}
+	bins := map[uint8]int{0: 2, 1: 1, 9: 1}
+
 	t.Run("ok", func(t *testing.T) {
 		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/peers", http.StatusOK,
 			jsonhttptest.WithExpectedJSONResponse(debugapi.PeersResponse{
-				Peers: []debugapi.Peer{{Address: overlay}},
+				Total: 4,
+				Peers: sorted,
+				Bins:  bins,
+			}),
+		)
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		for _, tc := range []struct {
+			query string
+			peers []debugapi.ConnectedPeer
+		}{
+			{query: "?limit=2", peers: sorted[:2]},
+			{query: "?offset=2&limit=2", peers: sorted[2:]},
+			{query: "?offset=3&limit=2", peers: sorted[3:]},
+			{query: "?offset=1&limit=1", peers: sorted[1:2]},
+			{query: "?offset=4", peers: []debugapi.ConnectedPeer{}},
+			{query: "?offset=100&limit=10", peers: []debugapi.ConnectedPeer{}},
+		} {
+			// the bins and the total count all peers
+			jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/peers"+tc.query, http.StatusOK,
+				jsonhttptest.WithExpectedJSONResponse(debugapi.PeersResponse{
+					Total: 4,
+					Peers: tc.peers,
+					Bins:  bins,
+				}),
+			)
+		}
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		for _, tc := range []struct {
+			query   string
+			message string
+		}{
+			{query: "?offset=-1", message: "invalid offset"},
+			{query: "?offset=first", message: "invalid offset"},
+			{query: "?limit=0", message: "invalid limit"},
+			{query: "?limit=-1", message: "invalid limit"},
+			{query: "?limit=1001", message: "invalid limit"},
+			{query: "?limit=all", message: "invalid limit"},
+		} {
+			jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/peers"+tc.query, http.StatusBadRequest,
+				jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+					Code:    http.StatusBadRequest,
+					Message: tc.message,
+				}),
+			)
+		}
+	})
+
+	t.Run("no peers", func(t *testing.T) {
+		testServer := newTestServer(t, testServerOptions{
+			NodeInfo: nodeinfomock.New(nodeinfomock.WithInfo(nodeinfo.Info{Overlay: base})),
+			P2P:      mock.New(),
+		})
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/peers", http.StatusOK,
+			jsonhttptest.WithExpectedJSONResponse(debugapi.PeersResponse{
+				Peers: []debugapi.ConnectedPeer{},
+				Bins:  map[uint8]int{},
+			}),
+		)
+	})
+
+	t.Run("node info error", func(t *testing.T) {
+		testErr := errors.New("test error")
+		testServer := newTestServer(t, testServerOptions{
+			NodeInfo: nodeinfomock.New(nodeinfomock.WithError(testErr)),
+			P2P:      mock.New(),
+		})
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/peers", http.StatusInternalServerError,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusInternalServerError,
+				Message: testErr.Error(),
 			}),
 		)
 	})
@@ -185,17 +453,155 @@ func TestPeer(t *testing.T) {
 	})
 }
 
+func TestPeerDetail(t *testing.T) {
+	overlay := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c")
+	unknownOverlay := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59e")
+	errorOverlay := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59a")
+	// the peer without the details of the connection
+	lightOverlay := swarm.MustParseHexAddress("4000000000000000000000000000000000000000000000000000000000000000")
+	// the peer which disconnects during the request
+	disconnectedOverlay := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59b")
+	base := swarm.MustParseHexAddress("0000000000000000000000000000000000000000000000000000000000000000")
+	underlay := ma.StringCast("/ip4/127.0.0.1/tcp/1634")
+	opened := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	testErr := errors.New("test error")
+
+	testServer := newTestServer(t, testServerOptions{
+		NodeInfo: nodeinfomock.New(nodeinfomock.WithInfo(nodeinfo.Info{Overlay: base})),
+		P2P: mock.New(
+			mock.WithPeersFunc(func() []p2p.Peer {
+				return []p2p.Peer{
+					{Address: overlay, FullNode: true},
+					{Address: errorOverlay},
+					{Address: lightOverlay},
+					{Address: disconnectedOverlay},
+				}
+			}),
+			mock.WithOpenStreamsFunc(func(addr swarm.Address) (int, error) {
+				if addr.Equal(errorOverlay) {
+					return 0, testErr
+				}
+				return 7, nil
+			}),
+			mock.WithConnectionInfoFunc(func(addr swarm.Address) (p2p.ConnectionInfo, error) {
+				switch {
+				case addr.Equal(overlay):
+					return p2p.ConnectionInfo{
+						Underlays: []ma.Multiaddr{underlay},
+						Direction: p2p.DirectionInbound,
+						Opened:    opened,
+					}, nil
+				case addr.Equal(lightOverlay):
+					return p2p.ConnectionInfo{}, nil
+				default:
+					return p2p.ConnectionInfo{}, p2p.ErrPeerNotFound
+				}
+			}),
+		),
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/peers/"+overlay.String(), http.StatusOK,
+			jsonhttptest.WithExpectedJSONResponse(debugapi.PeerResponse{
+				Peer:        debugapi.Peer{Address: overlay, FullNode: true},
+				Connected:   true,
+				PO:          0,
+				OpenStreams: 7,
+				Underlays:   []ma.Multiaddr{underlay},
+				Direction:   p2p.DirectionInbound,
+				ConnectedAt: &opened,
+			}),
+		)
+	})
+
+	t.Run("without connection details", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/peers/"+lightOverlay.String(), http.StatusOK,
+			jsonhttptest.WithExpectedJSONResponse(debugapi.PeerResponse{
+				Peer:        debugapi.Peer{Address: lightOverlay},
+				Connected:   true,
+				PO:          1,
+				OpenStreams: 7,
+			}),
+		)
+	})
+
+	t.Run("peer disconnected", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/peers/"+disconnectedOverlay.String(), http.StatusNotFound,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusNotFound,
+				Message: "peer not found",
+			}),
+		)
+	})
+
+	t.Run("peer not found", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/peers/"+unknownOverlay.String(), http.StatusNotFound,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusNotFound,
+				Message: "peer not found",
+			}),
+		)
+	})
+
+	t.Run("invalid peer address", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/peers/invalid-address", http.StatusBadRequest,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusBadRequest,
+				Message: "invalid peer address",
+			}),
+		)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/peers/"+errorOverlay.String(), http.StatusInternalServerError,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusInternalServerError,
+				Message: testErr.Error(),
+			}),
+		)
+	})
+}
+
 func TestBlocklistedPeers(t *testing.T) {
 	overlay := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c")
+	overlay2 := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59d")
+	lastAttempt := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	blocked := lastAttempt.Add(-20 * time.Minute)
+	underlay := ma.StringCast("/ip4/127.0.0.1/tcp/1634")
 	testServer := newTestServer(t, testServerOptions{
-		P2P: mock.New(mock.WithBlocklistedPeersFunc(func() ([]p2p.Peer, error) {
-			return []p2p.Peer{{Address: overlay}}, nil
+		P2P: mock.New(mock.WithBlocklistedPeersFunc(func() ([]p2p.BlockedPeer, error) {
+			return []p2p.BlockedPeer{
+				{Peer: p2p.Peer{Address: overlay}, Timestamp: blocked},
+				{
+					Peer:        p2p.Peer{Address: overlay2, FullNode: true},
+					Timestamp:   blocked,
+					Duration:    time.Hour,
+					Remaining:   40 * time.Minute,
+					Attempts:    3,
+					LastAttempt: lastAttempt,
+					Reason:      "bad handshake",
+					Underlays:   []ma.Multiaddr{underlay},
+				},
+			}, nil
 		})),
 	})
 
 	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/blocklist", http.StatusOK,
-		jsonhttptest.WithExpectedJSONResponse(debugapi.PeersResponse{
-			Peers: []debugapi.Peer{{Address: overlay}},
+		jsonhttptest.WithExpectedJSONResponse(debugapi.BlockedPeersResponse{
+			Peers: []debugapi.BlockedPeer{
+				{Address: overlay, Timestamp: blocked, Duration: "0s", Remaining: "0s", Permanent: true},
+				{
+					Address:     overlay2,
+					FullNode:    true,
+					Timestamp:   blocked,
+					Duration:    "1h0m0s",
+					Remaining:   "40m0s",
+					Attempts:    3,
+					LastAttempt: &lastAttempt,
+					Reason:      "bad handshake",
+					Underlays:   []ma.Multiaddr{underlay},
+				},
+			},
 		}),
 	)
 }
@@ -203,8 +609,8 @@ func TestBlocklistedPeers(t *testing.T) {
 func TestBlocklistedPeersErr(t *testing.T) {
 	overlay := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c")
 	testServer := newTestServer(t, testServerOptions{
-		P2P: mock.New(mock.WithBlocklistedPeersFunc(func() ([]p2p.Peer, error) {
-			return []p2p.Peer{{Address: overlay}}, errors.New("some error")
+		P2P: mock.New(mock.WithBlocklistedPeersFunc(func() ([]p2p.BlockedPeer, error) {
+			return []p2p.BlockedPeer{{Peer: p2p.Peer{Address: overlay}}}, errors.New("some error")
 		})),
 	})
 
@@ -216,3 +622,226 @@ func TestBlocklistedPeersErr(t *testing.T) {
 			}),
 	)
 }
+
+// mockBlocklister is a mock of the blocklister.
+type mockBlocklister struct {
+	blocklistPeer   func(overlay swarm.Address, duration time.Duration, reason string, disconnect bool) (p2p.BlockedPeer, error)
+	unblocklistPeer func(overlay swarm.Address) error
+	clearBlocklist  func() (int, error)
+}
+
+func (m *mockBlocklister) BlocklistPeer(overlay swarm.Address, duration time.Duration, reason string, disconnect bool) (p2p.BlockedPeer, error) {
+	if m.blocklistPeer == nil {
+		return p2p.BlockedPeer{}, errors.New("function BlocklistPeer not configured")
+	}
+	return m.blocklistPeer(overlay, duration, reason, disconnect)
+}
+
+func (m *mockBlocklister) UnblocklistPeer(overlay swarm.Address) error {
+	if m.unblocklistPeer == nil {
+		return errors.New("function UnblocklistPeer not configured")
+	}
+	return m.unblocklistPeer(overlay)
+}
+
+func (m *mockBlocklister) ClearBlocklist() (int, error) {
+	if m.clearBlocklist == nil {
+		return 0, errors.New("function ClearBlocklist not configured")
+	}
+	return m.clearBlocklist()
+}
+
+func TestBlocklistPeer(t *testing.T) {
+	overlay := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c")
+	errorOverlay := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59d")
+	blocked := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	testErr := errors.New("test error")
+
+	type call struct {
+		duration   time.Duration
+		reason     string
+		disconnect bool
+	}
+	var got call
+	testServer := newTestServer(t, testServerOptions{
+		Blocklister: &mockBlocklister{
+			blocklistPeer: func(addr swarm.Address, duration time.Duration, reason string, disconnect bool) (p2p.BlockedPeer, error) {
+				if addr.Equal(errorOverlay) {
+					return p2p.BlockedPeer{}, testErr
+				}
+				got = call{duration: duration, reason: reason, disconnect: disconnect}
+				return p2p.BlockedPeer{
+					Peer:      p2p.Peer{Address: addr},
+					Timestamp: blocked,
+					Duration:  duration,
+					Remaining: duration,
+					Reason:    reason,
+				}, nil
+			},
+		},
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/blocklist/"+overlay.String()+"?duration=1h&reason=spam&disconnect=true", http.StatusOK,
+			jsonhttptest.WithExpectedJSONResponse(debugapi.BlockedPeer{
+				Address:   overlay,
+				Timestamp: blocked,
+				Duration:  "1h0m0s",
+				Remaining: "1h0m0s",
+				Reason:    "spam",
+			}),
+		)
+		if want := (call{duration: time.Hour, reason: "spam", disconnect: true}); got != want {
+			t.Fatalf("got call %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("permanent", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/blocklist/"+overlay.String(), http.StatusOK,
+			jsonhttptest.WithExpectedJSONResponse(debugapi.BlockedPeer{
+				Address:   overlay,
+				Timestamp: blocked,
+				Duration:  "0s",
+				Remaining: "0s",
+				Permanent: true,
+			}),
+		)
+		if want := (call{}); got != want {
+			t.Fatalf("got call %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		for _, tc := range []struct {
+			path    string
+			message string
+		}{
+			{path: "/blocklist/invalid-address", message: "invalid peer address"},
+			{path: "/blocklist/" + overlay.String() + "?duration=1", message: "invalid duration"},
+			{path: "/blocklist/" + overlay.String() + "?duration=-1h", message: "invalid duration"},
+			{path: "/blocklist/" + overlay.String() + "?disconnect=maybe", message: "invalid disconnect"},
+		} {
+			jsonhttptest.Request(t, testServer.Client, http.MethodPost, tc.path, http.StatusBadRequest,
+				jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+					Code:    http.StatusBadRequest,
+					Message: tc.message,
+				}),
+			)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/blocklist/"+errorOverlay.String(), http.StatusInternalServerError,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusInternalServerError,
+				Message: testErr.Error(),
+			}),
+		)
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		testServer := newTestServer(t, testServerOptions{})
+
+		jsonhttptest.Request(t, testServer.Client, http.MethodPost, "/blocklist/"+overlay.String(), http.StatusNotFound)
+	})
+}
+
+func TestUnblocklistPeer(t *testing.T) {
+	overlay := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c")
+	unknownOverlay := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59d")
+	errorOverlay := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59e")
+	testErr := errors.New("test error")
+
+	testServer := newTestServer(t, testServerOptions{
+		Blocklister: &mockBlocklister{
+			unblocklistPeer: func(addr swarm.Address) error {
+				switch {
+				case addr.Equal(overlay):
+					return nil
+				case addr.Equal(errorOverlay):
+					return testErr
+				default:
+					return storage.ErrNotFound
+				}
+			},
+		},
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodDelete, "/blocklist/"+overlay.String(), http.StatusOK,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusOK,
+				Message: http.StatusText(http.StatusOK),
+			}),
+		)
+	})
+
+	t.Run("peer not blocklisted", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodDelete, "/blocklist/"+unknownOverlay.String(), http.StatusNotFound,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusNotFound,
+				Message: "peer not blocklisted",
+			}),
+		)
+	})
+
+	t.Run("invalid peer address", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodDelete, "/blocklist/invalid-address", http.StatusBadRequest,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusBadRequest,
+				Message: "invalid peer address",
+			}),
+		)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodDelete, "/blocklist/"+errorOverlay.String(), http.StatusInternalServerError,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusInternalServerError,
+				Message: testErr.Error(),
+			}),
+		)
+	})
+}
+
+func TestClearBlocklist(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		testServer := newTestServer(t, testServerOptions{
+			Blocklister: &mockBlocklister{
+				clearBlocklist: func() (int, error) {
+					return 3, nil
+				},
+			},
+		})
+
+		jsonhttptest.Request(t, testServer.Client, http.MethodDelete, "/blocklist", http.StatusOK,
+			jsonhttptest.WithExpectedJSONResponse(debugapi.ClearBlocklistResponse{
+				Removed: 3,
+			}),
+		)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		testErr := errors.New("test error")
+		testServer := newTestServer(t, testServerOptions{
+			Blocklister: &mockBlocklister{
+				clearBlocklist: func() (int, error) {
+					return 1, testErr
+				},
+			},
+		})
+
+		jsonhttptest.Request(t, testServer.Client, http.MethodDelete, "/blocklist", http.StatusInternalServerError,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusInternalServerError,
+				Message: testErr.Error(),
+			}),
+		)
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		testServer := newTestServer(t, testServerOptions{})
+
+		jsonhttptest.Request(t, testServer.Client, http.MethodDelete, "/blocklist", http.StatusMethodNotAllowed)
+	})
+}
@@ -31,8 +31,10 @@ func (s *Service) newBasicRouter() *mux.Router {
 
 	router.Path("/metrics").Handler(web.ChainHandlers(
 		httpaccess.SetAccessLogLevelHandler(0), // suppress access log messages
+		// the handler metrics are registered directly, as the router is
+		// constructed more than once and the existing metrics must be reused
 		web.FinalHandler(promhttp.InstrumentMetricHandler(
-			s.metricsRegistry,
+			s.metricsRegistry.Registry,
 			promhttp.HandlerFor(s.metricsRegistry, promhttp.HandlerOpts{}),
 		)),
 	))
@@ -52,7 +54,7 @@ func (s *Service) newBasicRouter() *mux.Router {
 
 	router.Handle("/health", web.ChainHandlers(
 		httpaccess.SetAccessLogLevelHandler(0), // suppress access log messages
-		web.FinalHandlerFunc(statusHandler),
+		web.FinalHandlerFunc(s.healthHandler),
 	))
 
 	router.Handle("/addresses", jsonhttp.MethodHandler{
@@ -81,9 +83,13 @@ func (s *Service) newRouter() *mux.Router {
 
 	router.Handle("/readiness", web.ChainHandlers(
 		httpaccess.SetAccessLogLevelHandler(0), // suppress access log messages
-		web.FinalHandlerFunc(statusHandler),
+		web.FinalHandlerFunc(s.readinessHandler),
 	))
 
+	router.Handle("/node", jsonhttp.MethodHandler{
+		"GET": http.HandlerFunc(s.nodeHandler),
+	})
+
 	router.Handle("/pingpong/{peer-id}", jsonhttp.MethodHandler{
 		"POST": http.HandlerFunc(s.pingpongHandler),
 	})
@@ -96,26 +102,70 @@ func (s *Service) newRouter() *mux.Router {
 		"GET": http.HandlerFunc(s.chainStateHandler),
 	})
 
+	router.Handle("/connect", jsonhttp.MethodHandler{
+		"POST": http.HandlerFunc(s.bulkConnectHandler),
+	})
 	router.Handle("/connect/{multi-address:.+}", jsonhttp.MethodHandler{
 		"POST": http.HandlerFunc(s.peerConnectHandler),
 	})
 	router.Handle("/peers", jsonhttp.MethodHandler{
-		"GET": http.HandlerFunc(s.peersHandler),
+		"GET":    http.HandlerFunc(s.peersHandler),
+		"DELETE": http.HandlerFunc(s.bulkDisconnectHandler),
 	})
-	router.Handle("/blocklist", jsonhttp.MethodHandler{
+	blocklistHandler := jsonhttp.MethodHandler{
 		"GET": http.HandlerFunc(s.blocklistedPeersHandler),
-	})
+	}
+	if s.blocklister != nil {
+		blocklistHandler["DELETE"] = http.HandlerFunc(s.clearBlocklistHandler)
+		router.Handle("/blocklist/{address}", jsonhttp.MethodHandler{
+			"POST":   http.HandlerFunc(s.blocklistPeerHandler),
+			"DELETE": http.HandlerFunc(s.unblocklistPeerHandler),
+		})
+	}
+	router.Handle("/blocklist", blocklistHandler)
 
 	router.Handle("/peers/{address}", jsonhttp.MethodHandler{
+		"GET":    http.HandlerFunc(s.peerHandler),
 		"DELETE": http.HandlerFunc(s.peerDisconnectHandler),
 	})
+	router.Handle("/events/peers", jsonhttp.MethodHandler{
+		"GET": http.HandlerFunc(s.peerEventsHandler),
+	})
 	router.Handle("/chunks/{address}", jsonhttp.MethodHandler{
 		"GET":    http.HandlerFunc(s.hasChunkHandler),
 		"DELETE": http.HandlerFunc(s.removeChunk),
 	})
+	router.Handle("/chunks/{address}/responsibility", jsonhttp.MethodHandler{
+		"GET": http.HandlerFunc(s.chunkResponsibilityHandler),
+	})
 	router.Handle("/topology", jsonhttp.MethodHandler{
 		"GET": http.HandlerFunc(s.topologyHandler),
 	})
+	router.Handle("/topology/suggestions", jsonhttp.MethodHandler{
+		"GET": http.HandlerFunc(s.topologySuggestionsHandler),
+	})
+	router.Handle("/selftest", jsonhttp.MethodHandler{
+		"POST": http.HandlerFunc(s.selfTestHandler),
+	})
+	router.Handle("/jobs/{id}", jsonhttp.MethodHandler{
+		"GET": http.HandlerFunc(s.jobHandler),
+	})
+
+	if s.stateStoreMaintainer != nil {
+		router.Handle("/db/check", jsonhttp.MethodHandler{
+			"POST": http.HandlerFunc(s.dbCheckHandler),
+		})
+		router.Handle("/db/compact", jsonhttp.MethodHandler{
+			"POST": http.HandlerFunc(s.dbCompactHandler),
+		})
+	}
+
+	if s.stateStore != nil {
+		router.Handle("/db/sweep", jsonhttp.MethodHandler{
+			"POST": http.HandlerFunc(s.dbSweepHandler),
+		})
+	}
+
 	router.Handle("/welcome-message", jsonhttp.MethodHandler{
 		"GET": http.HandlerFunc(s.getWelcomeMessageHandler),
 		"POST": web.ChainHandlers(
@@ -0,0 +1,200 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/bzz"
+	"github.com/ethersphere/bee/pkg/jsonhttp"
+	"github.com/ethersphere/bee/pkg/p2p"
+	"github.com/ethersphere/bee/pkg/storage"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+const (
+	selfTestKeyPrefix = "selftest-"
+
+	defaultSelfTestTimeout = 30 * time.Second
+	// selfTestInboundWindow is the time in which an inbound
+	// connection must have been received for the check to pass.
+	selfTestInboundWindow = time.Hour
+)
+
+func init() {
+	// the self-test records are removed right after they are written
+	storage.RegisterPrefix(selfTestKeyPrefix, func(_, _ []byte) error { return nil })
+}
+
+// SelfTestOptions configures the checks of the self-test endpoint.
+type SelfTestOptions struct {
+	// Bootnodes are the configured bootnode addresses,
+	// the first one that can be connected to is used.
+	Bootnodes []string
+	// LastInboundConnection returns the time of the last inbound
+	// connection or the zero time if there was none.
+	LastInboundConnection func() time.Time
+	// Timeout bounds the duration of the whole self-test.
+	Timeout time.Duration
+}
+
+type selfTestCheck struct {
+	Name     string `json:"name"`
+	Pass     bool   `json:"pass"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+type selfTestResponse struct {
+	Pass   bool            `json:"pass"`
+	Checks []selfTestCheck `json:"checks"`
+}
+
+// SetSelfTest sets the options of the self-test endpoint.
+// It must be called before Configure.
+func (s *Service) SetSelfTest(o SelfTestOptions) {
+	if o.Timeout == 0 {
+		o.Timeout = defaultSelfTestTimeout
+	}
+	s.selfTest = o
+}
+
+// selfTestHandler checks whether the node can participate in the network:
+// it connects to a bootnode, which includes the handshake, pings it, checks
+// that the node is reachable for inbound connections and that the state
+// store is writable. The connection to the bootnode is closed afterwards
+// if it was created by the self-test.
+func (s *Service) selfTestHandler(w http.ResponseWriter, r *http.Request) {
+	timeout := s.selfTest.Timeout
+	if timeout == 0 {
+		timeout = defaultSelfTestTimeout
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	resp := selfTestResponse{Pass: true}
+	run := func(name string, f func() error) bool {
+		start := time.Now()
+		err := f()
+		c := selfTestCheck{
+			Name:     name,
+			Pass:     err == nil,
+			Duration: time.Since(start).String(),
+		}
+		if err != nil {
+			c.Error = err.Error()
+			resp.Pass = false
+		}
+		resp.Checks = append(resp.Checks, c)
+		return err == nil
+	}
+
+	var (
+		bootnode *bzz.Address
+		created  bool
+	)
+	connected := run("bootnode connect", func() (err error) {
+		bootnode, created, err = s.selfTestConnect(ctx)
+		return err
+	})
+
+	run("ping", func() error {
+		if !connected {
+			return errors.New("no bootnode connection")
+		}
+		_, err := s.pingpong.Ping(ctx, bootnode.Overlay, "selftest")
+		return err
+	})
+
+	run("inbound connection", func() error {
+		if s.selfTest.LastInboundConnection == nil {
+			return errors.New("inbound connections are not tracked")
+		}
+		last := s.selfTest.LastInboundConnection()
+		if last.IsZero() {
+			return errors.New("no inbound connection received")
+		}
+		if since := time.Since(last); since > selfTestInboundWindow {
+			return fmt.Errorf("last inbound connection received %s ago", since.Round(time.Second))
+		}
+		return nil
+	})
+
+	run("statestore write", func() error {
+		if s.stateStore == nil {
+			return errors.New("no state store")
+		}
+		nonce := make([]byte, 8)
+		if _, err := rand.Read(nonce); err != nil {
+			return err
+		}
+		key := selfTestKeyPrefix + hex.EncodeToString(nonce)
+		if err := s.stateStore.Put(key, time.Now().Unix()); err != nil {
+			return fmt.Errorf("put: %w", err)
+		}
+		var v int64
+		err := s.stateStore.Get(key, &v)
+		if derr := s.stateStore.Delete(key); derr != nil && err == nil {
+			err = fmt.Errorf("delete: %w", derr)
+		}
+		return err
+	})
+
+	if created {
+		if err := s.p2p.Disconnect(bootnode.Overlay); err != nil {
+			s.logger.Debugf("debug api: self-test: disconnect bootnode %s: %v", bootnode.Overlay, err)
+		}
+	}
+
+	if !resp.Pass {
+		s.logger.Warning("debug api: self-test failed")
+	}
+	jsonhttp.OK(w, resp)
+}
+
+// selfTestConnect connects to the first configured bootnode that accepts the
+// connection. It reports whether the connection was created by this call.
+func (s *Service) selfTestConnect(ctx context.Context) (addr *bzz.Address, created bool, err error) {
+	if len(s.selfTest.Bootnodes) == 0 {
+		return nil, false, errors.New("no bootnodes configured")
+	}
+
+	for _, b := range s.selfTest.Bootnodes {
+		bootnode, err := ma.NewMultiaddr(b)
+		if err != nil {
+			s.logger.Debugf("debug api: self-test: parse bootnode %s: %v", b, err)
+			continue
+		}
+		_, err = p2p.Discover(ctx, bootnode, func(underlay ma.Multiaddr) (bool, error) {
+			a, err := s.p2p.Connect(ctx, underlay)
+			switch {
+			case errors.Is(err, p2p.ErrAlreadyConnected):
+				addr = a
+				return true, nil
+			case err != nil:
+				s.logger.Debugf("debug api: self-test: connect to bootnode %s: %v", underlay, err)
+				return false, nil
+			}
+			addr, created = a, true
+			return true, nil
+		})
+		if err != nil {
+			s.logger.Debugf("debug api: self-test: resolve bootnode %s: %v", b, err)
+		}
+		if addr != nil {
+			return addr, created, nil
+		}
+		if ctx.Err() != nil {
+			return nil, false, ctx.Err()
+		}
+	}
+	return nil, false, errors.New("no bootnode could be connected")
+}
@@ -0,0 +1,215 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debugapi_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/bzz"
+	"github.com/ethersphere/bee/pkg/debugapi"
+	"github.com/ethersphere/bee/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethersphere/bee/pkg/p2p"
+	p2pmock "github.com/ethersphere/bee/pkg/p2p/mock"
+	pingpongmock "github.com/ethersphere/bee/pkg/pingpong/mock"
+	"github.com/ethersphere/bee/pkg/statestore/mock"
+	"github.com/ethersphere/bee/pkg/storage"
+	"github.com/ethersphere/bee/pkg/swarm"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// failingStateStore fails all writes.
+type failingStateStore struct {
+	storage.StateStorer
+}
+
+func (failingStateStore) Put(string, interface{}) error {
+	return errors.New("read-only")
+}
+
+func TestSelfTest(t *testing.T) {
+	const bootnode = "/ip4/127.0.0.1/tcp/1634/p2p/16Uiu2HAmTBuJT9LvNmBiQiNoTsxE5mtNy6YG3paw79m94CRa9sRb"
+	overlay := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c")
+	testErr := errors.New("test error")
+
+	type result struct {
+		connectErr     error
+		pingErr        error
+		lastInbound    time.Time
+		stateStore     storage.StateStorer
+		bootnodes      []string
+		wantFailed     []string
+		wantDisconnect bool
+	}
+
+	for _, tc := range []struct {
+		name string
+		result
+	}{
+		{
+			name: "pass",
+			result: result{
+				lastInbound:    time.Now(),
+				stateStore:     mock.NewStateStore(),
+				bootnodes:      []string{bootnode},
+				wantDisconnect: true,
+			},
+		},
+		{
+			name: "already connected",
+			result: result{
+				connectErr:  p2p.ErrAlreadyConnected,
+				lastInbound: time.Now(),
+				stateStore:  mock.NewStateStore(),
+				bootnodes:   []string{bootnode},
+			},
+		},
+		{
+			name: "no bootnodes",
+			result: result{
+				lastInbound: time.Now(),
+				stateStore:  mock.NewStateStore(),
+				wantFailed:  []string{"bootnode connect", "ping"},
+			},
+		},
+		{
+			name: "connect error",
+			result: result{
+				connectErr:  testErr,
+				lastInbound: time.Now(),
+				stateStore:  mock.NewStateStore(),
+				bootnodes:   []string{bootnode},
+				wantFailed:  []string{"bootnode connect", "ping"},
+			},
+		},
+		{
+			name: "ping error",
+			result: result{
+				pingErr:        testErr,
+				lastInbound:    time.Now(),
+				stateStore:     mock.NewStateStore(),
+				bootnodes:      []string{bootnode},
+				wantFailed:     []string{"ping"},
+				wantDisconnect: true,
+			},
+		},
+		{
+			name: "no recent inbound connection",
+			result: result{
+				lastInbound:    time.Now().Add(-2 * time.Hour),
+				stateStore:     mock.NewStateStore(),
+				bootnodes:      []string{bootnode},
+				wantFailed:     []string{"inbound connection"},
+				wantDisconnect: true,
+			},
+		},
+		{
+			name: "statestore not writable",
+			result: result{
+				lastInbound:    time.Now(),
+				stateStore:     failingStateStore{mock.NewStateStore()},
+				bootnodes:      []string{bootnode},
+				wantFailed:     []string{"statestore write"},
+				wantDisconnect: true,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var disconnected int32
+			ts := newTestServer(t, testServerOptions{
+				P2P: p2pmock.New(
+					p2pmock.WithConnectFunc(func(_ context.Context, addr ma.Multiaddr) (*bzz.Address, error) {
+						if tc.connectErr != nil && !errors.Is(tc.connectErr, p2p.ErrAlreadyConnected) {
+							return nil, tc.connectErr
+						}
+						return &bzz.Address{Overlay: overlay, Underlay: addr}, tc.connectErr
+					}),
+					p2pmock.WithDisconnectFunc(func(addr swarm.Address) error {
+						if !addr.Equal(overlay) {
+							t.Errorf("disconnected from %s, want %s", addr, overlay)
+						}
+						atomic.StoreInt32(&disconnected, 1)
+						return nil
+					}),
+				),
+				Pingpong: pingpongmock.New(func(_ context.Context, addr swarm.Address, _ ...string) (time.Duration, error) {
+					if !addr.Equal(overlay) {
+						t.Errorf("pinged %s, want %s", addr, overlay)
+					}
+					return time.Millisecond, tc.pingErr
+				}),
+				StateStorer: tc.stateStore,
+				SelfTest: debugapi.SelfTestOptions{
+					Bootnodes:             tc.bootnodes,
+					LastInboundConnection: func() time.Time { return tc.lastInbound },
+				},
+			})
+
+			var resp debugapi.SelfTestResponse
+			jsonhttptest.Request(t, ts.Client, http.MethodPost, "/selftest", http.StatusOK,
+				jsonhttptest.WithUnmarshalJSONResponse(&resp),
+			)
+
+			if want := len(tc.wantFailed) == 0; resp.Pass != want {
+				t.Errorf("got pass %v, want %v", resp.Pass, want)
+			}
+			if len(resp.Checks) != 4 {
+				t.Fatalf("got %d checks, want 4", len(resp.Checks))
+			}
+			failed := make(map[string]bool)
+			for _, name := range tc.wantFailed {
+				failed[name] = true
+			}
+			for _, c := range resp.Checks {
+				if c.Pass == failed[c.Name] {
+					t.Errorf("check %q: got pass %v, want %v", c.Name, c.Pass, !failed[c.Name])
+				}
+				if !c.Pass && c.Error == "" {
+					t.Errorf("check %q: failed without an error", c.Name)
+				}
+				if _, err := time.ParseDuration(c.Duration); err != nil {
+					t.Errorf("check %q: invalid duration %q: %v", c.Name, c.Duration, err)
+				}
+			}
+			if got := atomic.LoadInt32(&disconnected) == 1; got != tc.wantDisconnect {
+				t.Errorf("got disconnected %v, want %v", got, tc.wantDisconnect)
+			}
+		})
+	}
+}
+
+func TestSelfTestTimeout(t *testing.T) {
+	const bootnode = "/ip4/127.0.0.1/tcp/1634/p2p/16Uiu2HAmTBuJT9LvNmBiQiNoTsxE5mtNy6YG3paw79m94CRa9sRb"
+
+	ts := newTestServer(t, testServerOptions{
+		P2P: p2pmock.New(
+			p2pmock.WithConnectFunc(func(ctx context.Context, _ ma.Multiaddr) (*bzz.Address, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}),
+		),
+		StateStorer: mock.NewStateStore(),
+		SelfTest: debugapi.SelfTestOptions{
+			Bootnodes:             []string{bootnode},
+			LastInboundConnection: time.Now,
+			Timeout:               50 * time.Millisecond,
+		},
+	})
+
+	var resp debugapi.SelfTestResponse
+	jsonhttptest.Request(t, ts.Client, http.MethodPost, "/selftest", http.StatusOK,
+		jsonhttptest.WithUnmarshalJSONResponse(&resp),
+	)
+	if resp.Pass {
+		t.Fatal("self-test passed after the deadline")
+	}
+	if c := resp.Checks[0]; c.Pass || c.Error != context.DeadlineExceeded.Error() {
+		t.Fatalf("got bootnode check %+v, want deadline exceeded", c)
+	}
+}
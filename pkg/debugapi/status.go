@@ -6,9 +6,11 @@ package debugapi
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/ethersphere/bee"
 	"github.com/ethersphere/bee/pkg/jsonhttp"
+	"github.com/ethersphere/bee/pkg/swarm"
 )
 
 type statusResponse struct {
@@ -22,3 +24,41 @@ func statusHandler(w http.ResponseWriter, r *http.Request) {
 		Version: bee.Version,
 	})
 }
+
+type nodeResponse struct {
+	Overlay   swarm.Address  `json:"overlay"`
+	PeerID    string         `json:"peerID"`
+	NetworkID uint64         `json:"networkID"`
+	FullNode  bool           `json:"fullNode"`
+	Swap      bool           `json:"swap"`
+	StartTime time.Time      `json:"startTime"`
+	Warmup    warmupResponse `json:"warmup"`
+	Version   string         `json:"version"`
+}
+
+type warmupResponse struct {
+	Active    bool   `json:"active"`
+	Remaining string `json:"remaining"`
+}
+
+func (s *Service) nodeHandler(w http.ResponseWriter, r *http.Request) {
+	info, err := s.info.Info()
+	if err != nil {
+		s.logger.Debugf("debug api: node info: %v", err)
+		jsonhttp.InternalServerError(w, err)
+		return
+	}
+	jsonhttp.OK(w, nodeResponse{
+		Overlay:   info.Overlay,
+		PeerID:    info.PeerID,
+		NetworkID: info.NetworkID,
+		FullNode:  info.Capabilities.FullNode,
+		Swap:      info.Capabilities.Swap,
+		StartTime: info.StartTime,
+		Warmup: warmupResponse{
+			Active:    info.Warmup.Active,
+			Remaining: info.Warmup.Remaining.String(),
+		},
+		Version: bee.Version,
+	})
+}
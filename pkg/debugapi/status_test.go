@@ -5,12 +5,18 @@
 package debugapi_test
 
 import (
+	"errors"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/ethersphere/bee"
 	"github.com/ethersphere/bee/pkg/debugapi"
+	"github.com/ethersphere/bee/pkg/jsonhttp"
 	"github.com/ethersphere/bee/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethersphere/bee/pkg/nodeinfo"
+	nodeinfomock "github.com/ethersphere/bee/pkg/nodeinfo/mock"
+	"github.com/ethersphere/bee/pkg/swarm"
 )
 
 func TestHealth(t *testing.T) {
@@ -34,3 +40,72 @@ func TestReadiness(t *testing.T) {
 		}),
 	)
 }
+
+func TestReadinessNodeInfoError(t *testing.T) {
+	testServer := newTestServer(t, testServerOptions{
+		NodeInfo: nodeinfomock.New(nodeinfomock.WithError(errors.New("test error"))),
+	})
+
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/readiness", http.StatusServiceUnavailable,
+		jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+			Code:    http.StatusServiceUnavailable,
+			Message: http.StatusText(http.StatusServiceUnavailable),
+		}),
+	)
+}
+
+func TestNode(t *testing.T) {
+	info := nodeinfo.Info{
+		Overlay:   swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c"),
+		PeerID:    "16Uiu2HAmTBuJT9LvNmBiQiNoTsxE5mtNy6YG3paw79m94CRa9sRb",
+		NetworkID: 10,
+		Capabilities: nodeinfo.Capabilities{
+			FullNode: true,
+			Swap:     true,
+		},
+		StartTime: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	testServer := newTestServer(t, testServerOptions{
+		NodeInfo: nodeinfomock.New(nodeinfomock.WithInfo(info)),
+	})
+
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/node", http.StatusOK,
+		jsonhttptest.WithExpectedJSONResponse(debugapi.NodeResponse{
+			Overlay:   info.Overlay,
+			PeerID:    info.PeerID,
+			NetworkID: info.NetworkID,
+			FullNode:  true,
+			Swap:      true,
+			StartTime: info.StartTime,
+			Warmup:    debugapi.WarmupResponse{Remaining: "0s"},
+			Version:   bee.Version,
+		}),
+	)
+}
+
+func TestWarmup(t *testing.T) {
+	testServer := newTestServer(t, testServerOptions{
+		NodeInfo: nodeinfomock.New(nodeinfomock.WithInfo(nodeinfo.Info{
+			Warmup: nodeinfo.Warmup{
+				Active:    true,
+				Remaining: 90 * time.Second,
+			},
+		})),
+	})
+
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/readiness", http.StatusServiceUnavailable,
+		jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+			Code:    http.StatusServiceUnavailable,
+			Message: "warming up",
+		}),
+	)
+
+	var resp debugapi.NodeResponse
+	jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/node", http.StatusOK,
+		jsonhttptest.WithUnmarshalJSONResponse(&resp),
+	)
+	want := debugapi.WarmupResponse{Active: true, Remaining: "1m30s"}
+	if resp.Warmup != want {
+		t.Fatalf("got warm-up %+v, want %+v", resp.Warmup, want)
+	}
+}
@@ -6,13 +6,23 @@ package debugapi
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/ethersphere/bee/pkg/jsonhttp"
+	"github.com/ethersphere/bee/pkg/p2p"
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/ethersphere/bee/pkg/topology"
 )
 
+// topologySuggestionsLimit is the number of
+// suggested peers returned for every bin.
+const topologySuggestionsLimit = 5
+
 func (s *Service) topologyHandler(w http.ResponseWriter, r *http.Request) {
 	params := s.topologyDriver.Snapshot()
 
@@ -27,3 +37,114 @@ func (s *Service) topologyHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", jsonhttp.DefaultContentTypeHeader)
 	_, _ = io.Copy(w, bytes.NewBuffer(b))
 }
+
+type connectSuggestionResponse struct {
+	Address    swarm.Address `json:"address"`
+	Underlay   string        `json:"underlay"`
+	LastSeen   *time.Time    `json:"lastSeen,omitempty"`
+	ConnectURL string        `json:"connectURL"`
+}
+
+type binSuggestionsResponse struct {
+	Bin       uint8                       `json:"bin"`
+	Connected int                         `json:"connected"`
+	Peers     []connectSuggestionResponse `json:"peers"`
+}
+
+type topologySuggestionsResponse struct {
+	Bins  []binSuggestionsResponse `json:"bins"`
+	JobID uint64                   `json:"jobId,omitempty"`
+}
+
+type connectSuggestionResult struct {
+	Address swarm.Address `json:"address"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// topologySuggestionsHandler lists the known but disconnected peers that
+// can be connected to in the under-saturated bins. With connect=true the
+// top suggestion of every bin is dialed in a job whose id is returned.
+func (s *Service) topologySuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	var connect bool
+	if v := r.URL.Query().Get("connect"); v != "" {
+		var err error
+		if connect, err = strconv.ParseBool(v); err != nil {
+			s.logger.Debugf("debug api: topology suggestions: parse connect %s: %v", v, err)
+			jsonhttp.BadRequest(w, "invalid connect value")
+			return
+		}
+	}
+
+	suggestions, err := s.topologyDriver.ConnectSuggestions(topologySuggestionsLimit)
+	if err != nil {
+		s.logger.Debugf("debug api: topology suggestions: %v", err)
+		s.logger.Error("debug api: topology suggestions")
+		jsonhttp.InternalServerError(w, err)
+		return
+	}
+
+	resp := topologySuggestionsResponse{
+		Bins: make([]binSuggestionsResponse, 0, len(suggestions)),
+	}
+	var top []topology.ConnectSuggestion
+	for _, bs := range suggestions {
+		b := binSuggestionsResponse{
+			Bin:       bs.Bin,
+			Connected: bs.Connected,
+			Peers:     make([]connectSuggestionResponse, 0, len(bs.Peers)),
+		}
+		for _, p := range bs.Peers {
+			c := connectSuggestionResponse{
+				Address:    p.Address,
+				Underlay:   p.Underlay.String(),
+				ConnectURL: "/connect" + p.Underlay.String(),
+			}
+			if !p.LastSeen.IsZero() {
+				lastSeen := p.LastSeen
+				c.LastSeen = &lastSeen
+			}
+			b.Peers = append(b.Peers, c)
+		}
+		if len(bs.Peers) > 0 {
+			top = append(top, bs.Peers[0])
+		}
+		resp.Bins = append(resp.Bins, b)
+	}
+
+	if !connect || len(top) == 0 {
+		jsonhttp.OK(w, resp)
+		return
+	}
+
+	resp.JobID = s.jobs.start("topology connect", func(ctx context.Context) (interface{}, error) {
+		return s.connectSuggestions(ctx, top), nil
+	})
+	jsonhttp.Accepted(w, resp)
+}
+
+// connectSuggestions connects to the suggested peers one by one
+// and adds them to the topology, as the connect endpoint does.
+func (s *Service) connectSuggestions(ctx context.Context, peers []topology.ConnectSuggestion) []connectSuggestionResult {
+	results := make([]connectSuggestionResult, 0, len(peers))
+	for _, p := range peers {
+		result := connectSuggestionResult{Address: p.Address}
+		if err := s.connectSuggestion(ctx, p); err != nil {
+			s.logger.Debugf("debug api: topology connect %s: %v", p.Underlay, err)
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func (s *Service) connectSuggestion(ctx context.Context, p topology.ConnectSuggestion) error {
+	bzzAddr, err := s.p2p.Connect(ctx, p.Underlay)
+	if err != nil {
+		return err
+	}
+	if err := s.topologyDriver.Connected(ctx, p2p.Peer{Address: bzzAddr.Overlay}, true); err != nil {
+		_ = s.p2p.Disconnect(bzzAddr.Overlay)
+		return err
+	}
+	return nil
+}
@@ -5,10 +5,23 @@
 package debugapi_test
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/ethersphere/bee/pkg/bzz"
+	"github.com/ethersphere/bee/pkg/debugapi"
+	"github.com/ethersphere/bee/pkg/jsonhttp"
 	"github.com/ethersphere/bee/pkg/jsonhttp/jsonhttptest"
+	p2pmock "github.com/ethersphere/bee/pkg/p2p/mock"
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/ethersphere/bee/pkg/topology"
+	topologymock "github.com/ethersphere/bee/pkg/topology/mock"
+	ma "github.com/multiformats/go-multiaddr"
 )
 
 func TestTopologyOK(t *testing.T) {
@@ -22,3 +35,163 @@ func TestTopologyOK(t *testing.T) {
 		t.Error("empty response")
 	}
 }
+
+func TestTopologySuggestions(t *testing.T) {
+	var (
+		peer1     = swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c")
+		peer2     = swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59d")
+		peer3     = swarm.MustParseHexAddress("0a1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c")
+		underlay1 = mustMultiaddr(t, "/ip4/127.0.0.1/tcp/1634/p2p/16Uiu2HAmTBuJT9LvNmBiQiNoTsxE5mtNy6YG3paw79m94CRa9sRb")
+		underlay2 = mustMultiaddr(t, "/ip4/127.0.0.2/tcp/1634/p2p/16Uiu2HAmTBuJT9LvNmBiQiNoTsxE5mtNy6YG3paw79m94CRa9sRb")
+		underlay3 = mustMultiaddr(t, "/ip4/127.0.0.3/tcp/1634/p2p/16Uiu2HAmTBuJT9LvNmBiQiNoTsxE5mtNy6YG3paw79m94CRa9sRb")
+		lastSeen  = time.Unix(1000, 0).UTC()
+		testErr   = errors.New("test error")
+	)
+	suggestions := []topology.BinSuggestions{
+		{
+			Bin:       0,
+			Connected: 2,
+			Peers: []topology.ConnectSuggestion{
+				{Address: peer1, Underlay: underlay1, LastSeen: lastSeen},
+				{Address: peer2, Underlay: underlay2},
+			},
+		},
+		{
+			Bin:       1,
+			Connected: 0,
+		},
+		{
+			Bin:       3,
+			Connected: 1,
+			Peers: []topology.ConnectSuggestion{
+				{Address: peer3, Underlay: underlay3},
+			},
+		},
+	}
+
+	var (
+		mu    sync.Mutex
+		dials []string
+	)
+	p2ps := p2pmock.New(p2pmock.WithConnectFunc(func(_ context.Context, addr ma.Multiaddr) (*bzz.Address, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		dials = append(dials, addr.String())
+		if addr.Equal(underlay3) {
+			return nil, testErr
+		}
+		return &bzz.Address{Overlay: peer1, Underlay: addr}, nil
+	}))
+	testServer := newTestServer(t, testServerOptions{
+		P2P:          p2ps,
+		TopologyOpts: []topologymock.Option{topologymock.WithConnectSuggestions(suggestions, nil)},
+	})
+
+	want := debugapi.TopologySuggestionsResponse{
+		Bins: []debugapi.BinSuggestionsResponse{
+			{
+				Bin:       0,
+				Connected: 2,
+				Peers: []debugapi.ConnectSuggestionResponse{
+					{
+						Address:    peer1,
+						Underlay:   underlay1.String(),
+						LastSeen:   &lastSeen,
+						ConnectURL: "/connect" + underlay1.String(),
+					},
+					{
+						Address:    peer2,
+						Underlay:   underlay2.String(),
+						ConnectURL: "/connect" + underlay2.String(),
+					},
+				},
+			},
+			{
+				Bin:       1,
+				Connected: 0,
+				Peers:     []debugapi.ConnectSuggestionResponse{},
+			},
+			{
+				Bin:       3,
+				Connected: 1,
+				Peers: []debugapi.ConnectSuggestionResponse{
+					{
+						Address:    peer3,
+						Underlay:   underlay3.String(),
+						ConnectURL: "/connect" + underlay3.String(),
+					},
+				},
+			},
+		},
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		var got debugapi.TopologySuggestionsResponse
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/topology/suggestions", http.StatusOK,
+			jsonhttptest.WithUnmarshalJSONResponse(&got),
+		)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got suggestions %+v, want %+v", got, want)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(dials) != 0 {
+			t.Fatalf("got dials %v without connect", dials)
+		}
+	})
+
+	t.Run("connect", func(t *testing.T) {
+		var got debugapi.TopologySuggestionsResponse
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/topology/suggestions?connect=true", http.StatusAccepted,
+			jsonhttptest.WithUnmarshalJSONResponse(&got),
+		)
+		if got.JobID == 0 {
+			t.Fatal("got no job id")
+		}
+		if !reflect.DeepEqual(got.Bins, want.Bins) {
+			t.Fatalf("got suggestions %+v, want %+v", got.Bins, want.Bins)
+		}
+
+		job := waitJob(t, testServer.Client, got.JobID)
+		if job.Status != "done" {
+			t.Fatalf("got job status %q, want %q", job.Status, "done")
+		}
+		results, ok := job.Result.([]interface{})
+		if !ok || len(results) != 2 {
+			t.Fatalf("got job result %v, want two connect results", job.Result)
+		}
+		if failed, _ := results[1].(map[string]interface{}); failed["error"] != testErr.Error() {
+			t.Fatalf("got connect result %v, want error %q", results[1], testErr)
+		}
+
+		// only the top suggestion of every bin is dialed
+		mu.Lock()
+		defer mu.Unlock()
+		if wantDials := []string{underlay1.String(), underlay3.String()}; !reflect.DeepEqual(dials, wantDials) {
+			t.Fatalf("got dials %v, want %v", dials, wantDials)
+		}
+	})
+
+	t.Run("invalid connect", func(t *testing.T) {
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/topology/suggestions?connect=maybe", http.StatusBadRequest,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusBadRequest,
+				Message: "invalid connect value",
+			}),
+		)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		testServer := newTestServer(t, testServerOptions{
+			TopologyOpts: []topologymock.Option{topologymock.WithConnectSuggestions(nil, testErr)},
+		})
+		jsonhttptest.Request(t, testServer.Client, http.MethodGet, "/topology/suggestions", http.StatusInternalServerError,
+			jsonhttptest.WithExpectedJSONResponse(jsonhttp.StatusResponse{
+				Code:    http.StatusInternalServerError,
+				Message: testErr.Error(),
+			}),
+		)
+	})
+}
@@ -22,9 +22,14 @@ type welcomeMessageResponse struct {
 }
 
 func (s *Service) getWelcomeMessageHandler(w http.ResponseWriter, r *http.Request) {
-	val := s.p2p.GetWelcomeMessage()
+	info, err := s.info.Info()
+	if err != nil {
+		s.logger.Debugf("debugapi: welcome message: node info: %v", err)
+		jsonhttp.InternalServerError(w, err)
+		return
+	}
 	jsonhttp.OK(w, welcomeMessageResponse{
-		WelcomeMesssage: val,
+		WelcomeMesssage: info.WelcomeMessage,
 	})
 }
 
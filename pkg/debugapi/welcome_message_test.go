@@ -14,6 +14,8 @@ import (
 	"github.com/ethersphere/bee/pkg/debugapi"
 	"github.com/ethersphere/bee/pkg/jsonhttp"
 	"github.com/ethersphere/bee/pkg/jsonhttp/jsonhttptest"
+	"github.com/ethersphere/bee/pkg/nodeinfo"
+	nodeinfomock "github.com/ethersphere/bee/pkg/nodeinfo/mock"
 	"github.com/ethersphere/bee/pkg/p2p/mock"
 )
 
@@ -21,9 +23,10 @@ func TestGetWelcomeMessage(t *testing.T) {
 	const DefaultTestWelcomeMessage = "Hello World!"
 
 	srv := newTestServer(t, testServerOptions{
-		P2P: mock.New(mock.WithGetWelcomeMessageFunc(func() string {
-			return DefaultTestWelcomeMessage
-		}))})
+		NodeInfo: nodeinfomock.New(nodeinfomock.WithInfo(nodeinfo.Info{
+			WelcomeMessage: DefaultTestWelcomeMessage,
+		})),
+	})
 
 	jsonhttptest.Request(t, srv.Client, http.MethodGet, "/welcome-message", http.StatusOK,
 		jsonhttptest.WithExpectedJSONResponse(debugapi.WelcomeMessageResponse{
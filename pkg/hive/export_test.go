@@ -4,5 +4,13 @@
 
 package hive
 
+import "time"
+
 var MaxBatchSize = maxBatchSize
 var LimitBurst = limitBurst
+
+func SetTimeNow(f func() time.Time) {
+	timeNow = f
+}
+
+var MaxInfractions = maxInfractions
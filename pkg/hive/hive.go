@@ -5,9 +5,12 @@
 // Package hive exposes the hive protocol implementation
 // which is the discovery protocol used to inform and be
 // informed about other peers in the network. It gossips
-// about all peers by default and performs no specific
-// prioritization about which peers are gossipped to
-// others.
+// about all peers by default; a BroadcastFilter can be set
+// to withhold bad peer records and to prioritize the ones
+// verified by a recent connection. The received records
+// signed by the owners of the addresses are verified before
+// they are saved, and the addresses without a record are
+// skipped.
 package hive
 
 import (
@@ -15,6 +18,7 @@ import (
 	"errors"
 	"fmt"
 	"golang.org/x/sync/semaphore"
+	"sort"
 	"sync"
 	"time"
 
@@ -35,6 +39,10 @@ const (
 	peersStreamName = "peers"
 	messageTimeout  = 1 * time.Minute // maximum allowed time for a message to be read or written.
 	maxBatchSize    = 30
+	maxInfractions  = 3 // number of invalid records from a peer after which the infraction handler is called
+
+	defaultDedupWindow = 10 * time.Minute
+	defaultDedupSize   = 512
 )
 
 var (
@@ -42,25 +50,86 @@ var (
 	limitRate  = time.Minute
 
 	ErrRateLimitExceeded = errors.New("rate limit exceeded")
+
+	// timeNow is used to deterministically mock time.Now() in tests.
+	timeNow = time.Now
 )
 
+// Options for the Service.
+type Options struct {
+	// DedupWindow is the time in which a peer record is not sent to the
+	// same peer again. A negative window disables the deduplication.
+	DedupWindow time.Duration
+	// DedupSize is the number of recently sent peer records
+	// that are remembered for each connected peer.
+	DedupSize int
+	// BroadcastRate is the time in which the broadcast token bucket of a
+	// peer is refilled by one token. Every sent peer record takes a token.
+	BroadcastRate time.Duration
+	// BroadcastBurst is the size of the broadcast token bucket of a peer.
+	BroadcastBurst int
+}
+
+// BroadcastFilter holds the checks that decide which peer records are
+// gossiped to other peers. Checks that are not set are skipped.
+type BroadcastFilter struct {
+	// Blocklisted reports whether the peer is blocklisted.
+	Blocklisted func(swarm.Address) (bool, error)
+	// Unreachable reports whether connecting to the peer is
+	// currently backed off after failed attempts.
+	Unreachable func(swarm.Address) bool
+	// LastSeen returns the time of the last successful connection
+	// to the peer or the zero time if there was none.
+	LastSeen func(swarm.Address) time.Time
+	// MaxStaleness is the age of the last successful connection
+	// after which a peer record is no longer gossiped. Zero disables
+	// the check. Records without a successful connection are not
+	// considered stale, but are gossiped after the verified ones.
+	MaxStaleness time.Duration
+}
+
 type Service struct {
-	streamer        p2p.StreamerPinger
-	addressBook     addressbook.GetPutter
-	addPeersHandler func(...swarm.Address)
-	networkID       uint64
-	logger          logging.Logger
-	metrics         metrics
-	inLimiter       *ratelimit.Limiter
-	outLimiter      *ratelimit.Limiter
-	clearMtx        sync.Mutex
-	quit            chan struct{}
-	wg              sync.WaitGroup
-	peersChan       chan pb.Peers
-	sem             *semaphore.Weighted
+	streamer          p2p.StreamerPinger
+	addressBook       addressbook.DiscoveryGetPutter
+	addPeersHandler   func(...swarm.Address)
+	infractionHandler func(swarm.Address)
+	broadcastFilter   BroadcastFilter
+	networkID         uint64
+	logger            logging.Logger
+	metrics           metrics
+	inLimiter         *ratelimit.Limiter
+	outLimiter        *ratelimit.Limiter
+	clearMtx          sync.Mutex
+	infractions       map[string]int
+	dedupWindow       time.Duration
+	dedupSize         int
+	sentMtx           sync.Mutex
+	sent              map[string]*sentRecords // recently sent records by addressee
+	quit              chan struct{}
+	wg                sync.WaitGroup
+	peersChan         chan peersMsg
+	sem               *semaphore.Weighted
+}
+
+// peersMsg is the peers message together with the peer that sent it.
+type peersMsg struct {
+	from  swarm.Address
+	peers pb.Peers
 }
 
-func New(streamer p2p.StreamerPinger, addressbook addressbook.GetPutter, networkID uint64, logger logging.Logger) *Service {
+func New(streamer p2p.StreamerPinger, addressbook addressbook.DiscoveryGetPutter, networkID uint64, logger logging.Logger, o Options) *Service {
+	if o.DedupWindow == 0 {
+		o.DedupWindow = defaultDedupWindow
+	}
+	if o.DedupSize == 0 {
+		o.DedupSize = defaultDedupSize
+	}
+	if o.BroadcastRate == 0 {
+		o.BroadcastRate = limitRate
+	}
+	if o.BroadcastBurst == 0 {
+		o.BroadcastBurst = limitBurst
+	}
 	svc := &Service{
 		streamer:    streamer,
 		logger:      logger,
@@ -68,9 +137,13 @@ func New(streamer p2p.StreamerPinger, addressbook addressbook.GetPutter, network
 		networkID:   networkID,
 		metrics:     newMetrics(),
 		inLimiter:   ratelimit.New(limitRate, limitBurst),
-		outLimiter:  ratelimit.New(limitRate, limitBurst),
+		outLimiter:  ratelimit.New(o.BroadcastRate, o.BroadcastBurst),
+		infractions: make(map[string]int),
+		dedupWindow: o.DedupWindow,
+		dedupSize:   o.DedupSize,
+		sent:        make(map[string]*sentRecords),
 		quit:        make(chan struct{}),
-		peersChan:   make(chan pb.Peers),
+		peersChan:   make(chan peersMsg),
 		sem:         semaphore.NewWeighted(int64(31)),
 	}
 	svc.startCheckPeersHandler()
@@ -95,6 +168,8 @@ func (s *Service) Protocol() p2p.ProtocolSpec {
 func (s *Service) BroadcastPeers(ctx context.Context, addressee swarm.Address, peers ...swarm.Address) error {
 	max := maxBatchSize
 	s.metrics.BroadcastPeers.Inc()
+	peers = s.filterBroadcast(peers)
+	peers = s.dedupBroadcast(addressee, peers)
 	s.metrics.BroadcastPeersPeers.Add(float64(len(peers)))
 
 	for len(peers) > 0 {
@@ -104,10 +179,13 @@ func (s *Service) BroadcastPeers(ctx context.Context, addressee swarm.Address, p
 
 		// If broadcasting limit is exceeded, return early
 		if !s.outLimiter.Allow(addressee.ByteString(), max) {
+			s.metrics.BroadcastPeersRateLimited.Add(float64(len(peers)))
+			s.forgetSent(addressee, peers)
 			return nil
 		}
 
 		if err := s.sendPeers(ctx, addressee, peers[:max]); err != nil {
+			s.forgetSent(addressee, peers)
 			return err
 		}
 
@@ -121,6 +199,114 @@ func (s *Service) SetAddPeersHandler(h func(addr ...swarm.Address)) {
 	s.addPeersHandler = h
 }
 
+// SetInfractionHandler sets the handler that is called with the peer that
+// gossiped maxInfractions invalid records since it connected.
+func (s *Service) SetInfractionHandler(h func(peer swarm.Address)) {
+	s.infractionHandler = h
+}
+
+// SetBroadcastFilter sets the filter applied to the peers passed to
+// BroadcastPeers. It must be called before the first broadcast.
+func (s *Service) SetBroadcastFilter(f BroadcastFilter) {
+	s.broadcastFilter = f
+}
+
+// filterBroadcast removes the peers that should not be gossiped and orders
+// the rest by the time of the last successful connection, the most recent
+// first, so that verified records make it into the rate limited batches.
+func (s *Service) filterBroadcast(peers []swarm.Address) []swarm.Address {
+	f := s.broadcastFilter
+	if f.Blocklisted == nil && f.Unreachable == nil && f.LastSeen == nil {
+		return peers
+	}
+
+	type candidate struct {
+		addr     swarm.Address
+		lastSeen time.Time
+	}
+	now := timeNow()
+	candidates := make([]candidate, 0, len(peers))
+	for _, p := range peers {
+		if f.Blocklisted != nil {
+			blocked, err := f.Blocklisted(p)
+			if err != nil {
+				s.logger.Debugf("hive broadcast peers: blocklist check for peer %s: %v", p, err)
+			}
+			if blocked {
+				s.metrics.BroadcastPeersBlocklisted.Inc()
+				continue
+			}
+		}
+		if f.Unreachable != nil && f.Unreachable(p) {
+			s.metrics.BroadcastPeersUnreachable.Inc()
+			continue
+		}
+		var lastSeen time.Time
+		if f.LastSeen != nil {
+			lastSeen = f.LastSeen(p)
+			if f.MaxStaleness > 0 && !lastSeen.IsZero() && now.Sub(lastSeen) > f.MaxStaleness {
+				s.metrics.BroadcastPeersStale.Inc()
+				continue
+			}
+		}
+		candidates = append(candidates, candidate{addr: p, lastSeen: lastSeen})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].lastSeen.After(candidates[j].lastSeen)
+	})
+
+	filtered := make([]swarm.Address, len(candidates))
+	for i, c := range candidates {
+		filtered[i] = c.addr
+	}
+	return filtered
+}
+
+// dedupBroadcast removes the peers with records that were sent to the
+// addressee within the deduplication window and marks the rest as sent.
+func (s *Service) dedupBroadcast(addressee swarm.Address, peers []swarm.Address) []swarm.Address {
+	if s.dedupWindow < 0 {
+		return peers
+	}
+
+	s.sentMtx.Lock()
+	defer s.sentMtx.Unlock()
+
+	sent, ok := s.sent[addressee.ByteString()]
+	if !ok {
+		sent = newSentRecords(s.dedupSize)
+		s.sent[addressee.ByteString()] = sent
+	}
+
+	now := timeNow()
+	deduped := make([]swarm.Address, 0, len(peers))
+	for _, p := range peers {
+		if sent.sentSince(p, now.Add(-s.dedupWindow)) {
+			s.metrics.BroadcastPeersDuplicates.Inc()
+			continue
+		}
+		sent.add(p, now)
+		deduped = append(deduped, p)
+	}
+	return deduped
+}
+
+// forgetSent removes the peers that were not sent to
+// the addressee from its recently sent records.
+func (s *Service) forgetSent(addressee swarm.Address, peers []swarm.Address) {
+	s.sentMtx.Lock()
+	defer s.sentMtx.Unlock()
+
+	sent, ok := s.sent[addressee.ByteString()]
+	if !ok {
+		return
+	}
+	for _, p := range peers {
+		sent.remove(p)
+	}
+}
+
 func (s *Service) Close() error {
 	close(s.quit)
 
@@ -165,11 +351,22 @@ func (s *Service) sendPeers(ctx context.Context, peer swarm.Address, peers []swa
 			return err
 		}
 
+		// the addresses saved before the records were introduced, or
+		// received from the peers which do not send them, are gossiped
+		// without a record, which the receivers only skip
+		var record []byte
+		if addr.Record != nil {
+			if record, err = addr.Record.MarshalBinary(); err != nil {
+				return err
+			}
+		}
+
 		peersRequest.Peers = append(peersRequest.Peers, &pb.BzzAddress{
 			Overlay:     addr.Overlay.Bytes(),
 			Underlay:    addr.Underlay.Bytes(),
 			Signature:   addr.Signature,
 			Transaction: addr.Transaction,
+			Record:      record,
 		})
 	}
 
@@ -204,7 +401,7 @@ func (s *Service) peersHandler(ctx context.Context, peer p2p.Peer, stream p2p.St
 	go stream.FullClose()
 
 	select {
-	case s.peersChan <- peersReq:
+	case s.peersChan <- peersMsg{from: peer.Address, peers: peersReq}:
 	case <-s.quit:
 		return errors.New("failed to process peers, shutting down hive")
 	}
@@ -219,6 +416,11 @@ func (s *Service) disconnect(peer p2p.Peer) error {
 
 	s.inLimiter.Clear(peer.Address.ByteString())
 	s.outLimiter.Clear(peer.Address.ByteString())
+	delete(s.infractions, peer.Address.ByteString())
+
+	s.sentMtx.Lock()
+	delete(s.sent, peer.Address.ByteString())
+	s.sentMtx.Unlock()
 
 	return nil
 }
@@ -239,18 +441,18 @@ func (s *Service) startCheckPeersHandler() {
 			select {
 			case <-ctx.Done():
 				return
-			case newPeers := <-s.peersChan:
+			case msg := <-s.peersChan:
 				s.wg.Add(1)
 				go func() {
 					defer s.wg.Done()
-					s.checkAndAddPeers(ctx, newPeers)
+					s.checkAndAddPeers(ctx, msg.from, msg.peers)
 				}()
 			}
 		}
 	}()
 }
 
-func (s *Service) checkAndAddPeers(ctx context.Context, peers pb.Peers) {
+func (s *Service) checkAndAddPeers(ctx context.Context, from swarm.Address, peers pb.Peers) {
 
 	var peersToAdd []swarm.Address
 	mtx := sync.Mutex{}
@@ -269,6 +471,14 @@ func (s *Service) checkAndAddPeers(ctx context.Context, peers pb.Peers) {
 				wg.Done()
 			}()
 
+			// the peers running the previous versions do not send the
+			// records, which is not an infraction
+			if len(newPeer.Record) == 0 {
+				s.metrics.UnverifiedRecords.Inc()
+				s.logger.Debugf("hive: skipping peer %x without a record from peer %s", newPeer.Overlay, from)
+				return
+			}
+
 			multiUnderlay, err := ma.NewMultiaddrBytes(newPeer.Underlay)
 			if err != nil {
 				s.logger.Errorf("hive: multi address underlay err: %v", err)
@@ -283,15 +493,30 @@ func (s *Service) checkAndAddPeers(ctx context.Context, peers pb.Peers) {
 				return
 			}
 
+			record := new(bzz.Record)
+			if err := record.UnmarshalBinary(newPeer.Record); err != nil {
+				s.invalidRecord(from, newPeer, err)
+				return
+			}
+
 			bzzAddress := bzz.Address{
 				Overlay:     swarm.NewAddress(newPeer.Overlay),
 				Underlay:    multiUnderlay,
 				Signature:   newPeer.Signature,
 				Transaction: newPeer.Transaction,
+				Record:      record,
 			}
 
-			err = s.addressBook.Put(bzzAddress.Overlay, bzzAddress)
-			if err != nil {
+			err = s.addressBook.PutDiscovered(bzzAddress, s.networkID)
+			switch {
+			case errors.Is(err, addressbook.ErrStaleRecord):
+				s.metrics.StaleRecords.Inc()
+				s.logger.Debugf("hive: skipping peer in response %s: %v", bzzAddress.Overlay, err)
+				return
+			case errors.Is(err, bzz.ErrInvalidRecord), errors.Is(err, bzz.ErrRecordNetworkID):
+				s.invalidRecord(from, newPeer, err)
+				return
+			case err != nil:
 				s.logger.Warningf("skipping peer in response %s: %v", newPeer.String(), err)
 				return
 			}
@@ -308,3 +533,22 @@ func (s *Service) checkAndAddPeers(ctx context.Context, peers pb.Peers) {
 		s.addPeersHandler(peersToAdd...)
 	}
 }
+
+// invalidRecord counts an infraction against the peer that gossiped the
+// invalid record and calls the infraction handler once there are too many.
+func (s *Service) invalidRecord(from swarm.Address, record *pb.BzzAddress, err error) {
+	s.metrics.InvalidRecords.Inc()
+	s.logger.Debugf("hive: invalid record of peer %x from peer %s: %v", record.Overlay, from, err)
+
+	s.clearMtx.Lock()
+	s.infractions[from.ByteString()]++
+	exceeded := s.infractions[from.ByteString()] >= maxInfractions
+	if exceeded {
+		delete(s.infractions, from.ByteString())
+	}
+	s.clearMtx.Unlock()
+
+	if exceeded && s.infractionHandler != nil {
+		s.infractionHandler(from)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"math/rand"
 	"runtime/debug"
 	"strconv"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -25,6 +26,7 @@ import (
 	"github.com/ethersphere/bee/pkg/hive"
 	"github.com/ethersphere/bee/pkg/hive/pb"
 	"github.com/ethersphere/bee/pkg/logging"
+	"github.com/ethersphere/bee/pkg/p2p"
 	"github.com/ethersphere/bee/pkg/p2p/protobuf"
 	"github.com/ethersphere/bee/pkg/p2p/streamtest"
 	"github.com/ethersphere/bee/pkg/statestore/mock"
@@ -49,7 +51,7 @@ func TestHandlerRateLimit(t *testing.T) {
 	// new recorder for handling Ping
 	streamer := streamtest.New()
 	// create a hive server that handles the incoming stream
-	server := hive.New(streamer, addressbookclean, networkID, logger)
+	server := hive.New(streamer, addressbookclean, networkID, logger, hive.Options{})
 
 	serverAddress := test.RandomAddress()
 
@@ -62,33 +64,16 @@ func TestHandlerRateLimit(t *testing.T) {
 	peers := make([]swarm.Address, hive.LimitBurst+1)
 	for i := range peers {
 
-		underlay, err := ma.NewMultiaddr("/ip4/127.0.0.1/udp/" + strconv.Itoa(i))
-		if err != nil {
-			t.Fatal(err)
-		}
-		pk, err := crypto.GenerateSecp256k1Key()
-		if err != nil {
-			t.Fatal(err)
-		}
-		signer := crypto.NewDefaultSigner(pk)
-		overlay, err := crypto.NewOverlayAddress(pk.PublicKey, networkID, block)
-		if err != nil {
-			t.Fatal(err)
-		}
-		bzzAddr, err := bzz.NewAddress(signer, underlay, overlay, networkID, tx)
-		if err != nil {
-			t.Fatal(err)
-		}
+		bzzAddr := newTestAddress(t, i, networkID)
 
-		err = addressbook.Put(bzzAddr.Overlay, *bzzAddr)
-		if err != nil {
+		if err := addressbook.Put(bzzAddr.Overlay, *bzzAddr); err != nil {
 			t.Fatal(err)
 		}
 		peers[i] = bzzAddr.Overlay
 	}
 
 	// create a hive client that will do broadcast
-	client := hive.New(serverRecorder, addressbook, networkID, logger)
+	client := hive.New(serverRecorder, addressbook, networkID, logger, hive.Options{})
 	err := client.BroadcastPeers(context.Background(), serverAddress, peers...)
 	if err != nil {
 		t.Fatal(err)
@@ -124,36 +109,24 @@ func TestBroadcastPeers(t *testing.T) {
 	}
 
 	for i := 0; i < 2*hive.MaxBatchSize; i++ {
-		underlay, err := ma.NewMultiaddr("/ip4/127.0.0.1/udp/" + strconv.Itoa(i))
-		if err != nil {
-			t.Fatal(err)
-		}
-		pk, err := crypto.GenerateSecp256k1Key()
-		if err != nil {
-			t.Fatal(err)
-		}
-		signer := crypto.NewDefaultSigner(pk)
-		overlay, err := crypto.NewOverlayAddress(pk.PublicKey, networkID, block)
-		if err != nil {
-			t.Fatal(err)
-		}
-		bzzAddr, err := bzz.NewAddress(signer, underlay, overlay, networkID, tx)
-		if err != nil {
-			t.Fatal(err)
-		}
+		bzzAddr := newTestAddress(t, i, networkID)
 
 		bzzAddresses = append(bzzAddresses, *bzzAddr)
 		overlays = append(overlays, bzzAddr.Overlay)
-		err = addressbook.Put(bzzAddr.Overlay, *bzzAddr)
-		if err != nil {
+		if err := addressbook.Put(bzzAddr.Overlay, *bzzAddr); err != nil {
 			t.Fatal(err)
 		}
 
+		record, err := bzzAddr.Record.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
 		wantMsgs[i/hive.MaxBatchSize].Peers = append(wantMsgs[i/hive.MaxBatchSize].Peers, &pb.BzzAddress{
 			Overlay:     bzzAddresses[i].Overlay.Bytes(),
 			Underlay:    bzzAddresses[i].Underlay.Bytes(),
 			Signature:   bzzAddresses[i].Signature,
 			Transaction: tx,
+			Record:      record,
 		})
 	}
 
@@ -229,7 +202,7 @@ func TestBroadcastPeers(t *testing.T) {
 				streamer = streamtest.New()
 			}
 			// create a hive server that handles the incoming stream
-			server := hive.New(streamer, addressbookclean, networkID, logger)
+			server := hive.New(streamer, addressbookclean, networkID, logger, hive.Options{})
 
 			// setup the stream recorder to record stream data
 			recorder := streamtest.New(
@@ -237,7 +210,7 @@ func TestBroadcastPeers(t *testing.T) {
 			)
 
 			// create a hive client that will do broadcast
-			client := hive.New(recorder, addressbook, networkID, logger)
+			client := hive.New(recorder, addressbook, networkID, logger, hive.Options{})
 			if err := client.BroadcastPeers(context.Background(), tc.addresee, tc.peers...); err != nil {
 				t.Fatal(err)
 			}
@@ -269,6 +242,410 @@ func TestBroadcastPeers(t *testing.T) {
 	}
 }
 
+func TestBroadcastPeersFilter(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	addressbook := ab.New(mock.NewStateStore())
+	networkID := uint64(1)
+
+	now := time.Unix(1000000, 0)
+	hive.SetTimeNow(func() time.Time { return now })
+	defer hive.SetTimeNow(time.Now)
+
+	const maxStaleness = time.Hour
+
+	var bzzAddresses []bzz.Address
+	for i := 0; i < 6; i++ {
+		bzzAddr := newTestAddress(t, i, networkID)
+		if err := addressbook.Put(bzzAddr.Overlay, *bzzAddr); err != nil {
+			t.Fatal(err)
+		}
+		bzzAddresses = append(bzzAddresses, *bzzAddr)
+	}
+
+	var (
+		blocklisted = bzzAddresses[0]
+		unreachable = bzzAddresses[1]
+		stale       = bzzAddresses[2]
+		neverSeen   = bzzAddresses[3]
+		seen        = bzzAddresses[4]
+		connected   = bzzAddresses[5]
+	)
+	lastSeen := map[string]time.Time{
+		blocklisted.Overlay.ByteString(): now,
+		unreachable.Overlay.ByteString(): now,
+		stale.Overlay.ByteString():       now.Add(-2 * maxStaleness),
+		seen.Overlay.ByteString():        now.Add(-maxStaleness / 2),
+		connected.Overlay.ByteString():   now,
+	}
+
+	recorder := streamtest.New(
+		streamtest.WithProtocols(hive.New(streamtest.New(), ab.New(mock.NewStateStore()), networkID, logger, hive.Options{}).Protocol()),
+	)
+	client := hive.New(recorder, addressbook, networkID, logger, hive.Options{})
+	client.SetBroadcastFilter(hive.BroadcastFilter{
+		Blocklisted: func(addr swarm.Address) (bool, error) {
+			return addr.Equal(blocklisted.Overlay), nil
+		},
+		Unreachable: func(addr swarm.Address) bool {
+			return addr.Equal(unreachable.Overlay)
+		},
+		LastSeen: func(addr swarm.Address) time.Time {
+			return lastSeen[addr.ByteString()]
+		},
+		MaxStaleness: maxStaleness,
+	})
+
+	var peers []swarm.Address
+	for _, a := range bzzAddresses {
+		peers = append(peers, a.Overlay)
+	}
+	addressee := test.RandomAddress()
+	if err := client.BroadcastPeers(context.Background(), addressee, peers...); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := recorder.Records(addressee, "hive", "1.0.0", "peers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(records); l != 1 {
+		t.Fatalf("got %v records, want %v", l, 1)
+	}
+	messages, err := readAndAssertPeersMsgs(records[0].In(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// verified records first, the most recent first
+	want := []bzz.Address{connected, seen, neverSeen}
+	got := messages[0].Peers
+	if len(got) != len(want) {
+		t.Fatalf("got %d peers, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if !bytes.Equal(got[i].Overlay, w.Overlay.Bytes()) {
+			t.Fatalf("got peer %x at position %d, want %s", got[i].Overlay, i, w.Overlay)
+		}
+	}
+}
+
+func TestBroadcastPeersDedup(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	addressbook := ab.New(mock.NewStateStore())
+	networkID := uint64(1)
+
+	now := time.Unix(1000000, 0)
+	hive.SetTimeNow(func() time.Time { return now })
+	defer hive.SetTimeNow(time.Now)
+
+	const window = time.Minute
+
+	var peers []swarm.Address
+	for i := 0; i < 5; i++ {
+		bzzAddr := newTestAddress(t, i, networkID)
+		if err := addressbook.Put(bzzAddr.Overlay, *bzzAddr); err != nil {
+			t.Fatal(err)
+		}
+		peers = append(peers, bzzAddr.Overlay)
+	}
+
+	recorder := streamtest.New(
+		streamtest.WithProtocols(hive.New(streamtest.New(), ab.New(mock.NewStateStore()), networkID, logger, hive.Options{}).Protocol()),
+	)
+	client := hive.New(recorder, addressbook, networkID, logger, hive.Options{DedupWindow: window, DedupSize: 4})
+	addressee := test.RandomAddress()
+
+	broadcast := func(t *testing.T, want []swarm.Address, peers ...swarm.Address) {
+		t.Helper()
+
+		before, _ := recorder.Records(addressee, "hive", "1.0.0", "peers")
+		if err := client.BroadcastPeers(context.Background(), addressee, peers...); err != nil {
+			t.Fatal(err)
+		}
+		records, _ := recorder.Records(addressee, "hive", "1.0.0", "peers")
+		if len(want) == 0 {
+			if len(records) != len(before) {
+				t.Fatalf("got %d new records, want none", len(records)-len(before))
+			}
+			return
+		}
+		if len(records) != len(before)+1 {
+			t.Fatalf("got %d new records, want 1", len(records)-len(before))
+		}
+		messages, err := readAndAssertPeersMsgs(records[len(records)-1].In(), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := messages[0].Peers
+		if len(got) != len(want) {
+			t.Fatalf("got %d peers, want %d", len(got), len(want))
+		}
+		for i, w := range want {
+			if !bytes.Equal(got[i].Overlay, w.Bytes()) {
+				t.Fatalf("got peer %x at position %d, want %s", got[i].Overlay, i, w)
+			}
+		}
+	}
+
+	broadcast(t, peers[:3], peers[:3]...)
+
+	// records sent within the window are skipped
+	broadcast(t, nil, peers[:3]...)
+	now = now.Add(window / 2)
+	broadcast(t, peers[3:4], peers[:4]...)
+
+	// records are sent again after the window
+	now = now.Add(window/2 + time.Second)
+	broadcast(t, peers[:3], peers[:4]...)
+
+	// the least recently sent record is evicted from the full history
+	broadcast(t, peers[4:5], peers[4])
+	broadcast(t, peers[3:4], peers[3:5]...)
+
+	// the history is removed when the peer disconnects
+	if err := client.Protocol().DisconnectOut(p2p.Peer{Address: addressee}); err != nil {
+		t.Fatal(err)
+	}
+	broadcast(t, peers[:2], peers[:2]...)
+}
+
+func TestBroadcastPeersRateCap(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	addressbook := ab.New(mock.NewStateStore())
+	networkID := uint64(1)
+
+	var peers []swarm.Address
+	for i := 0; i < 6; i++ {
+		bzzAddr := newTestAddress(t, i, networkID)
+		if err := addressbook.Put(bzzAddr.Overlay, *bzzAddr); err != nil {
+			t.Fatal(err)
+		}
+		peers = append(peers, bzzAddr.Overlay)
+	}
+
+	recorder := streamtest.New(
+		streamtest.WithProtocols(hive.New(streamtest.New(), ab.New(mock.NewStateStore()), networkID, logger, hive.Options{}).Protocol()),
+	)
+	client := hive.New(recorder, addressbook, networkID, logger, hive.Options{BroadcastRate: time.Hour, BroadcastBurst: 4})
+	addressee := test.RandomAddress()
+
+	if err := client.BroadcastPeers(context.Background(), addressee, peers[:3]...); err != nil {
+		t.Fatal(err)
+	}
+	// the bucket has a single token left
+	if err := client.BroadcastPeers(context.Background(), addressee, peers[3:5]...); err != nil {
+		t.Fatal(err)
+	}
+	// the records that were not sent are not deduplicated
+	if err := client.BroadcastPeers(context.Background(), addressee, peers[3]); err != nil {
+		t.Fatal(err)
+	}
+	// the bucket is empty
+	if err := client.BroadcastPeers(context.Background(), addressee, peers[5]); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := recorder.Records(addressee, "hive", "1.0.0", "peers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(records); l != 2 {
+		t.Fatalf("got %d records, want %d", l, 2)
+	}
+	for i, want := range [][]swarm.Address{peers[:3], peers[3:4]} {
+		messages, err := readAndAssertPeersMsgs(records[i].In(), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := messages[0].Peers; len(got) != len(want) || !bytes.Equal(got[0].Overlay, want[0].Bytes()) {
+			t.Fatalf("record %d: got peers %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestBroadcastPeersWithoutRecord(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	networkID := uint64(1)
+	addressbook := ab.New(mock.NewStateStore())
+
+	// the address saved before the records were introduced
+	legacy := newTestAddress(t, 0, networkID)
+	legacy.Record = nil
+	if err := addressbook.Put(legacy.Overlay, *legacy); err != nil {
+		t.Fatal(err)
+	}
+	valid := newTestAddress(t, 1, networkID)
+	if err := addressbook.Put(valid.Overlay, *valid); err != nil {
+		t.Fatal(err)
+	}
+
+	serverAddressbook := ab.New(mock.NewStateStore())
+	server := hive.New(streamtest.New(), serverAddressbook, networkID, logger, hive.Options{})
+	var infractions int32
+	server.SetInfractionHandler(func(swarm.Address) {
+		atomic.AddInt32(&infractions, 1)
+	})
+
+	addressee := test.RandomAddress()
+	recorder := streamtest.New(
+		streamtest.WithProtocols(server.Protocol()),
+		streamtest.WithBaseAddr(addressee),
+	)
+	client := hive.New(recorder, addressbook, networkID, logger, hive.Options{DedupWindow: -1})
+	for i := 0; i < hive.MaxInfractions; i++ {
+		if err := client.BroadcastPeers(context.Background(), addressee, legacy.Overlay); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the address is gossiped without a record
+	records, err := recorder.Records(addressee, "hive", "1.0.0", "peers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(records); l != hive.MaxInfractions {
+		t.Fatalf("got %d records, want %d", l, hive.MaxInfractions)
+	}
+	messages, err := readAndAssertPeersMsgs(records[0].In(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := messages[0].Peers; len(got) != 1 || !bytes.Equal(got[0].Overlay, legacy.Overlay.Bytes()) || len(got[0].Record) != 0 {
+		t.Fatalf("got peers %v, want %s without a record", got, legacy.Overlay)
+	}
+
+	// and the receiver skips it without an infraction, the gossiped
+	// messages are processed in order
+	if err := client.BroadcastPeers(context.Background(), addressee, valid.Overlay); err != nil {
+		t.Fatal(err)
+	}
+	expectOverlaysEventually(t, serverAddressbook, []swarm.Address{valid.Overlay})
+	if got := atomic.LoadInt32(&infractions); got != 0 {
+		t.Fatalf("got %d infractions, want none", got)
+	}
+}
+
+func TestPeersHandlerInvalidRecords(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	networkID := uint64(1)
+	addressbook := ab.New(mock.NewStateStore())
+
+	valid := newTestAddress(t, 0, networkID)
+
+	tampered := newTestAddress(t, 1, networkID)
+	tampered.Record.Timestamp++
+
+	wrongNetwork := newTestAddress(t, 2, networkID)
+	wrongNetwork.Record.NetworkID = 2
+
+	noRecord := newTestAddress(t, 3, networkID)
+	noRecord.Record = nil
+
+	garbled := newTestAddress(t, 5, networkID)
+
+	// the saved record of the stale address is more recent
+	stale := newTestAddress(t, 4, networkID)
+	if err := addressbook.Put(stale.Overlay, *stale); err != nil {
+		t.Fatal(err)
+	}
+	staleRecord := *stale.Record
+	staleRecord.Timestamp--
+	stale.Record = &staleRecord
+
+	server := hive.New(streamtest.New(), addressbook, networkID, logger, hive.Options{})
+	infractions := make(chan swarm.Address, 1)
+	server.SetInfractionHandler(func(peer swarm.Address) {
+		infractions <- peer
+	})
+
+	gossiper := test.RandomAddress()
+	recorder := streamtest.New(
+		streamtest.WithProtocols(server.Protocol()),
+		streamtest.WithBaseAddr(gossiper),
+	)
+	stream, err := recorder.NewStream(context.Background(), test.RandomAddress(), nil, "hive", "1.0.0", "peers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	var msg pb.Peers
+	for _, a := range []*bzz.Address{valid, tampered, wrongNetwork, noRecord, stale, garbled} {
+		var record []byte
+		if a.Record != nil {
+			if record, err = a.Record.MarshalBinary(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if a == garbled {
+			record = record[:len(record)/2]
+		}
+		msg.Peers = append(msg.Peers, &pb.BzzAddress{
+			Overlay:     a.Overlay.Bytes(),
+			Underlay:    a.Underlay.Bytes(),
+			Signature:   a.Signature,
+			Transaction: a.Transaction,
+			Record:      record,
+		})
+	}
+	w, _ := protobuf.NewWriterAndReader(stream)
+	if err := w.WriteMsgWithContext(context.Background(), &msg); err != nil {
+		t.Fatal(err)
+	}
+
+	// the three invalid records are an infraction of the gossiping peer,
+	// while the address without a record is skipped
+	if hive.MaxInfractions != 3 {
+		t.Fatalf("got %d max infractions, want %d", hive.MaxInfractions, 3)
+	}
+	select {
+	case peer := <-infractions:
+		if !peer.Equal(gossiper) {
+			t.Fatalf("got infraction of peer %s, want %s", peer, gossiper)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the infraction")
+	}
+
+	expectOverlaysEventually(t, addressbook, []swarm.Address{valid.Overlay, stale.Overlay})
+	got, err := addressbook.Get(stale.Overlay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Record.Timestamp != staleRecord.Timestamp+1 {
+		t.Fatal("stale record replaced the saved one")
+	}
+}
+
+// newTestAddress returns the address of a new peer
+// with the record signed by the peer.
+func newTestAddress(t *testing.T, i int, networkID uint64) *bzz.Address {
+	t.Helper()
+
+	underlay, err := ma.NewMultiaddr("/ip4/127.0.0.1/udp/" + strconv.Itoa(i))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := crypto.NewDefaultSigner(pk)
+	overlay, err := crypto.NewOverlayAddress(pk.PublicKey, networkID, block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bzzAddr, err := bzz.NewAddress(signer, underlay, overlay, networkID, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bzzAddr.Record, err = bzz.NewRecord(signer, overlay, []ma.Multiaddr{underlay}, networkID, block, time.Now().UnixNano())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bzzAddr
+}
+
 func expectOverlaysEventually(t *testing.T, exporter ab.Interface, wantOverlays []swarm.Address) {
 	var (
 		overlays []swarm.Address
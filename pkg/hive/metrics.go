@@ -14,9 +14,18 @@ type metrics struct {
 	BroadcastPeersPeers prometheus.Counter
 	BroadcastPeersSends prometheus.Counter
 
+	BroadcastPeersBlocklisted prometheus.Counter
+	BroadcastPeersUnreachable prometheus.Counter
+	BroadcastPeersStale       prometheus.Counter
+	BroadcastPeersDuplicates  prometheus.Counter
+	BroadcastPeersRateLimited prometheus.Counter
+
 	PeersHandler      prometheus.Counter
 	PeersHandlerPeers prometheus.Counter
 	UnreachablePeers  prometheus.Counter
+	InvalidRecords    prometheus.Counter
+	UnverifiedRecords prometheus.Counter
+	StaleRecords      prometheus.Counter
 }
 
 func newMetrics() metrics {
@@ -41,6 +50,36 @@ func newMetrics() metrics {
 			Name:      "broadcast_peers_message_count",
 			Help:      "Number of individual peer gossip messages sent.",
 		}),
+		BroadcastPeersBlocklisted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "broadcast_peers_blocklisted_count",
+			Help:      "Number of blocklisted peers not gossiped.",
+		}),
+		BroadcastPeersUnreachable: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "broadcast_peers_unreachable_count",
+			Help:      "Number of unreachable peers not gossiped.",
+		}),
+		BroadcastPeersStale: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "broadcast_peers_stale_count",
+			Help:      "Number of peers with stale records not gossiped.",
+		}),
+		BroadcastPeersDuplicates: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "broadcast_peers_duplicate_count",
+			Help:      "Number of peers not gossiped as their records were recently sent to the same peer.",
+		}),
+		BroadcastPeersRateLimited: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "broadcast_peers_rate_limited_count",
+			Help:      "Number of peers not gossiped as the broadcast rate limit was exceeded.",
+		}),
 		PeersHandler: prometheus.NewCounter(prometheus.CounterOpts{
 			Namespace: m.Namespace,
 			Subsystem: subsystem,
@@ -59,6 +98,24 @@ func newMetrics() metrics {
 			Name:      "unreachable_peers_count",
 			Help:      "Number of peers that are unreachable.",
 		}),
+		InvalidRecords: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "invalid_records_count",
+			Help:      "Number of received peer records with an invalid signature or network id.",
+		}),
+		UnverifiedRecords: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "unverified_records_count",
+			Help:      "Number of received peers without a record, which are skipped.",
+		}),
+		StaleRecords: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "stale_records_count",
+			Help:      "Number of received peer records older than the saved ones.",
+		}),
 	}
 }
 
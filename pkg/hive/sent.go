@@ -0,0 +1,64 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hive
+
+import (
+	"container/list"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+// sentRecords is a bounded LRU of the peer records recently sent to one
+// peer. When it is full, the record sent the longest time ago is evicted.
+type sentRecords struct {
+	size  int
+	order *list.List               // sent records, the most recently sent first
+	items map[string]*list.Element // elements of order by overlay
+}
+
+type sentRecord struct {
+	overlay string
+	sent    time.Time
+}
+
+func newSentRecords(size int) *sentRecords {
+	return &sentRecords{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// sentSince reports whether the record of the overlay was sent after t.
+func (r *sentRecords) sentSince(overlay swarm.Address, t time.Time) bool {
+	e, ok := r.items[overlay.ByteString()]
+	return ok && e.Value.(*sentRecord).sent.After(t)
+}
+
+// add records that the record of the overlay was sent at t.
+func (r *sentRecords) add(overlay swarm.Address, t time.Time) {
+	key := overlay.ByteString()
+	if e, ok := r.items[key]; ok {
+		e.Value.(*sentRecord).sent = t
+		r.order.MoveToFront(e)
+		return
+	}
+	r.items[key] = r.order.PushFront(&sentRecord{overlay: key, sent: t})
+	if r.order.Len() > r.size {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.items, oldest.Value.(*sentRecord).overlay)
+	}
+}
+
+// remove forgets the record of the overlay.
+func (r *sentRecords) remove(overlay swarm.Address) {
+	key := overlay.ByteString()
+	if e, ok := r.items[key]; ok {
+		r.order.Remove(e)
+		delete(r.items, key)
+	}
+}
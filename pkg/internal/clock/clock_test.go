@@ -0,0 +1,112 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/internal/clock"
+)
+
+var start = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestMockNow(t *testing.T) {
+	c := clock.NewMock(start)
+
+	c.Advance(time.Minute)
+	if got, want := c.Now(), start.Add(time.Minute); !got.Equal(want) {
+		t.Fatalf("got now %s, want %s", got, want)
+	}
+	if got := c.Since(start); got != time.Minute {
+		t.Fatalf("got since %s, want %s", got, time.Minute)
+	}
+}
+
+func TestMockTimer(t *testing.T) {
+	c := clock.NewMock(start)
+	timer := c.NewTimer(time.Second)
+
+	c.Advance(999 * time.Millisecond)
+	expectNotFired(t, timer.C())
+
+	c.Advance(time.Millisecond)
+	expectFired(t, timer.C(), start.Add(time.Second))
+
+	// fired timers do not fire again
+	c.Advance(time.Hour)
+	expectNotFired(t, timer.C())
+	if timer.Stop() {
+		t.Fatal("stopped a fired timer")
+	}
+
+	if timer.Reset(time.Second) {
+		t.Fatal("reset reported an active timer")
+	}
+	if !timer.Stop() {
+		t.Fatal("did not stop an active timer")
+	}
+	c.Advance(time.Hour)
+	expectNotFired(t, timer.C())
+}
+
+func TestMockTicker(t *testing.T) {
+	c := clock.NewMock(start)
+	ticker := c.NewTicker(time.Second)
+
+	c.Advance(time.Second)
+	expectFired(t, ticker.C(), start.Add(time.Second))
+
+	// missed ticks are dropped
+	c.Advance(3 * time.Second)
+	expectFired(t, ticker.C(), start.Add(2*time.Second))
+	expectNotFired(t, ticker.C())
+
+	ticker.Stop()
+	c.Advance(time.Hour)
+	expectNotFired(t, ticker.C())
+}
+
+func TestMockAdvance(t *testing.T) {
+	c := clock.NewMock(start)
+
+	durations := []time.Duration{2 * time.Second, time.Second, 2 * time.Second}
+	var timers []clock.Timer
+	for _, d := range durations {
+		timers = append(timers, c.NewTimer(d))
+	}
+
+	// all due timers fire with the time at which they are due
+	c.Advance(time.Minute)
+	for i, timer := range timers {
+		expectFired(t, timer.C(), start.Add(durations[i]))
+	}
+	if got, want := c.Now(), start.Add(time.Minute); !got.Equal(want) {
+		t.Fatalf("got now %s, want %s", got, want)
+	}
+}
+
+func expectFired(t *testing.T, c <-chan time.Time, want time.Time) {
+	t.Helper()
+
+	select {
+	case got := <-c:
+		if !got.Equal(want) {
+			t.Fatalf("got time %s, want %s", got, want)
+		}
+	default:
+		t.Fatal("not fired")
+	}
+}
+
+func expectNotFired(t *testing.T, c <-chan time.Time) {
+	t.Helper()
+
+	select {
+	case got := <-c:
+		t.Fatalf("fired at %s", got)
+	default:
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Mock is a Clock which time only changes with Advance.
+// It is intended to be used in tests.
+type Mock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*mockTimer // active timers and tickers in the order of creation
+}
+
+// NewMock returns a Mock clock set to the given time.
+func NewMock(now time.Time) *Mock {
+	return &Mock{now: now}
+}
+
+func (m *Mock) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.now
+}
+
+func (m *Mock) Since(t time.Time) time.Duration {
+	return m.Now().Sub(t)
+}
+
+func (m *Mock) NewTimer(d time.Duration) Timer {
+	return m.newTimer(d, 0)
+}
+
+func (m *Mock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	return mockTicker{m.newTimer(d, d)}
+}
+
+func (m *Mock) newTimer(d, period time.Duration) *mockTimer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := &mockTimer{
+		m:      m,
+		c:      make(chan time.Time, 1),
+		when:   m.now.Add(d),
+		period: period,
+	}
+	m.timers = append(m.timers, t)
+	return t
+}
+
+// Advance moves the time forward by d. The timers and tickers that are due
+// fire in the order of their times, and of their creation for equal times.
+// Like with the time package, a ticker drops the ticks its reader misses.
+func (m *Mock) Advance(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	end := m.now.Add(d)
+	for {
+		var next *mockTimer
+		for _, t := range m.timers {
+			if !t.when.After(end) && (next == nil || t.when.Before(next.when)) {
+				next = t
+			}
+		}
+		if next == nil {
+			break
+		}
+		m.now = next.when
+		select {
+		case next.c <- next.when:
+		default:
+		}
+		if next.period > 0 {
+			next.when = next.when.Add(next.period)
+		} else {
+			m.remove(next)
+		}
+	}
+	m.now = end
+}
+
+// remove deactivates the timer and reports whether it was active.
+// Must be called with mu locked.
+func (m *Mock) remove(t *mockTimer) bool {
+	for i, v := range m.timers {
+		if v == t {
+			m.timers = append(m.timers[:i], m.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type mockTimer struct {
+	m      *Mock
+	c      chan time.Time
+	when   time.Time
+	period time.Duration // zero for timers
+}
+
+func (t *mockTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *mockTimer) Stop() bool {
+	t.m.mu.Lock()
+	defer t.m.mu.Unlock()
+
+	return t.m.remove(t)
+}
+
+func (t *mockTimer) Reset(d time.Duration) bool {
+	t.m.mu.Lock()
+	defer t.m.mu.Unlock()
+
+	active := t.m.remove(t)
+	t.when = t.m.now.Add(d)
+	t.m.timers = append(t.m.timers, t)
+	return active
+}
+
+type mockTicker struct {
+	*mockTimer
+}
+
+func (t mockTicker) Stop() {
+	t.mockTimer.Stop()
+}
@@ -0,0 +1,40 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logging
+
+import (
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// PeerKey is the log field with the full hex overlay address of a peer.
+	PeerKey = "peer"
+	// PeerShortKey is the log field with the first characters
+	// of the hex overlay address of a peer.
+	PeerShortKey = "peer_short"
+
+	peerShortLength = 8
+)
+
+// PeerFields returns the log fields that identify the peer. They are the
+// same in all packages, so that the logs of a peer can be found reliably.
+func PeerFields(peer swarm.Address) logrus.Fields {
+	s := peer.String()
+	short := s
+	if len(short) > peerShortLength {
+		short = short[:peerShortLength]
+	}
+	return logrus.Fields{
+		PeerKey:      s,
+		PeerShortKey: short,
+	}
+}
+
+// WithPeer returns a log entry with the fields that identify the peer.
+// It should be used for all log messages about events of a single peer.
+func WithPeer(l Logger, peer swarm.Address) *logrus.Entry {
+	return l.WithFields(PeerFields(peer))
+}
@@ -0,0 +1,35 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package logging_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/logging"
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/sirupsen/logrus"
+)
+
+func TestWithPeer(t *testing.T) {
+	peer := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c")
+
+	var buf bytes.Buffer
+	logger := logging.New(&buf, logrus.InfoLevel)
+
+	logging.WithPeer(logger, peer).Info("connected")
+
+	line := buf.String()
+	for _, want := range []string{
+		"peer=ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c",
+		"peer_short=ca1e9f39",
+		`msg=connected`,
+	} {
+		if !strings.Contains(line, want) {
+			t.Errorf("log line %q does not contain %q", line, want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package persistent provides prometheus counters with totals that are kept
+// in the state store, so that long running statistics survive restarts.
+package persistent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/storage"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	keyPrefix = "persistent-counter-"
+
+	defaultFlushEvery    = 100
+	defaultFlushInterval = time.Minute
+)
+
+// timeNow is used to deterministically mock time.Now() in tests.
+var timeNow = time.Now
+
+func init() {
+	storage.RegisterPrefix(keyPrefix, func(_, value []byte) error {
+		var r record
+		return json.Unmarshal(value, &r)
+	})
+}
+
+// record is the persisted state of a counter.
+type record struct {
+	Total   uint64 `json:"total"`
+	Updated int64  `json:"updated"` // time of the last counted increment in unix nanoseconds
+}
+
+// Options configures how often a Counter writes its total to the state store.
+type Options struct {
+	// FlushEvery is the number of increments after which the total is persisted.
+	FlushEvery int
+	// FlushInterval is the time after which the total is persisted on the
+	// next increment, even if there were fewer than FlushEvery increments.
+	FlushInterval time.Duration
+}
+
+// Counter is a prometheus counter that keeps its total in the state
+// store, so that it is not reset when the node restarts. The total is written
+// at most once per FlushEvery increments or per FlushInterval, and on Flush.
+// After an unclean shutdown the counter undercounts by the increments since
+// the last write, it never overcounts as only counted totals are written.
+type Counter struct {
+	prometheus.CounterFunc
+
+	store         storage.StateStorer
+	key           string
+	flushEvery    uint64
+	flushInterval time.Duration
+
+	mtx       sync.Mutex
+	total     uint64    // total including the increments that are not persisted
+	persisted uint64    // total in the state store
+	updated   time.Time // time of the last increment
+	lastFlush time.Time // time of the last write
+}
+
+// NewCounter returns a counter with the total restored from the
+// store. The total is stored under a key derived from the counter name.
+func NewCounter(store storage.StateStorer, opts prometheus.CounterOpts, o Options) (*Counter, error) {
+	if o.FlushEvery == 0 {
+		o.FlushEvery = defaultFlushEvery
+	}
+	if o.FlushInterval == 0 {
+		o.FlushInterval = defaultFlushInterval
+	}
+	c := &Counter{
+		store:         store,
+		key:           keyPrefix + prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name),
+		flushEvery:    uint64(o.FlushEvery),
+		flushInterval: o.FlushInterval,
+		lastFlush:     timeNow(),
+	}
+	var r record
+	if err := store.Get(c.key, &r); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("load counter %s: %w", c.key, err)
+	}
+	c.total, c.persisted = r.Total, r.Total
+	if r.Updated > 0 {
+		c.updated = time.Unix(0, r.Updated)
+	}
+	c.CounterFunc = prometheus.NewCounterFunc(opts, c.Value)
+	return c, nil
+}
+
+// Inc increments the counter and persists the total if enough increments or
+// time have passed since the last write. A failed write is retried on the
+// next increment.
+func (c *Counter) Inc() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.total++
+	c.updated = timeNow()
+	if c.total-c.persisted >= c.flushEvery || timeNow().Sub(c.lastFlush) >= c.flushInterval {
+		_ = c.flush()
+	}
+}
+
+// Value returns the current total of the counter.
+func (c *Counter) Value() float64 {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return float64(c.total)
+}
+
+// LastIncrement returns the time of the last increment, restored from the
+// store after a restart. The zero time is returned if there was none.
+func (c *Counter) LastIncrement() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.updated
+}
+
+// Flush persists the increments that have not been written yet. It is
+// intended to be called when the node shuts down.
+func (c *Counter) Flush() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.flush()
+}
+
+// flush must be called with mtx locked.
+func (c *Counter) flush() error {
+	if c.total == c.persisted {
+		return nil
+	}
+	c.lastFlush = timeNow()
+	if err := c.store.Put(c.key, record{Total: c.total, Updated: c.updated.UnixNano()}); err != nil {
+		return fmt.Errorf("persist counter %s: %w", c.key, err)
+	}
+	c.persisted = c.total
+	return nil
+}
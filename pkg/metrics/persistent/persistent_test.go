@@ -0,0 +1,127 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package persistent_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/metrics/persistent"
+	"github.com/ethersphere/bee/pkg/statestore/mock"
+	"github.com/ethersphere/bee/pkg/storage"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const key = "persistent-counter-bee_test_count"
+
+var opts = prometheus.CounterOpts{
+	Namespace: "bee",
+	Subsystem: "test",
+	Name:      "count",
+}
+
+func stored(t *testing.T, store storage.StateStorer) uint64 {
+	t.Helper()
+
+	var r struct {
+		Total uint64 `json:"total"`
+	}
+	if err := store.Get(key, &r); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		t.Fatal(err)
+	}
+	return r.Total
+}
+
+func TestCounterFlushBatching(t *testing.T) {
+	now := time.Unix(1000, 0)
+	persistent.SetTimeNow(func() time.Time { return now })
+	defer persistent.SetTimeNow(time.Now)
+
+	store := mock.NewStateStore()
+	c, err := persistent.NewCounter(store, opts, persistent.Options{FlushEvery: 3, FlushInterval: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the total is written once per three increments
+	for i := 1; i <= 7; i++ {
+		c.Inc()
+		if got, want := stored(t, store), uint64(i/3*3); got != want {
+			t.Fatalf("increment %d: got stored total %d, want %d", i, got, want)
+		}
+		if got := c.Value(); got != float64(i) {
+			t.Fatalf("increment %d: got value %v, want %d", i, got, i)
+		}
+	}
+
+	// the interval flushes fewer increments on the next increment
+	now = now.Add(time.Minute)
+	c.Inc()
+	if got := stored(t, store); got != 8 {
+		t.Fatalf("got stored total %d, want %d", got, 8)
+	}
+
+	c.Inc()
+	if got := stored(t, store); got != 8 {
+		t.Fatalf("got stored total %d, want %d", got, 8)
+	}
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := stored(t, store); got != 9 {
+		t.Fatalf("got stored total %d after flush, want %d", got, 9)
+	}
+}
+
+func TestCounterRestore(t *testing.T) {
+	now := time.Unix(1000, 0)
+	persistent.SetTimeNow(func() time.Time { return now })
+	defer persistent.SetTimeNow(time.Now)
+
+	store := mock.NewStateStore()
+	o := persistent.Options{FlushEvery: 10}
+
+	c, err := persistent.NewCounter(store, opts, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.LastIncrement().IsZero() {
+		t.Fatalf("got last increment %s, want zero time", c.LastIncrement())
+	}
+	for i := 0; i < 5; i++ {
+		c.Inc()
+	}
+	incremented := now
+	now = now.Add(time.Second)
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// clean restart restores the flushed total
+	c, err = persistent.NewCounter(store, opts, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Value(); got != 5 {
+		t.Fatalf("got value %v after restart, want %d", got, 5)
+	}
+	if got := c.LastIncrement(); !got.Equal(incremented) {
+		t.Fatalf("got last increment %s after restart, want %s", got, incremented)
+	}
+
+	// unclean restart loses the increments that were not
+	// flushed, the counter never counts more than happened
+	for i := 0; i < 12; i++ {
+		c.Inc()
+	}
+	c, err = persistent.NewCounter(store, opts, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Value(); got != 15 {
+		t.Fatalf("got value %v after unclean restart, want %d", got, 15)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Registry is the prometheus registry shared by the components of a node.
+// Registering a collector with the same descriptors as an already registered
+// one is ignored, so that a component that is constructed more than once,
+// for example in tests, does not make the registration panic.
+type Registry struct {
+	*prometheus.Registry
+}
+
+// NewRegistry returns a new empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{Registry: prometheus.NewRegistry()}
+}
+
+// Register implements prometheus.Registerer. A collector with the same
+// descriptors as an already registered one is not registered again and
+// no error is returned.
+func (r *Registry) Register(c prometheus.Collector) error {
+	err := r.Registry.Register(c)
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		return nil
+	}
+	return err
+}
+
+// MustRegister implements prometheus.Registerer. It panics on any
+// registration error other than a duplicate registration.
+func (r *Registry) MustRegister(cs ...prometheus.Collector) {
+	for _, c := range cs {
+		if err := r.Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// MustRegisterComponents registers the metrics of all components that
+// implement the Collector interface. Other components and nil pointers
+// are skipped.
+func (r *Registry) MustRegisterComponents(components ...interface{}) {
+	for _, c := range components {
+		if v := reflect.ValueOf(c); !v.IsValid() || v.Kind() == reflect.Ptr && v.IsNil() {
+			continue
+		}
+		if mc, ok := c.(Collector); ok {
+			r.MustRegister(mc.Metrics()...)
+		}
+	}
+}
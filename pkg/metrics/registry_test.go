@@ -0,0 +1,65 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package metrics_test
+
+import (
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRegistryDuplicates(t *testing.T) {
+	r := metrics.NewRegistry()
+
+	// a component constructed twice registers equal collectors
+	r.MustRegisterComponents(newComponent(), newComponent())
+
+	families, err := r.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(families) != 2 {
+		t.Fatalf("got %d metric families, want %d", len(families), 2)
+	}
+
+	// a different collector with a conflicting name is still an error
+	err = r.Register(prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "api",
+		Name:      "request_count",
+		Help:      "Conflicting help.",
+	}))
+	if err == nil {
+		t.Fatal("expected registration error")
+	}
+}
+
+func TestRegistryComponents(t *testing.T) {
+	r := metrics.NewRegistry()
+
+	var nilComponent *component
+	r.MustRegisterComponents(nil, nilComponent, "not a collector", newComponent())
+
+	families, err := r.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(families) != 2 {
+		t.Fatalf("got %d metric families, want %d", len(families), 2)
+	}
+}
+
+type component struct {
+	s *service
+}
+
+func newComponent() *component {
+	return &component{s: newService()}
+}
+
+func (c *component) Metrics() []prometheus.Collector {
+	return metrics.PrometheusCollectorsFromFields(c.s)
+}
@@ -0,0 +1,12 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package node
+
+const (
+	IdentityKey           = identityKey
+	IdentityArchivePrefix = identityArchivePrefix
+)
+
+var SweepStateStore = sweepStateStore
@@ -0,0 +1,208 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package node
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/crypto"
+	"github.com/ethersphere/bee/pkg/logging"
+	"github.com/ethersphere/bee/pkg/storage"
+	"github.com/ethersphere/bee/pkg/swarm"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	identityKey           = "identity-libp2p"
+	identitySaltKey       = "identity-salt"
+	identityArchivePrefix = "identity-archive-"
+)
+
+// ErrIdentityCorrupted is returned when the persisted identity
+// record can not be decrypted or decoded.
+var ErrIdentityCorrupted = errors.New("identity record corrupted")
+
+// identityRecord is the persisted libp2p identity of the node together
+// with the nonce that the swarm overlay was derived with.
+type identityRecord struct {
+	PrivateKey   []byte `json:"privateKey"`
+	OverlayNonce []byte `json:"overlayNonce,omitempty"`
+}
+
+// IdentityStore returns the encrypted state store namespace in which the
+// node identity is kept. The encryption key is derived from the password
+// and a random salt that is persisted in the state store on the first use.
+// With an empty password the key is derived from the salt alone, which is
+// kept next to the record, so the record is only obfuscated.
+func IdentityStore(store storage.StateStorer, password string) (storage.StateStorer, error) {
+	var salt []byte
+	err := store.Get(identitySaltKey, &salt)
+	if errors.Is(err, storage.ErrNotFound) {
+		salt = make([]byte, 32)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return nil, fmt.Errorf("identity salt: %w", err)
+		}
+		err = store.Put(identitySaltKey, salt)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("identity salt: %w", err)
+	}
+
+	k, err := scrypt.Key([]byte(password), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, fmt.Errorf("identity key derivation: %w", err)
+	}
+	var key [32]byte
+	copy(key[:], k)
+
+	return storage.NewEncryptedStore(store, key), nil
+}
+
+// LoadIdentity returns the libp2p private key persisted in the identity
+// store. On the first start the fallback key is persisted and returned. If
+// the fallback key is nil, a new key is generated.
+//
+// A record that can not be decrypted, most likely because the keystore
+// password was changed, is archived like in ResetIdentity and replaced by
+// the fallback key encrypted with the current password. Without a fallback
+// key ErrIdentityCorrupted is returned.
+func LoadIdentity(logger logging.Logger, stateStore, identityStore storage.StateStorer, fallback *ecdsa.PrivateKey) (*ecdsa.PrivateKey, error) {
+	r, err := getIdentity(identityStore)
+	if err == nil {
+		return r.privateKey()
+	}
+	switch {
+	case errors.Is(err, ErrIdentityCorrupted) && fallback != nil:
+		logger.Warningf("libp2p identity: %v; the keystore password may have changed, using the keyfile key and archiving the previous identity in the state store", err)
+		if err := archiveIdentity(stateStore); err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, storage.ErrNotFound):
+		return nil, err
+	}
+
+	key := fallback
+	if key == nil {
+		if key, err = crypto.GenerateSecp256k1Key(); err != nil {
+			return nil, err
+		}
+	}
+	if err := putIdentity(identityStore, &identityRecord{PrivateKey: crypto.EncodeSecp256k1PrivateKey(key)}); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ResetIdentity replaces the persisted libp2p private key with a newly
+// generated one. The previous record, if any, is archived verbatim in the
+// state store under a key with the identityArchivePrefix and the time of
+// the reset, so that a record that can not be decrypted is kept as well.
+// An archived record is restored by putting it back under identityKey.
+func ResetIdentity(stateStore, identityStore storage.StateStorer) (*ecdsa.PrivateKey, error) {
+	if err := archiveIdentity(stateStore); err != nil {
+		return nil, err
+	}
+
+	key, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		return nil, err
+	}
+	if err := putIdentity(identityStore, &identityRecord{PrivateKey: crypto.EncodeSecp256k1PrivateKey(key)}); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// CheckIdentityOverlay derives the swarm overlay from the public key and
+// the nonce and validates it against the overlay persisted in the state
+// store. The nonce is persisted in the identity record on the first start
+// and a different nonce on later starts is refused.
+func CheckIdentityOverlay(identityStore, stateStore storage.StateStorer, publicKey ecdsa.PublicKey, networkID uint64, nonce []byte) (swarm.Address, error) {
+	r, err := getIdentity(identityStore)
+	if err != nil {
+		return swarm.ZeroAddress, err
+	}
+	if r.OverlayNonce != nil && !bytes.Equal(r.OverlayNonce, nonce) {
+		return swarm.ZeroAddress, fmt.Errorf("overlay nonce changed. was %x before but now is %x", r.OverlayNonce, nonce)
+	}
+
+	overlay, err := crypto.NewOverlayAddress(publicKey, networkID, nonce)
+	if err != nil {
+		return swarm.ZeroAddress, err
+	}
+	if err := CheckOverlayWithStore(overlay, stateStore); err != nil {
+		return swarm.ZeroAddress, err
+	}
+
+	if r.OverlayNonce == nil {
+		r.OverlayNonce = nonce
+		if err := putIdentity(identityStore, r); err != nil {
+			return swarm.ZeroAddress, err
+		}
+	}
+	return overlay, nil
+}
+
+// archiveIdentity copies the persisted identity record, if any, verbatim
+// under a key with the identityArchivePrefix and the current time.
+func archiveIdentity(stateStore storage.StateStorer) error {
+	var old rawRecord
+	err := stateStore.Get(identityKey, &old)
+	switch {
+	case err == nil:
+		archiveKey := fmt.Sprintf("%s%d", identityArchivePrefix, time.Now().UnixNano())
+		if err := stateStore.Put(archiveKey, old); err != nil {
+			return fmt.Errorf("archive identity: %w", err)
+		}
+	case !errors.Is(err, storage.ErrNotFound):
+		return fmt.Errorf("archive identity: %w", err)
+	}
+	return nil
+}
+
+func getIdentity(store storage.StateStorer) (*identityRecord, error) {
+	r := new(identityRecord)
+	if err := store.Get(identityKey, r); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrIdentityCorrupted, err)
+	}
+	return r, nil
+}
+
+func putIdentity(store storage.StateStorer, r *identityRecord) error {
+	if err := store.Put(identityKey, r); err != nil {
+		return fmt.Errorf("persist identity: %w", err)
+	}
+	return nil
+}
+
+func (r *identityRecord) privateKey() (*ecdsa.PrivateKey, error) {
+	key, err := crypto.DecodeSecp256k1PrivateKey(r.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIdentityCorrupted, err)
+	}
+	return key, nil
+}
+
+// rawRecord passes the stored bytes of a record through
+// the state store without decoding them.
+type rawRecord []byte
+
+func (r rawRecord) MarshalBinary() ([]byte, error) {
+	return r, nil
+}
+
+func (r *rawRecord) UnmarshalBinary(data []byte) error {
+	*r = append((*r)[:0], data...)
+	return nil
+}
@@ -0,0 +1,256 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package node_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/crypto"
+	"github.com/ethersphere/bee/pkg/logging"
+	"github.com/ethersphere/bee/pkg/node"
+	"github.com/ethersphere/bee/pkg/statestore/mock"
+)
+
+func TestLoadIdentity(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	stateStore := mock.NewStateStore()
+
+	keyfile, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// first start persists the key from the keyfile
+	identityStore, err := node.IdentityStore(stateStore, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := node.LoadIdentity(logger, stateStore, identityStore, keyfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.Equal(keyfile) {
+		t.Fatal("first start did not use the keyfile key")
+	}
+
+	// the key is not stored in plaintext
+	var raw []byte
+	if err := stateStore.Get(node.IdentityKey, &raw); err == nil {
+		t.Fatal("identity record is readable without the password")
+	}
+
+	// restart with a lost keyfile reuses the persisted key
+	newKeyfile, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	identityStore, err = node.IdentityStore(stateStore, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err = node.LoadIdentity(logger, stateStore, identityStore, newKeyfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.Equal(keyfile) {
+		t.Fatal("restart did not reuse the persisted key")
+	}
+
+	// wrong password without a keyfile key to fall back to
+	identityStore, err = node.IdentityStore(stateStore, "wrong password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := node.LoadIdentity(logger, stateStore, identityStore, nil); !errors.Is(err, node.ErrIdentityCorrupted) {
+		t.Fatalf("got error %v, want %v", err, node.ErrIdentityCorrupted)
+	}
+}
+
+func TestLoadIdentityGenerate(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	stateStore := mock.NewStateStore()
+	identityStore, err := node.IdentityStore(stateStore, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := node.LoadIdentity(logger, stateStore, identityStore, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key == nil {
+		t.Fatal("no key generated")
+	}
+
+	loaded, err := node.LoadIdentity(logger, stateStore, identityStore, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Equal(key) {
+		t.Fatal("generated key was not persisted")
+	}
+}
+
+func TestLoadIdentityCorrupted(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	stateStore := mock.NewStateStore()
+	if err := stateStore.Put(node.IdentityKey, "corrupted"); err != nil {
+		t.Fatal(err)
+	}
+
+	identityStore, err := node.IdentityStore(stateStore, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := node.LoadIdentity(logger, stateStore, identityStore, nil); !errors.Is(err, node.ErrIdentityCorrupted) {
+		t.Fatalf("got error %v, want %v", err, node.ErrIdentityCorrupted)
+	}
+
+	// reset archives the corrupted record and replaces it
+	key, err := node.ResetIdentity(stateStore, identityStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := node.LoadIdentity(logger, stateStore, identityStore, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Equal(key) {
+		t.Fatal("reset key was not persisted")
+	}
+
+	var archived []string
+	if err := stateStore.Iterate(node.IdentityArchivePrefix, func(k, v []byte) (bool, error) {
+		if strings.HasPrefix(string(k), node.IdentityArchivePrefix) {
+			archived = append(archived, string(v))
+		}
+		return false, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(archived) != 1 || archived[0] != `"corrupted"` {
+		t.Fatalf("got archived records %q, want the corrupted record", archived)
+	}
+}
+
+func TestLoadIdentityPasswordChange(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	stateStore := mock.NewStateStore()
+
+	keyfile, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	identityStore, err := node.IdentityStore(stateStore, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := node.LoadIdentity(logger, stateStore, identityStore, keyfile); err != nil {
+		t.Fatal(err)
+	}
+	var old []byte
+	if err := stateStore.Get(node.IdentityKey, (*rawValue)(&old)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the keystore password changed, the keyfile key is used
+	// and the record is encrypted with the new password
+	identityStore, err = node.IdentityStore(stateStore, "new password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := node.LoadIdentity(logger, stateStore, identityStore, keyfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.Equal(keyfile) {
+		t.Fatal("password change did not use the keyfile key")
+	}
+
+	key, err = node.LoadIdentity(logger, stateStore, identityStore, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.Equal(keyfile) {
+		t.Fatal("key was not encrypted with the new password")
+	}
+
+	var archived [][]byte
+	if err := stateStore.Iterate(node.IdentityArchivePrefix, func(k, v []byte) (bool, error) {
+		if strings.HasPrefix(string(k), node.IdentityArchivePrefix) {
+			archived = append(archived, v)
+		}
+		return false, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(archived) != 1 || !bytes.Equal(archived[0], old) {
+		t.Fatal("record encrypted with the old password was not archived")
+	}
+}
+
+// rawValue reads the stored bytes of a state store entry.
+type rawValue []byte
+
+func (v *rawValue) UnmarshalBinary(data []byte) error {
+	*v = append((*v)[:0], data...)
+	return nil
+}
+
+func TestCheckIdentityOverlay(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	stateStore := mock.NewStateStore()
+	identityStore, err := node.IdentityStore(stateStore, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := node.LoadIdentity(logger, stateStore, identityStore, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	swarmKey, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, 32)
+
+	overlay, err := node.CheckIdentityOverlay(identityStore, stateStore, swarmKey.PublicKey, 1, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := crypto.NewOverlayAddress(swarmKey.PublicKey, 1, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !overlay.Equal(want) {
+		t.Fatalf("got overlay %s, want %s", overlay, want)
+	}
+
+	// restart with the same key and nonce
+	if _, err := node.CheckIdentityOverlay(identityStore, stateStore, swarmKey.PublicKey, 1, nonce); err != nil {
+		t.Fatal(err)
+	}
+
+	// a different nonce is refused
+	otherNonce := make([]byte, 32)
+	otherNonce[0] = 1
+	if _, err := node.CheckIdentityOverlay(identityStore, stateStore, swarmKey.PublicKey, 1, otherNonce); err == nil {
+		t.Fatal("expected error for changed nonce")
+	}
+
+	// a different swarm key derives a mismatching overlay
+	otherKey, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := node.CheckIdentityOverlay(identityStore, stateStore, otherKey.PublicKey, 1, nonce); err == nil {
+		t.Fatal("expected error for mismatching overlay")
+	}
+}
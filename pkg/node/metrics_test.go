@@ -0,0 +1,151 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package node_test
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethersphere/bee/pkg/addressbook"
+	"github.com/ethersphere/bee/pkg/crypto"
+	"github.com/ethersphere/bee/pkg/debugapi"
+	mockdiscovery "github.com/ethersphere/bee/pkg/discovery/mock"
+	"github.com/ethersphere/bee/pkg/hive"
+	"github.com/ethersphere/bee/pkg/logging"
+	"github.com/ethersphere/bee/pkg/metrics"
+	"github.com/ethersphere/bee/pkg/p2p/libp2p"
+	p2pmock "github.com/ethersphere/bee/pkg/p2p/mock"
+	"github.com/ethersphere/bee/pkg/p2p/streamtest"
+	"github.com/ethersphere/bee/pkg/pingpong"
+	"github.com/ethersphere/bee/pkg/reputation"
+	"github.com/ethersphere/bee/pkg/shed"
+	"github.com/ethersphere/bee/pkg/statestore/mock"
+	"github.com/ethersphere/bee/pkg/storage"
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/ethersphere/bee/pkg/swarm/test"
+	"github.com/ethersphere/bee/pkg/topology/kademlia"
+	"github.com/ethersphere/bee/pkg/topology/lightnode"
+)
+
+func TestMetricsRegistry(t *testing.T) {
+	logger := logging.New(ioutil.Discard, 0)
+	base := test.RandomAddress()
+	stateStore := storage.NewCachedStore(mock.NewStateStore(), 100)
+	addressBook := addressbook.New(stateStore)
+
+	metricsDB, err := shed.NewDB("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := metricsDB.Close(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	peerReputation, err := reputation.New(stateStore, reputation.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the p2p service also exposes the metrics of its breakers and blocklist
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	key, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2ps, err := libp2p.New(ctx, crypto.NewDefaultSigner(key), 1, base, "127.0.0.1:0", addressBook, stateStore, lightnode.NewContainer(base), senderMatcher{}, logger, nil, libp2p.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p2ps.Close()
+
+	registry := metrics.NewRegistry()
+	registry.MustRegisterComponents(p2ps)
+
+	// components constructed more than once must not panic the registry
+	for i := 0; i < 2; i++ {
+		streamer := streamtest.New()
+		hiveService := hive.New(streamer, addressBook, 1, logger, hive.Options{})
+		kad := kademlia.New(base, addressBook, mockdiscovery.NewDiscovery(), p2pmock.New(), metricsDB, logger, kademlia.Options{Reputation: peerReputation})
+
+		registry.MustRegisterComponents(
+			hiveService,
+			kad,
+			metricsDB,
+			peerReputation,
+			pingpong.New(streamer, logger, nil),
+			lightnode.NewContainer(base),
+			stateStore,
+		)
+	}
+
+	debugAPIService := debugapi.New(key.PublicKey, key.PublicKey, common.Address{}, logger, nil, nil, nil, registry)
+	// the debug api registers its collectors in the same registry
+	_ = debugapi.New(key.PublicKey, key.PublicKey, common.Address{}, logger, nil, nil, nil, registry)
+
+	server := httptest.NewServer(debugAPIService)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	var families []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if name := strings.TrimPrefix(scanner.Text(), "# TYPE "); name != scanner.Text() {
+			families = append(families, strings.Fields(name)[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, prefix := range []string{
+		"go_",
+		"bee_info",
+		"bee_hive_",
+		"bee_kademlia_",
+		"bee_shed_",
+		"bee_reputation_",
+		"bee_pingpong_",
+		"bee_lightnode_",
+		"bee_statestore_",
+		"bee_libp2p_",
+		"bee_breaker_",
+		"bee_blocklist_",
+	} {
+		var found bool
+		for _, f := range families {
+			if strings.HasPrefix(f, prefix) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("no metric family with prefix %q", prefix)
+		}
+	}
+}
+
+// senderMatcher matches all transactions of the handshakes.
+type senderMatcher struct{}
+
+func (senderMatcher) Matches(context.Context, []byte, uint64, swarm.Address) ([]byte, error) {
+	return nil, nil
+}
@@ -38,6 +38,7 @@ import (
 	"github.com/ethersphere/bee/pkg/logging"
 	"github.com/ethersphere/bee/pkg/metrics"
 	"github.com/ethersphere/bee/pkg/netstore"
+	"github.com/ethersphere/bee/pkg/nodeinfo"
 	"github.com/ethersphere/bee/pkg/p2p"
 	"github.com/ethersphere/bee/pkg/p2p/libp2p"
 	"github.com/ethersphere/bee/pkg/pingpong"
@@ -56,6 +57,7 @@ import (
 	"github.com/ethersphere/bee/pkg/pusher"
 	"github.com/ethersphere/bee/pkg/pushsync"
 	"github.com/ethersphere/bee/pkg/recovery"
+	"github.com/ethersphere/bee/pkg/reputation"
 	"github.com/ethersphere/bee/pkg/resolver/multiresolver"
 	"github.com/ethersphere/bee/pkg/retrieval"
 	"github.com/ethersphere/bee/pkg/settlement/pseudosettle"
@@ -83,15 +85,18 @@ import (
 type Bee struct {
 	p2pService               io.Closer
 	p2pHalter                p2p.Halter
+	p2pDrainer               p2pDrainer
 	p2pCancel                context.CancelFunc
 	apiCloser                io.Closer
 	apiServer                *http.Server
 	debugAPIServer           *http.Server
+	debugAPIService          *debugapi.Service
 	resolverCloser           io.Closer
 	errorLogWriter           *io.PipeWriter
 	tracerCloser             io.Closer
 	tagsCloser               io.Closer
 	stateStoreCloser         io.Closer
+	stateStoreSweepStop      func()
 	localstoreCloser         io.Closer
 	topologyCloser           io.Closer
 	topologyHalter           topology.Halter
@@ -108,8 +113,17 @@ type Bee struct {
 	postageServiceCloser     io.Closer
 	priceOracleCloser        io.Closer
 	hiveCloser               io.Closer
+	shutdownGracePeriod      time.Duration
 	shutdownInProgress       bool
 	shutdownMutex            sync.Mutex
+	logger                   logging.Logger
+}
+
+// p2pDrainer closes the peer connections and waits
+// for the running protocol handlers on shutdown.
+type p2pDrainer interface {
+	DisconnectAll(ctx context.Context) error
+	Drain(ctx context.Context, grace time.Duration) error
 }
 
 type Options struct {
@@ -121,6 +135,8 @@ type Options struct {
 	DBDisableSeeksCompaction   bool
 	APIAddr                    string
 	DebugAPIAddr               string
+	DebugAPIMaxConnectTimeout  time.Duration
+	DebugAPIReadinessMinPeers  int
 	Addr                       string
 	NATAddr                    string
 	EnableWS                   bool
@@ -154,14 +170,54 @@ type Options struct {
 	DeployGasPrice             string
 	WarmupTime                 time.Duration
 	ChainID                    int64
+	StateStoreSweepBudget      time.Duration
+	ShutdownGracePeriod        time.Duration
+	IdentityPassword           string
+	ResetIdentity              bool
+	AdvertiseWarmupTime        time.Duration
 }
 
 const (
 	refreshRate = int64(4500000)
 	basePrice   = 10000
+
+	// kademliaDepthHysteresis is the time an increased neighborhood
+	// depth has to be stable before it is reported to the consumers.
+	kademliaDepthHysteresis = time.Minute
+
+	// kademliaWarmupBinPeers is the number of connected peers in each
+	// of the shallow bins after which the advertisement warm-up ends.
+	kademliaWarmupBinPeers = 2
+
+	// hiveBroadcastMaxStaleness is the age of the last successful
+	// connection to a peer after which its record is not gossiped.
+	hiveBroadcastMaxStaleness = 24 * time.Hour
+
+	// hiveInfractionBlocklistDuration is the time for which a peer
+	// that gossips invalid address records is blocklisted.
+	hiveInfractionBlocklistDuration = time.Hour
+
+	// hiveInfractionPenalty is subtracted from the reputation score
+	// of a peer that gossips invalid address records.
+	hiveInfractionPenalty = 10
+
+	// defaultShutdownGracePeriod is the time the running protocol
+	// handlers have to return on shutdown before they are cancelled.
+	defaultShutdownGracePeriod = 5 * time.Second
+
+	// shutdownDisconnectTimeout is the time to close
+	// the connections to all peers on shutdown.
+	shutdownDisconnectTimeout = 5 * time.Second
+
+	// pingpongBreakerLimit is the number of consecutive failed pingpong
+	// handlers of a peer after which its pings are not handled for the
+	// backoff of the breaker.
+	pingpongBreakerLimit = 10
 )
 
 func NewBee(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, networkID uint64, logger logging.Logger, libp2pPrivateKey, pssPrivateKey *ecdsa.PrivateKey, o *Options) (b *Bee, err error) {
+	startTime := time.Now()
+
 	tracer, tracerCloser, err := tracing.NewTracer(&tracing.Options{
 		Enabled:     o.TracingEnabled,
 		Endpoint:    o.TracingEndpoint,
@@ -187,17 +243,57 @@ func NewBee(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 		warmupTime = 0
 	}
 
+	shutdownGracePeriod := o.ShutdownGracePeriod
+	if shutdownGracePeriod == 0 {
+		shutdownGracePeriod = defaultShutdownGracePeriod
+	}
+
 	b = &Bee{
-		p2pCancel:      p2pCancel,
-		errorLogWriter: logger.WriterLevel(logrus.ErrorLevel),
-		tracerCloser:   tracerCloser,
+		p2pCancel:           p2pCancel,
+		errorLogWriter:      logger.WriterLevel(logrus.ErrorLevel),
+		tracerCloser:        tracerCloser,
+		shutdownGracePeriod: shutdownGracePeriod,
+		logger:              logger,
 	}
 
 	stateStore, err := InitStateStore(logger, o.DataDir)
 	if err != nil {
 		return nil, err
 	}
+	stateStoreMaintainer, _ := stateStore.(storage.StateStoreMaintainer)
 	b.stateStoreCloser = stateStore
+	// keep the records read on the hot paths in memory, the batches and the
+	// reserve state of the batch store and the sync intervals of the puller
+	cachedStateStore := storage.NewCachedStore(stateStore, stateStoreCacheEntries)
+
+	// the blocklist of the p2p service is swept before the service is
+	// created, as it keeps its entries in memory
+	b.stateStoreSweepStop = sweepStateStore(logger, stateStore, o.StateStoreSweepBudget, libp2p.StateStorePrefixes())
+	defer func() {
+		if err != nil {
+			b.stateStoreSweepStop()
+		}
+	}()
+
+	if o.IdentityPassword == "" {
+		logger.Warning("no password set, the libp2p identity is persisted in the state store without encryption; anyone with access to the data directory can read the libp2p private key")
+	}
+	identityStore, err := IdentityStore(stateStore, o.IdentityPassword)
+	if err != nil {
+		return nil, err
+	}
+	if o.ResetIdentity {
+		libp2pPrivateKey, err = ResetIdentity(stateStore, identityStore)
+		if err != nil {
+			return nil, fmt.Errorf("reset identity: %w", err)
+		}
+		logger.Info("libp2p identity reset, the previous identity is archived in the state store")
+	} else {
+		libp2pPrivateKey, err = LoadIdentity(logger, stateStore, identityStore, libp2pPrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("load identity: %w", err)
+		}
+	}
 
 	addressbook := addressbook.New(stateStore)
 
@@ -232,6 +328,9 @@ func NewBee(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 		return nil, fmt.Errorf("connected to wrong ethereum network: got chainID %d, want %d", chainID, o.ChainID)
 	}
 
+	// metrics of all components are registered in the same registry
+	metricsRegistry := metrics.NewRegistry()
+
 	var debugAPIService *debugapi.Service
 	if o.DebugAPIAddr != "" {
 		overlayEthAddress, err := signer.EthereumAddress()
@@ -239,7 +338,7 @@ func NewBee(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 			return nil, fmt.Errorf("eth address: %w", err)
 		}
 		// set up basic debug api endpoints for debugging and /health endpoint
-		debugAPIService = debugapi.New(*publicKey, pssPrivateKey.PublicKey, overlayEthAddress, logger, tracer, o.CORSAllowedOrigins, transactionService)
+		debugAPIService = debugapi.New(*publicKey, pssPrivateKey.PublicKey, overlayEthAddress, logger, tracer, o.CORSAllowedOrigins, transactionService, metricsRegistry)
 
 		debugAPIListener, err := net.Listen("tcp", o.DebugAPIAddr)
 		if err != nil {
@@ -263,6 +362,7 @@ func NewBee(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 		}()
 
 		b.debugAPIServer = debugAPIServer
+		b.debugAPIService = debugAPIService
 	}
 
 	// Sync the with the given Ethereum backend:
@@ -343,11 +443,9 @@ func NewBee(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 		return nil, fmt.Errorf("invalid block hash: %w", err)
 	}
 
-	swarmAddress, err := crypto.NewOverlayAddress(*pubKey, networkID, blockHash)
-
-	err = CheckOverlayWithStore(swarmAddress, stateStore)
+	swarmAddress, err := CheckIdentityOverlay(identityStore, stateStore, *pubKey, networkID, blockHash)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("check overlay: %w", err)
 	}
 
 	lightNodes := lightnode.NewContainer(swarmAddress)
@@ -362,12 +460,14 @@ func NewBee(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 		WelcomeMessage: o.WelcomeMessage,
 		FullNode:       o.FullNodeMode,
 		Transaction:    txHash,
+		Nonce:          blockHash,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("p2p service: %w", err)
 	}
 	b.p2pService = p2ps
 	b.p2pHalter = p2ps
+	b.p2pDrainer = p2ps
 
 	var unreserveFn func([]byte, uint8) (uint64, error)
 	var evictFn = func(b []byte) error {
@@ -375,7 +475,7 @@ func NewBee(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 		return err
 	}
 
-	batchStore, err := batchstore.New(stateStore, evictFn, logger)
+	batchStore, err := batchstore.New(cachedStateStore, evictFn, logger)
 	if err != nil {
 		return nil, fmt.Errorf("batchstore: %w", err)
 	}
@@ -470,15 +570,21 @@ func NewBee(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 	// Construct protocols.
 	pingPong := pingpong.New(p2ps, logger, tracer)
 
-	if err = p2ps.AddProtocol(pingPong.Protocol()); err != nil {
+	pingPongBreakers, err := p2ps.HandlerBreakers("pingpong", pingpongBreakerLimit)
+	if err != nil {
+		return nil, fmt.Errorf("pingpong service: %w", err)
+	}
+	pingPongSpec := pingPong.Protocol()
+	p2p.WithBreakerStreams(pingPongBreakers, pingPongSpec)
+	if err = p2ps.AddProtocol(pingPongSpec); err != nil {
 		return nil, fmt.Errorf("pingpong service: %w", err)
 	}
 
-	hive := hive.New(p2ps, addressbook, networkID, logger)
-	if err = p2ps.AddProtocol(hive.Protocol()); err != nil {
+	hiveService := hive.New(p2ps, addressbook, networkID, logger, hive.Options{})
+	if err = p2ps.AddProtocol(hiveService.Protocol()); err != nil {
 		return nil, fmt.Errorf("hive service: %w", err)
 	}
-	b.hiveCloser = hive
+	b.hiveCloser = hiveService
 
 	var bootnodes []ma.Multiaddr
 
@@ -500,10 +606,29 @@ func NewBee(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 		return nil, fmt.Errorf("unable to create metrics storage for kademlia: %w", err)
 	}
 
-	kad := kademlia.New(swarmAddress, addressbook, hive, p2ps, metricsDB, logger, kademlia.Options{Bootnodes: bootnodes, BootnodeMode: o.BootnodeMode})
+	peerReputation, err := reputation.New(stateStore, reputation.Options{})
+	if err != nil {
+		return nil, fmt.Errorf("reputation: %w", err)
+	}
+
+	kad := kademlia.New(swarmAddress, addressbook, hiveService, p2ps, metricsDB, logger, kademlia.Options{Bootnodes: bootnodes, BootnodeMode: o.BootnodeMode, DepthHysteresis: kademliaDepthHysteresis, Reputation: peerReputation, LightNode: !o.FullNodeMode, StateStore: stateStore, Warmup: o.AdvertiseWarmupTime, WarmupBinPeers: kademliaWarmupBinPeers})
 	b.topologyCloser = kad
 	b.topologyHalter = kad
-	hive.SetAddPeersHandler(kad.AddPeers)
+	hiveService.SetAddPeersHandler(kad.AddPeers)
+	hiveService.SetBroadcastFilter(hive.BroadcastFilter{
+		Blocklisted:  p2ps.Blocklisted,
+		Unreachable:  kad.Unreachable,
+		LastSeen:     kad.LastSeen,
+		MaxStaleness: hiveBroadcastMaxStaleness,
+	})
+	hiveService.SetInfractionHandler(func(peer swarm.Address) {
+		if err := peerReputation.Infraction(peer, hiveInfractionPenalty); err != nil {
+			logger.Debugf("hive: record infraction of peer %s: %v", peer, err)
+		}
+		if err := p2ps.Blocklist(peer, hiveInfractionBlocklistDuration); err != nil {
+			logger.Debugf("hive: blocklist peer %s: %v", peer, err)
+		}
+	})
 	p2ps.SetPickyNotifier(kad)
 	batchStore.SetRadiusSetter(kad)
 
@@ -613,6 +738,8 @@ func NewBee(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 	pricing.SetPaymentThresholdObserver(acc)
 
 	retrieve := retrieval.New(swarmAddress, storer, p2ps, kad, logger, acc, pricer, tracer, o.RetrievalCaching, validStamp)
+	retryBudget := p2p.NewRetryBudget(p2p.RetryBudgetOptions{})
+	retrieve.SetRetryBudget(retryBudget)
 	tagService := tags.NewTags(stateStore, logger)
 	b.tagsCloser = tagService
 
@@ -653,7 +780,7 @@ func NewBee(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 
 	var pullerService *puller.Puller
 	if o.FullNodeMode {
-		pullerService := puller.New(stateStore, kad, pullSyncProtocol, logger, puller.Options{}, warmupTime)
+		pullerService = puller.New(cachedStateStore, kad, pullSyncProtocol, logger, puller.Options{}, warmupTime)
 		b.pullerCloser = pullerService
 	}
 
@@ -665,9 +792,9 @@ func NewBee(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 		logger.Info("starting in full mode")
 	} else {
 		logger.Info("starting in light mode")
+		// the storage protocols are not registered by the light node
+		// p2p service, retrieval requests from peers are refused
 		p2p.WithBlocklistStreams(p2p.DefaultBlocklistTime, retrieveProtocolSpec)
-		p2p.WithBlocklistStreams(p2p.DefaultBlocklistTime, pushSyncProtocolSpec)
-		p2p.WithBlocklistStreams(p2p.DefaultBlocklistTime, pullSyncProtocolSpec)
 	}
 
 	if err = p2ps.AddProtocol(retrieveProtocolSpec); err != nil {
@@ -721,55 +848,61 @@ func NewBee(addr string, publicKey *ecdsa.PublicKey, signer crypto.Signer, netwo
 		b.apiCloser = apiService
 	}
 
-	if debugAPIService != nil {
-		// register metrics from components
-		debugAPIService.MustRegisterMetrics(p2ps.Metrics()...)
-		debugAPIService.MustRegisterMetrics(pingPong.Metrics()...)
-		debugAPIService.MustRegisterMetrics(acc.Metrics()...)
-		debugAPIService.MustRegisterMetrics(storer.Metrics()...)
-		debugAPIService.MustRegisterMetrics(kad.Metrics()...)
-
-		if pullerService != nil {
-			debugAPIService.MustRegisterMetrics(pullerService.Metrics()...)
-		}
-
-		debugAPIService.MustRegisterMetrics(pushSyncProtocol.Metrics()...)
-		debugAPIService.MustRegisterMetrics(pusherService.Metrics()...)
-		debugAPIService.MustRegisterMetrics(pullSyncProtocol.Metrics()...)
-		debugAPIService.MustRegisterMetrics(pullStorage.Metrics()...)
-		debugAPIService.MustRegisterMetrics(retrieve.Metrics()...)
-		debugAPIService.MustRegisterMetrics(lightNodes.Metrics()...)
-		debugAPIService.MustRegisterMetrics(hive.Metrics()...)
-
-		if bs, ok := batchStore.(metrics.Collector); ok {
-			debugAPIService.MustRegisterMetrics(bs.Metrics()...)
-		}
-
-		if eventListener != nil {
-			if ls, ok := eventListener.(metrics.Collector); ok {
-				debugAPIService.MustRegisterMetrics(ls.Metrics()...)
-			}
-		}
-
-		if pssServiceMetrics, ok := pssService.(metrics.Collector); ok {
-			debugAPIService.MustRegisterMetrics(pssServiceMetrics.Metrics()...)
-		}
-
-		if apiService != nil {
-			debugAPIService.MustRegisterMetrics(apiService.Metrics()...)
-		}
-		if l, ok := logger.(metrics.Collector); ok {
-			debugAPIService.MustRegisterMetrics(l.Metrics()...)
-		}
-
-		debugAPIService.MustRegisterMetrics(pseudosettleService.Metrics()...)
+	// register metrics from components, the ones
+	// that are not constructed are skipped
+	metricsRegistry.MustRegisterComponents(
+		p2ps,
+		pingPong,
+		acc,
+		storer,
+		kad,
+		metricsDB,
+		peerReputation,
+		pullerService,
+		pushSyncProtocol,
+		pusherService,
+		pullSyncProtocol,
+		pullStorage,
+		retrieve,
+		retryBudget,
+		lightNodes,
+		hiveService,
+		cachedStateStore,
+		batchStore,
+		eventListener,
+		pssService,
+		apiService,
+		logger,
+		pseudosettleService,
+		swapService,
+	)
 
-		if swapService != nil {
-			debugAPIService.MustRegisterMetrics(swapService.Metrics()...)
+	if debugAPIService != nil {
+		debugAPIService.SetSelfTest(debugapi.SelfTestOptions{
+			Bootnodes:             o.Bootnodes,
+			LastInboundConnection: p2ps.LastInboundConnection,
+		})
+		debugAPIService.SetMaxConnectTimeout(o.DebugAPIMaxConnectTimeout)
+		debugAPIService.SetBlocklister(p2ps)
+		debugAPIService.AddHealthChecker(debugapi.NewListenHealthChecker(p2ps))
+		debugAPIService.AddHealthChecker(debugapi.NewStateStoreHealthChecker(stateStore))
+		if o.DebugAPIReadinessMinPeers > 0 {
+			debugAPIService.AddHealthChecker(debugapi.NewPeersHealthChecker(p2ps, o.DebugAPIReadinessMinPeers))
 		}
-
 		// inject dependencies and configure full debug api http path routes
-		debugAPIService.Configure(swarmAddress, p2ps, pingPong, kad, lightNodes, storer, tagService, acc, pseudosettleService, o.SwapEnable, swapService, chequebookService, batchStore, post, postageContractService)
+		info := &nodeInfo{
+			overlay:   swarmAddress,
+			peerID:    p2ps.PeerID().String(),
+			networkID: networkID,
+			capabilities: nodeinfo.Capabilities{
+				FullNode: o.FullNodeMode,
+				Swap:     o.SwapEnable,
+			},
+			startTime: startTime,
+			warmup:    kad.Warmup,
+			p2p:       p2ps,
+		}
+		debugAPIService.Configure(info, p2ps, pingPong, kad, lightNodes, storer, tagService, acc, pseudosettleService, o.SwapEnable, swapService, chequebookService, batchStore, post, postageContractService, stateStoreMaintainer, stateStore)
 	}
 
 	if err := kad.Start(p2pCtx); err != nil {
@@ -792,13 +925,6 @@ func (b *Bee) Shutdown(ctx context.Context) error {
 	b.shutdownInProgress = true
 	b.shutdownMutex.Unlock()
 
-	// halt kademlia while shutting down other
-	// components.
-	b.topologyHalter.Halt()
-
-	// halt p2p layer from accepting new connections
-	// while shutting down other components
-	b.p2pHalter.Halt()
 	// tryClose is a convenient closure which decrease
 	// repetitive io.Closer tryClose procedure.
 	tryClose := func(c io.Closer, errMsg string) {
@@ -810,95 +936,146 @@ func (b *Bee) Shutdown(ctx context.Context) error {
 		}
 	}
 
-	tryClose(b.apiCloser, "api")
+	// the components are shut down in stages, so that no component
+	// is used by another one after it has been closed
+	stage := func(name string, f func()) {
+		start := time.Now()
+		f()
+		b.logger.Infof("shutdown: %s in %s", name, time.Since(start))
+	}
+
+	// the liveness and the readiness probes fail while shutting down
+	if b.debugAPIService != nil {
+		b.debugAPIService.SetShuttingDown()
+	}
+
+	stage("stopped accepting connections and requests", func() {
+		// halt kademlia from initiating new connections
+		b.topologyHalter.Halt()
+
+		// halt p2p layer from accepting new connections and streams
+		b.p2pHalter.Halt()
+
+		tryClose(b.apiCloser, "api")
+
+		var eg errgroup.Group
+		if b.apiServer != nil {
+			eg.Go(func() error {
+				if err := b.apiServer.Shutdown(ctx); err != nil {
+					return fmt.Errorf("api server: %w", err)
+				}
+				return nil
+			})
+		}
+		if b.debugAPIServer != nil {
+			eg.Go(func() error {
+				if err := b.debugAPIServer.Shutdown(ctx); err != nil {
+					return fmt.Errorf("debug api server: %w", err)
+				}
+				return nil
+			})
+		}
 
-	var eg errgroup.Group
-	if b.apiServer != nil {
-		eg.Go(func() error {
-			if err := b.apiServer.Shutdown(ctx); err != nil {
-				return fmt.Errorf("api server: %w", err)
+		if err := eg.Wait(); err != nil {
+			mErr = multierror.Append(mErr, err)
+		}
+
+		if b.recoveryHandleCleanup != nil {
+			b.recoveryHandleCleanup()
+		}
+	})
+
+	if b.p2pDrainer != nil {
+		stage("disconnected peers", func() {
+			ctx, cancel := context.WithTimeout(ctx, shutdownDisconnectTimeout)
+			defer cancel()
+			if err := b.p2pDrainer.DisconnectAll(ctx); err != nil {
+				b.logger.Debugf("shutdown: disconnect peers: %v", err)
 			}
-			return nil
 		})
-	}
-	if b.debugAPIServer != nil {
-		eg.Go(func() error {
-			if err := b.debugAPIServer.Shutdown(ctx); err != nil {
-				return fmt.Errorf("debug api server: %w", err)
+
+		stage("drained protocol handlers", func() {
+			switch err := b.p2pDrainer.Drain(ctx, b.shutdownGracePeriod); {
+			case errors.Is(err, libp2p.ErrHandlersCancelled):
+				b.logger.Warningf("shutdown: protocol handlers cancelled after %s", b.shutdownGracePeriod)
+			case err != nil:
+				mErr = multierror.Append(mErr, fmt.Errorf("drain protocol handlers: %w", err))
 			}
-			return nil
 		})
 	}
 
-	if err := eg.Wait(); err != nil {
-		mErr = multierror.Append(mErr, err)
-	}
+	stage("stopped background workers", func() {
+		var wg sync.WaitGroup
+		wg.Add(6)
+		go func() {
+			defer wg.Done()
+			tryClose(b.pssCloser, "pss")
+		}()
+		go func() {
+			defer wg.Done()
+			tryClose(b.pusherCloser, "pusher")
+		}()
+		go func() {
+			defer wg.Done()
+			tryClose(b.pullerCloser, "puller")
+		}()
+		go func() {
+			defer wg.Done()
+			tryClose(b.accountingCloser, "accounting")
+		}()
 
-	if b.recoveryHandleCleanup != nil {
-		b.recoveryHandleCleanup()
-	}
-	var wg sync.WaitGroup
-	wg.Add(6)
-	go func() {
-		defer wg.Done()
-		tryClose(b.pssCloser, "pss")
-	}()
-	go func() {
-		defer wg.Done()
-		tryClose(b.pusherCloser, "pusher")
-	}()
-	go func() {
-		defer wg.Done()
-		tryClose(b.pullerCloser, "puller")
-	}()
-	go func() {
-		defer wg.Done()
-		tryClose(b.accountingCloser, "accounting")
-	}()
+		b.p2pCancel()
+		go func() {
+			defer wg.Done()
+			tryClose(b.pullSyncCloser, "pull sync")
+		}()
+		go func() {
+			defer wg.Done()
+			tryClose(b.hiveCloser, "hive")
+		}()
 
-	b.p2pCancel()
-	go func() {
-		defer wg.Done()
-		tryClose(b.pullSyncCloser, "pull sync")
-	}()
-	go func() {
-		defer wg.Done()
-		tryClose(b.hiveCloser, "pull sync")
-	}()
+		wg.Wait()
+
+		tryClose(b.p2pService, "p2p server")
+		tryClose(b.priceOracleCloser, "price oracle service")
 
-	wg.Wait()
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			tryClose(b.transactionMonitorCloser, "transaction monitor")
+			tryClose(b.transactionCloser, "transaction")
+		}()
+		go func() {
+			defer wg.Done()
+			tryClose(b.listenerCloser, "listener")
+		}()
+		go func() {
+			defer wg.Done()
+			tryClose(b.postageServiceCloser, "postage service")
+		}()
 
-	tryClose(b.p2pService, "p2p server")
-	tryClose(b.priceOracleCloser, "price oracle service")
+		wg.Wait()
 
-	wg.Add(3)
-	go func() {
-		defer wg.Done()
-		tryClose(b.transactionMonitorCloser, "transaction monitor")
-		tryClose(b.transactionCloser, "transaction")
-	}()
-	go func() {
-		defer wg.Done()
-		tryClose(b.listenerCloser, "listener")
-	}()
-	go func() {
-		defer wg.Done()
-		tryClose(b.postageServiceCloser, "postage service")
-	}()
+		if c := b.ethClientCloser; c != nil {
+			c()
+		}
 
-	wg.Wait()
+		tryClose(b.tracerCloser, "tracer")
+		tryClose(b.tagsCloser, "tag persistence")
+		tryClose(b.topologyCloser, "topology driver")
+	})
 
-	if c := b.ethClientCloser; c != nil {
-		c()
-	}
+	stage("closed stores", func() {
+		if b.stateStoreSweepStop != nil {
+			b.stateStoreSweepStop()
+		}
+		tryClose(b.stateStoreCloser, "statestore")
+		tryClose(b.localstoreCloser, "localstore")
+		tryClose(b.resolverCloser, "resolver service")
+	})
 
-	tryClose(b.tracerCloser, "tracer")
-	tryClose(b.tagsCloser, "tag persistence")
-	tryClose(b.topologyCloser, "topology driver")
-	tryClose(b.stateStoreCloser, "statestore")
-	tryClose(b.localstoreCloser, "localstore")
+	// the error log writer is closed last, as it is used by the servers
 	tryClose(b.errorLogWriter, "error log writer")
-	tryClose(b.resolverCloser, "resolver service")
 
 	return mErr
 }
@@ -0,0 +1,49 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package node
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/nodeinfo"
+	"github.com/ethersphere/bee/pkg/p2p"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+var _ nodeinfo.Provider = (*nodeInfo)(nil)
+
+// nodeInfo provides the information about the node from the values known
+// at startup and the p2p service, without accessing its connected peers.
+type nodeInfo struct {
+	overlay      swarm.Address
+	peerID       string
+	networkID    uint64
+	capabilities nodeinfo.Capabilities
+	startTime    time.Time
+	warmup       func() (active bool, remaining time.Duration)
+	p2p          p2p.DebugService
+}
+
+func (n *nodeInfo) Info() (nodeinfo.Info, error) {
+	underlays, err := n.p2p.Addresses()
+	if err != nil {
+		return nodeinfo.Info{}, fmt.Errorf("underlay addresses: %w", err)
+	}
+	var warmup nodeinfo.Warmup
+	if n.warmup != nil {
+		warmup.Active, warmup.Remaining = n.warmup()
+	}
+	return nodeinfo.Info{
+		Overlay:        n.overlay,
+		PeerID:         n.peerID,
+		NetworkID:      n.networkID,
+		Underlays:      underlays,
+		Capabilities:   n.capabilities,
+		WelcomeMessage: n.p2p.GetWelcomeMessage(),
+		StartTime:      n.startTime,
+		Warmup:         warmup,
+	}, nil
+}
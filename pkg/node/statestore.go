@@ -5,9 +5,11 @@
 package node
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"path/filepath"
+	"time"
 
 	"github.com/ethersphere/bee/pkg/logging"
 	"github.com/ethersphere/bee/pkg/statestore/leveldb"
@@ -28,6 +30,65 @@ func InitStateStore(log logging.Logger, dataDir string) (ret storage.StateStorer
 	return leveldb.NewStateStore(filepath.Join(dataDir, "statestore"), log)
 }
 
+// sweepStateStore removes stale records from the state store. The sweeps of
+// the foreground prefixes, the records of which the components keep in
+// memory once they are created, are finished first, so that they do not
+// change the records under the running components. It waits for the other
+// sweeps to finish for at most the given budget, after which they are left
+// to complete in the background.
+//
+// The sweeps have their own context instead of the one of the p2p service,
+// as they must return before the state store is closed on shutdown. The
+// returned function cancels them and waits for them to return.
+func sweepStateStore(log logging.Logger, store storage.StateStorer, budget time.Duration, foreground []string) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	isForeground := func(prefix string) bool {
+		for _, p := range foreground {
+			if p == prefix {
+				return true
+			}
+		}
+		return false
+	}
+	logReport := func(report storage.SweepReport, err error) {
+		if err != nil {
+			log.Debugf("statestore sweep: %v", err)
+		}
+		for prefix, r := range report.Sweeps {
+			if r.Error != "" {
+				log.Errorf("statestore sweep %q: %s", prefix, r.Error)
+			}
+		}
+		log.Debugf("statestore sweep: removed %d stale records", report.Removed)
+	}
+
+	logReport(storage.RunSweepsFunc(ctx, store, isForeground))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		logReport(storage.RunSweepsFunc(ctx, store, func(prefix string) bool {
+			return !isForeground(prefix)
+		}))
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(budget):
+		log.Infof("statestore sweep is taking longer than %s, continuing in the background", budget)
+	}
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// stateStoreCacheEntries is the number of the hot state store
+// values kept in memory.
+const stateStoreCacheEntries = 1000
+
 const overlayKey = "overlay"
 const secureOverlayKey = "non-mineable-overlay"
 
@@ -0,0 +1,48 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package node_test
+
+import (
+	"context"
+	"io/ioutil"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/logging"
+	"github.com/ethersphere/bee/pkg/node"
+	"github.com/ethersphere/bee/pkg/statestore/mock"
+	"github.com/ethersphere/bee/pkg/storage"
+)
+
+func TestSweepStateStore(t *testing.T) {
+	var foregroundSwept, backgroundReturned int32
+	storage.RegisterSweep("node-test-foreground-", func(ctx context.Context, _ storage.StateStorer) (int, error) {
+		atomic.StoreInt32(&foregroundSwept, 1)
+		return 1, nil
+	})
+	started := make(chan struct{})
+	storage.RegisterSweep("node-test-background-", func(ctx context.Context, _ storage.StateStorer) (int, error) {
+		close(started)
+		<-ctx.Done()
+		atomic.StoreInt32(&backgroundReturned, 1)
+		return 0, ctx.Err()
+	})
+
+	// the foreground sweeps finish regardless of the budget
+	stop := node.SweepStateStore(logging.New(ioutil.Discard, 0), mock.NewStateStore(), 0, []string{"node-test-foreground-"})
+	if atomic.LoadInt32(&foregroundSwept) != 1 {
+		t.Fatal("foreground sweep did not finish")
+	}
+
+	// the background sweeps return before stop returns
+	<-started
+	if atomic.LoadInt32(&backgroundReturned) != 0 {
+		t.Fatal("background sweep returned before stop")
+	}
+	stop()
+	if atomic.LoadInt32(&backgroundReturned) != 1 {
+		t.Fatal("background sweep did not return")
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mock
+
+import (
+	"github.com/ethersphere/bee/pkg/nodeinfo"
+)
+
+var _ nodeinfo.Provider = (*Provider)(nil)
+
+// Provider is the mock of a nodeinfo.Provider.
+type Provider struct {
+	info nodeinfo.Info
+	err  error
+}
+
+// WithInfo sets the information returned by the Info function.
+func WithInfo(info nodeinfo.Info) Option {
+	return optionFunc(func(p *Provider) {
+		p.info = info
+	})
+}
+
+// WithError sets the error returned by the Info function.
+func WithError(err error) Option {
+	return optionFunc(func(p *Provider) {
+		p.err = err
+	})
+}
+
+// New creates a new mock Provider with the given options.
+func New(opts ...Option) *Provider {
+	p := new(Provider)
+	for _, o := range opts {
+		o.apply(p)
+	}
+	return p
+}
+
+func (p *Provider) Info() (nodeinfo.Info, error) {
+	if p.err != nil {
+		return nodeinfo.Info{}, p.err
+	}
+	return p.info, nil
+}
+
+type Option interface {
+	apply(*Provider)
+}
+
+type optionFunc func(*Provider)
+
+func (f optionFunc) apply(p *Provider) { f(p) }
@@ -0,0 +1,48 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package nodeinfo describes the identity, network and capabilities of the
+// running node, so that they can be provided to its consumers at once.
+package nodeinfo
+
+import (
+	"time"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// Provider provides the information about the node. Implementations must be
+// cheap to call and must not block on the state of the connected peers.
+type Provider interface {
+	Info() (Info, error)
+}
+
+// Info is the information about the node.
+type Info struct {
+	Overlay   swarm.Address
+	PeerID    string // libp2p peer ID
+	NetworkID uint64
+	// Underlays are the advertised underlay addresses of the node.
+	Underlays      []ma.Multiaddr
+	Capabilities   Capabilities
+	WelcomeMessage string
+	StartTime      time.Time
+	Warmup         Warmup
+}
+
+// Warmup is the state of the warm-up after the start, during which the
+// node does not advertise itself and does not report that it is ready.
+type Warmup struct {
+	Active bool
+	// Remaining is the time after which
+	// the warm-up ends at the latest.
+	Remaining time.Duration
+}
+
+// Capabilities are the optional functionalities enabled on the node.
+type Capabilities struct {
+	FullNode bool
+	Swap     bool
+}
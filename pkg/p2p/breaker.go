@@ -0,0 +1,51 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+// Breakers are the circuit breakers of the peers, which execute the stream
+// handlers wrapped by the BreakerMiddleware.
+type Breakers interface {
+	// Execute executes f with the breaker of the peer, passing it the
+	// context of the call. It returns BreakerClosedError without executing
+	// f while the breaker of the peer is closed. The errors returned by f
+	// after ctx is done are not failures.
+	Execute(ctx context.Context, peer swarm.Address, f func(ctx context.Context) error) error
+}
+
+// BreakerClosedError is returned by the stream handlers wrapped by the
+// BreakerMiddleware if the breaker of the peer is closed.
+type BreakerClosedError struct {
+	Peer     swarm.Address
+	TryAfter time.Time // the wall clock time of the next handled stream
+	Err      error     // the error of the closed breaker
+}
+
+func (e *BreakerClosedError) Error() string {
+	return fmt.Sprintf("%v for peer %s until %s", e.Err, e.Peer, e.TryAfter)
+}
+
+// Unwrap returns the error of the closed breaker.
+func (e *BreakerClosedError) Unwrap() error { return e.Err }
+
+// BreakerMiddleware returns the middleware which executes the stream
+// handlers with the breakers of the peers. The handlers are not called
+// while the breaker of the peer is closed.
+func BreakerMiddleware(b Breakers) HandlerMiddleware {
+	return func(h HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, peer Peer, stream Stream) error {
+			return b.Execute(ctx, peer.Address, func(ctx context.Context) error {
+				return h(ctx, peer, stream)
+			})
+		}
+	}
+}
@@ -18,6 +18,9 @@ var (
 	ErrAlreadyConnected = errors.New("already connected")
 	// ErrDialLightNode is returned if connect was attempted to a light node.
 	ErrDialLightNode = errors.New("target peer is a light node")
+	// ErrStreamLimit is returned if a new stream was requested to a peer
+	// which already has the maximal number of open streams.
+	ErrStreamLimit = errors.New("peer stream limit reached")
 )
 
 const (
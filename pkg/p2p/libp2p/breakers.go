@@ -0,0 +1,144 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package libp2p
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/p2p"
+	"github.com/ethersphere/bee/pkg/p2p/libp2p/internal/breaker"
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/libp2p/go-libp2p-core/mux"
+)
+
+// protocolBreakerKey is the key of the breaker of a protocol of a peer.
+type protocolBreakerKey struct {
+	overlay  string
+	protocol string
+}
+
+// protocolBreakers are the breakers of the protocols of the peers, which
+// call onClose with the peer, the protocol and the backoff duration when
+// the handlers of the protocol fail with the peer limit consecutive times.
+// The handlers canceled on a disconnect or on the shutdown and the streams
+// reset by the peer are not failures.
+type protocolBreakers struct {
+	options  breaker.Options
+	onClose  func(overlay swarm.Address, protocol string, backoff time.Duration)
+	mu       sync.Mutex
+	breakers map[protocolBreakerKey]breaker.Interface
+}
+
+func newProtocolBreakers(o breaker.Options, onClose func(overlay swarm.Address, protocol string, backoff time.Duration)) *protocolBreakers {
+	o.IsFailure = isProtocolFailure
+	return &protocolBreakers{
+		options:  o,
+		onClose:  onClose,
+		breakers: make(map[protocolBreakerKey]breaker.Interface),
+	}
+}
+
+// execute runs f with the breaker of the protocol of the peer.
+func (p *protocolBreakers) execute(overlay swarm.Address, protocol string, f func() error) error {
+	key := protocolBreakerKey{overlay: overlay.ByteString(), protocol: protocol}
+
+	p.mu.Lock()
+	b, ok := p.breakers[key]
+	if !ok {
+		o := p.options
+		o.OnClose = func(backoff time.Duration) {
+			p.onClose(overlay, protocol, backoff)
+		}
+		var err error
+		if b, err = breaker.NewBreaker(o); err != nil {
+			p.mu.Unlock()
+			return err
+		}
+		p.breakers[key] = b
+	}
+	p.mu.Unlock()
+
+	return b.Execute(f)
+}
+
+// remove removes the breakers of the peer.
+func (p *protocolBreakers) remove(overlay swarm.Address) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key := range p.breakers {
+		if key.overlay == overlay.ByteString() {
+			delete(p.breakers, key)
+		}
+	}
+}
+
+// isProtocolFailure reports whether the error of a stream handler
+// counts toward tripping the breaker of the protocol.
+func isProtocolFailure(err error) bool {
+	return !errors.Is(err, context.Canceled) &&
+		!errors.Is(err, context.DeadlineExceeded) &&
+		!errors.Is(err, mux.ErrReset)
+}
+
+// handlerBreakers are the breakers of the stream handlers of the peers,
+// keyed by their overlays.
+type handlerBreakers struct {
+	registry *breaker.Registry
+}
+
+// canceledError is the error of a stream handler returned after its context
+// was done, which is not a failure.
+type canceledError struct {
+	err error
+}
+
+func (e *canceledError) Error() string { return e.err.Error() }
+
+// HandlerBreakers returns the breakers of the stream handlers of the peers
+// for the p2p.BreakerMiddleware, which close for a peer after its handlers
+// fail limit consecutive times. Their counters are labeled with the name,
+// and the breakers are counted by their states, not reported one by one.
+func (s *Service) HandlerBreakers(name string, limit int) (p2p.Breakers, error) {
+	r, err := breaker.NewRegistry(breaker.Options{
+		Limit:   limit,
+		Clock:   s.breakerClock,
+		Metrics: s.breakerMetrics,
+		Name:    name,
+		IsFailure: func(err error) bool {
+			var ce *canceledError
+			return !errors.As(err, &ce)
+		},
+	}, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%s handler breakers: %w", name, err)
+	}
+	return &handlerBreakers{registry: r}, nil
+}
+
+func (h *handlerBreakers) Execute(ctx context.Context, peer swarm.Address, f func(ctx context.Context) error) error {
+	b := h.registry.Get(peer.String())
+	err := b.ExecuteCtx(ctx, func(callCtx context.Context) error {
+		err := f(callCtx)
+		if err != nil && ctx.Err() != nil {
+			return &canceledError{err: err}
+		}
+		return err
+	})
+
+	var ce *canceledError
+	switch {
+	case errors.As(err, &ce):
+		return ce.err
+	case errors.Is(err, breaker.ErrClosed):
+		// the callers compare the time with the wall clock
+		return &p2p.BreakerClosedError{Peer: peer, TryAfter: time.Now().Add(b.Remaining()), Err: err}
+	}
+	return err
+}
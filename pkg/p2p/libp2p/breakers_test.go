@@ -0,0 +1,168 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package libp2p_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/internal/clock"
+	"github.com/ethersphere/bee/pkg/p2p"
+	"github.com/ethersphere/bee/pkg/p2p/libp2p"
+	"github.com/ethersphere/bee/pkg/p2p/libp2p/internal/breaker"
+	"github.com/ethersphere/bee/pkg/p2p/streamtest"
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestHandlerBreakers(t *testing.T) {
+	testError := errors.New("test error")
+	c := clock.NewMock(time.Now())
+	s, _ := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{Clock: c}})
+	breakers, err := s.HandlerBreakers("test", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int32
+	handlerErr := testError
+	peer := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c")
+	recorder := streamtest.New(
+		streamtest.WithBaseAddr(peer),
+		streamtest.WithProtocols(newTestProtocol(func(context.Context, p2p.Peer, p2p.Stream) error {
+			atomic.AddInt32(&calls, 1)
+			return handlerErr
+		})),
+		streamtest.WithMiddlewares(p2p.BreakerMiddleware(breakers)),
+	)
+
+	// handle returns the error of the handler of a new stream
+	var streams int
+	handle := func() error {
+		t.Helper()
+
+		stream, err := recorder.NewStream(context.Background(), swarm.ZeroAddress, nil, testProtocolName, testProtocolVersion, testStreamName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stream.Close()
+
+		records, err := recorder.Records(swarm.ZeroAddress, testProtocolName, testProtocolVersion, testStreamName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if streams++; len(records) != streams {
+			t.Fatalf("got %d records, want %d", len(records), streams)
+		}
+		return records[streams-1].Err()
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := handle(); !errors.Is(err, testError) {
+			t.Fatalf("got error %v, want %v", err, testError)
+		}
+	}
+
+	// the handler is not called for the peer with the closed breaker
+	err = handle()
+	var closedErr *p2p.BreakerClosedError
+	if !errors.As(err, &closedErr) {
+		t.Fatalf("got error %v, want breaker closed error", err)
+	}
+	if !errors.Is(err, breaker.ErrClosed) {
+		t.Fatalf("got error %v, want %v", err, breaker.ErrClosed)
+	}
+	if !closedErr.Peer.Equal(peer) {
+		t.Fatalf("got peer %s, want %s", closedErr.Peer, peer)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("got %d handler calls, want %d", got, 2)
+	}
+
+	// the successful probe opens the breaker after the backoff
+	c.Advance(time.Until(closedErr.TryAfter) + time.Second)
+	handlerErr = nil
+	for i := 0; i < 2; i++ {
+		if err := handle(); err != nil {
+			t.Fatalf("got error %v, want none", err)
+		}
+	}
+	if got := atomic.LoadInt32(&calls); got != 4 {
+		t.Fatalf("got %d handler calls, want %d", got, 4)
+	}
+}
+
+func TestHandlerBreakersMetrics(t *testing.T) {
+	s, _ := newService(t, 1, libp2pServiceOpts{})
+	breakers, err := s.HandlerBreakers("test", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := p2p.BreakerMiddleware(breakers)(func(context.Context, p2p.Peer, p2p.Stream) error {
+		return errors.New("test error")
+	})
+	for _, overlay := range []string{
+		"ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c",
+		"ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59d",
+	} {
+		_ = h(context.Background(), p2p.Peer{Address: swarm.MustParseHexAddress(overlay)}, nil)
+	}
+	_ = breakers.Execute(context.Background(), swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59e"), func(context.Context) error {
+		return nil
+	})
+
+	// the breakers of the peers are counted by their states
+	m := s.BreakerMetrics()
+	const want = `
+		# HELP bee_breaker_breakers Number of the breakers of the registries by their states.
+		# TYPE bee_breaker_breakers gauge
+		bee_breaker_breakers{breaker="connect",state="closed"} 0
+		bee_breaker_breakers{breaker="connect",state="half-open"} 0
+		bee_breaker_breakers{breaker="connect",state="open"} 0
+		bee_breaker_breakers{breaker="test",state="closed"} 2
+		bee_breaker_breakers{breaker="test",state="half-open"} 0
+		bee_breaker_breakers{breaker="test",state="open"} 1
+	`
+	if err := testutil.CollectAndCompare(m.Breakers, strings.NewReader(want)); err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.CollectAndCount(m.State); got != 0 {
+		t.Fatalf("got %d state metrics, want none", got)
+	}
+}
+
+func TestHandlerBreakersCanceled(t *testing.T) {
+	s, _ := newService(t, 1, libp2pServiceOpts{})
+	breakers, err := s.HandlerBreakers("test", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int32
+	h := p2p.BreakerMiddleware(breakers)(func(ctx context.Context, _ p2p.Peer, _ p2p.Stream) error {
+		atomic.AddInt32(&calls, 1)
+		<-ctx.Done()
+		return fmt.Errorf("handle: %w", ctx.Err())
+	})
+
+	// the canceled handlers are not failures
+	peer := p2p.Peer{Address: swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c")}
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := h(ctx, peer, nil); !errors.Is(err, context.Canceled) {
+			t.Fatalf("got error %v, want %v", err, context.Canceled)
+		}
+	}
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Fatalf("got %d handler calls, want %d", got, 3)
+	}
+}
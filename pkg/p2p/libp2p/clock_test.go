@@ -0,0 +1,58 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package libp2p_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/internal/clock"
+	"github.com/ethersphere/bee/pkg/p2p/libp2p/internal/blocklist"
+	"github.com/ethersphere/bee/pkg/p2p/libp2p/internal/breaker"
+	"github.com/ethersphere/bee/pkg/statestore/mock"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+// TestSharedClock validates that the breaker and the blocklist observe
+// the same time when they share a clock.
+func TestSharedClock(t *testing.T) {
+	c := clock.NewMock(time.Now())
+	testErr := errors.New("test error")
+
+	b, err := breaker.NewBreaker(breaker.Options{
+		Limit:        1,
+		StartBackoff: time.Minute,
+		Clock:        c,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{Clock: c})
+
+	if err := b.Execute(func() error { return testErr }); !errors.Is(err, testErr) {
+		t.Fatalf("got error %v, want %v", err, testErr)
+	}
+	if err := b.Execute(func() error { return nil }); !errors.Is(err, breaker.ErrClosed) {
+		t.Fatalf("got error %v, want %v", err, breaker.ErrClosed)
+	}
+
+	addr := swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c")
+	if err := bl.Add(addr, 30*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := bl.Exists(addr); err != nil || !exists {
+		t.Fatalf("got exists %v, error %v, want blocklisted", exists, err)
+	}
+
+	c.Advance(time.Minute)
+
+	if err := b.Execute(func() error { return nil }); err != nil {
+		t.Fatalf("breaker not reopened: %v", err)
+	}
+	if exists, err := bl.Exists(addr); err != nil || exists {
+		t.Fatalf("got exists %v, error %v, want expired", exists, err)
+	}
+}
@@ -7,16 +7,21 @@ package libp2p_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"net"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/ethersphere/bee/pkg/addressbook"
+	"github.com/ethersphere/bee/pkg/internal/clock"
 	"github.com/ethersphere/bee/pkg/p2p"
 	"github.com/ethersphere/bee/pkg/p2p/libp2p"
 	"github.com/ethersphere/bee/pkg/p2p/libp2p/internal/handshake"
 	"github.com/ethersphere/bee/pkg/statestore/mock"
+	"github.com/ethersphere/bee/pkg/storage"
 	"github.com/ethersphere/bee/pkg/swarm"
 	"github.com/ethersphere/bee/pkg/swarm/test"
 	"github.com/ethersphere/bee/pkg/topology/lightnode"
@@ -387,6 +392,45 @@ func TestConnectRepeatHandshake(t *testing.T) {
 	expectPeersEventually(t, s1)
 }
 
+func TestConnectionInfo(t *testing.T) {
+	s1, overlay1 := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+		FullNode: true,
+	}})
+	s2, overlay2 := newService(t, 1, libp2pServiceOpts{})
+
+	addr1 := serviceUnderlayAddress(t, s1)
+
+	if _, err := s2.ConnectionInfo(overlay1); !errors.Is(err, p2p.ErrPeerNotFound) {
+		t.Fatalf("got error %v, want %v", err, p2p.ErrPeerNotFound)
+	}
+
+	if _, err := s2.Connect(context.Background(), addr1); err != nil {
+		t.Fatal(err)
+	}
+
+	expectPeers(t, s2, overlay1)
+	expectPeersEventually(t, s1, overlay2)
+
+	info, err := s2.ConnectionInfo(overlay1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Direction != p2p.DirectionOutbound {
+		t.Fatalf("got direction %q, want %q", info.Direction, p2p.DirectionOutbound)
+	}
+	if len(info.Underlays) == 0 {
+		t.Fatal("got no underlays")
+	}
+
+	info, err = s1.ConnectionInfo(overlay2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Direction != p2p.DirectionInbound {
+		t.Fatalf("got direction %q, want %q", info.Direction, p2p.DirectionInbound)
+	}
+}
+
 func TestBlocklisting(t *testing.T) {
 	s1, overlay1 := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
 		FullNode: true,
@@ -428,6 +472,176 @@ func TestBlocklisting(t *testing.T) {
 	expectPeers(t, s2)
 }
 
+func TestBlocklistPeer(t *testing.T) {
+	s1, overlay1 := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+		FullNode: true,
+	}})
+	s2, overlay2 := newService(t, 1, libp2pServiceOpts{})
+
+	addr1 := serviceUnderlayAddress(t, s1)
+
+	if _, err := s2.Connect(context.Background(), addr1); err != nil {
+		t.Fatal(err)
+	}
+
+	expectPeers(t, s2, overlay1)
+	expectPeersEventually(t, s1, overlay2)
+
+	// the peer is not disconnected unless requested
+	p, err := s2.BlocklistPeer(overlay1, time.Hour, "test reason", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Address.Equal(overlay1) {
+		t.Fatalf("got blocklisted peer %s, want %s", p.Address, overlay1)
+	}
+	if p.Duration != time.Hour {
+		t.Fatalf("got duration %s, want %s", p.Duration, time.Hour)
+	}
+	if p.Reason != "test reason" {
+		t.Fatalf("got reason %q, want %q", p.Reason, "test reason")
+	}
+	if len(p.Underlays) == 0 {
+		t.Fatal("got no underlays of the connected peer")
+	}
+
+	expectPeers(t, s2, overlay1)
+
+	if _, err := s2.BlocklistPeer(overlay1, time.Hour, "test reason", true); err != nil {
+		t.Fatal(err)
+	}
+
+	expectPeers(t, s2)
+	expectPeersEventually(t, s1)
+}
+
+func TestUnblocklistPeer(t *testing.T) {
+	s1, overlay1 := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+		FullNode: true,
+	}})
+	s2, overlay2 := newService(t, 1, libp2pServiceOpts{})
+
+	addr1 := serviceUnderlayAddress(t, s1)
+
+	if err := s2.Blocklist(overlay1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s2.Connect(context.Background(), addr1); err == nil {
+		t.Fatal("expected error during connection, got nil")
+	}
+
+	if err := s2.UnblocklistPeer(overlay1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s2.UnblocklistPeer(overlay1); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, storage.ErrNotFound)
+	}
+
+	if _, err := s2.Connect(context.Background(), addr1); err != nil {
+		t.Fatal(err)
+	}
+
+	expectPeers(t, s2, overlay1)
+	expectPeersEventually(t, s1, overlay2)
+}
+
+func TestClearBlocklist(t *testing.T) {
+	s, _ := newService(t, 1, libp2pServiceOpts{})
+
+	for _, overlay := range []swarm.Address{
+		swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c"),
+		swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59d"),
+	} {
+		if err := s.Blocklist(overlay, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := s.ClearBlocklist()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Fatalf("got %d removed peers, want %d", removed, 2)
+	}
+
+	peers, err := s.BlocklistedPeers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 0 {
+		t.Fatalf("got blocklisted peers %v, want none", peers)
+	}
+}
+
+func TestBlocklistedPeerAttempts(t *testing.T) {
+	s1, _ := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+		FullNode: true,
+	}})
+	s2, overlay2 := newService(t, 1, libp2pServiceOpts{})
+
+	addr1 := serviceUnderlayAddress(t, s1)
+
+	if err := s1.Blocklist(overlay2, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// the blocklisted peer keeps dialing
+	const attempts = 3
+	for i := 0; i < attempts; i++ {
+		_, _ = s2.Connect(context.Background(), addr1)
+		expectPeersEventually(t, s2)
+		expectPeersEventually(t, s1)
+	}
+
+	var got uint64
+	for i := 0; i < 100; i++ {
+		peers, err := s1.BlocklistedPeers()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(peers) != 1 || !peers[0].Address.Equal(overlay2) {
+			t.Fatalf("got blocklisted peers %v, want only %s", peers, overlay2)
+		}
+		if got = peers[0].Attempts; got == attempts {
+			if peers[0].LastAttempt.IsZero() {
+				t.Fatal("got no last attempt time")
+			}
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("got %d recorded attempts, want %d", got, attempts)
+}
+
+func TestBlocklistedIP(t *testing.T) {
+	s1, _ := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+		FullNode: true,
+	}})
+	s2, _ := newService(t, 1, libp2pServiceOpts{})
+
+	addr1 := serviceUnderlayAddress(t, s1)
+
+	// the dialing service connects from the same host, so
+	// from the ip address of the listening service
+	ip, err := addr1.ValueForProtocol(ma.P_IP4)
+	if err != nil {
+		if ip, err = addr1.ValueForProtocol(ma.P_IP6); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s1.BlocklistIP(net.ParseIP(ip), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s2.Connect(context.Background(), addr1); err == nil {
+		t.Fatal("got no error, want the connection rejected")
+	}
+
+	expectPeers(t, s2)
+	expectPeersEventually(t, s1)
+}
+
 func TestTopologyNotifier(t *testing.T) {
 	var (
 		mtx sync.Mutex
@@ -437,8 +651,10 @@ func TestTopologyNotifier(t *testing.T) {
 
 		n1connectedPeer    p2p.Peer
 		n1disconnectedPeer p2p.Peer
+		n1disconnectReason p2p.DisconnectReason
 		n2connectedPeer    p2p.Peer
 		n2disconnectedPeer p2p.Peer
+		n2disconnectReason p2p.DisconnectReason
 
 		n1c = func(_ context.Context, p p2p.Peer, _ bool) error {
 			mtx.Lock()
@@ -448,10 +664,11 @@ func TestTopologyNotifier(t *testing.T) {
 			n1connectedPeer = p
 			return nil
 		}
-		n1d = func(p p2p.Peer) {
+		n1d = func(p p2p.Peer, r p2p.DisconnectReason) {
 			mtx.Lock()
 			defer mtx.Unlock()
 			n1disconnectedPeer = p
+			n1disconnectReason = r
 		}
 
 		n2c = func(_ context.Context, p p2p.Peer, _ bool) error {
@@ -462,10 +679,11 @@ func TestTopologyNotifier(t *testing.T) {
 			expectFullNode(t, p)
 			return nil
 		}
-		n2d = func(p p2p.Peer) {
+		n2d = func(p p2p.Peer, r p2p.DisconnectReason) {
 			mtx.Lock()
 			defer mtx.Unlock()
 			n2disconnectedPeer = p
+			n2disconnectReason = r
 		}
 	)
 	notifier1 := mockNotifier(n1c, n1d, true)
@@ -523,6 +741,8 @@ func TestTopologyNotifier(t *testing.T) {
 	// topology driver
 	mtx.Lock()
 	expectZeroAddress(t, n2connectedPeer.Address)
+	expectDisconnectReason(t, n1disconnectReason, p2p.DisconnectReasonRemote)
+	expectDisconnectReason(t, n2disconnectReason, p2p.DisconnectReasonLocal)
 	mtx.Unlock()
 
 	addr2 := serviceUnderlayAddress(t, s2)
@@ -543,6 +763,10 @@ func TestTopologyNotifier(t *testing.T) {
 	expectPeers(t, s1)
 	expectPeersEventually(t, s2)
 	waitAddrSet(t, &n2disconnectedPeer.Address, &mtx, overlay1)
+
+	mtx.Lock()
+	expectDisconnectReason(t, n2disconnectReason, p2p.DisconnectReasonRemote)
+	mtx.Unlock()
 }
 
 func TestTopologyOverSaturated(t *testing.T) {
@@ -563,7 +787,7 @@ func TestTopologyOverSaturated(t *testing.T) {
 			n1connectedPeer = p
 			return nil
 		}
-		n1d = func(p p2p.Peer) {}
+		n1d = func(p2p.Peer, p2p.DisconnectReason) {}
 
 		n2c = func(_ context.Context, p p2p.Peer, _ bool) error {
 			mtx.Lock()
@@ -572,7 +796,7 @@ func TestTopologyOverSaturated(t *testing.T) {
 			n2connectedPeer = p
 			return nil
 		}
-		n2d = func(p p2p.Peer) {
+		n2d = func(p p2p.Peer, _ p2p.DisconnectReason) {
 			mtx.Lock()
 			defer mtx.Unlock()
 			n2disconnectedPeer = p
@@ -699,6 +923,222 @@ func TestWithBlocklistStreams(t *testing.T) {
 	expectPeersEventually(t, s1)
 }
 
+func TestProtocolBreakerBlocklist(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const limit = 3
+	s1, overlay1 := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+		FullNode:             true,
+		ProtocolBreakerLimit: limit,
+		Clock:                clock.NewMock(time.Now()),
+	}})
+	s2, overlay2 := newService(t, 1, libp2pServiceOpts{})
+
+	if err := s1.AddProtocol(newTestProtocol(func(_ context.Context, _ p2p.Peer, _ p2p.Stream) error {
+		return errors.New("handler failed")
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s2.Connect(ctx, serviceUnderlayAddress(t, s1)); err != nil {
+		t.Fatal(err)
+	}
+	expectPeersEventually(t, s1, overlay2)
+
+	for i := 0; i < limit; i++ {
+		s, err := s2.NewStream(ctx, overlay1, nil, testProtocolName, testProtocolVersion, testStreamName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = s.Read(make([]byte, 1))
+		_ = s.Close()
+	}
+
+	wantReason := "breaker tripped for protocol " + testProtocolName + "/" + testProtocolVersion
+	for i := 0; i < 100; i++ {
+		peers, err := s1.BlocklistedPeers()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(peers) == 1 {
+			if !peers[0].Address.Equal(overlay2) {
+				t.Fatalf("got blocklisted peer %s, want %s", peers[0].Address, overlay2)
+			}
+			// the peer is blocklisted for the backoff of the breaker
+			if peers[0].Duration != 2*time.Minute {
+				t.Errorf("got duration %v, want %v", peers[0].Duration, 2*time.Minute)
+			}
+			if peers[0].Reason != wantReason {
+				t.Errorf("got reason %q, want %q", peers[0].Reason, wantReason)
+			}
+			expectPeersEventually(t, s1)
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("peer not blocklisted after the breaker tripped")
+}
+
+func TestProtocolBreakerCanceledHandlers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const limit = 3
+	s1, overlay1 := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+		FullNode:             true,
+		ProtocolBreakerLimit: limit,
+		Clock:                clock.NewMock(time.Now()),
+	}})
+	s2, overlay2 := newService(t, 1, libp2pServiceOpts{})
+
+	handlerErrors := []error{
+		fmt.Errorf("read: %w", context.Canceled),
+		fmt.Errorf("write: %w", context.DeadlineExceeded),
+		fmt.Errorf("read: %w", mux.ErrReset),
+	}
+	var calls int32
+	if err := s1.AddProtocol(newTestProtocol(func(_ context.Context, _ p2p.Peer, _ p2p.Stream) error {
+		i := atomic.AddInt32(&calls, 1) - 1
+		return handlerErrors[int(i)%len(handlerErrors)]
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s2.Connect(ctx, serviceUnderlayAddress(t, s1)); err != nil {
+		t.Fatal(err)
+	}
+	expectPeersEventually(t, s1, overlay2)
+
+	// the stream is closed after the breaker has counted the handler
+	for i := 0; i < 2*limit*len(handlerErrors); i++ {
+		s, err := s2.NewStream(ctx, overlay1, nil, testProtocolName, testProtocolVersion, testStreamName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = s.Read(make([]byte, 1))
+		_ = s.Close()
+	}
+
+	peers, err := s1.BlocklistedPeers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 0 {
+		t.Fatalf("got blocklisted peers %v, want none", peers)
+	}
+	expectPeers(t, s1, overlay2)
+}
+
+func TestDisconnectReasons(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		spec       func(p2p.ProtocolSpec)
+		disconnect func(s *libp2p.Service, overlay swarm.Address) error
+		want       p2p.DisconnectReason
+	}{
+		{
+			name: "local",
+			disconnect: func(s *libp2p.Service, overlay swarm.Address) error {
+				return s.Disconnect(overlay)
+			},
+			want: p2p.DisconnectReasonLocal,
+		},
+		{
+			name: "pruned",
+			disconnect: func(s *libp2p.Service, overlay swarm.Address) error {
+				return s.DisconnectWithReason(overlay, p2p.DisconnectReasonPruned)
+			},
+			want: p2p.DisconnectReasonPruned,
+		},
+		{
+			name: "blocklisted",
+			disconnect: func(s *libp2p.Service, overlay swarm.Address) error {
+				return s.Blocklist(overlay, time.Minute)
+			},
+			want: p2p.DisconnectReasonBlocklisted,
+		},
+		{
+			name: "blocklisted by protocol",
+			spec: func(spec p2p.ProtocolSpec) {
+				p2p.WithBlocklistStreams(time.Minute, spec)
+			},
+			want: p2p.DisconnectReasonBlocklisted,
+		},
+		{
+			name: "rejected by protocol",
+			spec: p2p.WithDisconnectStreams,
+			want: p2p.DisconnectReasonRejected,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			reasons := make(chan p2p.DisconnectReason, 1)
+			notifier := mockNotifier(noopCf, func(_ p2p.Peer, r p2p.DisconnectReason) {
+				select {
+				case reasons <- r:
+				default:
+				}
+			}, true)
+
+			s1, overlay1 := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+				FullNode: true,
+			}})
+			s1.SetPickyNotifier(notifier)
+			s2, overlay2 := newService(t, 1, libp2pServiceOpts{})
+
+			if tc.spec != nil {
+				spec := p2p.ProtocolSpec{
+					Name:    testProtocolName,
+					Version: testProtocolVersion,
+					StreamSpecs: []p2p.StreamSpec{
+						{
+							Name: testStreamName,
+							Handler: func(context.Context, p2p.Peer, p2p.Stream) error {
+								return nil
+							},
+						},
+					},
+				}
+				tc.spec(spec)
+				if err := s1.AddProtocol(spec); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if _, err := s2.Connect(ctx, serviceUnderlayAddress(t, s1)); err != nil {
+				t.Fatal(err)
+			}
+			expectPeersEventually(t, s1, overlay2)
+
+			if tc.disconnect != nil {
+				if err := tc.disconnect(s1, overlay2); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				s, err := s2.NewStream(ctx, overlay1, nil, testProtocolName, testProtocolVersion, testStreamName)
+				expectStreamReset(t, s, err)
+			}
+
+			select {
+			case r := <-reasons:
+				expectDisconnectReason(t, r, tc.want)
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for the disconnect notification")
+			}
+		})
+	}
+}
+
+func expectDisconnectReason(t *testing.T, got, want p2p.DisconnectReason) {
+	t.Helper()
+	if got != want {
+		t.Errorf("got disconnect reason %s, want %s", got, want)
+	}
+}
+
 func expectStreamReset(t *testing.T, s io.ReadCloser, err error) {
 	t.Helper()
 
@@ -774,7 +1214,7 @@ func checkAddressbook(t *testing.T, ab addressbook.Getter, overlay swarm.Address
 
 type notifiee struct {
 	connected    func(context.Context, p2p.Peer, bool) error
-	disconnected func(p2p.Peer)
+	disconnected func(p2p.Peer, p2p.DisconnectReason)
 	pick         bool
 }
 
@@ -782,8 +1222,8 @@ func (n *notifiee) Connected(c context.Context, p p2p.Peer, f bool) error {
 	return n.connected(c, p, f)
 }
 
-func (n *notifiee) Disconnected(p p2p.Peer) {
-	n.disconnected(p)
+func (n *notifiee) Disconnected(p p2p.Peer, r p2p.DisconnectReason) {
+	n.disconnected(p, r)
 }
 
 func (n *notifiee) Pick(p p2p.Peer) bool {
@@ -800,11 +1240,11 @@ func mockNotifier(c cFunc, d dFunc, pick bool) p2p.PickyNotifier {
 
 type (
 	cFunc func(context.Context, p2p.Peer, bool) error
-	dFunc func(p2p.Peer)
+	dFunc func(p2p.Peer, p2p.DisconnectReason)
 )
 
 var noopCf = func(_ context.Context, _ p2p.Peer, _ bool) error {
 	return nil
 }
 
-var noopDf = func(p p2p.Peer) {}
+var noopDf = func(p2p.Peer, p2p.DisconnectReason) {}
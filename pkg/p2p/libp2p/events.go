@@ -0,0 +1,72 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package libp2p
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/p2p"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+// peerEventsBufferSize is the number of the peer events buffered for a subscriber.
+const peerEventsBufferSize = 64
+
+// peerEvents are the channels of the peer event subscribers.
+type peerEvents struct {
+	mu       sync.Mutex
+	channels []chan p2p.PeerEvent
+}
+
+// SubscribePeerEvents returns the channel on which the peers that connect,
+// disconnect or are blocklisted are delivered. The events are never waited
+// for to be received. Up to 64 events are buffered for a subscriber and the
+// events that do not fit in the buffer are dropped. The returned function
+// closes the channel and is safe to be called multiple times.
+func (s *Service) SubscribePeerEvents() (c <-chan p2p.PeerEvent, unsubscribe func()) {
+	channel := make(chan p2p.PeerEvent, peerEventsBufferSize)
+	var closeOnce sync.Once
+
+	s.peerEvents.mu.Lock()
+	defer s.peerEvents.mu.Unlock()
+
+	s.peerEvents.channels = append(s.peerEvents.channels, channel)
+
+	unsubscribe = func() {
+		s.peerEvents.mu.Lock()
+		defer s.peerEvents.mu.Unlock()
+
+		for i, c := range s.peerEvents.channels {
+			if c == channel {
+				s.peerEvents.channels = append(s.peerEvents.channels[:i], s.peerEvents.channels[i+1:]...)
+				break
+			}
+		}
+
+		closeOnce.Do(func() { close(channel) })
+	}
+
+	return channel, unsubscribe
+}
+
+// publishPeerEvent delivers the event of the peer to the subscribers.
+func (s *Service) publishPeerEvent(overlay swarm.Address, t p2p.PeerEventType) {
+	ev := p2p.PeerEvent{
+		Overlay:   overlay,
+		Type:      t,
+		Timestamp: time.Now(),
+	}
+
+	s.peerEvents.mu.Lock()
+	defer s.peerEvents.mu.Unlock()
+
+	for _, c := range s.peerEvents.channels {
+		select {
+		case c <- ev:
+		default:
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package libp2p_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/p2p"
+	"github.com/ethersphere/bee/pkg/p2p/libp2p"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+func TestSubscribePeerEvents(t *testing.T) {
+	s1, overlay1 := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+		FullNode: true,
+	}})
+	s2, overlay2 := newService(t, 1, libp2pServiceOpts{})
+
+	c1, unsubscribe1 := s1.SubscribePeerEvents()
+	defer unsubscribe1()
+	c2, unsubscribe2 := s2.SubscribePeerEvents()
+	defer unsubscribe2()
+
+	addr1 := serviceUnderlayAddress(t, s1)
+
+	if _, err := s2.Connect(context.Background(), addr1); err != nil {
+		t.Fatal(err)
+	}
+
+	expectPeerEvent(t, c2, overlay1, p2p.PeerEventConnected)
+	expectPeerEvent(t, c1, overlay2, p2p.PeerEventConnected)
+
+	if _, err := s2.BlocklistPeer(overlay1, time.Hour, "test reason", true); err != nil {
+		t.Fatal(err)
+	}
+
+	expectPeerEvent(t, c2, overlay1, p2p.PeerEventBlocklisted)
+	expectPeerEvent(t, c2, overlay1, p2p.PeerEventDisconnected)
+	expectPeerEvent(t, c1, overlay2, p2p.PeerEventDisconnected)
+
+	// the channel is closed on unsubscribe
+	unsubscribe2()
+	if _, ok := <-c2; ok {
+		t.Fatal("got an event after unsubscribe")
+	}
+}
+
+func expectPeerEvent(t *testing.T, c <-chan p2p.PeerEvent, overlay swarm.Address, typ p2p.PeerEventType) {
+	t.Helper()
+
+	select {
+	case e := <-c:
+		if !e.Overlay.Equal(overlay) {
+			t.Fatalf("got event of peer %s, want %s", e.Overlay, overlay)
+		}
+		if e.Type != typ {
+			t.Fatalf("got event %s, want %s", e.Type, typ)
+		}
+		if e.Timestamp.IsZero() {
+			t.Fatal("got event without timestamp")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s event of peer %s", typ, overlay)
+	}
+}
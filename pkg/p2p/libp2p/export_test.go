@@ -6,20 +6,53 @@ package libp2p
 
 import (
 	"context"
+	"io/ioutil"
+	"net"
+	"time"
 
+	"github.com/ethersphere/bee/pkg/logging"
+	"github.com/ethersphere/bee/pkg/p2p"
+	"github.com/ethersphere/bee/pkg/p2p/libp2p/internal/blocklist"
+	"github.com/ethersphere/bee/pkg/p2p/libp2p/internal/breaker"
 	handshake "github.com/ethersphere/bee/pkg/p2p/libp2p/internal/handshake"
+	"github.com/ethersphere/bee/pkg/swarm"
 	"github.com/libp2p/go-libp2p-core/network"
 	libp2ppeer "github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
 )
 
 func (s *Service) HandshakeService() *handshake.Service {
 	return s.handshakeService
 }
 
+func (s *Service) BlocklistIP(ip net.IP, duration time.Duration) error {
+	return s.blocklist.AddIP(ip, duration)
+}
+
 func (s *Service) NewStreamForPeerID(peerID libp2ppeer.ID, protocolName, protocolVersion, streamName string) (network.Stream, error) {
 	return s.newStreamForPeerID(context.Background(), peerID, protocolName, protocolVersion, streamName)
 }
 
+// RemoveTracedHandshakeHandler makes the service handle only the handshake
+// stream without the headers, like the peers which do not support tracing.
+func (s *Service) RemoveTracedHandshakeHandler() {
+	s.host.RemoveStreamHandler(protocol.ID(p2p.NewSwarmStreamName(handshake.ProtocolName, handshake.ProtocolVersion, handshakeTracedStreamName)))
+}
+
+func (s *Service) BreakerMetrics() *breaker.Metrics {
+	return s.breakerMetrics
+}
+
 type StaticAddressResolver = staticAddressResolver
 
 var NewStaticAddressResolver = newStaticAddressResolver
+
+type ConnectionGater = connectionGater
+
+func NewConnectionGater(bl *blocklist.Blocklist) *ConnectionGater {
+	return newConnectionGater(bl, logging.New(ioutil.Discard, 0), newMetrics().GaterRejectedCount)
+}
+
+func (g *connectionGater) SetOverlay(peerID libp2ppeer.ID, overlay swarm.Address) {
+	g.setOverlay(peerID, overlay)
+}
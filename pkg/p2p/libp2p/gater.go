@@ -0,0 +1,157 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package libp2p
+
+import (
+	"net"
+	"sync"
+
+	"github.com/ethersphere/bee/pkg/logging"
+	"github.com/ethersphere/bee/pkg/p2p/libp2p/internal/blocklist"
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/libp2p/go-libp2p-core/control"
+	"github.com/libp2p/go-libp2p-core/network"
+	libp2ppeer "github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxGaterOverlays is the number of the overlays of the peer IDs that are
+// remembered by the connectionGater. When it is exceeded, the overlay of an
+// arbitrary peer ID is forgotten.
+const maxGaterOverlays = 4096
+
+// connectionGater rejects the connections with the blocklisted peers before
+// the handshake. The connections from and to the blocklisted IP addresses,
+// or the IP addresses in the blocklisted ranges, are rejected as soon as they
+// are accepted or dialed, and the connections of the blocklisted overlays
+// once the peer ID is known, after the connection is secured. The overlays
+// of the peer IDs are learned from the handshakes, so the first connection
+// of a peer is checked by the handshake. The connections are allowed if the
+// blocklist can not be read.
+type connectionGater struct {
+	blocklist *blocklist.Blocklist
+	logger    logging.Logger
+	rejected  prometheus.Counter
+
+	mu       sync.RWMutex
+	overlays map[libp2ppeer.ID]swarm.Address
+}
+
+func newConnectionGater(bl *blocklist.Blocklist, logger logging.Logger, rejected prometheus.Counter) *connectionGater {
+	return &connectionGater{
+		blocklist: bl,
+		logger:    logger,
+		rejected:  rejected,
+		overlays:  make(map[libp2ppeer.ID]swarm.Address),
+	}
+}
+
+// setOverlay remembers the overlay of the peer ID learned from the handshake.
+func (g *connectionGater) setOverlay(peerID libp2ppeer.ID, overlay swarm.Address) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.overlays[peerID]; !ok && len(g.overlays) >= maxGaterOverlays {
+		for k := range g.overlays {
+			delete(g.overlays, k)
+			break
+		}
+	}
+	g.overlays[peerID] = overlay
+}
+
+func (g *connectionGater) overlay(peerID libp2ppeer.ID) (swarm.Address, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	overlay, ok := g.overlays[peerID]
+	return overlay, ok
+}
+
+// InterceptPeerDial implements connmgr.ConnectionGater.
+func (g *connectionGater) InterceptPeerDial(peerID libp2ppeer.ID) (allow bool) {
+	return !g.blockedPeer(peerID)
+}
+
+// InterceptAddrDial implements connmgr.ConnectionGater.
+func (g *connectionGater) InterceptAddrDial(_ libp2ppeer.ID, addr ma.Multiaddr) (allow bool) {
+	return !g.blockedAddr(addr)
+}
+
+// InterceptAccept implements connmgr.ConnectionGater.
+func (g *connectionGater) InterceptAccept(addrs network.ConnMultiaddrs) (allow bool) {
+	return !g.blockedAddr(addrs.RemoteMultiaddr())
+}
+
+// InterceptSecured implements connmgr.ConnectionGater.
+func (g *connectionGater) InterceptSecured(_ network.Direction, peerID libp2ppeer.ID, addrs network.ConnMultiaddrs) (allow bool) {
+	return !g.blockedPeer(peerID) && !g.blockedAddr(addrs.RemoteMultiaddr())
+}
+
+// InterceptUpgraded implements connmgr.ConnectionGater.
+func (g *connectionGater) InterceptUpgraded(network.Conn) (allow bool, reason control.DisconnectReason) {
+	return true, 0
+}
+
+// blockedPeer reports whether the overlay of the peer ID is known and
+// blocklisted, and records the connection attempt of the blocklisted peer.
+func (g *connectionGater) blockedPeer(peerID libp2ppeer.ID) bool {
+	overlay, ok := g.overlay(peerID)
+	if !ok {
+		return false
+	}
+	blocked, err := g.blocklist.Exists(overlay)
+	if err != nil {
+		g.logger.Debugf("connection gater: blocklist exists %s: %v", peerID, err)
+		return false
+	}
+	if !blocked {
+		return false
+	}
+	g.rejected.Inc()
+	if _, err := g.blocklist.RecordAttempt(overlay); err != nil {
+		g.logger.Debugf("connection gater: blocklist record attempt %s: %v", peerID, err)
+	}
+	return true
+}
+
+// blockedAddr reports whether the IP address of the multiaddress is
+// blocklisted, by itself or in a blocklisted range.
+func (g *connectionGater) blockedAddr(addr ma.Multiaddr) bool {
+	ip := multiaddrIP(addr)
+	if ip == nil {
+		return false
+	}
+	blocked, err := g.blocklist.ExistsIP(ip)
+	if err != nil {
+		g.logger.Debugf("connection gater: blocklist exists ip %s: %v", ip, err)
+		blocked = false
+	}
+	if !blocked {
+		blocked, err = g.blocklist.ExistsIPInRange(ip)
+		if err != nil {
+			g.logger.Debugf("connection gater: blocklist exists ip %s in range: %v", ip, err)
+			return false
+		}
+	}
+	if blocked {
+		g.rejected.Inc()
+	}
+	return blocked
+}
+
+// multiaddrIP returns the IP address of the multiaddress or nil if it has none.
+func multiaddrIP(addr ma.Multiaddr) net.IP {
+	if addr == nil {
+		return nil
+	}
+	for _, code := range []int{ma.P_IP4, ma.P_IP6} {
+		if v, err := addr.ValueForProtocol(code); err == nil {
+			return net.ParseIP(v)
+		}
+	}
+	return nil
+}
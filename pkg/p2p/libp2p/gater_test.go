@@ -0,0 +1,154 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package libp2p_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/logging"
+	"github.com/ethersphere/bee/pkg/p2p/libp2p"
+	"github.com/ethersphere/bee/pkg/p2p/libp2p/internal/blocklist"
+	"github.com/ethersphere/bee/pkg/statestore/leveldb"
+	"github.com/ethersphere/bee/pkg/statestore/mock"
+	"github.com/ethersphere/bee/pkg/storage"
+	"github.com/ethersphere/bee/pkg/swarm/test"
+	"github.com/libp2p/go-libp2p-core/network"
+	libp2ppeer "github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+func TestConnectionGater(t *testing.T) {
+	bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{})
+	g := libp2p.NewConnectionGater(bl)
+
+	blockedAddrs := connMultiaddrs(t, "/ip4/10.0.0.1/tcp/1634")
+	allowedAddrs := connMultiaddrs(t, "/ip4/10.0.0.2/tcp/1634")
+	if err := bl.AddIP(net.ParseIP("10.0.0.1"), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	blockedOverlay, allowedOverlay := test.RandomAddress(), test.RandomAddress()
+	blockedPeer, allowedPeer, unknownPeer := libp2ppeer.ID("blocked"), libp2ppeer.ID("allowed"), libp2ppeer.ID("unknown")
+	g.SetOverlay(blockedPeer, blockedOverlay)
+	g.SetOverlay(allowedPeer, allowedOverlay)
+	if err := bl.Add(blockedOverlay, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if g.InterceptAccept(blockedAddrs) {
+		t.Error("accepted the connection from a blocklisted ip address")
+	}
+	if !g.InterceptAccept(allowedAddrs) {
+		t.Error("rejected the connection from an allowed ip address")
+	}
+	if g.InterceptAddrDial(allowedPeer, blockedAddrs.RemoteMultiaddr()) {
+		t.Error("allowed the dial of a blocklisted ip address")
+	}
+
+	for _, tc := range []struct {
+		name      string
+		peer      libp2ppeer.ID
+		addrs     network.ConnMultiaddrs
+		allow     bool
+		allowDial bool
+	}{
+		{name: "blocklisted overlay", peer: blockedPeer, addrs: allowedAddrs, allow: false, allowDial: false},
+		{name: "blocklisted ip address", peer: allowedPeer, addrs: blockedAddrs, allow: false, allowDial: true},
+		{name: "allowed", peer: allowedPeer, addrs: allowedAddrs, allow: true, allowDial: true},
+		{name: "unknown overlay", peer: unknownPeer, addrs: allowedAddrs, allow: true, allowDial: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := g.InterceptSecured(network.DirInbound, tc.peer, tc.addrs); got != tc.allow {
+				t.Errorf("got allow %v, want %v", got, tc.allow)
+			}
+			if got := g.InterceptPeerDial(tc.peer); got != tc.allowDial {
+				t.Errorf("got dial allow %v, want %v", got, tc.allowDial)
+			}
+		})
+	}
+
+	// the rejected attempts of the blocklisted overlay are recorded
+	first, err := bl.RecordAttempt(blockedOverlay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first {
+		t.Error("rejected attempts of the blocklisted overlay not recorded")
+	}
+}
+
+func TestConnectionGaterFailOpen(t *testing.T) {
+	bl := blocklist.NewBlocklist(&failingStore{StateStorer: mock.NewStateStore()}, blocklist.Options{})
+	g := libp2p.NewConnectionGater(bl)
+
+	peerID := libp2ppeer.ID("peer")
+	g.SetOverlay(peerID, test.RandomAddress())
+	addrs := connMultiaddrs(t, "/ip4/10.0.0.1/tcp/1634")
+
+	if !g.InterceptAccept(addrs) {
+		t.Error("rejected the connection on a blocklist error")
+	}
+	if !g.InterceptSecured(network.DirOutbound, peerID, addrs) {
+		t.Error("rejected the secured connection on a blocklist error")
+	}
+}
+
+func BenchmarkConnectionGaterSecured(b *testing.B) {
+	store, err := leveldb.NewStateStore(b.TempDir(), logging.New(ioutil.Discard, 0))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { _ = store.Close() })
+
+	bl := blocklist.NewBlocklist(store, blocklist.Options{})
+	g := libp2p.NewConnectionGater(bl)
+
+	peers := make([]libp2ppeer.ID, 1000)
+	for i := range peers {
+		overlay := test.RandomAddress()
+		peers[i] = libp2ppeer.ID(overlay.Bytes())
+		g.SetOverlay(peers[i], overlay)
+		if i%2 == 0 {
+			if err := bl.Add(overlay, 0); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	addrs := &testConnMultiaddrs{remote: ma.StringCast("/ip4/10.0.0.1/tcp/1634")}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		g.InterceptSecured(network.DirInbound, peers[n%len(peers)], addrs)
+	}
+}
+
+type testConnMultiaddrs struct {
+	remote ma.Multiaddr
+}
+
+func (m *testConnMultiaddrs) LocalMultiaddr() ma.Multiaddr  { return nil }
+func (m *testConnMultiaddrs) RemoteMultiaddr() ma.Multiaddr { return m.remote }
+
+func connMultiaddrs(t *testing.T, addr string) network.ConnMultiaddrs {
+	t.Helper()
+
+	remote, err := ma.NewMultiaddr(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testConnMultiaddrs{remote: remote}
+}
+
+// failingStore is a statestore on which all reads fail.
+type failingStore struct {
+	storage.StateStorer
+}
+
+func (s *failingStore) Get(string, interface{}) error {
+	return errors.New("read failed")
+}
@@ -5,123 +5,1220 @@
 package blocklist
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/ethersphere/bee/pkg/internal/clock"
+	"github.com/ethersphere/bee/pkg/logging"
 	"github.com/ethersphere/bee/pkg/p2p"
 	"github.com/ethersphere/bee/pkg/storage"
 	"github.com/ethersphere/bee/pkg/swarm"
+	ma "github.com/multiformats/go-multiaddr"
 )
 
 var keyPrefix = "blocklist-"
 
-// timeNow is used to deterministically mock time.Now() in tests.
-var timeNow = time.Now
+const (
+	defaultAttemptsFlushEvery    = 100
+	defaultAttemptsFlushInterval = time.Minute
+)
+
+func init() {
+	storage.RegisterPrefix(keyPrefix, func(_, value []byte) error {
+		var e entry
+		return json.Unmarshal(value, &e)
+	})
+	storage.RegisterSweep(keyPrefix, sweepExpired(clock.Real))
+	storage.RegisterPrefix(ipKeyPrefix, func(_, value []byte) error {
+		var e entry
+		return json.Unmarshal(value, &e)
+	})
+	storage.RegisterSweep(ipKeyPrefix, sweepIPs(clock.Real))
+	storage.RegisterPrefix(cidrKeyPrefix, func(_, value []byte) error {
+		var e entry
+		return json.Unmarshal(value, &e)
+	})
+	storage.RegisterSweep(cidrKeyPrefix, sweepCIDRs(clock.Real))
+}
+
+// KeyPrefixes returns the prefixes of the state store keys of the blocklist
+// entries. The blocklist keeps them in memory, so they must not be changed
+// by the others while it is used.
+func KeyPrefixes() []string {
+	return []string{keyPrefix, ipKeyPrefix, cidrKeyPrefix}
+}
 
 type Blocklist struct {
-	store storage.StateStorer
+	store             storage.StateStorer
+	clock             clock.Clock
+	logger            logging.Logger
+	flushEvery        int
+	flushInterval     time.Duration
+	mu                sync.Mutex // serializes the updates of the entries
+	migrateOnce       sync.Once
+	migrateKeysOnce   sync.Once
+	attempts          map[string]*attempts // connection attempts that are not persisted by entry key
+	attemptsCount     int                  // total count of the attempts that are not persisted
+	attemptsLastFlush time.Time
+	maxEntries        int
+	renewal           Renewal
+	escalationFactor  float64
+	escalationMax     time.Duration
+	escalationWindow  time.Duration
+	subscriptions     subscriptions
+	entries           int // number of the entries in the store
+	cidrMu            sync.RWMutex
+	cidrs             map[string]entry // index of the blocklisted ip address ranges by key, nil until loaded
+	cidrLengths       []cidrLength     // lengths of the network prefixes of the blocklisted ranges
+	metrics           metrics
+}
+
+// Options for the Blocklist.
+type Options struct {
+	// Clock is the source of time, the real clock is used if it is nil.
+	Clock clock.Clock
+	// Logger logs the periodic sweeps, they are not logged if it is nil.
+	Logger logging.Logger
+	// AttemptsFlushEvery is the number of recorded connection
+	// attempts after which they are persisted.
+	AttemptsFlushEvery int
+	// AttemptsFlushInterval is the time after which the connection
+	// attempts are persisted on the next recorded attempt, even if
+	// there were fewer than AttemptsFlushEvery of them.
+	AttemptsFlushInterval time.Duration
+	// MaxEntries caps the number of the entries. When a new peer is added
+	// to a full blocklist, the entry that expires first is evicted, or the
+	// oldest one if all are permanent. Zero does not cap the entries.
+	MaxEntries int
+	// Renewal is how the block of a peer that is blocklisted again
+	// is renewed, RenewalReset if it is not set.
+	Renewal Renewal
+	// EscalationFactor multiplies the block duration of a peer that is
+	// temporarily blocklisted again while it is blocklisted, or within
+	// EscalationWindow after its block expired, once for every such
+	// consecutive block. A factor of 1 or less does not escalate the
+	// block durations.
+	EscalationFactor float64
+	// EscalationMax caps the escalated block durations. Zero does not cap
+	// them.
+	EscalationMax time.Duration
+	// EscalationWindow is how long the expired entries of the escalated
+	// peers are kept, so that their next block is escalated further.
+	EscalationWindow time.Duration
+}
+
+// Renewal is how the block of a temporarily blocklisted peer
+// is renewed when the peer is blocklisted again.
+type Renewal int
+
+const (
+	// RenewalReset starts the block anew from the time the peer is
+	// blocklisted again, if it expires later than the existing one.
+	RenewalReset Renewal = iota
+	// RenewalKeepTimestamp keeps the time the peer was originally
+	// blocklisted and only raises the block duration, so that
+	// blocklisting the peer again with the same duration does not
+	// extend its block.
+	RenewalKeepTimestamp
+)
+
+func NewBlocklist(store storage.StateStorer, o Options) *Blocklist {
+	if o.Clock == nil {
+		o.Clock = clock.Real
+	}
+	if o.Logger == nil {
+		o.Logger = logging.New(ioutil.Discard, 0)
+	}
+	if o.AttemptsFlushEvery == 0 {
+		o.AttemptsFlushEvery = defaultAttemptsFlushEvery
+	}
+	if o.AttemptsFlushInterval == 0 {
+		o.AttemptsFlushInterval = defaultAttemptsFlushInterval
+	}
+	return &Blocklist{
+		store:             store,
+		clock:             o.Clock,
+		logger:            o.Logger,
+		flushEvery:        o.AttemptsFlushEvery,
+		flushInterval:     o.AttemptsFlushInterval,
+		maxEntries:        o.MaxEntries,
+		renewal:           o.Renewal,
+		escalationFactor:  o.EscalationFactor,
+		escalationMax:     o.EscalationMax,
+		escalationWindow:  o.EscalationWindow,
+		attempts:          make(map[string]*attempts),
+		attemptsLastFlush: o.Clock.Now(),
+		metrics:           newMetrics(),
+	}
 }
 
-func NewBlocklist(store storage.StateStorer) *Blocklist {
-	return &Blocklist{store: store}
+// NewBlocklistWithSweep returns a new Blocklist which removes the entries
+// with an elapsed block duration from the store every interval, until the
+// context is cancelled.
+func NewBlocklistWithSweep(ctx context.Context, store storage.StateStorer, interval time.Duration, o Options) *Blocklist {
+	b := NewBlocklist(store, o)
+	ticker := b.clock.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+			}
+			removed, err := b.Sweep(ctx)
+			if err != nil {
+				b.logger.Debugf("blocklist: sweep: %v", err)
+				continue
+			}
+			if removed > 0 {
+				b.logger.Debugf("blocklist: swept %d expired entries", removed)
+			}
+		}
+	}()
+	return b
 }
 
 type entry struct {
-	Timestamp time.Time `json:"timestamp"`
-	Duration  string    `json:"duration"` // Duration is string because the time.Duration does not implement MarshalJSON/UnmarshalJSON methods.
+	Timestamp time.Time     `json:"timestamp"`
+	Duration  entryDuration `json:"duration"`
+	// Permanent is set if the peer is blocklisted forever. The entries with
+	// a zero duration, which were persisted without it, are permanent too.
+	Permanent bool `json:"permanent,omitempty"`
+	// Attempts is the number of rejected connection
+	// attempts of the peer while it was blocklisted.
+	Attempts    uint64 `json:"attempts,omitempty"`
+	LastAttempt int64  `json:"lastAttempt,omitempty"` // time of the last attempt in unix nanoseconds
+	Reason      string `json:"reason,omitempty"`      // why the peer was blocklisted, empty if not known
+	// Escalations is the number of the consecutive blocks of
+	// the peer which escalated its block duration.
+	Escalations int `json:"escalations,omitempty"`
+	// Retain is how long the entry is kept after it expired.
+	Retain entryDuration `json:"retain,omitempty"`
+	// Underlays are the last known underlay addresses of the peer.
+	Underlays []string `json:"underlays,omitempty"`
+}
+
+// permanent reports whether the peer is blocklisted forever.
+func (e entry) permanent() bool {
+	return e.Permanent || e.Duration == 0
+}
+
+// expires returns the time when the block duration of a temporary entry elapses.
+func (e entry) expires() time.Time {
+	return e.Timestamp.Add(time.Duration(e.Duration))
+}
+
+// expired reports whether the block duration of the entry has elapsed.
+func (e entry) expired(c clock.Clock) bool {
+	return !e.permanent() && c.Since(e.Timestamp) > time.Duration(e.Duration)
+}
+
+// removable reports whether the entry has expired and is not retained anymore.
+func (e entry) removable(c clock.Clock) bool {
+	return e.expired(c) && c.Since(e.expires()) > time.Duration(e.Retain)
+}
+
+// merge returns the entry of a peer which is blocklisted again, with the
+// existing entry e and the new entry n. A permanent entry always wins.
+// Otherwise, with RenewalReset, the entry that expires later wins and the
+// existing entry is kept if they are equal. With RenewalKeepTimestamp,
+// the timestamp of the existing entry is kept with the longer of the
+// durations, unless the existing entry has expired. The connection attempts
+// the reason and the underlays of the existing entry are kept, unless there
+// are new ones, and the greater of the escalations and of the retention
+// durations is kept.
+func (e entry) merge(n entry, r Renewal, c clock.Clock) entry {
+	m := e
+	switch {
+	case e.permanent():
+		m.Permanent = true
+	case r == RenewalKeepTimestamp && !e.expired(c):
+		if n.permanent() {
+			m.Duration = 0
+			m.Permanent = true
+		} else if n.Duration > e.Duration {
+			m.Duration = n.Duration
+		}
+	case n.permanent(), n.expires().After(e.expires()):
+		m.Timestamp = n.Timestamp
+		m.Duration = n.Duration
+		m.Permanent = n.permanent()
+	}
+	if n.Reason != "" {
+		m.Reason = n.Reason
+	}
+	if len(n.Underlays) > 0 {
+		m.Underlays = n.Underlays
+	}
+	if n.Escalations > m.Escalations {
+		m.Escalations = n.Escalations
+	}
+	if n.Retain > m.Retain {
+		m.Retain = n.Retain
+	}
+	return m
+}
+
+// entryDuration is a time.Duration persisted as nanoseconds. The durations
+// of the legacy entries, which were persisted as strings, are decoded too.
+type entryDuration time.Duration
+
+func (d entryDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int64(d))
 }
 
+func (d *entryDuration) UnmarshalJSON(b []byte) error {
+	if isLegacyDuration(b) {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		v, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*d = entryDuration(v)
+		return nil
+	}
+
+	var v int64
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*d = entryDuration(v)
+	return nil
+}
+
+func isLegacyDuration(b []byte) bool {
+	return len(b) > 0 && b[0] == '"'
+}
+
+// attempts are the connection attempts of a peer
+// which are aggregated before they are persisted.
+type attempts struct {
+	count uint64
+	last  time.Time
+}
+
+// PermanentRemaining is the remaining block duration of a permanently
+// blocklisted peer returned by ExistsWithRemaining.
+const PermanentRemaining time.Duration = -1
+
+// Exists reports whether the peer is blocklisted. The expired entry of the
+// peer is removed.
 func (b *Blocklist) Exists(overlay swarm.Address) (bool, error) {
-	key := generateKey(overlay)
-	timestamp, duration, err := b.get(key)
+	exists, _, err := b.ExistsWithRemaining(overlay)
+	return exists, err
+}
+
+// ExistsWithRemaining reports whether the peer is blocklisted, like Exists,
+// and returns the remaining block duration of the blocklisted peer, or
+// PermanentRemaining if it is blocklisted forever.
+func (b *Blocklist) ExistsWithRemaining(overlay swarm.Address) (exists bool, remaining time.Duration, err error) {
+	b.migrate()
+
+	key, e, err := b.find(overlay)
 	if err != nil {
 		if err == storage.ErrNotFound {
-			return false, nil
+			return false, 0, nil
 		}
 
-		return false, err
+		return false, 0, err
 	}
 
-	// using timeNow.Sub() so it can be mocked in unit tests
-	if timeNow().Sub(timestamp) > duration && duration != 0 {
-		_ = b.store.Delete(key)
-		return false, nil
+	if e.expired(b.clock) {
+		b.removeExpired(key)
+		return false, 0, nil
 	}
 
-	return true, nil
+	b.metrics.ExistsHitCount.Inc()
+	if e.permanent() {
+		return true, PermanentRemaining, nil
+	}
+	return true, time.Duration(e.Duration) - b.clock.Since(e.Timestamp), nil
 }
 
+// ExistsBatch reports whether each of the overlays is blocklisted, like
+// Exists, in a map keyed by the byte string of the overlays. The entries are
+// read with a single iteration over the blocklist, or with a lookup of each
+// overlay if there are fewer overlays than entries. The expired entries are
+// removed like in Exists.
+func (b *Blocklist) ExistsBatch(overlays []swarm.Address) (map[string]bool, error) {
+	b.migrate()
+
+	exists := make(map[string]bool, len(overlays))
+	for _, overlay := range overlays {
+		exists[overlay.ByteString()] = false
+	}
+
+	var expired []string
+	check := func(overlay swarm.Address, key string, e entry) {
+		if e.expired(b.clock) {
+			expired = append(expired, key)
+			return
+		}
+		exists[overlay.ByteString()] = true
+		b.metrics.ExistsHitCount.Inc()
+	}
+
+	b.mu.Lock()
+	iterate := len(exists) > b.entries
+	b.mu.Unlock()
+
+	if iterate {
+		if err := b.store.Iterate(keyPrefix, func(k, v []byte) (bool, error) {
+			if !strings.HasPrefix(string(k), keyPrefix) {
+				return true, nil
+			}
+			overlay, err := unmarshalKey(string(k))
+			if err != nil {
+				return true, err
+			}
+			if _, ok := exists[overlay.ByteString()]; !ok {
+				return false, nil
+			}
+			var e entry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return true, err
+			}
+			check(overlay, string(k), e)
+			return false, nil
+		}); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, overlay := range overlays {
+			key, e, err := b.find(overlay)
+			if err != nil {
+				if err == storage.ErrNotFound {
+					continue
+				}
+				return nil, err
+			}
+			check(overlay, key, e)
+		}
+	}
+
+	for _, key := range expired {
+		b.removeExpired(key)
+	}
+	return exists, nil
+}
+
+// removeExpired removes the entry if it is still expired, as it could have
+// been removed or renewed since it was found expired, unless it is retained.
+func (b *Blocklist) removeExpired(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e, err := b.get(key)
+	if err != nil || !e.removable(b.clock) {
+		return
+	}
+	if err := b.store.Delete(key); err != nil {
+		return
+	}
+	b.metrics.ExpiredCount.Inc()
+	b.addEntries(-1)
+	b.publish(key, ActionExpired, e)
+}
+
+// Add blocklists the peer for the duration. A zero duration blocklists the
+// peer forever, like AddPermanent. If the peer is already blocklisted, a
+// permanent block always wins, otherwise the block is renewed as configured
+// by Options.Renewal. The duration is escalated as configured by
+// Options.EscalationFactor if the peer is or was recently blocklisted.
 func (b *Blocklist) Add(overlay swarm.Address, duration time.Duration) (err error) {
-	key := generateKey(overlay)
-	_, d, err := b.get(key)
+	return b.AddWithReason(overlay, duration, "")
+}
+
+// AddPermanent blocklists the peer forever.
+func (b *Blocklist) AddPermanent(overlay swarm.Address) (err error) {
+	return b.add(overlay, entry{Permanent: true}, "")
+}
+
+// AddWithReason blocklists the peer like Add and stores the human-readable
+// reason with the entry. An empty reason keeps the reason of an existing
+// entry of the peer.
+func (b *Blocklist) AddWithReason(overlay swarm.Address, duration time.Duration, reason string) (err error) {
+	return b.add(overlay, entry{Duration: entryDuration(duration), Permanent: duration == 0}, reason)
+}
+
+// AddWithUnderlays blocklists the peer like AddWithReason and stores its
+// last known underlay addresses with the entry. No underlays keep the
+// underlays of an existing entry of the peer.
+func (b *Blocklist) AddWithUnderlays(overlay swarm.Address, underlays []ma.Multiaddr, duration time.Duration, reason string) (err error) {
+	n := entry{Duration: entryDuration(duration), Permanent: duration == 0}
+	for _, u := range underlays {
+		n.Underlays = append(n.Underlays, u.String())
+	}
+	return b.add(overlay, n, reason)
+}
+
+// add blocklists the peer with the new entry n, which
+// is merged with the existing entry of the peer, if any.
+func (b *Blocklist) add(overlay swarm.Address, n entry, reason string) (err error) {
+	b.migrate()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n.Timestamp = b.clock.Now()
+	n.Reason = reason
+	if err := b.escalate(overlay, &n); err != nil {
+		return err
+	}
+	return b.put(overlay, n, true)
+}
+
+// escalate multiplies the duration of the new temporary entry n of the peer
+// by the escalation factor for every consecutive block of the peer, if its
+// existing entry has not expired or is still retained, and caps it with the
+// maximal escalated duration. Must be called with mu locked.
+func (b *Blocklist) escalate(overlay swarm.Address, n *entry) error {
+	if b.escalationFactor <= 1 || n.permanent() {
+		return nil
+	}
+	n.Retain = entryDuration(b.escalationWindow)
+
+	_, e, err := b.find(overlay)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	if e.permanent() || e.removable(b.clock) {
+		return nil
+	}
+
+	n.Escalations = e.Escalations + 1
+	d := float64(n.Duration) * math.Pow(b.escalationFactor, float64(n.Escalations))
+	switch {
+	case b.escalationMax > 0 && d > float64(b.escalationMax):
+		n.Duration = entryDuration(b.escalationMax)
+	case d >= math.MaxInt64:
+		n.Duration = math.MaxInt64
+	default:
+		n.Duration = entryDuration(d)
+	}
+	return nil
+}
+
+// put stores the entry n of the peer, merged with the existing entry if
+// merge is true, or replacing it otherwise. The existing entry with the
+// legacy key is replaced by the one with the current key. Must be called
+// with mu locked.
+func (b *Blocklist) put(overlay swarm.Address, n entry, merge bool) error {
+	foundKey, e, err := b.find(overlay)
+	found := err == nil
 	if err != nil {
 		if err != storage.ErrNotFound {
 			return err
 		}
 	}
 
-	// if peer is already blacklisted, blacklist it for the maximum amount of time
-	if duration < d && duration != 0 || d == 0 {
-		duration = d
+	if !found && b.maxEntries > 0 && b.entries >= b.maxEntries {
+		if err := b.evict(b.entries - b.maxEntries + 1); err != nil {
+			return fmt.Errorf("evict: %w", err)
+		}
+	}
+
+	if found && merge {
+		n = e.merge(n, b.renewal, b.clock)
+	}
+	key := generateKey(overlay)
+	if err := b.store.Put(key, &n); err != nil {
+		return err
+	}
+	if found && foundKey != key {
+		if err := b.store.Delete(foundKey); err != nil {
+			return err
+		}
+	}
+
+	b.metrics.AddCount.Inc()
+	if !found {
+		b.addEntries(1)
+	}
+	b.publish(key, ActionAdded, n)
+	return nil
+}
+
+// Remove removes the peer from the blocklist before its block duration
+// elapses. It returns storage.ErrNotFound if the peer is not blocklisted.
+func (b *Blocklist) Remove(overlay swarm.Address) error {
+	b.migrate()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key, e, err := b.find(overlay)
+	if err != nil {
+		return err
+	}
+	if a, ok := b.attempts[generateKey(overlay)]; ok {
+		delete(b.attempts, generateKey(overlay))
+		b.attemptsCount -= int(a.count)
+	}
+	if err := b.store.Delete(key); err != nil {
+		return err
+	}
+	b.addEntries(-1)
+
+	if e.expired(b.clock) {
+		// already expired, only not swept yet
+		b.metrics.ExpiredCount.Inc()
+		b.publish(key, ActionExpired, e)
+		return storage.ErrNotFound
+	}
+	b.metrics.RemoveCount.Inc()
+	b.publish(key, ActionRemoved, e)
+	return nil
+}
+
+// RecordAttempt records a rejected connection attempt of a blocklisted peer.
+// The attempts are aggregated in memory and persisted to the entry of the
+// peer after AttemptsFlushEvery attempts or AttemptsFlushInterval, so that
+// recording is cheap. It reports whether this is the first attempt of the
+// peer since the attempts were last persisted.
+func (b *Blocklist) RecordAttempt(overlay swarm.Address) (first bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := generateKey(overlay)
+	a, ok := b.attempts[key]
+	if !ok {
+		a = new(attempts)
+		b.attempts[key] = a
+	}
+	a.count++
+	a.last = b.clock.Now()
+	b.attemptsCount++
+
+	if b.attemptsCount >= b.flushEvery || b.clock.Since(b.attemptsLastFlush) >= b.flushInterval {
+		err = b.flush()
+	}
+	return !ok, err
+}
+
+// Clear removes all entries of the overlays, including the invalid ones,
+// together with the pending connection attempts and returns the number of
+// the removed entries. The blocklisted IP addresses are kept.
+func (b *Blocklist) Clear() (removed int, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var (
+		keys    []string
+		entries []entry // decoded for the events, zero if invalid
+	)
+	if err := b.store.Iterate(keyPrefix, func(k, v []byte) (bool, error) {
+		if !strings.HasPrefix(string(k), keyPrefix) {
+			return true, nil
+		}
+		var e entry
+		_ = json.Unmarshal(v, &e)
+		keys = append(keys, string(k))
+		entries = append(entries, e)
+		return false, nil
+	}); err != nil {
+		return 0, err
+	}
+
+	b.attempts = make(map[string]*attempts)
+	b.attemptsCount = 0
+
+	defer func() {
+		b.metrics.RemoveCount.Add(float64(removed))
+		b.addEntries(-removed)
+	}()
+
+	for i, k := range keys {
+		if err := b.store.Delete(k); err != nil {
+			return removed, err
+		}
+		removed++
+		b.publish(k, ActionRemoved, entries[i])
+	}
+	return removed, nil
+}
+
+// Sweep removes the entries of the overlays, of the IP addresses and of the
+// IP address ranges with an elapsed block duration from the store and
+// returns the number of the removed entries.
+func (b *Blocklist) Sweep(ctx context.Context) (removed int, err error) {
+	b.migrate()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	removed, err = sweep(ctx, b.store, keyPrefix, b.clock, func(key string, e entry) {
+		b.publish(key, ActionExpired, e)
+	})
+	b.metrics.ExpiredCount.Add(float64(removed))
+	b.addEntries(-removed)
+	if err != nil {
+		return removed, err
+	}
+
+	removedIPs, err := sweep(ctx, b.store, ipKeyPrefix, b.clock, nil)
+	removed += removedIPs
+	if err != nil {
+		return removed, err
+	}
+
+	removedCIDRs, err := sweep(ctx, b.store, cidrKeyPrefix, b.clock, func(key string, _ entry) {
+		b.removeCIDR(key)
+	})
+	return removed + removedCIDRs, err
+}
+
+// NextExpiry returns the earliest time when the block duration of an entry
+// of an overlay, of an IP address or of an IP address range elapses, so that the sweep can be
+// scheduled, and false if there are no temporary entries. The entries that
+// have already expired expire now, as observed by the clock. The invalid
+// entries are skipped.
+func (b *Blocklist) NextExpiry() (next time.Time, ok bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock.Now()
+	for _, prefix := range []string{keyPrefix, ipKeyPrefix, cidrKeyPrefix} {
+		if err := b.store.Iterate(prefix, func(k, v []byte) (bool, error) {
+			if !strings.HasPrefix(string(k), prefix) {
+				return true, nil
+			}
+			var e entry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return false, nil
+			}
+			if e.permanent() {
+				return false, nil
+			}
+			expires := e.expires()
+			if e.expired(b.clock) {
+				expires = now
+			}
+			if !ok || expires.Before(next) {
+				next, ok = expires, true
+			}
+			return false, nil
+		}); err != nil {
+			return time.Time{}, false, err
+		}
+	}
+	return next, ok, nil
+}
+
+// Flush persists the recorded connection attempts.
+func (b *Blocklist) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.flush()
+}
+
+// flush adds the recorded connection attempts to the entries in the store.
+// The attempts of peers that are no longer blocklisted, including the ones
+// with an expired entry which is not removed yet, are dropped.
+// Must be called with mu locked.
+func (b *Blocklist) flush() error {
+	b.attemptsLastFlush = b.clock.Now()
+	for key, a := range b.attempts {
+		overlay, err := unmarshalKey(key)
+		if err != nil {
+			return err
+		}
+		foundKey, e, err := b.find(overlay)
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+		case err != nil:
+			return err
+		case e.expired(b.clock):
+			// expired since the attempts were recorded
+		default:
+			// the block duration is not affected by the attempts
+			e.Attempts += a.count
+			e.LastAttempt = a.last.UnixNano()
+			if err := b.store.Put(foundKey, &e); err != nil {
+				return err
+			}
+		}
+		delete(b.attempts, key)
+		b.attemptsCount -= int(a.count)
+	}
+	return nil
+}
+
+// CorruptedEntriesError is returned together with the valid blocklisted
+// peers when some of the entries could not be decoded and were skipped.
+// The errors of the statestore are returned as they are instead, as they
+// abort the iteration.
+type CorruptedEntriesError struct {
+	Keys []string // the statestore keys of the skipped entries
+	Errs []error  // the decoding errors of the skipped entries, by the index of their keys
+}
+
+func (e *CorruptedEntriesError) add(key string, err error) {
+	e.Keys = append(e.Keys, key)
+	e.Errs = append(e.Errs, err)
+}
+
+func (e *CorruptedEntriesError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "blocklist: %d corrupted entries skipped", len(e.Keys))
+	for i, k := range e.Keys {
+		fmt.Fprintf(&b, "; %q: %v", k, e.Errs[i])
 	}
+	return b.String()
+}
 
-	return b.store.Put(key, &entry{
-		Timestamp: timeNow(),
-		Duration:  duration.String(),
+// Peers returns all currently blocklisted peers with their remaining block
+// duration and their rejected connection attempts, including the ones that
+// are not persisted yet. The peers are sorted by the remaining block duration,
+// with the permanently blocklisted ones last, and by their overlay addresses
+// if it is equal. The entries that can not be read are skipped and reported
+// with a *CorruptedEntriesError returned with the valid peers.
+func (b *Blocklist) Peers() ([]p2p.BlockedPeer, error) {
+	peers, _, err := b.PeersFrom(swarm.ZeroAddress, 0)
+	sort.Slice(peers, func(i, j int) bool {
+		pi, pj := peers[i], peers[j]
+		if pi.Permanent() != pj.Permanent() {
+			return !pi.Permanent()
+		}
+		if pi.Remaining != pj.Remaining {
+			return pi.Remaining < pj.Remaining
+		}
+		return bytes.Compare(pi.Address.Bytes(), pj.Address.Bytes()) < 0
 	})
+	return peers, err
 }
 
-// Peers returns all currently blocklisted peers.
-func (b *Blocklist) Peers() ([]p2p.Peer, error) {
-	var peers []p2p.Peer
+// PeersFrom returns up to limit currently blocklisted peers, like Peers,
+// with the overlay addresses that follow the start address in the
+// lexicographic order, or from the first one if start is the zero address.
+// The last returned address is the start of the next page, which exists if
+// more is true. The iteration stops when the page is filled. The expired
+// entries do not count toward the limit and a limit of zero returns all
+// following peers. The corrupted entries are skipped like in Peers.
+func (b *Blocklist) PeersFrom(start swarm.Address, limit int) (peers []p2p.BlockedPeer, more bool, err error) {
+	b.migrateKeys()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var startKey string
+	if !start.IsZero() {
+		startKey = generateKey(start)
+	}
+
+	corrupted := new(CorruptedEntriesError)
 	if err := b.store.Iterate(keyPrefix, func(k, v []byte) (bool, error) {
 		if !strings.HasPrefix(string(k), keyPrefix) {
 			return true, nil
 		}
 		addr, err := unmarshalKey(string(k))
 		if err != nil {
-			return true, err
+			b.logger.Debugf("blocklist: skip entry with key %q: %v", k, err)
+			corrupted.add(string(k), err)
+			return false, nil
+		}
+		if generateKey(addr) <= startKey {
+			return false, nil
 		}
 
-		t, d, err := b.get(string(k))
+		var e entry
+		if err := json.Unmarshal(v, &e); err != nil {
+			// leave invalid entries to the integrity check
+			b.logger.Debugf("blocklist: decode entry of peer %s: %v", addr, err)
+			corrupted.add(string(k), err)
+			return false, nil
+		}
+		if e.expired(b.clock) {
+			// skip to the next item
+			return false, nil
+		}
+
+		if limit > 0 && len(peers) == limit {
+			more = true
+			return true, nil
+		}
+
+		peers = append(peers, b.blockedPeer(addr, e))
+		return false, nil
+	}); err != nil {
+		return nil, false, err
+	}
+
+	if len(corrupted.Keys) > 0 {
+		return peers, more, corrupted
+	}
+	return peers, more, nil
+}
+
+// Get returns the blocklisted peer with its block duration, remaining block
+// duration, reason and rejected connection attempts, like Peers. It returns
+// storage.ErrNotFound if the peer is not blocklisted. The expired entry of
+// the peer is removed like in Exists.
+func (b *Blocklist) Get(overlay swarm.Address) (p2p.BlockedPeer, error) {
+	b.migrate()
+
+	key, e, err := b.find(overlay)
+	if err != nil {
+		return p2p.BlockedPeer{}, err
+	}
+	if e.expired(b.clock) {
+		b.removeExpired(key)
+		return p2p.BlockedPeer{}, storage.ErrNotFound
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.blockedPeer(overlay, e), nil
+}
+
+// blockedPeer returns the blocklisted peer with the entry, including the
+// connection attempts that are not persisted yet. Must be called with mu
+// locked.
+func (b *Blocklist) blockedPeer(overlay swarm.Address, e entry) p2p.BlockedPeer {
+	p := p2p.BlockedPeer{
+		Peer:      p2p.Peer{Address: overlay},
+		Timestamp: e.Timestamp,
+		Attempts:  e.Attempts,
+		Reason:    e.Reason,
+	}
+	if !e.permanent() {
+		p.Duration = time.Duration(e.Duration)
+		p.Remaining = p.Duration - b.clock.Since(e.Timestamp)
+	}
+	if e.LastAttempt != 0 {
+		p.LastAttempt = time.Unix(0, e.LastAttempt)
+	}
+	if a, ok := b.attempts[generateKey(overlay)]; ok {
+		p.Attempts += a.count
+		p.LastAttempt = a.last
+	}
+	for _, u := range e.Underlays {
+		underlay, err := ma.NewMultiaddr(u)
 		if err != nil {
-			return true, err
+			b.logger.Debugf("blocklist: parse underlay %q of peer %s: %v", u, overlay, err)
+			continue
 		}
+		p.Underlays = append(p.Underlays, underlay)
+	}
+	return p
+}
 
-		if timeNow().Sub(t) > d && d != 0 {
-			// skip to the next item
+// migrate rewrites the legacy entries, which have the duration persisted as
+// a string, in the current format and counts the entries for the metrics.
+// It runs only once, on the first access to the entries, while the entries
+// of both formats can be read anyway.
+func (b *Blocklist) migrate() {
+	b.migrateOnce.Do(func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		var (
+			legacy  []string
+			entries int
+		)
+		if err := b.store.Iterate(keyPrefix, func(k, v []byte) (bool, error) {
+			if !strings.HasPrefix(string(k), keyPrefix) {
+				return true, nil
+			}
+			entries++
+			var e struct {
+				Duration json.RawMessage `json:"duration"`
+			}
+			if err := json.Unmarshal(v, &e); err == nil && isLegacyDuration(e.Duration) {
+				legacy = append(legacy, string(k))
+			}
 			return false, nil
+		}); err != nil {
+			b.logger.Debugf("blocklist: migrate entries: %v", err)
+			return
+		}
+		b.entries = entries
+		b.metrics.Entries.Set(float64(entries))
+
+		var migrated int
+		for _, key := range legacy {
+			e, err := b.get(key)
+			if err != nil {
+				// leave invalid entries to the integrity check
+				b.logger.Debugf("blocklist: migrate entry %q: %v", key, err)
+				continue
+			}
+			if err := b.store.Put(key, &e); err != nil {
+				b.logger.Debugf("blocklist: migrate entry %q: %v", key, err)
+				continue
+			}
+			migrated++
+		}
+		if migrated > 0 {
+			b.logger.Debugf("blocklist: migrated %d legacy entries", migrated)
 		}
+	})
+}
+
+// Migrate rewrites the entries with the legacy keys, which have the hex
+// encoded overlay, with the binary overlay keys. An entry of a peer with
+// both keys is merged into the one with the binary key. The migration runs
+// on the first listing of the peers too, while the entries with either key
+// are accessible anyway.
+func (b *Blocklist) Migrate() error {
+	b.migrate()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
-		p := p2p.Peer{Address: addr}
-		peers = append(peers, p)
+	var legacy []string
+	if err := b.store.Iterate(keyPrefix, func(k, _ []byte) (bool, error) {
+		if !strings.HasPrefix(string(k), keyPrefix) {
+			return true, nil
+		}
+		if _, ok := parseLegacyKey(string(k)); ok {
+			legacy = append(legacy, string(k))
+		}
 		return false, nil
 	}); err != nil {
-		return nil, err
+		return err
+	}
+
+	var migrated int
+	for _, legacyKey := range legacy {
+		overlay, _ := parseLegacyKey(legacyKey)
+		n, err := b.get(legacyKey)
+		if err != nil {
+			// leave invalid entries to the integrity check
+			b.logger.Debugf("blocklist: migrate entry of peer %s: %v", overlay, err)
+			continue
+		}
+
+		key := generateKey(overlay)
+		e, err := b.get(key)
+		found := err == nil
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+		if found {
+			n = e.merge(n, b.renewal, b.clock)
+		}
+		if err := b.store.Put(key, &n); err != nil {
+			return err
+		}
+		if err := b.store.Delete(legacyKey); err != nil {
+			return err
+		}
+		if found {
+			b.addEntries(-1)
+		}
+		migrated++
+	}
+	if migrated > 0 {
+		b.logger.Debugf("blocklist: migrated %d entries to binary keys", migrated)
+	}
+	return nil
+}
+
+// migrateKeys runs Migrate only once.
+func (b *Blocklist) migrateKeys() {
+	b.migrateKeysOnce.Do(func() {
+		if err := b.Migrate(); err != nil {
+			b.logger.Debugf("blocklist: migrate keys: %v", err)
+		}
+	})
+}
+
+// evict removes n entries, the invalid ones first, then the ones that expire
+// first and then the oldest permanent ones. Entries that are equal in this
+// order are evicted in the order of their keys. Must be called with mu locked.
+func (b *Blocklist) evict(n int) error {
+	type candidate struct {
+		key       string
+		entry     entry // zero if invalid
+		invalid   bool
+		permanent bool
+		at        time.Time // expiry, or the timestamp of a permanent entry
+	}
+
+	var candidates []candidate
+	if err := b.store.Iterate(keyPrefix, func(k, v []byte) (bool, error) {
+		if !strings.HasPrefix(string(k), keyPrefix) {
+			return true, nil
+		}
+		c := candidate{key: string(k)}
+		if err := json.Unmarshal(v, &c.entry); err != nil {
+			c.invalid = true
+		} else if e := c.entry; e.permanent() {
+			c.permanent = true
+			c.at = e.Timestamp
+		} else {
+			c.at = e.expires()
+		}
+		candidates = append(candidates, c)
+		return false, nil
+	}); err != nil {
+		return err
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		ci, cj := candidates[i], candidates[j]
+		if ci.invalid != cj.invalid {
+			return ci.invalid
+		}
+		if ci.permanent != cj.permanent {
+			return !ci.permanent
+		}
+		if !ci.at.Equal(cj.at) {
+			return ci.at.Before(cj.at)
+		}
+		return ci.key < cj.key
+	})
+
+	if n > len(candidates) {
+		n = len(candidates)
+	}
+	for _, c := range candidates[:n] {
+		if overlay, err := unmarshalKey(c.key); err == nil {
+			if a, ok := b.attempts[generateKey(overlay)]; ok {
+				delete(b.attempts, generateKey(overlay))
+				b.attemptsCount -= int(a.count)
+			}
+		}
+		if err := b.store.Delete(c.key); err != nil {
+			return err
+		}
+		b.addEntries(-1)
+		b.metrics.EvictedCount.Inc()
+		b.publish(c.key, ActionRemoved, c.entry)
 	}
+	return nil
+}
 
-	return peers, nil
+// addEntries adds n to the number of the entries. Must be called with mu locked.
+func (b *Blocklist) addEntries(n int) {
+	b.entries += n
+	b.metrics.Entries.Add(float64(n))
+}
+
+// find returns the entry of the peer and its key, which is the legacy key
+// if the entry has not been migrated yet.
+func (b *Blocklist) find(overlay swarm.Address) (key string, e entry, err error) {
+	key = generateKey(overlay)
+	e, err = b.get(key)
+	if errors.Is(err, storage.ErrNotFound) {
+		key = generateLegacyKey(overlay)
+		e, err = b.get(key)
+	}
+	return key, e, err
 }
 
-func (b *Blocklist) get(key string) (timestamp time.Time, duration time.Duration, err error) {
-	var e entry
+func (b *Blocklist) get(key string) (e entry, err error) {
 	if err := b.store.Get(key, &e); err != nil {
-		return time.Time{}, -1, err
+		return entry{}, err
 	}
 
-	duration, err = time.ParseDuration(e.Duration)
-	if err != nil {
-		return time.Time{}, -1, err
+	return e, nil
+}
+
+// sweepExpired returns the sweep which removes entries with an elapsed block
+// duration from the store, as observed by the clock.
+func sweepExpired(c clock.Clock) storage.SweepFunc {
+	return func(ctx context.Context, store storage.StateStorer) (removed int, err error) {
+		return sweep(ctx, store, keyPrefix, c, nil)
 	}
+}
+
+// sweep removes the expired entries with the prefix, which are not retained
+// anymore, from the store and calls the removed function, if it is set, for
+// each.
+func sweep(ctx context.Context, store storage.StateStorer, prefix string, c clock.Clock, removedFunc func(key string, e entry)) (removed int, err error) {
+	var (
+		expired []string
+		entries []entry
+	)
+	if err := store.Iterate(prefix, func(k, v []byte) (bool, error) {
+		if !strings.HasPrefix(string(k), prefix) {
+			return true, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return true, err
+		}
 
-	return e.Timestamp, duration, nil
+		var e entry
+		if err := json.Unmarshal(v, &e); err != nil {
+			// leave invalid entries to the integrity check
+			return false, nil
+		}
+		if e.removable(c) {
+			expired = append(expired, string(k))
+			entries = append(entries, e)
+		}
+		return false, nil
+	}); err != nil {
+		return 0, err
+	}
+
+	for i, k := range expired {
+		if err := store.Delete(k); err != nil {
+			return removed, err
+		}
+		removed++
+		if removedFunc != nil {
+			removedFunc(k, entries[i])
+		}
+	}
+	return removed, nil
 }
 
+// generateKey returns the key of the entry of the peer, with the binary overlay.
 func generateKey(overlay swarm.Address) string {
+	return keyPrefix + overlay.ByteString()
+}
+
+// generateLegacyKey returns the key of the entry of the peer with the
+// hex encoded overlay, which was used before the binary overlay keys.
+func generateLegacyKey(overlay swarm.Address) string {
 	return keyPrefix + overlay.String()
 }
 
+// unmarshalKey returns the overlay of the entry with either key.
 func unmarshalKey(s string) (swarm.Address, error) {
+	if addr, ok := parseLegacyKey(s); ok {
+		return addr, nil
+	}
 	addr := strings.TrimPrefix(s, keyPrefix)
-	return swarm.ParseHexAddress(addr)
+	if addr == "" {
+		return swarm.ZeroAddress, errors.New("empty overlay")
+	}
+	return swarm.NewAddress([]byte(addr)), nil
+}
+
+// parseLegacyKey returns the overlay of the entry with the legacy key and
+// reports whether the key is one. A binary overlay key is taken for a legacy
+// one only if all bytes of the overlay are lowercase hex digits, which is
+// practically impossible for the 32 bytes long overlays.
+func parseLegacyKey(s string) (swarm.Address, bool) {
+	addr := strings.TrimPrefix(s, keyPrefix)
+	if addr == "" || strings.ToLower(addr) != addr {
+		return swarm.ZeroAddress, false
+	}
+	a, err := swarm.ParseHexAddress(addr)
+	if err != nil {
+		return swarm.ZeroAddress, false
+	}
+	return a, true
 }
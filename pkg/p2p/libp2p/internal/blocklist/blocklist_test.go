@@ -5,20 +5,37 @@
 package blocklist_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/ethersphere/bee/pkg/internal/clock"
+	"github.com/ethersphere/bee/pkg/logging"
 	"github.com/ethersphere/bee/pkg/p2p"
 	"github.com/ethersphere/bee/pkg/p2p/libp2p/internal/blocklist"
+	"github.com/ethersphere/bee/pkg/statestore/leveldb"
 	"github.com/ethersphere/bee/pkg/statestore/mock"
+	"github.com/ethersphere/bee/pkg/storage"
 	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/ethersphere/bee/pkg/swarm/test"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestExist(t *testing.T) {
 	addr1 := swarm.NewAddress([]byte{0, 1, 2, 3})
 	addr2 := swarm.NewAddress([]byte{4, 5, 6, 7})
 
-	bl := blocklist.NewBlocklist(mock.NewStateStore())
+	c := clock.NewMock(time.Now())
+	bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{Clock: c})
 
 	exists, err := bl.Exists(addr1)
 	if err != nil {
@@ -39,8 +56,7 @@ func TestExist(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	blocklist.SetTimeNow(func() time.Time { return time.Now().Add(100 * time.Millisecond) })
-	defer func() { blocklist.SetTimeNow(time.Now) }()
+	c.Advance(100 * time.Millisecond)
 
 	exists, err = bl.Exists(addr1)
 	if err != nil {
@@ -61,11 +77,187 @@ func TestExist(t *testing.T) {
 
 }
 
+func TestExistsWithRemaining(t *testing.T) {
+	// the blocklists have their own clocks, so the tests run in parallel
+	t.Parallel()
+
+	addr1 := swarm.NewAddress([]byte{0, 1, 2, 3})
+	addr2 := swarm.NewAddress([]byte{4, 5, 6, 7})
+	addr3 := swarm.NewAddress([]byte{8, 9, 10, 11})
+
+	store := mock.NewStateStore()
+	c := clock.NewMock(time.Now())
+	bl := blocklist.NewBlocklist(store, blocklist.Options{Clock: c})
+
+	if err := bl.Add(addr1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Add(addr2, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Add(addr3, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	// the blocklist on another clock is not affected by the advance
+	other := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{Clock: clock.NewMock(c.Now())})
+	if err := other.Add(addr3, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Advance(20 * time.Minute)
+
+	for _, tc := range []struct {
+		addr      swarm.Address
+		exists    bool
+		remaining time.Duration
+	}{
+		{addr: addr1, exists: true, remaining: blocklist.PermanentRemaining},
+		{addr: addr2, exists: true, remaining: 40 * time.Minute},
+		{addr: addr3, exists: false, remaining: 0}, // expired
+	} {
+		exists, remaining, err := bl.ExistsWithRemaining(tc.addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists != tc.exists || remaining != tc.remaining {
+			t.Errorf("got exists %v and remaining %v for peer %s, want %v and %v", exists, remaining, tc.addr, tc.exists, tc.remaining)
+		}
+	}
+
+	// the expired entry is removed
+	expectStored(t, store, addr3, false)
+
+	exists, remaining, err := other.ExistsWithRemaining(addr3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exists || remaining != time.Minute {
+		t.Errorf("got exists %v and remaining %v on the other clock, want %v and %v", exists, remaining, true, time.Minute)
+	}
+}
+
+func TestGet(t *testing.T) {
+	addr1 := swarm.NewAddress([]byte{0, 1, 2, 3})
+	addr2 := swarm.NewAddress([]byte{4, 5, 6, 7})
+	addr3 := swarm.NewAddress([]byte{8, 9, 10, 11})
+
+	start := time.Now()
+	store := mock.NewStateStore()
+	c := clock.NewMock(start)
+	bl := blocklist.NewBlocklist(store, blocklist.Options{Clock: c})
+
+	if err := bl.AddWithReason(addr1, time.Hour, "misbehaved"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Add(addr2, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bl.RecordAttempt(addr1); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Advance(20 * time.Minute)
+
+	t.Run("found", func(t *testing.T) {
+		p, err := bl.Get(addr1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !p.Address.Equal(addr1) ||
+			!p.Timestamp.Equal(start) ||
+			p.Duration != time.Hour ||
+			p.Remaining != 40*time.Minute ||
+			p.Reason != "misbehaved" ||
+			p.Attempts != 1 {
+			t.Fatalf("got blocklisted peer %+v", p)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		if _, err := bl.Get(addr3); !errors.Is(err, storage.ErrNotFound) {
+			t.Fatalf("got error %v, want %v", err, storage.ErrNotFound)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		if _, err := bl.Get(addr2); !errors.Is(err, storage.ErrNotFound) {
+			t.Fatalf("got error %v, want %v", err, storage.ErrNotFound)
+		}
+		expectStored(t, store, addr2, false)
+	})
+}
+
+func TestExistsBatch(t *testing.T) {
+	addr1 := swarm.NewAddress([]byte{0, 1, 2, 3})
+	addr2 := swarm.NewAddress([]byte{4, 5, 6, 7})
+	addr3 := swarm.NewAddress([]byte{8, 9, 10, 11})
+	addr4 := swarm.NewAddress([]byte{12, 13, 14, 15})
+	addr5 := swarm.NewAddress([]byte{16, 17, 18, 19})
+
+	for _, tc := range []struct {
+		name     string
+		overlays []swarm.Address
+	}{
+		// fewer overlays than entries are looked up one by one
+		{name: "lookup", overlays: []swarm.Address{addr1, addr2}},
+		// more overlays than entries are found with an iteration
+		{name: "iteration", overlays: []swarm.Address{addr1, addr2, addr3, addr4, addr5}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store := mock.NewStateStore()
+			c := clock.NewMock(time.Now())
+			bl := blocklist.NewBlocklist(store, blocklist.Options{Clock: c})
+
+			if err := bl.Add(addr1, 0); err != nil {
+				t.Fatal(err)
+			}
+			if err := bl.Add(addr2, time.Minute); err != nil {
+				t.Fatal(err)
+			}
+			if err := bl.Add(addr3, time.Hour); err != nil {
+				t.Fatal(err)
+			}
+
+			c.Advance(time.Hour / 2)
+
+			got, err := bl.ExistsBatch(tc.overlays)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := map[string]bool{
+				addr1.ByteString(): true,
+				addr2.ByteString(): false,
+				addr3.ByteString(): true,
+				addr4.ByteString(): false,
+				addr5.ByteString(): false,
+			}
+			if len(got) != len(tc.overlays) {
+				t.Fatalf("got %d results, want %d", len(got), len(tc.overlays))
+			}
+			for _, overlay := range tc.overlays {
+				exists, ok := got[overlay.ByteString()]
+				if !ok {
+					t.Fatalf("got no result for peer %s", overlay)
+				}
+				if exists != want[overlay.ByteString()] {
+					t.Errorf("got exists %v for peer %s, want %v", exists, overlay, want[overlay.ByteString()])
+				}
+			}
+
+			// the expired entry is removed
+			expectStored(t, store, addr2, false)
+			expectStored(t, store, addr3, true)
+		})
+	}
+}
+
 func TestPeers(t *testing.T) {
 	addr1 := swarm.NewAddress([]byte{0, 1, 2, 3})
 	addr2 := swarm.NewAddress([]byte{4, 5, 6, 7})
 
-	bl := blocklist.NewBlocklist(mock.NewStateStore())
+	c := clock.NewMock(time.Now())
+	bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{Clock: c})
 
 	// add forever
 	if err := bl.Add(addr1, 0); err != nil {
@@ -89,8 +281,7 @@ func TestPeers(t *testing.T) {
 		t.Fatalf("expected addr2 to exist in peers: %v", addr2)
 	}
 
-	blocklist.SetTimeNow(func() time.Time { return time.Now().Add(100 * time.Millisecond) })
-	defer func() { blocklist.SetTimeNow(time.Now) }()
+	c.Advance(100 * time.Millisecond)
 
 	// now expect just one
 	peers, err = bl.Peers()
@@ -106,11 +297,1626 @@ func TestPeers(t *testing.T) {
 	}
 }
 
-func isIn(p swarm.Address, peers []p2p.Peer) bool {
-	for _, v := range peers {
-		if v.Address.Equal(p) {
-			return true
+func TestPeersRemaining(t *testing.T) {
+	addr1 := swarm.NewAddress([]byte{0, 1, 2, 3})
+	addr2 := swarm.NewAddress([]byte{4, 5, 6, 7})
+
+	start := time.Now()
+	c := clock.NewMock(start)
+	bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{Clock: c})
+
+	if err := bl.Add(addr1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Add(addr2, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Advance(20 * time.Minute)
+
+	peers, err := bl.Peers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 2 {
+		t.Fatalf("got %d blocklisted peers, want %d", len(peers), 2)
+	}
+	for _, p := range peers {
+		if !p.Timestamp.Equal(start) {
+			t.Errorf("got timestamp %v for peer %s, want %v", p.Timestamp, p.Address, start)
+		}
+		switch {
+		case p.Address.Equal(addr1):
+			if !p.Permanent() || p.Duration != 0 || p.Remaining != 0 {
+				t.Errorf("got duration %v and remaining %v for the permanent block, want zero", p.Duration, p.Remaining)
+			}
+		case p.Address.Equal(addr2):
+			if p.Permanent() || p.Duration != time.Hour || p.Remaining != 40*time.Minute {
+				t.Errorf("got duration %v and remaining %v, want %v and %v", p.Duration, p.Remaining, time.Hour, 40*time.Minute)
+			}
+		default:
+			t.Errorf("got unexpected peer %s", p.Address)
 		}
 	}
-	return false
+}
+
+func TestPeersOrder(t *testing.T) {
+	addr1 := swarm.NewAddress([]byte{0, 1, 2, 3})
+	addr2 := swarm.NewAddress([]byte{4, 5, 6, 7})
+	addr3 := swarm.NewAddress([]byte{8, 9, 10, 11})
+	addr4 := swarm.NewAddress([]byte{12, 13, 14, 15})
+	addr5 := swarm.NewAddress([]byte{16, 17, 18, 19})
+	addr6 := swarm.NewAddress([]byte{20, 21, 22, 23})
+
+	c := clock.NewMock(time.Now())
+	bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{Clock: c})
+
+	for _, p := range []struct {
+		addr     swarm.Address
+		duration time.Duration
+	}{
+		{addr: addr6, duration: 0},
+		{addr: addr1, duration: 0},
+		{addr: addr5, duration: time.Hour},
+		{addr: addr4, duration: 2 * time.Hour},
+		{addr: addr2, duration: time.Hour},
+	} {
+		if err := bl.Add(p.addr, p.duration); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// blocklisted later for a shorter duration, but expires first
+	c.Advance(time.Minute)
+	if err := bl.Add(addr3, 30*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	peers, err := bl.Peers()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []swarm.Address{
+		addr3,        // 30 minutes remaining
+		addr2, addr5, // equal remaining durations in the order of the overlays
+		addr4,        // the longest remaining duration
+		addr1, addr6, // permanent blocks in the order of the overlays
+	}
+	got := make([]swarm.Address, len(peers))
+	for i, p := range peers {
+		got[i] = p.Address
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got peers %v, want %v", got, want)
+	}
+}
+
+func TestAddMerge(t *testing.T) {
+	addr := swarm.NewAddress([]byte{0, 1, 2, 3})
+
+	now := time.Now()
+	before := now.Add(-30 * time.Minute)
+
+	type storedEntry struct {
+		Timestamp time.Time `json:"timestamp"`
+		Duration  int64     `json:"duration"`
+		Permanent bool      `json:"permanent,omitempty"`
+	}
+	existing := []struct {
+		name  string
+		entry *storedEntry
+	}{
+		{name: "none"},
+		{name: "temporary expiring earlier", entry: &storedEntry{Timestamp: before, Duration: int64(time.Hour)}},
+		{name: "temporary expiring later", entry: &storedEntry{Timestamp: before, Duration: int64(2 * time.Hour)}},
+		{name: "temporary expired", entry: &storedEntry{Timestamp: before, Duration: int64(10 * time.Minute)}},
+		{name: "permanent", entry: &storedEntry{Timestamp: before, Permanent: true}},
+		{name: "legacy permanent", entry: &storedEntry{Timestamp: before}},
+	}
+	added := []struct {
+		name string
+		add  func(*blocklist.Blocklist) error
+	}{
+		{name: "temporary", add: func(bl *blocklist.Blocklist) error { return bl.Add(addr, time.Hour) }},
+		{name: "permanent", add: func(bl *blocklist.Blocklist) error { return bl.AddPermanent(addr) }},
+		{name: "zero duration", add: func(bl *blocklist.Blocklist) error { return bl.Add(addr, 0) }},
+	}
+
+	type result struct {
+		timestamp time.Time
+		duration  time.Duration // zero if permanent
+	}
+	temporaryResult := result{timestamp: now, duration: time.Hour}
+	permanentResult := result{timestamp: now}
+	existingPermanentResult := result{timestamp: before}
+	want := map[string]map[string]result{
+		"none": {
+			"temporary":     temporaryResult,
+			"permanent":     permanentResult,
+			"zero duration": permanentResult,
+		},
+		"temporary expiring earlier": {
+			"temporary":     temporaryResult,
+			"permanent":     permanentResult,
+			"zero duration": permanentResult,
+		},
+		"temporary expiring later": {
+			"temporary":     {timestamp: before, duration: 2 * time.Hour},
+			"permanent":     permanentResult,
+			"zero duration": permanentResult,
+		},
+		"temporary expired": {
+			"temporary":     temporaryResult,
+			"permanent":     permanentResult,
+			"zero duration": permanentResult,
+		},
+		"permanent": {
+			"temporary":     existingPermanentResult,
+			"permanent":     existingPermanentResult,
+			"zero duration": existingPermanentResult,
+		},
+		"legacy permanent": {
+			"temporary":     existingPermanentResult,
+			"permanent":     existingPermanentResult,
+			"zero duration": existingPermanentResult,
+		},
+	}
+
+	for _, ex := range existing {
+		for _, ad := range added {
+			t.Run(ex.name+" "+ad.name, func(t *testing.T) {
+				store := mock.NewStateStore()
+				if ex.entry != nil {
+					if err := store.Put(blocklist.GenerateKey(addr), ex.entry); err != nil {
+						t.Fatal(err)
+					}
+				}
+				bl := blocklist.NewBlocklist(store, blocklist.Options{Clock: clock.NewMock(now)})
+
+				if err := ad.add(bl); err != nil {
+					t.Fatal(err)
+				}
+
+				peers, err := bl.Peers()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(peers) != 1 {
+					t.Fatalf("got %d blocklisted peers, want %d", len(peers), 1)
+				}
+				p := peers[0]
+				w := want[ex.name][ad.name]
+				if !p.Timestamp.Equal(w.timestamp) || p.Duration != w.duration {
+					t.Errorf("got timestamp %v and duration %v, want %v and %v", p.Timestamp, p.Duration, w.timestamp, w.duration)
+				}
+
+				// permanent entries are stored as such explicitly
+				var e storedEntry
+				if err := store.Get(blocklist.GenerateKey(addr), &e); err != nil {
+					t.Fatal(err)
+				}
+				if got, want := e.Permanent, w.duration == 0; got != want {
+					t.Errorf("got stored permanent %v, want %v", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestRenewal(t *testing.T) {
+	addr := swarm.NewAddress([]byte{0, 1, 2, 3})
+
+	for _, tc := range []struct {
+		name     string
+		renewal  blocklist.Renewal
+		duration time.Duration // of the second block
+		expires  time.Duration // since the first block
+	}{
+		{name: "reset", renewal: blocklist.RenewalReset, duration: time.Hour, expires: 90 * time.Minute},
+		{name: "keep timestamp", renewal: blocklist.RenewalKeepTimestamp, duration: time.Hour, expires: time.Hour},
+		{name: "keep timestamp shorter", renewal: blocklist.RenewalKeepTimestamp, duration: time.Minute, expires: time.Hour},
+		{name: "keep timestamp longer", renewal: blocklist.RenewalKeepTimestamp, duration: 2 * time.Hour, expires: 2 * time.Hour},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			start := time.Now()
+			c := clock.NewMock(start)
+			bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{Clock: c, Renewal: tc.renewal})
+
+			if err := bl.Add(addr, time.Hour); err != nil {
+				t.Fatal(err)
+			}
+			c.Advance(30 * time.Minute)
+			if err := bl.Add(addr, tc.duration); err != nil {
+				t.Fatal(err)
+			}
+
+			c.Advance(start.Add(tc.expires).Sub(c.Now()))
+			exists, err := bl.Exists(addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !exists {
+				t.Fatal("got not exists at the expiry, expected exists")
+			}
+
+			c.Advance(time.Millisecond)
+			exists, err = bl.Exists(addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exists {
+				t.Fatal("got exists after the expiry, expected not exists")
+			}
+		})
+	}
+
+	t.Run("keep timestamp expired", func(t *testing.T) {
+		start := time.Now()
+		c := clock.NewMock(start)
+		bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{Clock: c, Renewal: blocklist.RenewalKeepTimestamp})
+
+		if err := bl.Add(addr, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		c.Advance(time.Hour)
+		// the block of an expired entry starts anew
+		if err := bl.Add(addr, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		peers, err := bl.Peers()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(peers) != 1 || !peers[0].Timestamp.Equal(c.Now()) {
+			t.Fatalf("got blocklisted peers %v, want one blocklisted at %v", peers, c.Now())
+		}
+	})
+}
+
+func TestEscalation(t *testing.T) {
+	addr := swarm.NewAddress([]byte{0, 1, 2, 3})
+
+	// block adds the peer for a minute and returns its block duration
+	block := func(t *testing.T, bl *blocklist.Blocklist) time.Duration {
+		t.Helper()
+
+		if err := bl.Add(addr, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		peers, err := bl.Peers()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(peers) != 1 {
+			t.Fatalf("got %d blocklisted peers, want 1", len(peers))
+		}
+		return peers[0].Duration
+	}
+
+	for _, tc := range []struct {
+		name string
+		o    blocklist.Options
+		want []time.Duration
+	}{
+		{
+			name: "escalated",
+			o:    blocklist.Options{EscalationFactor: 2, EscalationWindow: 10 * time.Minute},
+			want: []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute},
+		},
+		{
+			name: "capped",
+			o:    blocklist.Options{EscalationFactor: 2, EscalationWindow: 10 * time.Minute, EscalationMax: 3 * time.Minute},
+			want: []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute},
+		},
+		{
+			name: "disabled",
+			o:    blocklist.Options{EscalationWindow: 10 * time.Minute},
+			want: []time.Duration{time.Minute, time.Minute, time.Minute},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := clock.NewMock(time.Now())
+			tc.o.Clock = c
+			bl := blocklist.NewBlocklist(mock.NewStateStore(), tc.o)
+
+			for i, want := range tc.want {
+				if got := block(t, bl); got != want {
+					t.Fatalf("block %d: got duration %v, want %v", i, got, want)
+				}
+				// the block expires, but the peer is blocklisted
+				// again within the escalation window
+				c.Advance(want + time.Minute)
+				exists, err := bl.Exists(addr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if exists {
+					t.Fatalf("block %d: got exists after the expiry, want not exists", i)
+				}
+				if _, err := bl.Sweep(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+
+	t.Run("window elapsed", func(t *testing.T) {
+		c := clock.NewMock(time.Now())
+		bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{Clock: c, EscalationFactor: 2, EscalationWindow: 10 * time.Minute})
+
+		if got := block(t, bl); got != time.Minute {
+			t.Fatalf("got duration %v, want %v", got, time.Minute)
+		}
+		c.Advance(2 * time.Minute)
+		if got := block(t, bl); got != 2*time.Minute {
+			t.Fatalf("got duration %v, want %v", got, 2*time.Minute)
+		}
+
+		// the retained entry is removed after the window
+		c.Advance(13 * time.Minute)
+		removed, err := bl.Sweep(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if removed != 1 {
+			t.Fatalf("got %d removed entries, want 1", removed)
+		}
+		if got := block(t, bl); got != time.Minute {
+			t.Fatalf("got duration %v, want %v", got, time.Minute)
+		}
+	})
+}
+
+func TestPeersFrom(t *testing.T) {
+	addrs := make([]swarm.Address, 5)
+	for i := range addrs {
+		addrs[i] = swarm.NewAddress([]byte{byte(i + 1)})
+	}
+
+	c := clock.NewMock(time.Now())
+	bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{Clock: c})
+
+	for i, addr := range addrs {
+		d := time.Duration(0)
+		if i == 2 {
+			d = time.Minute
+		}
+		if err := bl.Add(addr, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the entry of the third peer expires
+	c.Advance(time.Hour)
+
+	for _, tc := range []struct {
+		name     string
+		start    swarm.Address
+		limit    int
+		want     []swarm.Address
+		wantMore bool
+	}{
+		{
+			name:  "all",
+			start: swarm.ZeroAddress,
+			want:  []swarm.Address{addrs[0], addrs[1], addrs[3], addrs[4]},
+		},
+		{
+			name:     "first page",
+			start:    swarm.ZeroAddress,
+			limit:    2,
+			want:     []swarm.Address{addrs[0], addrs[1]},
+			wantMore: true,
+		},
+		{
+			name:  "last page",
+			start: addrs[1],
+			limit: 2,
+			want:  []swarm.Address{addrs[3], addrs[4]},
+		},
+		{
+			name:     "expired entry not counted",
+			start:    addrs[0],
+			limit:    2,
+			want:     []swarm.Address{addrs[1], addrs[3]},
+			wantMore: true,
+		},
+		{
+			name:  "after last",
+			start: addrs[4],
+			limit: 2,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			peers, more, err := bl.PeersFrom(tc.start, tc.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if more != tc.wantMore {
+				t.Errorf("got more %v, want %v", more, tc.wantMore)
+			}
+			var got []swarm.Address
+			for _, p := range peers {
+				got = append(got, p.Address)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got peers %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReason(t *testing.T) {
+	addr1 := swarm.NewAddress([]byte{0, 1, 2, 3})
+	addr2 := swarm.NewAddress([]byte{4, 5, 6, 7})
+	addr3 := swarm.NewAddress([]byte{8, 9, 10, 11})
+
+	store := mock.NewStateStore()
+	bl := blocklist.NewBlocklist(store, blocklist.Options{})
+
+	if err := bl.AddWithReason(addr1, 0, "bad handshake"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Add(addr2, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// an entry stored before the reasons were introduced
+	old := struct {
+		Timestamp time.Time `json:"timestamp"`
+		Duration  string    `json:"duration"`
+	}{
+		Timestamp: time.Now(),
+		Duration:  "0s",
+	}
+	if err := store.Put("blocklist-"+addr3.String(), old); err != nil {
+		t.Fatal(err)
+	}
+
+	expectReasons(t, bl, map[string]string{
+		addr1.String(): "bad handshake",
+		addr2.String(): "",
+		addr3.String(): "",
+	})
+
+	// renewing the entry without a reason keeps it
+	if err := bl.Add(addr1, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	// while a new reason replaces it
+	if err := bl.AddWithReason(addr2, 0, "operator"); err != nil {
+		t.Fatal(err)
+	}
+
+	expectReasons(t, bl, map[string]string{
+		addr1.String(): "bad handshake",
+		addr2.String(): "operator",
+		addr3.String(): "",
+	})
+}
+
+func expectReasons(t *testing.T, bl *blocklist.Blocklist, want map[string]string) {
+	t.Helper()
+
+	peers, err := bl.Peers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != len(want) {
+		t.Fatalf("got %d blocklisted peers, want %d", len(peers), len(want))
+	}
+	for _, p := range peers {
+		if reason, ok := want[p.Address.String()]; !ok || p.Reason != reason {
+			t.Errorf("got reason %q for peer %s, want %q", p.Reason, p.Address, reason)
+		}
+	}
+}
+
+func TestUnderlays(t *testing.T) {
+	addr1 := swarm.NewAddress([]byte{0, 1, 2, 3})
+	addr2 := swarm.NewAddress([]byte{4, 5, 6, 7})
+	addr3 := swarm.NewAddress([]byte{8, 9, 10, 11})
+
+	underlay := ma.StringCast("/ip4/10.0.0.1/tcp/1634")
+	store := mock.NewStateStore()
+	bl := blocklist.NewBlocklist(store, blocklist.Options{})
+
+	if err := bl.AddWithUnderlays(addr1, []ma.Multiaddr{underlay}, time.Hour, "misbehaved"); err != nil {
+		t.Fatal(err)
+	}
+	// blocklisting the peer again without underlays keeps them
+	if err := bl.Add(addr1, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Add(addr2, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	// an entry with a stored underlay that can not be parsed
+	if err := store.Put(blocklist.GenerateKey(addr3), map[string]interface{}{
+		"timestamp": time.Now(),
+		"underlays": []string{"invalid", underlay.String()},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	peers, err := bl.Peers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 3 {
+		t.Fatalf("got %d blocklisted peers, want %d", len(peers), 3)
+	}
+	for _, p := range peers {
+		var want []ma.Multiaddr
+		switch {
+		case p.Address.Equal(addr1), p.Address.Equal(addr3):
+			want = []ma.Multiaddr{underlay}
+		case p.Address.Equal(addr2):
+		default:
+			t.Fatalf("got unexpected peer %s", p.Address)
+		}
+		if len(p.Underlays) != len(want) {
+			t.Fatalf("got underlays %v of peer %s, want %v", p.Underlays, p.Address, want)
+		}
+		for i := range want {
+			if !p.Underlays[i].Equal(want[i]) {
+				t.Errorf("got underlays %v of peer %s, want %v", p.Underlays, p.Address, want)
+			}
+		}
+	}
+}
+
+func TestLegacyEntries(t *testing.T) {
+	addr1 := swarm.NewAddress([]byte{0, 1, 2, 3})
+	addr2 := swarm.NewAddress([]byte{4, 5, 6, 7})
+	addr3 := swarm.NewAddress([]byte{8, 9, 10, 11})
+
+	now := time.Now()
+	store := mock.NewStateStore()
+
+	// entries with the duration stored as a string
+	type legacyEntry struct {
+		Timestamp time.Time `json:"timestamp"`
+		Duration  string    `json:"duration"`
+	}
+	for addr, d := range map[string]string{
+		addr1.String(): "0s",
+		addr2.String(): "1h0m0s",
+		addr3.String(): "corrupted",
+	} {
+		if err := store.Put("blocklist-"+addr, legacyEntry{Timestamp: now, Duration: d}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := clock.NewMock(now.Add(time.Minute))
+	bl := blocklist.NewBlocklist(store, blocklist.Options{Clock: c})
+
+	// the corrupted entry does not prevent listing the others
+	peers, err := bl.Peers()
+	var corrupted *blocklist.CorruptedEntriesError
+	if !errors.As(err, &corrupted) {
+		t.Fatalf("got error %v, want %T", err, corrupted)
+	}
+	if len(peers) != 2 {
+		t.Fatalf("got %d blocklisted peers, want %d", len(peers), 2)
+	}
+	for _, p := range peers {
+		switch {
+		case p.Address.Equal(addr1):
+			if !p.Permanent() {
+				t.Errorf("got duration %v, want a permanent block", p.Duration)
+			}
+		case p.Address.Equal(addr2):
+			if p.Duration != time.Hour || p.Remaining != 59*time.Minute {
+				t.Errorf("got duration %v and remaining %v, want %v and %v", p.Duration, p.Remaining, time.Hour, 59*time.Minute)
+			}
+		default:
+			t.Errorf("got unexpected peer %s", p.Address)
+		}
+	}
+
+	// the valid entries are rewritten with the duration in
+	// nanoseconds and with the binary overlay keys, the
+	// corrupted one is left as it is
+	for key, want := range map[string]string{
+		blocklist.GenerateKey(addr1):  "0",
+		blocklist.GenerateKey(addr2):  "3600000000000",
+		"blocklist-" + addr3.String(): `"corrupted"`,
+	} {
+		var e struct {
+			Duration json.RawMessage `json:"duration"`
+		}
+		if err := store.Get(key, &e); err != nil {
+			t.Fatal(err)
+		}
+		if got := string(e.Duration); got != want {
+			t.Errorf("got stored duration %s of key %q, want %s", got, key, want)
+		}
+	}
+
+	exists, err := bl.Exists(addr2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exists {
+		t.Fatal("got not exists, expected exists")
+	}
+}
+
+func TestMixedKeys(t *testing.T) {
+	addr1 := swarm.NewAddress([]byte{0, 1, 2, 3})
+	addr2 := swarm.NewAddress([]byte{4, 5, 6, 7})
+	addr3 := swarm.NewAddress([]byte{8, 9, 10, 11})
+	addr4 := swarm.NewAddress([]byte{12, 13, 14, 15})
+	addr5 := swarm.NewAddress([]byte{16, 17, 18, 19})
+
+	now := time.Now()
+	store := mock.NewStateStore()
+
+	type storedEntry struct {
+		Timestamp time.Time `json:"timestamp"`
+		Duration  int64     `json:"duration"`
+		Reason    string    `json:"reason,omitempty"`
+	}
+	legacyKey := func(addr swarm.Address) string {
+		return "blocklist-" + addr.String()
+	}
+	for key, e := range map[string]storedEntry{
+		legacyKey(addr1):             {Timestamp: now, Duration: int64(time.Hour)},
+		blocklist.GenerateKey(addr2): {Timestamp: now, Duration: int64(time.Hour)},
+		// the entries of the same peer with both keys are merged
+		legacyKey(addr3):             {Timestamp: now, Duration: int64(2 * time.Hour), Reason: "legacy"},
+		blocklist.GenerateKey(addr3): {Timestamp: now, Duration: int64(time.Hour)},
+		legacyKey(addr4):             {Timestamp: now.Add(-time.Hour), Duration: int64(time.Minute)},
+	} {
+		if err := store.Put(key, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := clock.NewMock(now)
+	bl := blocklist.NewBlocklist(store, blocklist.Options{Clock: c})
+
+	for _, tc := range []struct {
+		addr swarm.Address
+		want bool
+	}{
+		{addr: addr1, want: true},
+		{addr: addr2, want: true},
+		{addr: addr3, want: true},
+		{addr: addr4, want: false}, // expired
+		{addr: addr5, want: false},
+	} {
+		exists, err := bl.Exists(tc.addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists != tc.want {
+			t.Errorf("got exists %v for peer %s, want %v", exists, tc.addr, tc.want)
+		}
+	}
+	// the expired entry with the legacy key is removed
+	var v interface{}
+	if err := store.Get(legacyKey(addr4), &v); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, storage.ErrNotFound)
+	}
+
+	// adding the peer replaces the entry with the legacy key
+	if err := bl.Add(addr1, 2*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Get(legacyKey(addr1), &v); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, storage.ErrNotFound)
+	}
+	expectStored(t, store, addr1, true)
+
+	peers, err := bl.Peers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []p2p.BlockedPeer{
+		{Peer: p2p.Peer{Address: addr1}, Timestamp: now, Duration: 2 * time.Hour, Remaining: 2 * time.Hour},
+		{Peer: p2p.Peer{Address: addr2}, Timestamp: now, Duration: time.Hour, Remaining: time.Hour},
+		{Peer: p2p.Peer{Address: addr3}, Timestamp: now, Duration: 2 * time.Hour, Remaining: 2 * time.Hour, Reason: "legacy"},
+	}
+	expectPeers(t, peers, want)
+
+	// listing the peers migrates the remaining legacy keys
+	var legacy []string
+	if err := store.Iterate("blocklist-", func(k, _ []byte) (bool, error) {
+		if _, err := swarm.ParseHexAddress(strings.TrimPrefix(string(k), "blocklist-")); err == nil {
+			legacy = append(legacy, string(k))
+		}
+		return false, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(legacy) != 0 {
+		t.Fatalf("got legacy keys %q, want none", legacy)
+	}
+	if got := testutil.ToFloat64(bl.MetricsValues().Entries); got != 3 {
+		t.Fatalf("got %v entries, want %v", got, 3)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	addr1 := swarm.NewAddress([]byte{0, 1, 2, 3})
+	addr2 := swarm.NewAddress([]byte{4, 5, 6, 7})
+	addr3 := swarm.NewAddress([]byte{8, 9, 10, 11})
+
+	store := mock.NewStateStore()
+	c := clock.NewMock(time.Now())
+	bl := blocklist.NewBlocklist(store, blocklist.Options{Clock: c})
+
+	// add forever
+	if err := bl.Add(addr1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Add(addr2, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Add(addr3, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Advance(2 * time.Minute)
+
+	removed, err := bl.Sweep(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Fatalf("got %d removed entries, want %d", removed, 1)
+	}
+	expectStored(t, store, addr1, true)
+	expectStored(t, store, addr2, false)
+	expectStored(t, store, addr3, true)
+
+	// nothing more to remove
+	removed, err = bl.Sweep(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 0 {
+		t.Fatalf("got %d removed entries, want %d", removed, 0)
+	}
+}
+
+func TestSweepPeriodic(t *testing.T) {
+	addr1 := swarm.NewAddress([]byte{0, 1, 2, 3})
+	addr2 := swarm.NewAddress([]byte{4, 5, 6, 7})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := mock.NewStateStore()
+	c := clock.NewMock(time.Now())
+	bl := blocklist.NewBlocklistWithSweep(ctx, store, time.Hour, blocklist.Options{Clock: c})
+
+	// add forever
+	if err := bl.Add(addr1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Add(addr2, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Advance(time.Hour)
+
+	for i := 0; i < 100; i++ {
+		var v interface{}
+		if err := store.Get(blocklist.GenerateKey(addr2), &v); errors.Is(err, storage.ErrNotFound) {
+			expectStored(t, store, addr1, true)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("expired entry was not swept")
+}
+
+func expectStored(t *testing.T, store storage.StateStorer, addr swarm.Address, want bool) {
+	t.Helper()
+
+	var v interface{}
+	err := store.Get(blocklist.GenerateKey(addr), &v)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		if want {
+			t.Errorf("entry of peer %s not stored", addr)
+		}
+	case err != nil:
+		t.Fatal(err)
+	case !want:
+		t.Errorf("entry of peer %s stored", addr)
+	}
+}
+
+func TestNextExpiry(t *testing.T) {
+	now := time.Now()
+	c := clock.NewMock(now)
+	bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{Clock: c})
+
+	expectNextExpiry := func(t *testing.T, want time.Time, wantOK bool) {
+		t.Helper()
+
+		next, ok, err := bl.NextExpiry()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != wantOK || !next.Equal(want) {
+			t.Fatalf("got next expiry %v %v, want %v %v", next, ok, want, wantOK)
+		}
+	}
+
+	// empty blocklist
+	expectNextExpiry(t, time.Time{}, false)
+
+	// only permanent entries
+	if err := bl.Add(test.RandomAddress(), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.AddIP(net.ParseIP("10.0.0.1"), 0); err != nil {
+		t.Fatal(err)
+	}
+	expectNextExpiry(t, time.Time{}, false)
+
+	for _, d := range []time.Duration{2 * time.Hour, 30 * time.Minute, time.Hour} {
+		if err := bl.Add(test.RandomAddress(), d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectNextExpiry(t, now.Add(30*time.Minute), true)
+
+	// the entries of the ip addresses expire too
+	if err := bl.AddIP(net.ParseIP("10.0.0.2"), 10*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	expectNextExpiry(t, now.Add(10*time.Minute), true)
+
+	// the expired entries expire now
+	c.Advance(45 * time.Minute)
+	expectNextExpiry(t, now.Add(45*time.Minute), true)
+
+	if _, err := bl.Sweep(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expectNextExpiry(t, now.Add(time.Hour), true)
+}
+
+func TestPeersCorruptedEntries(t *testing.T) {
+	addr1 := swarm.NewAddress([]byte{0, 1, 2, 3})
+	addr2 := swarm.NewAddress([]byte{4, 5, 6, 7})
+	addr3 := swarm.NewAddress([]byte{8, 9, 10, 11})
+
+	store := mock.NewStateStore()
+	bl := blocklist.NewBlocklist(store, blocklist.Options{})
+
+	// the valid entries follow the corrupted ones in the key order
+	if err := bl.Add(addr2, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Add(addr3, 0); err != nil {
+		t.Fatal(err)
+	}
+	// an entry with a key without an overlay
+	malformedKey := "blocklist-"
+	if err := store.Put(malformedKey, map[string]interface{}{"timestamp": time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	// an entry with an unparsable duration
+	garbageKey := blocklist.GenerateKey(addr1)
+	if err := store.Put(garbageKey, map[string]string{"duration": "garbage"}); err != nil {
+		t.Fatal(err)
+	}
+
+	peers, err := bl.Peers()
+	var corrupted *blocklist.CorruptedEntriesError
+	if !errors.As(err, &corrupted) {
+		t.Fatalf("got error %v, want %T", err, corrupted)
+	}
+	if want := []string{malformedKey, garbageKey}; !reflect.DeepEqual(corrupted.Keys, want) {
+		t.Errorf("got skipped keys %q, want %q", corrupted.Keys, want)
+	}
+	if len(corrupted.Errs) != len(corrupted.Keys) {
+		t.Errorf("got %d errors of %d skipped keys", len(corrupted.Errs), len(corrupted.Keys))
+	}
+	if len(peers) != 2 || !peers[0].Address.Equal(addr2) || !peers[1].Address.Equal(addr3) {
+		t.Fatalf("got blocklisted peers %v, want %s and %s", peers, addr2, addr3)
+	}
+}
+
+func TestPeersStoreError(t *testing.T) {
+	errIterate := errors.New("iterate failed")
+	store := &iterateFailingStore{StateStorer: mock.NewStateStore(), err: errIterate}
+	bl := blocklist.NewBlocklist(store, blocklist.Options{})
+
+	if err := bl.Add(test.RandomAddress(), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	// the errors of the statestore abort the iteration
+	store.fail = true
+	peers, err := bl.Peers()
+	if !errors.Is(err, errIterate) {
+		t.Fatalf("got error %v, want %v", err, errIterate)
+	}
+	var corrupted *blocklist.CorruptedEntriesError
+	if errors.As(err, &corrupted) {
+		t.Fatalf("got error %v, want a statestore error", err)
+	}
+	if len(peers) != 0 {
+		t.Fatalf("got blocklisted peers %v, want none", peers)
+	}
+}
+
+// iterateFailingStore is a statestore of which the iterations fail once
+// fail is set.
+type iterateFailingStore struct {
+	storage.StateStorer
+	err  error
+	fail bool
+}
+
+func (s *iterateFailingStore) Iterate(prefix string, iterFunc storage.StateIterFunc) error {
+	if s.fail {
+		return s.err
+	}
+	return s.StateStorer.Iterate(prefix, iterFunc)
+}
+
+func TestClear(t *testing.T) {
+	addr1 := swarm.NewAddress([]byte{0, 1, 2, 3})
+	addr2 := swarm.NewAddress([]byte{4, 5, 6, 7})
+	addr3 := swarm.NewAddress([]byte{8, 9, 10, 11})
+
+	store := mock.NewStateStore()
+	bl := blocklist.NewBlocklist(store, blocklist.Options{})
+
+	if err := bl.Add(addr1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Add(addr2, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bl.RecordAttempt(addr2); err != nil {
+		t.Fatal(err)
+	}
+	// an entry that can not be unmarshaled
+	if err := store.Put(blocklist.GenerateKey(addr3), "invalid"); err != nil {
+		t.Fatal(err)
+	}
+	// a key of another prefix
+	if err := store.Put("other", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := bl.Clear()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 3 {
+		t.Fatalf("got %d removed entries, want %d", removed, 3)
+	}
+	for _, addr := range []swarm.Address{addr1, addr2, addr3} {
+		expectStored(t, store, addr, false)
+	}
+	var v string
+	if err := store.Get("other", &v); err != nil {
+		t.Fatalf("key of another prefix: %v", err)
+	}
+
+	// the pending attempts are dropped too
+	if err := bl.Add(addr2, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	peers, err := bl.Peers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 1 || peers[0].Attempts != 0 {
+		t.Fatalf("got peers %v, want only %s without attempts", peers, addr2)
+	}
+
+	removed, err = bl.Clear()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Fatalf("got %d removed entries, want %d", removed, 1)
+	}
+}
+
+func TestClearConcurrent(t *testing.T) {
+	bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		addr := swarm.NewAddress([]byte{byte(i)})
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := bl.Add(addr, 0); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := bl.Clear(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// every entry added before the last clear is removed by it
+	if _, err := bl.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	peers, err := bl.Peers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 0 {
+		t.Fatalf("got blocklisted peers %v, want none", peers)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	addr1 := swarm.NewAddress([]byte{0, 1, 2, 3})
+	addr2 := swarm.NewAddress([]byte{4, 5, 6, 7})
+	addr3 := swarm.NewAddress([]byte{8, 9, 10, 11})
+
+	store := mock.NewStateStore()
+	// an entry of a previous run is counted
+	if err := blocklist.NewBlocklist(store, blocklist.Options{}).Add(addr3, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	c := clock.NewMock(time.Now())
+	bl := blocklist.NewBlocklist(store, blocklist.Options{Clock: c})
+	m := bl.MetricsValues()
+
+	expect := func(name string, c prometheus.Collector, want float64) {
+		t.Helper()
+		if got := testutil.ToFloat64(c); got != want {
+			t.Errorf("got %s %v, want %v", name, got, want)
+		}
+	}
+
+	if err := bl.Add(addr1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Add(addr2, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	// a renewal is not a new entry
+	if err := bl.Add(addr1, 0); err != nil {
+		t.Fatal(err)
+	}
+	expect("adds", m.AddCount, 3)
+	expect("entries", m.Entries, 3)
+
+	if _, err := bl.Exists(addr1); err != nil {
+		t.Fatal(err)
+	}
+	expect("exists hits", m.ExistsHitCount, 1)
+
+	if err := bl.Remove(addr1); err != nil {
+		t.Fatal(err)
+	}
+	expect("removals", m.RemoveCount, 1)
+	expect("entries", m.Entries, 2)
+
+	// the expired entry is removed on lookup
+	c.Advance(time.Hour)
+	exists, err := bl.Exists(addr2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Fatal("got exists, expected not exists")
+	}
+	expect("exists hits", m.ExistsHitCount, 1)
+	expect("expiries", m.ExpiredCount, 1)
+	expect("entries", m.Entries, 1)
+
+	if _, err := bl.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	expect("removals", m.RemoveCount, 2)
+	expect("entries", m.Entries, 0)
+}
+
+func TestMaxEntries(t *testing.T) {
+	addr1 := swarm.NewAddress([]byte{0, 1, 2, 3})
+	addr2 := swarm.NewAddress([]byte{4, 5, 6, 7})
+	addr3 := swarm.NewAddress([]byte{8, 9, 10, 11})
+	addr4 := swarm.NewAddress([]byte{12, 13, 14, 15})
+
+	t.Run("nearest expiry", func(t *testing.T) {
+		store := mock.NewStateStore()
+		c := clock.NewMock(time.Now())
+		bl := blocklist.NewBlocklist(store, blocklist.Options{Clock: c, MaxEntries: 3})
+
+		if err := bl.Add(addr1, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		if err := bl.Add(addr2, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := bl.Add(addr3, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		// a renewal of an entry does not evict
+		if err := bl.Add(addr1, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		expectStored(t, store, addr3, true)
+
+		// the entry of addr3 expires first
+		if err := bl.Add(addr4, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		expectStored(t, store, addr1, true)
+		expectStored(t, store, addr2, true)
+		expectStored(t, store, addr3, false)
+		expectStored(t, store, addr4, true)
+
+		// the permanent entry is evicted after the ones that expire
+		if err := bl.Add(addr3, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		expectStored(t, store, addr1, false)
+		expectStored(t, store, addr2, true)
+		expectStored(t, store, addr3, true)
+		expectStored(t, store, addr4, true)
+
+		if got := testutil.ToFloat64(bl.MetricsValues().EvictedCount); got != 2 {
+			t.Fatalf("got %v evictions, want %v", got, 2)
+		}
+	})
+
+	t.Run("all permanent", func(t *testing.T) {
+		store := mock.NewStateStore()
+		c := clock.NewMock(time.Now())
+		bl := blocklist.NewBlocklist(store, blocklist.Options{Clock: c, MaxEntries: 2})
+
+		for _, addr := range []swarm.Address{addr2, addr1} {
+			if err := bl.Add(addr, 0); err != nil {
+				t.Fatal(err)
+			}
+			c.Advance(time.Second)
+		}
+
+		// the entry of addr2 is the oldest one
+		if err := bl.Add(addr3, 0); err != nil {
+			t.Fatal(err)
+		}
+		expectStored(t, store, addr1, true)
+		expectStored(t, store, addr2, false)
+		expectStored(t, store, addr3, true)
+
+		// entries with the same timestamp are evicted in the order of their keys
+		if err := bl.Add(addr4, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := bl.Add(addr2, 0); err != nil {
+			t.Fatal(err)
+		}
+		expectStored(t, store, addr1, false)
+		expectStored(t, store, addr2, true)
+		expectStored(t, store, addr3, false)
+		expectStored(t, store, addr4, true)
+	})
+
+	t.Run("no cap", func(t *testing.T) {
+		store := mock.NewStateStore()
+		bl := blocklist.NewBlocklist(store, blocklist.Options{})
+
+		for _, addr := range []swarm.Address{addr1, addr2, addr3, addr4} {
+			if err := bl.Add(addr, 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, addr := range []swarm.Address{addr1, addr2, addr3, addr4} {
+			expectStored(t, store, addr, true)
+		}
+	})
+}
+
+func TestRemove(t *testing.T) {
+	addr1 := swarm.NewAddress([]byte{0, 1, 2, 3})
+	addr2 := swarm.NewAddress([]byte{4, 5, 6, 7})
+
+	c := clock.NewMock(time.Now())
+	bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{Clock: c})
+
+	if err := bl.Add(addr1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Add(addr2, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bl.Remove(addr1); err != nil {
+		t.Fatal(err)
+	}
+	exists, err := bl.Exists(addr1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Fatal("got exists after removal, expected not exists")
+	}
+
+	// removing a peer that is not blocklisted is reported
+	if err := bl.Remove(addr1); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, storage.ErrNotFound)
+	}
+
+	// and so is removing a peer whose block duration elapsed
+	c.Advance(2 * time.Hour)
+	if err := bl.Remove(addr2); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, storage.ErrNotFound)
+	}
+
+	peers, err := bl.Peers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 0 {
+		t.Fatalf("got blocklisted peers %v, want none", peers)
+	}
+}
+
+func TestRemoveConcurrent(t *testing.T) {
+	addrs := make([]swarm.Address, 10)
+	for i := range addrs {
+		addrs[i] = swarm.NewAddress([]byte{byte(i)})
+	}
+
+	bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{})
+	for _, addr := range addrs {
+		if err := bl.Add(addr, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, addr := range addrs {
+		addr := addr
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			if err := bl.Remove(addr); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := bl.Exists(addr); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := bl.Peers(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	peers, err := bl.Peers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 0 {
+		t.Fatalf("got blocklisted peers %v, want none", peers)
+	}
+}
+
+func TestAddConcurrent(t *testing.T) {
+	addrs := make([]swarm.Address, 10)
+	for i := range addrs {
+		addrs[i] = swarm.NewAddress([]byte{byte(i)})
+	}
+	const adds = 20
+
+	// the time does not pass, so the longest block expires last
+	c := clock.NewMock(time.Now())
+	bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{Clock: c})
+
+	var wg sync.WaitGroup
+	for _, addr := range addrs {
+		for i := 1; i <= adds; i++ {
+			addr, d := addr, time.Duration(i)*time.Minute
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := bl.Add(addr, d); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	peers, err := bl.Peers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != len(addrs) {
+		t.Fatalf("got %d blocklisted peers, want %d", len(peers), len(addrs))
+	}
+	for _, p := range peers {
+		if p.Duration != adds*time.Minute {
+			t.Errorf("got duration %v of peer %s, want %v", p.Duration, p.Address, adds*time.Minute)
+		}
+	}
+}
+
+func isIn(p swarm.Address, peers []p2p.BlockedPeer) bool {
+	for _, v := range peers {
+		if v.Address.Equal(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSweepExpired(t *testing.T) {
+	addr1 := swarm.NewAddress([]byte{0, 1, 2, 3})
+	addr2 := swarm.NewAddress([]byte{4, 5, 6, 7})
+
+	store := mock.NewStateStore()
+	c := clock.NewMock(time.Now())
+	bl := blocklist.NewBlocklist(store, blocklist.Options{Clock: c})
+
+	if err := bl.Add(addr1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Add(addr2, time.Millisecond*50); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Advance(100 * time.Millisecond)
+
+	removed, err := blocklist.SweepExpired(c)(context.Background(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Fatalf("got %d removed, want %d", removed, 1)
+	}
+
+	peers, err := bl.Peers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 1 || !peers[0].Address.Equal(addr1) {
+		t.Fatalf("got peers %v, want only %s", peers, addr1)
+	}
+}
+
+func TestRecordAttempt(t *testing.T) {
+	addr1 := swarm.NewAddress([]byte{0, 1, 2, 3})
+	addr2 := swarm.NewAddress([]byte{4, 5, 6, 7})
+
+	store := mock.NewStateStore()
+	c := clock.NewMock(time.Unix(1000, 0))
+	bl := blocklist.NewBlocklist(store, blocklist.Options{
+		Clock:                 c,
+		AttemptsFlushEvery:    5,
+		AttemptsFlushInterval: time.Hour,
+	})
+
+	if err := bl.Add(addr1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// the attempts are aggregated in memory, only the first one is reported
+	for i := 0; i < 4; i++ {
+		c.Advance(time.Second)
+		first, err := bl.RecordAttempt(addr1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := i == 0; first != want {
+			t.Fatalf("attempt %d: got first %v, want %v", i, first, want)
+		}
+	}
+	expectPersistedAttempts(t, store, addr1, 0, time.Time{})
+	// the listing includes the attempts that are not persisted
+	expectAttempts(t, bl, addr1, 4, c.Now())
+
+	// the attempts are flushed in a batch once there are enough of them
+	c.Advance(time.Second)
+	if _, err := bl.RecordAttempt(addr1); err != nil {
+		t.Fatal(err)
+	}
+	expectPersistedAttempts(t, store, addr1, 5, c.Now())
+	expectAttempts(t, bl, addr1, 5, c.Now())
+
+	first, err := bl.RecordAttempt(addr1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first {
+		t.Fatal("got not first attempt after a flush")
+	}
+	expectPersistedAttempts(t, store, addr1, 5, c.Now())
+
+	// or once the flush interval passes
+	c.Advance(time.Hour)
+	if _, err := bl.RecordAttempt(addr1); err != nil {
+		t.Fatal(err)
+	}
+	expectPersistedAttempts(t, store, addr1, 7, c.Now())
+
+	// the attempts are kept when the peer is blocklisted again
+	if err := bl.Add(addr1, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	expectPersistedAttempts(t, store, addr1, 7, c.Now())
+
+	// the attempts of peers which are not blocklisted are dropped
+	if _, err := bl.RecordAttempt(addr2); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	var e struct{}
+	if err := store.Get(blocklist.GenerateKey(addr2), &e); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, storage.ErrNotFound)
+	}
+}
+
+func TestRecordAttemptRestart(t *testing.T) {
+	addr1 := swarm.NewAddress([]byte{0, 1, 2, 3})
+	addr2 := swarm.NewAddress([]byte{4, 5, 6, 7})
+
+	store := mock.NewStateStore()
+	start := time.Unix(1000, 0)
+	c := clock.NewMock(start)
+	bl := blocklist.NewBlocklist(store, blocklist.Options{Clock: c})
+
+	if err := bl.Add(addr1, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Add(addr2, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		c.Advance(time.Second)
+		if _, err := bl.RecordAttempt(addr1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	last := c.Now()
+
+	// the entry of addr2 expires after the attempt is recorded
+	if _, err := bl.RecordAttempt(addr2); err != nil {
+		t.Fatal(err)
+	}
+	c.Advance(time.Minute)
+
+	// the attempts are flushed on shutdown
+	if err := bl.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// and are listed after a restart
+	bl = blocklist.NewBlocklist(store, blocklist.Options{Clock: c})
+	expectAttempts(t, bl, addr1, 3, last)
+
+	// the attempts do not extend the block duration
+	peers, err := bl.Peers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 1 {
+		t.Fatalf("got %d blocklisted peers, want %d", len(peers), 1)
+	}
+	if p := peers[0]; !p.Timestamp.Equal(start) || p.Duration != time.Hour {
+		t.Fatalf("got timestamp %v and duration %v, want %v and %v", p.Timestamp, p.Duration, start, time.Hour)
+	}
+
+	// the attempts of the expired entry are dropped
+	expectPersistedAttempts(t, store, addr2, 0, time.Time{})
+}
+
+func expectPersistedAttempts(t *testing.T, store storage.StateStorer, addr swarm.Address, wantAttempts uint64, wantLast time.Time) {
+	t.Helper()
+
+	var e struct {
+		Attempts    uint64 `json:"attempts"`
+		LastAttempt int64  `json:"lastAttempt"`
+	}
+	if err := store.Get(blocklist.GenerateKey(addr), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Attempts != wantAttempts {
+		t.Fatalf("got %d persisted attempts, want %d", e.Attempts, wantAttempts)
+	}
+	var last time.Time
+	if e.LastAttempt != 0 {
+		last = time.Unix(0, e.LastAttempt)
+	}
+	if !last.Equal(wantLast) {
+		t.Fatalf("got persisted last attempt %v, want %v", last, wantLast)
+	}
+}
+
+func expectAttempts(t *testing.T, bl *blocklist.Blocklist, addr swarm.Address, wantAttempts uint64, wantLast time.Time) {
+	t.Helper()
+
+	peers, err := bl.Peers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range peers {
+		if !p.Address.Equal(addr) {
+			continue
+		}
+		if p.Attempts != wantAttempts {
+			t.Fatalf("got %d attempts, want %d", p.Attempts, wantAttempts)
+		}
+		if !p.LastAttempt.Equal(wantLast) {
+			t.Fatalf("got last attempt %v, want %v", p.LastAttempt, wantLast)
+		}
+		return
+	}
+	t.Fatalf("peer %s not found", addr)
+}
+
+func BenchmarkExists(b *testing.B) {
+	bl, overlays := newBenchmarkBlocklist(b)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, overlay := range overlays {
+			if _, err := bl.Exists(overlay); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkExistsBatch(b *testing.B) {
+	bl, overlays := newBenchmarkBlocklist(b)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := bl.ExistsBatch(overlays); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// newBenchmarkBlocklist returns a blocklist with 1000 peers in a leveldb
+// statestore and twice as many overlays to check, half of them blocklisted.
+func newBenchmarkBlocklist(b *testing.B) (*blocklist.Blocklist, []swarm.Address) {
+	b.Helper()
+
+	store, err := leveldb.NewStateStore(b.TempDir(), logging.New(ioutil.Discard, 0))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { _ = store.Close() })
+
+	bl := blocklist.NewBlocklist(store, blocklist.Options{})
+	overlays := make([]swarm.Address, 2000)
+	for i := range overlays {
+		overlays[i] = test.RandomAddress()
+		if i%2 == 0 {
+			if err := bl.Add(overlays[i], 0); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	return bl, overlays
 }
@@ -0,0 +1,233 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blocklist
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/internal/clock"
+	"github.com/ethersphere/bee/pkg/storage"
+)
+
+// cidrKeyPrefix is the prefix of the keys of the blocklisted IP address
+// ranges. Like ipKeyPrefix, it must not start with keyPrefix.
+var cidrKeyPrefix = "cidrblocklist-"
+
+var errInvalidCIDR = errors.New("invalid ip address range")
+
+// cidrLength is the length of the network prefix of a range, in the bits
+// of the IP addresses of its family.
+type cidrLength struct {
+	ones, bits int
+}
+
+// AddCIDR blocklists all IP addresses in the range for the duration, forever
+// if it is zero, with the same policy as Add. The ranges may overlap, and an
+// IP address is blocklisted until the last of the blocks of the ranges that
+// contain it expires.
+func (b *Blocklist) AddCIDR(network *net.IPNet, duration time.Duration) error {
+	key, length, err := generateCIDRKey(network)
+	if err != nil {
+		return err
+	}
+	if err := b.loadCIDRs(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := entry{
+		Timestamp: b.clock.Now(),
+		Duration:  entryDuration(duration),
+		Permanent: duration == 0,
+	}
+	e, err := b.get(key)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+	} else {
+		n = e.merge(n, b.renewal, b.clock)
+	}
+	if err := b.store.Put(key, &n); err != nil {
+		return err
+	}
+
+	b.cidrMu.Lock()
+	defer b.cidrMu.Unlock()
+
+	b.cidrs[key] = n
+	b.addCIDRLength(length)
+	return nil
+}
+
+// ExistsIPInRange reports whether the IP address is in a blocklisted range.
+// The ranges are looked up in an in-memory index, with one lookup for every
+// length of the blocklisted network prefixes. The expired entries of the
+// ranges that contain the IP address are removed.
+func (b *Blocklist) ExistsIPInRange(ip net.IP) (bool, error) {
+	if ip.To16() == nil {
+		return false, errInvalidIP
+	}
+	if err := b.loadCIDRs(); err != nil {
+		return false, err
+	}
+
+	var (
+		exists  bool
+		expired []string
+	)
+	b.cidrMu.RLock()
+	for _, l := range b.cidrLengths {
+		addr := ip.To16()
+		if l.bits == 8*net.IPv4len {
+			if addr = ip.To4(); addr == nil {
+				continue
+			}
+		}
+		key := cidrKey(addr.Mask(net.CIDRMask(l.ones, l.bits)), l.ones)
+		e, ok := b.cidrs[key]
+		if !ok {
+			continue
+		}
+		if e.expired(b.clock) {
+			expired = append(expired, key)
+			continue
+		}
+		exists = true
+	}
+	b.cidrMu.RUnlock()
+
+	if len(expired) > 0 {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		for _, key := range expired {
+			// the entry could have been renewed since it was found expired
+			if e, err := b.get(key); err == nil && !e.expired(b.clock) {
+				continue
+			}
+			if err := b.store.Delete(key); err != nil {
+				return exists, err
+			}
+			b.removeCIDR(key)
+		}
+	}
+	return exists, nil
+}
+
+// loadCIDRs builds the index of the blocklisted ranges from the store, once.
+func (b *Blocklist) loadCIDRs() error {
+	b.cidrMu.RLock()
+	loaded := b.cidrs != nil
+	b.cidrMu.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	b.cidrMu.Lock()
+	defer b.cidrMu.Unlock()
+
+	if b.cidrs != nil {
+		return nil
+	}
+
+	cidrs := make(map[string]entry)
+	if err := b.store.Iterate(cidrKeyPrefix, func(k, v []byte) (bool, error) {
+		if !strings.HasPrefix(string(k), cidrKeyPrefix) {
+			return true, nil
+		}
+		length, ok := parseCIDRKey(string(k))
+		if !ok {
+			b.logger.Debugf("blocklist: invalid ip address range key %q", k)
+			return false, nil
+		}
+		var e entry
+		if err := json.Unmarshal(v, &e); err != nil {
+			// leave invalid entries to the integrity check
+			b.logger.Debugf("blocklist: decode entry of ip address range %q: %v", k, err)
+			return false, nil
+		}
+		cidrs[string(k)] = e
+		b.addCIDRLength(length)
+		return false, nil
+	}); err != nil {
+		return err
+	}
+	b.cidrs = cidrs
+	return nil
+}
+
+// addCIDRLength adds the length of the network prefix of a range to the
+// lengths looked up by ExistsIPInRange. Must be called with cidrMu locked.
+func (b *Blocklist) addCIDRLength(length cidrLength) {
+	for _, l := range b.cidrLengths {
+		if l == length {
+			return
+		}
+	}
+	b.cidrLengths = append(b.cidrLengths, length)
+}
+
+// removeCIDR removes the range from the index, if it is loaded.
+func (b *Blocklist) removeCIDR(key string) {
+	b.cidrMu.Lock()
+	defer b.cidrMu.Unlock()
+
+	delete(b.cidrs, key)
+}
+
+// sweepCIDRs returns the sweep which removes the entries of the ranges with
+// an elapsed block duration from the store, as observed by the clock.
+func sweepCIDRs(c clock.Clock) storage.SweepFunc {
+	return func(ctx context.Context, store storage.StateStorer) (removed int, err error) {
+		return sweep(ctx, store, cidrKeyPrefix, c, nil)
+	}
+}
+
+// generateCIDRKey returns the key of the entry of the range, with the IPv4
+// ranges in the 4 bytes form of their network addresses and the IPv6 ranges
+// in the 16 bytes one, followed by the length of the network prefix.
+func generateCIDRKey(network *net.IPNet) (string, cidrLength, error) {
+	if network == nil {
+		return "", cidrLength{}, errInvalidCIDR
+	}
+	ones, bits := network.Mask.Size()
+	var addr net.IP
+	switch bits {
+	case 8 * net.IPv4len:
+		addr = network.IP.To4()
+	case 8 * net.IPv6len:
+		addr = network.IP.To16()
+	}
+	if addr == nil {
+		return "", cidrLength{}, errInvalidCIDR
+	}
+	return cidrKey(addr.Mask(network.Mask), ones), cidrLength{ones: ones, bits: bits}, nil
+}
+
+func cidrKey(addr net.IP, ones int) string {
+	return cidrKeyPrefix + string(addr) + string([]byte{byte(ones)})
+}
+
+// parseCIDRKey returns the length of the network prefix of the range with
+// the key and reports whether the key is valid.
+func parseCIDRKey(key string) (cidrLength, bool) {
+	k := strings.TrimPrefix(key, cidrKeyPrefix)
+	if len(k) != net.IPv4len+1 && len(k) != net.IPv6len+1 {
+		return cidrLength{}, false
+	}
+	l := cidrLength{ones: int(k[len(k)-1]), bits: 8 * (len(k) - 1)}
+	if l.ones > l.bits {
+		return cidrLength{}, false
+	}
+	return l, true
+}
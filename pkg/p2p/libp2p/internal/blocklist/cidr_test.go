@@ -0,0 +1,131 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blocklist_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/internal/clock"
+	"github.com/ethersphere/bee/pkg/p2p/libp2p/internal/blocklist"
+	"github.com/ethersphere/bee/pkg/statestore/mock"
+)
+
+func TestCIDR(t *testing.T) {
+	start := time.Now()
+	c := clock.NewMock(start)
+	bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{Clock: c})
+
+	for _, r := range []struct {
+		cidr     string
+		duration time.Duration
+	}{
+		{cidr: "192.0.2.0/24", duration: time.Hour},
+		{cidr: "198.51.100.128/25", duration: 0},
+		{cidr: "2001:db8::/32", duration: 2 * time.Hour},
+		// overlapping ranges
+		{cidr: "203.0.113.0/24", duration: time.Hour},
+		{cidr: "203.0.113.0/28", duration: 3 * time.Hour},
+	} {
+		if err := bl.AddCIDR(mustParseCIDR(t, r.cidr), r.duration); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		ip      string
+		elapsed time.Duration
+		want    bool
+	}{
+		{ip: "192.0.2.1", want: true},
+		{ip: "192.0.2.255", want: true},
+		{ip: "::ffff:192.0.2.1", want: true},
+		{ip: "192.0.3.1", want: false},
+		{ip: "198.51.100.200", want: true},
+		{ip: "198.51.100.1", want: false},
+		{ip: "2001:db8:1::1", want: true},
+		{ip: "2001:db9::1", want: false},
+		{ip: "203.0.113.200", want: true},
+		{ip: "203.0.113.1", want: true},
+
+		{ip: "192.0.2.1", elapsed: 90 * time.Minute, want: false},
+		{ip: "2001:db8:1::1", elapsed: 90 * time.Minute, want: true},
+		{ip: "198.51.100.200", elapsed: 90 * time.Minute, want: true},
+		// the longest of the overlapping blocks wins
+		{ip: "203.0.113.200", elapsed: 90 * time.Minute, want: false},
+		{ip: "203.0.113.1", elapsed: 90 * time.Minute, want: true},
+
+		{ip: "2001:db8:1::1", elapsed: 150 * time.Minute, want: false},
+		{ip: "203.0.113.1", elapsed: 4 * time.Hour, want: false},
+		{ip: "198.51.100.200", elapsed: 4 * time.Hour, want: true},
+	} {
+		c.Advance(start.Add(tc.elapsed).Sub(c.Now()))
+		exists, err := bl.ExistsIPInRange(net.ParseIP(tc.ip))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists != tc.want {
+			t.Errorf("got exists %v for ip %s after %v, want %v", exists, tc.ip, tc.elapsed, tc.want)
+		}
+	}
+
+	if err := bl.AddCIDR(nil, 0); err == nil {
+		t.Fatal("got no error for an invalid ip address range")
+	}
+}
+
+func TestCIDRRestart(t *testing.T) {
+	store := mock.NewStateStore()
+	c := clock.NewMock(time.Now())
+
+	bl := blocklist.NewBlocklist(store, blocklist.Options{Clock: c})
+	if err := bl.AddCIDR(mustParseCIDR(t, "192.0.2.0/24"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.AddCIDR(mustParseCIDR(t, "2001:db8::/48"), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// the index is rebuilt from the store
+	bl = blocklist.NewBlocklist(store, blocklist.Options{Clock: c})
+	for _, ip := range []string{"192.0.2.1", "2001:db8::1"} {
+		exists, err := bl.ExistsIPInRange(net.ParseIP(ip))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !exists {
+			t.Errorf("got ip %s not blocklisted, want blocklisted", ip)
+		}
+	}
+
+	// the expired ranges are swept
+	c.Advance(2 * time.Hour)
+	removed, err := bl.Sweep(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Fatalf("got %d swept entries, want %d", removed, 1)
+	}
+	exists, err := bl.ExistsIPInRange(net.ParseIP("192.0.2.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Fatal("got the ip address blocklisted, want expired")
+	}
+}
+
+func mustParseCIDR(t *testing.T, s string) *net.IPNet {
+	t.Helper()
+
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return network
+}
@@ -0,0 +1,114 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blocklist
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+// eventsBufferSize is the number of the events buffered for a subscriber.
+const eventsBufferSize = 64
+
+// Action is the change of the block of a peer.
+type Action int
+
+const (
+	// ActionAdded is a peer that is blocklisted, including the renewals.
+	ActionAdded Action = iota + 1
+	// ActionRemoved is a peer that is removed from the blocklist
+	// before its block expired, including the evictions.
+	ActionRemoved
+	// ActionExpired is a peer that is removed from the
+	// blocklist as the block duration has elapsed.
+	ActionExpired
+)
+
+func (a Action) String() string {
+	switch a {
+	case ActionAdded:
+		return "added"
+	case ActionRemoved:
+		return "removed"
+	case ActionExpired:
+		return "expired"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a change of the block of a peer.
+type Event struct {
+	Overlay  swarm.Address
+	Action   Action
+	Duration time.Duration // the block duration, zero if the block is permanent
+	Reason   string        // why the peer was blocklisted, empty if not known
+}
+
+// subscriptions are the channels of the event subscribers.
+type subscriptions struct {
+	mu       sync.Mutex
+	channels []chan Event
+}
+
+// Subscribe returns the channel on which the changes of the blocklisted
+// peers are delivered. The events are never waited for to be received.
+// Up to 64 events are buffered for a subscriber and the events that do
+// not fit in the buffer are dropped. The returned function closes the
+// channel and is safe to be called multiple times.
+func (b *Blocklist) Subscribe() (c <-chan Event, unsubscribe func()) {
+	channel := make(chan Event, eventsBufferSize)
+	var closeOnce sync.Once
+
+	b.subscriptions.mu.Lock()
+	defer b.subscriptions.mu.Unlock()
+
+	b.subscriptions.channels = append(b.subscriptions.channels, channel)
+
+	unsubscribe = func() {
+		b.subscriptions.mu.Lock()
+		defer b.subscriptions.mu.Unlock()
+
+		for i, c := range b.subscriptions.channels {
+			if c == channel {
+				b.subscriptions.channels = append(b.subscriptions.channels[:i], b.subscriptions.channels[i+1:]...)
+				break
+			}
+		}
+
+		closeOnce.Do(func() { close(channel) })
+	}
+
+	return channel, unsubscribe
+}
+
+// publish delivers the event of the entry with the key to the subscribers.
+func (b *Blocklist) publish(key string, action Action, e entry) {
+	overlay, err := unmarshalKey(key)
+	if err != nil {
+		return
+	}
+	ev := Event{
+		Overlay: overlay,
+		Action:  action,
+		Reason:  e.Reason,
+	}
+	if !e.permanent() {
+		ev.Duration = time.Duration(e.Duration)
+	}
+
+	b.subscriptions.mu.Lock()
+	defer b.subscriptions.mu.Unlock()
+
+	for _, c := range b.subscriptions.channels {
+		select {
+		case c <- ev:
+		default:
+			b.metrics.DroppedEventsCount.Inc()
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blocklist_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/internal/clock"
+	"github.com/ethersphere/bee/pkg/p2p/libp2p/internal/blocklist"
+	"github.com/ethersphere/bee/pkg/statestore/mock"
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSubscribe(t *testing.T) {
+	addr1 := swarm.NewAddress([]byte{0, 1, 2, 3})
+	addr2 := swarm.NewAddress([]byte{4, 5, 6, 7})
+
+	c := clock.NewMock(time.Now())
+	bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{Clock: c})
+
+	events, unsubscribe := bl.Subscribe()
+	defer unsubscribe()
+
+	expect := func(want blocklist.Event) {
+		t.Helper()
+		select {
+		case got := <-events:
+			if !got.Overlay.Equal(want.Overlay) || got.Action != want.Action || got.Duration != want.Duration || got.Reason != want.Reason {
+				t.Fatalf("got event %+v, want %+v", got, want)
+			}
+		default:
+			t.Fatalf("got no event, want %+v", want)
+		}
+	}
+
+	if err := bl.AddWithReason(addr1, time.Hour, "misbehaved"); err != nil {
+		t.Fatal(err)
+	}
+	expect(blocklist.Event{Overlay: addr1, Action: blocklist.ActionAdded, Duration: time.Hour, Reason: "misbehaved"})
+
+	if err := bl.AddPermanent(addr2); err != nil {
+		t.Fatal(err)
+	}
+	expect(blocklist.Event{Overlay: addr2, Action: blocklist.ActionAdded})
+
+	if err := bl.Remove(addr2); err != nil {
+		t.Fatal(err)
+	}
+	expect(blocklist.Event{Overlay: addr2, Action: blocklist.ActionRemoved})
+
+	// the expiry is published on lookup
+	c.Advance(2 * time.Hour)
+	if _, err := bl.Exists(addr1); err != nil {
+		t.Fatal(err)
+	}
+	expect(blocklist.Event{Overlay: addr1, Action: blocklist.ActionExpired, Duration: time.Hour, Reason: "misbehaved"})
+
+	// and on sweep
+	if err := bl.Add(addr1, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	expect(blocklist.Event{Overlay: addr1, Action: blocklist.ActionAdded, Duration: time.Minute})
+	c.Advance(time.Hour)
+	if _, err := bl.Sweep(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expect(blocklist.Event{Overlay: addr1, Action: blocklist.ActionExpired, Duration: time.Minute})
+
+	if err := bl.Add(addr2, 0); err != nil {
+		t.Fatal(err)
+	}
+	expect(blocklist.Event{Overlay: addr2, Action: blocklist.ActionAdded})
+	if _, err := bl.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	expect(blocklist.Event{Overlay: addr2, Action: blocklist.ActionRemoved})
+
+	select {
+	case ev := <-events:
+		t.Fatalf("got unexpected event %+v", ev)
+	default:
+	}
+}
+
+func TestSubscribeSlowConsumer(t *testing.T) {
+	bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{})
+
+	events, unsubscribe := bl.Subscribe()
+	defer unsubscribe()
+
+	// adding does not block on the subscriber that does not receive
+	const added = blocklist.EventsBufferSize + 10
+	for i := 0; i < added; i++ {
+		if err := bl.Add(swarm.NewAddress([]byte{0, 1, 2, byte(i)}), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the events that did not fit in the buffer are dropped
+	for i := 0; i < blocklist.EventsBufferSize; i++ {
+		ev := <-events
+		if want := swarm.NewAddress([]byte{0, 1, 2, byte(i)}); !ev.Overlay.Equal(want) {
+			t.Fatalf("got event of peer %s, want %s", ev.Overlay, want)
+		}
+	}
+	select {
+	case ev := <-events:
+		t.Fatalf("got unexpected event %+v", ev)
+	default:
+	}
+	if got := testutil.ToFloat64(bl.MetricsValues().DroppedEventsCount); got != added-blocklist.EventsBufferSize {
+		t.Fatalf("got %v dropped events, want %v", got, added-blocklist.EventsBufferSize)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{})
+
+	events, unsubscribe := bl.Subscribe()
+	other, unsubscribeOther := bl.Subscribe()
+	defer unsubscribeOther()
+
+	unsubscribe()
+	unsubscribe() // safe to be called twice
+
+	if _, ok := <-events; ok {
+		t.Fatal("got an event, want the channel closed")
+	}
+
+	// the events are still delivered to the other subscribers
+	addr := swarm.NewAddress([]byte{0, 1, 2, 3})
+	if err := bl.Add(addr, 0); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ev := <-other:
+		if !ev.Overlay.Equal(addr) || ev.Action != blocklist.ActionAdded {
+			t.Fatalf("got event %+v, want peer %s added", ev, addr)
+		}
+	default:
+		t.Fatal("got no event")
+	}
+}
@@ -4,8 +4,12 @@
 
 package blocklist
 
-import "time"
+var SweepExpired = sweepExpired
 
-func SetTimeNow(f func() time.Time) {
-	timeNow = f
+func (b *Blocklist) MetricsValues() metrics {
+	return b.metrics
 }
+
+const EventsBufferSize = eventsBufferSize
+
+var GenerateKey = generateKey
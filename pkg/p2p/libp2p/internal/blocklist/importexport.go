@@ -0,0 +1,154 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blocklist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+// ErrMalformedRecord is returned by a strict Import of a malformed record.
+var ErrMalformedRecord = errors.New("malformed blocklist record")
+
+// record is a blocklisted peer in the exported blocklist, which is a JSON
+// array of the records.
+type record struct {
+	Overlay   string    `json:"overlay"`   // hex encoded
+	Timestamp time.Time `json:"timestamp"` // when the peer was blocklisted
+	Duration  int64     `json:"duration"`  // in nanoseconds, zero if the block is permanent
+	Permanent bool      `json:"permanent,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	Underlays []string  `json:"underlays,omitempty"`
+}
+
+// Export writes the currently blocklisted peers to w as a JSON array of
+// records with the overlay, timestamp, duration, reason and underlays of
+// each, in the order of their overlays. The expired and the invalid entries
+// are skipped.
+func (b *Blocklist) Export(w io.Writer) error {
+	b.migrateKeys()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	records := make([]record, 0)
+	if err := b.store.Iterate(keyPrefix, func(k, v []byte) (bool, error) {
+		if !strings.HasPrefix(string(k), keyPrefix) {
+			return true, nil
+		}
+		addr, err := unmarshalKey(string(k))
+		if err != nil {
+			return true, err
+		}
+		var e entry
+		if err := json.Unmarshal(v, &e); err != nil {
+			// leave invalid entries to the integrity check
+			b.logger.Debugf("blocklist: decode entry of peer %s: %v", addr, err)
+			return false, nil
+		}
+		if e.expired(b.clock) {
+			return false, nil
+		}
+		r := record{
+			Overlay:   addr.String(),
+			Timestamp: e.Timestamp,
+			Permanent: e.permanent(),
+			Reason:    e.Reason,
+			Underlays: e.Underlays,
+		}
+		if !r.Permanent {
+			r.Duration = int64(e.Duration)
+		}
+		records = append(records, r)
+		return false, nil
+	}); err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(records)
+}
+
+// Import blocklists the peers read from r in the format written by Export
+// and returns the number of the imported peers. If overwrite is true, the
+// entries of the peers that are already blocklisted are replaced, otherwise
+// they are merged with the same policy as in Add. A strict import fails with
+// ErrMalformedRecord on the first malformed record, before any peer is
+// imported, while otherwise the malformed records are skipped. The records
+// that have already expired are skipped.
+func (b *Blocklist) Import(r io.Reader, overwrite, strict bool) (imported int, err error) {
+	var raw []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return 0, fmt.Errorf("decode records: %w", err)
+	}
+
+	overlays := make([]swarm.Address, 0, len(raw))
+	entries := make([]entry, 0, len(raw))
+	for i, m := range raw {
+		addr, e, err := parseRecord(m)
+		if err != nil {
+			if strict {
+				return 0, fmt.Errorf("record %d: %v: %w", i, err, ErrMalformedRecord)
+			}
+			b.logger.Debugf("blocklist: import: skip record %d: %v", i, err)
+			continue
+		}
+		overlays = append(overlays, addr)
+		entries = append(entries, e)
+	}
+
+	b.migrate()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, overlay := range overlays {
+		if entries[i].expired(b.clock) {
+			continue
+		}
+		if err := b.put(overlay, entries[i], !overwrite); err != nil {
+			return imported, err
+		}
+		imported++
+	}
+	return imported, nil
+}
+
+// parseRecord validates the record and returns its overlay and entry.
+func parseRecord(m json.RawMessage) (swarm.Address, entry, error) {
+	var r record
+	if err := json.Unmarshal(m, &r); err != nil {
+		return swarm.ZeroAddress, entry{}, err
+	}
+	addr, err := swarm.ParseHexAddress(r.Overlay)
+	if err != nil {
+		return swarm.ZeroAddress, entry{}, fmt.Errorf("overlay: %w", err)
+	}
+	if addr.IsZero() {
+		return swarm.ZeroAddress, entry{}, errors.New("empty overlay")
+	}
+	if r.Timestamp.IsZero() {
+		return swarm.ZeroAddress, entry{}, errors.New("missing timestamp")
+	}
+	if r.Duration < 0 {
+		return swarm.ZeroAddress, entry{}, fmt.Errorf("negative duration %d", r.Duration)
+	}
+	e := entry{
+		Timestamp: r.Timestamp,
+		Duration:  entryDuration(r.Duration),
+		Permanent: r.Permanent || r.Duration == 0,
+		Reason:    r.Reason,
+		Underlays: r.Underlays,
+	}
+	if e.Permanent {
+		e.Duration = 0
+	}
+	return addr, e, nil
+}
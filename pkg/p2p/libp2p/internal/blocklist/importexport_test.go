@@ -0,0 +1,200 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blocklist_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/internal/clock"
+	"github.com/ethersphere/bee/pkg/p2p"
+	"github.com/ethersphere/bee/pkg/p2p/libp2p/internal/blocklist"
+	"github.com/ethersphere/bee/pkg/statestore/mock"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+func TestExportImport(t *testing.T) {
+	addr1 := swarm.NewAddress([]byte{0, 1, 2, 3})
+	addr2 := swarm.NewAddress([]byte{4, 5, 6, 7})
+	addr3 := swarm.NewAddress([]byte{8, 9, 10, 11})
+
+	c := clock.NewMock(time.Now())
+	bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{Clock: c})
+
+	if err := bl.AddPermanent(addr1); err != nil {
+		t.Fatal(err)
+	}
+	c.Advance(time.Minute)
+	if err := bl.AddWithReason(addr2, time.Hour, "misbehaved"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Add(addr3, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	c.Advance(time.Minute)
+
+	want, err := bl.Peers()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := bl.Export(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bl.Clear(); err != nil {
+		t.Fatal(err)
+	}
+
+	imported, err := bl.Import(&buf, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the expired entry of addr3 is not exported
+	if imported != 2 {
+		t.Fatalf("got %d imported peers, want %d", imported, 2)
+	}
+
+	got, err := bl.Peers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectPeers(t, got, want)
+}
+
+func TestImportMalformed(t *testing.T) {
+	addr := swarm.NewAddress([]byte{0, 1, 2, 3})
+	ts := time.Now().Format(time.RFC3339Nano)
+
+	records := `[
+		{"overlay": "00010203", "timestamp": "` + ts + `", "duration": 3600000000000},
+		{"overlay": "not hex", "timestamp": "` + ts + `", "duration": 0},
+		{"overlay": "", "timestamp": "` + ts + `", "duration": 0},
+		{"overlay": "04050607", "duration": 0},
+		{"overlay": "08090a0b", "timestamp": "` + ts + `", "duration": -1},
+		{"overlay": 1}
+	]`
+
+	t.Run("strict", func(t *testing.T) {
+		bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{})
+
+		imported, err := bl.Import(strings.NewReader(records), false, true)
+		if !errors.Is(err, blocklist.ErrMalformedRecord) {
+			t.Fatalf("got error %v, want %v", err, blocklist.ErrMalformedRecord)
+		}
+		if imported != 0 {
+			t.Fatalf("got %d imported peers, want %d", imported, 0)
+		}
+		peers, err := bl.Peers()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(peers) != 0 {
+			t.Fatalf("got %d blocklisted peers, want none", len(peers))
+		}
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{})
+
+		imported, err := bl.Import(strings.NewReader(records), false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if imported != 1 {
+			t.Fatalf("got %d imported peers, want %d", imported, 1)
+		}
+		peers, err := bl.Peers()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(peers) != 1 || !peers[0].Address.Equal(addr) {
+			t.Fatalf("got blocklisted peers %v, want only %s", peers, addr)
+		}
+	})
+
+	t.Run("not an array", func(t *testing.T) {
+		bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{})
+
+		if _, err := bl.Import(strings.NewReader(`{}`), false, false); err == nil {
+			t.Fatal("got no error, want an error")
+		}
+	})
+}
+
+func TestImportOverwrite(t *testing.T) {
+	addr := swarm.NewAddress([]byte{0, 1, 2, 3})
+
+	now := time.Now()
+	// blocklisted before for a shorter time than the existing entry
+	records := `[{"overlay": "00010203", "timestamp": "` + now.Add(-time.Minute).Format(time.RFC3339Nano) + `", "duration": 600000000000, "reason": "imported"}]`
+
+	for _, tc := range []struct {
+		name      string
+		overwrite bool
+		want      p2p.BlockedPeer
+	}{
+		{
+			name: "merge",
+			want: p2p.BlockedPeer{
+				Peer:      p2p.Peer{Address: addr},
+				Timestamp: now,
+				Duration:  time.Hour,
+				Remaining: time.Hour,
+				Reason:    "imported",
+			},
+		},
+		{
+			name:      "overwrite",
+			overwrite: true,
+			want: p2p.BlockedPeer{
+				Peer:      p2p.Peer{Address: addr},
+				Timestamp: now.Add(-time.Minute),
+				Duration:  10 * time.Minute,
+				Remaining: 9 * time.Minute,
+				Reason:    "imported",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{Clock: clock.NewMock(now)})
+			if err := bl.AddWithReason(addr, time.Hour, "local"); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := bl.Import(strings.NewReader(records), tc.overwrite, true); err != nil {
+				t.Fatal(err)
+			}
+
+			peers, err := bl.Peers()
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectPeers(t, peers, []p2p.BlockedPeer{tc.want})
+		})
+	}
+}
+
+func expectPeers(t *testing.T, got, want []p2p.BlockedPeer) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d blocklisted peers, want %d", len(got), len(want))
+	}
+	for i := range got {
+		g, w := got[i], want[i]
+		if !g.Address.Equal(w.Address) ||
+			!g.Timestamp.Equal(w.Timestamp) ||
+			g.Duration != w.Duration ||
+			g.Remaining != w.Remaining ||
+			g.Reason != w.Reason {
+			t.Errorf("got blocklisted peer %+v, want %+v", g, w)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blocklist
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/internal/clock"
+	"github.com/ethersphere/bee/pkg/storage"
+)
+
+// ipKeyPrefix is the prefix of the keys of the blocklisted IP addresses. It
+// must not start with keyPrefix, so that the iterations over the blocklisted
+// overlays do not include them.
+var ipKeyPrefix = "ipblocklist-"
+
+var errInvalidIP = errors.New("invalid ip address")
+
+// AddIP blocklists the IP address for the duration, forever if it is zero,
+// with the same policy as Add. The IP address is blocklisted independently of
+// the overlays, so both the IP address of a peer and its overlay can be
+// blocklisted at the same time. An IPv4 address and its IPv4-mapped IPv6 form
+// are the same IP address.
+func (b *Blocklist) AddIP(ip net.IP, duration time.Duration) error {
+	key, err := generateIPKey(ip)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := entry{
+		Timestamp: b.clock.Now(),
+		Duration:  entryDuration(duration),
+		Permanent: duration == 0,
+	}
+	e, err := b.get(key)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+	} else {
+		n = e.merge(n, b.renewal, b.clock)
+	}
+	return b.store.Put(key, &n)
+}
+
+// ExistsIP reports whether the IP address is blocklisted. The expired entry
+// of the IP address is removed like in Exists.
+func (b *Blocklist) ExistsIP(ip net.IP) (bool, error) {
+	key, err := generateIPKey(ip)
+	if err != nil {
+		return false, err
+	}
+
+	e, err := b.get(key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if e.expired(b.clock) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		// the entry could have been renewed since it was found expired
+		if e, err := b.get(key); err == nil && e.expired(b.clock) {
+			_ = b.store.Delete(key)
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+// RemoveIP removes the IP address from the blocklist before its block
+// duration elapses. It returns storage.ErrNotFound if it is not blocklisted.
+func (b *Blocklist) RemoveIP(ip net.IP) error {
+	key, err := generateIPKey(ip)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e, err := b.get(key)
+	if err != nil {
+		return err
+	}
+	if err := b.store.Delete(key); err != nil {
+		return err
+	}
+	if e.expired(b.clock) {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// sweepIPs removes the entries of the IP addresses with an elapsed block
+// duration from the store, as observed by the clock.
+func sweepIPs(c clock.Clock) storage.SweepFunc {
+	return func(ctx context.Context, store storage.StateStorer) (removed int, err error) {
+		return sweep(ctx, store, ipKeyPrefix, c, nil)
+	}
+}
+
+// generateIPKey returns the key of the entry of the IP address, with the IPv4
+// addresses in their 4 bytes form and the IPv6 addresses in the 16 bytes one.
+func generateIPKey(ip net.IP) (string, error) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ipKeyPrefix + string(ip4), nil
+	}
+	if ip16 := ip.To16(); ip16 != nil {
+		return ipKeyPrefix + string(ip16), nil
+	}
+	return "", errInvalidIP
+}
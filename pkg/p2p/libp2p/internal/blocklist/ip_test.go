@@ -0,0 +1,132 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blocklist_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/internal/clock"
+	"github.com/ethersphere/bee/pkg/p2p/libp2p/internal/blocklist"
+	"github.com/ethersphere/bee/pkg/statestore/mock"
+	"github.com/ethersphere/bee/pkg/storage"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+func TestIP(t *testing.T) {
+	ip4 := net.ParseIP("192.0.2.1")
+	ip6 := net.ParseIP("2001:db8::1")
+	otherIP := net.ParseIP("192.0.2.2")
+
+	c := clock.NewMock(time.Now())
+	bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{Clock: c})
+
+	if err := bl.AddIP(ip4, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.AddIP(ip6, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	expectIP := func(ip net.IP, want bool) {
+		t.Helper()
+		exists, err := bl.ExistsIP(ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists != want {
+			t.Fatalf("got exists %v for ip %s, want %v", exists, ip, want)
+		}
+	}
+
+	expectIP(ip4, true)
+	// the IPv4-mapped IPv6 form of the address is the same address
+	expectIP(net.ParseIP("::ffff:192.0.2.1"), true)
+	expectIP(ip4.To4(), true)
+	expectIP(ip6, true)
+	expectIP(otherIP, false)
+
+	c.Advance(2 * time.Hour)
+	expectIP(ip4, true)
+	expectIP(ip6, false)
+
+	if err := bl.RemoveIP(ip4); err != nil {
+		t.Fatal(err)
+	}
+	expectIP(ip4, false)
+	if err := bl.RemoveIP(ip4); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, storage.ErrNotFound)
+	}
+
+	if err := bl.AddIP(nil, 0); err == nil {
+		t.Fatal("got no error for an invalid ip address")
+	}
+}
+
+func TestIPAndOverlay(t *testing.T) {
+	ip := net.ParseIP("2001:db8::1")
+	overlay := swarm.NewAddress([]byte{0, 1, 2, 3})
+
+	c := clock.NewMock(time.Now())
+	bl := blocklist.NewBlocklist(mock.NewStateStore(), blocklist.Options{Clock: c})
+
+	if err := bl.AddIP(ip, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := bl.Add(overlay, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	// the ip addresses are not listed with the overlays
+	peers, err := bl.Peers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 1 || !peers[0].Address.Equal(overlay) {
+		t.Fatalf("got blocklisted peers %v, want only %s", peers, overlay)
+	}
+
+	// the ip address expires independently of the overlay
+	c.Advance(10 * time.Minute)
+	removed, err := bl.Sweep(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Fatalf("got %d swept entries, want %d", removed, 1)
+	}
+	exists, err := bl.ExistsIP(ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Fatal("got the ip address blocklisted, want expired")
+	}
+	exists, err = bl.Exists(overlay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exists {
+		t.Fatal("got the overlay not blocklisted, want blocklisted")
+	}
+
+	// clearing the overlays keeps the ip addresses
+	if err := bl.AddIP(ip, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bl.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	exists, err = bl.ExistsIP(ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exists {
+		t.Fatal("got the ip address not blocklisted, want blocklisted")
+	}
+}
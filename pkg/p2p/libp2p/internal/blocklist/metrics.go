@@ -0,0 +1,77 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package blocklist
+
+import (
+	m "github.com/ethersphere/bee/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metrics struct {
+	// all metrics fields must be exported
+	// to be able to return them by Metrics()
+	// using reflection
+	AddCount           prometheus.Counter
+	RemoveCount        prometheus.Counter
+	ExpiredCount       prometheus.Counter
+	EvictedCount       prometheus.Counter
+	ExistsHitCount     prometheus.Counter
+	DroppedEventsCount prometheus.Counter
+	Entries            prometheus.Gauge
+}
+
+func newMetrics() metrics {
+	subsystem := "blocklist"
+
+	return metrics{
+		AddCount: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "add_count",
+			Help:      "Number of peers added to the blocklist, including the renewals.",
+		}),
+		RemoveCount: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "remove_count",
+			Help:      "Number of entries removed from the blocklist before they expired.",
+		}),
+		ExpiredCount: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "expired_count",
+			Help:      "Number of expired entries removed from the blocklist.",
+		}),
+		EvictedCount: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "evicted_count",
+			Help:      "Number of entries evicted from the full blocklist.",
+		}),
+		ExistsHitCount: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "exists_hit_count",
+			Help:      "Number of lookups that found the peer blocklisted.",
+		}),
+		DroppedEventsCount: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "dropped_events_count",
+			Help:      "Number of events not delivered to the subscribers with a full buffer.",
+		}),
+		Entries: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "entries",
+			Help:      "Number of entries in the blocklist, including the expired ones that are not removed yet.",
+		}),
+	}
+}
+
+// Metrics returns the blocklist metrics.
+func (b *Blocklist) Metrics() []prometheus.Collector {
+	return m.PrometheusCollectorsFromFields(b.metrics)
+}
@@ -5,9 +5,15 @@
 package breaker
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"math/rand"
 	"sync"
 	"time"
+
+	"github.com/ethersphere/bee/pkg/internal/clock"
+	"golang.org/x/sync/semaphore"
 )
 
 const (
@@ -16,52 +22,349 @@ const (
 	failInterval = 30 * time.Minute
 	maxBackoff   = time.Hour
 	backoff      = 2 * time.Minute
+	minimumCalls = 10
+	tripHistory  = 16
 )
 
 var (
 	_ Interface = (*breaker)(nil)
 
-	// timeNow is used to deterministically mock time.Now() in tests.
-	timeNow = time.Now
-
 	// ErrClosed is the special error type that indicates that breaker is closed and that is not executing functions at the moment.
 	ErrClosed = errors.New("breaker closed")
+
+	// ErrTooManyRequests is returned if the limit of the calls in flight is
+	// reached and the breaker does not wait for a free slot.
+	ErrTooManyRequests = errors.New("breaker too many requests")
+
+	// ErrPanic is returned by ExecuteAsync if f() panics.
+	ErrPanic = errors.New("breaker execution panic")
+
+	// errCallTimeout is the failure of the calls which do not return
+	// within the call timeout.
+	errCallTimeout = fmt.Errorf("breaker call timeout: %w", context.DeadlineExceeded)
+
+	// ErrInvalidOptions is returned by NewBreaker if the options are not valid.
+	ErrInvalidOptions = errors.New("invalid breaker options")
 )
 
 type Interface interface {
 	// Execute runs f() if the limit number of consecutive failed calls is not reached within fail interval.
 	// f() call is not locked so it can still be executed concurrently.
 	// Returns `ErrClosed` if the limit is reached or f() result otherwise.
+	// Once the backoff elapses, a single f() call is executed as a probe and
+	// the others return `ErrClosed` until it returns. The breaker opens after
+	// the success threshold of consecutive successful probes and closes again
+	// with the doubled backoff if a probe fails. A panic of f() is counted as
+	// a failure before it is passed on to the caller.
 	Execute(f func() error) error
 
-	// ClosedUntil returns the timestamp when the breaker will become open again.
+	// ExecuteCtx is Execute, which stops waiting for a free slot of the
+	// calls in flight when the context is done, returning its error. The
+	// context passed to f() is the child of ctx, which is canceled once the
+	// call timeout elapses.
+	ExecuteCtx(ctx context.Context, f func(ctx context.Context) error) error
+
+	// ExecuteWithFallback is Execute, which returns the result of fallback()
+	// instead of `ErrClosed` if the breaker does not execute f() because it
+	// is closed. The result of fallback() is not counted.
+	ExecuteWithFallback(f, fallback func() error) error
+
+	// ExecuteCtxWithFallback is ExecuteCtx with the fallback of
+	// ExecuteWithFallback.
+	ExecuteCtxWithFallback(ctx context.Context, f func(ctx context.Context) error, fallback func() error) error
+
+	// ExecuteAsync is Execute, which runs in a new goroutine and sends its
+	// error to the returned channel once f() returns. The failure is counted
+	// when f() returns. A panic of f() is counted as a failure and returned
+	// as `ErrPanic`.
+	ExecuteAsync(f func() error) <-chan error
+
+	// ClosedUntil returns the timestamp when the closed breaker executes the
+	// probe. It returns the current time if the breaker is not closed or if
+	// its backoff elapsed, so that the next call is executed.
 	ClosedUntil() time.Time
+
+	// Remaining returns the duration until ClosedUntil, zero if the next
+	// call is executed.
+	Remaining() time.Duration
+
+	// Reset opens the breaker and restores its backoff to the start backoff.
+	// The calls in flight do not change the state of the reset breaker. It
+	// does nothing if the breaker is open.
+	Reset()
+
+	// Trip closes the breaker for the backoff duration, or for its current
+	// backoff if it is zero, as if the limit of the failures was reached.
+	// The calls in flight do not change the state of the tripped breaker.
+	Trip(backoff time.Duration)
+
+	// State returns the state of the breaker. A closed breaker stays
+	// closed after its backoff elapses, until the probe is executed.
+	State() State
+
+	// Stats returns the statistics of the calls of the breaker.
+	Stats() Stats
+}
+
+// State is the state of a breaker.
+type State int
+
+const (
+	StateOpen     State = iota // f() calls are executed
+	StateClosed                // f() calls are not executed until the backoff elapses
+	StateHalfOpen              // f() calls are executed as probes, one at a time
+)
+
+func (s State) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// BackoffError is a failure which closes the breaker for its Backoff, capped
+// by the max backoff, instead of the backoff of the breaker, if the breaker
+// closes because of it. The backoff of the breaker is not changed.
+type BackoffError struct {
+	Err     error
+	Backoff time.Duration
+}
+
+func (e *BackoffError) Error() string {
+	return fmt.Sprintf("%v, backoff %v", e.Err, e.Backoff)
+}
+
+// Unwrap returns the wrapped error.
+func (e *BackoffError) Unwrap() error { return e.Err }
+
+// Stats are the statistics of the calls of a breaker.
+type Stats struct {
+	ConsecutiveFailures int         // the failures counted towards the limit
+	Executions          uint64      // the number of the executed f() calls
+	Failures            uint64      // the number of the failed f() calls
+	SlowCalls           uint64      // the number of the successful f() calls counted as failures for being slow
+	LastFailure         time.Time   // zero if no call has failed
+	Trips               []TripEvent // the recent trips, newest first
 }
 
 type breaker struct {
+	state                State
+	generation           int // incremented on every reset and trip, so that the calls in flight are ignored
 	limit                int // breaker will not execute any more tasks after limit number of consecutive failures happen
 	consFailedCalls      int // current number of consecutive fails
+	consSuccessfulProbes int // current number of consecutive successful probes
+	successThreshold     int
+	restoreBackoff       bool          // whether the backoff is restored to the start backoff when the breaker opens
+	backoffReset         time.Duration // zero if the backoff is not restored after a healthy period
+	openedTimestamp      time.Time     // when the last probe opened the breaker, zero if backoffReset is not set
+	probing              bool          // whether a probe is in flight
 	firstFailedTimestamp time.Time
+	lastFailedTimestamp  time.Time
+	executions           uint64
+	failures             uint64
+	slowCalls            uint64
+	slowCall             time.Duration // zero if the slow calls are not failures
+	callTimeout          time.Duration // zero if the calls are not timed out
 	closedTimestamp      time.Time
-	backoff              time.Duration // initial backoff duration
+	backoff              time.Duration // current backoff duration
+	startBackoff         time.Duration // initial backoff duration
+	closedBackoff        time.Duration // the jittered backoff of the current closure
+	trips                *trips
+	jitter               float64
+	rand                 *rand.Rand
 	maxBackoff           time.Duration
 	failInterval         time.Duration // consecutive failures are counted if they happen within this interval
+	failureRate          float64       // the failure rate threshold, zero if the failures are counted
+	minimumCalls         int
+	window               *window // the calls within fail interval, nil if the consecutive failures are counted
+	clock                clock.Clock
+	onClose              func(backoff time.Duration)
+	isFailure            func(err error) bool
+	failureWeight        func(err error) int // nil if all failures weigh one
+	onStateChange        func(from, to State)
+	inflight             *semaphore.Weighted // nil if the calls in flight are not limited
+	waitInflight         bool
+	transitions          []transition // not yet passed to onStateChange
+	notifying            bool         // whether a goroutine is passing the transitions to onStateChange
+	metrics              *Metrics
+	name                 string
 	mtx                  sync.Mutex
 }
 
+// transition is a transition of the state of a breaker.
+type transition struct {
+	from, to State
+}
+
 type Options struct {
 	Limit        int
 	FailInterval time.Duration
 	StartBackoff time.Duration
 	MaxBackoff   time.Duration
+	// SuccessThreshold is the number of the consecutive successful probes
+	// after which the half-open breaker opens, one if it is not set. If it is
+	// set, the backoff is also restored to the start backoff when it opens.
+	SuccessThreshold int
+	// BackoffResetAfter, if set, restores the backoff to the start backoff
+	// when the breaker closes after it was open for at least this duration
+	// since the last successful probe, so that the old failures do not
+	// prolong the backoff.
+	BackoffResetAfter time.Duration
+	// Jitter randomizes every backoff for which the breaker closes within
+	// plus or minus this fraction of it, so that the breakers closed at the
+	// same time do not open at the same time. It must be between 0 and 1,
+	// the backoffs are not randomized if it is zero.
+	Jitter float64
+	// Rand is the source of the randomness of the jitter, one seeded with
+	// the current time is used if it is nil. It must not be used by others.
+	Rand *rand.Rand
+	// SlidingWindow closes the breaker when the Limit of the failures within
+	// the last FailInterval is reached, instead of when the Limit of the
+	// consecutive failures is reached. It can not be set together with the
+	// FailureRateThreshold.
+	SlidingWindow bool
+	// FailureRateThreshold, if set, closes the breaker when the rate of the
+	// failed calls within the last FailInterval exceeds it, instead of when
+	// the Limit of the consecutive failures is reached. It must be between
+	// 0 and 1, and it can not be set together with the Limit.
+	FailureRateThreshold float64
+	// MinimumCalls is the number of the calls within the last FailInterval
+	// after which the failure rate is considered, 10 if it is not set. It
+	// can only be set together with FailureRateThreshold.
+	MinimumCalls int
+	// Clock is the source of time, the real clock is used if it is nil.
+	Clock clock.Clock
+	// OnClose, if set, is called with the backoff duration for which the
+	// breaker is closed, every time the limit of the failures is reached.
+	OnClose func(backoff time.Duration)
+	// IsFailure reports whether the error returned by f() is a failure, all
+	// errors are failures if it is nil. The other errors are returned, but
+	// they neither count as failures nor reset the consecutive failures.
+	IsFailure func(err error) bool
+	// FailureWeight, if set, returns the number of the consecutive failures
+	// which the failure counts for, so that the severe failures close the
+	// breaker sooner. It is called only for the errors which are failures by
+	// IsFailure, and the failures of zero weight are treated as the errors
+	// which are not failures. The panics, the timeouts and the slow calls
+	// weigh one. The failures within the sliding window or the failure rate
+	// are not weighted.
+	FailureWeight func(err error) int
+	// OnStateChange, if set, is called once for every transition of the
+	// state of the breaker, in order. It is called without holding the lock
+	// of the breaker, possibly by a goroutine other than the one which caused
+	// the transition, and it can use the breaker.
+	OnStateChange func(from, to State)
+	// TripHistory is the number of the recent trips returned by Stats, 16
+	// if it is not set.
+	TripHistory int
+	// SlowCallDuration, if set, counts the successful f() calls which take
+	// longer than it as failures, while they still return no error.
+	SlowCallDuration time.Duration
+	// CallTimeout, if set, counts the f() calls which do not return within
+	// it as failures as soon as it elapses, and they return
+	// context.DeadlineExceeded. The f() calls are executed in new goroutines
+	// then, and their results are discarded if they return later. The
+	// context passed to f() by ExecuteCtx has the deadline of the timeout
+	// and it is canceled when the timeout elapses. The f() calls hold the
+	// slots of the calls in flight until they return.
+	CallTimeout time.Duration
+	// MaxInflight limits the number of the f() calls executed concurrently,
+	// they are not limited if it is zero. When the limit is reached, the
+	// calls return ErrTooManyRequests, or wait for a free slot if
+	// WaitInflight is set.
+	MaxInflight  int
+	WaitInflight bool
+	// Metrics, if set, are updated with the calls and the states of the
+	// breaker, labeled with the Name. The registries count the states of
+	// their breakers instead.
+	Metrics *Metrics
+	Name    string
 }
 
-func NewBreaker(o Options) Interface {
+func NewBreaker(o Options) (Interface, error) {
+	return newBreaker(o)
+}
+
+func newBreaker(o Options) (*breaker, error) {
+	if o.Limit < 0 {
+		return nil, fmt.Errorf("%w: negative limit %d", ErrInvalidOptions, o.Limit)
+	}
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{name: "fail interval", value: o.FailInterval},
+		{name: "start backoff", value: o.StartBackoff},
+		{name: "max backoff", value: o.MaxBackoff},
+		{name: "backoff reset after", value: o.BackoffResetAfter},
+		{name: "slow call duration", value: o.SlowCallDuration},
+		{name: "call timeout", value: o.CallTimeout},
+	} {
+		if d.value < 0 {
+			return nil, fmt.Errorf("%w: negative %s %v", ErrInvalidOptions, d.name, d.value)
+		}
+	}
+	if o.TripHistory < 0 {
+		return nil, fmt.Errorf("%w: negative trip history %d", ErrInvalidOptions, o.TripHistory)
+	}
+	if o.MinimumCalls < 0 {
+		return nil, fmt.Errorf("%w: negative minimum calls %d", ErrInvalidOptions, o.MinimumCalls)
+	}
+	if o.FailureRateThreshold != 0 {
+		if o.Limit != 0 {
+			return nil, fmt.Errorf("%w: both the limit and the failure rate threshold are set", ErrInvalidOptions)
+		}
+		if o.FailureRateThreshold < 0 || o.FailureRateThreshold >= 1 {
+			return nil, fmt.Errorf("%w: failure rate threshold %v not between 0 and 1", ErrInvalidOptions, o.FailureRateThreshold)
+		}
+		if o.SlidingWindow {
+			return nil, fmt.Errorf("%w: both the sliding window and the failure rate threshold are set", ErrInvalidOptions)
+		}
+	} else if o.MinimumCalls != 0 {
+		return nil, fmt.Errorf("%w: minimum calls set without the failure rate threshold", ErrInvalidOptions)
+	}
+	if o.MaxInflight < 0 {
+		return nil, fmt.Errorf("%w: negative max inflight %d", ErrInvalidOptions, o.MaxInflight)
+	}
+	if o.WaitInflight && o.MaxInflight == 0 {
+		return nil, fmt.Errorf("%w: wait inflight set without max inflight", ErrInvalidOptions)
+	}
+	if o.SuccessThreshold < 0 {
+		return nil, fmt.Errorf("%w: negative success threshold %d", ErrInvalidOptions, o.SuccessThreshold)
+	}
+	if o.Jitter < 0 || o.Jitter > 1 {
+		return nil, fmt.Errorf("%w: jitter %v not between 0 and 1", ErrInvalidOptions, o.Jitter)
+	}
+
 	breaker := &breaker{
-		limit:        o.Limit,
-		backoff:      o.StartBackoff,
-		maxBackoff:   o.MaxBackoff,
-		failInterval: o.FailInterval,
+		limit:            o.Limit,
+		backoff:          o.StartBackoff,
+		startBackoff:     o.StartBackoff,
+		successThreshold: o.SuccessThreshold,
+		restoreBackoff:   o.SuccessThreshold != 0,
+		backoffReset:     o.BackoffResetAfter,
+		slowCall:         o.SlowCallDuration,
+		callTimeout:      o.CallTimeout,
+		jitter:           o.Jitter,
+		rand:             o.Rand,
+		maxBackoff:       o.MaxBackoff,
+		failInterval:     o.FailInterval,
+		failureRate:      o.FailureRateThreshold,
+		minimumCalls:     o.MinimumCalls,
+		clock:            o.Clock,
+		onClose:          o.OnClose,
+		isFailure:        o.IsFailure,
+		failureWeight:    o.FailureWeight,
+		onStateChange:    o.OnStateChange,
+		waitInflight:     o.WaitInflight,
+		metrics:          o.Metrics,
+		name:             o.Name,
 	}
 
 	if o.Limit == 0 {
@@ -78,73 +381,527 @@ func NewBreaker(o Options) Interface {
 
 	if o.StartBackoff == 0 {
 		breaker.backoff = backoff
+		breaker.startBackoff = backoff
+	}
+
+	if o.IsFailure == nil {
+		breaker.isFailure = func(error) bool { return true }
 	}
 
-	return breaker
+	if o.SuccessThreshold == 0 {
+		breaker.successThreshold = 1
+	}
+
+	if o.MinimumCalls == 0 {
+		breaker.minimumCalls = minimumCalls
+	}
+
+	if o.TripHistory == 0 {
+		breaker.trips = newTrips(tripHistory)
+	} else {
+		breaker.trips = newTrips(o.TripHistory)
+	}
+
+	if o.MaxInflight > 0 {
+		breaker.inflight = semaphore.NewWeighted(int64(o.MaxInflight))
+	}
+
+	if o.SlidingWindow || o.FailureRateThreshold != 0 {
+		breaker.window = newWindow(breaker.failInterval)
+	}
+
+	// the defaults are validated too
+	if breaker.startBackoff > breaker.maxBackoff {
+		return nil, fmt.Errorf("%w: start backoff %v greater than max backoff %v", ErrInvalidOptions, breaker.startBackoff, breaker.maxBackoff)
+	}
+
+	if o.Clock == nil {
+		breaker.clock = clock.Real
+	}
+
+	if o.Jitter > 0 && o.Rand == nil {
+		breaker.rand = rand.New(rand.NewSource(time.Now().UnixNano())) // skipcq: GSC-G404
+	}
+
+	if o.Metrics != nil && o.Metrics.State != nil {
+		o.Metrics.State.WithLabelValues(o.Name).Set(float64(StateOpen))
+	}
+
+	return breaker, nil
 }
 
 func (b *breaker) Execute(f func() error) error {
-	if err := b.beforef(); err != nil {
-		return err
+	return b.ExecuteCtx(context.Background(), func(context.Context) error { return f() })
+}
+
+func (b *breaker) ExecuteCtx(ctx context.Context, f func(ctx context.Context) error) error {
+	_, err := b.execute(ctx, f)
+	return err
+}
+
+// execute executes f() and reports whether it was not executed because the
+// breaker is closed.
+func (b *breaker) execute(ctx context.Context, f func(ctx context.Context) error) (closed bool, err error) {
+	release := func() {}
+	if b.inflight != nil {
+		if b.waitInflight {
+			if err := b.inflight.Acquire(ctx, 1); err != nil {
+				return false, err
+			}
+		} else if !b.inflight.TryAcquire(1) {
+			return false, ErrTooManyRequests
+		}
+		release = func() { b.inflight.Release(1) }
+	}
+	// released also if f() panics, or by call once f() returns after the
+	// call timeout
+	held := true
+	defer func() {
+		if held {
+			release()
+		}
+	}()
+
+	probe, generation, err := b.beforef()
+	b.notify()
+	if err != nil {
+		// beforef only fails if the breaker is closed
+		return true, err
+	}
+
+	returned := false
+	defer func() {
+		if returned {
+			return
+		}
+		// the panic of f() is a failure, so that the probe of the
+		// half-open breaker is not left in flight, and it is passed on
+		r := recover()
+		_ = b.afterf(probe, generation, fmt.Errorf("%w: %v", ErrPanic, r), false)
+		if r != nil {
+			panic(r)
+		}
+	}()
+
+	var start time.Time
+	if b.slowCall > 0 {
+		start = b.clock.Now()
+	}
+	timedOut, err := b.call(ctx, f, release)
+	returned = true
+	if timedOut {
+		held = false
+		_ = b.afterf(probe, generation, errCallTimeout, false)
+		return false, context.DeadlineExceeded
+	}
+	return false, b.afterf(probe, generation, err, b.slowCall > 0 && b.clock.Since(start) > b.slowCall)
+}
+
+// call calls f() and reports whether it did not return within the call
+// timeout. The context of f() is canceled when the timeout elapses, and the
+// result of f() which returns after it is discarded. The release function
+// is then called once f() returns.
+func (b *breaker) call(ctx context.Context, f func(ctx context.Context) error, release func()) (timedOut bool, err error) {
+	if b.callTimeout == 0 {
+		return false, f(ctx)
 	}
 
-	return b.afterf(f())
+	type result struct {
+		err      error
+		panicked interface{}
+	}
+	// the timer is created before f() is called, so that the mocked
+	// clocks can be advanced once f() is called
+	timer := b.clock.NewTimer(b.callTimeout)
+	defer timer.Stop()
+	ctx, cancel := context.WithDeadline(ctx, b.clock.Now().Add(b.callTimeout))
+	defer cancel()
+	results := make(chan result, 1)
+	go func() {
+		var r result
+		defer func() {
+			r.panicked = recover()
+			results <- r
+		}()
+		r.err = f(ctx)
+	}()
+
+	select {
+	case r := <-results:
+		if r.panicked != nil {
+			panic(r.panicked)
+		}
+		return false, r.err
+	case <-timer.C():
+		go func() {
+			<-results
+			release()
+		}()
+		return true, nil
+	}
+}
+
+func (b *breaker) ExecuteWithFallback(f, fallback func() error) error {
+	return b.ExecuteCtxWithFallback(context.Background(), func(context.Context) error { return f() }, fallback)
+}
+
+func (b *breaker) ExecuteCtxWithFallback(ctx context.Context, f func(ctx context.Context) error, fallback func() error) error {
+	closed, err := b.execute(ctx, f)
+	if closed {
+		// executed after the slot of the call in flight is released
+		return fallback()
+	}
+	return err
+}
+
+func (b *breaker) ExecuteAsync(f func() error) <-chan error {
+	c := make(chan error, 1)
+	go func() {
+		c <- b.ExecuteCtx(context.Background(), func(context.Context) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("%w: %v", ErrPanic, r)
+				}
+			}()
+			return f()
+		})
+	}()
+	return c
 }
 
 func (b *breaker) ClosedUntil() time.Time {
+	now := b.clock.Now()
+	return now.Add(b.remaining(now))
+}
+
+func (b *breaker) Remaining() time.Duration {
+	return b.remaining(b.clock.Now())
+}
+
+func (b *breaker) remaining(now time.Time) time.Duration {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if b.state != StateClosed {
+		return 0
+	}
+	if d := b.closedTimestamp.Add(b.closedBackoff).Sub(now); d > 0 {
+		return d
+	}
+	return 0
+}
+
+func (b *breaker) State() State {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	return b.state
+}
+
+func (b *breaker) Stats() Stats {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	return Stats{
+		ConsecutiveFailures: b.consFailedCalls,
+		Executions:          b.executions,
+		Failures:            b.failures,
+		SlowCalls:           b.slowCalls,
+		LastFailure:         b.lastFailedTimestamp,
+		Trips:               b.trips.list(),
+	}
+}
+
+func (b *breaker) Reset() {
+	defer b.notify()
+
 	b.mtx.Lock()
 	defer b.mtx.Unlock()
 
-	if b.consFailedCalls >= b.limit {
-		return b.closedTimestamp.Add(b.backoff)
+	if b.state == StateOpen {
+		return
+	}
+
+	b.setState(StateOpen)
+	b.generation++
+	b.probing = false
+	b.resetFailed()
+	b.resetWindow()
+	b.closedTimestamp = time.Time{}
+	b.backoff = b.startBackoff
+}
+
+func (b *breaker) Trip(backoff time.Duration) {
+	b.mtx.Lock()
+
+	b.generation++
+	b.probing = false
+	b.resetFailed()
+	b.resetWindow()
+	now := b.clock.Now()
+	if b.state == StateOpen {
+		b.restoreHealthyBackoff(now)
 	}
+	if backoff > 0 {
+		b.backoff = backoff
+	}
+	// the given backoff is not randomized
+	b.close(now, b.backoff, backoff == 0)
+	backoff = b.closedBackoff
+	b.mtx.Unlock()
 
-	return timeNow()
+	b.notify()
+	// called without the lock, so that it can use the breaker
+	if b.onClose != nil {
+		b.onClose(backoff)
+	}
 }
 
-func (b *breaker) beforef() error {
+// beforef reports whether f() can be executed, whether it is the probe
+// of the half-open breaker, and the generation of the breaker.
+func (b *breaker) beforef() (probe bool, generation int, err error) {
 	b.mtx.Lock()
 	defer b.mtx.Unlock()
 
-	// use timeNow().Sub() instead of time.Since() so it can be deterministically mocked in tests
-	if b.consFailedCalls >= b.limit {
-		if b.closedTimestamp.IsZero() || timeNow().Sub(b.closedTimestamp) < b.backoff {
-			return ErrClosed
+	switch b.state {
+	case StateHalfOpen:
+		if b.probing {
+			return false, 0, ErrClosed
+		}
+
+		b.probing = true
+		return true, b.generation, nil
+	case StateClosed:
+		if b.closedTimestamp.IsZero() || b.clock.Since(b.closedTimestamp) < b.closedBackoff {
+			return false, 0, ErrClosed
+		}
+
+		b.setState(StateHalfOpen)
+		b.probing = true
+		b.consSuccessfulProbes = 0
+		return true, b.generation, nil
+	}
+
+	if !b.firstFailedTimestamp.IsZero() && b.clock.Since(b.firstFailedTimestamp) >= b.failInterval {
+		b.resetFailed()
+	}
+
+	return false, b.generation, nil
+}
+
+func (b *breaker) afterf(probe bool, generation int, err error, slow bool) error {
+	// the panics and the timeouts are failures regardless of isFailure
+	forced := errors.Is(err, ErrPanic) || err == errCallTimeout
+	failed := err != nil && (forced || b.isFailure(err))
+	weight := 1
+	if failed && b.failureWeight != nil && !forced {
+		if weight = b.failureWeight(err); weight <= 0 {
+			failed = false
+		}
+	}
+	ignored := err != nil && !failed
+	// the slow successful calls are failures of the breaker, not of the caller
+	slow = slow && err == nil
+	// the backoff of the failure replaces the backoff of the breaker
+	var override time.Duration
+	var be *BackoffError
+	if failed && errors.As(err, &be) && be.Backoff > 0 {
+		override = be.Backoff
+		if override > b.maxBackoff {
+			override = b.maxBackoff
+		}
+	}
+
+	b.mtx.Lock()
+
+	b.executions++
+	if b.metrics != nil {
+		b.metrics.ExecutionCount.WithLabelValues(b.name).Inc()
+		if failed {
+			b.metrics.FailureCount.WithLabelValues(b.name).Inc()
 		}
+	}
+	var now time.Time
+	if failed || slow || (!ignored && (b.window != nil || (probe && b.backoffReset > 0))) {
+		now = b.clock.Now()
+	}
+	if failed {
+		b.failures++
+		b.lastFailedTimestamp = now
+	}
+	if slow {
+		b.slowCalls++
+		failed = true
+	}
 
+	var closed bool
+	switch {
+	case generation != b.generation:
+		// the calls which return after the breaker was reset
+		// were executed before it and do not change its state
+	case ignored:
+		// the next call is the probe
+		if probe {
+			b.probing = false
+		}
+	case probe && !failed:
+		b.probing = false
+		if b.consSuccessfulProbes++; b.consSuccessfulProbes < b.successThreshold {
+			break
+		}
+		b.setState(StateOpen)
 		b.resetFailed()
+		b.resetWindow()
+		if b.restoreBackoff {
+			b.backoff = b.startBackoff
+		}
+		if b.backoffReset > 0 {
+			b.openedTimestamp = now
+		}
+	case probe:
+		b.probing = false
+		closed = true
+		if override > 0 {
+			b.close(now, override, false)
+			break
+		}
 		if newBackoff := b.backoff * 2; newBackoff <= b.maxBackoff {
 			b.backoff = newBackoff
 		} else {
 			b.backoff = b.maxBackoff
 		}
+		b.close(now, b.backoff, true)
+	case b.state != StateOpen:
+		// the calls which return while the breaker is not open were
+		// executed before it closed and do not change its state
+	case !failed:
+		b.resetFailed()
+		b.record(now, false)
+	default:
+		if b.consFailedCalls == 0 {
+			b.firstFailedTimestamp = now
+		}
+
+		b.consFailedCalls += weight
+		if !b.record(now, true) {
+			break
+		}
+		closed = true
+		if override > 0 {
+			b.close(now, override, false)
+			break
+		}
+		b.restoreHealthyBackoff(now)
+		b.close(now, b.backoff, true)
 	}
+	backoff := b.closedBackoff
+	b.mtx.Unlock()
 
-	if !b.firstFailedTimestamp.IsZero() && timeNow().Sub(b.firstFailedTimestamp) >= b.failInterval {
-		b.resetFailed()
+	b.notify()
+	// called without the lock, so that it can use the breaker
+	if closed && b.onClose != nil {
+		b.onClose(backoff)
 	}
+	return err
+}
 
-	return nil
+// restoreHealthyBackoff restores the backoff to the start backoff if the
+// breaker was open for at least backoffReset since the last probe.
+func (b *breaker) restoreHealthyBackoff(now time.Time) {
+	if b.backoffReset > 0 && !b.openedTimestamp.IsZero() && now.Sub(b.openedTimestamp) >= b.backoffReset {
+		b.backoff = b.startBackoff
+	}
 }
 
-func (b *breaker) afterf(err error) error {
-	b.mtx.Lock()
-	defer b.mtx.Unlock()
-	if err != nil {
-		if b.consFailedCalls == 0 {
-			b.firstFailedTimestamp = timeNow()
-		}
+// close closes the breaker for the backoff, randomized by the jitter if
+// jitter is set.
+func (b *breaker) close(now time.Time, backoff time.Duration, jitter bool) {
+	b.setState(StateClosed)
+	b.closedTimestamp = now
+	b.closedBackoff = backoff
+	if jitter && b.jitter > 0 {
+		b.closedBackoff += time.Duration(b.jitter * (2*b.rand.Float64() - 1) * float64(backoff))
+	}
+	b.trips.add(TripEvent{Timestamp: now, Backoff: b.closedBackoff})
+	if b.metrics != nil {
+		b.metrics.TripCount.WithLabelValues(b.name).Inc()
+	}
+}
+
+// record records the call in the window, if the failures are not counted
+// consecutively, and reports whether the breaker must close.
+func (b *breaker) record(now time.Time, failed bool) bool {
+	if b.window == nil {
+		// the weighted failures can exceed the limit
+		return failed && b.consFailedCalls >= b.limit
+	}
+
+	b.window.record(now, failed)
+	switch {
+	case !failed:
+		return false
+	case b.failureRate == 0:
+		return b.window.failures >= b.limit
+	case b.window.calls < b.minimumCalls:
+		return false
+	default:
+		return float64(b.window.failures)/float64(b.window.calls) > b.failureRate
+	}
+}
+
+func (b *breaker) resetWindow() {
+	if b.window != nil {
+		b.window.reset()
+	}
+}
 
-		b.consFailedCalls++
-		if b.consFailedCalls == b.limit {
-			b.closedTimestamp = timeNow()
+// setState sets the state of the breaker and queues the transition for
+// onStateChange. It must be called with the lock held.
+func (b *breaker) setState(s State) {
+	if b.state == s {
+		return
+	}
+	if b.onStateChange != nil {
+		b.transitions = append(b.transitions, transition{from: b.state, to: s})
+	}
+	if b.metrics != nil {
+		if b.state == StateClosed {
+			b.metrics.ClosedTime.WithLabelValues(b.name).Observe(b.clock.Since(b.closedTimestamp).Seconds())
+		}
+		if b.metrics.State != nil {
+			b.metrics.State.WithLabelValues(b.name).Set(float64(s))
 		}
+	}
+	b.state = s
+}
 
-		return err
+// notify passes the queued transitions to onStateChange, in order. Only one
+// goroutine passes them at a time, the others leave their transitions to it,
+// so that onStateChange can use the breaker.
+func (b *breaker) notify() {
+	if b.onStateChange == nil {
+		return
 	}
 
-	b.resetFailed()
-	return nil
+	b.mtx.Lock()
+	if b.notifying {
+		b.mtx.Unlock()
+		return
+	}
+	b.notifying = true
+	for len(b.transitions) > 0 {
+		transitions := b.transitions
+		b.transitions = nil
+		b.mtx.Unlock()
+
+		for _, t := range transitions {
+			b.onStateChange(t.from, t.to)
+		}
+
+		b.mtx.Lock()
+	}
+	b.notifying = false
+	b.mtx.Unlock()
 }
 
 func (b *breaker) resetFailed() {
@@ -5,11 +5,21 @@
 package breaker_test
 
 import (
+	"context"
 	"errors"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/ethersphere/bee/pkg/internal/clock"
 	"github.com/ethersphere/bee/pkg/p2p/libp2p/internal/breaker"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestExecute(t *testing.T) {
@@ -58,33 +68,31 @@ func TestExecute(t *testing.T) {
 			limit:        3,
 			ferrors:      []error{testErr, testErr, testErr, testErr, testErr},
 			iterations:   5,
-			times:        []time.Time{initTime, initTime, initTime.Add(2 * failInterval), initTime, initTime, initTime, initTime},
+			times:        []time.Time{initTime, initTime, initTime, initTime.Add(2 * failInterval), initTime, initTime, initTime, initTime, initTime},
 			expectedErrs: []error{testErr, testErr, testErr, testErr, testErr},
 		},
 		"Backoff - close, reopen, close, don't open": {
 			limit:        1,
 			ferrors:      []error{testErr, shouldNotBeCalledErr, testErr, shouldNotBeCalledErr, testErr, shouldNotBeCalledErr, shouldNotBeCalledErr},
 			iterations:   7,
-			times:        []time.Time{initTime, initTime, initTime, initTime.Add(startBackoff + time.Second), initTime, initTime, initTime, initTime.Add(2*startBackoff + time.Second), initTime, initTime, initTime, initTime.Add(startBackoff + time.Second)},
+			times:        []time.Time{initTime, initTime, initTime.Add(startBackoff + time.Second), initTime, initTime, initTime.Add(2*startBackoff + time.Second), initTime, initTime, initTime.Add(startBackoff + time.Second)},
 			expectedErrs: []error{testErr, breaker.ErrClosed, testErr, breaker.ErrClosed, testErr, breaker.ErrClosed, breaker.ErrClosed},
 		},
 	}
 
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
-			b := breaker.NewBreaker(breaker.Options{
+			var c clock.Clock
+			if tc.times != nil {
+				c = &timeMock{times: tc.times}
+			}
+			b := newBreaker(t, breaker.Options{
 				Limit:        tc.limit,
 				StartBackoff: startBackoff,
 				FailInterval: failInterval,
+				Clock:        c,
 			})
 
-			if tc.times != nil {
-				timeMock := timeMock{times: tc.times}
-				breaker.SetTimeNow(timeMock.next)
-			} else {
-				breaker.SetTimeNow(time.Now)
-			}
-
 			for i := 0; i < tc.iterations; i++ {
 				if err := b.Execute(func() error {
 					if tc.ferrors[i] == shouldNotBeCalledErr {
@@ -104,12 +112,10 @@ func TestClosedUntil(t *testing.T) {
 	timestamp := time.Now()
 	startBackoff := 1 * time.Minute
 	testError := errors.New("test error")
-	timeMock := timeMock{times: []time.Time{timestamp, timestamp, timestamp}}
-	breaker.SetTimeNow(timeMock.next)
-
-	b := breaker.NewBreaker(breaker.Options{
+	b := newBreaker(t, breaker.Options{
 		Limit:        1,
 		StartBackoff: startBackoff,
+		Clock:        &timeMock{times: []time.Time{timestamp, timestamp, timestamp}},
 	})
 
 	notClosed := b.ClosedUntil()
@@ -129,12 +135,1758 @@ func TestClosedUntil(t *testing.T) {
 	}
 }
 
-type timeMock struct {
-	times []time.Time
-	curr  int
+func TestRemaining(t *testing.T) {
+	// the breakers have their own clocks, so the tests run in parallel
+	t.Parallel()
+
+	testError := errors.New("test error")
+	startBackoff := 1 * time.Minute
+	c := clock.NewMock(time.Now())
+	b := newBreaker(t, breaker.Options{
+		Limit:        1,
+		StartBackoff: startBackoff,
+		Clock:        c,
+	})
+	// the breaker on another clock is not affected by the advances
+	other := newBreaker(t, breaker.Options{
+		Limit:        1,
+		StartBackoff: startBackoff,
+		Clock:        clock.NewMock(c.Now()),
+	})
+	_ = other.Execute(func() error { return testError })
+
+	expect := func(remaining time.Duration) {
+		t.Helper()
+
+		if got := b.Remaining(); got != remaining {
+			t.Fatalf("expected remaining: %s, got: %s", remaining, got)
+		}
+		if got, want := b.ClosedUntil(), c.Now().Add(remaining); !got.Equal(want) {
+			t.Fatalf("expected closed until: %s, got: %s", want, got)
+		}
+	}
+
+	expect(0)
+
+	// the trip
+	_ = b.Execute(func() error { return testError })
+	expect(startBackoff)
+	c.Advance(startBackoff / 2)
+	expect(startBackoff / 2)
+
+	// the elapsed backoff is not reported before the probe
+	c.Advance(startBackoff)
+	expect(0)
+
+	// the failed probe
+	_ = b.Execute(func() error { return testError })
+	expect(2 * startBackoff)
+
+	// the half-open breaker while the probe is in flight
+	c.Advance(2 * startBackoff)
+	release := make(chan struct{})
+	errs := b.ExecuteAsync(func() error {
+		<-release
+		return nil
+	})
+	for b.State() != breaker.StateHalfOpen {
+		time.Sleep(time.Millisecond)
+	}
+	expect(0)
+	close(release)
+	if err := <-errs; err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// the reopened breaker
+	if got := b.State(); got != breaker.StateOpen {
+		t.Fatalf("expected state: %s, got: %s", breaker.StateOpen, got)
+	}
+	expect(0)
+
+	if got := other.Remaining(); got != startBackoff {
+		t.Fatalf("expected remaining of the other breaker: %s, got: %s", startBackoff, got)
+	}
 }
 
-func (t *timeMock) next() time.Time {
-	defer func() { t.curr++ }()
-	return t.times[t.curr]
+func TestOnClose(t *testing.T) {
+	testError := errors.New("test error")
+	startBackoff := 1 * time.Minute
+	c := clock.NewMock(time.Now())
+
+	var backoffs []time.Duration
+	b := newBreaker(t, breaker.Options{
+		Limit:        2,
+		StartBackoff: startBackoff,
+		Clock:        c,
+		OnClose: func(backoff time.Duration) {
+			backoffs = append(backoffs, backoff)
+		},
+	})
+
+	fail := func(want error) {
+		t.Helper()
+		if err := b.Execute(func() error { return testError }); err != want {
+			t.Fatalf("expected: %v, got: %v", want, err)
+		}
+	}
+
+	fail(testError)
+	if len(backoffs) != 0 {
+		t.Fatalf("expected no close before the limit, got backoffs %v", backoffs)
+	}
+	fail(testError)
+	fail(breaker.ErrClosed)
+
+	// the failed probe after the backoff closes the breaker with the doubled one
+	c.Advance(startBackoff + time.Second)
+	fail(testError)
+	fail(breaker.ErrClosed)
+
+	want := []time.Duration{startBackoff, 2 * startBackoff}
+	if len(backoffs) != len(want) || backoffs[0] != want[0] || backoffs[1] != want[1] {
+		t.Fatalf("expected backoffs: %v, got: %v", want, backoffs)
+	}
+}
+
+func TestHalfOpen(t *testing.T) {
+	testError := errors.New("test error")
+	startBackoff := 1 * time.Minute
+
+	for _, tc := range []struct {
+		name     string
+		probeErr error
+	}{
+		{name: "probe succeeds", probeErr: nil},
+		{name: "probe fails", probeErr: testError},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := clock.NewMock(time.Now())
+			b := newBreaker(t, breaker.Options{
+				Limit:        1,
+				StartBackoff: startBackoff,
+				Clock:        c,
+			})
+
+			if err := b.Execute(func() error { return testError }); err != testError {
+				t.Fatalf("expected: %v, got: %v", testError, err)
+			}
+			c.Advance(startBackoff)
+
+			// only one of the calls at the end of the backoff is executed
+			const n = 50
+			var calls int32
+			release := make(chan struct{})
+			errs := make(chan error, n)
+			for i := 0; i < n; i++ {
+				go func() {
+					errs <- b.Execute(func() error {
+						atomic.AddInt32(&calls, 1)
+						<-release
+						return tc.probeErr
+					})
+				}()
+			}
+			for i := 0; i < n-1; i++ {
+				select {
+				case err := <-errs:
+					if err != breaker.ErrClosed {
+						t.Fatalf("expected: %v, got: %v", breaker.ErrClosed, err)
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatal("timed out waiting for the rejected calls")
+				}
+			}
+			close(release)
+			if err := <-errs; err != tc.probeErr {
+				t.Fatalf("expected: %v, got: %v", tc.probeErr, err)
+			}
+			if calls != 1 {
+				t.Fatalf("expected 1 probe call, got %d", calls)
+			}
+
+			if tc.probeErr == nil {
+				// the breaker is open for all calls
+				var wg sync.WaitGroup
+				for i := 0; i < n; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						if err := b.Execute(func() error { return nil }); err != nil {
+							t.Errorf("expected no error, got: %v", err)
+						}
+					}()
+				}
+				wg.Wait()
+				return
+			}
+
+			// the breaker is closed again with the doubled backoff
+			if got, want := b.ClosedUntil(), c.Now().Add(2*startBackoff); !got.Equal(want) {
+				t.Fatalf("expected: %s, got: %s", want, got)
+			}
+			c.Advance(startBackoff)
+			if err := b.Execute(func() error { return nil }); err != breaker.ErrClosed {
+				t.Fatalf("expected: %v, got: %v", breaker.ErrClosed, err)
+			}
+			c.Advance(startBackoff)
+			if err := b.Execute(func() error { return nil }); err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestHalfOpenProbePanic(t *testing.T) {
+	testError := errors.New("test error")
+	startBackoff := 1 * time.Minute
+	c := clock.NewMock(time.Now())
+	b := newBreaker(t, breaker.Options{
+		Limit:        1,
+		StartBackoff: startBackoff,
+		Clock:        c,
+	})
+
+	if err := b.Execute(func() error { return testError }); err != testError {
+		t.Fatalf("expected: %v, got: %v", testError, err)
+	}
+	c.Advance(startBackoff)
+
+	func() {
+		defer func() {
+			if r := recover(); r != "test panic" {
+				t.Fatalf("expected the panic of the probe, got: %v", r)
+			}
+		}()
+		_ = b.Execute(func() error { panic("test panic") })
+	}()
+
+	// the panicked probe is a failed probe
+	if got := b.State(); got != breaker.StateClosed {
+		t.Fatalf("expected state: %s, got: %s", breaker.StateClosed, got)
+	}
+	if got, want := b.ClosedUntil(), c.Now().Add(2*startBackoff); !got.Equal(want) {
+		t.Fatalf("expected: %s, got: %s", want, got)
+	}
+	c.Advance(2 * startBackoff)
+	if err := b.Execute(func() error { return nil }); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if got := b.State(); got != breaker.StateOpen {
+		t.Fatalf("expected state: %s, got: %s", breaker.StateOpen, got)
+	}
+}
+
+func TestHalfOpenInFlight(t *testing.T) {
+	testError := errors.New("test error")
+	startBackoff := 1 * time.Minute
+	c := clock.NewMock(time.Now())
+	b := newBreaker(t, breaker.Options{
+		Limit:        2,
+		StartBackoff: startBackoff,
+		Clock:        c,
+	})
+
+	// a call started before the breaker closed returns after its backoff
+	started, release := make(chan struct{}), make(chan struct{})
+	inFlight := make(chan error, 1)
+	go func() {
+		inFlight <- b.Execute(func() error {
+			close(started)
+			<-release
+			return testError
+		})
+	}()
+	<-started
+
+	for i := 0; i < 2; i++ {
+		if err := b.Execute(func() error { return testError }); err != testError {
+			t.Fatalf("expected: %v, got: %v", testError, err)
+		}
+	}
+	c.Advance(startBackoff)
+
+	probing, releaseProbe := make(chan struct{}), make(chan struct{})
+	probed := make(chan error, 1)
+	go func() {
+		probed <- b.Execute(func() error {
+			close(probing)
+			<-releaseProbe
+			return nil
+		})
+	}()
+	<-probing
+
+	// the failure of the earlier call does not end the probe
+	close(release)
+	if err := <-inFlight; err != testError {
+		t.Fatalf("expected: %v, got: %v", testError, err)
+	}
+	if err := b.Execute(func() error { return nil }); err != breaker.ErrClosed {
+		t.Fatalf("expected: %v, got: %v", breaker.ErrClosed, err)
+	}
+
+	close(releaseProbe)
+	if err := <-probed; err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := b.Execute(func() error { return nil }); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+}
+
+func TestReset(t *testing.T) {
+	testError := errors.New("test error")
+	startBackoff := 1 * time.Minute
+	c := clock.NewMock(time.Now())
+	b := newBreaker(t, breaker.Options{
+		Limit:        2,
+		StartBackoff: startBackoff,
+		Clock:        c,
+	})
+
+	fail := func(want error) {
+		t.Helper()
+		if err := b.Execute(func() error { return testError }); err != want {
+			t.Fatalf("expected: %v, got: %v", want, err)
+		}
+	}
+
+	// the reset of the open breaker does not clear the failures
+	fail(testError)
+	b.Reset()
+	fail(testError)
+	fail(breaker.ErrClosed)
+
+	// the failed probe doubles the backoff
+	c.Advance(startBackoff)
+	fail(testError)
+	if got, want := b.ClosedUntil(), c.Now().Add(2*startBackoff); !got.Equal(want) {
+		t.Fatalf("expected: %s, got: %s", want, got)
+	}
+
+	// the next call after the reset runs immediately
+	b.Reset()
+	if got, want := b.ClosedUntil(), c.Now(); !got.Equal(want) {
+		t.Fatalf("expected: %s, got: %s", want, got)
+	}
+	if err := b.Execute(func() error { return nil }); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// the backoff is restored to the start backoff
+	fail(testError)
+	fail(testError)
+	if got, want := b.ClosedUntil(), c.Now().Add(startBackoff); !got.Equal(want) {
+		t.Fatalf("expected: %s, got: %s", want, got)
+	}
+}
+
+func TestResetInFlight(t *testing.T) {
+	testError := errors.New("test error")
+	startBackoff := 1 * time.Minute
+	c := clock.NewMock(time.Now())
+	b := newBreaker(t, breaker.Options{
+		Limit:        1,
+		StartBackoff: startBackoff,
+		Clock:        c,
+	})
+
+	if err := b.Execute(func() error { return testError }); err != testError {
+		t.Fatalf("expected: %v, got: %v", testError, err)
+	}
+	c.Advance(startBackoff)
+
+	probing, release := make(chan struct{}), make(chan struct{})
+	probed := make(chan error, 1)
+	go func() {
+		probed <- b.Execute(func() error {
+			close(probing)
+			<-release
+			return testError
+		})
+	}()
+	<-probing
+
+	b.Reset()
+	close(release)
+	if err := <-probed; err != testError {
+		t.Fatalf("expected: %v, got: %v", testError, err)
+	}
+
+	// the probe which failed after the reset did not close the breaker
+	if err := b.Execute(func() error { return nil }); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+}
+
+func TestTrip(t *testing.T) {
+	testError := errors.New("test error")
+	startBackoff := 1 * time.Minute
+	c := clock.NewMock(time.Now())
+
+	var backoffs []time.Duration
+	b := newBreaker(t, breaker.Options{
+		Limit:        2,
+		StartBackoff: startBackoff,
+		Clock:        c,
+		OnClose: func(backoff time.Duration) {
+			backoffs = append(backoffs, backoff)
+		},
+	})
+
+	execute := func(ferr, want error) {
+		t.Helper()
+		if err := b.Execute(func() error { return ferr }); err != want {
+			t.Fatalf("expected: %v, got: %v", want, err)
+		}
+	}
+
+	// the breaker is tripped with the current backoff
+	b.Trip(0)
+	if got, want := b.ClosedUntil(), c.Now().Add(startBackoff); !got.Equal(want) {
+		t.Fatalf("expected: %s, got: %s", want, got)
+	}
+	execute(nil, breaker.ErrClosed)
+
+	// the failed probe closes it with the doubled backoff
+	c.Advance(startBackoff)
+	execute(testError, testError)
+	if got, want := b.ClosedUntil(), c.Now().Add(2*startBackoff); !got.Equal(want) {
+		t.Fatalf("expected: %s, got: %s", want, got)
+	}
+
+	// the successful probe opens it and the failures are counted again
+	c.Advance(2 * startBackoff)
+	execute(nil, nil)
+	execute(testError, testError)
+	execute(nil, nil)
+	execute(testError, testError)
+	execute(testError, testError)
+	execute(nil, breaker.ErrClosed)
+	if got, want := b.ClosedUntil(), c.Now().Add(2*startBackoff); !got.Equal(want) {
+		t.Fatalf("expected: %s, got: %s", want, got)
+	}
+
+	// the breaker is tripped with the given backoff, also when it is closed
+	b.Trip(10 * time.Minute)
+	if got, want := b.ClosedUntil(), c.Now().Add(10*time.Minute); !got.Equal(want) {
+		t.Fatalf("expected: %s, got: %s", want, got)
+	}
+	c.Advance(9 * time.Minute)
+	execute(nil, breaker.ErrClosed)
+	c.Advance(time.Minute)
+	execute(nil, nil)
+
+	want := []time.Duration{startBackoff, 2 * startBackoff, 2 * startBackoff, 10 * time.Minute}
+	if !reflect.DeepEqual(backoffs, want) {
+		t.Fatalf("expected backoffs: %v, got: %v", want, backoffs)
+	}
+}
+
+func TestState(t *testing.T) {
+	testError := errors.New("test error")
+	startBackoff := 1 * time.Minute
+	c := clock.NewMock(time.Now())
+	b := newBreaker(t, breaker.Options{
+		Limit:        2,
+		StartBackoff: startBackoff,
+		Clock:        c,
+	})
+
+	expectState := func(want breaker.State) {
+		t.Helper()
+		if got := b.State(); got != want {
+			t.Fatalf("expected state: %s, got: %s", want, got)
+		}
+	}
+	expectStats := func(want breaker.Stats) {
+		t.Helper()
+		if got := b.Stats(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("expected stats: %+v, got: %+v", want, got)
+		}
+	}
+
+	expectState(breaker.StateOpen)
+	expectStats(breaker.Stats{})
+
+	_ = b.Execute(func() error { return nil })
+	_ = b.Execute(func() error { return testError })
+	firstFailure := c.Now()
+	expectState(breaker.StateOpen)
+	expectStats(breaker.Stats{ConsecutiveFailures: 1, Executions: 2, Failures: 1, LastFailure: firstFailure})
+
+	c.Advance(time.Second)
+	_ = b.Execute(func() error { return testError })
+	lastFailure := c.Now()
+	trips := []breaker.TripEvent{{Timestamp: lastFailure, Backoff: startBackoff}}
+	expectState(breaker.StateClosed)
+	expectStats(breaker.Stats{ConsecutiveFailures: 2, Executions: 3, Failures: 2, LastFailure: lastFailure, Trips: trips})
+
+	// the rejected calls are not executed
+	_ = b.Execute(func() error { return nil })
+	expectStats(breaker.Stats{ConsecutiveFailures: 2, Executions: 3, Failures: 2, LastFailure: lastFailure, Trips: trips})
+
+	// the breaker stays closed until the probe is executed
+	c.Advance(startBackoff)
+	expectState(breaker.StateClosed)
+
+	probing, release := make(chan struct{}), make(chan struct{})
+	probed := make(chan error, 1)
+	go func() {
+		probed <- b.Execute(func() error {
+			close(probing)
+			<-release
+			return testError
+		})
+	}()
+	<-probing
+	expectState(breaker.StateHalfOpen)
+	close(release)
+	<-probed
+	lastFailure = c.Now()
+	trips = append([]breaker.TripEvent{{Timestamp: lastFailure, Backoff: 2 * startBackoff}}, trips...)
+	expectState(breaker.StateClosed)
+	expectStats(breaker.Stats{ConsecutiveFailures: 2, Executions: 4, Failures: 3, LastFailure: lastFailure, Trips: trips})
+
+	c.Advance(2 * startBackoff)
+	_ = b.Execute(func() error { return nil })
+	expectState(breaker.StateOpen)
+	expectStats(breaker.Stats{ConsecutiveFailures: 0, Executions: 5, Failures: 3, LastFailure: lastFailure, Trips: trips})
+
+	b.Trip(0)
+	trips = append([]breaker.TripEvent{{Timestamp: c.Now(), Backoff: 2 * startBackoff}}, trips...)
+	expectState(breaker.StateClosed)
+	b.Reset()
+	expectState(breaker.StateOpen)
+	expectStats(breaker.Stats{ConsecutiveFailures: 0, Executions: 5, Failures: 3, LastFailure: lastFailure, Trips: trips})
+}
+
+func TestTripHistory(t *testing.T) {
+	c := clock.NewMock(time.Now())
+	b := newBreaker(t, breaker.Options{TripHistory: 3, Clock: c})
+
+	var want []breaker.TripEvent
+	for i := 1; i <= 5; i++ {
+		backoff := time.Duration(i) * time.Minute
+		b.Trip(backoff)
+		want = append([]breaker.TripEvent{{Timestamp: c.Now(), Backoff: backoff}}, want...)
+		b.Reset()
+		c.Advance(time.Second)
+
+		if len(want) > 3 {
+			want = want[:3]
+		}
+		if got := b.Stats().Trips; !reflect.DeepEqual(got, want) {
+			t.Fatalf("trip %d: expected trips: %+v, got: %+v", i, want, got)
+		}
+	}
+
+	// the returned trips are a copy
+	b.Stats().Trips[0] = breaker.TripEvent{}
+	if got := b.Stats().Trips; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected trips: %+v, got: %+v", want, got)
+	}
+
+	// the default size
+	b = newBreaker(t, breaker.Options{})
+	for i := 0; i < 20; i++ {
+		b.Trip(0)
+		b.Reset()
+	}
+	if got := len(b.Stats().Trips); got != 16 {
+		t.Fatalf("expected %d trips, got: %d", 16, got)
+	}
+}
+
+func TestStateString(t *testing.T) {
+	for state, want := range map[breaker.State]string{
+		breaker.StateOpen:     "open",
+		breaker.StateClosed:   "closed",
+		breaker.StateHalfOpen: "half-open",
+		breaker.State(10):     "State(10)",
+	} {
+		if got := state.String(); got != want {
+			t.Errorf("expected: %q, got: %q", want, got)
+		}
+	}
+}
+
+func TestFailureRate(t *testing.T) {
+	testError := errors.New("test error")
+	failInterval := 10 * time.Minute
+	startBackoff := 1 * time.Minute
+	c := clock.NewMock(time.Now())
+
+	b := newBreaker(t, breaker.Options{
+		FailureRateThreshold: 0.5,
+		MinimumCalls:         4,
+		FailInterval:         failInterval,
+		StartBackoff:         startBackoff,
+		Clock:                c,
+	})
+
+	execute := func(ferr, want error) {
+		t.Helper()
+		if err := b.Execute(func() error { return ferr }); err != want {
+			t.Fatalf("expected: %v, got: %v", want, err)
+		}
+		c.Advance(time.Second)
+	}
+
+	// the failures before the minimum number of calls do not close the breaker
+	execute(testError, testError)
+	execute(testError, testError)
+	execute(testError, testError)
+
+	// the failures expire from the window
+	c.Advance(failInterval)
+	execute(nil, nil)
+	execute(nil, nil)
+	execute(testError, testError)
+	execute(testError, testError) // 2 of 4 failed calls
+
+	// the successes do not reset the failures
+	execute(nil, nil)
+	execute(testError, testError) // 3 of 6 failed calls
+	execute(testError, testError) // 4 of 7 failed calls
+	execute(nil, breaker.ErrClosed)
+	if got := b.State(); got != breaker.StateClosed {
+		t.Fatalf("expected state: %s, got: %s", breaker.StateClosed, got)
+	}
+
+	// the successful probe opens the breaker with an empty window
+	c.Advance(startBackoff)
+	execute(nil, nil)
+	execute(testError, testError)
+	execute(testError, testError)
+	execute(testError, testError)
+	execute(testError, testError) // 4 of 4 failed calls
+	execute(nil, breaker.ErrClosed)
+}
+
+func TestSlidingWindow(t *testing.T) {
+	testError := errors.New("test error")
+	failInterval := 10 * time.Minute
+
+	for _, tc := range []struct {
+		name          string
+		slidingWindow bool
+		interval      time.Duration // between the calls
+		results       []error
+		wantClosedAt  int // the index of the call which closes the breaker, -1 if none
+	}{
+		{
+			name:          "failures between successes",
+			slidingWindow: true,
+			interval:      time.Minute,
+			results:       []error{testError, nil, testError, nil, testError, nil, testError, nil, testError, nil},
+			wantClosedAt:  8,
+		},
+		{
+			name:         "failures between successes counted consecutively",
+			interval:     time.Minute,
+			results:      []error{testError, nil, testError, nil, testError, nil, testError, nil, testError, nil},
+			wantClosedAt: -1,
+		},
+		{
+			name:          "failures expire from the window",
+			slidingWindow: true,
+			interval:      3 * time.Minute,
+			results:       []error{testError, testError, testError, testError, testError, testError, testError, testError, testError, testError},
+			wantClosedAt:  -1,
+		},
+		{
+			name:          "consecutive failures",
+			slidingWindow: true,
+			interval:      time.Second,
+			results:       []error{nil, testError, testError, testError, testError, testError, nil},
+			wantClosedAt:  5,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := clock.NewMock(time.Now())
+			b := newBreaker(t, breaker.Options{
+				Limit:         5,
+				FailInterval:  failInterval,
+				SlidingWindow: tc.slidingWindow,
+				Clock:         c,
+			})
+
+			closedAt := -1
+			for i, ferr := range tc.results {
+				if err := b.Execute(func() error { return ferr }); err == breaker.ErrClosed {
+					t.Fatalf("expected the call %d to be executed", i)
+				}
+				if closedAt == -1 && b.State() == breaker.StateClosed {
+					closedAt = i
+					break
+				}
+				c.Advance(tc.interval)
+			}
+			if closedAt != tc.wantClosedAt {
+				t.Fatalf("expected the breaker closed by the call %d, got: %d", tc.wantClosedAt, closedAt)
+			}
+		})
+	}
+}
+
+func TestMaxInflight(t *testing.T) {
+	const maxInflight = 5
+	b := newBreaker(t, breaker.Options{MaxInflight: maxInflight})
+
+	// fill the slots of the calls in flight
+	var entered sync.WaitGroup
+	entered.Add(maxInflight)
+	release := make(chan struct{})
+	errs := make(chan error, maxInflight)
+	for i := 0; i < maxInflight; i++ {
+		go func() {
+			errs <- b.Execute(func() error {
+				entered.Done()
+				<-release
+				return nil
+			})
+		}()
+	}
+	entered.Wait()
+
+	if err := b.Execute(func() error { return nil }); err != breaker.ErrTooManyRequests {
+		t.Fatalf("expected: %v, got: %v", breaker.ErrTooManyRequests, err)
+	}
+
+	close(release)
+	for i := 0; i < maxInflight; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+
+	// the slot of the call which panicked is released
+	for i := 0; i < maxInflight+1; i++ {
+		func() {
+			defer func() { _ = recover() }()
+			_ = b.Execute(func() error { panic("test panic") })
+		}()
+	}
+	if err := b.Execute(func() error { return nil }); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+}
+
+func TestMaxInflightWait(t *testing.T) {
+	const (
+		maxInflight = 5
+		calls       = 50
+	)
+	b := newBreaker(t, breaker.Options{MaxInflight: maxInflight, WaitInflight: true})
+
+	// the calls wait until the slots are full, so that the cap is reached
+	var inflight, maxSeen, entered int32
+	full := make(chan struct{})
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			err := b.ExecuteCtx(context.Background(), func(context.Context) error {
+				n := atomic.AddInt32(&inflight, 1)
+				for {
+					max := atomic.LoadInt32(&maxSeen)
+					if n <= max || atomic.CompareAndSwapInt32(&maxSeen, max, n) {
+						break
+					}
+				}
+				if atomic.AddInt32(&entered, 1) == maxInflight {
+					close(full)
+				}
+				<-full
+				atomic.AddInt32(&inflight, -1)
+				return nil
+			})
+			if err != nil {
+				t.Errorf("expected no error, got: %v", err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if maxSeen != maxInflight {
+		t.Fatalf("expected %d calls in flight at most, got: %d", maxInflight, maxSeen)
+	}
+
+	// the waiting call returns when the context is done
+	release := make(chan struct{})
+	var started sync.WaitGroup
+	started.Add(maxInflight)
+	for i := 0; i < maxInflight; i++ {
+		go func() {
+			_ = b.Execute(func() error {
+				started.Done()
+				<-release
+				return nil
+			})
+		}()
+	}
+	started.Wait()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := b.ExecuteCtx(ctx, func(context.Context) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected: %v, got: %v", context.Canceled, err)
+	}
+}
+
+func TestExecuteWithFallback(t *testing.T) {
+	testError := errors.New("test error")
+	fallbackError := errors.New("fallback error")
+	b := newBreaker(t, breaker.Options{Limit: 2})
+
+	var fallbacks int
+	fallback := func() error {
+		fallbacks++
+		return fallbackError
+	}
+
+	// the fallback is not called for the failures of f()
+	for i := 0; i < 2; i++ {
+		if err := b.ExecuteWithFallback(func() error { return testError }, fallback); err != testError {
+			t.Fatalf("expected: %v, got: %v", testError, err)
+		}
+	}
+	if fallbacks != 0 {
+		t.Fatalf("expected no fallbacks, got: %d", fallbacks)
+	}
+
+	// nor for the errors of f() which are the errors of the closed breakers
+	b2 := newBreaker(t, breaker.Options{})
+	if err := b2.ExecuteWithFallback(func() error { return breaker.ErrClosed }, fallback); err != breaker.ErrClosed {
+		t.Fatalf("expected: %v, got: %v", breaker.ErrClosed, err)
+	}
+	if fallbacks != 0 {
+		t.Fatalf("expected no fallbacks, got: %d", fallbacks)
+	}
+
+	// the fallback is called instead of f() while the breaker is closed
+	for i := 0; i < 3; i++ {
+		if err := b.ExecuteCtxWithFallback(context.Background(), func(context.Context) error {
+			t.Fatal("f called while the breaker is closed")
+			return nil
+		}, fallback); err != fallbackError {
+			t.Fatalf("expected: %v, got: %v", fallbackError, err)
+		}
+	}
+	if fallbacks != 3 {
+		t.Fatalf("expected %d fallbacks, got: %d", 3, fallbacks)
+	}
+
+	// the results of the fallbacks are not counted
+	if got := b.Stats(); got.Executions != 2 || got.Failures != 2 {
+		t.Fatalf("expected %d executions and %d failures, got: %+v", 2, 2, got)
+	}
+}
+
+func TestExecuteAsync(t *testing.T) {
+	testError := errors.New("test error")
+	c := clock.NewMock(time.Now())
+	b := newBreaker(t, breaker.Options{Limit: 2, Clock: c})
+
+	if err := <-b.ExecuteAsync(func() error { return nil }); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// the failure is counted when f() returns
+	release := make(chan struct{})
+	errs := b.ExecuteAsync(func() error {
+		<-release
+		return testError
+	})
+	if got := b.Stats().Failures; got != 0 {
+		t.Fatalf("expected %d failures, got: %d", 0, got)
+	}
+	close(release)
+	if err := <-errs; err != testError {
+		t.Fatalf("expected: %v, got: %v", testError, err)
+	}
+	if got := b.Stats().Failures; got != 1 {
+		t.Fatalf("expected %d failures, got: %d", 1, got)
+	}
+
+	// the panic is a failure, which closes the breaker
+	if err := <-b.ExecuteAsync(func() error { panic("test panic") }); !errors.Is(err, breaker.ErrPanic) {
+		t.Fatalf("expected: %v, got: %v", breaker.ErrPanic, err)
+	}
+	if got := b.State(); got != breaker.StateClosed {
+		t.Fatalf("expected state: %s, got: %s", breaker.StateClosed, got)
+	}
+	if err := <-b.ExecuteAsync(func() error { return nil }); err != breaker.ErrClosed {
+		t.Fatalf("expected: %v, got: %v", breaker.ErrClosed, err)
+	}
+}
+
+func TestExecuteAsyncPanicIsFailure(t *testing.T) {
+	b := newBreaker(t, breaker.Options{
+		Limit:     1,
+		IsFailure: func(error) bool { return false },
+	})
+
+	if err := <-b.ExecuteAsync(func() error { panic("test panic") }); !errors.Is(err, breaker.ErrPanic) {
+		t.Fatalf("expected: %v, got: %v", breaker.ErrPanic, err)
+	}
+	if got := b.State(); got != breaker.StateClosed {
+		t.Fatalf("expected state: %s, got: %s", breaker.StateClosed, got)
+	}
+}
+
+func TestExecuteAsyncConcurrent(t *testing.T) {
+	const calls = 100
+	testError := errors.New("test error")
+	b := newBreaker(t, breaker.Options{Limit: 4 * calls})
+
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		f := func() error { return nil }
+		if i%2 == 0 {
+			f = func() error { return testError }
+		}
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			<-start
+			_ = b.Execute(f)
+		}()
+		go func() {
+			defer wg.Done()
+			<-start
+			_ = <-b.ExecuteAsync(f)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	s := b.Stats()
+	if s.Executions != 2*calls {
+		t.Fatalf("expected %d executions, got: %d", 2*calls, s.Executions)
+	}
+	if s.Failures != calls {
+		t.Fatalf("expected %d failures, got: %d", calls, s.Failures)
+	}
+	if got := b.State(); got != breaker.StateOpen {
+		t.Fatalf("expected state: %s, got: %s", breaker.StateOpen, got)
+	}
+}
+
+func TestSlowCall(t *testing.T) {
+	testError := errors.New("test error")
+	slowCall := time.Second
+	c := clock.NewMock(time.Now())
+	b := newBreaker(t, breaker.Options{
+		Limit:            2,
+		SlowCallDuration: slowCall,
+		Clock:            c,
+	})
+
+	execute := func(d time.Duration, ferr error) {
+		t.Helper()
+
+		if err := b.Execute(func() error {
+			c.Advance(d)
+			return ferr
+		}); err != ferr {
+			t.Fatalf("expected: %v, got: %v", ferr, err)
+		}
+	}
+	expectStats := func(consecutiveFailures int, failures, slowCalls uint64) {
+		t.Helper()
+
+		s := b.Stats()
+		if s.ConsecutiveFailures != consecutiveFailures || s.Failures != failures || s.SlowCalls != slowCalls {
+			t.Fatalf("expected consecutive failures %d, failures %d and slow calls %d, got: %+v", consecutiveFailures, failures, slowCalls, s)
+		}
+	}
+
+	// the slow call is a failure which returns no error
+	execute(2*slowCall, nil)
+	expectStats(1, 0, 1)
+
+	// the calls within the duration are not slow
+	execute(slowCall, nil)
+	expectStats(0, 0, 1)
+
+	// the slow call with an error is counted once
+	execute(2*slowCall, testError)
+	expectStats(1, 1, 1)
+
+	execute(2*slowCall, nil)
+	expectStats(2, 1, 2)
+	if got := b.State(); got != breaker.StateClosed {
+		t.Fatalf("expected state: %s, got: %s", breaker.StateClosed, got)
+	}
+}
+
+func TestBackoffError(t *testing.T) {
+	testError := errors.New("test error")
+	startBackoff := time.Minute
+	maxBackoff := 30 * time.Minute
+	c := clock.NewMock(time.Now())
+	b := newBreaker(t, breaker.Options{
+		Limit:        1,
+		StartBackoff: startBackoff,
+		MaxBackoff:   maxBackoff,
+		Clock:        c,
+	})
+
+	execute := func(ferr error) {
+		t.Helper()
+
+		err := b.Execute(func() error { return ferr })
+		if err != ferr {
+			t.Fatalf("expected: %v, got: %v", ferr, err)
+		}
+		if errors.Unwrap(err) != testError {
+			t.Fatalf("expected wrapped: %v, got: %v", testError, errors.Unwrap(err))
+		}
+	}
+	expectRemaining := func(want time.Duration) {
+		t.Helper()
+
+		if got := b.Remaining(); got != want {
+			t.Fatalf("expected remaining: %s, got: %s", want, got)
+		}
+	}
+
+	// the backoff of the error closes the breaker
+	execute(&breaker.BackoffError{Err: testError, Backoff: 5 * time.Minute})
+	expectRemaining(5 * time.Minute)
+
+	// the backoff of the failed probe is capped by the max backoff
+	c.Advance(5 * time.Minute)
+	execute(&breaker.BackoffError{Err: testError, Backoff: 2 * maxBackoff})
+	expectRemaining(maxBackoff)
+
+	// the plain errors keep the backoff of the breaker, which is not doubled
+	c.Advance(maxBackoff)
+	if err := b.Execute(func() error { return nil }); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := b.Execute(func() error { return testError }); err != testError {
+		t.Fatalf("expected: %v, got: %v", testError, err)
+	}
+	expectRemaining(startBackoff)
+	c.Advance(startBackoff)
+	if err := b.Execute(func() error { return testError }); err != testError {
+		t.Fatalf("expected: %v, got: %v", testError, err)
+	}
+	expectRemaining(2 * startBackoff)
+}
+
+func TestCallTimeout(t *testing.T) {
+	testError := errors.New("test error")
+	callTimeout := 10 * time.Second
+	c := clock.NewMock(time.Now())
+	b := newBreaker(t, breaker.Options{
+		Limit:       2,
+		CallTimeout: callTimeout,
+		// the timeouts are failures regardless of IsFailure
+		IsFailure: func(err error) bool { return !errors.Is(err, context.DeadlineExceeded) },
+		Clock:     c,
+	})
+
+	// the calls which return in time are not affected
+	if err := b.Execute(func() error { return testError }); err != testError {
+		t.Fatalf("expected: %v, got: %v", testError, err)
+	}
+	if err := b.Execute(func() error { return nil }); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// the call which overruns the timeout is a failure once the timeout elapses
+	started, release, returned := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	errs := make(chan error, 1)
+	go func() {
+		errs <- b.Execute(func() error {
+			defer close(returned)
+			close(started)
+			<-release
+			return testError
+		})
+	}()
+	<-started
+	c.Advance(callTimeout)
+	if err := <-errs; err != context.DeadlineExceeded {
+		t.Fatalf("expected: %v, got: %v", context.DeadlineExceeded, err)
+	}
+	expectFailures := func(consecutiveFailures int, failures uint64) {
+		t.Helper()
+
+		s := b.Stats()
+		if s.ConsecutiveFailures != consecutiveFailures || s.Failures != failures {
+			t.Fatalf("expected %d consecutive failures and %d failures, got: %+v", consecutiveFailures, failures, s)
+		}
+	}
+	expectFailures(1, 2)
+
+	// the late result is discarded
+	close(release)
+	<-returned
+	expectFailures(1, 2)
+	if got := b.Stats().Executions; got != 3 {
+		t.Fatalf("expected %d executions, got: %d", 3, got)
+	}
+}
+
+func TestCallTimeoutConcurrent(t *testing.T) {
+	const calls = 50
+	testError := errors.New("test error")
+	b := newBreaker(t, breaker.Options{
+		Limit:       4 * calls,
+		CallTimeout: time.Millisecond,
+	})
+
+	// the calls return around the timeout, so that the late results race
+	// with the timeouts
+	var wg sync.WaitGroup
+	var returned sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		i := i
+		wg.Add(1)
+		returned.Add(1)
+		go func() {
+			defer wg.Done()
+			err := b.Execute(func() error {
+				defer returned.Done()
+				time.Sleep(time.Duration(i%3) * time.Millisecond)
+				if i%2 == 0 {
+					return testError
+				}
+				return nil
+			})
+			if err != nil && err != testError && err != context.DeadlineExceeded {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	returned.Wait()
+
+	// every call is counted once
+	if got := b.Stats().Executions; got != calls {
+		t.Fatalf("expected %d executions, got: %d", calls, got)
+	}
+}
+
+func TestCallTimeoutPanic(t *testing.T) {
+	b := newBreaker(t, breaker.Options{CallTimeout: time.Minute})
+
+	defer func() {
+		if r := recover(); r != "test panic" {
+			t.Fatalf("expected the panic of f, got: %v", r)
+		}
+	}()
+	_ = b.Execute(func() error { panic("test panic") })
+}
+
+func TestCallTimeoutContext(t *testing.T) {
+	callTimeout := 10 * time.Second
+	c := clock.NewMock(time.Now())
+	b := newBreaker(t, breaker.Options{
+		CallTimeout: callTimeout,
+		MaxInflight: 1,
+		Clock:       c,
+	})
+
+	// the context of f() has the deadline of the call timeout and it is
+	// canceled when the timeout elapses
+	started, release, returned := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	errs := make(chan error, 1)
+	go func() {
+		errs <- b.ExecuteCtx(context.Background(), func(ctx context.Context) error {
+			defer close(returned)
+			if deadline, ok := ctx.Deadline(); !ok || !deadline.Equal(c.Now().Add(callTimeout)) {
+				t.Errorf("expected the deadline %v, got: %v", c.Now().Add(callTimeout), deadline)
+			}
+			close(started)
+			<-ctx.Done()
+			<-release
+			return ctx.Err()
+		})
+	}()
+	<-started
+	c.Advance(callTimeout)
+	if err := <-errs; err != context.DeadlineExceeded {
+		t.Fatalf("expected: %v, got: %v", context.DeadlineExceeded, err)
+	}
+
+	// the call holds the slot of the calls in flight until f() returns
+	if err := b.Execute(func() error { return nil }); err != breaker.ErrTooManyRequests {
+		t.Fatalf("expected: %v, got: %v", breaker.ErrTooManyRequests, err)
+	}
+	close(release)
+	<-returned
+	for {
+		err := b.Execute(func() error { return nil })
+		if err == nil {
+			break
+		}
+		if err != breaker.ErrTooManyRequests {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestNewBreakerInvalidOptions(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		options breaker.Options
+	}{
+		{name: "negative limit", options: breaker.Options{Limit: -1}},
+		{name: "negative fail interval", options: breaker.Options{FailInterval: -time.Minute}},
+		{name: "negative start backoff", options: breaker.Options{StartBackoff: -time.Minute}},
+		{name: "negative max backoff", options: breaker.Options{MaxBackoff: -time.Minute}},
+		{name: "negative backoff reset after", options: breaker.Options{BackoffResetAfter: -time.Minute}},
+		{name: "start backoff greater than max backoff", options: breaker.Options{StartBackoff: 2 * time.Minute, MaxBackoff: time.Minute}},
+		{name: "start backoff greater than default max backoff", options: breaker.Options{StartBackoff: 2 * time.Hour}},
+		{name: "default start backoff greater than max backoff", options: breaker.Options{MaxBackoff: time.Minute}},
+		{name: "negative minimum calls", options: breaker.Options{FailureRateThreshold: 0.5, MinimumCalls: -1}},
+		{name: "negative success threshold", options: breaker.Options{SuccessThreshold: -1}},
+		{name: "limit and failure rate", options: breaker.Options{Limit: 10, FailureRateThreshold: 0.5}},
+		{name: "negative failure rate", options: breaker.Options{FailureRateThreshold: -0.5}},
+		{name: "failure rate of 1", options: breaker.Options{FailureRateThreshold: 1}},
+		{name: "minimum calls without failure rate", options: breaker.Options{MinimumCalls: 10}},
+		{name: "negative jitter", options: breaker.Options{Jitter: -0.1}},
+		{name: "sliding window and failure rate", options: breaker.Options{SlidingWindow: true, FailureRateThreshold: 0.5}},
+		{name: "negative slow call duration", options: breaker.Options{SlowCallDuration: -time.Second}},
+		{name: "negative call timeout", options: breaker.Options{CallTimeout: -time.Second}},
+		{name: "negative trip history", options: breaker.Options{TripHistory: -1}},
+		{name: "negative max inflight", options: breaker.Options{MaxInflight: -1}},
+		{name: "wait inflight without max inflight", options: breaker.Options{WaitInflight: true}},
+		{name: "jitter above 1", options: breaker.Options{Jitter: 1.1}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := breaker.NewBreaker(tc.options); !errors.Is(err, breaker.ErrInvalidOptions) {
+				t.Fatalf("expected: %v, got: %v", breaker.ErrInvalidOptions, err)
+			}
+		})
+	}
+}
+
+func TestIsFailure(t *testing.T) {
+	testError := errors.New("test error")
+	startBackoff := 1 * time.Minute
+	c := clock.NewMock(time.Now())
+	b := newBreaker(t, breaker.Options{
+		Limit:        2,
+		StartBackoff: startBackoff,
+		Clock:        c,
+		IsFailure: func(err error) bool {
+			return !errors.Is(err, context.Canceled)
+		},
+	})
+
+	execute := func(ferr, want error) {
+		t.Helper()
+		if err := b.Execute(func() error { return ferr }); err != want {
+			t.Fatalf("expected: %v, got: %v", want, err)
+		}
+	}
+
+	// the ignored errors neither count as failures nor reset them
+	execute(testError, testError)
+	execute(context.Canceled, context.Canceled)
+	execute(context.Canceled, context.Canceled)
+	if got := b.Stats(); got.ConsecutiveFailures != 1 || got.Failures != 1 || got.Executions != 3 {
+		t.Fatalf("expected 1 consecutive failure of 3 executions, got: %+v", got)
+	}
+	execute(testError, testError)
+	execute(nil, breaker.ErrClosed)
+
+	// the probe with an ignored error leaves the next call to probe
+	c.Advance(startBackoff)
+	execute(context.Canceled, context.Canceled)
+	if got := b.State(); got != breaker.StateHalfOpen {
+		t.Fatalf("expected state: %s, got: %s", breaker.StateHalfOpen, got)
+	}
+	if got, want := b.ClosedUntil(), c.Now(); got.After(want) {
+		t.Fatalf("expected the backoff to have elapsed at %s, got: %s", want, got)
+	}
+	execute(nil, nil)
+
+	// the successes reset the failures
+	execute(testError, testError)
+	execute(nil, nil)
+	execute(testError, testError)
+	execute(nil, nil)
+}
+
+type transition struct {
+	from, to breaker.State
+}
+
+func TestFailureWeight(t *testing.T) {
+	var (
+		errSevere     = errors.New("severe error")
+		errMild       = errors.New("mild error")
+		errUncounted  = errors.New("uncounted error")
+		errNotFailure = errors.New("not a failure")
+	)
+	startBackoff := time.Minute
+	c := clock.NewMock(time.Now())
+	var weighed []error
+	b := newBreaker(t, breaker.Options{
+		Limit:        5,
+		StartBackoff: startBackoff,
+		Clock:        c,
+		IsFailure:    func(err error) bool { return err != errNotFailure },
+		FailureWeight: func(err error) int {
+			weighed = append(weighed, err)
+			switch err {
+			case errSevere:
+				return 4
+			case errUncounted:
+				return 0
+			}
+			return 1
+		},
+	})
+
+	execute := func(ferr error, consecutiveFailures int) {
+		t.Helper()
+
+		if err := b.Execute(func() error { return ferr }); err != ferr {
+			t.Fatalf("expected: %v, got: %v", ferr, err)
+		}
+		if got := b.Stats().ConsecutiveFailures; got != consecutiveFailures {
+			t.Fatalf("expected %d consecutive failures, got: %d", consecutiveFailures, got)
+		}
+	}
+
+	execute(errMild, 1)
+	execute(errMild, 2)
+
+	// the failures of zero weight neither count nor reset the failures
+	execute(errUncounted, 2)
+
+	// the errors which are not failures are not weighed
+	execute(errNotFailure, 2)
+	if want := []error{errMild, errMild, errUncounted}; !reflect.DeepEqual(weighed, want) {
+		t.Fatalf("expected weighed errors: %v, got: %v", want, weighed)
+	}
+	if got := b.State(); got != breaker.StateOpen {
+		t.Fatalf("expected state: %s, got: %s", breaker.StateOpen, got)
+	}
+
+	// the severe failure exceeds the limit
+	c.Advance(time.Second)
+	execute(errSevere, 6)
+	if got := b.State(); got != breaker.StateClosed {
+		t.Fatalf("expected state: %s, got: %s", breaker.StateClosed, got)
+	}
+	if got, want := b.ClosedUntil(), c.Now().Add(startBackoff); !got.Equal(want) {
+		t.Fatalf("expected: %s, got: %s", want, got)
+	}
+}
+
+func TestOnStateChange(t *testing.T) {
+	testError := errors.New("test error")
+	startBackoff := 1 * time.Minute
+	c := clock.NewMock(time.Now())
+
+	transitions := make(chan transition, 100)
+	b := newBreaker(t, breaker.Options{
+		Limit:        1,
+		StartBackoff: startBackoff,
+		Clock:        c,
+		OnStateChange: func(from, to breaker.State) {
+			transitions <- transition{from: from, to: to}
+		},
+	})
+
+	_ = b.Execute(func() error { return testError })
+	_ = b.Execute(func() error { return nil })
+	c.Advance(startBackoff)
+	_ = b.Execute(func() error { return testError })
+	c.Advance(2 * startBackoff)
+
+	// only one of the concurrent calls is the probe
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = b.Execute(func() error { return nil })
+		}()
+	}
+	wg.Wait()
+
+	b.Trip(0)
+	b.Trip(0)
+	b.Reset()
+	b.Reset()
+
+	expectTransitions(t, transitions, []transition{
+		{from: breaker.StateOpen, to: breaker.StateClosed},
+		{from: breaker.StateClosed, to: breaker.StateHalfOpen},
+		{from: breaker.StateHalfOpen, to: breaker.StateClosed},
+		{from: breaker.StateClosed, to: breaker.StateHalfOpen},
+		{from: breaker.StateHalfOpen, to: breaker.StateOpen},
+		{from: breaker.StateOpen, to: breaker.StateClosed},
+		{from: breaker.StateClosed, to: breaker.StateOpen},
+	})
+}
+
+func TestOnStateChangeReentrant(t *testing.T) {
+	testError := errors.New("test error")
+	transitions := make(chan transition, 100)
+
+	var b breaker.Interface
+	b = newBreaker(t, breaker.Options{
+		Limit: 1,
+		OnStateChange: func(from, to breaker.State) {
+			transitions <- transition{from: from, to: to}
+			// the breaker can be used from the callback
+			if to == breaker.StateClosed && b.State() == breaker.StateClosed {
+				b.Reset()
+			}
+		},
+	})
+
+	_ = b.Execute(func() error { return testError })
+	if err := b.Execute(func() error { return nil }); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	expectTransitions(t, transitions, []transition{
+		{from: breaker.StateOpen, to: breaker.StateClosed},
+		{from: breaker.StateClosed, to: breaker.StateOpen},
+	})
+}
+
+func expectTransitions(t *testing.T, transitions chan transition, want []transition) {
+	t.Helper()
+
+	var got []transition
+	for done := false; !done; {
+		select {
+		case tr := <-transitions:
+			got = append(got, tr)
+		default:
+			done = true
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected transitions: %v, got: %v", want, got)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	testError := errors.New("test error")
+	startBackoff := 1 * time.Minute
+	c := clock.NewMock(time.Now())
+	m := breaker.NewMetrics()
+
+	b := newBreaker(t, breaker.Options{
+		Limit:        2,
+		StartBackoff: startBackoff,
+		Clock:        c,
+		Metrics:      m,
+		Name:         "test",
+	})
+	// the metrics of the other breakers are not affected
+	_ = newBreaker(t, breaker.Options{Metrics: m, Name: "other"})
+
+	expectMetric := func(name string, c prometheus.Collector, want float64) {
+		t.Helper()
+		if got := testutil.ToFloat64(c); got != want {
+			t.Errorf("expected %s: %v, got: %v", name, want, got)
+		}
+	}
+
+	_ = b.Execute(func() error { return nil })
+	_ = b.Execute(func() error { return testError })
+	_ = b.Execute(func() error { return testError })
+	_ = b.Execute(func() error { return nil }) // not executed
+	expectMetric("state", m.State.WithLabelValues("test"), float64(breaker.StateClosed))
+
+	c.Advance(startBackoff)
+	_ = b.Execute(func() error { return testError })
+	c.Advance(2 * startBackoff)
+	_ = b.Execute(func() error { return nil })
+
+	expectMetric("executions", m.ExecutionCount.WithLabelValues("test"), 5)
+	expectMetric("failures", m.FailureCount.WithLabelValues("test"), 3)
+	expectMetric("trips", m.TripCount.WithLabelValues("test"), 2)
+	expectMetric("state", m.State.WithLabelValues("test"), float64(breaker.StateOpen))
+	expectMetric("other state", m.State.WithLabelValues("other"), float64(breaker.StateOpen))
+	expectMetric("other executions", m.ExecutionCount.WithLabelValues("other"), 0)
+
+	// the breaker was closed for the backoff and for the doubled one
+	const closedTime = `
+		# HELP bee_breaker_closed_time Time in seconds the breaker stayed closed.
+		# TYPE bee_breaker_closed_time histogram
+		bee_breaker_closed_time_bucket{breaker="test",le="1"} 0
+		bee_breaker_closed_time_bucket{breaker="test",le="10"} 0
+		bee_breaker_closed_time_bucket{breaker="test",le="60"} 1
+		bee_breaker_closed_time_bucket{breaker="test",le="120"} 2
+		bee_breaker_closed_time_bucket{breaker="test",le="300"} 2
+		bee_breaker_closed_time_bucket{breaker="test",le="600"} 2
+		bee_breaker_closed_time_bucket{breaker="test",le="1800"} 2
+		bee_breaker_closed_time_bucket{breaker="test",le="3600"} 2
+		bee_breaker_closed_time_bucket{breaker="test",le="+Inf"} 2
+		bee_breaker_closed_time_sum{breaker="test"} 180
+		bee_breaker_closed_time_count{breaker="test"} 2
+	`
+	if err := testutil.CollectAndCompare(m.ClosedTime, strings.NewReader(closedTime)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	testError := errors.New("test error")
+	startBackoff := 1 * time.Minute
+	jitter := 0.5
+	c := clock.NewMock(time.Now())
+
+	var backoffs []time.Duration
+	b := newBreaker(t, breaker.Options{
+		Limit:        1,
+		StartBackoff: startBackoff,
+		Jitter:       jitter,
+		Rand:         rand.New(rand.NewSource(1)),
+		Clock:        c,
+		OnClose: func(backoff time.Duration) {
+			backoffs = append(backoffs, backoff)
+		},
+	})
+
+	// the same sequence of the random numbers as the breaker
+	r := rand.New(rand.NewSource(1))
+	jittered := func(backoff time.Duration) time.Duration {
+		return backoff + time.Duration(jitter*(2*r.Float64()-1)*float64(backoff))
+	}
+
+	var want []time.Duration
+	for _, backoff := range []time.Duration{startBackoff, 2 * startBackoff, 4 * startBackoff} {
+		if err := b.Execute(func() error { return testError }); err != testError {
+			t.Fatalf("expected: %v, got: %v", testError, err)
+		}
+
+		// the backoff is doubled before it is randomized
+		d := jittered(backoff)
+		if d < backoff/2 || d > backoff*3/2 {
+			t.Fatalf("expected the backoff within the jitter of %v, got: %v", backoff, d)
+		}
+		want = append(want, d)
+		if got := b.ClosedUntil(); !got.Equal(c.Now().Add(d)) {
+			t.Fatalf("expected: %s, got: %s", c.Now().Add(d), got)
+		}
+
+		c.Advance(d - time.Nanosecond)
+		if err := b.Execute(func() error { return nil }); err != breaker.ErrClosed {
+			t.Fatalf("expected: %v, got: %v", breaker.ErrClosed, err)
+		}
+		c.Advance(time.Nanosecond)
+	}
+
+	if !reflect.DeepEqual(backoffs, want) {
+		t.Fatalf("expected backoffs: %v, got: %v", want, backoffs)
+	}
+}
+
+func TestBackoffResetAfter(t *testing.T) {
+	testError := errors.New("test error")
+	startBackoff := 1 * time.Minute
+	resetAfter := 10 * time.Minute
+	c := clock.NewMock(time.Now())
+	b := newBreaker(t, breaker.Options{
+		Limit:             1,
+		StartBackoff:      startBackoff,
+		BackoffResetAfter: resetAfter,
+		Clock:             c,
+	})
+
+	execute := func(ferr, want error) {
+		t.Helper()
+
+		if err := b.Execute(func() error { return ferr }); err != want {
+			t.Fatalf("expected: %v, got: %v", want, err)
+		}
+	}
+	expectBackoff := func(backoff time.Duration) {
+		t.Helper()
+
+		if got := b.Remaining(); got != backoff {
+			t.Fatalf("expected backoff: %s, got: %s", backoff, got)
+		}
+	}
+
+	// the failed probe doubles the backoff
+	execute(testError, testError)
+	c.Advance(startBackoff)
+	execute(testError, testError)
+	expectBackoff(2 * startBackoff)
+	c.Advance(2 * startBackoff)
+	execute(nil, nil)
+
+	// the trip soon after the breaker opened keeps the backoff
+	execute(testError, testError)
+	expectBackoff(2 * startBackoff)
+	c.Advance(2 * startBackoff)
+	execute(nil, nil)
+
+	// the trip after the healthy period restores the start backoff
+	c.Advance(resetAfter)
+	execute(testError, testError)
+	expectBackoff(startBackoff)
+
+	// and so does the manual trip
+	c.Advance(startBackoff)
+	execute(testError, testError)
+	c.Advance(2 * startBackoff)
+	execute(nil, nil)
+	c.Advance(resetAfter)
+	b.Trip(0)
+	expectBackoff(startBackoff)
+}
+
+func TestSuccessThreshold(t *testing.T) {
+	testError := errors.New("test error")
+	startBackoff := 1 * time.Minute
+
+	for _, tc := range []struct {
+		name        string
+		threshold   int
+		probes      []error // the results of the probes after the backoff
+		wantState   breaker.State
+		wantBackoff time.Duration // after the next failure
+	}{
+		{
+			name:        "default threshold",
+			probes:      []error{nil},
+			wantState:   breaker.StateOpen,
+			wantBackoff: 2 * startBackoff,
+		},
+		{
+			name:        "threshold reached",
+			threshold:   3,
+			probes:      []error{nil, nil, nil},
+			wantState:   breaker.StateOpen,
+			wantBackoff: startBackoff,
+		},
+		{
+			name:        "threshold not reached",
+			threshold:   3,
+			probes:      []error{nil, nil},
+			wantState:   breaker.StateHalfOpen,
+			wantBackoff: 4 * startBackoff,
+		},
+		{
+			name:        "failure before the threshold",
+			threshold:   3,
+			probes:      []error{nil, nil, testError},
+			wantState:   breaker.StateClosed,
+			wantBackoff: 4 * startBackoff,
+		},
+		{
+			name:        "failure of the first probe",
+			threshold:   3,
+			probes:      []error{testError},
+			wantState:   breaker.StateClosed,
+			wantBackoff: 4 * startBackoff,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := clock.NewMock(time.Now())
+			b := newBreaker(t, breaker.Options{
+				Limit:            1,
+				StartBackoff:     startBackoff,
+				SuccessThreshold: tc.threshold,
+				Clock:            c,
+			})
+
+			// close the breaker with the doubled backoff
+			_ = b.Execute(func() error { return testError })
+			c.Advance(startBackoff)
+			_ = b.Execute(func() error { return testError })
+			c.Advance(2 * startBackoff)
+
+			for i, perr := range tc.probes {
+				if err := b.Execute(func() error { return perr }); err != perr {
+					t.Fatalf("expected: %v, got: %v, probe %d", perr, err, i)
+				}
+			}
+			if got := b.State(); got != tc.wantState {
+				t.Fatalf("expected state: %s, got: %s", tc.wantState, got)
+			}
+
+			if tc.wantState != breaker.StateClosed {
+				_ = b.Execute(func() error { return testError })
+			}
+			if got, want := b.ClosedUntil(), c.Now().Add(tc.wantBackoff); !got.Equal(want) {
+				t.Fatalf("expected: %s, got: %s", want, got)
+			}
+		})
+	}
+}
+
+func BenchmarkExecute(b *testing.B) {
+	testError := errors.New("test error")
+
+	for _, bc := range []struct {
+		name    string
+		options breaker.Options
+	}{
+		{name: "consecutive", options: breaker.Options{Limit: math.MaxInt32}},
+		{name: "sliding window", options: breaker.Options{Limit: math.MaxInt32, SlidingWindow: true}},
+		{name: "failure rate", options: breaker.Options{FailureRateThreshold: 0.9}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			br, err := breaker.NewBreaker(bc.options)
+			if err != nil {
+				b.Fatal(err)
+			}
+			succeed := func() error { return nil }
+			fail := func() error { return testError }
+
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				f := succeed
+				if n%2 == 0 {
+					f = fail
+				}
+				_ = br.Execute(f)
+			}
+		})
+	}
+}
+
+func newBreaker(t *testing.T, o breaker.Options) breaker.Interface {
+	t.Helper()
+
+	b, err := breaker.NewBreaker(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// timeMock returns the times in sequence, one for each time it is asked.
+type timeMock struct {
+	clock.Clock
+	times []time.Time
+	curr  int
+}
+
+func (t *timeMock) Now() time.Time {
+	defer func() { t.curr++ }()
+	return t.times[t.curr]
+}
+
+func (t *timeMock) Since(tm time.Time) time.Duration {
+	return t.Now().Sub(tm)
 }
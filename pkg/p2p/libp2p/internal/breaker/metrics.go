@@ -0,0 +1,118 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package breaker
+
+import (
+	"sync"
+
+	m "github.com/ethersphere/bee/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics are the metrics of the breakers, labeled with their names. They
+// can be shared by the breakers with different names. The breakers of a
+// registry share its name, so the registry does not pass the State to them
+// and their states are counted by the Breakers instead.
+type Metrics struct {
+	// all metrics fields must be exported
+	// to be able to return them by Metrics()
+	// using reflection
+	ExecutionCount *prometheus.CounterVec
+	FailureCount   *prometheus.CounterVec
+	TripCount      *prometheus.CounterVec
+	State          *prometheus.GaugeVec
+	ClosedTime     *prometheus.HistogramVec
+	Breakers       *registryCollector
+}
+
+// NewMetrics returns the metrics to attach to the breakers with Options.
+func NewMetrics() *Metrics {
+	subsystem := "breaker"
+	labels := []string{"breaker"}
+
+	return &Metrics{
+		ExecutionCount: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "execution_count",
+			Help:      "Number of the executed calls.",
+		}, labels),
+		FailureCount: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "failure_count",
+			Help:      "Number of the failed calls.",
+		}, labels),
+		TripCount: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "trip_count",
+			Help:      "Number of times the breaker closed.",
+		}, labels),
+		State: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "state",
+			Help:      "State of the breaker, 0 if it is open, 1 if it is closed and 2 if it is half-open.",
+		}, labels),
+		ClosedTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "closed_time",
+			Help:      "Time in seconds the breaker stayed closed.",
+			Buckets:   []float64{1, 10, 60, 120, 300, 600, 1800, 3600},
+		}, labels),
+		Breakers: &registryCollector{
+			desc: prometheus.NewDesc(
+				prometheus.BuildFQName(m.Namespace, subsystem, "breakers"),
+				"Number of the breakers of the registries by their states.",
+				[]string{"breaker", "state"}, nil,
+			),
+		},
+	}
+}
+
+func (bm *Metrics) Metrics() []prometheus.Collector {
+	return m.PrometheusCollectorsFromFields(bm)
+}
+
+// registryCollector collects the number of the breakers of the registries
+// by their names and states, which are counted on every collection.
+type registryCollector struct {
+	desc       *prometheus.Desc
+	mu         sync.Mutex
+	registries []*Registry
+}
+
+func (c *registryCollector) add(r *Registry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.registries = append(c.registries, r)
+}
+
+func (c *registryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *registryCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	registries := c.registries
+	c.mu.Unlock()
+
+	// the registries with the same name are counted together
+	counts := make(map[string]map[State]int)
+	for _, r := range registries {
+		if counts[r.name] == nil {
+			counts[r.name] = make(map[State]int)
+		}
+		r.countStates(counts[r.name])
+	}
+	for name, states := range counts {
+		for _, s := range []State{StateOpen, StateClosed, StateHalfOpen} {
+			ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(states[s]), name, s.String())
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package breaker
+
+import (
+	"time"
+
+	"github.com/ethersphere/bee/pkg/storage"
+)
+
+// persistedState is the state of a breaker in the store.
+type persistedState struct {
+	Closed              bool          `json:"closed"`
+	ClosedTimestamp     time.Time     `json:"closedTimestamp"`
+	Backoff             time.Duration `json:"backoff"`
+	ClosedBackoff       time.Duration `json:"closedBackoff"`
+	ConsecutiveFailures int           `json:"consecutiveFailures"`
+}
+
+// NewPersistedBreaker returns a breaker which stores its state in the store
+// under the key on every transition of its state, and which starts with the
+// stored state, so that it stays closed across the restarts. It starts open
+// if the state is missing or can not be read. A half-open breaker is stored
+// as closed, so that it probes again after a restart.
+func NewPersistedBreaker(o Options, store storage.StateStorer, key string) (Interface, error) {
+	var b *breaker
+	onStateChange := o.OnStateChange
+	o.OnStateChange = func(from, to State) {
+		b.persist(store, key)
+		if onStateChange != nil {
+			onStateChange(from, to)
+		}
+	}
+
+	b, err := newBreaker(o)
+	if err != nil {
+		return nil, err
+	}
+	b.restore(store, key)
+	return b, nil
+}
+
+// persist stores the current state of the breaker. The breaker keeps
+// working if it can not be stored.
+func (b *breaker) persist(store storage.StateStorer, key string) {
+	b.mtx.Lock()
+	s := persistedState{
+		Closed:              b.state != StateOpen,
+		ClosedTimestamp:     b.closedTimestamp,
+		Backoff:             b.backoff,
+		ClosedBackoff:       b.closedBackoff,
+		ConsecutiveFailures: b.consFailedCalls,
+	}
+	b.mtx.Unlock()
+
+	_ = store.Put(key, s)
+}
+
+// restore sets the state of the breaker to the stored one, if it is valid.
+func (b *breaker) restore(store storage.StateStorer, key string) {
+	var s persistedState
+	if err := store.Get(key, &s); err != nil {
+		return
+	}
+	if s.Backoff <= 0 || s.Backoff > b.maxBackoff || s.ClosedBackoff < 0 || s.ConsecutiveFailures < 0 {
+		return
+	}
+	if s.Closed && (s.ClosedTimestamp.IsZero() || s.ClosedBackoff == 0) {
+		return
+	}
+	if !s.Closed && s.ConsecutiveFailures >= b.limit {
+		return
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.backoff = s.Backoff
+	b.consFailedCalls = s.ConsecutiveFailures
+	if s.Closed {
+		b.setState(StateClosed)
+		b.closedTimestamp = s.ClosedTimestamp
+		b.closedBackoff = s.ClosedBackoff
+	}
+	// the restored state is not a transition
+	b.transitions = nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package breaker_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/internal/clock"
+	"github.com/ethersphere/bee/pkg/p2p/libp2p/internal/breaker"
+	"github.com/ethersphere/bee/pkg/statestore/mock"
+	"github.com/ethersphere/bee/pkg/storage"
+)
+
+const persistedKey = "breaker-test"
+
+func TestPersistedBreaker(t *testing.T) {
+	testError := errors.New("test error")
+	startBackoff := 1 * time.Minute
+	store := mock.NewStateStore()
+	c := clock.NewMock(time.Now())
+	o := breaker.Options{
+		Limit:        1,
+		StartBackoff: startBackoff,
+		Clock:        c,
+	}
+
+	b := newPersistedBreaker(t, o, store)
+	_ = b.Execute(func() error { return testError })
+	c.Advance(startBackoff)
+	_ = b.Execute(func() error { return testError })
+	closedUntil := b.ClosedUntil()
+
+	// the breaker stays closed after the restart
+	b = newPersistedBreaker(t, o, store)
+	if got := b.State(); got != breaker.StateClosed {
+		t.Fatalf("expected state: %s, got: %s", breaker.StateClosed, got)
+	}
+	if got := b.ClosedUntil(); !got.Equal(closedUntil) {
+		t.Fatalf("expected: %s, got: %s", closedUntil, got)
+	}
+	if err := b.Execute(func() error { return nil }); err != breaker.ErrClosed {
+		t.Fatalf("expected: %v, got: %v", breaker.ErrClosed, err)
+	}
+
+	// and keeps doubling its backoff
+	c.Advance(2 * startBackoff)
+	_ = b.Execute(func() error { return testError })
+	if got, want := b.ClosedUntil(), c.Now().Add(4*startBackoff); !got.Equal(want) {
+		t.Fatalf("expected: %s, got: %s", want, got)
+	}
+
+	// the open state is stored too, with the doubled backoff
+	c.Advance(4 * startBackoff)
+	if err := b.Execute(func() error { return nil }); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	b = newPersistedBreaker(t, o, store)
+	if got := b.State(); got != breaker.StateOpen {
+		t.Fatalf("expected state: %s, got: %s", breaker.StateOpen, got)
+	}
+	_ = b.Execute(func() error { return testError })
+	if got, want := b.ClosedUntil(), c.Now().Add(4*startBackoff); !got.Equal(want) {
+		t.Fatalf("expected: %s, got: %s", want, got)
+	}
+}
+
+func TestPersistedBreakerInvalidState(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		state interface{}
+	}{
+		{name: "missing"},
+		{name: "corrupted", state: "not a breaker state"},
+		{name: "invalid backoff", state: map[string]interface{}{"closed": true, "closedTimestamp": time.Now(), "backoff": -1, "closedBackoff": time.Minute}},
+		{name: "closed without timestamp", state: map[string]interface{}{"closed": true, "backoff": time.Minute, "closedBackoff": time.Minute}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store := mock.NewStateStore()
+			if tc.state != nil {
+				if err := store.Put(persistedKey, tc.state); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// a fresh breaker is started
+			c := clock.NewMock(time.Now())
+			b := newPersistedBreaker(t, breaker.Options{Limit: 1, Clock: c}, store)
+			if got := b.State(); got != breaker.StateOpen {
+				t.Fatalf("expected state: %s, got: %s", breaker.StateOpen, got)
+			}
+			if err := b.Execute(func() error { return nil }); err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+		})
+	}
+}
+
+func newPersistedBreaker(t *testing.T, o breaker.Options, store storage.StateStorer) breaker.Interface {
+	t.Helper()
+
+	b, err := breaker.NewPersistedBreaker(o, store, persistedKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
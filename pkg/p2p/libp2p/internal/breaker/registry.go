@@ -0,0 +1,151 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package breaker
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/internal/clock"
+)
+
+// default duration after which an idle open breaker is evicted from the registry
+const idleTTL = time.Hour
+
+// Registry holds the breakers of the keys, for example of the peers, which
+// are created with the same options when their keys are first used. The open
+// breakers which are not used for the idle TTL are evicted, so that the
+// registry does not grow with every key ever used. The closed and half-open
+// breakers are kept, so that the eviction does not reset them.
+type Registry struct {
+	options   Options
+	name      string
+	idleTTL   time.Duration
+	clock     clock.Clock
+	mu        sync.Mutex
+	entries   map[string]*registryEntry
+	lastSweep time.Time
+}
+
+type registryEntry struct {
+	breaker  Interface
+	lastUsed time.Time
+}
+
+// Snapshot is the state and the statistics of a breaker of the registry.
+type Snapshot struct {
+	State State
+	Stats Stats
+}
+
+// NewRegistry returns a registry of the breakers created with the options,
+// which evicts the open breakers idle for the ttl, one hour if it is zero.
+// The Rand of the options, if set, only seeds the sources of the breakers.
+func NewRegistry(o Options, ttl time.Duration) (*Registry, error) {
+	if ttl < 0 {
+		return nil, fmt.Errorf("%w: negative idle ttl %v", ErrInvalidOptions, ttl)
+	}
+	// the breakers share the name, so their states are not reported one by
+	// one, as they would overwrite each other, but counted by the registry
+	metrics := o.Metrics
+	if metrics != nil {
+		shared := *metrics
+		shared.State = nil
+		o.Metrics = &shared
+	}
+
+	// the options are validated once, so that Get does not fail
+	if _, err := newBreaker(o); err != nil {
+		return nil, err
+	}
+
+	r := &Registry{
+		options: o,
+		name:    o.Name,
+		idleTTL: ttl,
+		clock:   o.Clock,
+		entries: make(map[string]*registryEntry),
+	}
+
+	if ttl == 0 {
+		r.idleTTL = idleTTL
+	}
+
+	if o.Clock == nil {
+		r.clock = clock.Real
+	}
+
+	r.lastSweep = r.clock.Now()
+	if metrics != nil {
+		metrics.Breakers.add(r)
+	}
+	return r, nil
+}
+
+// Get returns the breaker of the key, which is created if the key is not
+// used yet or if its breaker was evicted.
+func (r *Registry) Get(key string) Interface {
+	now := r.clock.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now.Sub(r.lastSweep) >= r.idleTTL {
+		r.sweep(now)
+	}
+
+	e, ok := r.entries[key]
+	if !ok {
+		o := r.options
+		if o.Rand != nil {
+			// the breakers do not share the source of the jitter,
+			// as it is not safe for concurrent use
+			o.Rand = rand.New(rand.NewSource(o.Rand.Int63())) // skipcq: GSC-G404
+		}
+		// the options are already validated
+		b, _ := newBreaker(o)
+		e = &registryEntry{breaker: b}
+		r.entries[key] = e
+	}
+	e.lastUsed = now
+	return e.breaker
+}
+
+// Snapshot returns the states and the statistics of the breakers by their keys.
+func (r *Registry) Snapshot() map[string]Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := make(map[string]Snapshot, len(r.entries))
+	for key, e := range r.entries {
+		s[key] = Snapshot{
+			State: e.breaker.State(),
+			Stats: e.breaker.Stats(),
+		}
+	}
+	return s
+}
+
+// countStates adds the number of the breakers by their states to counts.
+func (r *Registry) countStates(counts map[State]int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, e := range r.entries {
+		counts[e.breaker.State()]++
+	}
+}
+
+// sweep evicts the open breakers which were not used for the idle TTL.
+func (r *Registry) sweep(now time.Time) {
+	for key, e := range r.entries {
+		if now.Sub(e.lastUsed) >= r.idleTTL && e.breaker.State() == StateOpen {
+			delete(r.entries, key)
+		}
+	}
+	r.lastSweep = now
+}
@@ -0,0 +1,191 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package breaker_test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/internal/clock"
+	"github.com/ethersphere/bee/pkg/p2p/libp2p/internal/breaker"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRegistryGet(t *testing.T) {
+	r := newRegistry(t, breaker.Options{Limit: 1}, 0)
+
+	a := r.Get("a")
+	if got := r.Get("a"); got != a {
+		t.Fatal("got a different breaker for the same key")
+	}
+	if got := r.Get("b"); got == a {
+		t.Fatal("got the same breaker for different keys")
+	}
+
+	// the breakers are independent
+	_ = a.Execute(func() error { return errors.New("test error") })
+	if got := a.State(); got != breaker.StateClosed {
+		t.Fatalf("expected state: %s, got: %s", breaker.StateClosed, got)
+	}
+	if got := r.Get("b").State(); got != breaker.StateOpen {
+		t.Fatalf("expected state: %s, got: %s", breaker.StateOpen, got)
+	}
+}
+
+func TestRegistryGetConcurrent(t *testing.T) {
+	r := newRegistry(t, breaker.Options{}, 0)
+
+	const n = 100
+	breakers := make([]breaker.Interface, n)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			breakers[i] = r.Get("a")
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	for i, b := range breakers {
+		if b != breakers[0] {
+			t.Fatalf("got a different breaker from get %d", i)
+		}
+	}
+	if got := len(r.Snapshot()); got != 1 {
+		t.Fatalf("expected %d breakers, got %d", 1, got)
+	}
+}
+
+func TestRegistryEviction(t *testing.T) {
+	ttl := time.Hour
+	c := clock.NewMock(time.Now())
+	r := newRegistry(t, breaker.Options{Limit: 1, StartBackoff: 2 * ttl, MaxBackoff: 2 * ttl, Clock: c}, ttl)
+
+	idle := r.Get("idle")
+	closed := r.Get("closed")
+	_ = closed.Execute(func() error { return errors.New("test error") })
+
+	c.Advance(ttl / 2)
+	used := r.Get("used")
+
+	// the idle open breaker is evicted, the closed one is kept
+	c.Advance(ttl / 2)
+	if got := r.Get("used"); got != used {
+		t.Fatal("got a new breaker for the used key")
+	}
+	if got := r.Get("closed"); got != closed {
+		t.Fatal("got a new breaker for the closed key")
+	}
+	s := r.Snapshot()
+	if _, ok := s["idle"]; ok {
+		t.Fatal("idle breaker not evicted")
+	}
+	if len(s) != 2 {
+		t.Fatalf("expected %d breakers, got %d", 2, len(s))
+	}
+
+	// a new breaker is created for the evicted key
+	if got := r.Get("idle"); got == idle {
+		t.Fatal("got the evicted breaker")
+	}
+}
+
+func TestRegistrySnapshot(t *testing.T) {
+	c := clock.NewMock(time.Now())
+	r := newRegistry(t, breaker.Options{Limit: 2, Clock: c}, 0)
+
+	_ = r.Get("a").Execute(func() error { return errors.New("test error") })
+	_ = r.Get("b").Execute(func() error { return nil })
+
+	want := map[string]breaker.Snapshot{
+		"a": {
+			State: breaker.StateOpen,
+			Stats: breaker.Stats{ConsecutiveFailures: 1, Executions: 1, Failures: 1, LastFailure: c.Now()},
+		},
+		"b": {
+			State: breaker.StateOpen,
+			Stats: breaker.Stats{Executions: 1},
+		},
+	}
+	got := r.Snapshot()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d breakers, got %d", len(want), len(got))
+	}
+	for key, w := range want {
+		g, ok := got[key]
+		if !ok {
+			t.Fatalf("breaker %q not in the snapshot", key)
+		}
+		if g.State != w.State || g.Stats.ConsecutiveFailures != w.Stats.ConsecutiveFailures ||
+			g.Stats.Executions != w.Stats.Executions || g.Stats.Failures != w.Stats.Failures ||
+			!g.Stats.LastFailure.Equal(w.Stats.LastFailure) {
+			t.Fatalf("breaker %q: expected: %+v, got: %+v", key, w, g)
+		}
+	}
+}
+
+func TestRegistryMetrics(t *testing.T) {
+	m := breaker.NewMetrics()
+	r := newRegistry(t, breaker.Options{Limit: 1, Metrics: m, Name: "test"}, 0)
+	other := newRegistry(t, breaker.Options{Metrics: m, Name: "other"}, 0)
+
+	_ = r.Get("a").Execute(func() error { return errors.New("test error") })
+	_ = r.Get("b").Execute(func() error { return nil })
+	_ = r.Get("c").Execute(func() error { return nil })
+	_ = other.Get("a")
+
+	// the breakers are counted by their states, as a state of one of
+	// them would be overwritten by the others
+	const breakers = `
+		# HELP bee_breaker_breakers Number of the breakers of the registries by their states.
+		# TYPE bee_breaker_breakers gauge
+		bee_breaker_breakers{breaker="other",state="closed"} 0
+		bee_breaker_breakers{breaker="other",state="half-open"} 0
+		bee_breaker_breakers{breaker="other",state="open"} 1
+		bee_breaker_breakers{breaker="test",state="closed"} 1
+		bee_breaker_breakers{breaker="test",state="half-open"} 0
+		bee_breaker_breakers{breaker="test",state="open"} 2
+	`
+	if err := testutil.CollectAndCompare(m.Breakers, strings.NewReader(breakers)); err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.CollectAndCount(m.State); got != 0 {
+		t.Fatalf("expected no state metrics, got: %d", got)
+	}
+
+	// the counters are shared
+	if got := testutil.ToFloat64(m.ExecutionCount.WithLabelValues("test")); got != 3 {
+		t.Fatalf("expected %d executions, got: %v", 3, got)
+	}
+	if got := testutil.ToFloat64(m.TripCount.WithLabelValues("test")); got != 1 {
+		t.Fatalf("expected %d trips, got: %v", 1, got)
+	}
+}
+
+func TestNewRegistryInvalidOptions(t *testing.T) {
+	if _, err := breaker.NewRegistry(breaker.Options{Limit: -1}, 0); !errors.Is(err, breaker.ErrInvalidOptions) {
+		t.Fatalf("expected error: %v, got: %v", breaker.ErrInvalidOptions, err)
+	}
+	if _, err := breaker.NewRegistry(breaker.Options{}, -time.Second); !errors.Is(err, breaker.ErrInvalidOptions) {
+		t.Fatalf("expected error: %v, got: %v", breaker.ErrInvalidOptions, err)
+	}
+}
+
+func newRegistry(t *testing.T, o breaker.Options, ttl time.Duration) *breaker.Registry {
+	t.Helper()
+
+	r, err := breaker.NewRegistry(o, ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
@@ -0,0 +1,49 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package breaker
+
+import "time"
+
+// TripEvent is a closure of the breaker.
+type TripEvent struct {
+	Timestamp time.Time     // when the breaker closed
+	Backoff   time.Duration // the duration for which the breaker closed
+}
+
+// trips keeps the recent trips of a breaker in a ring of a fixed size.
+type trips struct {
+	events []TripEvent
+	next   int // the index of the next event
+	full   bool
+}
+
+func newTrips(size int) *trips {
+	return &trips{events: make([]TripEvent, size)}
+}
+
+func (h *trips) add(e TripEvent) {
+	h.events[h.next] = e
+	h.next = (h.next + 1) % len(h.events)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// list returns a copy of the events, newest first.
+func (h *trips) list() []TripEvent {
+	n := h.next
+	if h.full {
+		n = len(h.events)
+	}
+	if n == 0 {
+		return nil
+	}
+
+	l := make([]TripEvent, n)
+	for i := range l {
+		l[i] = h.events[(h.next-1-i+len(h.events))%len(h.events)]
+	}
+	return l
+}
@@ -0,0 +1,69 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package breaker
+
+import "time"
+
+// windowBuckets is the number of the buckets of a window, regardless
+// of its duration.
+const windowBuckets = 60
+
+// window counts the calls and the failures within its duration in a ring
+// of buckets, so that its memory does not depend on the rate of the calls.
+// The calls expire one bucket at a time.
+type window struct {
+	width    time.Duration // the duration of a bucket
+	buckets  []bucket
+	last     int64 // the index of the bucket of the last call since the epoch
+	calls    int
+	failures int
+}
+
+type bucket struct {
+	calls    int
+	failures int
+}
+
+func newWindow(duration time.Duration) *window {
+	width := duration / windowBuckets
+	if width <= 0 {
+		width = 1
+	}
+	return &window{
+		width:   width,
+		buckets: make([]bucket, windowBuckets),
+	}
+}
+
+// record records the call and expires the buckets which are older than the
+// duration of the window.
+func (w *window) record(now time.Time, failed bool) {
+	n := int64(len(w.buckets))
+	if i := now.UnixNano() / int64(w.width); i > w.last {
+		for j := w.last + 1; j <= i && j <= w.last+n; j++ {
+			b := &w.buckets[j%n]
+			w.calls -= b.calls
+			w.failures -= b.failures
+			*b = bucket{}
+		}
+		w.last = i
+	}
+
+	b := &w.buckets[w.last%n]
+	b.calls++
+	w.calls++
+	if failed {
+		b.failures++
+		w.failures++
+	}
+}
+
+func (w *window) reset() {
+	for i := range w.buckets {
+		w.buckets[i] = bucket{}
+	}
+	w.calls = 0
+	w.failures = 0
+}
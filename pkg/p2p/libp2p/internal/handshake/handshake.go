@@ -71,6 +71,7 @@ type Service struct {
 	overlay               swarm.Address
 	fullNode              bool
 	transaction           []byte
+	nonce                 []byte
 	networkID             uint64
 	welcomeMessage        atomic.Value
 	receivedHandshakes    map[libp2ppeer.ID]struct{}
@@ -94,8 +95,10 @@ func (i *Info) LightString() string {
 	return ""
 }
 
-// New creates a new handshake Service.
-func New(signer crypto.Signer, advertisableAddresser AdvertisableAddressResolver, isSender SenderMatcher, overlay swarm.Address, networkID uint64, fullNode bool, transaction []byte, welcomeMessage string, logger logging.Logger) (*Service, error) {
+// New creates a new handshake Service. If the nonce that the overlay is
+// derived with is set, the signed address record of the node is sent
+// with every handshake, otherwise it is omitted.
+func New(signer crypto.Signer, advertisableAddresser AdvertisableAddressResolver, isSender SenderMatcher, overlay swarm.Address, networkID uint64, fullNode bool, transaction, nonce []byte, welcomeMessage string, logger logging.Logger) (*Service, error) {
 	if len(welcomeMessage) > MaxWelcomeMessageLength {
 		return nil, ErrWelcomeMessageLength
 	}
@@ -107,6 +110,7 @@ func New(signer crypto.Signer, advertisableAddresser AdvertisableAddressResolver
 		networkID:             networkID,
 		fullNode:              fullNode,
 		transaction:           transaction,
+		nonce:                 nonce,
 		senderMatcher:         isSender,
 		receivedHandshakes:    make(map[libp2ppeer.ID]struct{}),
 		logger:                logger,
@@ -164,6 +168,11 @@ func (s *Service) Handshake(ctx context.Context, stream p2p.Stream, peerMultiadd
 		return nil, err
 	}
 
+	record, err := s.signRecord(advertisableUnderlay)
+	if err != nil {
+		return nil, err
+	}
+
 	overlay := swarm.NewAddress(resp.Ack.Address.Overlay)
 
 	if resp.Ack.NetworkID != s.networkID {
@@ -187,6 +196,7 @@ func (s *Service) Handshake(ctx context.Context, stream p2p.Stream, peerMultiadd
 			Underlay:  advertisableUnderlayBytes,
 			Overlay:   bzzAddress.Overlay.Bytes(),
 			Signature: bzzAddress.Signature,
+			Record:    record,
 		},
 		NetworkID:      s.networkID,
 		FullNode:       s.fullNode,
@@ -196,9 +206,10 @@ func (s *Service) Handshake(ctx context.Context, stream p2p.Stream, peerMultiadd
 		return nil, fmt.Errorf("write ack message: %w", err)
 	}
 
-	s.logger.Tracef("handshake finished for peer (outbound) %s", remoteBzzAddress.Overlay.String())
+	peerLogger := logging.WithPeer(s.logger, remoteBzzAddress.Overlay)
+	peerLogger.Trace("handshake finished for peer (outbound)")
 	if len(resp.Ack.WelcomeMessage) > 0 {
-		s.logger.Infof("greeting \"%s\" from peer: %s", resp.Ack.WelcomeMessage, remoteBzzAddress.Overlay.String())
+		peerLogger.Infof("greeting \"%s\" from peer", resp.Ack.WelcomeMessage)
 	}
 
 	return &Info{
@@ -256,6 +267,11 @@ func (s *Service) Handle(ctx context.Context, stream p2p.Stream, remoteMultiaddr
 		return nil, err
 	}
 
+	record, err := s.signRecord(advertisableUnderlay)
+	if err != nil {
+		return nil, err
+	}
+
 	welcomeMessage := s.GetWelcomeMessage()
 
 	if err := w.WriteMsgWithContext(ctx, &pb.SynAck{
@@ -267,6 +283,7 @@ func (s *Service) Handle(ctx context.Context, stream p2p.Stream, remoteMultiaddr
 				Underlay:  advertisableUnderlayBytes,
 				Overlay:   bzzAddress.Overlay.Bytes(),
 				Signature: bzzAddress.Signature,
+				Record:    record,
 			},
 			NetworkID:      s.networkID,
 			FullNode:       s.fullNode,
@@ -298,9 +315,10 @@ func (s *Service) Handle(ctx context.Context, stream p2p.Stream, remoteMultiaddr
 		return nil, err
 	}
 
-	s.logger.Tracef("handshake finished for peer (inbound) %s", remoteBzzAddress.Overlay.String())
+	peerLogger := logging.WithPeer(s.logger, remoteBzzAddress.Overlay)
+	peerLogger.Trace("handshake finished for peer (inbound)")
 	if len(ack.WelcomeMessage) > 0 {
-		s.logger.Infof("greeting \"%s\" from peer: %s", ack.WelcomeMessage, remoteBzzAddress.Overlay.String())
+		peerLogger.Infof("greeting \"%s\" from peer", ack.WelcomeMessage)
 	}
 
 	return &Info{
@@ -334,11 +352,49 @@ func buildFullMA(addr ma.Multiaddr, peerID libp2ppeer.ID) (ma.Multiaddr, error)
 	return ma.NewMultiaddr(fmt.Sprintf("%s/p2p/%s", addr.String(), peerID.Pretty()))
 }
 
+// signRecord returns the serialised address record of the node, signed
+// anew with the current time, so that the record that the peer gossips
+// supersedes the ones that it may have learned before.
+func (s *Service) signRecord(underlay ma.Multiaddr) ([]byte, error) {
+	if s.nonce == nil {
+		return nil, nil
+	}
+	record, err := bzz.NewRecord(s.signer, s.overlay, []ma.Multiaddr{underlay}, s.networkID, s.nonce, time.Now().UnixNano())
+	if err != nil {
+		return nil, err
+	}
+	return record.MarshalBinary()
+}
+
 func (s *Service) parseCheckAck(ack *pb.Ack, blockHash []byte) (*bzz.Address, error) {
 	bzzAddress, err := bzz.ParseAddress(ack.Address.Underlay, ack.Address.Overlay, ack.Address.Signature, ack.Transaction, blockHash, s.networkID)
 	if err != nil {
 		return nil, ErrInvalidAck
 	}
 
+	// The address is verified by the handshake itself, the record is only
+	// kept to be gossiped and is dropped if other peers would refuse it.
+	if len(ack.Address.Record) > 0 {
+		record := new(bzz.Record)
+		if err := s.checkRecord(record, ack.Address.Record, bzzAddress); err != nil {
+			logging.WithPeer(s.logger, bzzAddress.Overlay).Debugf("handshake: dropping address record: %v", err)
+		} else {
+			bzzAddress.Record = record
+		}
+	}
+
 	return bzzAddress, nil
 }
+
+func (s *Service) checkRecord(record *bzz.Record, data []byte, addr *bzz.Address) error {
+	if err := record.UnmarshalBinary(data); err != nil {
+		return err
+	}
+	if err := record.Verify(s.networkID); err != nil {
+		return err
+	}
+	if !record.Overlay.Equal(addr.Overlay) || !record.HasUnderlay(addr.Underlay) {
+		return bzz.ErrInvalidRecord
+	}
+	return nil
+}
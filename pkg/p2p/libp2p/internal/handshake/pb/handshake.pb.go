@@ -198,6 +198,7 @@ type BzzAddress struct {
 	Underlay  []byte `protobuf:"bytes,1,opt,name=Underlay,proto3" json:"Underlay,omitempty"`
 	Signature []byte `protobuf:"bytes,2,opt,name=Signature,proto3" json:"Signature,omitempty"`
 	Overlay   []byte `protobuf:"bytes,3,opt,name=Overlay,proto3" json:"Overlay,omitempty"`
+	Record    []byte `protobuf:"bytes,4,opt,name=Record,proto3" json:"Record,omitempty"`
 }
 
 func (m *BzzAddress) Reset()         { *m = BzzAddress{} }
@@ -254,6 +255,13 @@ func (m *BzzAddress) GetOverlay() []byte {
 	return nil
 }
 
+func (m *BzzAddress) GetRecord() []byte {
+	if m != nil {
+		return m.Record
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Syn)(nil), "handshake.Syn")
 	proto.RegisterType((*Ack)(nil), "handshake.Ack")
@@ -264,28 +272,27 @@ func init() {
 func init() { proto.RegisterFile("handshake.proto", fileDescriptor_a77305914d5d202f) }
 
 var fileDescriptor_a77305914d5d202f = []byte{
-	// 324 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x64, 0x91, 0xdf, 0x4a, 0xf3, 0x30,
-	0x18, 0xc6, 0x97, 0x75, 0xec, 0xcf, 0xbb, 0xb1, 0xef, 0x23, 0x20, 0x14, 0x19, 0x25, 0xf4, 0x40,
-	0x8a, 0x07, 0x13, 0xf5, 0x0a, 0x36, 0x44, 0x10, 0x74, 0x83, 0x54, 0x11, 0x3c, 0x32, 0x6b, 0xc3,
-	0x36, 0x5a, 0x93, 0x91, 0x74, 0x93, 0xee, 0x2a, 0xbc, 0x1e, 0xaf, 0xc0, 0xc3, 0x1d, 0x7a, 0x28,
-	0xdb, 0x8d, 0x48, 0xe3, 0xd6, 0xce, 0x79, 0xf8, 0xfe, 0x9e, 0x27, 0xc9, 0xf3, 0xbc, 0x81, 0x7f,
-	0x13, 0x26, 0x42, 0x3d, 0x61, 0x11, 0xef, 0xce, 0x94, 0x4c, 0x24, 0x6e, 0xe4, 0xc0, 0x3d, 0x07,
-	0xcb, 0x4f, 0x05, 0x3e, 0x85, 0xff, 0xc3, 0x91, 0xe6, 0x6a, 0xc1, 0xc3, 0x07, 0x11, 0x72, 0x15,
-	0xb3, 0xd4, 0x46, 0x04, 0x79, 0x2d, 0xfa, 0x87, 0xbb, 0xef, 0x08, 0xac, 0x5e, 0x10, 0xe1, 0x33,
-	0xa8, 0xf5, 0xc2, 0x50, 0x71, 0xad, 0x8d, 0xb5, 0x79, 0x71, 0xd4, 0x2d, 0x1e, 0xea, 0x2f, 0x97,
-	0x5b, 0x91, 0xee, 0x5c, 0xb8, 0x03, 0x8d, 0x01, 0x4f, 0x5e, 0xa5, 0x8a, 0x6e, 0xae, 0xec, 0x32,
-	0x41, 0x5e, 0x85, 0x16, 0x00, 0x1f, 0x43, 0xfd, 0x7a, 0x1e, 0xc7, 0x03, 0x19, 0x72, 0xdb, 0x22,
-	0xc8, 0xab, 0xd3, 0x7c, 0xc6, 0x04, 0x9a, 0xf7, 0x8a, 0x09, 0xcd, 0x82, 0x64, 0x2a, 0x85, 0x5d,
-	0x31, 0xc9, 0xf6, 0x11, 0x3e, 0x81, 0xf6, 0x23, 0x8f, 0x03, 0xf9, 0xc2, 0xef, 0xb8, 0xd6, 0x6c,
-	0xcc, 0xed, 0x80, 0x20, 0xaf, 0x41, 0x0f, 0xa8, 0x7b, 0x0b, 0x55, 0x3f, 0x15, 0x59, 0x7c, 0x62,
-	0x9a, 0x6f, 0xa3, 0xb7, 0xf7, 0xa2, 0xfb, 0xa9, 0xa0, 0x66, 0x29, 0xc4, 0xf4, 0x34, 0x49, 0x7f,
-	0x3b, 0x7a, 0x41, 0x44, 0x33, 0xc9, 0x7d, 0x06, 0x28, 0x8a, 0x66, 0x0d, 0x0e, 0x96, 0x97, 0xcf,
-	0x59, 0x77, 0x7f, 0x3a, 0x16, 0x2c, 0x99, 0x2b, 0x6e, 0x6e, 0x6c, 0xd1, 0x02, 0x60, 0x1b, 0x6a,
-	0xc3, 0xc5, 0xcf, 0x41, 0xcb, 0x68, 0xbb, 0xb1, 0xdf, 0xf9, 0x58, 0x3b, 0x68, 0xb5, 0x76, 0xd0,
-	0xd7, 0xda, 0x41, 0x6f, 0x1b, 0xa7, 0xb4, 0xda, 0x38, 0xa5, 0xcf, 0x8d, 0x53, 0x7a, 0x2a, 0xcf,
-	0x46, 0xa3, 0xaa, 0xf9, 0xcf, 0xcb, 0xef, 0x00, 0x00, 0x00, 0xff, 0xff, 0xd1, 0x48, 0x2c, 0x4a,
-	0xe2, 0x01, 0x00, 0x00,
+	// 320 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x65, 0x51, 0xc1, 0x4a, 0xc3, 0x40,
+	0x10, 0x35, 0x4d, 0x49, 0x9b, 0x69, 0xa9, 0xb2, 0xa0, 0x2c, 0x52, 0x4a, 0xc8, 0x41, 0xc4, 0x43,
+	0x45, 0xfd, 0x82, 0x16, 0x11, 0x04, 0x6d, 0x61, 0xab, 0x08, 0xde, 0x36, 0xd9, 0xa5, 0x2d, 0x8d,
+	0xbb, 0x65, 0x93, 0x56, 0xdb, 0xaf, 0xf0, 0x7b, 0xfc, 0x02, 0x8f, 0x3d, 0x7a, 0x14, 0xfd, 0x11,
+	0x37, 0x6b, 0x9a, 0xd4, 0x7a, 0x98, 0xc3, 0xbc, 0xf7, 0x66, 0xe7, 0xbd, 0x59, 0xd8, 0x1d, 0x51,
+	0xc1, 0xe2, 0x11, 0x9d, 0xf0, 0xf6, 0x54, 0xc9, 0x44, 0x22, 0x37, 0x07, 0xfc, 0x33, 0xb0, 0x07,
+	0x0b, 0x81, 0x4e, 0x60, 0xaf, 0x1f, 0xc4, 0x5c, 0xcd, 0x39, 0xbb, 0x17, 0x8c, 0xab, 0x88, 0x2e,
+	0xb0, 0xe5, 0x59, 0xc7, 0x75, 0xf2, 0x0f, 0xf7, 0xdf, 0x2c, 0xb0, 0x3b, 0xe1, 0x04, 0x9d, 0x42,
+	0xa5, 0xc3, 0x98, 0xe2, 0x71, 0x6c, 0xa4, 0xb5, 0xf3, 0xfd, 0x76, 0xb1, 0xa8, 0xbb, 0x5c, 0x66,
+	0x24, 0x59, 0xab, 0x50, 0x13, 0xdc, 0x1e, 0x4f, 0x9e, 0xa5, 0x9a, 0x5c, 0x5f, 0xe2, 0x92, 0x1e,
+	0x29, 0x93, 0x02, 0x40, 0x87, 0x50, 0xbd, 0x9a, 0x45, 0x51, 0x4f, 0x32, 0x8e, 0x6d, 0x4d, 0x56,
+	0x49, 0xde, 0x23, 0x0f, 0x6a, 0x77, 0x8a, 0x8a, 0x98, 0x86, 0xc9, 0x58, 0x0a, 0x5c, 0x36, 0xce,
+	0x36, 0x21, 0x74, 0x04, 0x8d, 0x07, 0x1e, 0x85, 0xf2, 0x89, 0xdf, 0xea, 0x55, 0x74, 0xc8, 0x71,
+	0xa8, 0x45, 0x2e, 0xd9, 0x42, 0xfd, 0x1b, 0x70, 0x74, 0xde, 0xd4, 0xbe, 0x67, 0x92, 0x67, 0xd6,
+	0x1b, 0x1b, 0xd6, 0x35, 0x4a, 0xcc, 0x51, 0x3c, 0x93, 0xd3, 0x38, 0xfd, 0xab, 0xd0, 0x28, 0x49,
+	0x29, 0xff, 0x05, 0xa0, 0x08, 0x9a, 0x26, 0xd8, 0x3a, 0x5e, 0xde, 0xa7, 0xd9, 0x07, 0xe3, 0xa1,
+	0xa0, 0xc9, 0x4c, 0x71, 0xf3, 0x62, 0x9d, 0x14, 0x00, 0xc2, 0x50, 0xe9, 0xcf, 0x7f, 0x07, 0x6d,
+	0xc3, 0xad, 0x5b, 0x74, 0x00, 0x0e, 0xe1, 0xa1, 0x54, 0x2c, 0x0b, 0x9d, 0x75, 0xdd, 0xe6, 0xfb,
+	0x57, 0xcb, 0x5a, 0xe9, 0xfa, 0xd4, 0xf5, 0xfa, 0xdd, 0xda, 0x59, 0xe9, 0xfa, 0xd0, 0xf5, 0x58,
+	0x9a, 0x06, 0x81, 0x63, 0xfe, 0xf9, 0xe2, 0x07, 0xfa, 0x6c, 0x36, 0x3b, 0xfa, 0x01, 0x00, 0x00,
 }
 
 func (m *Syn) Marshal() (dAtA []byte, err error) {
@@ -451,6 +458,13 @@ func (m *BzzAddress) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.Record) > 0 {
+		i -= len(m.Record)
+		copy(dAtA[i:], m.Record)
+		i = encodeVarintHandshake(dAtA, i, uint64(len(m.Record)))
+		i--
+		dAtA[i] = 0x22
+	}
 	if len(m.Overlay) > 0 {
 		i -= len(m.Overlay)
 		copy(dAtA[i:], m.Overlay)
@@ -561,6 +575,10 @@ func (m *BzzAddress) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovHandshake(uint64(l))
 	}
+	l = len(m.Record)
+	if l > 0 {
+		n += 1 + l + sovHandshake(uint64(l))
+	}
 	return n
 }
 
@@ -1107,6 +1125,40 @@ func (m *BzzAddress) Unmarshal(dAtA []byte) error {
 				m.Overlay = []byte{}
 			}
 			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Record", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowHandshake
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthHandshake
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthHandshake
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Record = append(m.Record[:0], dAtA[iNdEx:postIndex]...)
+			if m.Record == nil {
+				m.Record = []byte{}
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipHandshake(dAtA[iNdEx:])
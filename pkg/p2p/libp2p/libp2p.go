@@ -16,6 +16,7 @@ import (
 	"github.com/ethersphere/bee/pkg/addressbook"
 	"github.com/ethersphere/bee/pkg/bzz"
 	beecrypto "github.com/ethersphere/bee/pkg/crypto"
+	"github.com/ethersphere/bee/pkg/internal/clock"
 	"github.com/ethersphere/bee/pkg/logging"
 	"github.com/ethersphere/bee/pkg/p2p"
 	"github.com/ethersphere/bee/pkg/p2p/libp2p/internal/blocklist"
@@ -42,6 +43,7 @@ import (
 	ws "github.com/libp2p/go-ws-transport"
 	ma "github.com/multiformats/go-multiaddr"
 	"github.com/multiformats/go-multistream"
+	"github.com/opentracing/opentracing-go"
 )
 
 var (
@@ -49,7 +51,30 @@ var (
 	_ p2p.DebugService = (*Service)(nil)
 )
 
-const defaultLightNodeLimit = 100
+// errIncomingNotConnected marks the tracing span of an inbound
+// connection that was not established.
+var errIncomingNotConnected = errors.New("incoming connection not established")
+
+// ErrHandlersCancelled is returned by Drain when the protocol
+// handlers did not return within the grace period.
+var ErrHandlersCancelled = errors.New("protocol handlers cancelled")
+
+const (
+	defaultLightNodeLimit       = 100
+	defaultStreamLimit          = 128
+	defaultProtectedStreamLimit = 512
+
+	// blocklistSweepInterval is the interval at which the
+	// expired blocklist entries are removed from the store.
+	blocklistSweepInterval = time.Hour
+
+	// handshakeTracedStreamName is the name of the handshake stream which
+	// starts with the headers carrying the tracing context of the
+	// connecting peer. The handshake stream without the headers is used
+	// with the peers which do not support it, so that the handshake
+	// protocol version is not changed.
+	handshakeTracedStreamName = "handshake-traced"
+)
 
 type Service struct {
 	ctx               context.Context
@@ -60,12 +85,18 @@ type Service struct {
 	pingDialer        host.Host
 	libp2pPeerstore   peerstore.Peerstore
 	metrics           metrics
+	persistentMetrics persistentMetrics
 	networkID         uint64
 	handshakeService  *handshake.Service
 	addressbook       addressbook.Putter
 	peers             *peerRegistry
-	connectionBreaker breaker.Interface
+	connectBreakers   *breaker.Registry
+	breakerMetrics    *breaker.Metrics
+	breakerClock      clock.Clock       // nil for the real clock
+	protocolBreakers  *protocolBreakers // nil if disabled
 	blocklist         *blocklist.Blocklist
+	peerEvents        peerEvents
+	gater             *connectionGater
 	protocols         []p2p.ProtocolSpec
 	notifier          p2p.PickyNotifier
 	logger            logging.Logger
@@ -74,7 +105,14 @@ type Service struct {
 	halt              chan struct{}
 	lightNodes        lightnodes
 	lightNodeLimit    int
+	streamLimit       int  // open streams per peer in all protocols
+	protectedLimit    int  // open streams per neighbourhood peer in all protocols
+	fullNode          bool // light nodes do not register full node only protocols
 	protocolsmu       sync.RWMutex
+	handlersCtx       context.Context    // parent context of the protocol handlers
+	handlersCancel    context.CancelFunc // cancels the running protocol handlers
+	handlers          sync.WaitGroup     // running protocol handlers
+	handlersMu        sync.Mutex         // orders the start of handlers and Halt
 }
 
 type lightnodes interface {
@@ -84,6 +122,12 @@ type lightnodes interface {
 	RandomPeer(swarm.Address) (swarm.Address, error)
 }
 
+// neighbourhood is implemented by the notifier to report the peers
+// that are protected by higher limits.
+type neighbourhood interface {
+	IsWithinDepth(swarm.Address) bool
+}
+
 type Options struct {
 	PrivateKey     *ecdsa.PrivateKey
 	NATAddr        string
@@ -91,16 +135,41 @@ type Options struct {
 	EnableQUIC     bool
 	FullNode       bool
 	LightNodeLimit int
+	// StreamLimit is the number of streams that can be open with a
+	// peer at the same time in all protocols and both directions.
+	StreamLimit int
+	// ProtectedStreamLimit is the StreamLimit of the neighbourhood peers.
+	ProtectedStreamLimit int
+	// ProtocolBreakerLimit enables the breakers of the protocols of every
+	// peer. When the handlers of a protocol fail with a peer this number
+	// of consecutive times, the peer is blocklisted for the backoff
+	// duration of the breaker. Zero disables the protocol breakers.
+	ProtocolBreakerLimit int
+	// Clock is the source of time of the breakers and the blocklist,
+	// the real clock is used if it is nil.
+	Clock          clock.Clock
 	WelcomeMessage string
 	Transaction    []byte
+	Nonce          []byte
 }
 
-func New(ctx context.Context, signer beecrypto.Signer, networkID uint64, overlay swarm.Address, addr string, ab addressbook.Putter, storer storage.StateStorer, lightNodes *lightnode.Container, swapBackend handshake.SenderMatcher, logger logging.Logger, tracer *tracing.Tracer, o Options) (*Service, error) {
+// StateStorePrefixes returns the prefixes of the state store keys which the
+// service keeps in memory. Their sweeps must finish before it is created.
+func StateStorePrefixes() []string {
+	return blocklist.KeyPrefixes()
+}
+
+func New(ctx context.Context, signer beecrypto.Signer, networkID uint64, overlay swarm.Address, addr string, ab addressbook.Putter, storer storage.StateStorer, lightNodes *lightnode.Container, swapBackend handshake.SenderMatcher, logger logging.Logger, tracer *tracing.Tracer, o Options) (s *Service, err error) {
 	host, port, err := net.SplitHostPort(addr)
 	if err != nil {
 		return nil, fmt.Errorf("address: %w", err)
 	}
 
+	persistentMetrics, err := newPersistentMetrics(storer)
+	if err != nil {
+		return nil, fmt.Errorf("persistent metrics: %w", err)
+	}
+
 	ip4Addr := "0.0.0.0"
 	ip6Addr := "::"
 
@@ -136,6 +205,17 @@ func New(ctx context.Context, signer beecrypto.Signer, networkID uint64, overlay
 		}
 	}
 
+	handlersCtx, handlersCancel := context.WithCancel(ctx)
+	defer func() {
+		if err != nil {
+			handlersCancel()
+		}
+	}()
+
+	metrics := newMetrics()
+	bl := blocklist.NewBlocklistWithSweep(handlersCtx, storer, blocklistSweepInterval, blocklist.Options{Clock: o.Clock, Logger: logger})
+	gater := newConnectionGater(bl, logger, metrics.GaterRejectedCount)
+
 	security := libp2p.DefaultSecurity
 	libp2pPeerstore := pstoremem.NewPeerstore()
 
@@ -146,6 +226,8 @@ func New(ctx context.Context, signer beecrypto.Signer, networkID uint64, overlay
 		security,
 		// Use dedicated peerstore instead the global DefaultPeerstore
 		libp2p.Peerstore(libp2pPeerstore),
+		// reject the blocklisted peers before the handshake
+		libp2p.ConnectionGater(gater),
 	}
 
 	if o.NATAddr == "" {
@@ -214,7 +296,7 @@ func New(ctx context.Context, signer beecrypto.Signer, networkID uint64, overlay
 		advertisableAddresser = natAddrResolver
 	}
 
-	handshakeService, err := handshake.New(signer, advertisableAddresser, swapBackend, overlay, networkID, o.FullNode, o.Transaction, o.WelcomeMessage, logger)
+	handshakeService, err := handshake.New(signer, advertisableAddresser, swapBackend, overlay, networkID, o.FullNode, o.Transaction, o.Nonce, o.WelcomeMessage, logger)
 	if err != nil {
 		return nil, fmt.Errorf("handshake service: %w", err)
 	}
@@ -229,8 +311,20 @@ func New(ctx context.Context, signer beecrypto.Signer, networkID uint64, overlay
 		return nil, err
 	}
 
+	// the connection breakers of the peers use the default limits, so that
+	// an unreachable peer does not block the connections to the others
+	breakerMetrics := breaker.NewMetrics()
+	connectBreakers, err := breaker.NewRegistry(breaker.Options{
+		Clock:   o.Clock,
+		Metrics: breakerMetrics,
+		Name:    "connect",
+	}, 0)
+	if err != nil {
+		return nil, fmt.Errorf("connection breakers: %w", err)
+	}
+
 	peerRegistry := newPeerRegistry()
-	s := &Service{
+	s = &Service{
 		ctx:               ctx,
 		host:              h,
 		natManager:        natManager,
@@ -239,25 +333,44 @@ func New(ctx context.Context, signer beecrypto.Signer, networkID uint64, overlay
 		pingDialer:        pingDialer,
 		handshakeService:  handshakeService,
 		libp2pPeerstore:   libp2pPeerstore,
-		metrics:           newMetrics(),
+		metrics:           metrics,
+		persistentMetrics: persistentMetrics,
 		networkID:         networkID,
 		peers:             peerRegistry,
 		addressbook:       ab,
-		blocklist:         blocklist.NewBlocklist(storer),
+		blocklist:         bl,
+		gater:             gater,
 		logger:            logger,
 		tracer:            tracer,
-		connectionBreaker: breaker.NewBreaker(breaker.Options{}), // use default options
+		connectBreakers:   connectBreakers,
+		breakerMetrics:    breakerMetrics,
+		breakerClock:      o.Clock,
 		ready:             make(chan struct{}),
 		halt:              make(chan struct{}),
 		lightNodes:        lightNodes,
+		fullNode:          o.FullNode,
+		handlersCtx:       handlersCtx,
+		handlersCancel:    handlersCancel,
 	}
 
 	peerRegistry.setDisconnecter(s)
 
+	if o.ProtocolBreakerLimit > 0 {
+		s.protocolBreakers = newProtocolBreakers(breaker.Options{Limit: o.ProtocolBreakerLimit, Clock: o.Clock}, s.protocolBreakerClosed)
+	}
+
 	s.lightNodeLimit = defaultLightNodeLimit
 	if o.LightNodeLimit > 0 {
 		s.lightNodeLimit = o.LightNodeLimit
 	}
+	s.streamLimit = defaultStreamLimit
+	if o.StreamLimit > 0 {
+		s.streamLimit = o.StreamLimit
+	}
+	s.protectedLimit = defaultProtectedStreamLimit
+	if o.ProtectedStreamLimit > 0 {
+		s.protectedLimit = o.ProtectedStreamLimit
+	}
 
 	// Construct protocols.
 	id := protocol.ID(p2p.NewSwarmStreamName(handshake.ProtocolName, handshake.ProtocolVersion, handshake.StreamName))
@@ -266,7 +379,19 @@ func New(ctx context.Context, signer beecrypto.Signer, networkID uint64, overlay
 		return nil, fmt.Errorf("protocol version match %s: %w", id, err)
 	}
 
-	s.host.SetStreamHandlerMatch(id, matcher, s.handleIncoming)
+	s.host.SetStreamHandlerMatch(id, matcher, func(stream network.Stream) {
+		s.handleIncoming(stream, false)
+	})
+
+	tracedID := protocol.ID(p2p.NewSwarmStreamName(handshake.ProtocolName, handshake.ProtocolVersion, handshakeTracedStreamName))
+	tracedMatcher, err := s.protocolSemverMatcher(tracedID)
+	if err != nil {
+		return nil, fmt.Errorf("protocol version match %s: %w", tracedID, err)
+	}
+
+	s.host.SetStreamHandlerMatch(tracedID, tracedMatcher, func(stream network.Stream) {
+		s.handleIncoming(stream, true)
+	})
 
 	h.Network().SetConnHandler(func(_ network.Conn) {
 		s.metrics.HandledConnectionCount.Inc()
@@ -277,7 +402,9 @@ func New(ctx context.Context, signer beecrypto.Signer, networkID uint64, overlay
 	return s, nil
 }
 
-func (s *Service) handleIncoming(stream network.Stream) {
+// handleIncoming handles the handshake stream of an inbound connection. The
+// traced handshake stream starts with the headers of the connecting peer.
+func (s *Service) handleIncoming(stream network.Stream, traced bool) {
 	select {
 	case <-s.ready:
 	case <-s.halt:
@@ -288,8 +415,58 @@ func (s *Service) handleIncoming(stream network.Stream) {
 		return
 	}
 
+	// the handshake is drained on shutdown like the protocol handlers
+	if !s.startHandler() {
+		go func() { _ = stream.Reset() }()
+		return
+	}
+	defer s.handlers.Done()
+
 	peerID := stream.Conn().RemotePeer()
-	handshakeStream := NewStream(stream)
+
+	handshakeStream := newStream(stream)
+
+	ctx := s.ctx
+	if traced {
+		if err := handleHeaders(nil, handshakeStream, swarm.ZeroAddress); err != nil {
+			s.logger.Debugf("stream handler: handshake: headers %s: %v", peerID, err)
+			_ = handshakeStream.Reset()
+			_ = s.host.Network().ClosePeer(peerID)
+			return
+		}
+
+		// join the trace of the connecting peer
+		var err error
+		ctx, err = s.tracer.WithContextFromHeaders(ctx, handshakeStream.Headers())
+		if err != nil && !errors.Is(err, tracing.ErrContextNotFound) {
+			s.logger.Debugf("stream handler: handshake: tracing context %s: %v", peerID, err)
+		}
+	}
+
+	var (
+		overlay   swarm.Address
+		connected bool
+		stage     opentracing.Span
+	)
+	span, ctx := s.startConnectSpan(ctx, "libp2p-handle-incoming", "inbound", stream.Conn().RemoteMultiaddr())
+	nextStage := func(name string) {
+		if stage != nil {
+			stage.Finish()
+		}
+		stage, _, _ = s.tracer.StartSpanFromContext(ctx, "libp2p-handle-incoming-"+name, nil)
+	}
+	defer func() {
+		if stage != nil {
+			stage.Finish()
+		}
+		var err error
+		if !connected {
+			err = errIncomingNotConnected
+		}
+		finishConnectSpan(span, overlay, err)
+	}()
+
+	nextStage("handshake")
 	i, err := s.handshakeService.Handle(s.ctx, handshakeStream, stream.Conn().RemoteMultiaddr(), peerID)
 	if err != nil {
 		s.logger.Debugf("stream handler: handshake: handle %s: %v", peerID, err)
@@ -299,19 +476,29 @@ func (s *Service) handleIncoming(stream network.Stream) {
 		return
 	}
 
-	overlay := i.BzzAddress.Overlay
+	overlay = i.BzzAddress.Overlay
+	peerLogger := logging.WithPeer(s.logger, overlay)
+	s.gater.setOverlay(peerID, overlay)
 
-	blocked, err := s.blocklist.Exists(overlay)
+	blocked, remaining, err := s.blocklist.ExistsWithRemaining(overlay)
 	if err != nil {
-		s.logger.Debugf("stream handler: blocklisting: exists %s: %v", overlay, err)
-		s.logger.Errorf("stream handler: internal error while connecting with peer %s", overlay)
+		peerLogger.Debugf("stream handler: blocklisting: exists: %v", err)
+		peerLogger.Error("stream handler: internal error while connecting with peer")
 		_ = handshakeStream.Reset()
 		_ = s.host.Network().ClosePeer(peerID)
 		return
 	}
 
 	if blocked {
-		s.logger.Errorf("stream handler: blocked connection from blocklisted peer %s", overlay)
+		s.metrics.BlocklistedPeerAttemptCount.Inc()
+		first, err := s.blocklist.RecordAttempt(overlay)
+		if err != nil {
+			peerLogger.Debugf("stream handler: blocklisting: record attempt: %v", err)
+		}
+		// log only once for all attempts that are persisted together
+		if first {
+			peerLogger.Errorf("stream handler: blocked connection from peer blocklisted %s", formatRemaining(remaining))
+		}
 		_ = handshakeStream.Reset()
 		_ = s.host.Network().ClosePeer(peerID)
 		return
@@ -319,7 +506,7 @@ func (s *Service) handleIncoming(stream network.Stream) {
 
 	if s.notifier != nil {
 		if !s.notifier.Pick(p2p.Peer{Address: overlay, FullNode: i.FullNode}) {
-			s.logger.Warningf("stream handler: don't want incoming peer %s. disconnecting", overlay)
+			peerLogger.Warning("stream handler: don't want incoming peer. disconnecting")
 			_ = handshakeStream.Reset()
 			_ = s.host.Network().ClosePeer(peerID)
 			return
@@ -327,27 +514,28 @@ func (s *Service) handleIncoming(stream network.Stream) {
 	}
 
 	if exists := s.peers.addIfNotExists(stream.Conn(), overlay, i.FullNode); exists {
-		s.logger.Debugf("stream handler: peer %s already exists", overlay)
+		peerLogger.Debug("stream handler: peer already exists")
 		if err = handshakeStream.FullClose(); err != nil {
-			s.logger.Debugf("stream handler: could not close stream %s: %v", overlay, err)
-			s.logger.Errorf("stream handler: unable to handshake with peer %v", overlay)
+			peerLogger.Debugf("stream handler: could not close stream: %v", err)
+			peerLogger.Error("stream handler: unable to handshake with peer")
 			_ = s.Disconnect(overlay)
 		}
 		return
 	}
 
 	if err = handshakeStream.FullClose(); err != nil {
-		s.logger.Debugf("stream handler: could not close stream %s: %v", overlay, err)
-		s.logger.Errorf("stream handler: unable to handshake with peer %v", overlay)
+		peerLogger.Debugf("stream handler: could not close stream: %v", err)
+		peerLogger.Error("stream handler: unable to handshake with peer")
 		_ = s.Disconnect(overlay)
 		return
 	}
 
+	nextStage("addressbook")
 	if i.FullNode {
 		err = s.addressbook.Put(i.BzzAddress.Overlay, *i.BzzAddress)
 		if err != nil {
-			s.logger.Debugf("stream handler: addressbook put error %s: %v", peerID, err)
-			s.logger.Errorf("stream handler: unable to persist peer %v", peerID)
+			peerLogger.Debugf("stream handler: addressbook put error %s: %v", peerID, err)
+			peerLogger.Errorf("stream handler: unable to persist peer %v", peerID)
 			_ = s.Disconnect(i.BzzAddress.Overlay)
 			return
 		}
@@ -355,11 +543,15 @@ func (s *Service) handleIncoming(stream network.Stream) {
 
 	peer := p2p.Peer{Address: overlay, FullNode: i.FullNode, EthereumAddress: i.BzzAddress.EthereumAddress}
 
+	nextStage("protocols")
 	s.protocolsmu.RLock()
 	for _, tn := range s.protocols {
+		if tn.FullNodeOnly && !peer.FullNode {
+			continue
+		}
 		if tn.ConnectIn != nil {
 			if err := tn.ConnectIn(s.ctx, peer); err != nil {
-				s.logger.Debugf("stream handler: connectIn: protocol: %s, version:%s, peer: %s: %v", tn.Name, tn.Version, overlay, err)
+				peerLogger.Debugf("stream handler: connectIn: protocol: %s, version:%s: %v", tn.Name, tn.Version, err)
 				_ = s.Disconnect(overlay)
 				s.protocolsmu.RUnlock()
 				return
@@ -368,31 +560,32 @@ func (s *Service) handleIncoming(stream network.Stream) {
 	}
 	s.protocolsmu.RUnlock()
 
+	nextStage("topology")
 	if s.notifier != nil {
 		if !i.FullNode {
 			s.lightNodes.Connected(s.ctx, peer)
 			// light node announces explicitly
 			if err := s.notifier.Announce(s.ctx, peer.Address, i.FullNode); err != nil {
-				s.logger.Debugf("stream handler: notifier.Announce: %s: %v", peer.Address.String(), err)
+				peerLogger.Debugf("stream handler: notifier.Announce: %v", err)
 			}
 
 			if s.lightNodes.Count() > s.lightNodeLimit {
 				// kick another node to fit this one in
 				p, err := s.lightNodes.RandomPeer(peer.Address)
 				if err != nil {
-					s.logger.Debugf("stream handler: cant find a peer slot for light node: %v", err)
-					_ = s.Disconnect(peer.Address)
+					peerLogger.Debugf("stream handler: cant find a peer slot for light node: %v", err)
+					_ = s.DisconnectWithReason(peer.Address, p2p.DisconnectReasonPruned)
 					return
 				} else {
-					s.logger.Tracef("stream handler: kicking away light node %s to make room for %s", p.String(), peer.Address.String())
+					peerLogger.Tracef("stream handler: kicking away light node %s to make room", p)
 					s.metrics.KickedOutPeersCount.Inc()
-					_ = s.Disconnect(p)
+					_ = s.DisconnectWithReason(p, p2p.DisconnectReasonPruned)
 					return
 				}
 			}
 		} else if err := s.notifier.Connected(s.ctx, peer, false); err != nil {
 			// full node announces implicitly
-			s.logger.Debugf("stream handler: notifier.Connected: peer disconnected: %s: %v", i.BzzAddress.Overlay, err)
+			peerLogger.Debugf("stream handler: notifier.Connected: peer disconnected: %v", err)
 			// note: this cannot be unit tested since the node
 			// waiting on handshakeStream.FullClose() on the other side
 			// might actually get a stream reset when we disconnect here
@@ -409,13 +602,15 @@ func (s *Service) handleIncoming(stream network.Stream) {
 
 	s.metrics.HandledStreamCount.Inc()
 	if !s.peers.Exists(overlay) {
-		s.logger.Warningf("stream handler: inbound peer %s does not exist, disconnecting", overlay)
+		peerLogger.Warning("stream handler: inbound peer does not exist, disconnecting")
 		_ = s.Disconnect(overlay)
 		return
 	}
 
-	s.logger.Debugf("stream handler: successfully connected to peer %s%s (inbound)", i.BzzAddress.ShortString(), i.LightString())
-	s.logger.Infof("stream handler: successfully connected to peer %s%s (inbound)", i.BzzAddress.Overlay, i.LightString())
+	connected = true
+	s.persistentMetrics.InboundConnectionTotal.Inc()
+	s.publishPeerEvent(overlay, p2p.PeerEventConnected)
+	peerLogger.Infof("stream handler: successfully connected to peer%s (inbound)", i.LightString())
 }
 
 func (s *Service) SetPickyNotifier(n p2p.PickyNotifier) {
@@ -423,6 +618,11 @@ func (s *Service) SetPickyNotifier(n p2p.PickyNotifier) {
 }
 
 func (s *Service) AddProtocol(p p2p.ProtocolSpec) (err error) {
+	if p.FullNodeOnly && !s.fullNode {
+		s.logger.Debugf("light node: protocol %s/%s not registered", p.Name, p.Version)
+		return nil
+	}
+
 	for _, ss := range p.StreamSpecs {
 		ss := ss
 		id := protocol.ID(p2p.NewSwarmStreamName(p.Name, p.Version, ss.Name))
@@ -432,6 +632,12 @@ func (s *Service) AddProtocol(p p2p.ProtocolSpec) (err error) {
 		}
 
 		s.host.SetStreamHandlerMatch(id, matcher, func(streamlibp2p network.Stream) {
+			if !s.startHandler() {
+				_ = streamlibp2p.Reset()
+				return
+			}
+			defer s.handlers.Done()
+
 			peerID := streamlibp2p.Conn().RemotePeer()
 			overlay, found := s.peers.overlay(peerID)
 			if !found {
@@ -446,16 +652,24 @@ func (s *Service) AddProtocol(p p2p.ProtocolSpec) (err error) {
 				return
 			}
 
+			release, ok := s.acquireStream(peerID, overlay)
+			if !ok {
+				_ = streamlibp2p.Reset()
+				logging.WithPeer(s.logger, overlay).Debugf("handle protocol %s/%s: stream %s: peer stream limit reached", p.Name, p.Version, ss.Name)
+				return
+			}
 			stream := newStream(streamlibp2p)
+			stream.release = release
+			defer stream.done()
 
 			// exchange headers
 			if err := handleHeaders(ss.Headler, stream, overlay); err != nil {
-				s.logger.Debugf("handle protocol %s/%s: stream %s: peer %s: handle headers: %v", p.Name, p.Version, ss.Name, overlay, err)
+				logging.WithPeer(s.logger, overlay).Debugf("handle protocol %s/%s: stream %s: handle headers: %v", p.Name, p.Version, ss.Name, err)
 				_ = stream.Reset()
 				return
 			}
 
-			ctx, cancel := context.WithCancel(s.ctx)
+			ctx, cancel := context.WithCancel(s.handlersCtx)
 
 			s.peers.addStream(peerID, streamlibp2p, cancel)
 			defer s.peers.removeStream(peerID, streamlibp2p)
@@ -464,35 +678,51 @@ func (s *Service) AddProtocol(p p2p.ProtocolSpec) (err error) {
 			// silently ignore if the peer is not providing tracing
 			ctx, err := s.tracer.WithContextFromHeaders(ctx, stream.Headers())
 			if err != nil && !errors.Is(err, tracing.ErrContextNotFound) {
-				s.logger.Debugf("handle protocol %s/%s: stream %s: peer %s: get tracing context: %v", p.Name, p.Version, ss.Name, overlay, err)
+				logging.WithPeer(s.logger, overlay).Debugf("handle protocol %s/%s: stream %s: get tracing context: %v", p.Name, p.Version, ss.Name, err)
 				_ = stream.Reset()
 				return
 			}
 
-			logger := tracing.NewLoggerWithTraceID(ctx, s.logger)
+			logger := tracing.NewLoggerWithTraceID(ctx, s.logger).WithFields(logging.PeerFields(overlay))
 
 			s.metrics.HandledStreamCount.Inc()
-			if err := ss.Handler(ctx, p2p.Peer{Address: overlay, FullNode: full}, stream); err != nil {
+			handle := func() error {
+				return ss.Handler(ctx, p2p.Peer{Address: overlay, FullNode: full}, stream)
+			}
+			if s.protocolBreakers != nil {
+				err = s.protocolBreakers.execute(overlay, p.Name+"/"+p.Version, handle)
+			} else {
+				err = handle()
+			}
+			if err != nil {
+				if errors.Is(err, breaker.ErrClosed) {
+					_ = stream.Reset()
+				}
+
 				var de *p2p.DisconnectError
 				if errors.As(err, &de) {
+					reason := p2p.DisconnectReasonRejected
+					if errors.Is(err, context.DeadlineExceeded) {
+						reason = p2p.DisconnectReasonTimeout
+					}
 					_ = stream.Reset()
-					_ = s.Disconnect(overlay)
+					_ = s.DisconnectWithReason(overlay, reason)
 				}
 
 				var bpe *p2p.BlockPeerError
 				if errors.As(err, &bpe) {
 					_ = stream.Reset()
-					if err := s.Blocklist(overlay, bpe.Duration()); err != nil {
+					reason := fmt.Sprintf("protocol %s/%s/%s: %v", p.Name, p.Version, ss.Name, bpe.Unwrap())
+					if err := s.blocklistWithReason(overlay, bpe.Duration(), reason); err != nil {
 						logger.Debugf("blocklist: could not blocklist peer %s: %v", peerID, err)
 						logger.Errorf("unable to blocklist peer %v", peerID)
 					}
-					logger.Tracef("blocklisted a peer %s", peerID)
 				}
 				// count unexpected requests
 				if errors.Is(err, p2p.ErrUnexpected) {
 					s.metrics.UnexpectedProtocolReqCount.Inc()
 				}
-				logger.Debugf("could not handle protocol %s/%s: stream %s: peer %s: error: %v", p.Name, p.Version, ss.Name, overlay, err)
+				logger.Debugf("could not handle protocol %s/%s: stream %s: error: %v", p.Name, p.Version, ss.Name, err)
 				return
 			}
 		})
@@ -524,22 +754,89 @@ func (s *Service) Addresses() (addreses []ma.Multiaddr, err error) {
 	return addreses, nil
 }
 
+// PeerID returns the libp2p peer ID of the node.
+func (s *Service) PeerID() libp2ppeer.ID {
+	return s.host.ID()
+}
+
 func (s *Service) NATManager() basichost.NATManager {
 	return s.natManager
 }
 
 func (s *Service) Blocklist(overlay swarm.Address, duration time.Duration) error {
-	if err := s.blocklist.Add(overlay, duration); err != nil {
+	return s.blocklistWithReason(overlay, duration, "")
+}
+
+// BlocklistPeer blocklists the peer for the duration, permanently if it is
+// zero, with the reason and returns its blocklist entry. Unlike Blocklist,
+// it disconnects the peer only if disconnect is true.
+func (s *Service) BlocklistPeer(overlay swarm.Address, duration time.Duration, reason string, disconnect bool) (p2p.BlockedPeer, error) {
+	if err := s.addToBlocklist(overlay, duration, reason); err != nil {
+		return p2p.BlockedPeer{}, err
+	}
+	if disconnect {
+		_ = s.DisconnectWithReason(overlay, p2p.DisconnectReasonBlocklisted)
+	}
+	return s.blocklist.Get(overlay)
+}
+
+// protocolBreakerClosed blocklists the peer for the backoff
+// duration of the breaker of its protocol which has closed.
+func (s *Service) protocolBreakerClosed(overlay swarm.Address, protocol string, backoff time.Duration) {
+	reason := fmt.Sprintf("breaker tripped for protocol %s", protocol)
+	if err := s.blocklistWithReason(overlay, backoff, reason); err != nil {
+		logging.WithPeer(s.logger, overlay).Debugf("protocol breaker: blocklist: %v", err)
+	}
+}
+
+// UnblocklistPeer removes the peer from the blocklist. It returns
+// storage.ErrNotFound if the peer is not blocklisted.
+func (s *Service) UnblocklistPeer(overlay swarm.Address) error {
+	return s.blocklist.Remove(overlay)
+}
+
+// ClearBlocklist removes all peers from the blocklist and returns
+// the number of the removed entries.
+func (s *Service) ClearBlocklist() (removed int, err error) {
+	return s.blocklist.Clear()
+}
+
+// blocklistWithReason blocklists the peer with the reason and
+// disconnects it, even if it could not be blocklisted.
+func (s *Service) blocklistWithReason(overlay swarm.Address, duration time.Duration, reason string) error {
+	err := s.addToBlocklist(overlay, duration, reason)
+	_ = s.DisconnectWithReason(overlay, p2p.DisconnectReasonBlocklisted)
+	return err
+}
+
+// addToBlocklist blocklists the peer and stores the reason and
+// the underlays of its connections with the blocklist entry.
+func (s *Service) addToBlocklist(overlay swarm.Address, duration time.Duration, reason string) error {
+	var underlays []ma.Multiaddr
+	if peerID, found := s.peers.peerID(overlay); found {
+		for _, c := range s.host.Network().ConnsToPeer(peerID) {
+			underlays = append(underlays, c.RemoteMultiaddr())
+		}
+	}
+	if err := s.blocklist.AddWithUnderlays(overlay, underlays, duration, reason); err != nil {
 		s.metrics.BlocklistedPeerErrCount.Inc()
-		_ = s.Disconnect(overlay)
 		return fmt.Errorf("blocklist peer %s: %v", overlay, err)
 	}
 	s.metrics.BlocklistedPeerCount.Inc()
-
-	_ = s.Disconnect(overlay)
+	s.persistentMetrics.BlocklistedPeerTotal.Inc()
+	logging.WithPeer(s.logger, overlay).Debugf("blocklisted peer for %s", duration)
+	s.publishPeerEvent(overlay, p2p.PeerEventBlocklisted)
 	return nil
 }
 
+// formatRemaining describes the remaining block duration of a blocklisted peer.
+func formatRemaining(remaining time.Duration) string {
+	if remaining == blocklist.PermanentRemaining {
+		return "permanently"
+	}
+	return "for " + remaining.Round(time.Second).String()
+}
+
 func buildHostAddress(peerID libp2ppeer.ID) (ma.Multiaddr, error) {
 	return ma.NewMultiaddr(fmt.Sprintf("/p2p/%s", peerID.Pretty()))
 }
@@ -555,6 +852,24 @@ func buildUnderlayAddress(addr ma.Multiaddr, peerID libp2ppeer.ID) (ma.Multiaddr
 }
 
 func (s *Service) Connect(ctx context.Context, addr ma.Multiaddr) (address *bzz.Address, err error) {
+	var (
+		overlay swarm.Address
+		stage   opentracing.Span
+	)
+	span, ctx := s.startConnectSpan(ctx, "libp2p-connect", "outbound", addr)
+	nextStage := func(name string) {
+		if stage != nil {
+			stage.Finish()
+		}
+		stage, _, _ = s.tracer.StartSpanFromContext(ctx, "libp2p-connect-"+name, nil)
+	}
+	defer func() {
+		if stage != nil {
+			stage.Finish()
+		}
+		finishConnectSpan(span, overlay, err)
+	}()
+
 	// Extract the peer ID from the multiaddr.
 	info, err := libp2ppeer.AddrInfoFromP2pAddr(addr)
 	if err != nil {
@@ -576,21 +891,49 @@ func (s *Service) Connect(ctx context.Context, addr ma.Multiaddr) (address *bzz.
 		return address, p2p.ErrAlreadyConnected
 	}
 
-	if err := s.connectionBreaker.Execute(func() error { return s.host.Connect(ctx, *info) }); err != nil {
+	// the dial stage includes the security and muxer negotiation
+	nextStage("dial")
+	// the overlay is not known before the handshake,
+	// so the breakers are keyed by the peer IDs
+	connectionBreaker := s.connectBreakers.Get(info.ID.String())
+	if err := connectionBreaker.Execute(func() error { return s.host.Connect(ctx, *info) }); err != nil {
 		if errors.Is(err, breaker.ErrClosed) {
 			s.metrics.ConnectBreakerCount.Inc()
-			return nil, p2p.NewConnectionBackoffError(err, s.connectionBreaker.ClosedUntil())
+			// the callers compare the time with the wall clock
+			return nil, p2p.NewConnectionBackoffError(err, time.Now().Add(connectionBreaker.Remaining()))
 		}
 		return nil, err
 	}
 
-	stream, err := s.newStreamForPeerID(ctx, info.ID, handshake.ProtocolName, handshake.ProtocolVersion, handshake.StreamName)
+	nextStage("handshake")
+	// the traced handshake stream is preferred, and the peers which do
+	// not support it negotiate the handshake stream without the headers
+	tracedID := protocol.ID(p2p.NewSwarmStreamName(handshake.ProtocolName, handshake.ProtocolVersion, handshakeTracedStreamName))
+	stream, err := s.openStream(ctx, info.ID,
+		tracedID,
+		protocol.ID(p2p.NewSwarmStreamName(handshake.ProtocolName, handshake.ProtocolVersion, handshake.StreamName)),
+	)
 	if err != nil {
 		_ = s.host.Network().ClosePeer(info.ID)
 		return nil, fmt.Errorf("connect new stream: %w", err)
 	}
 
-	handshakeStream := NewStream(stream)
+	handshakeStream := newStream(stream)
+
+	if stream.Protocol() == tracedID {
+		// propagate the trace of the handshake stage to the peer
+		headers := make(p2p.Headers)
+		stageCtx := tracing.WithContext(ctx, stage.Context())
+		if err := s.tracer.AddContextHeader(stageCtx, headers); err != nil {
+			s.logger.Debugf("connect: handshake: tracing context %s: %v", info.ID, err)
+		}
+		if err := sendHeaders(ctx, headers, handshakeStream); err != nil {
+			_ = handshakeStream.Reset()
+			_ = s.host.Network().ClosePeer(info.ID)
+			return nil, fmt.Errorf("handshake: send headers: %w", err)
+		}
+	}
+
 	i, err := s.handshakeService.Handshake(ctx, handshakeStream, stream.Conn().RemoteMultiaddr(), stream.Conn().RemotePeer())
 	if err != nil {
 		_ = handshakeStream.Reset()
@@ -604,22 +947,24 @@ func (s *Service) Connect(ctx context.Context, addr ma.Multiaddr) (address *bzz.
 		return nil, p2p.ErrDialLightNode
 	}
 
-	overlay := i.BzzAddress.Overlay
+	overlay = i.BzzAddress.Overlay
+	peerLogger := logging.WithPeer(s.logger, overlay)
+	s.gater.setOverlay(info.ID, overlay)
 
-	blocked, err := s.blocklist.Exists(overlay)
+	blocked, remaining, err := s.blocklist.ExistsWithRemaining(overlay)
 	if err != nil {
-		s.logger.Debugf("blocklisting: exists %s: %v", info.ID, err)
-		s.logger.Errorf("internal error while connecting with peer %s", info.ID)
+		peerLogger.Debugf("blocklisting: exists %s: %v", info.ID, err)
+		peerLogger.Errorf("internal error while connecting with peer %s", info.ID)
 		_ = handshakeStream.Reset()
 		_ = s.host.Network().ClosePeer(info.ID)
 		return nil, fmt.Errorf("peer blocklisted")
 	}
 
 	if blocked {
-		s.logger.Errorf("blocked connection to blocklisted peer %s", info.ID)
+		peerLogger.Errorf("blocked connection to peer %s blocklisted %s", info.ID, formatRemaining(remaining))
 		_ = handshakeStream.Reset()
 		_ = s.host.Network().ClosePeer(info.ID)
-		return nil, fmt.Errorf("peer blocklisted")
+		return nil, fmt.Errorf("peer blocklisted %s", formatRemaining(remaining))
 	}
 
 	if exists := s.peers.addIfNotExists(stream.Conn(), overlay, i.FullNode); exists {
@@ -636,6 +981,7 @@ func (s *Service) Connect(ctx context.Context, addr ma.Multiaddr) (address *bzz.
 		return nil, fmt.Errorf("connect full close %w", err)
 	}
 
+	nextStage("addressbook")
 	if i.FullNode {
 		err = s.addressbook.Put(overlay, *i.BzzAddress)
 		if err != nil {
@@ -644,11 +990,15 @@ func (s *Service) Connect(ctx context.Context, addr ma.Multiaddr) (address *bzz.
 		}
 	}
 
+	nextStage("protocols")
 	s.protocolsmu.RLock()
 	for _, tn := range s.protocols {
+		if tn.FullNodeOnly && !i.FullNode {
+			continue
+		}
 		if tn.ConnectOut != nil {
 			if err := tn.ConnectOut(ctx, p2p.Peer{Address: overlay, FullNode: i.FullNode, EthereumAddress: i.BzzAddress.EthereumAddress}); err != nil {
-				s.logger.Debugf("connectOut: protocol: %s, version:%s, peer: %s: %v", tn.Name, tn.Version, overlay, err)
+				peerLogger.Debugf("connectOut: protocol: %s, version:%s: %v", tn.Name, tn.Version, err)
 				_ = s.Disconnect(overlay)
 				s.protocolsmu.RUnlock()
 				return nil, fmt.Errorf("connectOut: protocol: %s, version:%s: %w", tn.Name, tn.Version, err)
@@ -663,19 +1013,28 @@ func (s *Service) Connect(ctx context.Context, addr ma.Multiaddr) (address *bzz.
 	}
 
 	s.metrics.CreatedConnectionCount.Inc()
+	s.persistentMetrics.OutboundConnectionTotal.Inc()
+	s.publishPeerEvent(overlay, p2p.PeerEventConnected)
 
-	s.logger.Debugf("successfully connected to peer %s%s (outbound)", i.BzzAddress.ShortString(), i.LightString())
-	s.logger.Infof("successfully connected to peer %s%s (outbound)", overlay, i.LightString())
+	peerLogger.Infof("successfully connected to peer%s (outbound)", i.LightString())
 	return i.BzzAddress, nil
 }
 
 func (s *Service) Disconnect(overlay swarm.Address) error {
+	return s.DisconnectWithReason(overlay, p2p.DisconnectReasonLocal)
+}
+
+func (s *Service) DisconnectWithReason(overlay swarm.Address, reason p2p.DisconnectReason) error {
 	s.metrics.DisconnectCount.Inc()
 
-	s.logger.Debugf("libp2p disconnect: disconnecting peer %s", overlay)
+	peerLogger := logging.WithPeer(s.logger, overlay)
+	peerLogger.Debugf("libp2p disconnect: disconnecting peer: %s", reason)
 
 	// found is checked at the bottom of the function
 	found, full, peerID := s.peers.remove(overlay)
+	if s.protocolBreakers != nil {
+		s.protocolBreakers.remove(overlay)
+	}
 
 	_ = s.host.Network().ClosePeer(peerID)
 
@@ -685,29 +1044,38 @@ func (s *Service) Disconnect(overlay swarm.Address) error {
 	for _, tn := range s.protocols {
 		if tn.DisconnectOut != nil {
 			if err := tn.DisconnectOut(peer); err != nil {
-				s.logger.Debugf("disconnectOut: protocol: %s, version:%s, peer: %s: %v", tn.Name, tn.Version, overlay, err)
+				peerLogger.Debugf("disconnectOut: protocol: %s, version:%s: %v", tn.Name, tn.Version, err)
 			}
 		}
 	}
 	s.protocolsmu.RUnlock()
 
 	if s.notifier != nil {
-		s.notifier.Disconnected(peer)
+		s.notifier.Disconnected(peer, reason)
 	}
 	if s.lightNodes != nil {
 		s.lightNodes.Disconnected(peer)
 	}
 
 	if !found {
-		s.logger.Debugf("libp2p disconnect: peer %s not found", overlay)
+		peerLogger.Debug("libp2p disconnect: peer not found")
 		return p2p.ErrPeerNotFound
 	}
 
+	s.publishPeerEvent(overlay, p2p.PeerEventDisconnected)
 	return nil
 }
 
-// disconnected is a registered peer registry event
+// disconnected is a registered peer registry event for
+// the connections that were closed by the peer or the network
 func (s *Service) disconnected(address swarm.Address) {
+	peerLogger := logging.WithPeer(s.logger, address)
+	peerLogger.Debug("libp2p disconnect: peer disconnected")
+
+	if s.protocolBreakers != nil {
+		s.protocolBreakers.remove(address)
+	}
+
 	peer := p2p.Peer{Address: address}
 	peerID, found := s.peers.peerID(address)
 	if found {
@@ -721,7 +1089,7 @@ func (s *Service) disconnected(address swarm.Address) {
 	for _, tn := range s.protocols {
 		if tn.DisconnectIn != nil {
 			if err := tn.DisconnectIn(peer); err != nil {
-				s.logger.Debugf("disconnectIn: protocol: %s, version:%s, peer: %s: %v", tn.Name, tn.Version, address.String(), err)
+				peerLogger.Debugf("disconnectIn: protocol: %s, version:%s: %v", tn.Name, tn.Version, err)
 			}
 		}
 	}
@@ -729,19 +1097,41 @@ func (s *Service) disconnected(address swarm.Address) {
 	s.protocolsmu.RUnlock()
 
 	if s.notifier != nil {
-		s.notifier.Disconnected(peer)
+		s.notifier.Disconnected(peer, p2p.DisconnectReasonRemote)
 	}
 	if s.lightNodes != nil {
 		s.lightNodes.Disconnected(peer)
 	}
+
+	s.publishPeerEvent(address, p2p.PeerEventDisconnected)
 }
 
 func (s *Service) Peers() []p2p.Peer {
 	return s.peers.peers()
 }
 
-func (s *Service) BlocklistedPeers() ([]p2p.Peer, error) {
-	return s.blocklist.Peers()
+// LastInboundConnection returns the time of the last inbound peer
+// connection, also across restarts. The zero time is returned if
+// there was none.
+func (s *Service) LastInboundConnection() time.Time {
+	return s.persistentMetrics.InboundConnectionTotal.LastIncrement()
+}
+
+// Blocklisted reports whether the peer is on the blocklist.
+func (s *Service) Blocklisted(overlay swarm.Address) (bool, error) {
+	return s.blocklist.Exists(overlay)
+}
+
+// BlocklistedPeers returns the blocklisted peers. The corrupted blocklist
+// entries are logged and skipped, so that they do not hide the valid ones.
+func (s *Service) BlocklistedPeers() ([]p2p.BlockedPeer, error) {
+	peers, err := s.blocklist.Peers()
+	var corrupted *blocklist.CorruptedEntriesError
+	if errors.As(err, &corrupted) {
+		s.logger.Warningf("blocklisted peers: %v", err)
+		return peers, nil
+	}
+	return peers, err
 }
 
 func (s *Service) NewStream(ctx context.Context, overlay swarm.Address, headers p2p.Headers, protocolName, protocolVersion, streamName string) (p2p.Stream, error) {
@@ -750,18 +1140,26 @@ func (s *Service) NewStream(ctx context.Context, overlay swarm.Address, headers
 		return nil, p2p.ErrPeerNotFound
 	}
 
+	release, ok := s.acquireStream(peerID, overlay)
+	if !ok {
+		return nil, p2p.ErrStreamLimit
+	}
+
 	streamlibp2p, err := s.newStreamForPeerID(ctx, peerID, protocolName, protocolVersion, streamName)
 	if err != nil {
+		release()
 		return nil, fmt.Errorf("new stream for peerid: %w", err)
 	}
 
 	stream := newStream(streamlibp2p)
+	stream.release = release
 
 	// tracing: add span context header
 	if headers == nil {
 		headers = make(p2p.Headers)
 	}
 	if err := s.tracer.AddContextHeader(ctx, headers); err != nil && !errors.Is(err, tracing.ErrContextNotFound) {
+		_ = stream.Reset()
 		return nil, err
 	}
 
@@ -774,9 +1172,52 @@ func (s *Service) NewStream(ctx context.Context, overlay swarm.Address, headers
 	return stream, nil
 }
 
+// acquireStream counts a new open stream of the peer if it is within the
+// stream limit of the peer. The returned function uncounts the stream.
+func (s *Service) acquireStream(peerID libp2ppeer.ID, overlay swarm.Address) (release func(), ok bool) {
+	limit := s.streamLimit
+	if n, ok := s.notifier.(neighbourhood); ok && n.IsWithinDepth(overlay) {
+		limit = s.protectedLimit
+	}
+	if !s.peers.acquireStream(peerID, limit) {
+		s.metrics.StreamLimitResetCount.Inc()
+		return nil, false
+	}
+	s.metrics.OpenStreams.Inc()
+	return func() {
+		s.peers.releaseStream(peerID)
+		s.metrics.OpenStreams.Dec()
+	}, true
+}
+
+// OpenStreams returns the number of currently open protocol streams of
+// the peer in both directions.
+func (s *Service) OpenStreams(overlay swarm.Address) (int, error) {
+	peerID, found := s.peers.peerID(overlay)
+	if !found {
+		return 0, p2p.ErrPeerNotFound
+	}
+	return s.peers.streamCount(peerID), nil
+}
+
+// ConnectionInfo returns the details of the connection with a connected
+// peer. It returns p2p.ErrPeerNotFound if the peer is not connected.
+func (s *Service) ConnectionInfo(overlay swarm.Address) (p2p.ConnectionInfo, error) {
+	info, found := s.peers.connectionInfo(overlay)
+	if !found {
+		return p2p.ConnectionInfo{}, p2p.ErrPeerNotFound
+	}
+	return info, nil
+}
+
 func (s *Service) newStreamForPeerID(ctx context.Context, peerID libp2ppeer.ID, protocolName, protocolVersion, streamName string) (network.Stream, error) {
-	swarmStreamName := p2p.NewSwarmStreamName(protocolName, protocolVersion, streamName)
-	st, err := s.host.NewStream(ctx, peerID, protocol.ID(swarmStreamName))
+	return s.openStream(ctx, peerID, protocol.ID(p2p.NewSwarmStreamName(protocolName, protocolVersion, streamName)))
+}
+
+// openStream opens a stream to the peer with the first of the protocol IDs
+// which the peer supports.
+func (s *Service) openStream(ctx context.Context, peerID libp2ppeer.ID, pids ...protocol.ID) (network.Stream, error) {
+	st, err := s.host.NewStream(ctx, peerID, pids...)
 	if err != nil {
 		if st != nil {
 			s.logger.Debug("stream experienced unexpected early close")
@@ -785,13 +1226,21 @@ func (s *Service) newStreamForPeerID(ctx context.Context, peerID libp2ppeer.ID,
 		if err == multistream.ErrNotSupported || err == multistream.ErrIncorrectVersion {
 			return nil, p2p.NewIncompatibleStreamError(err)
 		}
-		return nil, fmt.Errorf("create stream %q to %q: %w", swarmStreamName, peerID, err)
+		return nil, fmt.Errorf("create stream %q to %q: %w", pids, peerID, err)
 	}
 	s.metrics.CreatedStreamCount.Inc()
 	return st, nil
 }
 
 func (s *Service) Close() error {
+	if err := s.persistentMetrics.flush(); err != nil {
+		s.logger.Debugf("libp2p close: flush metrics: %v", err)
+		s.logger.Error("libp2p close: unable to persist connection counters")
+	}
+	if err := s.blocklist.Flush(); err != nil {
+		s.logger.Debugf("libp2p close: flush blocklist attempts: %v", err)
+		s.logger.Error("libp2p close: unable to persist blocklisted peer attempts")
+	}
 	if err := s.libp2pPeerstore.Close(); err != nil {
 		return err
 	}
@@ -824,10 +1273,87 @@ func (s *Service) Ready() {
 	close(s.ready)
 }
 
+// Halt stops accepting new connections and protocol streams.
 func (s *Service) Halt() {
+	s.handlersMu.Lock()
+	defer s.handlersMu.Unlock()
+
 	close(s.halt)
 }
 
+// startHandler registers a protocol handler that is about to run. It
+// returns false if the service is halted and the stream must be refused.
+func (s *Service) startHandler() bool {
+	s.handlersMu.Lock()
+	defer s.handlersMu.Unlock()
+
+	select {
+	case <-s.halt:
+		return false
+	default:
+	}
+	s.handlers.Add(1)
+	return true
+}
+
+// DisconnectAll disconnects from all connected peers. It returns
+// the context error if the peers are not disconnected in time.
+func (s *Service) DisconnectAll(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, peer := range s.peers.peers() {
+		wg.Add(1)
+		go func(overlay swarm.Address) {
+			defer wg.Done()
+			_ = s.Disconnect(overlay)
+		}(peer.Address)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Drain waits for the running protocol handlers to return. It should be
+// called after Halt, so that no new handlers are started. The handlers that
+// are still running after the grace period have their contexts cancelled
+// and ErrHandlersCancelled is returned once they return. The context error
+// is returned if the handlers do not return before the context is done.
+func (s *Service) Drain(ctx context.Context, grace time.Duration) error {
+	done := make(chan struct{})
+	go func() {
+		s.handlers.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	s.handlersCancel()
+
+	select {
+	case <-done:
+		return ErrHandlersCancelled
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (s *Service) Ping(ctx context.Context, addr ma.Multiaddr) (rtt time.Duration, err error) {
 	info, err := libp2ppeer.AddrInfoFromP2pAddr(addr)
 	if err != nil {
@@ -844,3 +1370,29 @@ func (s *Service) Ping(ctx context.Context, addr ma.Multiaddr) (rtt time.Duratio
 		return res.RTT, res.Error
 	}
 }
+
+// startConnectSpan starts the root tracing span of a connection attempt.
+// The stages of the attempt are traced as its child spans.
+func (s *Service) startConnectSpan(ctx context.Context, operationName, direction string, underlay ma.Multiaddr) (opentracing.Span, context.Context) {
+	span, _, ctx := s.tracer.StartSpanFromContext(ctx, operationName, nil,
+		opentracing.Tag{Key: "underlay", Value: underlay.String()},
+		opentracing.Tag{Key: "direction", Value: direction},
+	)
+	return span, ctx
+}
+
+// finishConnectSpan tags the connection attempt span with the peer overlay
+// and the outcome of the attempt and finishes it.
+func finishConnectSpan(span opentracing.Span, overlay swarm.Address, err error) {
+	if !overlay.IsZero() {
+		span.SetTag("overlay", overlay.String())
+	}
+	if err != nil {
+		span.SetTag("outcome", "failure")
+		span.SetTag("error", true)
+		span.LogKV("error", err.Error())
+	} else {
+		span.SetTag("outcome", "success")
+	}
+	span.Finish()
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/ethersphere/bee/pkg/statestore/mock"
 	"github.com/ethersphere/bee/pkg/swarm"
 	"github.com/ethersphere/bee/pkg/topology/lightnode"
+	"github.com/ethersphere/bee/pkg/tracing"
 	"github.com/multiformats/go-multiaddr"
 )
 
@@ -32,6 +33,7 @@ type libp2pServiceOpts struct {
 	MockPeerKey *ecdsa.PrivateKey
 	libp2pOpts  libp2p.Options
 	lightNodes  *lightnode.Container
+	tracer      *tracing.Tracer
 }
 
 // newService constructs a new libp2p service.
@@ -83,7 +85,7 @@ func newService(t *testing.T, networkID uint64, o libp2pServiceOpts) (s *libp2p.
 		BlockHash: blockHash,
 	}
 
-	s, err = libp2p.New(ctx, crypto.NewDefaultSigner(swarmKey), networkID, overlay, addr, o.Addressbook, statestore, o.lightNodes, senderMatcher, o.Logger, nil, opts)
+	s, err = libp2p.New(ctx, crypto.NewDefaultSigner(swarmKey), networkID, overlay, addr, o.Addressbook, statestore, o.lightNodes, senderMatcher, o.Logger, o.tracer, opts)
 	if err != nil {
 		t.Fatal(err)
 	}
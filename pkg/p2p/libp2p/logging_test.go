@@ -0,0 +1,114 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package libp2p_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/logging"
+	"github.com/ethersphere/bee/pkg/p2p/libp2p"
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/sirupsen/logrus"
+)
+
+func TestPeerLogFields(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var buf1, buf2 lockedBuffer
+	s1, overlay1 := newService(t, 1, libp2pServiceOpts{
+		Logger:     logging.New(&buf1, logrus.DebugLevel),
+		libp2pOpts: libp2p.Options{FullNode: true},
+	})
+	s2, overlay2 := newService(t, 1, libp2pServiceOpts{
+		Logger:     logging.New(&buf2, logrus.DebugLevel),
+		libp2pOpts: libp2p.Options{FullNode: true},
+	})
+
+	addr := serviceUnderlayAddress(t, s1)
+
+	// connect
+	if _, err := s2.Connect(ctx, addr); err != nil {
+		t.Fatal(err)
+	}
+	expectPeers(t, s2, overlay1)
+	expectPeersEventually(t, s1, overlay2)
+
+	expectPeerLog(t, &buf2, "successfully connected to peer (outbound)", overlay1)
+	expectPeerLog(t, &buf1, "successfully connected to peer (inbound)", overlay2)
+
+	// disconnect
+	if err := s2.Disconnect(overlay1); err != nil {
+		t.Fatal(err)
+	}
+	expectPeers(t, s2)
+	expectPeersEventually(t, s1)
+
+	expectPeerLog(t, &buf2, "libp2p disconnect: disconnecting peer", overlay1)
+	expectPeerLog(t, &buf1, "libp2p disconnect: peer disconnected", overlay2)
+
+	// blocklist
+	if _, err := s2.Connect(ctx, addr); err != nil {
+		t.Fatal(err)
+	}
+	expectPeers(t, s2, overlay1)
+
+	if err := s2.Blocklist(overlay1, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	expectPeerLog(t, &buf2, "blocklisted peer for 1m0s", overlay1)
+}
+
+// expectPeerLog waits for a log line with the message
+// and the structured fields that identify the peer.
+func expectPeerLog(t *testing.T, buf *lockedBuffer, msg string, peer swarm.Address) {
+	t.Helper()
+
+	fields := []string{
+		logging.PeerKey + "=" + peer.String(),
+		logging.PeerShortKey + "=" + peer.String()[:8],
+	}
+	for i := 0; i < 100; i++ {
+		for _, line := range strings.Split(buf.String(), "\n") {
+			if !strings.Contains(line, msg) {
+				continue
+			}
+			found := true
+			for _, f := range fields {
+				if !strings.Contains(line, f) {
+					found = false
+				}
+			}
+			if found {
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no log line %q with fields %v in:\n%s", msg, fields, buf.String())
+}
+
+// lockedBuffer is a bytes.Buffer that is safe for concurrent use.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
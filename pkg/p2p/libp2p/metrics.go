@@ -6,6 +6,8 @@ package libp2p
 
 import (
 	m "github.com/ethersphere/bee/pkg/metrics"
+	"github.com/ethersphere/bee/pkg/metrics/persistent"
+	"github.com/ethersphere/bee/pkg/storage"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -13,16 +15,20 @@ type metrics struct {
 	// all metrics fields must be exported
 	// to be able to return them by Metrics()
 	// using reflection
-	CreatedConnectionCount     prometheus.Counter
-	HandledConnectionCount     prometheus.Counter
-	CreatedStreamCount         prometheus.Counter
-	HandledStreamCount         prometheus.Counter
-	BlocklistedPeerCount       prometheus.Counter
-	BlocklistedPeerErrCount    prometheus.Counter
-	DisconnectCount            prometheus.Counter
-	ConnectBreakerCount        prometheus.Counter
-	UnexpectedProtocolReqCount prometheus.Counter
-	KickedOutPeersCount        prometheus.Counter
+	CreatedConnectionCount      prometheus.Counter
+	HandledConnectionCount      prometheus.Counter
+	CreatedStreamCount          prometheus.Counter
+	HandledStreamCount          prometheus.Counter
+	BlocklistedPeerCount        prometheus.Counter
+	BlocklistedPeerErrCount     prometheus.Counter
+	BlocklistedPeerAttemptCount prometheus.Counter
+	GaterRejectedCount          prometheus.Counter
+	DisconnectCount             prometheus.Counter
+	ConnectBreakerCount         prometheus.Counter
+	UnexpectedProtocolReqCount  prometheus.Counter
+	KickedOutPeersCount         prometheus.Counter
+	StreamLimitResetCount       prometheus.Counter
+	OpenStreams                 prometheus.Gauge
 }
 
 func newMetrics() metrics {
@@ -65,6 +71,18 @@ func newMetrics() metrics {
 			Name:      "blocklisted_peer_err_count",
 			Help:      "Number of peers we've been unable to blocklist.",
 		}),
+		BlocklistedPeerAttemptCount: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "blocklisted_peer_attempt_count",
+			Help:      "Number of rejected connection attempts of blocklisted peers.",
+		}),
+		GaterRejectedCount: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "gater_rejected_count",
+			Help:      "Number of connections with blocklisted peers or ip addresses rejected before the handshake.",
+		}),
 		DisconnectCount: prometheus.NewCounter(prometheus.CounterOpts{
 			Namespace: m.Namespace,
 			Subsystem: subsystem,
@@ -89,9 +107,78 @@ func newMetrics() metrics {
 			Name:      "kickedout_peers_count",
 			Help:      "Number of total kicked-out peers.",
 		}),
+		StreamLimitResetCount: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "stream_limit_reset_count",
+			Help:      "Number of streams refused because the peer reached its stream limit.",
+		}),
+		OpenStreams: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "open_streams",
+			Help:      "Number of currently open protocol streams of all peers.",
+		}),
+	}
+}
+
+// persistentMetrics groups the libp2p counters with totals
+// that are kept in the state store across restarts.
+type persistentMetrics struct {
+	InboundConnectionTotal  *persistent.Counter
+	OutboundConnectionTotal *persistent.Counter
+	BlocklistedPeerTotal    *persistent.Counter
+}
+
+func newPersistentMetrics(store storage.StateStorer) (pm persistentMetrics, err error) {
+	subsystem := "libp2p"
+
+	if pm.InboundConnectionTotal, err = persistent.NewCounter(store, prometheus.CounterOpts{
+		Namespace: m.Namespace,
+		Subsystem: subsystem,
+		Name:      "inbound_connection_total",
+		Help:      "Number of inbound peer connections since the node was first started.",
+	}, persistent.Options{}); err != nil {
+		return persistentMetrics{}, err
+	}
+	if pm.OutboundConnectionTotal, err = persistent.NewCounter(store, prometheus.CounterOpts{
+		Namespace: m.Namespace,
+		Subsystem: subsystem,
+		Name:      "outbound_connection_total",
+		Help:      "Number of outbound peer connections since the node was first started.",
+	}, persistent.Options{}); err != nil {
+		return persistentMetrics{}, err
+	}
+	if pm.BlocklistedPeerTotal, err = persistent.NewCounter(store, prometheus.CounterOpts{
+		Namespace: m.Namespace,
+		Subsystem: subsystem,
+		Name:      "blocklisted_peer_total",
+		Help:      "Number of peer blocklistings since the node was first started.",
+	}, persistent.Options{}); err != nil {
+		return persistentMetrics{}, err
+	}
+	return pm, nil
+}
+
+// flush persists the totals of the counters.
+func (pm persistentMetrics) flush() error {
+	for _, c := range []*persistent.Counter{
+		pm.InboundConnectionTotal,
+		pm.OutboundConnectionTotal,
+		pm.BlocklistedPeerTotal,
+	} {
+		if err := c.Flush(); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 func (s *Service) Metrics() []prometheus.Collector {
-	return m.PrometheusCollectorsFromFields(s.metrics)
+	collectors := append(
+		m.PrometheusCollectorsFromFields(s.metrics),
+		m.PrometheusCollectorsFromFields(s.persistentMetrics)...,
+	)
+	collectors = append(collectors, s.breakerMetrics.Metrics()...)
+	return append(collectors, s.blocklist.Metrics()...)
 }
@@ -23,6 +23,7 @@ type peerRegistry struct {
 	full        map[libp2ppeer.ID]bool                      // map to track whether a node is full or light node (true=full)
 	connections map[libp2ppeer.ID]map[network.Conn]struct{} // list of connections for safe removal on Disconnect notification
 	streams     map[libp2ppeer.ID]map[network.Stream]context.CancelFunc
+	openStreams map[libp2ppeer.ID]int // number of open protocol streams of a peer in both directions
 	mu          sync.RWMutex
 
 	//nolint:misspell
@@ -41,6 +42,7 @@ func newPeerRegistry() *peerRegistry {
 		full:        make(map[libp2ppeer.ID]bool),
 		connections: make(map[libp2ppeer.ID]map[network.Conn]struct{}),
 		streams:     make(map[libp2ppeer.ID]map[network.Stream]context.CancelFunc),
+		openStreams: make(map[libp2ppeer.ID]int),
 
 		Notifiee: new(network.NoopNotifiee),
 	}
@@ -115,6 +117,39 @@ func (r *peerRegistry) removeStream(peerID libp2ppeer.ID, stream network.Stream)
 	delete(r.streams[peerID], stream)
 }
 
+// acquireStream counts a new open stream of the peer. It returns false
+// without counting the stream if the peer already has limit open streams.
+func (r *peerRegistry) acquireStream(peerID libp2ppeer.ID, limit int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.openStreams[peerID] >= limit {
+		return false
+	}
+	r.openStreams[peerID]++
+	return true
+}
+
+// releaseStream uncounts an open stream of the peer. The count is kept after
+// the peer disconnects, until all of its streams are released.
+func (r *peerRegistry) releaseStream(peerID libp2ppeer.ID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.openStreams[peerID] <= 1 {
+		delete(r.openStreams, peerID)
+		return
+	}
+	r.openStreams[peerID]--
+}
+
+func (r *peerRegistry) streamCount(peerID libp2ppeer.ID) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.openStreams[peerID]
+}
+
 func (r *peerRegistry) peers() []p2p.Peer {
 	r.mu.RLock()
 	peers := make([]p2p.Peer, 0, len(r.overlays))
@@ -162,6 +197,41 @@ func (r *peerRegistry) peerID(overlay swarm.Address) (peerID libp2ppeer.ID, foun
 	return peerID, found
 }
 
+// connectionInfo returns the remote addresses of the connections with the
+// peer and the direction and the opening time of the first one of them.
+func (r *peerRegistry) connectionInfo(overlay swarm.Address) (info p2p.ConnectionInfo, found bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	peerID, found := r.underlays[overlay.ByteString()]
+	if !found {
+		return p2p.ConnectionInfo{}, false
+	}
+
+	var (
+		first network.Stat
+		seen  bool
+	)
+	for c := range r.connections[peerID] {
+		info.Underlays = append(info.Underlays, c.RemoteMultiaddr())
+		if stat := c.Stat(); !seen || stat.Opened.Before(first.Opened) {
+			first, seen = stat, true
+		}
+	}
+	sort.Slice(info.Underlays, func(i, j int) bool {
+		return info.Underlays[i].String() < info.Underlays[j].String()
+	})
+
+	switch first.Direction {
+	case network.DirInbound:
+		info.Direction = p2p.DirectionInbound
+	case network.DirOutbound:
+		info.Direction = p2p.DirectionOutbound
+	}
+	info.Opened = first.Opened
+	return info, true
+}
+
 func (r *peerRegistry) overlay(peerID libp2ppeer.ID) (swarm.Address, bool) {
 	r.mu.RLock()
 	overlay, found := r.overlays[peerID]
@@ -402,11 +402,84 @@ func TestPing(t *testing.T) {
 	}
 }
 
+// TestLightNodeProtocols tests that light nodes do not register the full
+// node only protocols and that the connect hooks of those protocols are
+// not called for light node peers.
+func TestLightNodeProtocols(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	full, fullOverlay := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+		FullNode: true,
+	}})
+	light, lightOverlay := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+		FullNode: false,
+	}})
+
+	var connects int32
+	storageProtocol := func() p2p.ProtocolSpec {
+		spec := newTestProtocol(func(_ context.Context, _ p2p.Peer, _ p2p.Stream) error {
+			return nil
+		})
+		spec.Name = testStorageProtocolName
+		spec.FullNodeOnly = true
+		spec.ConnectIn = func(context.Context, p2p.Peer) error {
+			atomic.AddInt32(&connects, 1)
+			return nil
+		}
+		spec.ConnectOut = spec.ConnectIn
+		return spec
+	}
+
+	for _, s := range []*libp2p.Service{full, light} {
+		if err := s.AddProtocol(newTestProtocol(func(_ context.Context, _ p2p.Peer, _ p2p.Stream) error {
+			return nil
+		})); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.AddProtocol(storageProtocol()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := light.Connect(ctx, serviceUnderlayAddress(t, full)); err != nil {
+		t.Fatal(err)
+	}
+	expectPeers(t, light, fullOverlay)
+	expectPeersEventually(t, full, lightOverlay)
+
+	if got := atomic.LoadInt32(&connects); got != 0 {
+		t.Fatalf("got %d storage protocol connect hook calls, want none", got)
+	}
+
+	// the light node uses the storage protocol of the full node
+	stream, err := light.NewStream(ctx, fullOverlay, nil, testStorageProtocolName, testProtocolVersion, testStreamName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the full node negotiates only the other protocols with the light node
+	stream, err = full.NewStream(ctx, lightOverlay, nil, testProtocolName, testProtocolVersion, testStreamName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+	_, err = full.NewStream(ctx, lightOverlay, nil, testStorageProtocolName, testProtocolVersion, testStreamName)
+	expectErrNotSupported(t, err)
+}
+
 const (
 	testProtocolName     = "testing"
 	testProtocolVersion  = "2.3.4"
 	testStreamName       = "messages"
 	testSecondStreamName = "cookies"
+
+	testStorageProtocolName = "storage-testing"
 )
 
 func newTestProtocol(h p2p.HandlerFunc) p2p.ProtocolSpec {
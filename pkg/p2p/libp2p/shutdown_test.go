@@ -0,0 +1,148 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package libp2p_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/p2p"
+	"github.com/ethersphere/bee/pkg/p2p/libp2p"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+// newSlowHandlerServices connects two services and opens a stream to the
+// handler on the first one. It returns when the handler is running.
+func newSlowHandlerServices(t *testing.T, h p2p.HandlerFunc) (s1, s2 *libp2p.Service, overlay1 swarm.Address) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s1, overlay1 = newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+		FullNode: true,
+	}})
+	s2, _ = newService(t, 1, libp2pServiceOpts{})
+
+	started := make(chan struct{})
+	if err := s1.AddProtocol(newTestProtocol(func(ctx context.Context, p p2p.Peer, s p2p.Stream) error {
+		close(started)
+		return h(ctx, p, s)
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s2.Connect(ctx, serviceUnderlayAddress(t, s1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s2.NewStream(ctx, overlay1, nil, testProtocolName, testProtocolVersion, testStreamName); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler not started")
+	}
+	return s1, s2, overlay1
+}
+
+func TestDrain(t *testing.T) {
+	t.Run("handlers return", func(t *testing.T) {
+		release := make(chan struct{})
+		cancelled := make(chan bool, 1)
+		s1, s2, overlay1 := newSlowHandlerServices(t, func(ctx context.Context, _ p2p.Peer, _ p2p.Stream) error {
+			select {
+			case <-release:
+				cancelled <- false
+			case <-ctx.Done():
+				cancelled <- true
+			}
+			return nil
+		})
+
+		s1.Halt()
+
+		// new streams are refused
+		if _, err := s2.NewStream(context.Background(), overlay1, nil, testProtocolName, testProtocolVersion, testStreamName); err == nil {
+			t.Fatal("expected the stream to be refused")
+		}
+
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			close(release)
+		}()
+
+		if err := s1.Drain(context.Background(), 5*time.Second); err != nil {
+			t.Fatal(err)
+		}
+		if <-cancelled {
+			t.Fatal("handler cancelled")
+		}
+	})
+
+	t.Run("handlers cancelled", func(t *testing.T) {
+		cancelled := make(chan bool, 1)
+		s1, _, _ := newSlowHandlerServices(t, func(ctx context.Context, _ p2p.Peer, _ p2p.Stream) error {
+			<-ctx.Done()
+			cancelled <- true
+			return ctx.Err()
+		})
+
+		s1.Halt()
+
+		start := time.Now()
+		if err := s1.Drain(context.Background(), 100*time.Millisecond); !errors.Is(err, libp2p.ErrHandlersCancelled) {
+			t.Fatalf("got error %v, want %v", err, libp2p.ErrHandlersCancelled)
+		}
+		if d := time.Since(start); d < 100*time.Millisecond {
+			t.Fatalf("handlers cancelled after %s, before the grace period", d)
+		}
+		if !<-cancelled {
+			t.Fatal("handler not cancelled")
+		}
+	})
+
+	t.Run("handlers stuck", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		s1, _, _ := newSlowHandlerServices(t, func(context.Context, p2p.Peer, p2p.Stream) error {
+			<-release
+			return nil
+		})
+
+		s1.Halt()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		if err := s1.Drain(ctx, 100*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+}
+
+func TestDisconnectAll(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s1, overlay1 := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+		FullNode: true,
+	}})
+	s2, overlay2 := newService(t, 1, libp2pServiceOpts{})
+
+	if _, err := s2.Connect(ctx, serviceUnderlayAddress(t, s1)); err != nil {
+		t.Fatal(err)
+	}
+	expectPeers(t, s2, overlay1)
+	expectPeersEventually(t, s1, overlay2)
+
+	if err := s1.DisconnectAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expectPeers(t, s1)
+	expectPeersEventually(t, s2)
+}
@@ -7,6 +7,7 @@ package libp2p
 import (
 	"errors"
 	"io"
+	"sync"
 	"time"
 
 	"github.com/ethersphere/bee/pkg/p2p"
@@ -23,6 +24,10 @@ type stream struct {
 	network.Stream
 	headers         map[string][]byte
 	responseHeaders map[string][]byte
+	// release removes the stream from the per-peer accounting,
+	// it is nil for the streams that are not accounted
+	release     func()
+	releaseOnce sync.Once
 }
 
 func NewStream(s network.Stream) p2p.Stream {
@@ -40,6 +45,25 @@ func (s *stream) ResponseHeaders() p2p.Headers {
 	return s.responseHeaders
 }
 
+// Close closes the stream and releases it from the per-peer accounting.
+func (s *stream) Close() error {
+	defer s.done()
+	return s.Stream.Close()
+}
+
+// Reset resets the stream and releases it from the per-peer accounting.
+func (s *stream) Reset() error {
+	defer s.done()
+	return s.Stream.Reset()
+}
+
+// done releases the stream from the per-peer accounting once.
+func (s *stream) done() {
+	if s.release != nil {
+		s.releaseOnce.Do(s.release)
+	}
+}
+
 func (s *stream) FullClose() error {
 	// close the stream to make sure it is gc'd
 	defer s.Close()
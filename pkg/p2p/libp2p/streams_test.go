@@ -0,0 +1,203 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package libp2p_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/p2p"
+	"github.com/ethersphere/bee/pkg/p2p/libp2p"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+// blockingProtocol returns a protocol which handlers
+// read from the stream until it is closed or reset.
+func blockingProtocol() p2p.ProtocolSpec {
+	return newTestProtocol(func(_ context.Context, _ p2p.Peer, s p2p.Stream) error {
+		_, err := s.Read(make([]byte, 1))
+		if err != nil {
+			_ = s.Reset()
+			return nil
+		}
+		return s.FullClose()
+	})
+}
+
+func TestStreamLimitInbound(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s1, overlay1 := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+		FullNode:    true,
+		StreamLimit: 2,
+	}})
+	s2, overlay2 := newService(t, 1, libp2pServiceOpts{})
+
+	if err := s1.AddProtocol(blockingProtocol()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s2.Connect(ctx, serviceUnderlayAddress(t, s1)); err != nil {
+		t.Fatal(err)
+	}
+
+	var streams []p2p.Stream
+	for i := 0; i < 2; i++ {
+		s, err := s2.NewStream(ctx, overlay1, nil, testProtocolName, testProtocolVersion, testStreamName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		streams = append(streams, s)
+	}
+	expectOpenStreams(t, s1, overlay2, 2)
+	expectOpenStreams(t, s2, overlay1, 2)
+
+	// the stream over the limit is reset by the remote peer
+	s, err := s2.NewStream(ctx, overlay1, nil, testProtocolName, testProtocolVersion, testStreamName)
+	expectStreamReset(t, s, err)
+	if s != nil {
+		_ = s.Reset()
+	}
+	expectOpenStreams(t, s1, overlay2, 2)
+	expectOpenStreams(t, s2, overlay1, 2)
+
+	// a reset stream is released on both sides
+	if err := streams[0].Reset(); err != nil {
+		t.Fatal(err)
+	}
+	expectOpenStreams(t, s2, overlay1, 1)
+	expectOpenStreams(t, s1, overlay2, 1)
+
+	// repeated resets are not accounted twice
+	_ = streams[0].Reset()
+	_ = streams[0].Close()
+	expectOpenStreams(t, s2, overlay1, 1)
+
+	s, err = s2.NewStream(ctx, overlay1, nil, testProtocolName, testProtocolVersion, testStreamName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	streams = append(streams, s)
+	expectOpenStreams(t, s1, overlay2, 2)
+
+	for _, s := range streams[1:] {
+		if err := s.Reset(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectOpenStreams(t, s2, overlay1, 0)
+	expectOpenStreams(t, s1, overlay2, 0)
+}
+
+func TestStreamLimitOutbound(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s1, overlay1 := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+		FullNode: true,
+	}})
+	s2, _ := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+		StreamLimit: 1,
+	}})
+
+	if err := s1.AddProtocol(blockingProtocol()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s2.Connect(ctx, serviceUnderlayAddress(t, s1)); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := s2.NewStream(ctx, overlay1, nil, testProtocolName, testProtocolVersion, testStreamName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s2.NewStream(ctx, overlay1, nil, testProtocolName, testProtocolVersion, testStreamName); !errors.Is(err, p2p.ErrStreamLimit) {
+		t.Fatalf("got error %v, want %v", err, p2p.ErrStreamLimit)
+	}
+	expectOpenStreams(t, s2, overlay1, 1)
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expectOpenStreams(t, s2, overlay1, 0)
+
+	s, err = s2.NewStream(ctx, overlay1, nil, testProtocolName, testProtocolVersion, testStreamName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Reset()
+}
+
+func TestStreamLimitProtected(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s1, overlay1 := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+		FullNode:             true,
+		StreamLimit:          1,
+		ProtectedStreamLimit: 2,
+	}})
+	s2, overlay2 := newService(t, 1, libp2pServiceOpts{})
+
+	s1.SetPickyNotifier(&neighbourhoodNotifiee{
+		notifiee:  notifiee{connected: noopCf, disconnected: noopDf, pick: true},
+		neighbour: overlay2,
+	})
+	if err := s1.AddProtocol(blockingProtocol()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s2.Connect(ctx, serviceUnderlayAddress(t, s1)); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := s2.NewStream(ctx, overlay1, nil, testProtocolName, testProtocolVersion, testStreamName); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectOpenStreams(t, s1, overlay2, 2)
+
+	s, err := s2.NewStream(ctx, overlay1, nil, testProtocolName, testProtocolVersion, testStreamName)
+	expectStreamReset(t, s, err)
+	expectOpenStreams(t, s1, overlay2, 2)
+}
+
+func TestOpenStreamsPeerNotFound(t *testing.T) {
+	s, _ := newService(t, 1, libp2pServiceOpts{})
+
+	if _, err := s.OpenStreams(swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c")); !errors.Is(err, p2p.ErrPeerNotFound) {
+		t.Fatalf("got error %v, want %v", err, p2p.ErrPeerNotFound)
+	}
+}
+
+// neighbourhoodNotifiee reports a single peer within the depth.
+type neighbourhoodNotifiee struct {
+	notifiee
+	neighbour swarm.Address
+}
+
+func (n *neighbourhoodNotifiee) IsWithinDepth(addr swarm.Address) bool {
+	return addr.Equal(n.neighbour)
+}
+
+func expectOpenStreams(t *testing.T, s *libp2p.Service, overlay swarm.Address, want int) {
+	t.Helper()
+
+	var got int
+	for i := 0; i < 100; i++ {
+		var err error
+		got, err = s.OpenStreams(overlay)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got == want {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("got %d open streams, want %d", got, want)
+}
@@ -1,4 +1,4 @@
-// Copyright 2020 The Swarm Authors. All rights reserved.
+// Copyright 2021 The Swarm Authors. All rights reserved.
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
@@ -7,85 +7,220 @@ package libp2p_test
 import (
 	"context"
 	"fmt"
+	"io"
 	"testing"
 	"time"
 
-	"github.com/ethersphere/bee/pkg/p2p"
 	"github.com/ethersphere/bee/pkg/p2p/libp2p"
 	"github.com/ethersphere/bee/pkg/tracing"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
 )
 
-func TestTracing(t *testing.T) {
-	tracer1, closer1, err := tracing.NewTracer(&tracing.Options{
-		Enabled:     true,
-		ServiceName: "bee-test",
-	})
-	if err != nil {
+func TestConnectTracing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mt := newMockTracer()
+	tracer := tracing.NewTracerFromOpentracing(mt)
+
+	s1, overlay1 := newService(t, 1, libp2pServiceOpts{tracer: tracer, libp2pOpts: libp2p.Options{
+		FullNode: true,
+	}})
+	s2, overlay2 := newService(t, 1, libp2pServiceOpts{tracer: tracer})
+
+	addr := serviceUnderlayAddress(t, s1)
+
+	if _, err := s2.Connect(ctx, addr); err != nil {
 		t.Fatal(err)
 	}
-	defer closer1.Close()
+	expectPeers(t, s2, overlay1)
+	expectPeersEventually(t, s1, overlay2)
+
+	spans := waitSpans(t, mt, "libp2p-connect", "libp2p-handle-incoming")
 
-	tracer2, closer2, err := tracing.NewTracer(&tracing.Options{
-		Enabled:     true,
-		ServiceName: "bee-test",
+	connect := spans["libp2p-connect"]
+	if connect.ParentID != 0 {
+		t.Fatalf("got connect span parent %d, want root span", connect.ParentID)
+	}
+	expectTags(t, connect, map[string]interface{}{
+		"underlay":  addr.String(),
+		"direction": "outbound",
+		"overlay":   overlay1.String(),
+		"outcome":   "success",
 	})
-	if err != nil {
-		t.Fatal(err)
+	for _, stage := range []string{"dial", "handshake", "addressbook", "protocols"} {
+		s, ok := spans["libp2p-connect-"+stage]
+		if !ok {
+			t.Fatalf("stage %s span not found", stage)
+		}
+		if s.ParentID != connect.SpanContext.SpanID {
+			t.Errorf("stage %s: got parent %d, want %d", stage, s.ParentID, connect.SpanContext.SpanID)
+		}
 	}
-	defer closer2.Close()
 
-	s1, overlay1 := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+	// the inbound connection joins the trace in the handshake stage
+	incoming := spans["libp2p-handle-incoming"]
+	if incoming.SpanContext.TraceID != connect.SpanContext.TraceID {
+		t.Fatalf("got incoming span trace %d, want %d", incoming.SpanContext.TraceID, connect.SpanContext.TraceID)
+	}
+	if want := spans["libp2p-connect-handshake"].SpanContext.SpanID; incoming.ParentID != want {
+		t.Fatalf("got incoming span parent %d, want %d", incoming.ParentID, want)
+	}
+	expectTags(t, incoming, map[string]interface{}{
+		"direction": "inbound",
+		"overlay":   overlay2.String(),
+		"outcome":   "success",
+	})
+	for _, stage := range []string{"handshake", "addressbook", "protocols", "topology"} {
+		s, ok := spans["libp2p-handle-incoming-"+stage]
+		if !ok {
+			t.Fatalf("incoming stage %s span not found", stage)
+		}
+		if s.ParentID != incoming.SpanContext.SpanID {
+			t.Errorf("incoming stage %s: got parent %d, want %d", stage, s.ParentID, incoming.SpanContext.SpanID)
+		}
+	}
+}
+
+func TestConnectTracingUntracedPeer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mt := newMockTracer()
+	tracer := tracing.NewTracerFromOpentracing(mt)
+
+	s1, overlay1 := newService(t, 1, libp2pServiceOpts{tracer: tracer, libp2pOpts: libp2p.Options{
 		FullNode: true,
 	}})
+	s2, overlay2 := newService(t, 1, libp2pServiceOpts{tracer: tracer})
 
-	s2, _ := newService(t, 1, libp2pServiceOpts{})
+	// the peer without the traced handshake stream is still connected
+	s1.RemoveTracedHandshakeHandler()
 
-	var handledTracingSpan string
-	handled := make(chan struct{})
-	if err := s1.AddProtocol(newTestProtocol(func(ctx context.Context, _ p2p.Peer, _ p2p.Stream) error {
+	if _, err := s2.Connect(ctx, serviceUnderlayAddress(t, s1)); err != nil {
+		t.Fatal(err)
+	}
+	expectPeers(t, s2, overlay1)
+	expectPeersEventually(t, s1, overlay2)
+
+	// and it does not join the trace
+	spans := waitSpans(t, mt, "libp2p-connect", "libp2p-handle-incoming")
+	incoming := spans["libp2p-handle-incoming"]
+	if incoming.ParentID != 0 {
+		t.Fatalf("got incoming span parent %d, want root span", incoming.ParentID)
+	}
+	if incoming.SpanContext.TraceID == spans["libp2p-connect"].SpanContext.TraceID {
+		t.Fatal("incoming span joined the trace of the untraced handshake")
+	}
+	expectTags(t, incoming, map[string]interface{}{
+		"overlay": overlay2.String(),
+		"outcome": "success",
+	})
+}
 
-		span, _, _ := tracer1.StartSpanFromContext(ctx, "test-p2p-handler", nil)
-		defer span.Finish()
+func TestConnectTracingFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mt := newMockTracer()
 
-		handledTracingSpan = fmt.Sprint(span.Context())
-		close(handled)
-		return nil
-	})); err != nil {
+	s1, overlay1 := newService(t, 1, libp2pServiceOpts{libp2pOpts: libp2p.Options{
+		FullNode: true,
+	}})
+	s2, _ := newService(t, 1, libp2pServiceOpts{tracer: tracing.NewTracerFromOpentracing(mt)})
+
+	if err := s2.Blocklist(overlay1, 0); err != nil {
 		t.Fatal(err)
 	}
 
 	addr := serviceUnderlayAddress(t, s1)
+	if _, err := s2.Connect(ctx, addr); err == nil {
+		t.Fatal("expected connect error")
+	}
 
-	connectContext, connectCancel := context.WithCancel(context.Background())
-	defer connectCancel()
+	spans := waitSpans(t, mt, "libp2p-connect")
+	connect := spans["libp2p-connect"]
+	expectTags(t, connect, map[string]interface{}{
+		"underlay":  addr.String(),
+		"direction": "outbound",
+		"overlay":   overlay1.String(),
+		"outcome":   "failure",
+		"error":     true,
+	})
 
-	if _, err := s2.Connect(connectContext, addr); err != nil {
-		t.Fatal(err)
+	// the connection failed after the handshake
+	if _, ok := spans["libp2p-connect-handshake"]; !ok {
+		t.Fatal("handshake stage span not found")
 	}
+	if _, ok := spans["libp2p-connect-addressbook"]; ok {
+		t.Fatal("unexpected addressbook stage span")
+	}
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+// waitSpans waits for the spans with the operation names to be finished
+// and returns all finished spans by their operation names.
+func waitSpans(t *testing.T, mt *mocktracer.MockTracer, operationNames ...string) map[string]*mocktracer.MockSpan {
+	t.Helper()
+
+	for i := 0; i < 50; i++ {
+		spans := make(map[string]*mocktracer.MockSpan)
+		for _, s := range mt.FinishedSpans() {
+			spans[s.OperationName] = s
+		}
+		found := true
+		for _, name := range operationNames {
+			if _, ok := spans[name]; !ok {
+				found = false
+			}
+		}
+		if found {
+			return spans
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for spans %v", operationNames)
+	return nil
+}
 
-	span, _, ctx := tracer2.StartSpanFromContext(ctx, "test-p2p-client", nil)
-	defer span.Finish()
+func expectTags(t *testing.T, span *mocktracer.MockSpan, tags map[string]interface{}) {
+	t.Helper()
 
-	if fmt.Sprint(span.Context()) == "" {
-		t.Error("not tracing span context to send")
+	for k, want := range tags {
+		if got := span.Tag(k); got != want {
+			t.Errorf("span %s: got tag %s %v, want %v", span.OperationName, k, got, want)
+		}
 	}
+}
 
-	stream, err := s2.NewStream(ctx, overlay1, nil, testProtocolName, testProtocolVersion, testStreamName)
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer stream.Close()
+// newMockTracer returns an in-memory tracer that propagates span
+// contexts in the binary format used for p2p headers.
+func newMockTracer() *mocktracer.MockTracer {
+	mt := mocktracer.New()
+	mt.RegisterInjector(opentracing.Binary, binaryPropagator{})
+	mt.RegisterExtractor(opentracing.Binary, binaryPropagator{})
+	return mt
+}
 
-	select {
-	case <-handled:
-	case <-time.After(30 * time.Second):
-		t.Fatal("timeout waiting for handler")
+type binaryPropagator struct{}
+
+func (binaryPropagator) Inject(sc mocktracer.MockSpanContext, carrier interface{}) error {
+	w, ok := carrier.(io.Writer)
+	if !ok {
+		return opentracing.ErrInvalidCarrier
 	}
+	_, err := fmt.Fprintf(w, "%d:%d", sc.TraceID, sc.SpanID)
+	return err
+}
 
-	if handledTracingSpan == "" {
-		t.Error("got not tracing span context in handler")
+func (binaryPropagator) Extract(carrier interface{}) (mocktracer.MockSpanContext, error) {
+	r, ok := carrier.(io.Reader)
+	if !ok {
+		return mocktracer.MockSpanContext{}, opentracing.ErrInvalidCarrier
+	}
+	sc := mocktracer.MockSpanContext{Sampled: true}
+	if _, err := fmt.Fscanf(r, "%d:%d", &sc.TraceID, &sc.SpanID); err != nil {
+		return mocktracer.MockSpanContext{}, opentracing.ErrSpanContextNotFound
 	}
+	return sc, nil
 }
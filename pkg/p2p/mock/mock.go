@@ -7,6 +7,7 @@ package mock
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/ethersphere/bee/pkg/bzz"
@@ -21,15 +22,39 @@ type Service struct {
 	connectFunc           func(ctx context.Context, addr ma.Multiaddr) (address *bzz.Address, err error)
 	disconnectFunc        func(overlay swarm.Address) error
 	peersFunc             func() []p2p.Peer
-	blocklistedPeersFunc  func() ([]p2p.Peer, error)
+	blocklistedPeersFunc  func() ([]p2p.BlockedPeer, error)
 	addressesFunc         func() ([]ma.Multiaddr, error)
 	setNotifierFunc       func(p2p.PickyNotifier)
 	setWelcomeMessageFunc func(string) error
 	getWelcomeMessageFunc func() string
 	blocklistFunc         func(swarm.Address, time.Duration) error
+	openStreamsFunc       func(swarm.Address) (int, error)
+	connectionInfoFunc    func(swarm.Address) (p2p.ConnectionInfo, error)
+	peerEventsFunc        func() (<-chan p2p.PeerEvent, func())
 	welcomeMessage        string
 }
 
+// WithOpenStreamsFunc sets the mock implementation of the OpenStreams function
+func WithOpenStreamsFunc(f func(swarm.Address) (int, error)) Option {
+	return optionFunc(func(s *Service) {
+		s.openStreamsFunc = f
+	})
+}
+
+// WithConnectionInfoFunc sets the mock implementation of the ConnectionInfo function
+func WithConnectionInfoFunc(f func(swarm.Address) (p2p.ConnectionInfo, error)) Option {
+	return optionFunc(func(s *Service) {
+		s.connectionInfoFunc = f
+	})
+}
+
+// WithSubscribePeerEventsFunc sets the mock implementation of the SubscribePeerEvents function
+func WithSubscribePeerEventsFunc(f func() (<-chan p2p.PeerEvent, func())) Option {
+	return optionFunc(func(s *Service) {
+		s.peerEventsFunc = f
+	})
+}
+
 // WithAddProtocolFunc sets the mock implementation of the AddProtocol function
 func WithAddProtocolFunc(f func(p2p.ProtocolSpec) error) Option {
 	return optionFunc(func(s *Service) {
@@ -66,7 +91,7 @@ func WithPeersFunc(f func() []p2p.Peer) Option {
 }
 
 // WithBlocklistedPeersFunc sets the mock implementation of the BlocklistedPeers function
-func WithBlocklistedPeersFunc(f func() ([]p2p.Peer, error)) Option {
+func WithBlocklistedPeersFunc(f func() ([]p2p.BlockedPeer, error)) Option {
 	return optionFunc(func(s *Service) {
 		s.blocklistedPeersFunc = f
 	})
@@ -119,6 +144,9 @@ func (s *Service) Connect(ctx context.Context, addr ma.Multiaddr) (address *bzz.
 	if s.connectFunc == nil {
 		return nil, errors.New("function Connect not configured")
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return s.connectFunc(ctx, addr)
 }
 
@@ -129,6 +157,10 @@ func (s *Service) Disconnect(overlay swarm.Address) error {
 	return s.disconnectFunc(overlay)
 }
 
+func (s *Service) DisconnectWithReason(overlay swarm.Address, _ p2p.DisconnectReason) error {
+	return s.Disconnect(overlay)
+}
+
 func (s *Service) Addresses() ([]ma.Multiaddr, error) {
 	if s.addressesFunc == nil {
 		return nil, errors.New("function Addresses not configured")
@@ -143,7 +175,7 @@ func (s *Service) Peers() []p2p.Peer {
 	return s.peersFunc()
 }
 
-func (s *Service) BlocklistedPeers() ([]p2p.Peer, error) {
+func (s *Service) BlocklistedPeers() ([]p2p.BlockedPeer, error) {
 	if s.blocklistedPeersFunc == nil {
 		return nil, nil
 	}
@@ -166,6 +198,31 @@ func (s *Service) GetWelcomeMessage() string {
 	return s.welcomeMessage
 }
 
+func (s *Service) OpenStreams(overlay swarm.Address) (int, error) {
+	if s.openStreamsFunc == nil {
+		return 0, errors.New("function OpenStreams not configured")
+	}
+	return s.openStreamsFunc(overlay)
+}
+
+func (s *Service) ConnectionInfo(overlay swarm.Address) (p2p.ConnectionInfo, error) {
+	if s.connectionInfoFunc == nil {
+		return p2p.ConnectionInfo{}, errors.New("function ConnectionInfo not configured")
+	}
+	return s.connectionInfoFunc(overlay)
+}
+
+// SubscribePeerEvents returns a channel without events
+// if the mock implementation is not configured.
+func (s *Service) SubscribePeerEvents() (c <-chan p2p.PeerEvent, unsubscribe func()) {
+	if s.peerEventsFunc == nil {
+		channel := make(chan p2p.PeerEvent)
+		var closeOnce sync.Once
+		return channel, func() { closeOnce.Do(func() { close(channel) }) }
+	}
+	return s.peerEventsFunc()
+}
+
 func (s *Service) Halt() {}
 
 func (s *Service) Blocklist(overlay swarm.Address, duration time.Duration) error {
@@ -23,7 +23,7 @@ type Service interface {
 	Connect(ctx context.Context, addr ma.Multiaddr) (address *bzz.Address, err error)
 	Disconnecter
 	Peers() []Peer
-	BlocklistedPeers() ([]Peer, error)
+	BlocklistedPeers() ([]BlockedPeer, error)
 	Addresses() ([]ma.Multiaddr, error)
 	SetPickyNotifier(PickyNotifier)
 	Halter
@@ -31,6 +31,8 @@ type Service interface {
 
 type Disconnecter interface {
 	Disconnect(overlay swarm.Address) error
+	// DisconnectWithReason disconnects a peer and passes the reason to the notifier.
+	DisconnectWithReason(overlay swarm.Address, reason DisconnectReason) error
 	// Blocklist will disconnect a peer and put it on a blocklist (blocking in & out connections) for provided duration
 	// duration 0 is treated as an infinite duration
 	Blocklist(overlay swarm.Address, duration time.Duration) error
@@ -48,16 +50,119 @@ type PickyNotifier interface {
 }
 
 type Notifier interface {
+	Connected(context.Context, Peer, bool) error
+	Disconnected(Peer, DisconnectReason)
+	Announce(context.Context, swarm.Address, bool) error
+}
+
+// DisconnectReason tells the notifier why a peer was disconnected.
+type DisconnectReason int
+
+const (
+	// DisconnectReasonUnknown is used when the cause of the disconnect is not known.
+	DisconnectReasonUnknown DisconnectReason = iota
+	// DisconnectReasonLocal is used when the peer was disconnected on a local request.
+	DisconnectReasonLocal
+	// DisconnectReasonPruned is used when the peer was disconnected to make room for other peers.
+	DisconnectReasonPruned
+	// DisconnectReasonBlocklisted is used when the peer was blocklisted.
+	DisconnectReasonBlocklisted
+	// DisconnectReasonRejected is used when a protocol refused to talk to the peer any more.
+	DisconnectReasonRejected
+	// DisconnectReasonTimeout is used when the peer did not respond in time.
+	DisconnectReasonTimeout
+	// DisconnectReasonRemote is used when the connection was closed by the peer or the network.
+	DisconnectReasonRemote
+)
+
+func (r DisconnectReason) String() string {
+	switch r {
+	case DisconnectReasonLocal:
+		return "local"
+	case DisconnectReasonPruned:
+		return "pruned"
+	case DisconnectReasonBlocklisted:
+		return "blocklisted"
+	case DisconnectReasonRejected:
+		return "rejected"
+	case DisconnectReasonTimeout:
+		return "timeout"
+	case DisconnectReasonRemote:
+		return "remote"
+	default:
+		return "unknown"
+	}
+}
+
+// LegacyNotifier is a Notifier that does not take the disconnect reason.
+type LegacyNotifier interface {
 	Connected(context.Context, Peer, bool) error
 	Disconnected(Peer)
 	Announce(context.Context, swarm.Address, bool) error
 }
 
+// AdaptNotifier returns a Notifier that drops the disconnect
+// reason before it is passed to the legacy notifier.
+func AdaptNotifier(n LegacyNotifier) Notifier {
+	return legacyNotifier{LegacyNotifier: n}
+}
+
+type legacyNotifier struct {
+	LegacyNotifier
+}
+
+func (n legacyNotifier) Disconnected(p Peer, _ DisconnectReason) {
+	n.LegacyNotifier.Disconnected(p)
+}
+
 // DebugService extends the Service with method used for debugging.
 type DebugService interface {
 	Service
 	SetWelcomeMessage(val string) error
 	GetWelcomeMessage() string
+	// OpenStreams returns the number of currently open protocol streams
+	// of a connected peer.
+	OpenStreams(overlay swarm.Address) (int, error)
+	// ConnectionInfo returns the details of the connection with a
+	// connected peer. It returns ErrPeerNotFound if the peer is not
+	// connected.
+	ConnectionInfo(overlay swarm.Address) (ConnectionInfo, error)
+	// SubscribePeerEvents returns the channel on which the peer events
+	// are delivered and the function which ends the subscription and
+	// closes the channel.
+	SubscribePeerEvents() (c <-chan PeerEvent, unsubscribe func())
+}
+
+// PeerEventType is the type of a change of the connection of a peer.
+type PeerEventType int
+
+const (
+	// PeerEventConnected is a peer that is connected.
+	PeerEventConnected PeerEventType = iota + 1
+	// PeerEventDisconnected is a peer that is disconnected.
+	PeerEventDisconnected
+	// PeerEventBlocklisted is a peer that is blocklisted.
+	PeerEventBlocklisted
+)
+
+func (t PeerEventType) String() string {
+	switch t {
+	case PeerEventConnected:
+		return "connected"
+	case PeerEventDisconnected:
+		return "disconnected"
+	case PeerEventBlocklisted:
+		return "blocklisted"
+	default:
+		return "unknown"
+	}
+}
+
+// PeerEvent is a change of the connection of a peer.
+type PeerEvent struct {
+	Overlay   swarm.Address
+	Type      PeerEventType
+	Timestamp time.Time
 }
 
 // Streamer is able to create a new Stream.
@@ -101,6 +206,10 @@ type ProtocolSpec struct {
 	ConnectOut    func(context.Context, Peer) error
 	DisconnectIn  func(Peer) error
 	DisconnectOut func(Peer) error
+	// FullNodeOnly marks the protocols that store or sync chunks. They are
+	// not registered by light nodes and their connect hooks are not called
+	// for light node peers.
+	FullNodeOnly bool
 }
 
 // StreamSpec defines a Stream handling within the protocol.
@@ -117,6 +226,37 @@ type Peer struct {
 	EthereumAddress []byte
 }
 
+// Directions of the connections with the peers.
+const (
+	DirectionInbound  = "inbound"
+	DirectionOutbound = "outbound"
+)
+
+// ConnectionInfo holds the details of the connection with a connected peer.
+type ConnectionInfo struct {
+	Underlays []ma.Multiaddr // the remote addresses of the connections
+	Direction string         // the direction of the first connection, empty if not known
+	Opened    time.Time      // when the first connection was opened, zero if not known
+}
+
+// BlockedPeer is a blocklisted peer with the duration of its block and
+// the record of its rejected connection attempts while it was blocklisted.
+type BlockedPeer struct {
+	Peer
+	Timestamp   time.Time     // when the peer was blocklisted
+	Duration    time.Duration // the block duration, zero if the block is permanent
+	Remaining   time.Duration // the remaining block duration, zero if the block is permanent
+	Attempts    uint64
+	LastAttempt time.Time      // zero if there were no attempts
+	Reason      string         // why the peer was blocklisted, empty if not known
+	Underlays   []ma.Multiaddr // the last known underlay addresses, empty if not known
+}
+
+// Permanent reports whether the peer is blocklisted forever.
+func (p BlockedPeer) Permanent() bool {
+	return p.Duration == 0
+}
+
 // HandlerFunc handles a received Stream from a Peer.
 type HandlerFunc func(context.Context, Peer, Stream) error
 
@@ -5,9 +5,11 @@
 package p2p_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/ethersphere/bee/pkg/p2p"
+	"github.com/ethersphere/bee/pkg/swarm"
 )
 
 func TestNewSwarmStreamName(t *testing.T) {
@@ -18,3 +20,34 @@ func TestNewSwarmStreamName(t *testing.T) {
 		t.Errorf("got %s, want %s", got, want)
 	}
 }
+
+// legacyNotifier records the peers passed to its Disconnected method.
+type legacyNotifier struct {
+	disconnected []p2p.Peer
+}
+
+func (n *legacyNotifier) Connected(context.Context, p2p.Peer, bool) error { return nil }
+
+func (n *legacyNotifier) Disconnected(p p2p.Peer) {
+	n.disconnected = append(n.disconnected, p)
+}
+
+func (n *legacyNotifier) Announce(context.Context, swarm.Address, bool) error { return nil }
+
+func TestAdaptNotifier(t *testing.T) {
+	peer := p2p.Peer{
+		Address:  swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c"),
+		FullNode: true,
+	}
+	legacy := new(legacyNotifier)
+
+	var n p2p.Notifier = p2p.AdaptNotifier(legacy)
+	n.Disconnected(peer, p2p.DisconnectReasonPruned)
+
+	if len(legacy.disconnected) != 1 {
+		t.Fatalf("got %d disconnected peers, want 1", len(legacy.disconnected))
+	}
+	if got := legacy.disconnected[0]; !got.Address.Equal(peer.Address) || got.FullNode != peer.FullNode {
+		t.Fatalf("got disconnected peer %+v, want %+v", got, peer)
+	}
+}
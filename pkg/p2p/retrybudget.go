@@ -0,0 +1,214 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package p2p
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	m "github.com/ethersphere/bee/pkg/metrics"
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ErrRetryBudgetExhausted is returned when a request is not
+// retried because the retry budget has been used up.
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+const (
+	defaultRetryBudgetPeerTokens   = 10
+	defaultRetryBudgetGlobalTokens = 200
+	defaultRetryBudgetRatio        = 0.1
+
+	// maxRetryBudgetPeers is the number of peers with a partially used
+	// budget that are remembered. When it is exceeded, the budget of an
+	// arbitrary peer is forgotten, which makes its bucket full again.
+	maxRetryBudgetPeers = 1024
+)
+
+// RetryBudgetOptions configures the RetryBudget.
+type RetryBudgetOptions struct {
+	// PeerTokens is the number of retries initially
+	// allowed to each peer and the size of its bucket.
+	PeerTokens float64
+	// GlobalTokens is the number of retries initially
+	// allowed to all peers together and the size of their bucket.
+	GlobalTokens float64
+	// Ratio is the fraction of a retry earned by every successful
+	// first attempt, both for the peer and for the global bucket.
+	Ratio float64
+}
+
+// RetryBudget limits the retries of requests that have failed, so that the
+// retries do not multiply the load on peers that are unable to handle it.
+// Every retry takes a token from the bucket of the peer and from the global
+// bucket and it is not allowed if either of them is empty. The buckets start
+// full and are refilled only by successful first attempts, while the first
+// attempts themselves are never limited.
+//
+// The methods of a nil RetryBudget allow all retries.
+type RetryBudget struct {
+	peerTokens   float64
+	globalTokens float64
+	ratio        float64
+
+	mu     sync.Mutex
+	global float64
+	peers  map[string]float64 // only the peers with a bucket that is not full
+
+	metrics retryBudgetMetrics
+}
+
+// NewRetryBudget returns a new RetryBudget with full buckets.
+func NewRetryBudget(o RetryBudgetOptions) *RetryBudget {
+	if o.PeerTokens == 0 {
+		o.PeerTokens = defaultRetryBudgetPeerTokens
+	}
+	if o.GlobalTokens == 0 {
+		o.GlobalTokens = defaultRetryBudgetGlobalTokens
+	}
+	if o.Ratio == 0 {
+		o.Ratio = defaultRetryBudgetRatio
+	}
+	b := &RetryBudget{
+		peerTokens:   o.PeerTokens,
+		globalTokens: o.GlobalTokens,
+		ratio:        o.Ratio,
+		global:       o.GlobalTokens,
+		peers:        make(map[string]float64),
+		metrics:      newRetryBudgetMetrics(),
+	}
+	b.metrics.GlobalTokens.Set(b.global)
+	return b
+}
+
+// Retry takes a retry token for a request to the peer. It returns
+// ErrRetryBudgetExhausted if the request must not be retried.
+func (b *RetryBudget) Retry(peer swarm.Address) error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := peer.ByteString()
+	tokens, ok := b.peers[key]
+	if !ok {
+		tokens = b.peerTokens
+	}
+	if tokens < 1 || b.global < 1 {
+		b.metrics.Exhausted.Inc()
+		return ErrRetryBudgetExhausted
+	}
+	if !ok && len(b.peers) >= maxRetryBudgetPeers {
+		for k := range b.peers {
+			delete(b.peers, k)
+			break
+		}
+	}
+	b.peers[key] = tokens - 1
+	b.global--
+	b.updateMetrics()
+	return nil
+}
+
+// Success records a successful first attempt of a request to the peer,
+// which refills a fraction of a token for the peer and globally.
+func (b *RetryBudget) Success(peer swarm.Address) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := peer.ByteString()
+	if tokens, ok := b.peers[key]; ok {
+		if tokens += b.ratio; tokens < b.peerTokens {
+			b.peers[key] = tokens
+		} else {
+			delete(b.peers, key)
+		}
+	}
+	if b.global += b.ratio; b.global > b.globalTokens {
+		b.global = b.globalTokens
+	}
+	b.updateMetrics()
+}
+
+// Tokens returns the number of retries currently
+// allowed to the peer and to all peers together.
+func (b *RetryBudget) Tokens(peer swarm.Address) (peerTokens, globalTokens float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	tokens, ok := b.peers[peer.ByteString()]
+	if !ok {
+		tokens = b.peerTokens
+	}
+	return tokens, b.global
+}
+
+// updateMetrics sets the budget gauges. Must be called with mu locked.
+func (b *RetryBudget) updateMetrics() {
+	b.metrics.GlobalTokens.Set(b.global)
+	b.metrics.LimitedPeers.Set(float64(len(b.peers)))
+}
+
+type retryBudgetContextKey struct{}
+
+// WithRetryBudget returns a context which carries the retry budget that
+// the request helpers consult before they retry a failed request.
+func WithRetryBudget(ctx context.Context, b *RetryBudget) context.Context {
+	return context.WithValue(ctx, retryBudgetContextKey{}, b)
+}
+
+// RetryBudgetFromContext returns the retry budget carried by the
+// context or nil, which allows all retries, if there is none.
+func RetryBudgetFromContext(ctx context.Context) *RetryBudget {
+	b, _ := ctx.Value(retryBudgetContextKey{}).(*RetryBudget)
+	return b
+}
+
+type retryBudgetMetrics struct {
+	// all metrics fields must be exported
+	// to be able to return them by Metrics()
+	// using reflection
+	GlobalTokens prometheus.Gauge
+	LimitedPeers prometheus.Gauge
+	Exhausted    prometheus.Counter
+}
+
+func newRetryBudgetMetrics() retryBudgetMetrics {
+	subsystem := "retry_budget"
+
+	return retryBudgetMetrics{
+		GlobalTokens: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "global_tokens",
+			Help:      "Number of retries currently allowed to all peers together.",
+		}),
+		LimitedPeers: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "limited_peers",
+			Help:      "Number of peers with a partially used retry budget.",
+		}),
+		Exhausted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "exhausted_count",
+			Help:      "Number of retries not made because the retry budget was exhausted.",
+		}),
+	}
+}
+
+// Metrics returns the retry budget metrics.
+func (b *RetryBudget) Metrics() []prometheus.Collector {
+	return m.PrometheusCollectorsFromFields(b.metrics)
+}
@@ -0,0 +1,174 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package p2p_test
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/p2p"
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/ethersphere/bee/pkg/swarm/test"
+)
+
+// TestRetryBudgetHighFailureRate simulates requests of which most fail and
+// checks that the retries are throttled to the initial tokens and the tokens
+// earned by the successful first attempts, while all first attempts are made.
+func TestRetryBudgetHighFailureRate(t *testing.T) {
+	const (
+		requests    = 10000
+		failureRate = 0.9
+		maxRetries  = 3
+	)
+
+	peers := make([]swarm.Address, 20)
+	for i := range peers {
+		peers[i] = test.RandomAddress()
+	}
+
+	b := p2p.NewRetryBudget(p2p.RetryBudgetOptions{
+		PeerTokens:   10,
+		GlobalTokens: 100,
+		Ratio:        0.1,
+	})
+	r := rand.New(rand.NewSource(1))
+
+	var firstAttempts, successes, retries, exhausted int
+	for i := 0; i < requests; i++ {
+		peer := peers[r.Intn(len(peers))]
+
+		// the first attempt is never limited
+		firstAttempts++
+		if r.Float64() >= failureRate {
+			successes++
+			b.Success(peer)
+			continue
+		}
+
+		for j := 0; j < maxRetries; j++ {
+			err := b.Retry(peers[r.Intn(len(peers))])
+			if errors.Is(err, p2p.ErrRetryBudgetExhausted) {
+				exhausted++
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			retries++
+			if r.Float64() >= failureRate {
+				break
+			}
+		}
+	}
+
+	if firstAttempts != requests {
+		t.Fatalf("got %d first attempts, want %d", firstAttempts, requests)
+	}
+	if exhausted == 0 {
+		t.Fatal("no retries were throttled")
+	}
+	if max := 100 + int(0.1*float64(successes)); retries > max {
+		t.Fatalf("got %d retries, want at most %d", retries, max)
+	}
+	// without the budget, every failed request would be retried at least once
+	if failed := requests - successes; retries >= failed {
+		t.Fatalf("got %d retries for %d failed requests, want fewer", retries, failed)
+	}
+}
+
+func TestRetryBudgetPeer(t *testing.T) {
+	b := p2p.NewRetryBudget(p2p.RetryBudgetOptions{
+		PeerTokens:   2,
+		GlobalTokens: 100,
+		Ratio:        0.5,
+	})
+	p1, p2 := test.RandomAddress(), test.RandomAddress()
+
+	for i := 0; i < 2; i++ {
+		if err := b.Retry(p1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Retry(p1); !errors.Is(err, p2p.ErrRetryBudgetExhausted) {
+		t.Fatalf("got error %v, want %v", err, p2p.ErrRetryBudgetExhausted)
+	}
+
+	// the budget of the other peers is not affected
+	if err := b.Retry(p2); err != nil {
+		t.Fatal(err)
+	}
+
+	// successful first attempts refill the bucket of the peer
+	b.Success(p1)
+	if err := b.Retry(p1); !errors.Is(err, p2p.ErrRetryBudgetExhausted) {
+		t.Fatalf("got error %v, want %v", err, p2p.ErrRetryBudgetExhausted)
+	}
+	b.Success(p1)
+	if err := b.Retry(p1); err != nil {
+		t.Fatal(err)
+	}
+
+	// the bucket does not grow over its size
+	for i := 0; i < 10; i++ {
+		b.Success(p2)
+	}
+	if got, _ := b.Tokens(p2); got != 2 {
+		t.Fatalf("got %v peer tokens, want %v", got, 2)
+	}
+}
+
+func TestRetryBudgetGlobal(t *testing.T) {
+	b := p2p.NewRetryBudget(p2p.RetryBudgetOptions{
+		PeerTokens:   10,
+		GlobalTokens: 3,
+		Ratio:        1,
+	})
+
+	for i := 0; i < 3; i++ {
+		if err := b.Retry(test.RandomAddress()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	peer := test.RandomAddress()
+	if err := b.Retry(peer); !errors.Is(err, p2p.ErrRetryBudgetExhausted) {
+		t.Fatalf("got error %v, want %v", err, p2p.ErrRetryBudgetExhausted)
+	}
+	if got, _ := b.Tokens(peer); got != 10 {
+		t.Fatalf("got %v peer tokens after a denied retry, want %v", got, 10)
+	}
+
+	// a success with any peer refills the global bucket
+	b.Success(test.RandomAddress())
+	if err := b.Retry(peer); err != nil {
+		t.Fatal(err)
+	}
+	if _, got := b.Tokens(peer); got != 0 {
+		t.Fatalf("got %v global tokens, want %v", got, 0)
+	}
+}
+
+func TestRetryBudgetContext(t *testing.T) {
+	peer := test.RandomAddress()
+
+	// without a budget all retries are allowed
+	b := p2p.RetryBudgetFromContext(context.Background())
+	if b != nil {
+		t.Fatalf("got budget %v, want none", b)
+	}
+	for i := 0; i < 100; i++ {
+		if err := b.Retry(peer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b.Success(peer)
+
+	want := p2p.NewRetryBudget(p2p.RetryBudgetOptions{})
+	ctx := p2p.WithRetryBudget(context.Background(), want)
+	if got := p2p.RetryBudgetFromContext(ctx); got != want {
+		t.Fatal("got a different budget from the context")
+	}
+}
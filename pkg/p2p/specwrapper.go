@@ -29,3 +29,11 @@ func WithBlocklistStreams(dur time.Duration, spec ProtocolSpec) {
 		}
 	}
 }
+
+// WithBreakerStreams will mutate the given spec and wrap the handlers with the BreakerMiddleware of the breakers.
+func WithBreakerStreams(b Breakers, spec ProtocolSpec) {
+	middleware := BreakerMiddleware(b)
+	for i := range spec.StreamSpecs {
+		spec.StreamSpecs[i].Handler = middleware(spec.StreamSpecs[i].Handler)
+	}
+}
@@ -11,6 +11,8 @@ import (
 	"time"
 
 	"github.com/ethersphere/bee/pkg/p2p"
+	"github.com/ethersphere/bee/pkg/p2p/mock"
+	"github.com/ethersphere/bee/pkg/swarm"
 )
 
 func newTestProtocol(h p2p.HandlerFunc) p2p.ProtocolSpec {
@@ -66,3 +68,75 @@ func TestDisconnectError(t *testing.T) {
 		}
 	}
 }
+
+var errTestBreakerClosed = errors.New("test breaker closed")
+
+// testBreakers close for a peer once its handlers fail limit times.
+type testBreakers struct {
+	limit    int
+	failures map[string]int
+}
+
+func (b *testBreakers) Execute(ctx context.Context, peer swarm.Address, f func(ctx context.Context) error) error {
+	if b.failures[peer.ByteString()] >= b.limit {
+		return &p2p.BreakerClosedError{Peer: peer, Err: errTestBreakerClosed}
+	}
+	err := f(ctx)
+	if err != nil {
+		b.failures[peer.ByteString()]++
+	}
+	return err
+}
+
+func TestBreakerStreams(t *testing.T) {
+	testErr := errors.New("test")
+	var calls int
+	tp := newTestProtocol(func(context.Context, p2p.Peer, p2p.Stream) error {
+		calls++
+		return testErr
+	})
+
+	var added p2p.ProtocolSpec
+	s := mock.New(mock.WithAddProtocolFunc(func(spec p2p.ProtocolSpec) error {
+		added = spec
+		return nil
+	}))
+	p2p.WithBreakerStreams(&testBreakers{limit: 2, failures: make(map[string]int)}, tp)
+	if err := s.AddProtocol(tp); err != nil {
+		t.Fatal(err)
+	}
+
+	peer := p2p.Peer{Address: swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59c")}
+	for _, sp := range added.StreamSpecs {
+		if err := sp.Handler(context.Background(), peer, nil); !errors.Is(err, testErr) {
+			t.Fatalf("got error %v, want %v", err, testErr)
+		}
+	}
+
+	// the handlers are not called while the breaker of the peer is closed
+	for _, sp := range added.StreamSpecs {
+		err := sp.Handler(context.Background(), peer, nil)
+		var closedErr *p2p.BreakerClosedError
+		if !errors.As(err, &closedErr) {
+			t.Fatalf("got error %v, want breaker closed error", err)
+		}
+		if !errors.Is(err, errTestBreakerClosed) {
+			t.Fatal("unexpected wrapped error type")
+		}
+		if !closedErr.Peer.Equal(peer.Address) {
+			t.Fatalf("got peer %s, want %s", closedErr.Peer, peer.Address)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("got %d handler calls, want %d", calls, 2)
+	}
+
+	// nor are the handlers of the other peers affected
+	other := p2p.Peer{Address: swarm.MustParseHexAddress("ca1e9f3938cc1425c6061b96ad9eb93e134dfe8734ad490164ef20af9d1cf59d")}
+	if err := added.StreamSpecs[0].Handler(context.Background(), other, nil); !errors.Is(err, testErr) {
+		t.Fatalf("got error %v, want %v", err, testErr)
+	}
+	if calls != 3 {
+		t.Fatalf("got %d handler calls, want %d", calls, 3)
+	}
+}
@@ -407,6 +407,10 @@ func (r *RecorderDisconnecter) Disconnect(overlay swarm.Address) error {
 	return nil
 }
 
+func (r *RecorderDisconnecter) DisconnectWithReason(overlay swarm.Address, _ p2p.DisconnectReason) error {
+	return r.Disconnect(overlay)
+}
+
 func (r *RecorderDisconnecter) Blocklist(overlay swarm.Address, d time.Duration) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
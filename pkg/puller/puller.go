@@ -117,7 +117,7 @@ func (p *Puller) manage(warmupTime time.Duration) {
 
 			// if we're already syncing with this peer, make sure
 			// that we're syncing the correct bins according to depth
-			depth := p.topology.NeighborhoodDepth()
+			_, depth := p.topology.NeighborhoodDepth()
 
 			// we defer the actual start of syncing to get out of the iterator first
 			var (
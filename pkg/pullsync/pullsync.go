@@ -93,8 +93,9 @@ func New(streamer p2p.Streamer, storage pullstorage.Storer, unwrap func(swarm.Ch
 
 func (s *Syncer) Protocol() p2p.ProtocolSpec {
 	return p2p.ProtocolSpec{
-		Name:    protocolName,
-		Version: protocolVersion,
+		Name:         protocolName,
+		Version:      protocolVersion,
+		FullNodeOnly: true,
 		StreamSpecs: []p2p.StreamSpec{
 			{
 				Name:    streamName,
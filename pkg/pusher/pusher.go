@@ -212,7 +212,7 @@ LOOP:
 					}
 
 					po := swarm.Proximity(ch.Address().Bytes(), storerPeer.Bytes())
-					d := s.depther.NeighborhoodDepth()
+					_, d := s.depther.NeighborhoodDepth()
 					if po < d {
 						mtx.Lock()
 						retryCounter[ch.Address().ByteString()]++
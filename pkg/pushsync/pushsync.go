@@ -108,8 +108,9 @@ func New(address swarm.Address, blockHash []byte, streamer p2p.StreamerDisconnec
 
 func (s *PushSync) Protocol() p2p.ProtocolSpec {
 	return p2p.ProtocolSpec{
-		Name:    protocolName,
-		Version: protocolVersion,
+		Name:         protocolName,
+		Version:      protocolVersion,
+		FullNodeOnly: true,
 		StreamSpecs: []p2p.StreamSpec{
 			{
 				Name:    streamName,
@@ -0,0 +1,13 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reputation
+
+import "time"
+
+func SetTimeNow(f func() time.Time) {
+	timeNow = f
+}
+
+var GenerateKey = generateKey
@@ -0,0 +1,52 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reputation
+
+import (
+	m "github.com/ethersphere/bee/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metrics struct {
+	Records         prometheus.Gauge
+	InfractionCount prometheus.Counter
+	EvictedCount    prometheus.Counter
+	ExpiredCount    prometheus.Counter
+}
+
+func newMetrics() metrics {
+	subsystem := "reputation"
+
+	return metrics{
+		Records: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "records",
+			Help:      "Number of persisted reputation records.",
+		}),
+		InfractionCount: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "infraction_count",
+			Help:      "Number of recorded peer infractions.",
+		}),
+		EvictedCount: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "evicted_count",
+			Help:      "Number of records removed over the records limit.",
+		}),
+		ExpiredCount: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "expired_count",
+			Help:      "Number of records removed after their TTL.",
+		}),
+	}
+}
+
+func (s *Scorer) Metrics() []prometheus.Collector {
+	return m.PrometheusCollectorsFromFields(s.metrics)
+}
@@ -0,0 +1,304 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package reputation keeps the scores of peers in the state store, so that
+// the misbehaviour of a peer is not forgotten when the node restarts.
+//
+// A score decays exponentially towards zero with a configured half-life.
+// The decay is not applied in the background, but every time a record is
+// read, from the time the record was last updated. Records that have not
+// been updated for longer than the TTL are removed when they are read.
+package reputation
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/storage"
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+const (
+	keyPrefix = "reputation-"
+
+	// recordSize is the size of a serialised record: score,
+	// last update time and the number of infractions.
+	recordSize = 8 + 8 + 4
+
+	defaultHalfLife   = 24 * time.Hour
+	defaultTTL        = 30 * 24 * time.Hour
+	defaultMaxRecords = 10000
+)
+
+// timeNow is used to deterministically mock time.Now() in tests.
+var timeNow = time.Now
+
+// errRecordSize is returned when a stored record has an unexpected size.
+var errRecordSize = errors.New("invalid reputation record size")
+
+func init() {
+	storage.RegisterPrefix(keyPrefix, func(_, value []byte) error {
+		var r Record
+		return r.UnmarshalBinary(value)
+	})
+}
+
+// Options for the Scorer.
+type Options struct {
+	// HalfLife is the time in which a score decays to half of its value.
+	HalfLife time.Duration
+	// TTL is the time after the last update when a record is removed.
+	TTL time.Duration
+	// MaxRecords is the number of persisted records. When it is exceeded,
+	// the records that were updated the longest time ago are removed.
+	MaxRecords int
+}
+
+// Record is the persisted reputation of a single peer. It has a fixed
+// size, regardless of the history of the peer.
+type Record struct {
+	// Score is the score at the time of the last update.
+	Score float64
+	// Updated is the time of the last update in unix nanoseconds.
+	Updated int64
+	// Infractions is the total number of infractions of the peer.
+	Infractions uint32
+}
+
+// Decayed returns the record with the score decayed to the time now.
+func (r Record) Decayed(now time.Time, halfLife time.Duration) Record {
+	elapsed := now.UnixNano() - r.Updated
+	if elapsed > 0 && halfLife > 0 {
+		r.Score *= math.Exp2(-float64(elapsed) / float64(halfLife))
+	}
+	return r
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (r Record) MarshalBinary() ([]byte, error) {
+	b := make([]byte, recordSize)
+	binary.BigEndian.PutUint64(b[0:8], math.Float64bits(r.Score))
+	binary.BigEndian.PutUint64(b[8:16], uint64(r.Updated))
+	binary.BigEndian.PutUint32(b[16:20], r.Infractions)
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (r *Record) UnmarshalBinary(b []byte) error {
+	if len(b) != recordSize {
+		return errRecordSize
+	}
+	r.Score = math.Float64frombits(binary.BigEndian.Uint64(b[0:8]))
+	r.Updated = int64(binary.BigEndian.Uint64(b[8:16]))
+	r.Infractions = binary.BigEndian.Uint32(b[16:20])
+	return nil
+}
+
+// Scorer keeps the scores of peers. The records of peers are loaded into
+// memory when they are first used, while the update times of all persisted
+// records are kept to enforce the limit on their number.
+type Scorer struct {
+	store      storage.StateStorer
+	halfLife   time.Duration
+	ttl        time.Duration
+	maxRecords int
+	metrics    metrics
+
+	mtx     sync.Mutex
+	records map[string]Record // loaded records
+	updated map[string]int64  // update times of all persisted records
+}
+
+// New returns a new Scorer with the records persisted in the store. Expired
+// records and the records over the limit are removed from the store.
+func New(store storage.StateStorer, o Options) (*Scorer, error) {
+	if o.HalfLife == 0 {
+		o.HalfLife = defaultHalfLife
+	}
+	if o.TTL == 0 {
+		o.TTL = defaultTTL
+	}
+	if o.MaxRecords == 0 {
+		o.MaxRecords = defaultMaxRecords
+	}
+	s := &Scorer{
+		store:      store,
+		halfLife:   o.HalfLife,
+		ttl:        o.TTL,
+		maxRecords: o.MaxRecords,
+		metrics:    newMetrics(),
+		records:    make(map[string]Record),
+		updated:    make(map[string]int64),
+	}
+
+	var stale []string
+	if err := store.Iterate(keyPrefix, func(k, v []byte) (bool, error) {
+		if !strings.HasPrefix(string(k), keyPrefix) {
+			return true, nil
+		}
+		var r Record
+		if err := r.UnmarshalBinary(v); err != nil {
+			stale = append(stale, string(k))
+			return false, nil
+		}
+		if s.expired(r) {
+			s.metrics.ExpiredCount.Inc()
+			stale = append(stale, string(k))
+			return false, nil
+		}
+		s.updated[string(k)] = r.Updated
+		return false, nil
+	}); err != nil {
+		return nil, fmt.Errorf("load reputation: %w", err)
+	}
+	for _, k := range stale {
+		if err := store.Delete(k); err != nil {
+			return nil, fmt.Errorf("remove reputation: %w", err)
+		}
+	}
+	for len(s.updated) > s.maxRecords {
+		if err := s.evictOldest(); err != nil {
+			return nil, err
+		}
+	}
+	s.metrics.Records.Set(float64(len(s.updated)))
+	return s, nil
+}
+
+// Load loads the record of the peer into memory. It is intended to be
+// called when a connection to the peer is established.
+func (s *Scorer) Load(addr swarm.Address) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	_, err := s.get(generateKey(addr))
+	return err
+}
+
+// Get returns the record of the peer with the score decayed to the current
+// time. A zero record is returned for a peer without a record.
+func (s *Scorer) Get(addr swarm.Address) (Record, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	r, err := s.get(generateKey(addr))
+	if err != nil {
+		return Record{}, err
+	}
+	return r.Decayed(timeNow(), s.halfLife), nil
+}
+
+// Adjust adds the delta to the score of the peer.
+func (s *Scorer) Adjust(addr swarm.Address, delta float64) error {
+	return s.update(addr, delta, false)
+}
+
+// Infraction subtracts the penalty from the score of the peer and
+// increments the number of its infractions.
+func (s *Scorer) Infraction(addr swarm.Address, penalty float64) error {
+	return s.update(addr, -penalty, true)
+}
+
+func (s *Scorer) update(addr swarm.Address, delta float64, infraction bool) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	key := generateKey(addr)
+	r, err := s.get(key)
+	if err != nil {
+		return err
+	}
+
+	now := timeNow()
+	r = r.Decayed(now, s.halfLife)
+	r.Score += delta
+	r.Updated = now.UnixNano()
+	if infraction {
+		s.metrics.InfractionCount.Inc()
+		if r.Infractions < math.MaxUint32 {
+			r.Infractions++
+		}
+	}
+
+	if _, ok := s.updated[key]; !ok && len(s.updated) >= s.maxRecords {
+		if err := s.evictOldest(); err != nil {
+			return err
+		}
+	}
+	if err := s.store.Put(key, r); err != nil {
+		return fmt.Errorf("persist reputation: %w", err)
+	}
+	s.records[key] = r
+	s.updated[key] = r.Updated
+	s.metrics.Records.Set(float64(len(s.updated)))
+	return nil
+}
+
+// get returns the record stored under the key, loading it from the store if
+// necessary. Expired records are removed. Must be called with mtx locked.
+func (s *Scorer) get(key string) (Record, error) {
+	r, ok := s.records[key]
+	if !ok {
+		if _, ok := s.updated[key]; !ok {
+			return Record{}, nil
+		}
+		if err := s.store.Get(key, &r); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				delete(s.updated, key)
+				return Record{}, nil
+			}
+			return Record{}, fmt.Errorf("load reputation: %w", err)
+		}
+	}
+	if s.expired(r) {
+		s.metrics.ExpiredCount.Inc()
+		if err := s.remove(key); err != nil {
+			return Record{}, err
+		}
+		return Record{}, nil
+	}
+	s.records[key] = r
+	return r, nil
+}
+
+// evictOldest removes the record that was updated the longest time ago.
+// Must be called with mtx locked.
+func (s *Scorer) evictOldest() error {
+	var (
+		oldestKey string
+		oldest    int64 = math.MaxInt64
+	)
+	for k, u := range s.updated {
+		if u < oldest {
+			oldestKey, oldest = k, u
+		}
+	}
+	s.metrics.EvictedCount.Inc()
+	return s.remove(oldestKey)
+}
+
+// remove removes the record from the store and memory.
+// Must be called with mtx locked.
+func (s *Scorer) remove(key string) error {
+	if err := s.store.Delete(key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("remove reputation: %w", err)
+	}
+	delete(s.records, key)
+	delete(s.updated, key)
+	s.metrics.Records.Set(float64(len(s.updated)))
+	return nil
+}
+
+func (s *Scorer) expired(r Record) bool {
+	return timeNow().UnixNano()-r.Updated > int64(s.ttl)
+}
+
+func generateKey(addr swarm.Address) string {
+	return keyPrefix + addr.String()
+}
@@ -0,0 +1,207 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reputation_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/reputation"
+	"github.com/ethersphere/bee/pkg/statestore/mock"
+	"github.com/ethersphere/bee/pkg/storage"
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/ethersphere/bee/pkg/swarm/test"
+)
+
+func TestDecayOnLoad(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	reputation.SetTimeNow(func() time.Time { return now })
+	defer reputation.SetTimeNow(time.Now)
+
+	halfLife := time.Hour
+	store := mock.NewStateStore()
+	peer := test.RandomAddress()
+
+	s, err := reputation.New(store, reputation.Options{HalfLife: halfLife, TTL: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Infraction(peer, 8); err != nil {
+		t.Fatal(err)
+	}
+
+	// restart after two half-lives
+	now = now.Add(2 * halfLife)
+	s, err = reputation.New(store, reputation.Options{HalfLife: halfLife, TTL: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Load(peer); err != nil {
+		t.Fatal(err)
+	}
+	r, err := s.Get(peer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Score != -2 {
+		t.Fatalf("got score %v, want %v", r.Score, -2)
+	}
+	if r.Infractions != 1 {
+		t.Fatalf("got %d infractions, want %d", r.Infractions, 1)
+	}
+
+	// the decayed score is the base of the next update
+	now = now.Add(halfLife)
+	if err := s.Infraction(peer, 1); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(halfLife / 2)
+	r, err = s.Get(peer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := -2 * math.Sqrt2 / 2; math.Abs(r.Score-want) > 1e-9 {
+		t.Fatalf("got score %v, want %v", r.Score, want)
+	}
+	if r.Infractions != 2 {
+		t.Fatalf("got %d infractions, want %d", r.Infractions, 2)
+	}
+
+	// records are removed after the ttl
+	now = now.Add(25 * time.Hour)
+	s, err = reputation.New(store, reputation.Options{HalfLife: halfLife, TTL: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = s.Get(peer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != (reputation.Record{}) {
+		t.Fatalf("got record %+v, want none", r)
+	}
+	var stored reputation.Record
+	if err := store.Get(reputation.GenerateKey(peer), &stored); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, storage.ErrNotFound)
+	}
+}
+
+func TestDecayed(t *testing.T) {
+	now := time.Unix(1000, 0)
+	r := reputation.Record{Score: 10, Updated: now.UnixNano()}
+
+	for _, tc := range []struct {
+		elapsed time.Duration
+		want    float64
+	}{
+		{0, 10},
+		{time.Hour, 5},
+		{3 * time.Hour, 1.25},
+		{-time.Hour, 10}, // updates from the future do not grow
+	} {
+		got := r.Decayed(now.Add(tc.elapsed), time.Hour).Score
+		if math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("elapsed %s: got score %v, want %v", tc.elapsed, got, tc.want)
+		}
+	}
+}
+
+func TestEvictionCap(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	reputation.SetTimeNow(func() time.Time { return now })
+	defer reputation.SetTimeNow(time.Now)
+
+	store := mock.NewStateStore()
+	s, err := reputation.New(store, reputation.Options{MaxRecords: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peers := make([]swarm.Address, 5)
+	for i := range peers {
+		peers[i] = test.RandomAddress()
+	}
+	for _, p := range peers[:3] {
+		now = now.Add(time.Second)
+		if err := s.Adjust(p, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// updating an existing record does not evict
+	now = now.Add(time.Second)
+	if err := s.Adjust(peers[0], 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := countRecords(t, store); got != 3 {
+		t.Fatalf("got %d records, want %d", got, 3)
+	}
+
+	// new records evict the oldest updated ones
+	for _, p := range peers[3:] {
+		now = now.Add(time.Second)
+		if err := s.Adjust(p, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := countRecords(t, store); got != 3 {
+		t.Fatalf("got %d records, want %d", got, 3)
+	}
+	for i, want := range []bool{true, false, false, true, true} {
+		var r reputation.Record
+		err := store.Get(reputation.GenerateKey(peers[i]), &r)
+		if got := err == nil; got != want {
+			t.Errorf("peer %d: got persisted %v, want %v", i, got, want)
+		}
+	}
+
+	// a lower cap on restart evicts the records over it
+	s, err = reputation.New(store, reputation.Options{MaxRecords: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := countRecords(t, store); got != 1 {
+		t.Fatalf("got %d records, want %d", got, 1)
+	}
+	r, err := s.Get(peers[4])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Score == 0 {
+		t.Fatal("the most recently updated record was evicted")
+	}
+}
+
+func TestRecordMarshal(t *testing.T) {
+	r := reputation.Record{Score: -3.5, Updated: 123456789, Infractions: 7}
+	b, err := r.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got reputation.Record
+	if err := got.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if got != r {
+		t.Fatalf("got record %+v, want %+v", got, r)
+	}
+	if err := got.UnmarshalBinary(b[1:]); err == nil {
+		t.Fatal("expected error for truncated record")
+	}
+}
+
+func countRecords(t *testing.T, store storage.StateStorer) (n int) {
+	t.Helper()
+
+	if err := store.Iterate("reputation-", func(_, _ []byte) (bool, error) {
+		n++
+		return false, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
@@ -66,6 +66,7 @@ type Service struct {
 	tracer        *tracing.Tracer
 	caching       bool
 	validStamp    postage.ValidStampFn
+	retryBudget   *p2p.RetryBudget
 }
 
 func New(addr swarm.Address, storer storage.Storer, streamer p2p.Streamer, chunkPeerer topology.EachPeerer, logger logging.Logger, accounting accounting.Interface, pricer pricer.Interface, tracer *tracing.Tracer, forwarderCaching bool, validStamp postage.ValidStampFn) *Service {
@@ -84,6 +85,13 @@ func New(addr swarm.Address, storer storage.Storer, streamer p2p.Streamer, chunk
 	}
 }
 
+// SetRetryBudget sets the budget which limits the retries of the
+// requests to peers. It is used for the requests with a context that
+// does not carry a retry budget. Without it, retries are not limited.
+func (s *Service) SetRetryBudget(b *p2p.RetryBudget) {
+	s.retryBudget = b
+}
+
 func (s *Service) Protocol() p2p.ProtocolSpec {
 	return p2p.ProtocolSpec{
 		Name:    protocolName,
@@ -116,6 +124,11 @@ func (s *Service) RetrieveChunk(ctx context.Context, addr swarm.Address, origin
 	// topCtx is passing the tracing span to the first singleflight call
 	topCtx := ctx
 
+	retryBudget := p2p.RetryBudgetFromContext(ctx)
+	if retryBudget == nil {
+		retryBudget = s.retryBudget
+	}
+
 	v, _, err := s.singleflight.Do(ctx, flightRoute, func(ctx context.Context) (interface{}, error) {
 		maxPeers := 1
 		if origin {
@@ -144,11 +157,14 @@ func (s *Service) RetrieveChunk(ctx context.Context, addr swarm.Address, origin
 				// create a new context without cancelation but
 				// set the tracing span to the new context from the context of the first caller
 				ctx := tracing.WithContext(context.Background(), tracing.FromContext(topCtx))
+				ctx = p2p.WithRetryBudget(ctx, retryBudget)
 
 				// get the tracing span
 				span, _, ctx := s.tracer.StartSpanFromContext(ctx, "retrieve-chunk", s.logger, opentracing.Tag{Key: "address", Value: addr.String()})
 				defer span.Finish()
 
+				// every request after the first one is a retry
+				retry := peerAttempt > 0 || requestAttempt > 0
 				peerAttempt++
 				s.metrics.PeerRequestCounter.Inc()
 				go func() {
@@ -157,7 +173,7 @@ func (s *Service) RetrieveChunk(ctx context.Context, addr swarm.Address, origin
 					ctx, cancel := context.WithTimeout(ctx, retrieveChunkTimeout)
 					defer cancel()
 
-					chunk, peer, requested, err := s.retrieveChunk(ctx, addr, sp, origin)
+					chunk, peer, requested, err := s.retrieveChunk(ctx, addr, sp, origin, retry)
 					select {
 					case resultC <- retrievalResult{
 						chunk:     chunk,
@@ -236,7 +252,9 @@ func (s *Service) RetrieveChunk(ctx context.Context, addr swarm.Address, origin
 	return v.(swarm.Chunk), nil
 }
 
-func (s *Service) retrieveChunk(ctx context.Context, addr swarm.Address, sp *skipPeers, originated bool) (chunk swarm.Chunk, peer swarm.Address, requested bool, err error) {
+// retrieveChunk requests the chunk from the closest peer which is not skipped.
+// A retry is made only if the retry budget carried by the context allows it.
+func (s *Service) retrieveChunk(ctx context.Context, addr swarm.Address, sp *skipPeers, originated, retry bool) (chunk swarm.Chunk, peer swarm.Address, requested bool, err error) {
 	startTimer := time.Now()
 	v := ctx.Value(requestSourceContextKey{})
 	sourcePeerAddr := swarm.Address{}
@@ -261,6 +279,15 @@ func (s *Service) retrieveChunk(ctx context.Context, addr swarm.Address, sp *ski
 		return nil, peer, false, fmt.Errorf("get closest for address %s, allow upstream %v: %w", addr.String(), allowUpstream, err)
 	}
 
+	retryBudget := p2p.RetryBudgetFromContext(ctx)
+	if retry {
+		if err := retryBudget.Retry(peer); err != nil {
+			// try the next closest peer instead, which may have a budget left
+			sp.Add(peer)
+			return nil, peer, false, err
+		}
+	}
+
 	peerPO := swarm.Proximity(s.addr.Bytes(), peer.Bytes())
 
 	if !sourcePeerAddr.IsZero() {
@@ -342,6 +369,9 @@ func (s *Service) retrieveChunk(ctx context.Context, addr swarm.Address, sp *ski
 		return nil, peer, true, err
 	}
 	s.metrics.ChunkPrice.Observe(float64(chunkPrice))
+	if !retry {
+		retryBudget.Success(peer)
+	}
 	return chunk, peer, true, err
 }
 
@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -359,6 +360,42 @@ func TestRetrievePreemptiveRetry(t *testing.T) {
 		}
 	})
 
+	t.Run("retry budget exhausted", func(t *testing.T) {
+		var requests int32
+		recorder := streamtest.New(
+			streamtest.WithProtocols(
+				server1.Protocol(),
+				server2.Protocol(),
+			),
+			streamtest.WithMiddlewares(
+				func(h p2p.HandlerFunc) p2p.HandlerFunc {
+					return func(ctx context.Context, peer p2p.Peer, stream p2p.Stream) error {
+						atomic.AddInt32(&requests, 1)
+						return fmt.Errorf("peer not reachable: %s", peer.Address.String())
+					}
+				},
+			),
+			streamtest.WithBaseAddr(clientAddress),
+		)
+
+		client := retrieval.New(clientAddress, nil, recorder, peerSuggesterFn(peers...), logger, accountingmock.NewAccounting(), pricerMock, nil, false, noopStampValidator)
+
+		budget := p2p.NewRetryBudget(p2p.RetryBudgetOptions{
+			PeerTokens:   1,
+			GlobalTokens: 1,
+		})
+		ctx := p2p.WithRetryBudget(context.Background(), budget)
+
+		if _, err := client.RetrieveChunk(ctx, chunk.Address(), true); !errors.Is(err, storage.ErrNotFound) {
+			t.Fatalf("got error %v, want %v", err, storage.ErrNotFound)
+		}
+
+		// the first attempt and the only retry allowed by the budget
+		if got := atomic.LoadInt32(&requests); got != 2 {
+			t.Fatalf("got %d requests, want %d", got, 2)
+		}
+	})
+
 	t.Run("peer does not have chunk", func(t *testing.T) {
 		ranOnce := true
 		ranMux := sync.Mutex{}
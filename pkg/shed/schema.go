@@ -83,7 +83,13 @@ func (db *DB) schemaFieldKey(name, fieldType string) (key []byte, err error) {
 			return nil, fmt.Errorf("put schema: %w", err)
 		}
 	}
-	return append([]byte{keyPrefixFields}, []byte(name)...), nil
+	return FieldKey(name), nil
+}
+
+// FieldKey returns the LevelDB key under which the value of the field with
+// the given name is stored.
+func FieldKey(name string) []byte {
+	return append([]byte{keyPrefixFields}, []byte(name)...)
 }
 
 // RenameIndex changes the schema so that an existing index name is changed
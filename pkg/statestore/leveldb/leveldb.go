@@ -5,6 +5,7 @@
 package leveldb
 
 import (
+	"context"
 	"encoding"
 	"encoding/json"
 	"errors"
@@ -17,7 +18,10 @@ import (
 	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
-var _ storage.StateStorer = (*store)(nil)
+var (
+	_ storage.StateStorer          = (*store)(nil)
+	_ storage.StateStoreMaintainer = (*store)(nil)
+)
 
 // store uses LevelDB to store values.
 type store struct {
@@ -123,6 +127,62 @@ func (s *store) Iterate(prefix string, iterFunc storage.StateIterFunc) (err erro
 	return iter.Error()
 }
 
+// CheckIntegrity iterates over a snapshot of all entries and validates the
+// values of namespaces registered with storage.RegisterPrefix.
+func (s *store) CheckIntegrity(ctx context.Context) (storage.IntegrityReport, error) {
+	report := storage.IntegrityReport{
+		Namespaces: make(map[string]*storage.NamespaceReport),
+	}
+
+	snapshot, err := s.db.GetSnapshot()
+	if err != nil {
+		return report, fmt.Errorf("snapshot: %w", err)
+	}
+	defer snapshot.Release()
+
+	validators := storage.PrefixValidators()
+	for prefix := range validators {
+		report.Namespaces[prefix] = new(storage.NamespaceReport)
+	}
+
+	iter := snapshot.NewIterator(nil, nil)
+	defer iter.Release()
+	for iter.Next() {
+		select {
+		case <-ctx.Done():
+			return report, ctx.Err()
+		default:
+		}
+
+		report.Keys++
+		prefix, validate, ok := storage.MatchPrefix(validators, string(iter.Key()))
+		if !ok || validate == nil {
+			continue
+		}
+
+		ns := report.Namespaces[prefix]
+		ns.Keys++
+		if err := validate(iter.Key(), iter.Value()); err != nil {
+			ns.BadKeys++
+			if s.logger != nil {
+				s.logger.Debugf("statestore integrity: invalid value for key %q: %v", iter.Key(), err)
+			}
+		}
+	}
+
+	return report, iter.Error()
+}
+
+// Compact triggers a leveldb compaction of the keys with the given prefix.
+// An empty prefix compacts the whole store.
+func (s *store) Compact(prefix string) error {
+	var r util.Range
+	if prefix != "" {
+		r = *util.BytesPrefix([]byte(prefix))
+	}
+	return s.db.CompactRange(r)
+}
+
 func (s *store) getSchemaName() (string, error) {
 	name, err := s.db.Get([]byte(dbSchemaKey), nil)
 	if err != nil {
@@ -5,6 +5,10 @@
 package leveldb_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"testing"
@@ -80,3 +84,88 @@ func TestGetSchemaName(t *testing.T) {
 		t.Fatalf("wanted current db schema but got '%s'", n)
 	}
 }
+
+func TestCheckIntegrity(t *testing.T) {
+	dir, err := ioutil.TempDir("", "statestore_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	store, err := leveldb.NewStateStore(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := store.Close(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	const (
+		prefix      = "integrity_test_"
+		otherPrefix = "integrity_test_other_"
+	)
+	validate := func(_, value []byte) error {
+		var v struct {
+			Value int `json:"value"`
+		}
+		return json.Unmarshal(value, &v)
+	}
+	storage.RegisterPrefix(prefix, validate)
+	storage.RegisterPrefix(otherPrefix, validate)
+
+	for i := 0; i < 5; i++ {
+		if err := store.Put(fmt.Sprintf("%s%d", prefix, i), map[string]int{"value": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Put(otherPrefix+"0", map[string]int{"value": 0}); err != nil {
+		t.Fatal(err)
+	}
+	// unregistered namespaces are counted but not validated
+	if err := store.Put("unregistered_0", "{"); err != nil {
+		t.Fatal(err)
+	}
+
+	// inject corrupt values directly into the database
+	for _, key := range []string{prefix + "1", prefix + "3", otherPrefix + "1"} {
+		if err := store.DB().Put([]byte(key), []byte("{corrupt"), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := store.(storage.StateStoreMaintainer).CheckIntegrity(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// schema name, unregistered key and the namespaced keys
+	if want := 2 + 5 + 2; report.Keys != want {
+		t.Fatalf("got %d keys, want %d", report.Keys, want)
+	}
+
+	if got, want := *report.Namespaces[prefix], (storage.NamespaceReport{Keys: 5, BadKeys: 2}); got != want {
+		t.Fatalf("got report %+v for %s, want %+v", got, prefix, want)
+	}
+	if got, want := *report.Namespaces[otherPrefix], (storage.NamespaceReport{Keys: 2, BadKeys: 1}); got != want {
+		t.Fatalf("got report %+v for %s, want %+v", got, otherPrefix, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := store.(storage.StateStoreMaintainer).CheckIntegrity(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want %v", err, context.Canceled)
+	}
+
+	if err := store.(storage.StateStoreMaintainer).Compact(prefix); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.(storage.StateStoreMaintainer).Compact(""); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"encoding"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 
@@ -77,15 +78,22 @@ func (s *store) Delete(key string) (err error) {
 	return nil
 }
 
+// Iterate iterates over the keys with the prefix in
+// the lexicographic order, like the leveldb store.
 func (s *store) Iterate(prefix string, iterFunc storage.StateIterFunc) (err error) {
 	s.mtx.RLock()
 	defer s.mtx.RUnlock()
 
-	for k, v := range s.store {
-		if !strings.HasPrefix(k, prefix) {
-			continue
+	var keys []string
+	for k := range s.store {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
 		}
+	}
+	sort.Strings(keys)
 
+	for _, k := range keys {
+		v := s.store[k]
 		val := make([]byte, len(v))
 		copy(val, v)
 		stop, err := iterFunc([]byte(k), val)
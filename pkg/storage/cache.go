@@ -0,0 +1,183 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"container/list"
+	"hash/fnv"
+	"sync"
+
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+var _ StateStorer = (*cachedStore)(nil)
+
+// cacheLockStripes is the number of mutexes used to serialize
+// operations that touch both the cache and the underlying store
+// for the same key.
+const cacheLockStripes = 64
+
+// cachedStore is a StateStorer that keeps the raw values of the most
+// recently used keys in memory. Writes go through to the underlying
+// store before the cache is updated.
+type cachedStore struct {
+	store      StateStorer
+	maxEntries int
+	metrics    metrics
+
+	mtx     sync.Mutex // protects entries and lru
+	entries map[string]*list.Element
+	lru     *list.List // front is the most recently used
+
+	keyLocks [cacheLockStripes]sync.Mutex
+}
+
+type cacheEntry struct {
+	key   string
+	value []byte
+}
+
+// NewCachedStore wraps the given StateStorer with a write-through LRU cache
+// that holds up to maxEntries values.
+func NewCachedStore(s StateStorer, maxEntries int) StateStorer {
+	return &cachedStore{
+		store:      s,
+		maxEntries: maxEntries,
+		metrics:    newMetrics(),
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// Get retrieves a value of the requested key, from the cache if possible.
+func (s *cachedStore) Get(key string, i interface{}) error {
+	if data, ok := s.get(key); ok {
+		s.metrics.CacheHits.Inc()
+		return unmarshalValue(data, i)
+	}
+	s.metrics.CacheMisses.Inc()
+
+	// hold the key lock so that a concurrent write can not be
+	// overwritten in the cache by the value read here
+	l := s.keyLock(key)
+	l.Lock()
+	var v rawValue
+	err := s.store.Get(key, &v)
+	if err == nil {
+		s.add(key, v)
+	}
+	l.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return unmarshalValue(copyBytes(v), i)
+}
+
+// Put stores a value for the key in the underlying store and the cache.
+func (s *cachedStore) Put(key string, i interface{}) error {
+	data, err := marshalValue(i)
+	if err != nil {
+		return err
+	}
+	data = copyBytes(data)
+
+	l := s.keyLock(key)
+	l.Lock()
+	defer l.Unlock()
+
+	if err := s.store.Put(key, rawValue(data)); err != nil {
+		s.remove(key)
+		return err
+	}
+	s.add(key, data)
+	return nil
+}
+
+// Delete removes the key from the underlying store and the cache.
+func (s *cachedStore) Delete(key string) error {
+	l := s.keyLock(key)
+	l.Lock()
+	defer l.Unlock()
+
+	s.remove(key)
+	return s.store.Delete(key)
+}
+
+// Iterate entries that match the supplied prefix. Iteration always
+// reads from the underlying store.
+func (s *cachedStore) Iterate(prefix string, iterFunc StateIterFunc) error {
+	return s.store.Iterate(prefix, iterFunc)
+}
+
+// DB implements StateStorer.DB method.
+func (s *cachedStore) DB() *leveldb.DB {
+	return s.store.DB()
+}
+
+// Close releases the resources used by the wrapped store.
+func (s *cachedStore) Close() error {
+	return s.store.Close()
+}
+
+func (s *cachedStore) keyLock(key string) *sync.Mutex {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return &s.keyLocks[h.Sum32()%cacheLockStripes]
+}
+
+// get returns a copy of the cached value and marks it as recently used.
+func (s *cachedStore) get(key string) ([]byte, bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	s.lru.MoveToFront(e)
+	return copyBytes(e.Value.(*cacheEntry).value), true
+}
+
+// add caches the value, evicting the least recently used entries
+// if the cache is full.
+func (s *cachedStore) add(key string, value []byte) {
+	if s.maxEntries <= 0 {
+		return
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		e.Value.(*cacheEntry).value = value
+		s.lru.MoveToFront(e)
+		return
+	}
+
+	s.entries[key] = s.lru.PushFront(&cacheEntry{key: key, value: value})
+	for s.lru.Len() > s.maxEntries {
+		e := s.lru.Back()
+		s.lru.Remove(e)
+		delete(s.entries, e.Value.(*cacheEntry).key)
+		s.metrics.CacheEvictions.Inc()
+	}
+}
+
+func (s *cachedStore) remove(key string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		s.lru.Remove(e)
+		delete(s.entries, key)
+	}
+}
+
+func copyBytes(b []byte) []byte {
+	c := make([]byte, len(b))
+	copy(c, b)
+	return c
+}
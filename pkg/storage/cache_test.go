@@ -0,0 +1,148 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storage_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/statestore/mock"
+	"github.com/ethersphere/bee/pkg/statestore/test"
+	"github.com/ethersphere/bee/pkg/storage"
+)
+
+func TestCachedStore(t *testing.T) {
+	test.Run(t, func(t *testing.T) storage.StateStorer {
+		return storage.NewCachedStore(mock.NewStateStore(), 100)
+	})
+}
+
+func TestCachedStoreWriteThrough(t *testing.T) {
+	underlying := mock.NewStateStore()
+	store := storage.NewCachedStore(underlying, 10)
+
+	if err := store.Put("key", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	var got string
+	if err := underlying.Get("key", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != "value" {
+		t.Fatalf("got %q, want %q", got, "value")
+	}
+
+	if err := store.Delete("key"); err != nil {
+		t.Fatal(err)
+	}
+	if err := underlying.Get("key", &got); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, storage.ErrNotFound)
+	}
+	if err := store.Get("key", &got); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, storage.ErrNotFound)
+	}
+}
+
+func TestCachedStoreEviction(t *testing.T) {
+	store := storage.NewCachedStore(mock.NewStateStore(), 3)
+
+	for i := 0; i < 3; i++ {
+		if err := store.Put(fmt.Sprintf("key%d", i), i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, want := storage.CachedKeys(store), []string{"key2", "key1", "key0"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got cached keys %v, want %v", got, want)
+	}
+
+	// reading key0 makes it the most recently used one
+	var v int
+	if err := store.Get("key0", &v); err != nil {
+		t.Fatal(err)
+	}
+	if v != 0 {
+		t.Fatalf("got %d, want %d", v, 0)
+	}
+
+	// key1 is the least recently used and must be evicted
+	if err := store.Put("key3", 3); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := storage.CachedKeys(store), []string{"key3", "key0", "key2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got cached keys %v, want %v", got, want)
+	}
+
+	// evicted values are still read from the underlying store
+	if err := store.Get("key1", &v); err != nil {
+		t.Fatal(err)
+	}
+	if v != 1 {
+		t.Fatalf("got %d, want %d", v, 1)
+	}
+	if got, want := storage.CachedKeys(store), []string{"key1", "key3", "key0"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got cached keys %v, want %v", got, want)
+	}
+}
+
+func TestCachedStoreConcurrency(t *testing.T) {
+	underlying := mock.NewStateStore()
+	store := storage.NewCachedStore(underlying, 2)
+
+	const (
+		writers = 4
+		readers = 4
+		rounds  = 200
+	)
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				if err := store.Put("key", w*rounds+i); err != nil {
+					t.Error(err)
+					return
+				}
+				// churn other keys to force evictions
+				if err := store.Put(fmt.Sprintf("other%d", w), i); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(w)
+	}
+	for r := 0; r < readers; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				var v int
+				if err := store.Get("key", &v); err != nil && !errors.Is(err, storage.ErrNotFound) {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// the cache must agree with the underlying store after all writes
+	var cached, stored int
+	if err := store.Get("key", &cached); err != nil {
+		t.Fatal(err)
+	}
+	if err := underlying.Get("key", &stored); err != nil {
+		t.Fatal(err)
+	}
+	if cached != stored {
+		t.Fatalf("cached value %d differs from stored value %d", cached, stored)
+	}
+}
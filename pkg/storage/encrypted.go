@@ -0,0 +1,175 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+var _ StateStorer = (*encryptedStore)(nil)
+
+// ErrDecrypt is returned when a stored value fails authentication
+// while being decrypted, e.g. because it has been tampered with.
+var ErrDecrypt = errors.New("storage: decrypt value")
+
+// envelopeMagic prefixes every value written by the encrypted store. Values
+// without it are treated as legacy plaintext and are re-encrypted the next
+// time they are written.
+var envelopeMagic = []byte{0xbe, 0xe5, 0xec, 0x01}
+
+// encryptedStore is a StateStorer that encrypts values with AES-GCM before
+// handing them to the wrapped store. Keys are kept in plaintext so that
+// prefix iteration keeps working.
+type encryptedStore struct {
+	store StateStorer
+	aead  cipher.AEAD
+}
+
+// NewEncryptedStore wraps the given StateStorer so that all values are
+// transparently encrypted at rest with the provided key.
+func NewEncryptedStore(s StateStorer, key [32]byte) StateStorer {
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		panic(fmt.Errorf("new cipher: %w", err))
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(fmt.Errorf("new gcm: %w", err))
+	}
+	return &encryptedStore{
+		store: s,
+		aead:  aead,
+	}
+}
+
+// Get retrieves and decrypts the value of the requested key. Legacy
+// plaintext values are returned as they are.
+func (s *encryptedStore) Get(key string, i interface{}) error {
+	var v rawValue
+	if err := s.store.Get(key, &v); err != nil {
+		return err
+	}
+
+	data, err := s.open(key, v)
+	if err != nil {
+		return err
+	}
+
+	return unmarshalValue(data, i)
+}
+
+// Put encrypts and stores a value for the given key.
+func (s *encryptedStore) Put(key string, i interface{}) error {
+	data, err := marshalValue(i)
+	if err != nil {
+		return err
+	}
+
+	sealed, err := s.seal(key, data)
+	if err != nil {
+		return err
+	}
+
+	return s.store.Put(key, rawValue(sealed))
+}
+
+// Delete removes entries stored under a specific key.
+func (s *encryptedStore) Delete(key string) error {
+	return s.store.Delete(key)
+}
+
+// Iterate entries that match the supplied prefix, passing decrypted
+// values to the iterFunc.
+func (s *encryptedStore) Iterate(prefix string, iterFunc StateIterFunc) error {
+	return s.store.Iterate(prefix, func(k, v []byte) (bool, error) {
+		data, err := s.open(string(k), v)
+		if err != nil {
+			return true, err
+		}
+		return iterFunc(k, data)
+	})
+}
+
+// DB implements StateStorer.DB method.
+func (s *encryptedStore) DB() *leveldb.DB {
+	return s.store.DB()
+}
+
+// Close releases the resources used by the wrapped store.
+func (s *encryptedStore) Close() error {
+	return s.store.Close()
+}
+
+// seal encrypts data into an envelope: magic | nonce | ciphertext.
+// The key is used as additional data so that values can not be
+// swapped between keys without detection.
+func (s *encryptedStore) seal(key string, data []byte) ([]byte, error) {
+	nonceSize := s.aead.NonceSize()
+	envelope := make([]byte, len(envelopeMagic)+nonceSize, len(envelopeMagic)+nonceSize+len(data)+s.aead.Overhead())
+	copy(envelope, envelopeMagic)
+	nonce := envelope[len(envelopeMagic):]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("nonce: %w", err)
+	}
+	return s.aead.Seal(envelope, nonce, data, []byte(key)), nil
+}
+
+// open decrypts an envelope created by seal. Data that does not carry the
+// envelope magic is considered legacy plaintext and is returned unchanged.
+func (s *encryptedStore) open(key string, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, envelopeMagic) {
+		return data, nil
+	}
+	data = data[len(envelopeMagic):]
+	nonceSize := s.aead.NonceSize()
+	if len(data) < nonceSize {
+		return nil, ErrDecrypt
+	}
+	plain, err := s.aead.Open(nil, data[:nonceSize], data[nonceSize:], []byte(key))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plain, nil
+}
+
+// rawValue passes already serialized bytes through a StateStorer
+// without any additional encoding.
+type rawValue []byte
+
+func (v rawValue) MarshalBinary() ([]byte, error) {
+	return v, nil
+}
+
+func (v *rawValue) UnmarshalBinary(data []byte) error {
+	*v = append((*v)[:0], data...)
+	return nil
+}
+
+// marshalValue serializes a value the same way state store implementations
+// do: BinaryMarshaler with a fallback to JSON.
+func marshalValue(i interface{}) ([]byte, error) {
+	if marshaler, ok := i.(encoding.BinaryMarshaler); ok {
+		return marshaler.MarshalBinary()
+	}
+	return json.Marshal(i)
+}
+
+// unmarshalValue is the counterpart of marshalValue.
+func unmarshalValue(data []byte, i interface{}) error {
+	if unmarshaler, ok := i.(encoding.BinaryUnmarshaler); ok {
+		return unmarshaler.UnmarshalBinary(data)
+	}
+	return json.Unmarshal(data, i)
+}
@@ -0,0 +1,144 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storage_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/statestore/mock"
+	"github.com/ethersphere/bee/pkg/statestore/test"
+	"github.com/ethersphere/bee/pkg/storage"
+)
+
+var testKey = [32]byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+type raw []byte
+
+func (r raw) MarshalBinary() ([]byte, error) {
+	return r, nil
+}
+
+func (r *raw) UnmarshalBinary(data []byte) error {
+	*r = append((*r)[:0], data...)
+	return nil
+}
+
+func TestEncryptedStore(t *testing.T) {
+	test.Run(t, func(t *testing.T) storage.StateStorer {
+		return storage.NewEncryptedStore(mock.NewStateStore(), testKey)
+	})
+}
+
+func TestEncryptedStoreCiphertext(t *testing.T) {
+	underlying := mock.NewStateStore()
+	store := storage.NewEncryptedStore(underlying, testKey)
+
+	if err := store.Put("key", "secret value"); err != nil {
+		t.Fatal(err)
+	}
+
+	var r raw
+	if err := underlying.Get("key", &r); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(r, storage.EnvelopeMagic) {
+		t.Fatal("stored value is not an encrypted envelope")
+	}
+	if bytes.Contains(r, []byte("secret value")) {
+		t.Fatal("stored value contains plaintext")
+	}
+
+	var got string
+	if err := store.Get("key", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != "secret value" {
+		t.Fatalf("got %q, want %q", got, "secret value")
+	}
+}
+
+func TestEncryptedStoreTamper(t *testing.T) {
+	underlying := mock.NewStateStore()
+	store := storage.NewEncryptedStore(underlying, testKey)
+
+	if err := store.Put("key", "secret value"); err != nil {
+		t.Fatal(err)
+	}
+
+	var r raw
+	if err := underlying.Get("key", &r); err != nil {
+		t.Fatal(err)
+	}
+	r[len(r)-1] ^= 0xff
+	if err := underlying.Put("key", r); err != nil {
+		t.Fatal(err)
+	}
+
+	var got string
+	if err := store.Get("key", &got); !errors.Is(err, storage.ErrDecrypt) {
+		t.Fatalf("got error %v, want %v", err, storage.ErrDecrypt)
+	}
+
+	// a value moved under a different key must not be accepted either
+	if err := store.Put("key", "secret value"); err != nil {
+		t.Fatal(err)
+	}
+	if err := underlying.Get("key", &r); err != nil {
+		t.Fatal(err)
+	}
+	if err := underlying.Put("other", r); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Get("other", &got); !errors.Is(err, storage.ErrDecrypt) {
+		t.Fatalf("got error %v, want %v", err, storage.ErrDecrypt)
+	}
+
+	// a different key must not decrypt the value
+	other := storage.NewEncryptedStore(underlying, [32]byte{9})
+	if err := other.Get("key", &got); !errors.Is(err, storage.ErrDecrypt) {
+		t.Fatalf("got error %v, want %v", err, storage.ErrDecrypt)
+	}
+}
+
+func TestEncryptedStorePlaintextUpgrade(t *testing.T) {
+	underlying := mock.NewStateStore()
+
+	// legacy value written before encryption was enabled
+	if err := underlying.Put("key", "legacy value"); err != nil {
+		t.Fatal(err)
+	}
+
+	store := storage.NewEncryptedStore(underlying, testKey)
+
+	var got string
+	if err := store.Get("key", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != "legacy value" {
+		t.Fatalf("got %q, want %q", got, "legacy value")
+	}
+
+	if err := store.Put("key", got); err != nil {
+		t.Fatal(err)
+	}
+
+	var r raw
+	if err := underlying.Get("key", &r); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(r, storage.EnvelopeMagic) {
+		t.Fatal("legacy value was not re-encrypted on write")
+	}
+
+	got = ""
+	if err := store.Get("key", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != "legacy value" {
+		t.Fatalf("got %q, want %q", got, "legacy value")
+	}
+}
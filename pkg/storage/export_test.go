@@ -0,0 +1,20 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storage
+
+var EnvelopeMagic = envelopeMagic
+
+// CachedKeys returns the keys held by a store created with NewCachedStore,
+// ordered from the most to the least recently used.
+func CachedKeys(s StateStorer) (keys []string) {
+	c := s.(*cachedStore)
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for e := c.lru.Front(); e != nil; e = e.Next() {
+		keys = append(keys, e.Value.(*cacheEntry).key)
+	}
+	return keys
+}
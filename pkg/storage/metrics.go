@@ -0,0 +1,45 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	m "github.com/ethersphere/bee/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metrics struct {
+	CacheHits      prometheus.Counter
+	CacheMisses    prometheus.Counter
+	CacheEvictions prometheus.Counter
+}
+
+func newMetrics() metrics {
+	subsystem := "statestore"
+
+	return metrics{
+		CacheHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "cache_hits",
+			Help:      "Number of state store reads served from the cache.",
+		}),
+		CacheMisses: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "cache_misses",
+			Help:      "Number of state store reads not found in the cache.",
+		}),
+		CacheEvictions: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "cache_evictions",
+			Help:      "Number of entries evicted from the state store cache.",
+		}),
+	}
+}
+
+func (s *cachedStore) Metrics() []prometheus.Collector {
+	return m.PrometheusCollectorsFromFields(s.metrics)
+}
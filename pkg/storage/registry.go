@@ -0,0 +1,108 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// ValidateFunc reports an error if the raw value stored under the key
+// can not be decoded by the component that owns the key namespace.
+type ValidateFunc func(key, value []byte) error
+
+// SweepFunc removes stale records from the key namespace it is registered
+// for and returns the number of removed records.
+type SweepFunc func(ctx context.Context, store StateStorer) (removed int, err error)
+
+// prefixes holds the state store key namespaces registered by components.
+var prefixes = struct {
+	mtx        sync.RWMutex
+	validators map[string]ValidateFunc
+	sweeps     map[string]SweepFunc
+}{
+	validators: make(map[string]ValidateFunc),
+	sweeps:     make(map[string]SweepFunc),
+}
+
+// RegisterPrefix registers a state store key namespace together with a
+// function that validates values stored in it. Registering the same
+// prefix again replaces the previous validator.
+func RegisterPrefix(prefix string, validate ValidateFunc) {
+	prefixes.mtx.Lock()
+	defer prefixes.mtx.Unlock()
+
+	prefixes.validators[prefix] = validate
+}
+
+// PrefixValidators returns all registered namespaces with their validators.
+func PrefixValidators() map[string]ValidateFunc {
+	prefixes.mtx.RLock()
+	defer prefixes.mtx.RUnlock()
+
+	v := make(map[string]ValidateFunc, len(prefixes.validators))
+	for p, f := range prefixes.validators {
+		v[p] = f
+	}
+	return v
+}
+
+// RegisterSweep registers a function that removes stale records from the
+// given key namespace. Registering the same prefix again replaces the
+// previous sweep.
+func RegisterSweep(prefix string, sweep SweepFunc) {
+	prefixes.mtx.Lock()
+	defer prefixes.mtx.Unlock()
+
+	prefixes.sweeps[prefix] = sweep
+}
+
+// Sweeps returns all registered namespaces with their sweeps.
+func Sweeps() map[string]SweepFunc {
+	prefixes.mtx.RLock()
+	defer prefixes.mtx.RUnlock()
+
+	v := make(map[string]SweepFunc, len(prefixes.sweeps))
+	for p, f := range prefixes.sweeps {
+		v[p] = f
+	}
+	return v
+}
+
+// MatchPrefix returns the longest prefix from the validators map that the
+// key starts with and its validator. If no prefix matches, ok is false.
+func MatchPrefix(validators map[string]ValidateFunc, key string) (prefix string, validate ValidateFunc, ok bool) {
+	for p, f := range validators {
+		if strings.HasPrefix(key, p) && (!ok || len(p) > len(prefix)) {
+			prefix, validate, ok = p, f, true
+		}
+	}
+	return prefix, validate, ok
+}
+
+// IntegrityReport is the result of a state store integrity check.
+type IntegrityReport struct {
+	Keys       int                         `json:"keys"`
+	Namespaces map[string]*NamespaceReport `json:"namespaces"`
+}
+
+// NamespaceReport holds integrity check results for a single
+// registered key namespace.
+type NamespaceReport struct {
+	Keys    int `json:"keys"`
+	BadKeys int `json:"badKeys"`
+}
+
+// StateStoreMaintainer is implemented by state stores that support
+// integrity checks and manual compaction.
+type StateStoreMaintainer interface {
+	// CheckIntegrity iterates over all keys and validates the values of
+	// the registered namespaces.
+	CheckIntegrity(ctx context.Context) (IntegrityReport, error)
+	// Compact compacts the key range with the given prefix. An empty
+	// prefix compacts the whole store.
+	Compact(prefix string) error
+}
@@ -0,0 +1,68 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"context"
+	"sort"
+)
+
+// SweepReport is the combined result of all registered sweeps.
+type SweepReport struct {
+	Removed int                     `json:"removed"`
+	Sweeps  map[string]*SweepResult `json:"sweeps"`
+}
+
+// SweepResult is the result of the sweep of a single key namespace.
+type SweepResult struct {
+	Removed int    `json:"removed"`
+	Error   string `json:"error,omitempty"`
+}
+
+// RunSweeps runs all registered sweeps over the store, one after another.
+// A failing sweep does not prevent the others from running; its error is
+// recorded in the report. If the context is canceled, the sweeps that have
+// not been run yet are skipped and the context error is returned together
+// with the partial report.
+func RunSweeps(ctx context.Context, store StateStorer) (SweepReport, error) {
+	return RunSweepsFunc(ctx, store, func(string) bool { return true })
+}
+
+// RunSweepsFunc is RunSweeps, which only runs the sweeps of the prefixes
+// for which match returns true.
+func RunSweepsFunc(ctx context.Context, store StateStorer, match func(prefix string) bool) (SweepReport, error) {
+	sweeps := Sweeps()
+	for prefix := range sweeps {
+		if !match(prefix) {
+			delete(sweeps, prefix)
+		}
+	}
+	report := SweepReport{
+		Sweeps: make(map[string]*SweepResult, len(sweeps)),
+	}
+
+	// run in a deterministic order
+	names := make([]string, 0, len(sweeps))
+	for prefix := range sweeps {
+		names = append(names, prefix)
+	}
+	sort.Strings(names)
+
+	for _, prefix := range names {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		removed, err := sweeps[prefix](ctx, store)
+		result := &SweepResult{Removed: removed}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		report.Sweeps[prefix] = result
+		report.Removed += removed
+	}
+
+	return report, ctx.Err()
+}
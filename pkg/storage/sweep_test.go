@@ -0,0 +1,78 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/statestore/mock"
+	"github.com/ethersphere/bee/pkg/storage"
+)
+
+func TestRunSweeps(t *testing.T) {
+	store := mock.NewStateStore()
+	if err := store.Put("sweep-test-a-stale", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	storage.RegisterSweep("sweep-test-a-", func(ctx context.Context, s storage.StateStorer) (int, error) {
+		if err := s.Delete("sweep-test-a-stale"); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	})
+	storage.RegisterSweep("sweep-test-b-", func(ctx context.Context, s storage.StateStorer) (int, error) {
+		return 2, errors.New("sweep error")
+	})
+
+	report, err := storage.RunSweeps(context.Background(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := report.Sweeps["sweep-test-a-"]
+	if a == nil || a.Removed != 1 || a.Error != "" {
+		t.Fatalf("got sweep result %+v, want 1 removed without error", a)
+	}
+	b := report.Sweeps["sweep-test-b-"]
+	if b == nil || b.Removed != 2 || b.Error != "sweep error" {
+		t.Fatalf("got sweep result %+v, want 2 removed with error", b)
+	}
+	if report.Removed < 3 {
+		t.Fatalf("got %d removed in total, want at least %d", report.Removed, 3)
+	}
+
+	var v string
+	if err := store.Get("sweep-test-a-stale", &v); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("got error %v, want %v", err, storage.ErrNotFound)
+	}
+
+	t.Run("func", func(t *testing.T) {
+		report, err := storage.RunSweepsFunc(context.Background(), store, func(prefix string) bool {
+			return prefix == "sweep-test-b-"
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Sweeps) != 1 || report.Sweeps["sweep-test-b-"] == nil {
+			t.Fatalf("got sweep results %v, want only %q", report.Sweeps, "sweep-test-b-")
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		report, err := storage.RunSweeps(ctx, store)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("got error %v, want %v", err, context.Canceled)
+		}
+		if len(report.Sweeps) != 0 {
+			t.Fatalf("got %d sweep results, want none", len(report.Sweeps))
+		}
+	})
+}
@@ -56,3 +56,13 @@ func ExtendedProximity(one, other []byte) (ret uint8) {
 	}
 	return ExtendedPO
 }
+
+// ProximityHistogram returns the number of addresses in each
+// proximity order bin relative to the base address.
+func ProximityHistogram(base Address, addrs []Address) [MaxBins]int {
+	var h [MaxBins]int
+	for _, a := range addrs {
+		h[Proximity(base.Bytes(), a.Bytes())]++
+	}
+	return h
+}
@@ -195,3 +195,24 @@ func TestProximity(t *testing.T) {
 		}
 	}
 }
+
+func TestProximityHistogram(t *testing.T) {
+	base := NewAddress([]byte{0b00000000, 0b00000000, 0b00000000, 0b00000000})
+	addrs := []Address{
+		NewAddress([]byte{0b10000000, 0b00000000, 0b00000000, 0b00000000}),
+		NewAddress([]byte{0b11000000, 0b00000000, 0b00000000, 0b00000000}),
+		NewAddress([]byte{0b00100000, 0b00000000, 0b00000000, 0b00000000}),
+		NewAddress([]byte{0b00000000, 0b00000001, 0b00000000, 0b00000000}),
+		base,
+	}
+
+	var want [MaxBins]int
+	want[0] = 2
+	want[2] = 1
+	want[15] = 1
+	want[MaxPO] = 1
+
+	if got := ProximityHistogram(base, addrs); got != want {
+		t.Fatalf("got histogram %v, want %v", got, want)
+	}
+}
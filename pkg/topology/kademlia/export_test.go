@@ -4,10 +4,19 @@
 
 package kademlia
 
+import "time"
+
 var (
 	TimeToRetry                 = &timeToRetry
 	QuickSaturationPeers        = &quickSaturationPeers
 	SaturationPeers             = &saturationPeers
 	OverSaturationPeers         = &overSaturationPeers
 	BootnodeOverSaturationPeers = &bootNodeOverSaturationPeers
+
+	RetryAfterDisconnect = (*Kad).retryAfterDisconnect
+	FilterSuggestions    = filterSuggestions
 )
+
+func SetTimeNow(f func() time.Time) {
+	timeNow = f
+}
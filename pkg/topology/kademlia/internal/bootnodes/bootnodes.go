@@ -0,0 +1,157 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package bootnodes keeps the connection health of the configured bootnodes
+// in the state store, so that the bootnodes which are known to be reachable
+// are tried first when the node starts. It is intended to be used with the
+// kademlia.
+package bootnodes
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/storage"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+const keyPrefix = "bootnode-health-"
+
+var (
+	// timeNow is used to deterministically mock time.Now() in tests.
+	timeNow = time.Now
+	// shuffle randomizes the order of bootnodes with equal health.
+	shuffle = rand.Shuffle
+)
+
+func init() {
+	storage.RegisterPrefix(keyPrefix, func(_, value []byte) error {
+		var h Health
+		return json.Unmarshal(value, &h)
+	})
+}
+
+// Health is the connection history of a bootnode.
+type Health struct {
+	// LastConnected is the time of the last successful connection,
+	// zero if the bootnode was never connected.
+	LastConnected time.Time `json:"lastConnected"`
+	// LastFailed is the time of the last failed connection attempt.
+	LastFailed time.Time `json:"lastFailed"`
+	// ConsecutiveFailures is the number of failed connection
+	// attempts since the last successful connection.
+	ConsecutiveFailures int `json:"consecutiveFailures"`
+}
+
+// Status is the health of a single bootnode.
+type Status struct {
+	Address ma.Multiaddr
+	Health
+}
+
+// Tracker keeps the health of the bootnodes.
+type Tracker struct {
+	store storage.StateStorer
+	addrs []ma.Multiaddr
+
+	mtx    sync.Mutex
+	health map[string]Health
+}
+
+// New returns a Tracker for the bootnodes with the health loaded from the
+// store. Bootnodes without a stored record, or with a record that can not be
+// read, start with an empty history. The health is not persisted if the store
+// is nil.
+func New(store storage.StateStorer, addrs []ma.Multiaddr) *Tracker {
+	t := &Tracker{
+		store:  store,
+		addrs:  addrs,
+		health: make(map[string]Health, len(addrs)),
+	}
+	if store == nil {
+		return t
+	}
+	for _, addr := range addrs {
+		var h Health
+		if err := store.Get(generateKey(addr), &h); err == nil {
+			t.health[addr.String()] = h
+		}
+	}
+	return t
+}
+
+// Order returns the bootnodes ordered by their health. The bootnodes with the
+// fewest consecutive failures come first and among them the most recently
+// connected ones. Bootnodes with equal health are in random order.
+func (t *Tracker) Order() []ma.Multiaddr {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	addrs := make([]ma.Multiaddr, len(t.addrs))
+	copy(addrs, t.addrs)
+	shuffle(len(addrs), func(i, j int) {
+		addrs[i], addrs[j] = addrs[j], addrs[i]
+	})
+	sort.SliceStable(addrs, func(i, j int) bool {
+		hi, hj := t.health[addrs[i].String()], t.health[addrs[j].String()]
+		if hi.ConsecutiveFailures != hj.ConsecutiveFailures {
+			return hi.ConsecutiveFailures < hj.ConsecutiveFailures
+		}
+		return hi.LastConnected.After(hj.LastConnected)
+	})
+	return addrs
+}
+
+// Connected records a successful connection to the bootnode.
+func (t *Tracker) Connected(addr ma.Multiaddr) error {
+	return t.update(addr, func(h *Health) {
+		h.LastConnected = timeNow()
+		h.ConsecutiveFailures = 0
+	})
+}
+
+// Failed records a failed connection attempt to the bootnode.
+func (t *Tracker) Failed(addr ma.Multiaddr) error {
+	return t.update(addr, func(h *Health) {
+		h.LastFailed = timeNow()
+		h.ConsecutiveFailures++
+	})
+}
+
+// Status returns the health of all bootnodes in the configured order.
+func (t *Tracker) Status() []Status {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	status := make([]Status, len(t.addrs))
+	for i, addr := range t.addrs {
+		status[i] = Status{Address: addr, Health: t.health[addr.String()]}
+	}
+	return status
+}
+
+func (t *Tracker) update(addr ma.Multiaddr, f func(*Health)) error {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	h := t.health[addr.String()]
+	f(&h)
+	t.health[addr.String()] = h
+
+	if t.store == nil {
+		return nil
+	}
+	if err := t.store.Put(generateKey(addr), h); err != nil {
+		return fmt.Errorf("persist bootnode health: %w", err)
+	}
+	return nil
+}
+
+func generateKey(addr ma.Multiaddr) string {
+	return keyPrefix + addr.String()
+}
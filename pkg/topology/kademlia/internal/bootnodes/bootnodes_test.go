@@ -0,0 +1,114 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bootnodes_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/statestore/mock"
+	"github.com/ethersphere/bee/pkg/topology/kademlia/internal/bootnodes"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+func newAddrs(t *testing.T, n int) []ma.Multiaddr {
+	t.Helper()
+
+	addrs := make([]ma.Multiaddr, n)
+	for i := range addrs {
+		addr, err := ma.NewMultiaddr(fmt.Sprintf("/ip4/127.0.0.1/tcp/%d", 1634+i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		addrs[i] = addr
+	}
+	return addrs
+}
+
+func expectOrder(t *testing.T, got, want []ma.Multiaddr) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d bootnodes, want %d", len(got), len(want))
+	}
+	for i := range got {
+		if !got[i].Equal(want[i]) {
+			t.Fatalf("got bootnode %d %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestOrder(t *testing.T) {
+	now := time.Unix(1000, 0)
+	bootnodes.SetTimeNow(func() time.Time { return now })
+	defer bootnodes.SetTimeNow(time.Now)
+
+	// reverse the order to check that the ties are shuffled
+	bootnodes.SetShuffle(func(n int, swap func(i, j int)) {
+		for i := 0; i < n/2; i++ {
+			swap(i, n-1-i)
+		}
+	})
+
+	store := mock.NewStateStore()
+	addrs := newAddrs(t, 5)
+	tracker := bootnodes.New(store, addrs)
+
+	// no history, only shuffled
+	expectOrder(t, tracker.Order(), []ma.Multiaddr{addrs[4], addrs[3], addrs[2], addrs[1], addrs[0]})
+
+	// addrs 0 and 1 are dead, the more failures the later
+	for _, addr := range []ma.Multiaddr{addrs[0], addrs[0], addrs[1]} {
+		if err := tracker.Failed(addr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// addrs 2 and 3 are healthy, the more recent the earlier
+	if err := tracker.Connected(addrs[3]); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Minute)
+	if err := tracker.Connected(addrs[2]); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ma.Multiaddr{addrs[2], addrs[3], addrs[4], addrs[1], addrs[0]}
+	expectOrder(t, tracker.Order(), want)
+
+	// the health is persisted
+	expectOrder(t, bootnodes.New(store, addrs).Order(), want)
+
+	// a successful connection resets the failures
+	now = now.Add(time.Minute)
+	if err := tracker.Connected(addrs[0]); err != nil {
+		t.Fatal(err)
+	}
+	expectOrder(t, tracker.Order(), []ma.Multiaddr{addrs[0], addrs[2], addrs[3], addrs[4], addrs[1]})
+}
+
+func TestStatus(t *testing.T) {
+	now := time.Unix(1000, 0)
+	bootnodes.SetTimeNow(func() time.Time { return now })
+	defer bootnodes.SetTimeNow(time.Now)
+
+	addrs := newAddrs(t, 2)
+	tracker := bootnodes.New(nil, addrs)
+
+	if err := tracker.Failed(addrs[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	status := tracker.Status()
+	if len(status) != 2 {
+		t.Fatalf("got %d statuses, want %d", len(status), 2)
+	}
+	if !status[0].Address.Equal(addrs[0]) || status[0].ConsecutiveFailures != 0 || !status[0].LastFailed.IsZero() {
+		t.Fatalf("got status %+v for a bootnode without history", status[0])
+	}
+	if !status[1].Address.Equal(addrs[1]) || status[1].ConsecutiveFailures != 1 || !status[1].LastFailed.Equal(now) {
+		t.Fatalf("got status %+v for a failed bootnode", status[1])
+	}
+}
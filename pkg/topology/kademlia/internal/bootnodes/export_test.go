@@ -0,0 +1,15 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bootnodes
+
+import "time"
+
+func SetTimeNow(f func() time.Time) {
+	timeNow = f
+}
+
+func SetShuffle(f func(n int, swap func(i, j int))) {
+	shuffle = f
+}
@@ -0,0 +1,144 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package churn tracks the connection lifetimes of peers and counts
+// the peers that reconnect shortly after they have disconnected.
+// It is intended to be used with the kademlia.
+package churn
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+const (
+	defaultFlapWindow = 5 * time.Minute
+	defaultMaxPeers   = 10000
+)
+
+// Options for the Tracker.
+type Options struct {
+	// FlapWindow is the time after a disconnect during which
+	// a connect of the same peer is counted as a flap.
+	FlapWindow time.Duration
+	// MaxPeers is the number of peers tracked. When it is exceeded,
+	// the least recently active peers are forgotten.
+	MaxPeers int
+}
+
+// Stats holds the churn statistics of a single peer.
+type Stats struct {
+	// ConnectedAt is the time the current connection was
+	// established, zero if the peer is not connected.
+	ConnectedAt time.Time
+	// LastConnectionDuration is the duration of the last
+	// finished connection.
+	LastConnectionDuration time.Duration
+	// Flaps is the number of connects that happened within
+	// the flap window after a disconnect.
+	Flaps int
+}
+
+type entry struct {
+	addr           string
+	disconnectedAt time.Time
+	stats          Stats
+}
+
+// Tracker keeps the churn statistics of peers in a bounded structure.
+type Tracker struct {
+	flapWindow time.Duration
+	maxPeers   int
+
+	mtx   sync.Mutex
+	peers map[string]*list.Element
+	lru   *list.List // most recently active peers in front
+}
+
+// New returns a new Tracker.
+func New(o Options) *Tracker {
+	if o.FlapWindow == 0 {
+		o.FlapWindow = defaultFlapWindow
+	}
+	if o.MaxPeers == 0 {
+		o.MaxPeers = defaultMaxPeers
+	}
+	return &Tracker{
+		flapWindow: o.FlapWindow,
+		maxPeers:   o.MaxPeers,
+		peers:      make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// Connected records that the connection to the peer was established at
+// the time t. It returns true if the connect is counted as a flap.
+func (t *Tracker) Connected(addr swarm.Address, now time.Time) (flap bool) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	e := t.entry(addr)
+	if !e.stats.ConnectedAt.IsZero() {
+		return false // already connected
+	}
+	e.stats.ConnectedAt = now
+	if !e.disconnectedAt.IsZero() && now.Sub(e.disconnectedAt) <= t.flapWindow {
+		e.stats.Flaps++
+		return true
+	}
+	return false
+}
+
+// Disconnected records that the connection to the peer was closed at the
+// time t. It returns the duration of the closed connection and false if
+// the peer was not known to be connected.
+func (t *Tracker) Disconnected(addr swarm.Address, now time.Time) (time.Duration, bool) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	e := t.entry(addr)
+	if e.stats.ConnectedAt.IsZero() {
+		return 0, false
+	}
+	d := now.Sub(e.stats.ConnectedAt)
+	e.stats.LastConnectionDuration = d
+	e.stats.ConnectedAt = time.Time{}
+	e.disconnectedAt = now
+	return d, true
+}
+
+// Stats returns the churn statistics of the peer and false if the peer
+// is not tracked.
+func (t *Tracker) Stats(addr swarm.Address) (Stats, bool) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	el, ok := t.peers[addr.ByteString()]
+	if !ok {
+		return Stats{}, false
+	}
+	return el.Value.(*entry).stats, true
+}
+
+// entry returns the entry of the peer, creating it if necessary, and marks
+// it as the most recently active one. Must be called with mtx locked.
+func (t *Tracker) entry(addr swarm.Address) *entry {
+	key := addr.ByteString()
+	if el, ok := t.peers[key]; ok {
+		t.lru.MoveToFront(el)
+		return el.Value.(*entry)
+	}
+
+	e := &entry{addr: key}
+	t.peers[key] = t.lru.PushFront(e)
+	for t.lru.Len() > t.maxPeers {
+		el := t.lru.Back()
+		t.lru.Remove(el)
+		delete(t.peers, el.Value.(*entry).addr)
+	}
+	return e
+}
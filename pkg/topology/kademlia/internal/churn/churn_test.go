@@ -0,0 +1,87 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package churn_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/swarm/test"
+	"github.com/ethersphere/bee/pkg/topology/kademlia/internal/churn"
+)
+
+func TestTracker(t *testing.T) {
+	tracker := churn.New(churn.Options{FlapWindow: time.Minute})
+	addr := test.RandomAddress()
+	now := time.Unix(1000, 0)
+
+	if _, ok := tracker.Stats(addr); ok {
+		t.Fatal("unknown peer is tracked")
+	}
+
+	if tracker.Connected(addr, now) {
+		t.Fatal("first connect counted as a flap")
+	}
+	// repeated connect notifications do not restart the connection
+	tracker.Connected(addr, now.Add(time.Second))
+
+	now = now.Add(time.Hour)
+	d, ok := tracker.Disconnected(addr, now)
+	if !ok || d != time.Hour {
+		t.Fatalf("got connection duration %s %v, want %s", d, ok, time.Hour)
+	}
+	if _, ok := tracker.Disconnected(addr, now); ok {
+		t.Fatal("disconnect of a disconnected peer reported a connection")
+	}
+
+	// reconnect within the flap window
+	now = now.Add(30 * time.Second)
+	if !tracker.Connected(addr, now) {
+		t.Fatal("reconnect within the flap window not counted as a flap")
+	}
+	now = now.Add(10 * time.Second)
+	tracker.Disconnected(addr, now)
+
+	// reconnect after the flap window
+	now = now.Add(2 * time.Minute)
+	if tracker.Connected(addr, now) {
+		t.Fatal("reconnect after the flap window counted as a flap")
+	}
+
+	stats, ok := tracker.Stats(addr)
+	if !ok {
+		t.Fatal("peer not tracked")
+	}
+	want := churn.Stats{
+		ConnectedAt:            now,
+		LastConnectionDuration: 10 * time.Second,
+		Flaps:                  1,
+	}
+	if stats != want {
+		t.Fatalf("got stats %+v, want %+v", stats, want)
+	}
+}
+
+func TestTrackerBounded(t *testing.T) {
+	tracker := churn.New(churn.Options{MaxPeers: 2})
+	now := time.Unix(1000, 0)
+
+	a, b, c := test.RandomAddress(), test.RandomAddress(), test.RandomAddress()
+	tracker.Connected(a, now)
+	tracker.Connected(b, now)
+	// a becomes the most recently active peer
+	tracker.Disconnected(a, now)
+	tracker.Connected(c, now)
+
+	if _, ok := tracker.Stats(b); ok {
+		t.Fatal("least recently active peer not evicted")
+	}
+	if _, ok := tracker.Stats(a); !ok {
+		t.Fatal("peer a evicted")
+	}
+	if _, ok := tracker.Stats(c); !ok {
+		t.Fatal("peer c evicted")
+	}
+}
@@ -7,11 +7,14 @@
 package metrics
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/ethersphere/bee/pkg/shed"
+	"github.com/ethersphere/bee/pkg/storage"
 	"github.com/ethersphere/bee/pkg/swarm"
 	"github.com/hashicorp/go-multierror"
 	"github.com/syndtr/goleveldb/leveldb"
@@ -23,6 +26,10 @@ const (
 	peerTotalConnectionDuration string = "peer-total-connection-duration"
 )
 
+// LastSeenKeyPrefix is the state store key prefix of
+// the persisted peers' last seen timestamps.
+var LastSeenKeyPrefix = string(shed.FieldKey(peerLastSeenTimestamp + "-"))
+
 // PeerConnectionDirection represents peer connection direction.
 type PeerConnectionDirection string
 
@@ -341,6 +348,48 @@ func (c *Collector) Flush(addresses ...swarm.Address) error {
 	return mErr
 }
 
+// SweepOrphaned removes the persisted counters of peers for which
+// the known function returns false.
+func SweepOrphaned(ctx context.Context, store storage.StateStorer, known func(swarm.Address) (bool, error)) (removed int, err error) {
+	var orphaned []swarm.Address
+	if err := store.Iterate(LastSeenKeyPrefix, func(k, _ []byte) (bool, error) {
+		if !strings.HasPrefix(string(k), LastSeenKeyPrefix) {
+			return true, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return true, err
+		}
+
+		addr, err := swarm.ParseHexAddress(strings.TrimPrefix(string(k), LastSeenKeyPrefix))
+		if err != nil {
+			return true, fmt.Errorf("invalid last seen key %q: %w", k, err)
+		}
+		orphaned = append(orphaned, addr)
+		return false, nil
+	}); err != nil {
+		return 0, err
+	}
+
+	for _, addr := range orphaned {
+		ok, err := known(addr)
+		if err != nil {
+			return removed, err
+		}
+		if ok {
+			continue
+		}
+
+		for _, prefix := range []string{peerLastSeenTimestamp, peerTotalConnectionDuration} {
+			key := string(shed.FieldKey(newPeerKey(prefix, addr.String()).String()))
+			if err := store.Delete(key); err != nil {
+				return removed, err
+			}
+		}
+		removed++
+	}
+	return removed, nil
+}
+
 // Finalize logs out all ongoing peer sessions
 // and flushes all in-memory metrics counters.
 func (c *Collector) Finalize(t time.Time) error {
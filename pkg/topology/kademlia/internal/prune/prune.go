@@ -0,0 +1,96 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package prune selects the connected peers to disconnect from when the
+// number of connections has to be reduced, without unbalancing the bins.
+// It is intended to be used with the kademlia.
+package prune
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+)
+
+// Peer is the snapshot of a connected peer considered for pruning.
+type Peer struct {
+	Address swarm.Address
+	// Bin is the proximity order of the peer address to the base address.
+	Bin uint8
+	// Score ranks the peers of a bin, the peers with
+	// the lowest score are disconnected first.
+	Score float64
+	// Protected peers are never selected, but they
+	// count towards the population of their bin.
+	Protected bool
+}
+
+// Options for the pruning policy.
+type Options struct {
+	// Target is the number of peers in a bin above
+	// which the bin is considered over-populated.
+	Target int
+	// Min is the number of peers below which a bin is never pruned.
+	Min int
+}
+
+// Victims returns up to n peers to disconnect from. Peers are only selected
+// from bins that have more peers than the target, and never so many that a
+// bin would drop below the target or the minimum. The worst scoring peers
+// of the most over-populated bin are selected first.
+func Victims(base swarm.Address, peers []Peer, n int, o Options) []swarm.Address {
+	addrs := make([]swarm.Address, len(peers))
+	for i, p := range peers {
+		addrs[i] = p.Address
+	}
+	population := swarm.ProximityHistogram(base, addrs)
+
+	floor := o.Target
+	if o.Min > floor {
+		floor = o.Min
+	}
+
+	var (
+		surplus    [swarm.MaxBins]int
+		candidates [swarm.MaxBins][]Peer
+	)
+	for bin, count := range population {
+		surplus[bin] = count - floor
+	}
+	for _, p := range peers {
+		if !p.Protected && surplus[p.Bin] > 0 {
+			candidates[p.Bin] = append(candidates[p.Bin], p)
+		}
+	}
+	for _, c := range candidates {
+		sort.Slice(c, func(i, j int) bool {
+			if c[i].Score != c[j].Score {
+				return c[i].Score < c[j].Score
+			}
+			return bytes.Compare(c[i].Address.Bytes(), c[j].Address.Bytes()) < 0
+		})
+	}
+
+	var victims []swarm.Address
+	for len(victims) < n {
+		bin := -1
+		for b := range candidates {
+			if surplus[b] <= 0 || len(candidates[b]) == 0 {
+				continue
+			}
+			if bin == -1 || surplus[b] > surplus[bin] ||
+				surplus[b] == surplus[bin] && candidates[b][0].Score < candidates[bin][0].Score {
+				bin = b
+			}
+		}
+		if bin == -1 {
+			break
+		}
+		victims = append(victims, candidates[bin][0].Address)
+		candidates[bin] = candidates[bin][1:]
+		surplus[bin]--
+	}
+	return victims
+}
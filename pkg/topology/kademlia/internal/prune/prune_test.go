@@ -0,0 +1,128 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package prune_test
+
+import (
+	"testing"
+
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/ethersphere/bee/pkg/swarm/test"
+	"github.com/ethersphere/bee/pkg/topology/kademlia/internal/prune"
+)
+
+// peersAt returns n peers in the bin with the scores 0..n-1.
+func peersAt(base swarm.Address, bin uint8, n int) []prune.Peer {
+	peers := make([]prune.Peer, n)
+	for i := range peers {
+		peers[i] = prune.Peer{
+			Address: test.RandomAddressAt(base, int(bin)),
+			Bin:     bin,
+			Score:   float64(i),
+		}
+	}
+	return peers
+}
+
+func binCounts(base swarm.Address, addrs []swarm.Address) map[uint8]int {
+	counts := make(map[uint8]int)
+	for _, a := range addrs {
+		counts[swarm.Proximity(base.Bytes(), a.Bytes())]++
+	}
+	return counts
+}
+
+func TestVictimsSkewed(t *testing.T) {
+	base := test.RandomAddress()
+
+	var peers []prune.Peer
+	peers = append(peers, peersAt(base, 0, 10)...)
+	peers = append(peers, peersAt(base, 1, 5)...)
+	peers = append(peers, peersAt(base, 2, 1)...)
+	peers = append(peers, peersAt(base, 3, 4)...)
+
+	o := prune.Options{Target: 4, Min: 2}
+
+	// the most over-populated bin is pruned first, worst scores first
+	victims := prune.Victims(base, peers, 5, o)
+	if got := binCounts(base, victims); len(victims) != 5 || got[0] != 5 {
+		t.Fatalf("got victims in bins %v, want 5 in bin 0", got)
+	}
+	for i, v := range victims {
+		if !v.Equal(peers[i].Address) {
+			t.Fatalf("victim %d: got %s, want %s", i, v, peers[i].Address)
+		}
+	}
+
+	// equal surpluses prefer the worse scoring peer
+	victims = prune.Victims(base, peers, 6, o)
+	if got := binCounts(base, victims); got[0] != 5 || got[1] != 1 {
+		t.Fatalf("got victims in bins %v, want 5 in bin 0 and 1 in bin 1", got)
+	}
+	victims = prune.Victims(base, peers, 7, o)
+	if got := binCounts(base, victims); got[0] != 6 || got[1] != 1 {
+		t.Fatalf("got victims in bins %v, want 6 in bin 0 and 1 in bin 1", got)
+	}
+
+	// bins at or below the target are never pruned
+	victims = prune.Victims(base, peers, 100, o)
+	if got := binCounts(base, victims); len(victims) != 7 || got[2] != 0 || got[3] != 0 {
+		t.Fatalf("got victims in bins %v, want only surplus peers of bins 0 and 1", got)
+	}
+}
+
+func TestVictimsMin(t *testing.T) {
+	base := test.RandomAddress()
+	peers := append(peersAt(base, 0, 3), peersAt(base, 1, 6)...)
+
+	// the minimum overrides a lower target
+	victims := prune.Victims(base, peers, 100, prune.Options{Target: 1, Min: 3})
+	if got := binCounts(base, victims); got[0] != 0 || got[1] != 3 {
+		t.Fatalf("got victims in bins %v, want 3 in bin 1", got)
+	}
+}
+
+func TestVictimsProtected(t *testing.T) {
+	base := test.RandomAddress()
+	peers := peersAt(base, 0, 6)
+
+	// the worst scoring peers are protected
+	peers[0].Protected = true
+	peers[1].Protected = true
+
+	victims := prune.Victims(base, peers, 100, prune.Options{Target: 3})
+	if len(victims) != 3 {
+		t.Fatalf("got %d victims, want %d", len(victims), 3)
+	}
+	for i, v := range victims {
+		if want := peers[i+2].Address; !v.Equal(want) {
+			t.Fatalf("victim %d: got %s, want %s", i, v, want)
+		}
+	}
+
+	// protected peers count towards the population, so only
+	// the unprotected peers above the target are selected
+	for i := range peers[:3] {
+		peers[i].Protected = true
+	}
+	victims = prune.Victims(base, peers, 100, prune.Options{Target: 3})
+	if len(victims) != 3 {
+		t.Fatalf("got %d victims, want %d", len(victims), 3)
+	}
+	for _, v := range victims {
+		for _, p := range peers[:3] {
+			if v.Equal(p.Address) {
+				t.Fatalf("protected peer %s selected", v)
+			}
+		}
+	}
+
+	// all peers protected
+	for i := range peers {
+		peers[i].Protected = true
+	}
+	if victims := prune.Victims(base, peers, 100, prune.Options{Target: 3}); len(victims) != 0 {
+		t.Fatalf("got %d victims, want none", len(victims))
+	}
+}
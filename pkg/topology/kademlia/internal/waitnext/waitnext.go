@@ -58,6 +58,20 @@ func (r *WaitNext) Waiting(addr swarm.Address) bool {
 	return ok && time.Now().Before(info.tryAfter)
 }
 
+// NextDialAttempt returns the time after which the peer may be dialed
+// again. The zero time is returned if there is no wait for the peer.
+func (r *WaitNext) NextDialAttempt(addr swarm.Address) time.Time {
+
+	r.Lock()
+	defer r.Unlock()
+
+	if info, ok := r.next[addr.ByteString()]; ok {
+		return info.tryAfter
+	}
+
+	return time.Time{}
+}
+
 func (r *WaitNext) Attempts(addr swarm.Address) int {
 
 	r.Lock()
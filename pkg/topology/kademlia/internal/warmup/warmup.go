@@ -0,0 +1,107 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package warmup implements the gate which holds back a freshly started
+// node from advertising itself until its connections have stabilized.
+// It is intended to be used with the kademlia.
+package warmup
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/internal/clock"
+)
+
+const defaultShallowBins = 4
+
+// BinSizeFunc returns the number of connected peers in the bin.
+type BinSizeFunc func(bin uint8) int
+
+// Options for the Gate.
+type Options struct {
+	// Duration is the time after the start after which the gate opens.
+	// Zero disables the gate.
+	Duration time.Duration
+	// MinBinPeers is the number of connected peers that each of the
+	// shallow bins must have for the gate to open before the Duration
+	// passes. Zero disables the condition.
+	MinBinPeers int
+	// ShallowBins is the number of the shallowest bins
+	// checked for MinBinPeers.
+	ShallowBins uint8
+	// Clock is the source of time, the real clock if not set.
+	Clock clock.Clock
+}
+
+// Gate is closed for a configured duration after its creation or until
+// the shallow bins are populated, whichever comes first. Once open, it
+// stays open.
+type Gate struct {
+	clock       clock.Clock
+	start       time.Time
+	duration    time.Duration
+	minBinPeers int
+	shallowBins uint8
+	binSize     BinSizeFunc
+
+	mu   sync.Mutex
+	open bool
+}
+
+// New returns a new Gate which starts at the time of the call.
+func New(binSize BinSizeFunc, o Options) *Gate {
+	if o.ShallowBins == 0 {
+		o.ShallowBins = defaultShallowBins
+	}
+	if o.Clock == nil {
+		o.Clock = clock.Real
+	}
+	return &Gate{
+		clock:       o.Clock,
+		start:       o.Clock.Now(),
+		duration:    o.Duration,
+		minBinPeers: o.MinBinPeers,
+		shallowBins: o.ShallowBins,
+		binSize:     binSize,
+		open:        o.Duration <= 0,
+	}
+}
+
+// Open reports whether the warm-up is over.
+func (g *Gate) Open() bool {
+	open, _ := g.State()
+	return open
+}
+
+// State reports whether the warm-up is over and, if it is
+// not, the time after which it ends at the latest.
+func (g *Gate) State() (open bool, remaining time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.open {
+		return true, 0
+	}
+	remaining = g.duration - g.clock.Since(g.start)
+	if remaining <= 0 || g.populated() {
+		g.open = true
+		return true, 0
+	}
+	return false, remaining
+}
+
+// populated reports whether every shallow bin
+// has at least the minimal number of peers.
+func (g *Gate) populated() bool {
+	if g.minBinPeers <= 0 {
+		return false
+	}
+	for bin := uint8(0); bin < g.shallowBins; bin++ {
+		if g.binSize(bin) < g.minBinPeers {
+			return false
+		}
+	}
+	return true
+}
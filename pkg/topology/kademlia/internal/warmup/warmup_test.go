@@ -0,0 +1,87 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package warmup_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/internal/clock"
+	"github.com/ethersphere/bee/pkg/topology/kademlia/internal/warmup"
+)
+
+// bins is a stateful mock of the connected peer counts.
+type bins map[uint8]int
+
+func (b bins) size(bin uint8) int { return b[bin] }
+
+func TestGateDuration(t *testing.T) {
+	c := clock.NewMock(time.Unix(1000, 0))
+	g := warmup.New(bins{}.size, warmup.Options{
+		Duration:    time.Minute,
+		MinBinPeers: 2,
+		Clock:       c,
+	})
+
+	expectState(t, g, false, time.Minute)
+
+	c.Advance(40 * time.Second)
+	expectState(t, g, false, 20*time.Second)
+
+	c.Advance(20 * time.Second)
+	expectState(t, g, true, 0)
+}
+
+func TestGatePopulated(t *testing.T) {
+	c := clock.NewMock(time.Unix(1000, 0))
+	b := bins{}
+	g := warmup.New(b.size, warmup.Options{
+		Duration:    time.Hour,
+		MinBinPeers: 2,
+		ShallowBins: 2,
+		Clock:       c,
+	})
+
+	b[0] = 2
+	b[1] = 1
+	b[5] = 10
+	c.Advance(time.Minute)
+	expectState(t, g, false, time.Hour-time.Minute)
+
+	b[1] = 2
+	expectState(t, g, true, 0)
+
+	// the gate does not close once it is open
+	b[0] = 0
+	expectState(t, g, true, 0)
+}
+
+func TestGateDisabled(t *testing.T) {
+	g := warmup.New(bins{}.size, warmup.Options{})
+	expectState(t, g, true, 0)
+
+	// without the peers condition only the duration opens the gate
+	c := clock.NewMock(time.Unix(1000, 0))
+	b := bins{0: 100, 1: 100, 2: 100, 3: 100}
+	g = warmup.New(b.size, warmup.Options{Duration: time.Minute, Clock: c})
+	expectState(t, g, false, time.Minute)
+	c.Advance(time.Minute)
+	expectState(t, g, true, 0)
+}
+
+func expectState(t *testing.T, g *warmup.Gate, wantOpen bool, wantRemaining time.Duration) {
+	t.Helper()
+
+	open, remaining := g.State()
+	if open != wantOpen {
+		t.Fatalf("got open %v, want %v", open, wantOpen)
+	}
+	if remaining != wantRemaining {
+		t.Fatalf("got remaining %v, want %v", remaining, wantRemaining)
+	}
+	if g.Open() != wantOpen {
+		t.Fatalf("got Open %v, want %v", !wantOpen, wantOpen)
+	}
+}
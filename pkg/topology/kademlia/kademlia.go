@@ -17,13 +17,20 @@ import (
 
 	"github.com/ethersphere/bee/pkg/addressbook"
 	"github.com/ethersphere/bee/pkg/discovery"
+	"github.com/ethersphere/bee/pkg/internal/clock"
 	"github.com/ethersphere/bee/pkg/logging"
 	"github.com/ethersphere/bee/pkg/p2p"
+	"github.com/ethersphere/bee/pkg/reputation"
 	"github.com/ethersphere/bee/pkg/shed"
+	"github.com/ethersphere/bee/pkg/storage"
 	"github.com/ethersphere/bee/pkg/swarm"
 	"github.com/ethersphere/bee/pkg/topology"
+	"github.com/ethersphere/bee/pkg/topology/kademlia/internal/bootnodes"
+	"github.com/ethersphere/bee/pkg/topology/kademlia/internal/churn"
 	im "github.com/ethersphere/bee/pkg/topology/kademlia/internal/metrics"
+	"github.com/ethersphere/bee/pkg/topology/kademlia/internal/prune"
 	"github.com/ethersphere/bee/pkg/topology/kademlia/internal/waitnext"
+	"github.com/ethersphere/bee/pkg/topology/kademlia/internal/warmup"
 	"github.com/ethersphere/bee/pkg/topology/pslice"
 	ma "github.com/multiformats/go-multiaddr"
 )
@@ -32,11 +39,20 @@ const (
 	nnLowWatermark         = 2 // the number of peers in consecutive deepest bins that constitute as nearest neighbours
 	maxConnAttempts        = 1 // when there is maxConnAttempts failed connect calls for a given peer it is considered non-connectable
 	maxBootNodeAttempts    = 3 // how many attempts to dial to boot-nodes before giving up
+	maxBootNodeConnections = 3 // the number of connected boot-nodes at which no more are dialed
+	bootNodeConcurrency    = 3 // the number of boot-nodes that are dialed in parallel
 	defaultBitSuffixLength = 3 // the number of bits used to create pseudo addresses for balancing
 
 	addPeerBatchSize = 500
 
 	peerConnectionAttemptTimeout = 5 * time.Second // Timeout for establishing a new connection with peer.
+
+	maxFlapPenalty = 10 // the number of flaps after which the reconnect wait time stops growing
+
+	pruneMinBinPeers = 2 // the number of peers below which a bin is never pruned
+
+	binRefillLimit    = 16          // the number of addressbook peers added to an empty bin
+	binRefillInterval = time.Minute // the minimal time between the addressbook refills of a bin
 )
 
 var (
@@ -47,12 +63,25 @@ var (
 	shortRetry                  = 30 * time.Second
 	timeToRetry                 = 2 * shortRetry
 	broadcastBinSize            = 4
+	timeNow                     = time.Now
 )
 
+func init() {
+	storage.RegisterSweep(im.LastSeenKeyPrefix, func(ctx context.Context, store storage.StateStorer) (int, error) {
+		ab := addressbook.New(store)
+		return im.SweepOrphaned(ctx, store, func(addr swarm.Address) (bool, error) {
+			_, err := ab.Get(addr)
+			if errors.Is(err, addressbook.ErrNotFound) {
+				return false, nil
+			}
+			return err == nil, err
+		})
+	})
+}
+
 var (
 	errOverlayMismatch = errors.New("overlay mismatch")
 	errPruneEntry      = errors.New("prune entry")
-	errEmptyBin        = errors.New("empty bin")
 )
 
 type (
@@ -68,6 +97,29 @@ type Options struct {
 	Bootnodes       []ma.Multiaddr
 	BootnodeMode    bool
 	BitSuffixLength int
+	// DepthHysteresis is the time the calculated depth has to stay
+	// above the reported depth before the reported depth increases.
+	// Zero disables the smoothing.
+	DepthHysteresis time.Duration
+	// FlapWindow is the time after a disconnect during which
+	// a reconnect of the same peer is counted as a flap.
+	FlapWindow time.Duration
+	// Reputation, if set, gets the persisted reputation
+	// of peers loaded when they connect.
+	Reputation *reputation.Scorer
+	// LightNode nodes do not store chunks, so no
+	// address is considered to be within their depth.
+	LightNode bool
+	// StateStore, if set, persists the connection
+	// health of the bootnodes across restarts.
+	StateStore storage.StateStorer
+	// Warmup is the time after the start during which the node does
+	// not gossip via discovery, unless the shallow bins have WarmupBinPeers
+	// connected peers sooner. Zero disables the warm-up.
+	Warmup         time.Duration
+	WarmupBinPeers int
+	// Clock is the source of time of the warm-up, the real clock if not set.
+	Clock clock.Clock
 }
 
 // Kad is the Swarm forwarding kademlia implementation.
@@ -81,8 +133,11 @@ type Kad struct {
 	commonBinPrefixes [][]swarm.Address     // list of address prefixes for each bin
 	connectedPeers    *pslice.PSlice        // a slice of peers sorted and indexed by po, indexes kept in `bins`
 	knownPeers        *pslice.PSlice        // both are po aware slice of addresses
-	bootnodes         []ma.Multiaddr
+	bootnodes         *bootnodes.Tracker
 	depth             uint8         // current neighborhood depth
+	effectiveDepth    uint8         // neighborhood depth reported to consumers
+	depthSince        time.Time     // time of the last change of depth
+	depthHysteresis   time.Duration // delay of effectiveDepth increases
 	radius            uint8         // storage area of responsibility
 	depthMu           sync.RWMutex  // protect depth changes
 	manageC           chan struct{} // trigger the manage forever loop to connect to new peers
@@ -90,12 +145,17 @@ type Kad struct {
 	peerSigMtx        sync.Mutex
 	logger            logging.Logger // logger
 	bootnode          bool           // indicates whether the node is working in bootnode mode
+	lightNode         bool           // indicates whether the node is working in light mode
 	collector         *im.Collector
-	quit              chan struct{} // quit channel
-	halt              chan struct{} // halt channel
-	done              chan struct{} // signal that `manage` has quit
+	churn             *churn.Tracker     // connection lifetimes and flaps of peers
+	reputation        *reputation.Scorer // persisted reputation of peers
+	quit              chan struct{}      // quit channel
+	halt              chan struct{}      // halt channel
+	done              chan struct{}      // signal that `manage` has quit
 	wg                sync.WaitGroup
 	waitNext          *waitnext.WaitNext
+	warmup            *warmup.Gate
+	binRefills        [swarm.MaxBins]time.Time // last addressbook refills of bins, used only by the manage loop
 	metrics           metrics
 }
 
@@ -130,12 +190,16 @@ func New(
 		commonBinPrefixes: make([][]swarm.Address, int(swarm.MaxBins)),
 		connectedPeers:    pslice.New(int(swarm.MaxBins), base),
 		knownPeers:        pslice.New(int(swarm.MaxBins), base),
-		bootnodes:         o.Bootnodes,
+		bootnodes:         bootnodes.New(o.StateStore, o.Bootnodes),
+		depthHysteresis:   o.DepthHysteresis,
 		manageC:           make(chan struct{}, 1),
 		waitNext:          waitnext.New(),
 		logger:            logger,
 		bootnode:          o.BootnodeMode,
+		lightNode:         o.LightNode,
 		collector:         im.NewCollector(metricsDB),
+		churn:             churn.New(churn.Options{FlapWindow: o.FlapWindow}),
+		reputation:        o.Reputation,
 		quit:              make(chan struct{}),
 		halt:              make(chan struct{}),
 		done:              make(chan struct{}),
@@ -147,6 +211,18 @@ func New(
 		k.generateCommonBinPrefixes()
 	}
 
+	if o.BootnodeMode {
+		// bootnodes exist to gossip about other peers
+		o.Warmup = 0
+	}
+	k.warmup = warmup.New(func(bin uint8) int {
+		return len(k.connectedPeers.BinPeers(bin))
+	}, warmup.Options{
+		Duration:    o.Warmup,
+		MinBinPeers: o.WarmupBinPeers,
+		Clock:       o.Clock,
+	})
+
 	return k
 }
 
@@ -244,7 +320,7 @@ func (k *Kad) connectBalanced(wg *sync.WaitGroup, peerConnChan chan<- *peerConnI
 	}
 
 	for i := range k.commonBinPrefixes {
-		if i >= int(k.NeighborhoodDepth()) {
+		if i >= int(k.rawDepth()) {
 			continue
 		}
 		for j := range k.commonBinPrefixes[i] {
@@ -298,6 +374,69 @@ func (k *Kad) connectBalanced(wg *sync.WaitGroup, peerConnChan chan<- *peerConnI
 	}
 }
 
+// refillEmptyBins adds peers from the addressbook to the bins up to depth
+// that have neither connected peers nor known peers that can be dialed,
+// so that the bins do not stay empty until discovery repopulates them.
+// Blocklisted peers and peers that wait for their next dial attempt
+// are not added.
+func (k *Kad) refillEmptyBins() {
+	depth := k.rawDepth()
+	refillTime := timeNow()
+	now := time.Now() // the dial attempts are scheduled with the wall clock
+
+	var blocklisted map[string]struct{}
+	for bin := uint8(0); bin <= depth && bin < swarm.MaxBins; bin++ {
+		if len(k.connectedPeers.BinPeers(bin)) > 0 || refillTime.Sub(k.binRefills[bin]) < binRefillInterval {
+			continue
+		}
+		dialable := false
+		for _, addr := range k.knownPeers.BinPeers(bin) {
+			if !k.waitNext.NextDialAttempt(addr).After(now) {
+				dialable = true
+				break
+			}
+		}
+		if dialable {
+			continue
+		}
+		k.binRefills[bin] = refillTime
+
+		addrs, err := k.addressBook.BinPeers(k.base, bin, binRefillLimit)
+		if err != nil {
+			k.logger.Debugf("kademlia: addressbook peers for bin %d: %v", bin, err)
+			continue
+		}
+		if len(addrs) == 0 {
+			continue
+		}
+
+		if blocklisted == nil {
+			blocklisted = make(map[string]struct{})
+			peers, err := k.p2p.BlocklistedPeers()
+			if err != nil {
+				k.logger.Debugf("kademlia: blocklisted peers: %v", err)
+			}
+			for _, p := range peers {
+				blocklisted[p.Address.ByteString()] = struct{}{}
+			}
+		}
+
+		var added int
+		for _, addr := range addrs {
+			if _, ok := blocklisted[addr.Overlay.ByteString()]; ok {
+				continue
+			}
+			if k.waitNext.NextDialAttempt(addr.Overlay).After(now) {
+				continue
+			}
+			k.knownPeers.Add(addr.Overlay)
+			added++
+		}
+		k.metrics.TotalBinRefillPeers.Add(float64(added))
+		k.logger.Debugf("kademlia: added %d addressbook peers to empty bin %d", added, bin)
+	}
+}
+
 // connectNeighbours attempts to connect to the neighbours
 // which were not considered by the connectBalanced method.
 func (k *Kad) connectNeighbours(wg *sync.WaitGroup, peerConnChan, peerConnChan2 chan<- *peerConnInfo) {
@@ -305,7 +444,7 @@ func (k *Kad) connectNeighbours(wg *sync.WaitGroup, peerConnChan, peerConnChan2
 
 	sent := 0
 	_ = k.knownPeers.EachBinRev(func(addr swarm.Address, po uint8) (bool, bool, error) {
-		depth := k.NeighborhoodDepth()
+		depth := k.rawDepth()
 
 		if depth > po || po >= depth+multiplePeerThreshold {
 			return false, true, nil
@@ -346,7 +485,7 @@ func (k *Kad) connectNeighbours(wg *sync.WaitGroup, peerConnChan, peerConnChan2
 	})
 
 	_ = k.knownPeers.EachBinRev(func(addr swarm.Address, po uint8) (bool, bool, error) {
-		depth := k.NeighborhoodDepth()
+		depth := k.rawDepth()
 
 		if po < depth+multiplePeerThreshold {
 			return false, true, nil
@@ -422,9 +561,10 @@ func (k *Kad) connectionAttemptsHandler(ctx context.Context, wg *sync.WaitGroup,
 
 		k.metrics.TotalOutboundConnections.Inc()
 		k.collector.Record(peer.addr, im.PeerLogIn(time.Now(), im.PeerConnectionDirectionOutbound))
+		k.recordConnected(peer.addr)
 
 		k.depthMu.Lock()
-		k.depth = recalcDepth(k.connectedPeers, k.radius)
+		k.setDepth(recalcDepth(k.connectedPeers, k.radius))
 		k.depthMu.Unlock()
 
 		k.logger.Debugf("kademlia: connected to peer: %q in bin: %d", peer.addr, peer.po)
@@ -526,16 +666,24 @@ func (k *Kad) manage() {
 			default:
 			}
 
-			oldDepth := k.NeighborhoodDepth()
+			oldDepth := k.rawDepth()
+			k.refillEmptyBins()
 			k.connectNeighbours(&wg, peerConnChan, peerConnChan2)
 			k.connectBalanced(&wg, peerConnChan2)
 			wg.Wait()
 
 			k.depthMu.Lock()
 			depth := k.depth
+			oldEffectiveDepth := k.effectiveDepth
+			k.setDepth(depth) // settle a pending effective depth increase
+			effectiveDepth := k.effectiveDepth
 			radius := k.radius
 			k.depthMu.Unlock()
 
+			if effectiveDepth != oldEffectiveDepth {
+				k.notifyPeerSig()
+			}
+
 			k.logger.Tracef(
 				"kademlia: connector took %s to finish: old depth %d; new depth %d",
 				time.Since(start),
@@ -544,9 +692,11 @@ func (k *Kad) manage() {
 			)
 
 			k.metrics.CurrentDepth.Set(float64(depth))
+			k.metrics.CurrentEffectiveDepth.Set(float64(effectiveDepth))
 			k.metrics.CurrentRadius.Set(float64(radius))
 			k.metrics.CurrentlyKnownPeers.Set(float64(k.knownPeers.Length()))
 			k.metrics.CurrentlyConnectedPeers.Set(float64(k.connectedPeers.Length()))
+			k.updateNeighborhoodMetrics()
 
 			if k.connectedPeers.Length() == 0 {
 				select {
@@ -601,29 +751,80 @@ func (k *Kad) Start(_ context.Context) error {
 	return nil
 }
 
-func (k *Kad) connectBootNodes(ctx context.Context) {
-	var attempts, connected int
-	totalAttempts := maxBootNodeAttempts * len(k.bootnodes)
+// bootnodeInfos returns the connection health of the bootnodes.
+func (k *Kad) bootnodeInfos() []topology.BootnodeInfo {
+	var infos []topology.BootnodeInfo
+	for _, s := range k.bootnodes.Status() {
+		info := topology.BootnodeInfo{
+			Address:             s.Address.String(),
+			ConsecutiveFailures: s.ConsecutiveFailures,
+		}
+		if !s.LastConnected.IsZero() {
+			info.LastConnected = s.LastConnected.Unix()
+		}
+		if !s.LastFailed.IsZero() {
+			info.LastFailed = s.LastFailed.Unix()
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
 
+// connectBootNodes dials the bootnodes in the order of their connection
+// health until maxBootNodeConnections of them are connected. The bootnodes
+// are dialed in parallel, at most bootNodeConcurrency at a time.
+func (k *Kad) connectBootNodes(ctx context.Context) {
 	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
-	for _, addr := range k.bootnodes {
-		if attempts >= totalAttempts || connected >= 3 {
-			return
+	var (
+		mu        sync.Mutex
+		connected int // the number of connected bootnodes
+		pending   int // the number of connection attempts in progress
+	)
+
+	// reserve returns false if there are enough connected bootnodes, or
+	// connection attempts in progress that would make them enough.
+	reserve := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if connected+pending >= maxBootNodeConnections {
+			return false
+		}
+		pending++
+		return true
+	}
+	release := func(ok bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		pending--
+		if ok {
+			connected++
 		}
+	}
+	needed := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return maxBootNodeConnections - connected
+	}
 
-		if _, err := p2p.Discover(ctx, addr, func(addr ma.Multiaddr) (stop bool, err error) {
-			k.logger.Tracef("connecting to bootnode %s", addr)
-			if attempts >= maxBootNodeAttempts {
+	connect := func(bootnode ma.Multiaddr) {
+		var (
+			attempts int
+			ok       bool
+		)
+		_, err := p2p.Discover(ctx, bootnode, func(addr ma.Multiaddr) (stop bool, err error) {
+			if attempts >= maxBootNodeAttempts || !reserve() {
 				return true, nil
 			}
-			bzzAddress, err := k.p2p.Connect(ctx, addr)
-
 			attempts++
+
+			k.logger.Tracef("connecting to bootnode %s", addr)
 			k.metrics.TotalBootNodesConnectionAttempts.Inc()
 
+			bzzAddress, err := k.p2p.Connect(ctx, addr)
 			if err != nil {
+				release(false)
 				if !errors.Is(err, p2p.ErrAlreadyConnected) {
 					k.logger.Debugf("connect fail %s: %v", addr, err)
 					k.logger.Warningf("connect to bootnode %s", addr)
@@ -634,17 +835,52 @@ func (k *Kad) connectBootNodes(ctx context.Context) {
 			}
 
 			if err := k.connected(ctx, bzzAddress.Overlay); err != nil {
+				release(false)
 				return false, err
 			}
+			release(true)
+			ok = true
 			k.logger.Tracef("connected to bootnode %s", addr)
-			connected++
-			// connect to max 3 bootnodes
-			return connected >= 3, nil
-		}); err != nil && !errors.Is(err, context.Canceled) {
-			k.logger.Debugf("discover fail %s: %v", addr, err)
-			k.logger.Warningf("discover to bootnode %s", addr)
+			return false, nil
+		})
+
+		switch {
+		case ok:
+			err = k.bootnodes.Connected(bootnode)
+		case err != nil && !errors.Is(err, context.Canceled):
+			k.logger.Debugf("discover fail %s: %v", bootnode, err)
+			k.logger.Warningf("discover to bootnode %s", bootnode)
+			err = k.bootnodes.Failed(bootnode)
+		default:
+			return
+		}
+		if err != nil {
+			k.logger.Debugf("bootnode health %s: %v", bootnode, err)
+		}
+	}
+
+	for order := k.bootnodes.Order(); len(order) > 0; {
+		n := needed()
+		if n <= 0 || ctx.Err() != nil {
 			return
 		}
+		if n > bootNodeConcurrency {
+			n = bootNodeConcurrency
+		}
+		if n > len(order) {
+			n = len(order)
+		}
+
+		var wg sync.WaitGroup
+		for _, bootnode := range order[:n] {
+			wg.Add(1)
+			go func(bootnode ma.Multiaddr) {
+				defer wg.Done()
+				connect(bootnode)
+			}(bootnode)
+		}
+		wg.Wait()
+		order = order[n:]
 	}
 }
 
@@ -740,7 +976,8 @@ func recalcDepth(peers *pslice.PSlice, radius uint8) uint8 {
 // connect connects to a peer and gossips its address to our connected peers,
 // as well as sends the peers we are connected to to the newly connected peer
 func (k *Kad) connect(ctx context.Context, peer swarm.Address, ma ma.Multiaddr) error {
-	k.logger.Infof("attempting to connect to peer %q", peer)
+	peerLogger := logging.WithPeer(k.logger, peer)
+	peerLogger.Info("attempting to connect to peer")
 
 	ctx, cancel := context.WithTimeout(ctx, peerConnectionAttemptTimeout)
 	defer cancel()
@@ -758,13 +995,14 @@ func (k *Kad) connect(ctx context.Context, peer swarm.Address, ma ma.Multiaddr)
 	case errors.Is(err, context.Canceled):
 		return err
 	case err != nil:
-		k.logger.Debugf("could not connect to peer %q: %v", peer, err)
+		peerLogger.Debugf("could not connect to peer: %v", err)
 
 		retryTime := time.Now().Add(timeToRetry)
 		var e *p2p.ConnectionBackoffError
 		failedAttempts := 0
 		if errors.As(err, &e) {
 			retryTime = e.TryAfter()
+			peerLogger.Debugf("connection breaker closed, retrying after %s", retryTime)
 		} else {
 			failedAttempts = k.waitNext.Attempts(peer)
 			failedAttempts++
@@ -779,9 +1017,9 @@ func (k *Kad) connect(ctx context.Context, peer swarm.Address, ma ma.Multiaddr)
 				k.waitNext.Remove(peer)
 				k.knownPeers.Remove(peer)
 				if err := k.addressBook.Remove(peer); err != nil {
-					k.logger.Debugf("could not remove peer from addressbook: %q", peer)
+					peerLogger.Debug("could not remove peer from addressbook")
 				}
-				k.logger.Debugf("kademlia pruned peer from address book %q", peer)
+				peerLogger.Debug("kademlia pruned peer from address book")
 			} else {
 				k.waitNext.Set(peer, retryTime, failedAttempts)
 			}
@@ -798,8 +1036,14 @@ func (k *Kad) connect(ctx context.Context, peer swarm.Address, ma ma.Multiaddr)
 }
 
 // Announce a newly connected peer to our connected peers, but also
-// notify the peer about our already connected peers
+// notify the peer about our already connected peers. Nothing is
+// announced while the node is warming up.
 func (k *Kad) Announce(ctx context.Context, peer swarm.Address, fullnode bool) error {
+	if !k.warmup.Open() {
+		k.metrics.TotalWarmupSuppressedAnnounces.Inc()
+		return nil
+	}
+
 	var addrs []swarm.Address
 
 	for bin := uint8(0); bin < swarm.MaxBins; bin++ {
@@ -836,7 +1080,11 @@ func (k *Kad) Announce(ctx context.Context, peer swarm.Address, fullnode bool) e
 	err := k.discovery.BroadcastPeers(ctx, peer, addrs...)
 	if err != nil {
 		k.logger.Errorf("kademlia: could not broadcast to peer %s", peer)
-		_ = k.p2p.Disconnect(peer)
+		reason := p2p.DisconnectReasonLocal
+		if errors.Is(err, context.DeadlineExceeded) {
+			reason = p2p.DisconnectReasonTimeout
+		}
+		_ = k.p2p.DisconnectWithReason(peer, reason)
 	}
 
 	return err
@@ -875,11 +1123,9 @@ func (k *Kad) Connected(ctx context.Context, peer p2p.Peer, forceConnection bool
 
 	if _, overSaturated := k.saturationFunc(po, k.knownPeers, k.connectedPeers); overSaturated {
 		if k.bootnode {
-			randPeer, err := k.randomPeer(po)
-			if err != nil {
-				return err
+			for _, victim := range k.pruneVictims(1, bootNodeOverSaturationPeers-1) {
+				_ = k.p2p.DisconnectWithReason(victim, p2p.DisconnectReasonPruned)
 			}
-			_ = k.p2p.Disconnect(randPeer)
 			return k.connected(ctx, address)
 		}
 		if !forceConnection {
@@ -900,11 +1146,12 @@ func (k *Kad) connected(ctx context.Context, addr swarm.Address) error {
 
 	k.metrics.TotalInboundConnections.Inc()
 	k.collector.Record(addr, im.PeerLogIn(time.Now(), im.PeerConnectionDirectionInbound))
+	k.recordConnected(addr)
 
 	k.waitNext.Remove(addr)
 
 	k.depthMu.Lock()
-	k.depth = recalcDepth(k.connectedPeers, k.radius)
+	k.setDepth(recalcDepth(k.connectedPeers, k.radius))
 	k.depthMu.Unlock()
 
 	k.notifyManageLoop()
@@ -913,24 +1160,56 @@ func (k *Kad) connected(ctx context.Context, addr swarm.Address) error {
 }
 
 // Disconnected is called when peer disconnects.
-func (k *Kad) Disconnected(peer p2p.Peer) {
-	k.logger.Debugf("kademlia: disconnected peer %s", peer.Address)
+func (k *Kad) Disconnected(peer p2p.Peer, reason p2p.DisconnectReason) {
+	logging.WithPeer(k.logger, peer.Address).Debugf("kademlia: disconnected peer: %s", reason)
 
 	k.connectedPeers.Remove(peer.Address)
 
-	k.waitNext.SetTryAfter(peer.Address, time.Now().Add(timeToRetry))
+	k.waitNext.SetTryAfter(peer.Address, time.Now().Add(k.retryAfterDisconnect(peer.Address, reason)))
 
 	k.metrics.TotalInboundDisconnections.Inc()
 	k.collector.Record(peer.Address, im.PeerLogOut(time.Now()))
+	if d, ok := k.churn.Disconnected(peer.Address, timeNow()); ok {
+		k.metrics.ConnectionDuration.Observe(d.Seconds())
+	}
 
 	k.depthMu.Lock()
-	k.depth = recalcDepth(k.connectedPeers, k.radius)
+	k.setDepth(recalcDepth(k.connectedPeers, k.radius))
 	k.depthMu.Unlock()
 
 	k.notifyManageLoop()
 	k.notifyPeerSig()
 }
 
+// recordConnected records the establishment of
+// the connection for the churn statistics.
+func (k *Kad) recordConnected(addr swarm.Address) {
+	if k.churn.Connected(addr, timeNow()) {
+		k.metrics.TotalFlaps.Inc()
+	}
+	if k.reputation != nil {
+		if err := k.reputation.Load(addr); err != nil {
+			k.logger.Debugf("kademlia: load reputation of peer %s: %v", addr, err)
+		}
+	}
+}
+
+// retryAfterDisconnect returns the time to wait before connecting to the
+// disconnected peer again. Peers that flap are deprioritized by a wait time
+// that grows with the number of their flaps. Peers that were blocklisted or
+// rejected by a protocol wait for the longest time.
+func (k *Kad) retryAfterDisconnect(addr swarm.Address, reason p2p.DisconnectReason) time.Duration {
+	if reason == p2p.DisconnectReasonBlocklisted || reason == p2p.DisconnectReasonRejected {
+		return timeToRetry * (1 + maxFlapPenalty)
+	}
+	stats, _ := k.churn.Stats(addr)
+	flaps := stats.Flaps
+	if flaps > maxFlapPenalty {
+		flaps = maxFlapPenalty
+	}
+	return timeToRetry * time.Duration(1+flaps)
+}
+
 func (k *Kad) notifyPeerSig() {
 	k.peerSigMtx.Lock()
 	defer k.peerSigMtx.Unlock()
@@ -1051,7 +1330,7 @@ func (k *Kad) ClosestPeer(addr swarm.Address, includeSelf bool, skipPeers ...swa
 	}
 
 	for _, v := range peersToDisconnect {
-		k.Disconnected(p2p.Peer{Address: v})
+		k.Disconnected(p2p.Peer{Address: v}, p2p.DisconnectReasonUnknown)
 	}
 
 	// check if self
@@ -1062,14 +1341,39 @@ func (k *Kad) ClosestPeer(addr swarm.Address, includeSelf bool, skipPeers ...swa
 	return closest, nil
 }
 
+// Unreachable reports whether connecting to the peer is currently
+// backed off after failed connection attempts.
+func (k *Kad) Unreachable(addr swarm.Address) bool {
+	return k.waitNext.Attempts(addr) > 0 && k.waitNext.Waiting(addr)
+}
+
+// LastSeen returns the time of the last successful connection to the peer,
+// the current time if the peer is connected or the zero time if the peer
+// has never been connected.
+func (k *Kad) LastSeen(addr swarm.Address) time.Time {
+	if k.connectedPeers.Exists(addr) {
+		return timeNow()
+	}
+	ss, ok := k.collector.Snapshot(time.Now(), addr)[addr.ByteString()]
+	if !ok || ss.LastSeenTimestamp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ss.LastSeenTimestamp)
+}
+
 // IsWithinDepth returns if an address is within the neighborhood depth of a node.
+// Light nodes do not store chunks and have no address within their depth.
 func (k *Kad) IsWithinDepth(addr swarm.Address) bool {
-	return swarm.Proximity(k.base.Bytes(), addr.Bytes()) >= k.NeighborhoodDepth()
+	if k.lightNode {
+		return false
+	}
+	_, depth := k.NeighborhoodDepth()
+	return swarm.Proximity(k.base.Bytes(), addr.Bytes()) >= depth
 }
 
 // EachNeighbor iterates from closest bin to farthest of the neighborhood peers.
 func (k *Kad) EachNeighbor(f topology.EachPeerFunc) error {
-	depth := k.NeighborhoodDepth()
+	_, depth := k.NeighborhoodDepth()
 	fn := func(a swarm.Address, po uint8) (bool, bool, error) {
 		if po < depth {
 			return true, false, nil
@@ -1081,7 +1385,7 @@ func (k *Kad) EachNeighbor(f topology.EachPeerFunc) error {
 
 // EachNeighborRev iterates from farthest bin to closest of the neighborhood peers.
 func (k *Kad) EachNeighborRev(f topology.EachPeerFunc) error {
-	depth := k.NeighborhoodDepth()
+	_, depth := k.NeighborhoodDepth()
 	fn := func(a swarm.Address, po uint8) (bool, bool, error) {
 		if po < depth {
 			return false, true, nil
@@ -1129,14 +1433,55 @@ func (k *Kad) SubscribePeersChange() (c <-chan struct{}, unsubscribe func()) {
 	return channel, unsubscribe
 }
 
-// NeighborhoodDepth returns the current Kademlia depth.
-func (k *Kad) NeighborhoodDepth() uint8 {
+// NeighborhoodDepth returns the current Kademlia depth as calculated from
+// the connected peers (raw) and the depth reported to consumers (effective).
+// The effective depth follows a decrease of the raw depth immediately, but
+// an increase only once the raw depth has been stable for DepthHysteresis.
+func (k *Kad) NeighborhoodDepth() (raw, effective uint8) {
+	k.depthMu.RLock()
+	defer k.depthMu.RUnlock()
+
+	return k.depth, k.effective(timeNow())
+}
+
+// Warmup reports whether the node is still warming up after the start
+// and the time after which the warm-up ends at the latest.
+func (k *Kad) Warmup() (active bool, remaining time.Duration) {
+	open, remaining := k.warmup.State()
+	return !open, remaining
+}
+
+// rawDepth returns the depth calculated from the connected peers.
+func (k *Kad) rawDepth() uint8 {
 	k.depthMu.RLock()
 	defer k.depthMu.RUnlock()
 
 	return k.depth
 }
 
+// setDepth sets the raw depth and updates the effective depth according
+// to the hysteresis rules. Must be called with depthMu locked.
+func (k *Kad) setDepth(depth uint8) {
+	now := timeNow()
+	k.effectiveDepth = k.effective(now)
+	if depth != k.depth {
+		k.depth = depth
+		k.depthSince = now
+	}
+	if depth < k.effectiveDepth || k.depthHysteresis == 0 {
+		k.effectiveDepth = depth
+	}
+}
+
+// effective returns the effective depth at the given time.
+// Must be called with depthMu at least read locked.
+func (k *Kad) effective(now time.Time) uint8 {
+	if k.depth > k.effectiveDepth && now.Sub(k.depthSince) >= k.depthHysteresis {
+		return k.depth
+	}
+	return k.effectiveDepth
+}
+
 // IsBalanced returns if Kademlia is balanced to bin.
 func (k *Kad) IsBalanced(bin uint8) bool {
 	k.depthMu.RLock()
@@ -1171,12 +1516,45 @@ func (k *Kad) SetRadius(r uint8) {
 	}
 	k.radius = r
 	oldD := k.depth
-	k.depth = recalcDepth(k.connectedPeers, k.radius)
+	k.setDepth(recalcDepth(k.connectedPeers, k.radius))
 	if k.depth != oldD {
 		k.notifyManageLoop()
 	}
 }
 
+// NeighborhoodStats returns the statistics of the neighborhood
+// at the current effective depth.
+func (k *Kad) NeighborhoodStats() topology.NeighborhoodStats {
+	_, depth := k.NeighborhoodDepth()
+
+	stats := topology.NeighborhoodStats{
+		Depth:              depth,
+		ConnectedHistogram: make([]int, swarm.MaxBins),
+	}
+
+	_ = k.connectedPeers.EachBin(func(_ swarm.Address, po uint8) (bool, bool, error) {
+		stats.ConnectedHistogram[po]++
+		if po >= depth {
+			stats.ConnectedWithinDepth++
+		}
+		return false, false, nil
+	})
+
+	// known peers are iterated from the deepest bin, so
+	// the iteration stops at the first bin outside depth
+	_ = k.knownPeers.EachBin(func(addr swarm.Address, po uint8) (bool, bool, error) {
+		if po < depth {
+			return true, false, nil
+		}
+		if !k.connectedPeers.Exists(addr) {
+			stats.DisconnectedWithinDepth++
+		}
+		return false, false, nil
+	})
+
+	return stats
+}
+
 func (k *Kad) Snapshot() *topology.KadParams {
 	var infos []topology.BinInfo
 	for i := int(swarm.MaxPO); i >= 0; i-- {
@@ -1191,7 +1569,7 @@ func (k *Kad) Snapshot() *topology.KadParams {
 			infos[po].ConnectedPeers,
 			&topology.PeerInfo{
 				Address: addr,
-				Metrics: createMetricsSnapshotView(ss[addr.ByteString()]),
+				Metrics: k.createMetricsSnapshotView(addr, ss[addr.ByteString()]),
 			},
 		)
 		return false, false, nil
@@ -1212,19 +1590,24 @@ func (k *Kad) Snapshot() *topology.KadParams {
 			infos[po].DisconnectedPeers,
 			&topology.PeerInfo{
 				Address: addr,
-				Metrics: createMetricsSnapshotView(ss[addr.ByteString()]),
+				Metrics: k.createMetricsSnapshotView(addr, ss[addr.ByteString()]),
 			},
 		)
 		return false, false, nil
 	})
 
+	rawDepth, effectiveDepth := k.NeighborhoodDepth()
+
 	return &topology.KadParams{
 		Base:           k.base.String(),
 		Population:     k.knownPeers.Length(),
 		Connected:      k.connectedPeers.Length(),
 		Timestamp:      time.Now(),
 		NNLowWatermark: nnLowWatermark,
-		Depth:          k.NeighborhoodDepth(),
+		Depth:          effectiveDepth,
+		RawDepth:       rawDepth,
+		Bootnodes:      k.bootnodeInfos(),
+		Neighborhood:   k.NeighborhoodStats(),
 		Bins: topology.KadBins{
 			Bin0:  infos[0],
 			Bin1:  infos[1],
@@ -1328,33 +1711,42 @@ func randomSubset(addrs []swarm.Address, count int) ([]swarm.Address, error) {
 	return addrs[:count], nil
 }
 
-func (k *Kad) randomPeer(bin uint8) (swarm.Address, error) {
-	peers := k.connectedPeers.BinPeers(bin)
-
-	if len(peers) == 0 {
-		return swarm.ZeroAddress, errEmptyBin
-	}
+// pruneVictims returns up to n connected peers to disconnect from, selected
+// from the bins with more than target peers. Peers in the neighborhood are
+// never selected and the peers with the worst reputation are selected first.
+func (k *Kad) pruneVictims(n, target int) []swarm.Address {
+	depth, _ := k.NeighborhoodDepth()
 
-	rndIndx, err := random.Int(random.Reader, big.NewInt(int64(len(peers))))
-	if err != nil {
-		return swarm.ZeroAddress, err
-	}
+	var peers []prune.Peer
+	_ = k.connectedPeers.EachBin(func(addr swarm.Address, po uint8) (bool, bool, error) {
+		p := prune.Peer{Address: addr, Bin: po, Protected: po >= depth}
+		if k.reputation != nil {
+			if r, err := k.reputation.Get(addr); err == nil {
+				p.Score = r.Score
+			}
+		}
+		peers = append(peers, p)
+		return false, false, nil
+	})
 
-	return peers[rndIndx.Int64()], nil
+	return prune.Victims(k.base, peers, n, prune.Options{Target: target, Min: pruneMinBinPeers})
 }
 
 // createMetricsSnapshotView creates new topology.MetricSnapshotView from the
 // given metrics.Snapshot and rounds all the timestamps and durations to its
 // nearest second.
-func createMetricsSnapshotView(ss *im.Snapshot) *topology.MetricSnapshotView {
+func (k *Kad) createMetricsSnapshotView(addr swarm.Address, ss *im.Snapshot) *topology.MetricSnapshotView {
 	if ss == nil {
 		return nil
 	}
+	cs, _ := k.churn.Stats(addr)
 	return &topology.MetricSnapshotView{
 		LastSeenTimestamp:          time.Unix(0, ss.LastSeenTimestamp).Unix(),
 		SessionConnectionRetry:     ss.SessionConnectionRetry,
 		ConnectionTotalDuration:    ss.ConnectionTotalDuration.Truncate(time.Second).Seconds(),
 		SessionConnectionDuration:  ss.SessionConnectionDuration.Truncate(time.Second).Seconds(),
 		SessionConnectionDirection: string(ss.SessionConnectionDirection),
+		LastConnectionDuration:     cs.LastConnectionDuration.Truncate(time.Second).Seconds(),
+		FlapCount:                  cs.Flaps,
 	}
 }
@@ -11,6 +11,7 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"reflect"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -22,6 +23,7 @@ import (
 	"github.com/ethersphere/bee/pkg/bzz"
 	beeCrypto "github.com/ethersphere/bee/pkg/crypto"
 	"github.com/ethersphere/bee/pkg/discovery/mock"
+	"github.com/ethersphere/bee/pkg/internal/clock"
 	"github.com/ethersphere/bee/pkg/logging"
 	"github.com/ethersphere/bee/pkg/p2p"
 	p2pmock "github.com/ethersphere/bee/pkg/p2p/mock"
@@ -199,6 +201,84 @@ func TestNeighborhoodDepth(t *testing.T) {
 
 }
 
+func TestNeighborhoodDepthHysteresis(t *testing.T) {
+	const hysteresis = time.Minute
+
+	now := time.Unix(1000, 0)
+	kademlia.SetTimeNow(func() time.Time { return now })
+	defer kademlia.SetTimeNow(time.Now)
+
+	var (
+		base, kad, ab, _, signer = newTestKademlia(t, nil, nil, kademlia.Options{DepthHysteresis: hysteresis})
+	)
+
+	kad.SetRadius(swarm.MaxPO)
+
+	// one peer in each of the bins 0 to 7
+	for i := 0; i < 8; i++ {
+		connectOne(t, signer, kad, ab, test.RandomAddressAt(base, i), nil)
+	}
+	depths(t, kad, 0, 0)
+
+	// saturate bin 0, the depth candidate increases
+	var bin0 []swarm.Address
+	for i := 0; i < 3; i++ {
+		addr := test.RandomAddressAt(base, 0)
+		connectOne(t, signer, kad, ab, addr, nil)
+		bin0 = append(bin0, addr)
+	}
+	depths(t, kad, 1, 0)
+
+	now = now.Add(hysteresis / 2)
+	depths(t, kad, 1, 0)
+
+	// the candidate changes before it is stable, the interval restarts
+	var bin1 []swarm.Address
+	for i := 0; i < 3; i++ {
+		addr := test.RandomAddressAt(base, 1)
+		connectOne(t, signer, kad, ab, addr, nil)
+		bin1 = append(bin1, addr)
+	}
+	depths(t, kad, 2, 0)
+
+	now = now.Add(hysteresis / 2)
+	depths(t, kad, 2, 0)
+
+	now = now.Add(hysteresis / 2)
+	depths(t, kad, 2, 2)
+
+	snapshot := kad.Snapshot()
+	if snapshot.Depth != 2 || snapshot.RawDepth != 2 {
+		t.Fatalf("got snapshot depth %d and raw depth %d, want 2 and 2", snapshot.Depth, snapshot.RawDepth)
+	}
+
+	// connectivity drops below saturation, the depth decreases immediately
+	removeOne(kad, bin1[0])
+	depths(t, kad, 1, 1)
+
+	removeOne(kad, bin0[0])
+	depths(t, kad, 0, 0)
+
+	// peers flapping at the boundary do not change the effective depth
+	for i := 0; i < 3; i++ {
+		connectOne(t, signer, kad, ab, bin0[0], nil)
+		depths(t, kad, 1, 0)
+
+		now = now.Add(hysteresis / 2)
+		removeOne(kad, bin0[0])
+		depths(t, kad, 0, 0)
+	}
+
+	connectOne(t, signer, kad, ab, bin0[0], nil)
+	snapshot = kad.Snapshot()
+	if snapshot.Depth != 0 || snapshot.RawDepth != 1 {
+		t.Fatalf("got snapshot depth %d and raw depth %d, want 0 and 1", snapshot.Depth, snapshot.RawDepth)
+	}
+
+	now = now.Add(hysteresis)
+	depths(t, kad, 1, 1)
+}
+
 func TestEachNeighbor(t *testing.T) {
 	var (
 		conns                    int32 // how many connect calls were made to the p2p mock
@@ -232,8 +312,8 @@ func TestEachNeighbor(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if depth < kad.NeighborhoodDepth() {
-		t.Fatalf("incorrect depth argument pass to iterator function: expected >= %d (neighbourhood depth), got %d", kad.NeighborhoodDepth(), depth)
+	if _, d := kad.NeighborhoodDepth(); depth < d {
+		t.Fatalf("incorrect depth argument pass to iterator function: expected >= %d (neighbourhood depth), got %d", d, depth)
 	}
 
 	depth = 15
@@ -248,8 +328,8 @@ func TestEachNeighbor(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if depth < kad.NeighborhoodDepth() {
-		t.Fatalf("incorrect depth argument pass to iterator function: expected >= %d (neighbourhood depth), got %d", kad.NeighborhoodDepth(), depth)
+	if _, d := kad.NeighborhoodDepth(); depth < d {
+		t.Fatalf("incorrect depth argument pass to iterator function: expected >= %d (neighbourhood depth), got %d", d, depth)
 	}
 }
 
@@ -629,7 +709,7 @@ func TestNotifierHooks(t *testing.T) {
 	}
 
 	// disconnect the peer, expect error
-	kad.Disconnected(p2p.Peer{Address: peer})
+	kad.Disconnected(p2p.Peer{Address: peer}, p2p.DisconnectReasonRemote)
 	_, err = kad.ClosestPeer(addr, true)
 	if !errors.Is(err, topology.ErrNotFound) {
 		t.Fatalf("expected topology.ErrNotFound but got %v", err)
@@ -1086,6 +1166,367 @@ func TestSnapshot(t *testing.T) {
 	}
 }
 
+func TestNeighborhoodStats(t *testing.T) {
+	base, kad, ab, _, signer := newTestKademlia(t, nil, nil, kademlia.Options{})
+
+	kad.SetRadius(swarm.MaxPO)
+
+	want := topology.NeighborhoodStats{ConnectedHistogram: make([]int, swarm.MaxBins)}
+	if got := kad.NeighborhoodStats(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got stats %+v, want %+v", got, want)
+	}
+
+	// one peer in each of the bins 0 to 7 and
+	// three more in bin 0 shift the depth to 1
+	for i := 0; i < 8; i++ {
+		connectOne(t, signer, kad, ab, test.RandomAddressAt(base, i), nil)
+	}
+	for i := 0; i < 3; i++ {
+		connectOne(t, signer, kad, ab, test.RandomAddressAt(base, 0), nil)
+	}
+
+	// known peers that are not connected, only
+	// the ones within depth are counted
+	addOne(t, signer, kad, ab, test.RandomAddressAt(base, 0))
+	addOne(t, signer, kad, ab, test.RandomAddressAt(base, 5))
+	addOne(t, signer, kad, ab, test.RandomAddressAt(base, 9))
+
+	want = topology.NeighborhoodStats{
+		Depth:                   1,
+		ConnectedWithinDepth:    7,
+		DisconnectedWithinDepth: 2,
+		ConnectedHistogram:      make([]int, swarm.MaxBins),
+	}
+	want.ConnectedHistogram[0] = 4
+	for i := 1; i < 8; i++ {
+		want.ConnectedHistogram[i] = 1
+	}
+
+	got := kad.NeighborhoodStats()
+	if got.Depth != want.Depth {
+		t.Errorf("got depth %d, want %d", got.Depth, want.Depth)
+	}
+	if got.ConnectedWithinDepth != want.ConnectedWithinDepth {
+		t.Errorf("got %d connected peers within depth, want %d", got.ConnectedWithinDepth, want.ConnectedWithinDepth)
+	}
+	if got.DisconnectedWithinDepth != want.DisconnectedWithinDepth {
+		t.Errorf("got %d disconnected peers within depth, want %d", got.DisconnectedWithinDepth, want.DisconnectedWithinDepth)
+	}
+	if !reflect.DeepEqual(got.ConnectedHistogram, want.ConnectedHistogram) {
+		t.Errorf("got histogram %v, want %v", got.ConnectedHistogram, want.ConnectedHistogram)
+	}
+
+	if snapshot := kad.Snapshot(); !reflect.DeepEqual(snapshot.Neighborhood, want) {
+		t.Errorf("got snapshot stats %+v, want %+v", snapshot.Neighborhood, want)
+	}
+
+	// a disconnected peer within depth becomes a known peer
+	var peer swarm.Address
+	_ = kad.EachPeer(func(addr swarm.Address, po uint8) (bool, bool, error) {
+		if po == 7 {
+			peer = addr
+			return true, false, nil
+		}
+		return false, false, nil
+	})
+	removeOne(kad, peer)
+
+	got = kad.NeighborhoodStats()
+	if got.ConnectedWithinDepth != 6 || got.DisconnectedWithinDepth != 3 || got.ConnectedHistogram[7] != 0 {
+		t.Fatalf("got stats %+v after disconnect, want 6 connected and 3 disconnected peers within depth and empty bin 7", got)
+	}
+}
+
+func TestConnectSuggestions(t *testing.T) {
+	base, kad, ab, _, signer := newTestKademlia(t, nil, nil, kademlia.Options{})
+
+	kad.SetRadius(swarm.MaxPO)
+
+	// one peer in each of the bins 0 to 7 and
+	// three more in bin 0 shift the depth to 1
+	for i := 0; i < 8; i++ {
+		connectOne(t, signer, kad, ab, test.RandomAddressAt(base, i), nil)
+	}
+	for i := 0; i < 3; i++ {
+		connectOne(t, signer, kad, ab, test.RandomAddressAt(base, 0), nil)
+	}
+	depths(t, kad, 1, 1)
+
+	// a known peer and a peer only in the addressbook in bin 0
+	known := test.RandomAddressAt(base, 0)
+	addOne(t, signer, kad, ab, known)
+	stored := test.RandomAddressAt(base, 0)
+	multiaddr, err := ma.NewMultiaddr(underlayBase + stored.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bzzAddr, err := bzz.NewAddress(signer, multiaddr, stored, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ab.Put(stored, *bzzAddr); err != nil {
+		t.Fatal(err)
+	}
+
+	// a disconnected peer in bin 0 waits for its next dial attempt
+	waiting := test.RandomAddressAt(base, 0)
+	connectOne(t, signer, kad, ab, waiting, nil)
+	removeOne(kad, waiting)
+
+	// a known peer within depth
+	neighbor := test.RandomAddressAt(base, 9)
+	addOne(t, signer, kad, ab, neighbor)
+
+	suggestions, err := kad.ConnectSuggestions(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the bin 0 above depth is under-saturated and of the bins
+	// within depth only the one with a candidate is listed
+	var bins []uint8
+	for _, s := range suggestions {
+		bins = append(bins, s.Bin)
+	}
+	if want := []uint8{0, 9}; !reflect.DeepEqual(bins, want) {
+		t.Fatalf("got bins %v, want %v", bins, want)
+	}
+
+	bin0 := suggestions[0]
+	if bin0.Connected != 4 {
+		t.Errorf("got %d connected peers in bin 0, want %d", bin0.Connected, 4)
+	}
+	if len(bin0.Peers) != 2 || !bin0.Peers[0].Address.Equal(known) || !bin0.Peers[1].Address.Equal(stored) {
+		t.Fatalf("got bin 0 suggestions %v, want %s and %s", bin0.Peers, known, stored)
+	}
+	if !bin0.Peers[1].Underlay.Equal(multiaddr) {
+		t.Errorf("got underlay %s, want %s", bin0.Peers[1].Underlay, multiaddr)
+	}
+	if bin9 := suggestions[1]; bin9.Connected != 0 || len(bin9.Peers) != 1 || !bin9.Peers[0].Address.Equal(neighbor) {
+		t.Errorf("got bin 9 suggestions %v, want %s", bin9.Peers, neighbor)
+	}
+
+	// the limit applies to every bin
+	suggestions, err = kad.ConnectSuggestions(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := suggestions[0].Peers; len(got) != 1 || !got[0].Address.Equal(known) {
+		t.Fatalf("got limited bin 0 suggestions %v, want %s", got, known)
+	}
+}
+
+func TestFilterSuggestions(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	var (
+		connected   = topology.ConnectSuggestion{Address: test.RandomAddress(), LastSeen: now}
+		blocklisted = topology.ConnectSuggestion{Address: test.RandomAddress(), LastSeen: now}
+		waiting     = topology.ConnectSuggestion{Address: test.RandomAddress(), LastSeen: now}
+		neverSeen1  = topology.ConnectSuggestion{Address: test.RandomAddress()}
+		seenBefore  = topology.ConnectSuggestion{Address: test.RandomAddress(), LastSeen: now.Add(-time.Hour)}
+		retried     = topology.ConnectSuggestion{Address: test.RandomAddress()}
+		seenRecent  = topology.ConnectSuggestion{Address: test.RandomAddress(), LastSeen: now.Add(-time.Minute)}
+	)
+	candidates := []topology.ConnectSuggestion{connected, blocklisted, waiting, neverSeen1, seenBefore, retried, seenRecent}
+
+	isConnected := func(addr swarm.Address) bool {
+		return addr.Equal(connected.Address)
+	}
+	blocklist := map[string]struct{}{
+		blocklisted.Address.ByteString(): {},
+	}
+	nextDialAttempt := func(addr swarm.Address) time.Time {
+		switch {
+		case addr.Equal(waiting.Address):
+			return now.Add(time.Minute)
+		case addr.Equal(retried.Address):
+			return now.Add(-time.Minute) // the wait is over
+		}
+		return time.Time{}
+	}
+
+	for _, tc := range []struct {
+		limit int
+		want  []topology.ConnectSuggestion
+	}{
+		{
+			limit: 0,
+			want:  []topology.ConnectSuggestion{seenRecent, seenBefore, neverSeen1, retried},
+		},
+		{
+			limit: 3,
+			want:  []topology.ConnectSuggestion{seenRecent, seenBefore, neverSeen1},
+		},
+	} {
+		got := kademlia.FilterSuggestions(candidates, tc.limit, isConnected, blocklist, nextDialAttempt, now)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("limit %d: got suggestions %v, want %v", tc.limit, got, tc.want)
+		}
+	}
+}
+
+func TestEmptyBinRefill(t *testing.T) {
+	var offset int64
+	kademlia.SetTimeNow(func() time.Time { return time.Now().Add(time.Duration(atomic.LoadInt64(&offset))) })
+	defer kademlia.SetTimeNow(time.Now)
+
+	var conns int32
+	base, kad, ab, _, signer := newTestKademlia(t, &conns, nil, kademlia.Options{})
+	if err := kad.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer kad.Close()
+
+	// peers that are only in the addressbook, unknown to the kademlia
+	for i := 0; i < 3; i++ {
+		addr := test.RandomAddressAt(base, 0)
+		multiaddr, err := ma.NewMultiaddr(underlayBase + addr.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		bzzAddr, err := bzz.NewAddress(signer, multiaddr, addr, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ab.Put(addr, *bzzAddr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the empty addressbook was already checked by the first manage loop
+	atomic.StoreInt64(&offset, int64(time.Hour))
+
+	// a peer added in another bin triggers the manage loop, the
+	// empty bin 0 is refilled from the addressbook and dialed
+	addOne(t, signer, kad, ab, test.RandomAddressAt(base, 5))
+	waitCounter(t, &conns, 4)
+
+	var got int
+	for i := 0; i < 50; i++ {
+		if got = kad.NeighborhoodStats().ConnectedHistogram[0]; got == 3 {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("got %d connected peers in bin 0, want %d", got, 3)
+}
+
+func TestPeerChurn(t *testing.T) {
+	now := time.Unix(1000, 0)
+	kademlia.SetTimeNow(func() time.Time { return now })
+	defer kademlia.SetTimeNow(time.Now)
+
+	base, kad, ab, _, signer := newTestKademlia(t, nil, nil, kademlia.Options{FlapWindow: time.Minute})
+	peer := test.RandomAddressAt(base, 1)
+
+	connectOne(t, signer, kad, ab, peer, nil)
+	now = now.Add(time.Hour)
+	removeOne(kad, peer)
+
+	if got := kademlia.RetryAfterDisconnect(kad, peer, p2p.DisconnectReasonRemote); got != *kademlia.TimeToRetry {
+		t.Fatalf("got retry after %s, want %s", got, *kademlia.TimeToRetry)
+	}
+
+	// reconnects within the flap window
+	for i := 0; i < 2; i++ {
+		now = now.Add(10 * time.Second)
+		connectOne(t, signer, kad, ab, peer, nil)
+		now = now.Add(5 * time.Second)
+		removeOne(kad, peer)
+	}
+
+	// flapping peers wait longer before they are connected again
+	if got, want := kademlia.RetryAfterDisconnect(kad, peer, p2p.DisconnectReasonRemote), 3*(*kademlia.TimeToRetry); got != want {
+		t.Fatalf("got retry after %s, want %s", got, want)
+	}
+
+	// blocklisted and rejected peers are not dialed again soon, regardless of their flaps
+	for _, reason := range []p2p.DisconnectReason{p2p.DisconnectReasonBlocklisted, p2p.DisconnectReasonRejected} {
+		if got, want := kademlia.RetryAfterDisconnect(kad, peer, reason), 11*(*kademlia.TimeToRetry); got != want {
+			t.Fatalf("got retry after %s for reason %s, want %s", got, reason, want)
+		}
+	}
+
+	// reconnect after the flap window
+	now = now.Add(2 * time.Minute)
+	connectOne(t, signer, kad, ab, peer, nil)
+
+	m := peerMetrics(t, kad.Snapshot(), 1, peer)
+	if m.FlapCount != 2 {
+		t.Fatalf("got flap count %d, want %d", m.FlapCount, 2)
+	}
+	if m.LastConnectionDuration != 5 {
+		t.Fatalf("got last connection duration %v, want %v", m.LastConnectionDuration, 5)
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	t.Run("duration", func(t *testing.T) {
+		c := clock.NewMock(time.Unix(1000, 0))
+		base, kad, ab, disc, signer := newTestKademlia(t, nil, nil, kademlia.Options{
+			Warmup:         time.Hour,
+			WarmupBinPeers: 2,
+			Clock:          c,
+		})
+		p1, p2, p3 := test.RandomAddressAt(base, 0), test.RandomAddressAt(base, 1), test.RandomAddressAt(base, 2)
+
+		connectOne(t, signer, kad, ab, p1, nil)
+		connectOne(t, signer, kad, ab, p2, nil)
+		expectWarmup(t, kad, true, time.Hour)
+		if n := disc.Broadcasts(); n != 0 {
+			t.Fatalf("got %d broadcasts during the warm-up, want none", n)
+		}
+
+		c.Advance(time.Hour)
+		expectWarmup(t, kad, false, 0)
+
+		connectOne(t, signer, kad, ab, p3, nil)
+		waitBcast(t, disc, p3, p1, p2)
+	})
+
+	t.Run("populated bins", func(t *testing.T) {
+		c := clock.NewMock(time.Unix(1000, 0))
+		base, kad, ab, disc, signer := newTestKademlia(t, nil, nil, kademlia.Options{
+			Warmup:         time.Hour,
+			WarmupBinPeers: 1,
+			Clock:          c,
+		})
+
+		c.Advance(time.Minute)
+		for bin := uint8(0); bin < 3; bin++ {
+			connectOne(t, signer, kad, ab, test.RandomAddressAt(base, int(bin)), nil)
+		}
+		expectWarmup(t, kad, true, time.Hour-time.Minute)
+		if n := disc.Broadcasts(); n != 0 {
+			t.Fatalf("got %d broadcasts during the warm-up, want none", n)
+		}
+
+		connectOne(t, signer, kad, ab, test.RandomAddressAt(base, 3), nil)
+		expectWarmup(t, kad, false, 0)
+	})
+
+	t.Run("bootnode", func(t *testing.T) {
+		_, kad, _, _, _ := newTestKademlia(t, nil, nil, kademlia.Options{
+			Warmup:       time.Hour,
+			BootnodeMode: true,
+		})
+		expectWarmup(t, kad, false, 0)
+	})
+}
+
+func expectWarmup(t *testing.T, k *kademlia.Kad, wantActive bool, wantRemaining time.Duration) {
+	t.Helper()
+
+	active, remaining := k.Warmup()
+	if active != wantActive {
+		t.Fatalf("got warm-up active %v, want %v", active, wantActive)
+	}
+	if remaining != wantRemaining {
+		t.Fatalf("got warm-up remaining %v, want %v", remaining, wantRemaining)
+	}
+}
+
 func getBinPopulation(bins *topology.KadBins, po uint8) uint64 {
 	rv := reflect.ValueOf(bins)
 	bin := fmt.Sprintf("Bin%d", po)
@@ -1094,6 +1535,22 @@ func getBinPopulation(bins *topology.KadBins, po uint8) uint64 {
 	return bp.Uint()
 }
 
+func peerMetrics(t *testing.T, ss *topology.KadParams, po uint8, addr swarm.Address) *topology.MetricSnapshotView {
+	t.Helper()
+
+	bin := reflect.ValueOf(ss.Bins).FieldByName(fmt.Sprintf("Bin%d", po)).Interface().(topology.BinInfo)
+	for _, p := range append(bin.ConnectedPeers, bin.DisconnectedPeers...) {
+		if p.Address.Equal(addr) {
+			if p.Metrics == nil {
+				t.Fatalf("no metrics for peer %s", addr)
+			}
+			return p.Metrics
+		}
+	}
+	t.Fatalf("peer %s not found in bin %d", addr, po)
+	return nil
+}
+
 func TestStart(t *testing.T) {
 	var bootnodes []ma.Multiaddr
 	for i := 0; i < 10; i++ {
@@ -1148,6 +1605,151 @@ func TestStart(t *testing.T) {
 	})
 }
 
+// TestBootnodeHealth tests that the bootnodes are dialed in the order of their
+// connection health and that no more are dialed once enough are connected.
+func TestBootnodeHealth(t *testing.T) {
+	var bootnodes, healthy []ma.Multiaddr
+	dead := make(map[string]bool)
+	for i := 0; i < 6; i++ {
+		addr, err := ma.NewMultiaddr(underlayBase + test.RandomAddress().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		bootnodes = append(bootnodes, addr)
+		if i%2 == 0 {
+			dead[addr.String()] = true
+		} else {
+			healthy = append(healthy, addr)
+		}
+	}
+
+	stateStore := mockstate.NewStateStore()
+
+	// start returns the bootnodes dialed until three of them are connected
+	start := func(t *testing.T) ([]string, *topology.KadParams) {
+		t.Helper()
+
+		metricsDB, err := shed.NewDB("", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer metricsDB.Close()
+
+		pk, _ := beeCrypto.GenerateSecp256k1Key()
+		signer := beeCrypto.NewDefaultSigner(pk)
+		ab := addressbook.New(mockstate.NewStateStore())
+
+		var (
+			mtx    sync.Mutex
+			dialed []string
+		)
+		p2ps := p2pmock.New(p2pmock.WithConnectFunc(func(_ context.Context, addr ma.Multiaddr) (*bzz.Address, error) {
+			mtx.Lock()
+			dialed = append(dialed, addr.String())
+			mtx.Unlock()
+
+			if dead[addr.String()] {
+				return nil, errors.New("dead bootnode")
+			}
+			overlay := test.RandomAddress()
+			bzzAddr, err := bzz.NewAddress(signer, addr, overlay, 0, nil)
+			if err != nil {
+				return nil, err
+			}
+			if err := ab.Put(overlay, *bzzAddr); err != nil {
+				return nil, err
+			}
+			return bzzAddr, nil
+		}))
+
+		kad := kademlia.New(test.RandomAddress(), ab, mock.NewDiscovery(), p2ps, metricsDB, logging.New(ioutil.Discard, 0), kademlia.Options{
+			Bootnodes:  bootnodes,
+			StateStore: stateStore,
+		})
+		if err := kad.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		defer kad.Close()
+
+		waitPeers(t, kad, 3)
+
+		var ss *topology.KadParams
+		for i := 0; i < 50; i++ {
+			// wait for the health of the connected bootnodes
+			ss = kad.Snapshot()
+			connected := 0
+			for _, b := range ss.Bootnodes {
+				if b.LastConnected != 0 {
+					connected++
+				}
+			}
+			if connected == len(healthy) {
+				break
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+
+		mtx.Lock()
+		defer mtx.Unlock()
+		return append([]string(nil), dialed...), ss
+	}
+
+	// without history the bootnodes are dialed until all healthy ones are connected
+	dialed, ss := start(t)
+	if len(dialed) < len(healthy) {
+		t.Fatalf("got %d dialed bootnodes, want at least %d", len(dialed), len(healthy))
+	}
+	if len(ss.Bootnodes) != len(bootnodes) {
+		t.Fatalf("got %d bootnode statuses, want %d", len(ss.Bootnodes), len(bootnodes))
+	}
+	for i, b := range ss.Bootnodes {
+		if b.Address != bootnodes[i].String() {
+			t.Fatalf("got bootnode %d %s, want %s", i, b.Address, bootnodes[i])
+		}
+		if !dead[b.Address] && (b.LastConnected == 0 || b.ConsecutiveFailures != 0) {
+			t.Fatalf("got status %+v for a healthy bootnode", b)
+		}
+	}
+
+	// the persisted health puts the healthy bootnodes first and
+	// no other bootnodes are dialed once they are connected
+	dialed, _ = start(t)
+	if len(dialed) != len(healthy) {
+		t.Fatalf("got dialed bootnodes %v, want %d healthy ones", dialed, len(healthy))
+	}
+	for _, addr := range dialed {
+		if dead[addr] {
+			t.Fatalf("dead bootnode %s dialed", addr)
+		}
+	}
+}
+
+// TestLightNodeDepth tests that no address is within the depth of a light node.
+func TestLightNodeDepth(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		lightNode bool
+		want      bool
+	}{
+		{name: "full node", want: true},
+		{name: "light node", lightNode: true, want: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			base, kad, _, _, _ := newTestKademlia(t, nil, nil, kademlia.Options{LightNode: tc.lightNode})
+			if err := kad.Start(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			defer kad.Close()
+
+			// without connected peers the depth is zero
+			kDepth(t, kad, 0)
+			if got := kad.IsWithinDepth(test.RandomAddressAt(base, 0)); got != tc.want {
+				t.Fatalf("got within depth %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
 func newTestKademlia(t *testing.T, connCounter, failedConnCounter *int32, kadOpts kademlia.Options) (swarm.Address, *kademlia.Kad, addressbook.Interface, *mock.Discovery, beeCrypto.Signer) {
 	t.Helper()
 
@@ -1220,7 +1822,7 @@ func p2pMock(ab addressbook.Interface, signer beeCrypto.Signer, counter, failedC
 }
 
 func removeOne(k *kademlia.Kad, peer swarm.Address) {
-	k.Disconnected(p2p.Peer{Address: peer})
+	k.Disconnected(p2p.Peer{Address: peer}, p2p.DisconnectReasonRemote)
 }
 
 const underlayBase = "/ip4/127.0.0.1/tcp/1634/dns/"
@@ -1273,7 +1875,8 @@ func kDepth(t *testing.T, k *kademlia.Kad, d int) {
 	t.Helper()
 	var depth int
 	for i := 0; i < 50; i++ {
-		depth = int(k.NeighborhoodDepth())
+		_, effective := k.NeighborhoodDepth()
+		depth = int(effective)
 		if depth == d {
 			return
 		}
@@ -1282,6 +1885,15 @@ func kDepth(t *testing.T, k *kademlia.Kad, d int) {
 	t.Fatalf("timed out waiting for depth. want %d got %d", d, depth)
 }
 
+func depths(t *testing.T, k *kademlia.Kad, raw, effective uint8) {
+	t.Helper()
+
+	gotRaw, gotEffective := k.NeighborhoodDepth()
+	if gotRaw != raw || gotEffective != effective {
+		t.Fatalf("got raw depth %d and effective depth %d, want %d and %d", gotRaw, gotEffective, raw, effective)
+	}
+}
+
 func waitConn(t *testing.T, conns *int32) {
 	t.Helper()
 	waitCounter(t, conns, 1)
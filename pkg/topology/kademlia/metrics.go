@@ -5,6 +5,8 @@
 package kademlia
 
 import (
+	"strconv"
+
 	m "github.com/ethersphere/bee/pkg/metrics"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -14,9 +16,13 @@ type metrics struct {
 	PickCalls                             prometheus.Counter
 	PickCallsFalse                        prometheus.Counter
 	CurrentDepth                          prometheus.Gauge
+	CurrentEffectiveDepth                 prometheus.Gauge
 	CurrentRadius                         prometheus.Gauge
 	CurrentlyKnownPeers                   prometheus.Gauge
 	CurrentlyConnectedPeers               prometheus.Gauge
+	NeighborhoodConnectedPeers            prometheus.Gauge
+	NeighborhoodDisconnectedPeers         prometheus.Gauge
+	ConnectedPeersPerBin                  *prometheus.GaugeVec
 	InternalMetricsFlushTime              prometheus.Histogram
 	InternalMetricsFlushTotalErrors       prometheus.Counter
 	TotalBeforeExpireWaits                prometheus.Counter
@@ -27,6 +33,10 @@ type metrics struct {
 	TotalOutboundConnectionFailedAttempts prometheus.Counter
 	TotalBootNodesConnectionAttempts      prometheus.Counter
 	StartAddAddressBookOverlaysTime       prometheus.Histogram
+	ConnectionDuration                    prometheus.Histogram
+	TotalFlaps                            prometheus.Counter
+	TotalBinRefillPeers                   prometheus.Counter
+	TotalWarmupSuppressedAnnounces        prometheus.Counter
 }
 
 // newMetrics is a convenient constructor for creating new metrics.
@@ -52,6 +62,12 @@ func newMetrics() metrics {
 			Name:      "current_depth",
 			Help:      "The current value of depth.",
 		}),
+		CurrentEffectiveDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "current_effective_depth",
+			Help:      "The current value of depth reported to consumers.",
+		}),
 		CurrentRadius: prometheus.NewGauge(prometheus.GaugeOpts{
 			Namespace: m.Namespace,
 			Subsystem: subsystem,
@@ -70,6 +86,27 @@ func newMetrics() metrics {
 			Name:      "currently_connected_peers",
 			Help:      "Number of currently connected peers.",
 		}),
+		NeighborhoodConnectedPeers: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "neighborhood_connected_peers",
+			Help:      "Number of connected peers within depth.",
+		}),
+		NeighborhoodDisconnectedPeers: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "neighborhood_disconnected_peers",
+			Help:      "Number of known but not connected peers within depth.",
+		}),
+		ConnectedPeersPerBin: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: m.Namespace,
+				Subsystem: subsystem,
+				Name:      "connected_peers_per_bin",
+				Help:      "Number of connected peers in each bin.",
+			},
+			[]string{"bin"},
+		),
 		InternalMetricsFlushTime: prometheus.NewHistogram(prometheus.HistogramOpts{
 			Namespace: m.Namespace,
 			Subsystem: subsystem,
@@ -130,6 +167,42 @@ func newMetrics() metrics {
 			Name:      "start_add_addressbook_overlays_time",
 			Help:      "The time spent adding overlays peers from addressbook on kademlia start.",
 		}),
+		ConnectionDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "connection_duration",
+			Help:      "The duration of peer connections in seconds, observed when the peer disconnects.",
+			Buckets:   []float64{1, 10, 60, 5 * 60, 30 * 60, 60 * 60, 6 * 60 * 60, 24 * 60 * 60},
+		}),
+		TotalFlaps: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "total_flaps",
+			Help:      "Total connects of peers shortly after they have disconnected.",
+		}),
+		TotalBinRefillPeers: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "total_bin_refill_peers",
+			Help:      "Total addressbook peers added to empty bins.",
+		}),
+		TotalWarmupSuppressedAnnounces: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: m.Namespace,
+			Subsystem: subsystem,
+			Name:      "total_warmup_suppressed_announces",
+			Help:      "Total peer announcements not made while the node was warming up.",
+		}),
+	}
+}
+
+// updateNeighborhoodMetrics sets the neighborhood gauges
+// from the current neighborhood statistics.
+func (k *Kad) updateNeighborhoodMetrics() {
+	stats := k.NeighborhoodStats()
+	k.metrics.NeighborhoodConnectedPeers.Set(float64(stats.ConnectedWithinDepth))
+	k.metrics.NeighborhoodDisconnectedPeers.Set(float64(stats.DisconnectedWithinDepth))
+	for bin, n := range stats.ConnectedHistogram {
+		k.metrics.ConnectedPeersPerBin.WithLabelValues(strconv.Itoa(bin)).Set(float64(n))
 	}
 }
 
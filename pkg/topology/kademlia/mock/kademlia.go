@@ -40,18 +40,19 @@ func WithDepthCalls(d ...uint8) Option {
 }
 
 type Mock struct {
-	mtx          sync.Mutex
-	peers        []swarm.Address
-	eachPeerRev  []AddrTuple
-	depth        uint8
-	depthReplies []uint8
-	depthCalls   int
-	trigs        []chan struct{}
-	trigMtx      sync.Mutex
+	mtx               sync.Mutex
+	peers             []swarm.Address
+	disconnectReasons map[string]p2p.DisconnectReason
+	eachPeerRev       []AddrTuple
+	depth             uint8
+	depthReplies      []uint8
+	depthCalls        int
+	trigs             []chan struct{}
+	trigMtx           sync.Mutex
 }
 
 func NewMockKademlia(o ...Option) *Mock {
-	m := &Mock{}
+	m := &Mock{disconnectReasons: make(map[string]p2p.DisconnectReason)}
 	for _, v := range o {
 		v.apply(m)
 	}
@@ -114,15 +115,15 @@ func (m *Mock) EachPeerRev(f topology.EachPeerFunc) error {
 	return nil
 }
 
-func (m *Mock) NeighborhoodDepth() uint8 {
+func (m *Mock) NeighborhoodDepth() (raw, effective uint8) {
 	m.mtx.Lock()
 	defer m.mtx.Unlock()
 
 	m.depthCalls++
 	if len(m.depthReplies) > 0 {
-		return m.depthReplies[m.depthCalls]
+		return m.depthReplies[m.depthCalls], m.depthReplies[m.depthCalls]
 	}
-	return m.depth
+	return m.depth, m.depth
 }
 
 // Connected is called when a peer dials in.
@@ -135,10 +136,12 @@ func (m *Mock) Connected(_ context.Context, peer p2p.Peer, _ bool) error {
 }
 
 // Disconnected is called when a peer disconnects.
-func (m *Mock) Disconnected(peer p2p.Peer) {
+func (m *Mock) Disconnected(peer p2p.Peer, reason p2p.DisconnectReason) {
 	m.mtx.Lock()
 	defer m.mtx.Unlock()
 
+	m.disconnectReasons[peer.Address.ByteString()] = reason
+
 	for i, addr := range m.peers {
 		if addr.Equal(peer.Address) {
 			m.peers = append(m.peers[:i], m.peers[i+1:]...)
@@ -148,6 +151,15 @@ func (m *Mock) Disconnected(peer p2p.Peer) {
 	m.Trigger()
 }
 
+// DisconnectReason returns the reason of the last disconnect of the peer.
+func (m *Mock) DisconnectReason(addr swarm.Address) (p2p.DisconnectReason, bool) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	r, ok := m.disconnectReasons[addr.ByteString()]
+	return r, ok
+}
+
 func (m *Mock) Announce(_ context.Context, _ swarm.Address, _ bool) error {
 	return nil
 }
@@ -203,6 +215,14 @@ func (m *Mock) Snapshot() *topology.KadParams {
 	panic("not implemented") // TODO: Implement
 }
 
+func (m *Mock) NeighborhoodStats() topology.NeighborhoodStats {
+	panic("not implemented") // TODO: Implement
+}
+
+func (m *Mock) ConnectSuggestions(int) ([]topology.BinSuggestions, error) {
+	panic("not implemented") // TODO: Implement
+}
+
 type Option interface {
 	apply(*Mock)
 }
@@ -0,0 +1,157 @@
+// Copyright 2021 The Swarm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kademlia
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ethersphere/bee/pkg/addressbook"
+	"github.com/ethersphere/bee/pkg/swarm"
+	"github.com/ethersphere/bee/pkg/topology"
+)
+
+// maxSuggestionCandidates is the number of addressbook entries
+// considered for the connect suggestions of a single bin.
+const maxSuggestionCandidates = 64
+
+// ConnectSuggestions implements topology.ConnectSuggester. A bin shallower
+// than the depth is under-saturated if it has fewer than saturationPeers
+// connected peers. A bin within the depth is under-saturated if any of its
+// known peers can be connected, as all neighbors should be connected.
+// The candidates are the known peers of the bin and its peers in the
+// addressbook.
+func (k *Kad) ConnectSuggestions(limit int) ([]topology.BinSuggestions, error) {
+	blocklisted := make(map[string]struct{})
+	peers, err := k.p2p.BlocklistedPeers()
+	if err != nil {
+		return nil, fmt.Errorf("blocklisted peers: %w", err)
+	}
+	for _, p := range peers {
+		blocklisted[p.Address.ByteString()] = struct{}{}
+	}
+
+	depth := k.rawDepth()
+	now := time.Now() // the dial attempts are scheduled with the wall clock
+
+	var suggestions []topology.BinSuggestions
+	for bin := uint8(0); bin < swarm.MaxBins; bin++ {
+		connected := len(k.connectedPeers.BinPeers(bin))
+		if bin < depth && connected >= saturationPeers {
+			continue
+		}
+
+		candidates, err := k.suggestionCandidates(bin)
+		if err != nil {
+			return nil, fmt.Errorf("bin %d: %w", bin, err)
+		}
+		candidates = filterSuggestions(candidates, limit, k.connectedPeers.Exists, blocklisted, k.waitNext.NextDialAttempt, now)
+		if bin >= depth && len(candidates) == 0 {
+			continue
+		}
+
+		suggestions = append(suggestions, topology.BinSuggestions{
+			Bin:       bin,
+			Connected: connected,
+			Peers:     candidates,
+		})
+	}
+	return suggestions, nil
+}
+
+// suggestionCandidates returns the known peers of the bin followed by the
+// other peers of the bin from the addressbook, with their underlays and
+// the time they were last seen.
+func (k *Kad) suggestionCandidates(bin uint8) ([]topology.ConnectSuggestion, error) {
+	var (
+		candidates []topology.ConnectSuggestion
+		seen       = make(map[string]struct{})
+	)
+
+	for _, addr := range k.knownPeers.BinPeers(bin) {
+		bzzAddr, err := k.addressBook.Get(addr)
+		if err != nil {
+			if errors.Is(err, addressbook.ErrNotFound) {
+				continue // no underlay to dial
+			}
+			return nil, fmt.Errorf("addressbook get %s: %w", addr, err)
+		}
+		seen[addr.ByteString()] = struct{}{}
+		candidates = append(candidates, topology.ConnectSuggestion{
+			Address:  addr,
+			Underlay: bzzAddr.Underlay,
+		})
+	}
+
+	addrs, err := k.addressBook.BinPeers(k.base, bin, maxSuggestionCandidates)
+	if err != nil {
+		return nil, fmt.Errorf("addressbook bin peers: %w", err)
+	}
+	for _, a := range addrs {
+		if _, ok := seen[a.Overlay.ByteString()]; ok {
+			continue
+		}
+		candidates = append(candidates, topology.ConnectSuggestion{
+			Address:  a.Overlay,
+			Underlay: a.Underlay,
+		})
+	}
+
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	overlays := make([]swarm.Address, 0, len(candidates))
+	for _, c := range candidates {
+		overlays = append(overlays, c.Address)
+	}
+	ss := k.collector.Snapshot(time.Now(), overlays...)
+	for i, c := range candidates {
+		if s, ok := ss[c.Address.ByteString()]; ok && s.LastSeenTimestamp > 0 {
+			candidates[i].LastSeen = time.Unix(0, s.LastSeenTimestamp)
+		}
+	}
+
+	return candidates, nil
+}
+
+// filterSuggestions removes the connected peers, then the blocklisted peers
+// and then the peers that wait for their next dial attempt from the
+// candidates. The remaining ones are ordered by the time they were last seen,
+// the most recent first and the never seen ones last, keeping the order of
+// the candidates otherwise, and up to limit of them are returned.
+func filterSuggestions(
+	candidates []topology.ConnectSuggestion,
+	limit int,
+	connected func(swarm.Address) bool,
+	blocklisted map[string]struct{},
+	nextDialAttempt func(swarm.Address) time.Time,
+	now time.Time,
+) []topology.ConnectSuggestion {
+	filtered := make([]topology.ConnectSuggestion, 0, len(candidates))
+	for _, c := range candidates {
+		if connected(c.Address) {
+			continue
+		}
+		if _, ok := blocklisted[c.Address.ByteString()]; ok {
+			continue
+		}
+		if nextDialAttempt(c.Address).After(now) {
+			continue
+		}
+		filtered = append(filtered, c)
+	}
+
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return filtered[i].LastSeen.After(filtered[j].LastSeen)
+	})
+
+	if limit > 0 && len(filtered) > limit {
+		filtered = filtered[:limit]
+	}
+	return filtered
+}
@@ -22,6 +22,8 @@ type mock struct {
 	addPeersErr     error
 	isWithinFunc    func(c swarm.Address) bool
 	marshalJSONFunc func() ([]byte, error)
+	suggestions     []topology.BinSuggestions
+	suggestionsErr  error
 	mtx             sync.Mutex
 }
 
@@ -67,6 +69,15 @@ func WithIsWithinFunc(f func(swarm.Address) bool) Option {
 	})
 }
 
+// WithConnectSuggestions sets the connect suggestions
+// and the error returned by ConnectSuggestions.
+func WithConnectSuggestions(suggestions []topology.BinSuggestions, err error) Option {
+	return optionFunc(func(d *mock) {
+		d.suggestions = suggestions
+		d.suggestionsErr = err
+	})
+}
+
 func NewTopologyDriver(opts ...Option) topology.Driver {
 	d := new(mock)
 	for _, o := range opts {
@@ -87,7 +98,7 @@ func (d *mock) Connected(ctx context.Context, peer p2p.Peer, _ bool) error {
 	return nil
 }
 
-func (d *mock) Disconnected(peer p2p.Peer) {
+func (d *mock) Disconnected(peer p2p.Peer, _ p2p.DisconnectReason) {
 	d.mtx.Lock()
 	defer d.mtx.Unlock()
 
@@ -161,8 +172,8 @@ func (d *mock) SubscribePeersChange() (c <-chan struct{}, unsubscribe func()) {
 	return c, unsubscribe
 }
 
-func (m *mock) NeighborhoodDepth() uint8 {
-	return m.depth
+func (m *mock) NeighborhoodDepth() (raw, effective uint8) {
+	return m.depth, m.depth
 }
 
 func (m *mock) IsWithinDepth(addr swarm.Address) bool {
@@ -218,6 +229,32 @@ func (d *mock) Snapshot() *topology.KadParams {
 	return new(topology.KadParams)
 }
 
+func (d *mock) NeighborhoodStats() topology.NeighborhoodStats {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	return topology.NeighborhoodStats{
+		Depth:                d.depth,
+		ConnectedWithinDepth: len(d.peers),
+		ConnectedHistogram:   make([]int, swarm.MaxBins),
+	}
+}
+
+func (d *mock) ConnectSuggestions(limit int) ([]topology.BinSuggestions, error) {
+	if d.suggestionsErr != nil {
+		return nil, d.suggestionsErr
+	}
+
+	suggestions := make([]topology.BinSuggestions, 0, len(d.suggestions))
+	for _, s := range d.suggestions {
+		if limit > 0 && len(s.Peers) > limit {
+			s.Peers = s.Peers[:limit]
+		}
+		suggestions = append(suggestions, s)
+	}
+	return suggestions, nil
+}
+
 func (d *mock) Halt()        {}
 func (d *mock) Close() error { return nil }
 
@@ -13,6 +13,7 @@ import (
 
 	"github.com/ethersphere/bee/pkg/p2p"
 	"github.com/ethersphere/bee/pkg/swarm"
+	ma "github.com/multiformats/go-multiaddr"
 )
 
 var (
@@ -32,6 +33,8 @@ type Driver interface {
 	io.Closer
 	Halter
 	Snapshot() *KadParams
+	NeighborhoodStats() NeighborhoodStats
+	ConnectSuggester
 }
 
 type PeerAdder interface {
@@ -79,6 +82,25 @@ type MetricSnapshotView struct {
 	ConnectionTotalDuration    float64 `json:"connectionTotalDuration"`
 	SessionConnectionDuration  float64 `json:"sessionConnectionDuration"`
 	SessionConnectionDirection string  `json:"sessionConnectionDirection"`
+	LastConnectionDuration     float64 `json:"lastConnectionDuration"`
+	FlapCount                  int     `json:"flapCount"`
+}
+
+// BootnodeInfo is the connection health of a bootnode. The timestamps
+// are in unix seconds, zero if there was no such connection attempt.
+type BootnodeInfo struct {
+	Address             string `json:"address"`
+	LastConnected       int64  `json:"lastConnected"`
+	LastFailed          int64  `json:"lastFailed"`
+	ConsecutiveFailures int    `json:"consecutiveFailures"`
+}
+
+// NeighborhoodStats summarises the connectivity of the neighborhood.
+type NeighborhoodStats struct {
+	Depth                   uint8 `json:"depth"`                   // current effective depth
+	ConnectedWithinDepth    int   `json:"connectedWithinDepth"`    // connected peers at or deeper than depth
+	DisconnectedWithinDepth int   `json:"disconnectedWithinDepth"` // known but not connected peers at or deeper than depth
+	ConnectedHistogram      []int `json:"connectedHistogram"`      // connected peers per proximity order
 }
 
 type BinInfo struct {
@@ -124,14 +146,40 @@ type KadBins struct {
 }
 
 type KadParams struct {
-	Base           string    `json:"baseAddr"`       // base address string
-	Population     int       `json:"population"`     // known
-	Connected      int       `json:"connected"`      // connected count
-	Timestamp      time.Time `json:"timestamp"`      // now
-	NNLowWatermark int       `json:"nnLowWatermark"` // low watermark for depth calculation
-	Depth          uint8     `json:"depth"`          // current depth
-	Bins           KadBins   `json:"bins"`           // individual bin info
-	LightNodes     BinInfo   `json:"lightNodes"`     // light nodes bin info
+	Base           string            `json:"baseAddr"`            // base address string
+	Population     int               `json:"population"`          // known
+	Connected      int               `json:"connected"`           // connected count
+	Timestamp      time.Time         `json:"timestamp"`           // now
+	NNLowWatermark int               `json:"nnLowWatermark"`      // low watermark for depth calculation
+	Depth          uint8             `json:"depth"`               // current effective depth
+	RawDepth       uint8             `json:"rawDepth"`            // current depth before hysteresis
+	Bins           KadBins           `json:"bins"`                // individual bin info
+	LightNodes     BinInfo           `json:"lightNodes"`          // light nodes bin info
+	Bootnodes      []BootnodeInfo    `json:"bootnodes,omitempty"` // connection health of the bootnodes
+	Neighborhood   NeighborhoodStats `json:"neighborhood"`        // neighborhood statistics
+}
+
+// ConnectSuggester suggests peers to connect to
+// in the bins that lack connected peers.
+type ConnectSuggester interface {
+	// ConnectSuggestions returns the under-saturated bins with up to limit
+	// known but disconnected peers each that may be dialed, the most
+	// recently seen first. A limit of zero does not limit the peers.
+	ConnectSuggestions(limit int) ([]BinSuggestions, error)
+}
+
+// BinSuggestions are the peers suggested to connect to in an under-saturated bin.
+type BinSuggestions struct {
+	Bin       uint8
+	Connected int
+	Peers     []ConnectSuggestion
+}
+
+// ConnectSuggestion is a known but disconnected peer that may be dialed.
+type ConnectSuggestion struct {
+	Address  swarm.Address
+	Underlay ma.Multiaddr
+	LastSeen time.Time // zero if the peer was never seen connected
 }
 
 type Halter interface {
@@ -141,5 +189,7 @@ type Halter interface {
 }
 
 type NeighborhoodDepther interface {
-	NeighborhoodDepth() uint8
+	// NeighborhoodDepth returns the depth calculated from the connected
+	// peers and the smoothed depth that consumers should act upon.
+	NeighborhoodDepth() (raw, effective uint8)
 }
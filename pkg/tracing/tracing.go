@@ -89,6 +89,12 @@ func NewTracer(o *Options) (*Tracer, io.Closer, error) {
 	return &Tracer{tracer: t}, closer, nil
 }
 
+// NewTracerFromOpentracing returns a Tracer that uses the provided
+// opentracing Tracer, for example an in-memory tracer in tests.
+func NewTracerFromOpentracing(t opentracing.Tracer) *Tracer {
+	return &Tracer{tracer: t}
+}
+
 // StartSpanFromContext starts a new tracing span that is either a root one or a
 // child of existing one from the provided Context. If logger is provided, a new
 // log Entry will be returned with "traceID" log field.